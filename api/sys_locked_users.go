@@ -0,0 +1,82 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// LockedUsers returns the users locked out of auth mounts after too many
+// failed login attempts. If mountAccessor is set, only users of that auth
+// mount are returned.
+func (c *Sys) LockedUsers(mountAccessor string) (*LockedUsersResponse, error) {
+	return c.LockedUsersWithContext(context.Background(), mountAccessor)
+}
+
+func (c *Sys) LockedUsersWithContext(ctx context.Context, mountAccessor string) (*LockedUsersResponse, error) {
+	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
+	defer cancelFunc()
+
+	r := c.c.NewRequest(http.MethodGet, "/v1/sys/locked-users")
+	if mountAccessor != "" {
+		r.Params.Set("mount_accessor", mountAccessor)
+	}
+
+	resp, err := c.c.rawRequestWithContext(ctx, r)
+	if resp != nil {
+		defer resp.Body.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := ParseSecret(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.New("data from server response is empty")
+	}
+
+	var result LockedUsersResponse
+	if err := mapstructure.Decode(secret.Data, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// UnlockUser lifts the lockout of the user with the given entity alias name
+// in the auth mount with the given accessor.
+func (c *Sys) UnlockUser(mountAccessor, aliasIdentifier string) error {
+	return c.UnlockUserWithContext(context.Background(), mountAccessor, aliasIdentifier)
+}
+
+func (c *Sys) UnlockUserWithContext(ctx context.Context, mountAccessor, aliasIdentifier string) error {
+	ctx, cancelFunc := c.c.withConfiguredTimeout(ctx)
+	defer cancelFunc()
+
+	r := c.c.NewRequest(http.MethodPost, fmt.Sprintf("/v1/sys/locked-users/%s/unlock/%s", mountAccessor, aliasIdentifier))
+
+	resp, err := c.c.rawRequestWithContext(ctx, r)
+	if err == nil {
+		defer resp.Body.Close()
+	}
+	return err
+}
+
+type LockedUsersResponse struct {
+	LockedUsers []*LockedUser `json:"locked_users" mapstructure:"locked_users"`
+	Total       int           `json:"total" mapstructure:"total"`
+}
+
+type LockedUser struct {
+	MountAccessor       string `json:"mount_accessor" mapstructure:"mount_accessor"`
+	AliasIdentifier     string `json:"alias_identifier" mapstructure:"alias_identifier"`
+	FailedLoginAttempts int    `json:"failed_login_attempts" mapstructure:"failed_login_attempts"`
+	LockedAt            string `json:"locked_at" mapstructure:"locked_at"`
+	LockedUntil         string `json:"locked_until" mapstructure:"locked_until"`
+}
@@ -42,6 +42,13 @@ type Backend interface {
 	Invalidate(context.Context)
 }
 
+// Closer is an optional interface for audit backends holding resources, such
+// as background delivery goroutines, which must be released once the
+// backend is removed from the audit broker.
+type Closer interface {
+	Close() error
+}
+
 // BackendConfig contains configuration parameters used in the factory func to
 // instantiate audit backends
 type BackendConfig struct {
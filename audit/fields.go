@@ -0,0 +1,209 @@
+package audit
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/mitchellh/copystructure"
+)
+
+// FieldPath identifies a field of an audit entry by the names it has in the
+// JSON output, such as {"request", "data", "password"}.
+type FieldPath []string
+
+func (p FieldPath) String() string {
+	return strings.Join(p, ".")
+}
+
+var auditEntryType = reflect.TypeOf(AuditResponseEntry{})
+
+// ParseFieldList parses the comma separated list of dotted field paths given
+// to the drop_fields and hmac_fields audit device options. Paths are checked
+// against the layout of audit entries; below maps such as request.data or
+// request.headers any key is accepted.
+func ParseFieldList(raw string) ([]FieldPath, error) {
+	var paths []FieldPath
+	for _, field := range strutil.ParseStringSlice(raw, ",") {
+		if field == "" {
+			continue
+		}
+		path := FieldPath(strings.Split(field, "."))
+		for _, elem := range path {
+			if elem == "" {
+				return nil, fmt.Errorf("invalid field %q", field)
+			}
+		}
+		if err := validateFieldPath(auditEntryType, path); err != nil {
+			return nil, fmt.Errorf("invalid field %q: %w", field, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func validateFieldPath(t reflect.Type, path FieldPath) error {
+	for i, elem := range path {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct:
+			field, ok := fieldByJSONName(t, elem)
+			if !ok {
+				return fmt.Errorf("unknown field %q", FieldPath(path[:i+1]))
+			}
+			t = field.Type
+		case reflect.Map, reflect.Interface:
+			// Map keys are not known ahead of time.
+			return nil
+		default:
+			return fmt.Errorf("%q has no fields", FieldPath(path[:i]))
+		}
+	}
+	return nil
+}
+
+// fieldByJSONName returns the field of the struct type which is encoded
+// with the given name.
+func fieldByJSONName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tagName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if tagName == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// applyFieldConfig returns a copy of the given audit entry, a pointer to an
+// AuditRequestEntry or AuditResponseEntry, with the configured fields dropped
+// or HMAC'd. The entry is returned unchanged when there is nothing to do.
+func applyFieldConfig(salter *salt.Salt, config FormatterConfig, entry interface{}) (interface{}, error) {
+	if len(config.DropFields) == 0 && len(config.HMACFields) == 0 {
+		return entry, nil
+	}
+
+	// The entry may share maps with the request or response being logged,
+	// which must not be modified.
+	entry, err := copystructure.Copy(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range config.HMACFields {
+		err := rewriteField(reflect.ValueOf(entry), path, func(v reflect.Value) (reflect.Value, error) {
+			return hmacFieldValue(salter, v)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to HMAC field %q: %w", path, err)
+		}
+	}
+
+	for _, path := range config.DropFields {
+		err := rewriteField(reflect.ValueOf(entry), path, func(reflect.Value) (reflect.Value, error) {
+			return reflect.Value{}, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to drop field %q: %w", path, err)
+		}
+	}
+
+	return entry, nil
+}
+
+// rewriteField replaces the value found at path below v with the result of
+// fn. An invalid result removes the value. Paths leading to absent values are
+// ignored.
+func rewriteField(v reflect.Value, path FieldPath, fn func(reflect.Value) (reflect.Value, error)) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		field, ok := fieldByJSONName(v.Type(), path[0])
+		if !ok {
+			return nil
+		}
+		fv := v.FieldByIndex(field.Index)
+		if len(path) > 1 {
+			return rewriteField(fv, path[1:], fn)
+		}
+
+		nv, err := fn(fv)
+		if err != nil {
+			return err
+		}
+		if !nv.IsValid() {
+			nv = reflect.Zero(fv.Type())
+		}
+		return setValue(fv, nv)
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		key := reflect.ValueOf(path[0]).Convert(v.Type().Key())
+		ev := v.MapIndex(key)
+		if !ev.IsValid() {
+			return nil
+		}
+		if len(path) > 1 {
+			return rewriteField(ev, path[1:], fn)
+		}
+
+		nv, err := fn(ev)
+		if err != nil {
+			return err
+		}
+		if !nv.IsValid() {
+			v.SetMapIndex(key, reflect.Value{})
+			return nil
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setValue(elem, nv); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+	}
+
+	return nil
+}
+
+func setValue(dst, src reflect.Value) error {
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case src.Type().ConvertibleTo(dst.Type()):
+		dst.Set(src.Convert(dst.Type()))
+	default:
+		return fmt.Errorf("cannot store %s value in %s field", src.Type(), dst.Type())
+	}
+	return nil
+}
+
+// hmacFieldValue HMACs all strings found in the given value, the same way
+// request and response data is hashed.
+func hmacFieldValue(salter *salt.Salt, v reflect.Value) (reflect.Value, error) {
+	if v.Kind() == reflect.String {
+		return reflect.ValueOf(salter.GetIdentifiedHMAC(v.String())), nil
+	}
+
+	// HashStructure replaces values in place, so hand it a map holding the
+	// value.
+	holder := map[string]interface{}{"value": v.Interface()}
+	if err := HashStructure(holder, salter.GetIdentifiedHMAC, nil); err != nil {
+		return reflect.Value{}, err
+	}
+	if holder["value"] == nil {
+		return reflect.Zero(v.Type()), nil
+	}
+	return reflect.ValueOf(holder["value"]), nil
+}
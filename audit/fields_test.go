@@ -0,0 +1,159 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/copystructure"
+)
+
+func TestParseFieldList(t *testing.T) {
+	paths, err := ParseFieldList(" request.data.password, auth.metadata ,response.headers.set-cookie,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []FieldPath{
+		{"request", "data", "password"},
+		{"auth", "metadata"},
+		{"response", "headers", "set-cookie"},
+	}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("bad: %#v", paths)
+	}
+
+	paths, err = ParseFieldList("")
+	if err != nil || len(paths) != 0 {
+		t.Fatalf("bad: %#v, %v", paths, err)
+	}
+
+	for _, raw := range []string{
+		"request..data",
+		"unknown",
+		"request.unknown",
+		"request.path.foo",
+	} {
+		if _, err := ParseFieldList(raw); err == nil {
+			t.Fatalf("expected error parsing %q", raw)
+		}
+	}
+}
+
+func TestFormatResponse_Fields(t *testing.T) {
+	salter, err := salt.NewSalt(context.Background(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	formatter := AuditFormatter{
+		AuditFormatWriter: &JSONFormatWriter{
+			SaltFunc: func(context.Context) (*salt.Salt, error) {
+				return salter, nil
+			},
+		},
+	}
+
+	dropFields, err := ParseFieldList("request.data.plaintext,response.data.nested.secret,request.remote_address")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hmacFields, err := ParseFieldList("auth.metadata.user,request.headers.x-user,response.data.nested,request.path,auth.policies")
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := FormatterConfig{
+		Raw:        true,
+		DropFields: dropFields,
+		HMACFields: hmacFields,
+	}
+
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			ClientToken: "foo",
+			Policies:    []string{"default", "transit"},
+			Metadata: map[string]string{
+				"user": "bob",
+				"team": "payments",
+			},
+		},
+		Request: &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "transit/encrypt/payments",
+			Connection: &logical.Connection{
+				RemoteAddr: "127.0.0.1",
+			},
+			Data: map[string]interface{}{
+				"plaintext": "c2VjcmV0",
+				"context":   "ctx",
+			},
+			Headers: map[string][]string{
+				"x-user": {"bob"},
+			},
+		},
+		Response: &logical.Response{
+			Data: map[string]interface{}{
+				"ciphertext": "vault:v1:abc",
+				"nested": map[string]interface{}{
+					"secret": "drop me",
+					"other":  "hash me",
+				},
+			},
+		},
+	}
+	inCopy, err := copystructure.Copy(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	ctx := namespace.RootContext(nil)
+	if err := formatter.FormatResponse(ctx, &buf, config, in); err != nil {
+		t.Fatal(err)
+	}
+
+	var entry AuditResponseEntry
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := entry.Request.Data["plaintext"]; ok {
+		t.Fatalf("expected request.data.plaintext to be dropped: %#v", entry.Request.Data)
+	}
+	if entry.Request.Data["context"] != "ctx" {
+		t.Fatalf("expected request.data.context to be kept: %#v", entry.Request.Data)
+	}
+	if entry.Request.RemoteAddr != "" {
+		t.Fatalf("expected request.remote_address to be dropped: %q", entry.Request.RemoteAddr)
+	}
+	if entry.Request.Path != salter.GetIdentifiedHMAC("transit/encrypt/payments") {
+		t.Fatalf("expected request.path to be hashed: %q", entry.Request.Path)
+	}
+	if !reflect.DeepEqual(entry.Request.Headers["x-user"], []string{salter.GetIdentifiedHMAC("bob")}) {
+		t.Fatalf("expected request.headers.x-user to be hashed: %#v", entry.Request.Headers)
+	}
+	if entry.Auth.Metadata["user"] != salter.GetIdentifiedHMAC("bob") || entry.Auth.Metadata["team"] != "payments" {
+		t.Fatalf("expected only auth.metadata.user to be hashed: %#v", entry.Auth.Metadata)
+	}
+	if !reflect.DeepEqual(entry.Auth.Policies, []string{salter.GetIdentifiedHMAC("default"), salter.GetIdentifiedHMAC("transit")}) {
+		t.Fatalf("expected auth.policies to be hashed: %#v", entry.Auth.Policies)
+	}
+	if entry.Response.Data["ciphertext"] != "vault:v1:abc" {
+		t.Fatalf("expected response.data.ciphertext to be kept: %#v", entry.Response.Data)
+	}
+	expectedNested := map[string]interface{}{
+		"other": salter.GetIdentifiedHMAC("hash me"),
+	}
+	if !reflect.DeepEqual(entry.Response.Data["nested"], expectedNested) {
+		t.Fatalf("bad response.data.nested: %#v", entry.Response.Data["nested"])
+	}
+
+	// The input must be left untouched, as it is passed on to the other audit
+	// devices.
+	if !reflect.DeepEqual(in, inCopy) {
+		t.Fatalf("input was modified: %#v", in)
+	}
+}
@@ -0,0 +1,420 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Filter is a boolean expression, configured through the "filter" option of
+// an audit device, selecting which entries are sent to the device.
+//
+// Expressions compare a selector against a value, for example:
+//
+//	mount_type == "transit" and operation != "read"
+//	namespace matches "^team-a/" or error != ""
+//	not (path matches "^sys/(health|seal-status)$")
+//
+// The supported operators are ==, !=, matches and not matches (regular
+// expressions), and contains (substring, or element for list selectors).
+// Comparisons can be combined with and, or, not and parentheses. Values are
+// double quoted strings; simple values such as update may be left unquoted.
+//
+// A filter is evaluated both when a request is logged and when its response
+// is logged, so that selectors such as error also see errors returned while
+// handling the request. A request and its response are always logged
+// together: when only the response matches, the request is logged along with
+// it.
+type Filter struct {
+	expr string
+	root filterNode
+}
+
+// filterSelectors maps the names usable in a filter expression to the
+// functions extracting their value from an audit entry.
+var filterSelectors = map[string]func(*filterInput) interface{}{
+	"operation": func(in *filterInput) interface{} {
+		return string(in.Request.Operation)
+	},
+	"path": func(in *filterInput) interface{} {
+		return in.Request.Path
+	},
+	"mount_point": func(in *filterInput) interface{} {
+		return in.Request.MountPoint
+	},
+	"mount_type": func(in *filterInput) interface{} {
+		return in.Request.MountType
+	},
+	"mount_accessor": func(in *filterInput) interface{} {
+		return in.Request.MountAccessor
+	},
+	"namespace": func(in *filterInput) interface{} {
+		return in.namespace
+	},
+	"auth_method": func(in *filterInput) interface{} {
+		// Only requests routed to an auth mount, such as logins, carry the
+		// type of the auth method they are for.
+		if strings.HasPrefix(in.Request.MountPoint, "auth/") {
+			return in.Request.MountType
+		}
+		return ""
+	},
+	"display_name": func(in *filterInput) interface{} {
+		if in.Auth == nil {
+			return ""
+		}
+		return in.Auth.DisplayName
+	},
+	"entity_id": func(in *filterInput) interface{} {
+		if in.Auth == nil {
+			return ""
+		}
+		return in.Auth.EntityID
+	},
+	"policies": func(in *filterInput) interface{} {
+		if in.Auth == nil {
+			return []string(nil)
+		}
+		return in.Auth.Policies
+	},
+	"remote_address": func(in *filterInput) interface{} {
+		return getRemoteAddr(in.Request)
+	},
+	"error": func(in *filterInput) interface{} {
+		if in.OuterErr != nil {
+			return in.OuterErr.Error()
+		}
+		if in.Response != nil && in.Response.IsError() {
+			return in.Response.Error().Error()
+		}
+		return ""
+	},
+}
+
+// NewFilter parses the given filter expression.
+func NewFilter(expr string) (*Filter, error) {
+	p := &filterParser{}
+	if err := p.lex(expr); err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf("empty filter expression")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if !p.done() {
+		return nil, fmt.Errorf("unexpected %q at position %d", p.peek().text, p.peek().pos)
+	}
+
+	return &Filter{
+		expr: expr,
+		root: root,
+	}, nil
+}
+
+// String returns the expression the filter was created from.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Evaluate reports whether the given entry matches the filter.
+func (f *Filter) Evaluate(ctx context.Context, in *logical.LogInput) (bool, error) {
+	if in == nil || in.Request == nil {
+		return false, fmt.Errorf("cannot filter a nil request")
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return f.root.eval(&filterInput{
+		LogInput:  in,
+		namespace: ns.Path,
+	}), nil
+}
+
+type filterInput struct {
+	*logical.LogInput
+	namespace string
+}
+
+type filterNode interface {
+	eval(*filterInput) bool
+}
+
+type filterAnd struct{ left, right filterNode }
+
+func (n *filterAnd) eval(in *filterInput) bool { return n.left.eval(in) && n.right.eval(in) }
+
+type filterOr struct{ left, right filterNode }
+
+func (n *filterOr) eval(in *filterInput) bool { return n.left.eval(in) || n.right.eval(in) }
+
+type filterNot struct{ node filterNode }
+
+func (n *filterNot) eval(in *filterInput) bool { return !n.node.eval(in) }
+
+type filterMatch struct {
+	selector func(*filterInput) interface{}
+	operator string
+	value    string
+	re       *regexp.Regexp
+}
+
+func (n *filterMatch) eval(in *filterInput) bool {
+	switch actual := n.selector(in).(type) {
+	case []string:
+		switch n.operator {
+		case "contains":
+			return strutil.StrListContains(actual, n.value)
+		case "==":
+			return len(actual) == 1 && actual[0] == n.value
+		case "!=":
+			return !(len(actual) == 1 && actual[0] == n.value)
+		case "matches":
+			return n.anyMatch(actual)
+		case "not matches":
+			return !n.anyMatch(actual)
+		}
+	case string:
+		switch n.operator {
+		case "contains":
+			return strings.Contains(actual, n.value)
+		case "==":
+			return actual == n.value
+		case "!=":
+			return actual != n.value
+		case "matches":
+			return n.re.MatchString(actual)
+		case "not matches":
+			return !n.re.MatchString(actual)
+		}
+	}
+	return false
+}
+
+func (n *filterMatch) anyMatch(values []string) bool {
+	for _, v := range values {
+		if n.re.MatchString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	filterTokenWord = iota
+	filterTokenString
+	filterTokenOperator
+	filterTokenLParen
+	filterTokenRParen
+)
+
+type filterToken struct {
+	kind int
+	text string
+	pos  int
+}
+
+type filterParser struct {
+	tokens []filterToken
+	next   int
+}
+
+func (p *filterParser) lex(expr string) error {
+	runes := []rune(expr)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenRParen, text: ")", pos: i})
+			i++
+		case r == '=' || r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return fmt.Errorf("unexpected %q at position %d", string(r), i)
+			}
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenOperator, text: string(runes[i : i+2]), pos: i})
+			i += 2
+		case r == '"':
+			end := i + 1
+			for ; end < len(runes) && runes[end] != '"'; end++ {
+				if runes[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(runes) {
+				return fmt.Errorf("unterminated string at position %d", i)
+			}
+			value, err := strconv.Unquote(string(runes[i : end+1]))
+			if err != nil {
+				return fmt.Errorf("invalid string at position %d: %w", i, err)
+			}
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenString, text: value, pos: i})
+			i = end + 1
+		case isFilterWordRune(r):
+			end := i
+			for end < len(runes) && isFilterWordRune(runes[end]) {
+				end++
+			}
+			p.tokens = append(p.tokens, filterToken{kind: filterTokenWord, text: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			return fmt.Errorf("unexpected %q at position %d", string(r), i)
+		}
+	}
+	return nil
+}
+
+func isFilterWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_-./:*", r)
+}
+
+func (p *filterParser) done() bool {
+	return p.next >= len(p.tokens)
+}
+
+func (p *filterParser) peek() filterToken {
+	if p.done() {
+		return filterToken{}
+	}
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) peekKeyword(keyword string) bool {
+	tok := p.peek()
+	return !p.done() && tok.kind == filterTokenWord && tok.text == keyword
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("or") {
+		p.next++
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterOr{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peekKeyword("and") {
+		p.next++
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &filterAnd{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseNot() (filterNode, error) {
+	if p.peekKeyword("not") {
+		p.next++
+		node, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &filterNot{node: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	if p.done() {
+		return nil, fmt.Errorf("unexpected end of filter expression")
+	}
+
+	tok := p.tokens[p.next]
+	p.next++
+	switch tok.kind {
+	case filterTokenLParen:
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.done() || p.peek().kind != filterTokenRParen {
+			return nil, fmt.Errorf("missing closing parenthesis for position %d", tok.pos)
+		}
+		p.next++
+		return node, nil
+	case filterTokenWord:
+		return p.parseMatch(tok)
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+	}
+}
+
+func (p *filterParser) parseMatch(selectorTok filterToken) (filterNode, error) {
+	selector, ok := filterSelectors[selectorTok.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown selector %q at position %d", selectorTok.text, selectorTok.pos)
+	}
+
+	if p.done() {
+		return nil, fmt.Errorf("missing operator after %q", selectorTok.text)
+	}
+	opTok := p.tokens[p.next]
+	p.next++
+
+	var operator string
+	switch {
+	case opTok.kind == filterTokenOperator:
+		operator = opTok.text
+	case opTok.kind == filterTokenWord && (opTok.text == "matches" || opTok.text == "contains"):
+		operator = opTok.text
+	case opTok.kind == filterTokenWord && opTok.text == "not" && p.peekKeyword("matches"):
+		p.next++
+		operator = "not matches"
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d, expected an operator", opTok.text, opTok.pos)
+	}
+
+	if p.done() {
+		return nil, fmt.Errorf("missing value after %q", operator)
+	}
+	valueTok := p.tokens[p.next]
+	p.next++
+	if valueTok.kind != filterTokenString && valueTok.kind != filterTokenWord {
+		return nil, fmt.Errorf("unexpected %q at position %d, expected a value", valueTok.text, valueTok.pos)
+	}
+
+	node := &filterMatch{
+		selector: selector,
+		operator: operator,
+		value:    valueTok.text,
+	}
+	if operator == "matches" || operator == "not matches" {
+		re, err := regexp.Compile(valueTok.text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression at position %d: %w", valueTok.pos, err)
+		}
+		node.re = re
+	}
+	return node, nil
+}
@@ -0,0 +1,112 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestNewFilter_Invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"mount_type",
+		"mount_type ==",
+		"unknown == foo",
+		`mount_type = "kv"`,
+		`mount_type == "kv" and`,
+		`(mount_type == "kv"`,
+		`mount_type == "kv")`,
+		`path matches "("`,
+		`mount_type == "kv`,
+		`mount_type === kv`,
+	} {
+		if _, err := NewFilter(expr); err == nil {
+			t.Fatalf("expected error parsing %q", expr)
+		}
+	}
+}
+
+func TestFilter_Evaluate(t *testing.T) {
+	ctx := namespace.ContextWithNamespace(context.Background(), &namespace.Namespace{
+		ID:   "abc12",
+		Path: "team-a/",
+	})
+
+	in := &logical.LogInput{
+		Auth: &logical.Auth{
+			DisplayName: "userpass-bob",
+			Policies:    []string{"default", "transit-user"},
+		},
+		Request: &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "transit/encrypt/payments",
+			MountPoint: "transit/",
+			MountType:  "transit",
+		},
+	}
+	login := &logical.LogInput{
+		Auth: &logical.Auth{},
+		Request: &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "auth/userpass/login/bob",
+			MountPoint: "auth/userpass/",
+			MountType:  "userpass",
+		},
+		Response: logical.ErrorResponse("invalid username or password"),
+	}
+
+	cases := []struct {
+		expr     string
+		in       *logical.LogInput
+		expected bool
+	}{
+		{`mount_type == "transit"`, in, true},
+		{`mount_type != transit`, in, false},
+		{`operation == update and mount_point == "transit/"`, in, true},
+		{`operation == read or path matches "^transit/encrypt/"`, in, true},
+		{`not (mount_type == "transit")`, in, false},
+		{`not mount_type == "transit" or namespace == "team-a/"`, in, true},
+		{`namespace matches "^team-b/"`, in, false},
+		{`path not matches "^transit/"`, in, false},
+		{`policies contains "transit-user"`, in, true},
+		{`policies contains "admin"`, in, false},
+		{`display_name contains bob`, in, true},
+		{`auth_method == userpass`, in, false},
+		{`auth_method == userpass`, login, true},
+		{`error != ""`, in, false},
+		{`error != ""`, login, true},
+		{`error == "invalid username or password"`, login, true},
+		{`mount_type == "transit" and error == "" or auth_method == "userpass"`, login, true},
+	}
+
+	for _, tc := range cases {
+		filter, err := NewFilter(tc.expr)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", tc.expr, err)
+		}
+		actual, err := filter.Evaluate(ctx, tc.in)
+		if err != nil {
+			t.Fatalf("failed to evaluate %q: %v", tc.expr, err)
+		}
+		if actual != tc.expected {
+			t.Fatalf("expected %q to evaluate to %t", tc.expr, tc.expected)
+		}
+	}
+
+	// An error returned to the client takes precedence over the response.
+	filter, err := NewFilter(`error == "permission denied"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login.OuterErr = errors.New("permission denied")
+	match, err := filter.Evaluate(ctx, login)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !match {
+		t.Fatal("expected outer error to match")
+	}
+}
@@ -157,7 +157,12 @@ func (f *AuditFormatter) FormatRequest(ctx context.Context, w io.Writer, config
 		reqEntry.Time = time.Now().UTC().Format(time.RFC3339Nano)
 	}
 
-	return f.AuditFormatWriter.WriteRequest(w, reqEntry)
+	entry, err := applyFieldConfig(salt, config, reqEntry)
+	if err != nil {
+		return err
+	}
+
+	return f.AuditFormatWriter.WriteRequest(w, entry.(*AuditRequestEntry))
 }
 
 func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config FormatterConfig, in *logical.LogInput) error {
@@ -348,7 +353,12 @@ func (f *AuditFormatter) FormatResponse(ctx context.Context, w io.Writer, config
 		respEntry.Time = time.Now().UTC().Format(time.RFC3339Nano)
 	}
 
-	return f.AuditFormatWriter.WriteResponse(w, respEntry)
+	entry, err := applyFieldConfig(salt, config, respEntry)
+	if err != nil {
+		return err
+	}
+
+	return f.AuditFormatWriter.WriteResponse(w, entry.(*AuditResponseEntry))
 }
 
 // AuditRequestEntry is the structure of a request audit log entry in Audit.
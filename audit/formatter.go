@@ -21,6 +21,12 @@ type FormatterConfig struct {
 	Raw          bool
 	HMACAccessor bool
 
+	// DropFields and HMACFields are paths, such as "request.data.password"
+	// or "auth.metadata", of fields of the audit entry which are removed or
+	// HMAC'd before the entry is written. See ParseFieldList.
+	DropFields []FieldPath
+	HMACFields []FieldPath
+
 	// This should only ever be used in a testing context
 	OmitTime bool
 }
@@ -71,6 +71,16 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	// Check for fields to drop from or HMAC in the entries
+	dropFields, err := audit.ParseFieldList(conf.Config["drop_fields"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid drop_fields: %w", err)
+	}
+	hmacFields, err := audit.ParseFieldList(conf.Config["hmac_fields"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hmac_fields: %w", err)
+	}
+
 	// Check if mode is provided
 	mode := os.FileMode(0o600)
 	if modeRaw, ok := conf.Config["mode"]; ok {
@@ -104,6 +114,8 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		formatConfig: audit.FormatterConfig{
 			Raw:          logRaw,
 			HMACAccessor: hmacAccessor,
+			DropFields:   dropFields,
+			HMACFields:   hmacFields,
 		},
 	}
 
@@ -0,0 +1,628 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// FailurePolicyFailClosed makes logging wait for each entry to be
+	// delivered (or spooled), failing the audited request otherwise.
+	FailurePolicyFailClosed = "fail_closed"
+
+	// FailurePolicyDrop queues entries without waiting for their delivery,
+	// dropping them when they cannot be delivered or spooled.
+	FailurePolicyDrop = "drop"
+)
+
+var errClosed = errors.New("audit device is closed")
+
+func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, error) {
+	if conf.SaltConfig == nil {
+		return nil, fmt.Errorf("nil salt config")
+	}
+	if conf.SaltView == nil {
+		return nil, fmt.Errorf("nil salt view")
+	}
+
+	address, ok := conf.Config["url"]
+	if !ok {
+		return nil, fmt.Errorf("url is required")
+	}
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid url: %w", err)
+	}
+	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
+		return nil, fmt.Errorf("url must use the http or https scheme")
+	}
+
+	format, ok := conf.Config["format"]
+	if !ok {
+		format = "json"
+	}
+	switch format {
+	case "json", "jsonx":
+	default:
+		return nil, fmt.Errorf("unknown format type %q", format)
+	}
+
+	// Check if hashing of accessor is disabled
+	hmacAccessor := true
+	if hmacAccessorRaw, ok := conf.Config["hmac_accessor"]; ok {
+		value, err := strconv.ParseBool(hmacAccessorRaw)
+		if err != nil {
+			return nil, err
+		}
+		hmacAccessor = value
+	}
+
+	// Check if raw logging is enabled
+	logRaw := false
+	if raw, ok := conf.Config["log_raw"]; ok {
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, err
+		}
+		logRaw = b
+	}
+
+	// Check for fields to drop from or HMAC in the entries
+	dropFields, err := audit.ParseFieldList(conf.Config["drop_fields"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid drop_fields: %w", err)
+	}
+	hmacFields, err := audit.ParseFieldList(conf.Config["hmac_fields"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hmac_fields: %w", err)
+	}
+
+	failurePolicy, ok := conf.Config["failure_policy"]
+	if !ok {
+		failurePolicy = FailurePolicyFailClosed
+	}
+	switch failurePolicy {
+	case FailurePolicyFailClosed, FailurePolicyDrop:
+	default:
+		return nil, fmt.Errorf("unknown failure_policy %q", failurePolicy)
+	}
+
+	batchSize, err := parseInt(conf.Config, "batch_size", 100)
+	if err != nil {
+		return nil, err
+	}
+	queueSize, err := parseInt(conf.Config, "queue_size", 10000)
+	if err != nil {
+		return nil, err
+	}
+	maxRetries, err := parseInt(conf.Config, "max_retries", 3)
+	if err != nil {
+		return nil, err
+	}
+	if batchSize < 1 || queueSize < 1 || maxRetries < 0 {
+		return nil, fmt.Errorf("batch_size and queue_size must be positive, and max_retries not negative")
+	}
+
+	batchInterval, err := parseDuration(conf.Config, "batch_interval", "1s")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := parseDuration(conf.Config, "timeout", "5s")
+	if err != nil {
+		return nil, err
+	}
+	retryMinBackoff, err := parseDuration(conf.Config, "retry_min_backoff", "500ms")
+	if err != nil {
+		return nil, err
+	}
+	retryMaxBackoff, err := parseDuration(conf.Config, "retry_max_backoff", "10s")
+	if err != nil {
+		return nil, err
+	}
+	if batchInterval <= 0 || timeout <= 0 || retryMinBackoff <= 0 || retryMaxBackoff < retryMinBackoff {
+		return nil, fmt.Errorf("batch_interval, timeout and retry backoffs must be positive, with retry_max_backoff at least retry_min_backoff")
+	}
+
+	tlsConfig, err := parseTLSConfig(conf.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Backend{
+		saltConfig: conf.SaltConfig,
+		saltView:   conf.SaltView,
+		formatConfig: audit.FormatterConfig{
+			Raw:          logRaw,
+			HMACAccessor: hmacAccessor,
+			DropFields:   dropFields,
+			HMACFields:   hmacFields,
+		},
+
+		url:             parsedURL.String(),
+		failClosed:      failurePolicy == FailurePolicyFailClosed,
+		batchSize:       batchSize,
+		batchInterval:   batchInterval,
+		maxRetries:      maxRetries,
+		retryMinBackoff: retryMinBackoff,
+		retryMaxBackoff: retryMaxBackoff,
+		client: &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: tlsConfig,
+			},
+		},
+
+		entries: make(chan *entry, queueSize),
+		stopCh:  make(chan struct{}),
+		doneCh:  make(chan struct{}),
+	}
+
+	if spoolPath, ok := conf.Config["spool_path"]; ok && spoolPath != "" {
+		spoolMaxSize, err := parseInt(conf.Config, "spool_max_size", 100*1024*1024)
+		if err != nil {
+			return nil, err
+		}
+		if b.spool, err = newSpool(spoolPath, int64(spoolMaxSize)); err != nil {
+			return nil, fmt.Errorf("failed to set up spool: %w", err)
+		}
+	}
+
+	switch format {
+	case "json":
+		b.contentType = "application/x-ndjson"
+		b.formatter.AuditFormatWriter = &audit.JSONFormatWriter{
+			Prefix:   conf.Config["prefix"],
+			SaltFunc: b.Salt,
+		}
+	case "jsonx":
+		b.contentType = "application/xml"
+		b.formatter.AuditFormatWriter = &audit.JSONxFormatWriter{
+			Prefix:   conf.Config["prefix"],
+			SaltFunc: b.Salt,
+		}
+	}
+
+	return b, nil
+}
+
+func parseInt(config map[string]string, key string, def int) (int, error) {
+	raw, ok := config[key]
+	if !ok {
+		return def, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func parseDuration(config map[string]string, key string, def string) (time.Duration, error) {
+	raw, ok := config[key]
+	if !ok {
+		raw = def
+	}
+	value, err := parseutil.ParseDurationSecond(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func parseTLSConfig(config map[string]string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: config["tls_server_name"],
+	}
+
+	if skipRaw, ok := config["tls_skip_verify"]; ok {
+		skip, err := strconv.ParseBool(skipRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tls_skip_verify: %w", err)
+		}
+		tlsConfig.InsecureSkipVerify = skip
+	}
+
+	if caPath, ok := config["tls_ca_cert"]; ok {
+		caPEM, err := os.ReadFile(caPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls_ca_cert: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("no certificates found in tls_ca_cert")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	certPath, haveCert := config["tls_client_cert"]
+	keyPath, haveKey := config["tls_client_key"]
+	switch {
+	case haveCert && haveKey:
+		cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	case haveCert || haveKey:
+		return nil, fmt.Errorf("tls_client_cert and tls_client_key must be provided together")
+	}
+
+	return tlsConfig, nil
+}
+
+// Backend is the audit backend for the HTTP audit transport. Entries are
+// queued and POSTed in batches to the configured URL by a background
+// goroutine, which retries failed deliveries and spools them to disk if
+// they keep failing.
+type Backend struct {
+	formatter    audit.AuditFormatter
+	formatConfig audit.FormatterConfig
+
+	url             string
+	contentType     string
+	client          *http.Client
+	failClosed      bool
+	batchSize       int
+	batchInterval   time.Duration
+	maxRetries      int
+	retryMinBackoff time.Duration
+	retryMaxBackoff time.Duration
+	spool           *spool
+
+	// The delivery goroutine is only started on first use, so that backends
+	// created solely to log a test message don't leave it running.
+	startOnce sync.Once
+	closeOnce sync.Once
+	entries   chan *entry
+	stopCh    chan struct{}
+	doneCh    chan struct{}
+
+	saltMutex  sync.RWMutex
+	salt       *salt.Salt
+	saltConfig *salt.Config
+	saltView   logical.Storage
+}
+
+// entry is a single formatted audit entry awaiting delivery. result is only
+// set when the caller waits for delivery, and receives its outcome.
+type entry struct {
+	data   []byte
+	result chan error
+}
+
+var (
+	_ audit.Backend = (*Backend)(nil)
+	_ audit.Closer  = (*Backend)(nil)
+)
+
+func (b *Backend) GetHash(ctx context.Context, data string) (string, error) {
+	salt, err := b.Salt(ctx)
+	if err != nil {
+		return "", err
+	}
+	return audit.HashString(salt, data), nil
+}
+
+func (b *Backend) LogRequest(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatRequest(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.enqueue(ctx, buf.Bytes())
+}
+
+func (b *Backend) LogResponse(ctx context.Context, in *logical.LogInput) error {
+	var buf bytes.Buffer
+	if err := b.formatter.FormatResponse(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.enqueue(ctx, buf.Bytes())
+}
+
+// LogTestMessage delivers the test message synchronously, bypassing the
+// queue, so that an unreachable endpoint is reported when enabling the
+// device.
+func (b *Backend) LogTestMessage(ctx context.Context, in *logical.LogInput, config map[string]string) error {
+	var buf bytes.Buffer
+	temporaryFormatter := audit.NewTemporaryFormatter(config["format"], config["prefix"])
+	if err := temporaryFormatter.FormatRequest(ctx, &buf, b.formatConfig, in); err != nil {
+		return err
+	}
+
+	return b.send(ctx, buf.Bytes())
+}
+
+func (b *Backend) enqueue(ctx context.Context, data []byte) error {
+	b.startOnce.Do(func() {
+		go b.run()
+	})
+
+	select {
+	case <-b.stopCh:
+		return errClosed
+	default:
+	}
+
+	e := &entry{data: data}
+	if !b.failClosed {
+		select {
+		case b.entries <- e:
+		case <-b.stopCh:
+			return errClosed
+		default:
+			metrics.IncrCounter([]string{"audit", "http", "dropped"}, 1)
+		}
+		return nil
+	}
+
+	e.result = make(chan error, 1)
+	select {
+	case b.entries <- e:
+	case <-b.stopCh:
+		return errClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-e.result:
+		return err
+	case <-b.doneCh:
+		// The entry may have been delivered as part of the final flush.
+		select {
+		case err := <-e.result:
+			return err
+		default:
+			return errClosed
+		}
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run collects queued entries into batches, delivering a batch once it is
+// full or batch_interval has elapsed. Entries whose callers wait for their
+// delivery, under the fail_closed policy, are delivered right away instead,
+// batched only with the entries queued in the meantime, as they hold up the
+// audited requests. Spooled batches are retried whenever the endpoint is
+// reachable again.
+func (b *Backend) run() {
+	defer close(b.doneCh)
+
+	ticker := time.NewTicker(b.batchInterval)
+	defer ticker.Stop()
+
+	batch := make([]*entry, 0, b.batchSize)
+	for {
+		select {
+		case e := <-b.entries:
+			batch = append(batch, e)
+			if e.result == nil {
+				if len(batch) < b.batchSize {
+					continue
+				}
+				break
+			}
+		fill:
+			for len(batch) < b.batchSize {
+				select {
+				case e := <-b.entries:
+					batch = append(batch, e)
+				default:
+					break fill
+				}
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				b.drainSpool()
+				continue
+			}
+		case <-b.stopCh:
+			// Flush whatever is left, without retrying as we are shutting
+			// down; failed batches still end up in the spool.
+		drain:
+			for {
+				select {
+				case e := <-b.entries:
+					batch = append(batch, e)
+				default:
+					break drain
+				}
+			}
+			if len(batch) > 0 {
+				b.flush(batch, false)
+			}
+			return
+		}
+
+		b.flush(batch, true)
+		batch = make([]*entry, 0, b.batchSize)
+	}
+}
+
+func (b *Backend) flush(batch []*entry, retry bool) {
+	var body bytes.Buffer
+	for _, e := range batch {
+		body.Write(e.data)
+	}
+
+	err := b.deliver(body.Bytes(), retry)
+	if err == nil {
+		b.drainSpool()
+	} else {
+		metrics.IncrCounter([]string{"audit", "http", "failed"}, float32(len(batch)))
+		if b.spool != nil && isRetryable(err) {
+			if spoolErr := b.spool.write(body.Bytes()); spoolErr == nil {
+				metrics.IncrCounter([]string{"audit", "http", "spooled"}, float32(len(batch)))
+				err = nil
+			} else {
+				err = fmt.Errorf("%w; additionally failed to spool: %v", err, spoolErr)
+			}
+		}
+		if err != nil && !b.failClosed {
+			metrics.IncrCounter([]string{"audit", "http", "dropped"}, float32(len(batch)))
+		}
+	}
+
+	for _, e := range batch {
+		if e.result != nil {
+			e.result <- err
+		}
+	}
+}
+
+// deliver sends the body, retrying with exponential backoff on retryable
+// failures.
+func (b *Backend) deliver(body []byte, retry bool) error {
+	backoff := b.retryMinBackoff
+	for attempt := 0; ; attempt++ {
+		err := b.send(context.Background(), body)
+		if err == nil || !isRetryable(err) || !retry || attempt >= b.maxRetries {
+			return err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-b.stopCh:
+			return err
+		}
+
+		backoff *= 2
+		if backoff > b.retryMaxBackoff {
+			backoff = b.retryMaxBackoff
+		}
+	}
+}
+
+// drainSpool re-sends spooled batches, oldest first, until the spool is
+// empty or a delivery fails.
+func (b *Backend) drainSpool() {
+	if b.spool == nil {
+		return
+	}
+
+	for {
+		name, body, err := b.spool.oldest()
+		if err != nil || name == "" {
+			return
+		}
+		if err := b.send(context.Background(), body); err != nil {
+			return
+		}
+		if err := b.spool.remove(name); err != nil {
+			return
+		}
+	}
+}
+
+// deliveryError is returned by send; retryable is false for responses the
+// endpoint will keep rejecting however often we retry them.
+type deliveryError struct {
+	err       error
+	retryable bool
+}
+
+func (e *deliveryError) Error() string {
+	return e.err.Error()
+}
+
+func (e *deliveryError) Unwrap() error {
+	return e.err
+}
+
+func isRetryable(err error) bool {
+	var dErr *deliveryError
+	return errors.As(err, &dErr) && dErr.retryable
+}
+
+func (b *Backend) send(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", b.contentType)
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return &deliveryError{err: err, retryable: true}
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return &deliveryError{err: fmt.Errorf("audit endpoint returned %s", resp.Status), retryable: true}
+	default:
+		return &deliveryError{err: fmt.Errorf("audit endpoint returned %s", resp.Status), retryable: false}
+	}
+}
+
+func (b *Backend) Reload(_ context.Context) error {
+	return nil
+}
+
+// Close stops the delivery goroutine after flushing the queued entries.
+func (b *Backend) Close() error {
+	b.closeOnce.Do(func() {
+		close(b.stopCh)
+		started := true
+		b.startOnce.Do(func() {
+			started = false
+		})
+		if started {
+			<-b.doneCh
+		} else {
+			close(b.doneCh)
+		}
+	})
+	return nil
+}
+
+func (b *Backend) Salt(ctx context.Context) (*salt.Salt, error) {
+	b.saltMutex.RLock()
+	if b.salt != nil {
+		defer b.saltMutex.RUnlock()
+		return b.salt, nil
+	}
+	b.saltMutex.RUnlock()
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	if b.salt != nil {
+		return b.salt, nil
+	}
+	salt, err := salt.NewSalt(ctx, b.saltView, b.saltConfig)
+	if err != nil {
+		return nil, err
+	}
+	b.salt = salt
+	return salt, nil
+}
+
+func (b *Backend) Invalidate(_ context.Context) {
+	b.saltMutex.Lock()
+	defer b.saltMutex.Unlock()
+	b.salt = nil
+}
@@ -0,0 +1,423 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/audit"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/helper/salt"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// testCollector is an audit endpoint which records the batches it receives,
+// failing requests while its status is set to an error.
+type testCollector struct {
+	sync.Mutex
+	status   int
+	attempts int
+	batches  []string
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	c.Lock()
+	defer c.Unlock()
+	c.attempts++
+	if c.status != 0 && c.status != http.StatusOK {
+		w.WriteHeader(c.status)
+		return
+	}
+	c.batches = append(c.batches, string(body))
+}
+
+func (c *testCollector) setStatus(status int) {
+	c.Lock()
+	defer c.Unlock()
+	c.status = status
+}
+
+func (c *testCollector) received() (int, []string) {
+	c.Lock()
+	defer c.Unlock()
+	return c.attempts, append([]string(nil), c.batches...)
+}
+
+func testBackend(t *testing.T, config map[string]string) *Backend {
+	t.Helper()
+	be, err := Factory(context.Background(), &audit.BackendConfig{
+		SaltConfig: &salt.Config{},
+		SaltView:   &logical.InmemStorage{},
+		Config:     config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := be.(*Backend)
+	t.Cleanup(func() { b.Close() })
+	return b
+}
+
+func testLogInput(path string) *logical.LogInput {
+	return &logical.LogInput{
+		Request: &logical.Request{
+			ID:        "test-" + path,
+			Operation: logical.UpdateOperation,
+			Path:      path,
+		},
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestAuditHTTP_Factory(t *testing.T) {
+	for name, config := range map[string]map[string]string{
+		"missing url":        {},
+		"bad scheme":         {"url": "ftp://example.com"},
+		"bad format":         {"url": "http://example.com", "format": "xml"},
+		"bad failure_policy": {"url": "http://example.com", "failure_policy": "maybe"},
+		"bad batch_size":     {"url": "http://example.com", "batch_size": "0"},
+		"bad backoff":        {"url": "http://example.com", "retry_min_backoff": "10s", "retry_max_backoff": "1s"},
+		"half client cert":   {"url": "http://example.com", "tls_client_cert": "cert.pem"},
+	} {
+		_, err := Factory(context.Background(), &audit.BackendConfig{
+			SaltConfig: &salt.Config{},
+			SaltView:   &logical.InmemStorage{},
+			Config:     config,
+		})
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+	}
+}
+
+func TestAuditHTTP_Batching(t *testing.T) {
+	collector := &testCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	b := testBackend(t, map[string]string{
+		"url":            server.URL,
+		"failure_policy": "drop",
+		"batch_size":     "3",
+		"batch_interval": "1h",
+	})
+
+	ctx := namespace.RootContext(nil)
+	for _, path := range []string{"secret/a", "secret/b", "secret/c"} {
+		if err := b.LogRequest(ctx, testLogInput(path)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(t, func() bool {
+		_, batches := collector.received()
+		return len(batches) == 1
+	})
+
+	_, batches := collector.received()
+	lines := strings.Split(strings.TrimSpace(batches[0]), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 entries in the batch, got %d: %q", len(lines), batches[0])
+	}
+	for index, line := range lines {
+		var entry audit.AuditRequestEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"secret/a", "secret/b", "secret/c"}[index]; entry.Request.Path != expected {
+			t.Fatalf("expected entry for %q, got %q", expected, entry.Request.Path)
+		}
+	}
+
+	// Closing flushes partial batches.
+	if err := b.LogRequest(ctx, testLogInput("secret/d")); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	if _, batches := collector.received(); len(batches) != 2 {
+		t.Fatalf("expected the partial batch to be flushed on close, got %d batches", len(batches))
+	}
+
+	if err := b.LogRequest(ctx, testLogInput("secret/e")); err != errClosed {
+		t.Fatalf("expected logging after close to fail, got %v", err)
+	}
+}
+
+func TestAuditHTTP_FailClosedDeliversImmediately(t *testing.T) {
+	collector := &testCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	// Callers waiting for delivery don't wait for the batch to fill up or
+	// for batch_interval to elapse.
+	b := testBackend(t, map[string]string{
+		"url":            server.URL,
+		"batch_interval": "1h",
+	})
+
+	ctx := namespace.RootContext(nil)
+	for index, path := range []string{"secret/a", "secret/b"} {
+		if err := b.LogRequest(ctx, testLogInput(path)); err != nil {
+			t.Fatal(err)
+		}
+		if _, batches := collector.received(); len(batches) != index+1 {
+			t.Fatalf("expected %d delivered batches, got %d", index+1, len(batches))
+		}
+	}
+}
+
+func TestAuditHTTP_Retry(t *testing.T) {
+	collector := &testCollector{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	b := testBackend(t, map[string]string{
+		"url":               server.URL,
+		"batch_size":        "1",
+		"max_retries":       "5",
+		"retry_min_backoff": "20ms",
+		"retry_max_backoff": "40ms",
+	})
+
+	go func() {
+		waitFor(t, func() bool {
+			attempts, _ := collector.received()
+			return attempts >= 2
+		})
+		collector.setStatus(http.StatusOK)
+	}()
+
+	if err := b.LogRequest(namespace.RootContext(nil), testLogInput("secret/a")); err != nil {
+		t.Fatal(err)
+	}
+	attempts, batches := collector.received()
+	if attempts < 3 || len(batches) != 1 {
+		t.Fatalf("expected delivery after retries, got %d attempts and %d batches", attempts, len(batches))
+	}
+}
+
+func TestAuditHTTP_FailurePolicy(t *testing.T) {
+	collector := &testCollector{status: http.StatusInternalServerError}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	ctx := namespace.RootContext(nil)
+	config := map[string]string{
+		"url":               server.URL,
+		"batch_size":        "1",
+		"max_retries":       "1",
+		"retry_min_backoff": "10ms",
+	}
+
+	failClosed := testBackend(t, config)
+	if err := failClosed.LogRequest(ctx, testLogInput("secret/a")); err == nil {
+		t.Fatal("expected fail_closed device to report the delivery failure")
+	}
+	if attempts, _ := collector.received(); attempts != 2 {
+		t.Fatalf("expected 2 delivery attempts, got %d", attempts)
+	}
+
+	// Client errors are not retried.
+	collector.setStatus(http.StatusBadRequest)
+	if err := failClosed.LogRequest(ctx, testLogInput("secret/b")); err == nil {
+		t.Fatal("expected fail_closed device to report the delivery failure")
+	}
+	if attempts, _ := collector.received(); attempts != 3 {
+		t.Fatalf("expected 3 delivery attempts, got %d", attempts)
+	}
+
+	config["failure_policy"] = "drop"
+	drop := testBackend(t, config)
+	if err := drop.LogRequest(ctx, testLogInput("secret/c")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool {
+		attempts, _ := collector.received()
+		return attempts == 4
+	})
+}
+
+func TestAuditHTTP_Spool(t *testing.T) {
+	collector := &testCollector{status: http.StatusBadGateway}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	spoolPath := filepath.Join(t.TempDir(), "spool")
+	b := testBackend(t, map[string]string{
+		"url":            server.URL,
+		"batch_size":     "1",
+		"batch_interval": "20ms",
+		"max_retries":    "0",
+		"spool_path":     spoolPath,
+		"spool_max_size": "1024",
+	})
+
+	// Undeliverable entries are spooled, which counts as success.
+	ctx := namespace.RootContext(nil)
+	if err := b.LogRequest(ctx, testLogInput("secret/a")); err != nil {
+		t.Fatal(err)
+	}
+	files, err := os.ReadDir(spoolPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected 1 spooled batch, got %d", len(files))
+	}
+
+	// Until the spool is full.
+	var spoolErr error
+	for i := 0; i < 20 && spoolErr == nil; i++ {
+		spoolErr = b.LogRequest(ctx, testLogInput("secret/b"))
+	}
+	if spoolErr == nil {
+		t.Fatal("expected the spool to fill up")
+	}
+
+	// Once the endpoint recovers, the spool is drained in order.
+	collector.setStatus(http.StatusOK)
+	waitFor(t, func() bool {
+		files, err := os.ReadDir(spoolPath)
+		return err == nil && len(files) == 0
+	})
+	_, batches := collector.received()
+	if len(batches) < 2 || !strings.Contains(batches[0], "secret/a") {
+		t.Fatalf("expected spooled batches to be delivered oldest first, got %q", batches)
+	}
+
+	// Spooled batches survive a restart of the device.
+	collector.setStatus(http.StatusBadGateway)
+	if err := b.LogRequest(ctx, testLogInput("secret/c")); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+
+	s, err := newSpool(spoolPath, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name, body, err := s.oldest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "" || !strings.Contains(string(body), "secret/c") {
+		t.Fatalf("expected the spooled batch to be found again, got %q", body)
+	}
+}
+
+func TestAuditHTTP_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "Test Client CA"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "vault"},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, caCert, clientKey.Public(), caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientKeyDER, err := x509.MarshalECPrivateKey(clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	collector := &testCollector{}
+	server := httptest.NewUnstartedServer(collector)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(caCert)
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	serverCAPath := writePEM("server-ca.pem", "CERTIFICATE", server.Certificate().Raw)
+	clientCertPath := writePEM("client.pem", "CERTIFICATE", clientDER)
+	clientKeyPath := writePEM("client-key.pem", "EC PRIVATE KEY", clientKeyDER)
+
+	ctx := namespace.RootContext(nil)
+	withoutCert := testBackend(t, map[string]string{
+		"url":         server.URL,
+		"tls_ca_cert": serverCAPath,
+	})
+	if err := withoutCert.LogTestMessage(ctx, testLogInput("sys/audit/test"), map[string]string{}); err == nil {
+		t.Fatal("expected delivery without a client certificate to fail")
+	}
+
+	withCert := testBackend(t, map[string]string{
+		"url":             server.URL,
+		"tls_ca_cert":     serverCAPath,
+		"tls_client_cert": clientCertPath,
+		"tls_client_key":  clientKeyPath,
+	})
+	if err := withCert.LogTestMessage(ctx, testLogInput("sys/audit/test"), map[string]string{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, batches := collector.received(); len(batches) != 1 || !strings.Contains(batches[0], "sys/audit/test") {
+		t.Fatalf("expected the test message to be delivered, got %q", batches)
+	}
+}
@@ -0,0 +1,140 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const spoolFileSuffix = ".batch"
+
+var errSpoolFull = errors.New("spool is full")
+
+// spool is a bounded on-disk queue of batches which could not be delivered.
+// Each batch is stored as its own file, named by a sequence number so that
+// batches are re-sent in the order they were spooled.
+type spool struct {
+	dir     string
+	maxSize int64
+
+	l    sync.Mutex
+	size int64
+	seq  uint64
+}
+
+func newSpool(dir string, maxSize int64) (*spool, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("spool_max_size must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	s := &spool{
+		dir:     dir,
+		maxSize: maxSize,
+	}
+
+	// Pick up batches spooled before a restart.
+	names, err := s.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+		s.size += info.Size()
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileSuffix), 10, 64)
+		if err == nil && seq >= s.seq {
+			s.seq = seq + 1
+		}
+	}
+
+	return s, nil
+}
+
+// list returns the names of the spooled batches, oldest first.
+func (s *spool) list() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), spoolFileSuffix) {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (s *spool) write(body []byte) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	if s.size+int64(len(body)) > s.maxSize {
+		return errSpoolFull
+	}
+
+	// Zero padding keeps the lexical order of the names equal to the
+	// order of their sequence numbers.
+	name := fmt.Sprintf("%020d%s", s.seq, spoolFileSuffix)
+	tmpPath := filepath.Join(s.dir, name+".tmp")
+	if err := os.WriteFile(tmpPath, body, 0o600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	s.seq++
+	s.size += int64(len(body))
+	return nil
+}
+
+// oldest returns the name and contents of the oldest spooled batch, or an
+// empty name when the spool is empty.
+func (s *spool) oldest() (string, []byte, error) {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	names, err := s.list()
+	if err != nil || len(names) == 0 {
+		return "", nil, err
+	}
+
+	body, err := os.ReadFile(filepath.Join(s.dir, names[0]))
+	if err != nil {
+		return "", nil, err
+	}
+	return names[0], body, nil
+}
+
+func (s *spool) remove(name string) error {
+	s.l.Lock()
+	defer s.l.Unlock()
+
+	path := filepath.Join(s.dir, name)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+
+	s.size -= info.Size()
+	return nil
+}
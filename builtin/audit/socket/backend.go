@@ -73,12 +73,24 @@ func Factory(ctx context.Context, conf *audit.BackendConfig) (audit.Backend, err
 		logRaw = b
 	}
 
+	// Check for fields to drop from or HMAC in the entries
+	dropFields, err := audit.ParseFieldList(conf.Config["drop_fields"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid drop_fields: %w", err)
+	}
+	hmacFields, err := audit.ParseFieldList(conf.Config["hmac_fields"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid hmac_fields: %w", err)
+	}
+
 	b := &Backend{
 		saltConfig: conf.SaltConfig,
 		saltView:   conf.SaltView,
 		formatConfig: audit.FormatterConfig{
 			Raw:          logRaw,
 			HMACAccessor: hmacAccessor,
+			DropFields:   dropFields,
+			HMACFields:   hmacFields,
 		},
 
 		writeDuration: writeDuration,
@@ -2,6 +2,7 @@ package approle
 
 import (
 	"context"
+	"strings"
 	"sync"
 
 	"github.com/hashicorp/vault/sdk/framework"
@@ -59,6 +60,17 @@ type backend struct {
 	// secretIDListingLock is a dedicated lock for listing SecretIDAccessors
 	// for all the SecretIDs issued against an approle
 	secretIDListingLock sync.RWMutex
+
+	// jwtKeySets caches the key sets used to validate the JWTs presented
+	// for secret IDs bound to JWT claims, indexed by role name. Entries are
+	// invalidated when the role is written.
+	jwtKeySets     map[string]*cachedJWTKeySet
+	jwtKeySetsLock sync.Mutex
+
+	// keySetCtx is used by the cached key sets to fetch remote keys, and is
+	// cancelled when the backend is cleaned up
+	keySetCtx       context.Context
+	keySetCtxCancel context.CancelFunc
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -90,7 +102,10 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 		secretIDAccessorLocks: locksutil.CreateLocks(),
 
 		tidySecretIDCASGuard: new(uint32),
+
+		jwtKeySets: make(map[string]*cachedJWTKeySet),
 	}
+	b.keySetCtx, b.keySetCtxCancel = context.WithCancel(context.Background())
 
 	// Attach the paths and secrets that are to be handled by the backend
 	b.Backend = &framework.Backend{
@@ -115,6 +130,7 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			},
 		),
 		Invalidate:     b.invalidate,
+		Clean:          b.cleanup,
 		BackendType:    logical.TypeCredential,
 		RunningVersion: ReportedVersion,
 	}
@@ -145,14 +161,20 @@ func (b *backend) Salt(ctx context.Context) (*salt.Salt, error) {
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
-	switch key {
-	case salt.DefaultLocation:
+	switch {
+	case key == salt.DefaultLocation:
 		b.saltMutex.Lock()
 		defer b.saltMutex.Unlock()
 		b.salt = nil
+	case strings.HasPrefix(key, "role/"):
+		b.invalidateJWTKeySet(strings.TrimPrefix(key, "role/"))
 	}
 }
 
+func (b *backend) cleanup(_ context.Context) {
+	b.keySetCtxCancel()
+}
+
 // periodicFunc of the backend will be invoked once a minute by the RollbackManager.
 // RoleRole backend utilizes this function to delete expired SecretID entries.
 // This could mean that the SecretID may live in the backend upto 1 min after its
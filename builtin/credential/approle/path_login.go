@@ -25,6 +25,10 @@ func pathLogin(b *backend) *framework.Path {
 				Default:     "",
 				Description: "SecretID belong to the App role",
 			},
+			"jwt": {
+				Type:        framework.TypeString,
+				Description: "JWT carrying the claims required by the 'bound_jwt_claims' constraint of the SecretID.",
+			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
 			logical.UpdateOperation: &framework.PathOperation{
@@ -142,6 +146,25 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 
 		entryIndex := fmt.Sprintf("%s%s/%s", role.SecretIDPrefix, roleNameHMAC, secretIDHMAC)
 
+		// Check the client certificate and JWT bindings of the secret ID
+		// before taking its lock, as validating a JWT may need to fetch keys
+		// remotely. The bindings can't change once the secret ID is created,
+		// so the entry is only decoded here and never persisted.
+		rawEntry, err := req.Storage.Get(ctx, entryIndex)
+		if err != nil {
+			return nil, err
+		}
+		if rawEntry == nil {
+			return logical.ErrorResponse("invalid secret id"), logical.ErrInvalidCredentials
+		}
+		bindings, err := decodeSecretIDStorageEntry(rawEntry)
+		if err != nil {
+			return nil, err
+		}
+		if err := b.verifySecretIDBindings(ctx, req, role, bindings, data.Get("jwt").(string)); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
 		secretIDLock := b.secretIDLock(secretIDHMAC)
 		secretIDLock.RLock()
 
@@ -191,6 +214,35 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 			return logical.ErrorResponse("invalid secret id"), nil
 		}
 
+		// Ensure that the CIDRs on the secret ID are still a subset of that of
+		// role's
+		err = verifyCIDRRoleSecretIDSubset(entry.CIDRList, role.SecretIDBoundCIDRs)
+		if err != nil {
+			return nil, err
+		}
+
+		// If CIDR restrictions are present on the secret ID, check if the
+		// source IP complies to it. This is done before the use count is
+		// updated, so that a login failing the check doesn't use up the
+		// secret ID.
+		if len(entry.CIDRList) != 0 {
+			if req.Connection == nil || req.Connection.RemoteAddr == "" {
+				return nil, fmt.Errorf("failed to get connection information")
+			}
+
+			belongs, err := cidrutil.IPBelongsToCIDRBlocksSlice(req.Connection.RemoteAddr, entry.CIDRList)
+			if err != nil {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+
+			if !belongs {
+				return logical.ErrorResponse(fmt.Errorf(
+					"source address %q unauthorized through CIDR restrictions on the secret ID",
+					req.Connection.RemoteAddr,
+				).Error()), nil
+			}
+		}
+
 		switch {
 		case entry.SecretIDNumUses == 0:
 			//
@@ -198,33 +250,6 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 			// in which case, the SecretID will remain to be valid as long as it is not
 			// expired.
 			//
-
-			// Ensure that the CIDRs on the secret ID are still a subset of that of
-			// role's
-			err = verifyCIDRRoleSecretIDSubset(entry.CIDRList, role.SecretIDBoundCIDRs)
-			if err != nil {
-				return nil, err
-			}
-
-			// If CIDR restrictions are present on the secret ID, check if the
-			// source IP complies to it
-			if len(entry.CIDRList) != 0 {
-				if req.Connection == nil || req.Connection.RemoteAddr == "" {
-					return nil, fmt.Errorf("failed to get connection information")
-				}
-
-				belongs, err := cidrutil.IPBelongsToCIDRBlocksSlice(req.Connection.RemoteAddr, entry.CIDRList)
-				if err != nil {
-					return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
-				}
-
-				if !belongs {
-					return logical.ErrorResponse(fmt.Errorf(
-						"source address %q unauthorized through CIDR restrictions on the secret ID",
-						req.Connection.RemoteAddr,
-					).Error()), nil
-				}
-			}
 		default:
 			//
 			// If the SecretIDNumUses is non-zero, it means that its use-count should be updated
@@ -273,31 +298,6 @@ func (b *backend) pathLoginUpdate(ctx context.Context, req *logical.Request, dat
 					return nil, err
 				}
 			}
-
-			// Ensure that the CIDRs on the secret ID are still a subset of that of
-			// role's
-			err = verifyCIDRRoleSecretIDSubset(entry.CIDRList, role.SecretIDBoundCIDRs)
-			if err != nil {
-				return nil, err
-			}
-
-			// If CIDR restrictions are present on the secret ID, check if the
-			// source IP complies to it
-			if len(entry.CIDRList) != 0 {
-				if req.Connection == nil || req.Connection.RemoteAddr == "" {
-					return nil, fmt.Errorf("failed to get connection information")
-				}
-
-				belongs, err := cidrutil.IPBelongsToCIDRBlocksSlice(req.Connection.RemoteAddr, entry.CIDRList)
-				if err != nil || !belongs {
-					return logical.ErrorResponse(
-						fmt.Errorf(
-							"source address %q unauthorized by CIDR restrictions on the secret ID: %w",
-							req.Connection.RemoteAddr,
-							err,
-						).Error()), nil
-				}
-			}
 		}
 
 		metadata = entry.Metadata
@@ -2,11 +2,22 @@ package approle
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-test/deep"
 	"github.com/hashicorp/vault/sdk/logical"
+	jose "gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
 )
 
 func TestAppRole_BoundCIDRLogin(t *testing.T) {
@@ -390,3 +401,318 @@ func TestAppRole_RoleDoesNotExist(t *testing.T) {
 		t.Fatalf("Error was not due to invalid role ID. Error: %s", errString)
 	}
 }
+
+func TestAppRole_BoundClientCertLogin(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, s := createBackendWithStorage(t)
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole",
+		Operation: logical.CreateOperation,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole/role-id",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	roleID := resp.Data["role_id"]
+
+	cert := &x509.Certificate{Raw: []byte("client certificate")}
+	otherCert := &x509.Certificate{Raw: []byte("other client certificate")}
+	sum := sha256.Sum256(cert.Raw)
+	fingerprint := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	// Invalid fingerprints are rejected
+	roleSecretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/testrole/secret-id",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"bound_client_cert_fingerprints": "abcd",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an invalid fingerprint, err:%v resp:%#v", err, resp)
+	}
+
+	// A single use is enough as failed logins don't use up the secret ID
+	roleSecretIDReq.Data["bound_client_cert_fingerprints"] = fingerprint
+	roleSecretIDReq.Data["num_uses"] = 1
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	secretID := resp.Data["secret_id"]
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/testrole/secret-id/lookup",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"secret_id": secretID,
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if diff := deep.Equal(resp.Data["bound_client_cert_fingerprints"], []string{strings.ToLower(fingerprint)}); diff != nil {
+		t.Fatal(diff)
+	}
+
+	login := func(connState *tls.ConnectionState) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+			},
+			Storage: s,
+			Connection: &logical.Connection{
+				RemoteAddr: "127.0.0.1",
+				ConnState:  connState,
+			},
+		})
+	}
+
+	// Login without a client certificate fails
+	resp, err = login(nil)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected login without a client certificate to fail, err:%v resp:%#v", err, resp)
+	}
+
+	// Login with a different client certificate fails
+	resp, err = login(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{otherCert}})
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected login with a different client certificate to fail, err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = login(&tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Auth == nil {
+		t.Fatal("expected login to succeed")
+	}
+}
+
+func TestAppRole_BoundJWTClaimsLogin(t *testing.T) {
+	var resp *logical.Response
+	var err error
+	b, s := createBackendWithStorage(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyPEM := func(k *ecdsa.PrivateKey) string {
+		der, err := x509.MarshalPKIXPublicKey(k.Public())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	}
+
+	signJWT := func(signingKey *ecdsa.PrivateKey, claims map[string]interface{}) string {
+		signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.ES256, Key: signingKey}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, err := jwt.Signed(signer).Claims(claims).CompactSerialize()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole",
+		Operation: logical.CreateOperation,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	// JWT claims can't be bound without keys on the role
+	roleSecretIDReq := &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "role/testrole/secret-id",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"bound_jwt_claims": map[string]interface{}{
+				"sub":    "ci-runner",
+				"groups": "deploy",
+				"run":    "1000000",
+			},
+			"bound_audiences": "vault",
+			"bound_issuer":    "https://ci.example.com",
+			"num_uses":        2,
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error without keys on the role, err:%v resp:%#v", err, resp)
+	}
+
+	// Keys must be valid, and only one source of keys may be set
+	roleReq := &logical.Request{
+		Path:      "role/testrole",
+		Operation: logical.UpdateOperation,
+		Storage:   s,
+		Data: map[string]interface{}{
+			"jwt_validation_pubkeys": "not a key",
+		},
+	}
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for an invalid key, err:%v resp:%#v", err, resp)
+	}
+	roleReq.Data = map[string]interface{}{
+		"jwt_validation_pubkeys": publicKeyPEM(key),
+		"jwks_url":               "https://127.0.0.1/jwks",
+	}
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected an error for both sources of keys, err:%v resp:%#v", err, resp)
+	}
+
+	roleReq.Data = map[string]interface{}{
+		"jwt_validation_pubkeys": publicKeyPEM(key),
+	}
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Path:      "role/testrole/role-id",
+		Operation: logical.ReadOperation,
+		Storage:   s,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	roleID := resp.Data["role_id"]
+
+	// Audiences and issuer are required to bind a JWT
+	for _, field := range []string{"bound_audiences", "bound_issuer"} {
+		value := roleSecretIDReq.Data[field]
+		delete(roleSecretIDReq.Data, field)
+		resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected an error without %s, err:%v resp:%#v", field, err, resp)
+		}
+		roleSecretIDReq.Data[field] = value
+	}
+
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	secretID := resp.Data["secret_id"]
+
+	login := func(token string) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Path:      "login",
+			Operation: logical.UpdateOperation,
+			Data: map[string]interface{}{
+				"role_id":   roleID,
+				"secret_id": secretID,
+				"jwt":       token,
+			},
+			Storage:    s,
+			Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+		})
+	}
+
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		ret := map[string]interface{}{
+			"iss":    "https://ci.example.com",
+			"aud":    []string{"vault"},
+			"sub":    "ci-runner",
+			"groups": []string{"build", "deploy"},
+			"run":    1000000,
+			"iat":    now.Unix(),
+			"exp":    now.Add(time.Minute).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(ret, k)
+				continue
+			}
+			ret[k] = v
+		}
+		return ret
+	}
+
+	testCases := map[string]string{
+		"missing jwt":       "",
+		"untrusted key":     signJWT(otherKey, claims(nil)),
+		"mismatched claim":  signJWT(key, claims(map[string]interface{}{"sub": "someone-else"})),
+		"mismatched number": signJWT(key, claims(map[string]interface{}{"run": 1000001})),
+		"missing claim":     signJWT(key, claims(map[string]interface{}{"groups": nil})),
+		"other audience":    signJWT(key, claims(map[string]interface{}{"aud": []string{"other-service"}})),
+		"other issuer":      signJWT(key, claims(map[string]interface{}{"iss": "https://other.example.com"})),
+		"missing exp":       signJWT(key, claims(map[string]interface{}{"exp": nil})),
+		"expired": signJWT(key, claims(map[string]interface{}{
+			"iat": now.Add(-time.Hour).Unix(),
+			"exp": now.Add(-time.Minute).Unix(),
+		})),
+	}
+	for name, token := range testCases {
+		resp, err = login(token)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s: expected login to fail, err:%v resp:%#v", name, err, resp)
+		}
+	}
+
+	// Failed logins don't use up the secret ID, which allows two uses
+	for i := 0; i < 2; i++ {
+		resp, err = login(signJWT(key, claims(nil)))
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		if resp.Auth == nil {
+			t.Fatal("expected login to succeed")
+		}
+	}
+
+	// Rotating the keys on the role takes effect for new logins
+	resp, err = b.HandleRequest(context.Background(), roleSecretIDReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	secretID = resp.Data["secret_id"]
+
+	roleReq.Data = map[string]interface{}{
+		"jwt_validation_pubkeys": publicKeyPEM(otherKey),
+	}
+	resp, err = b.HandleRequest(context.Background(), roleReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	resp, err = login(signJWT(key, claims(nil)))
+	if err != nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected login with a rotated out key to fail, err:%v resp:%#v", err, resp)
+	}
+	resp, err = login(signJWT(otherKey, claims(nil)))
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+}
@@ -2,12 +2,14 @@ package approle
 
 import (
 	"context"
+	"crypto"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/hashicorp/cap/jwt"
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	uuid "github.com/hashicorp/go-uuid"
@@ -77,6 +79,53 @@ type roleStorageEntry struct {
 	// SecretIDPrefix is the storage prefix for persisting secret IDs. This
 	// differs based on whether the secret IDs are cluster local or not.
 	SecretIDPrefix string `json:"secret_id_prefix" mapstructure:"secret_id_prefix"`
+
+	// JWTValidationPubKeys is a list of PEM encoded public keys trusted to
+	// sign the JWTs presented for secret IDs bound to JWT claims
+	JWTValidationPubKeys []string `json:"jwt_validation_pubkeys" mapstructure:"jwt_validation_pubkeys"`
+
+	// JWKSURL is the URL of a JSON Web Key Set trusted to sign the JWTs
+	// presented for secret IDs bound to JWT claims
+	JWKSURL string `json:"jwks_url" mapstructure:"jwks_url"`
+
+	// JWKSCAPEM is the CA certificate used to verify the connection to
+	// JWKSURL
+	JWKSCAPEM string `json:"jwks_ca_pem" mapstructure:"jwks_ca_pem"`
+}
+
+// supportedJWTAlgorithms are the signing algorithms accepted for the JWTs
+// presented for secret IDs bound to JWT claims
+var supportedJWTAlgorithms = []jwt.Alg{
+	jwt.RS256, jwt.RS384, jwt.RS512,
+	jwt.ES256, jwt.ES384, jwt.ES512,
+	jwt.PS256, jwt.PS384, jwt.PS512,
+	jwt.EdDSA,
+}
+
+// hasJWTKeys returns whether keys to validate JWTs are configured on the role
+func (r *roleStorageEntry) hasJWTKeys() bool {
+	return len(r.JWTValidationPubKeys) != 0 || r.JWKSURL != ""
+}
+
+// jwtKeySet returns the key set used to validate the JWTs presented for
+// secret IDs bound to JWT claims
+func (r *roleStorageEntry) jwtKeySet(ctx context.Context) (jwt.KeySet, error) {
+	switch {
+	case len(r.JWTValidationPubKeys) != 0:
+		var keys []crypto.PublicKey
+		for _, v := range r.JWTValidationPubKeys {
+			key, err := jwt.ParsePublicKeyPEM([]byte(v))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing public key: %w", err)
+			}
+			keys = append(keys, key)
+		}
+		return jwt.NewStaticKeySet(keys)
+	case r.JWKSURL != "":
+		return jwt.NewJSONWebKeySet(ctx, r.JWKSURL, r.JWKSCAPEM)
+	default:
+		return nil, fmt.Errorf("no keys to validate JWTs are configured on the role")
+	}
 }
 
 // roleIDStorageEntry represents the reverse mapping from RoleID to Role
@@ -167,6 +216,23 @@ to 0, meaning no expiration.`,
 				Description: `If set, the secret IDs generated using this role will be cluster local. This
 can only be set during role creation and once set, it can't be reset later.`,
 			},
+
+			"jwt_validation_pubkeys": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `A list of PEM-encoded public keys used to validate the JWTs presented at login
+for secret IDs bound to JWT claims. Cannot be used with "jwks_url".`,
+			},
+
+			"jwks_url": {
+				Type: framework.TypeString,
+				Description: `JWKS URL used to fetch the keys which validate the JWTs presented at login for
+secret IDs bound to JWT claims. Cannot be used with "jwt_validation_pubkeys".`,
+			},
+
+			"jwks_ca_pem": {
+				Type:        framework.TypeString,
+				Description: "The CA certificate or chain of certificates, in PEM format, to use to validate connections to the JWKS URL.",
+			},
 		},
 		ExistenceCheck: b.pathRoleExistenceCheck,
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -481,6 +547,29 @@ the role.`,
 					Type:        framework.TypeCommaStringSlice,
 					Description: defTokenFields["token_bound_cidrs"].Description,
 				},
+				"bound_client_cert_fingerprints": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated string or list of SHA-256 fingerprints of TLS client
+certificates. If set, one of these certificates must be presented on the
+connection when logging in with the SecretID.`,
+				},
+				"bound_jwt_claims": {
+					Type: framework.TypeKVPairs,
+					Description: `Map of claims and values which must be present in a JWT supplied in the
+'jwt' parameter when logging in with the SecretID. The JWT is validated with
+the keys configured on the role. Requires 'bound_audiences' and 'bound_issuer'.`,
+				},
+				"bound_audiences": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated string or list of audiences. The JWT supplied when logging in
+with the SecretID must be issued for one of them. Required to bind the SecretID
+to a JWT.`,
+				},
+				"bound_issuer": {
+					Type: framework.TypeString,
+					Description: `Issuer of the JWT supplied when logging in with the SecretID. Required to bind
+the SecretID to a JWT.`,
+				},
 				"num_uses": {
 					Type: framework.TypeInt,
 					Description: `Number of times this SecretID can be used, after which the SecretID expires.
@@ -600,6 +689,29 @@ the role.`,
 					Type: framework.TypeCommaStringSlice,
 					Description: `Comma separated string or list of CIDR blocks. If set, specifies the blocks of
 IP addresses which can use the returned token. Should be a subset of the token CIDR blocks listed on the role, if any.`,
+				},
+				"bound_client_cert_fingerprints": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated string or list of SHA-256 fingerprints of TLS client
+certificates. If set, one of these certificates must be presented on the
+connection when logging in with the SecretID.`,
+				},
+				"bound_jwt_claims": {
+					Type: framework.TypeKVPairs,
+					Description: `Map of claims and values which must be present in a JWT supplied in the
+'jwt' parameter when logging in with the SecretID. The JWT is validated with
+the keys configured on the role. Requires 'bound_audiences' and 'bound_issuer'.`,
+				},
+				"bound_audiences": {
+					Type: framework.TypeCommaStringSlice,
+					Description: `Comma separated string or list of audiences. The JWT supplied when logging in
+with the SecretID must be issued for one of them. Required to bind the SecretID
+to a JWT.`,
+				},
+				"bound_issuer": {
+					Type: framework.TypeString,
+					Description: `Issuer of the JWT supplied when logging in with the SecretID. Required to bind
+the SecretID to a JWT.`,
 				},
 				"num_uses": {
 					Type: framework.TypeInt,
@@ -791,6 +903,7 @@ func (b *backend) setRoleEntry(ctx context.Context, s logical.Storage, roleName
 	if err = s.Put(ctx, entry); err != nil {
 		return err
 	}
+	b.invalidateJWTKeySet(roleName)
 
 	// If previousRoleID is still intact, don't create another one
 	if previousRoleID != "" && previousRoleID == role.RoleID {
@@ -988,6 +1101,29 @@ func (b *backend) pathRoleCreateUpdate(ctx context.Context, req *logical.Request
 		role.SecretIDTTL = time.Second * time.Duration(data.Get("secret_id_ttl").(int))
 	}
 
+	if pubKeysRaw, ok := data.GetOk("jwt_validation_pubkeys"); ok {
+		role.JWTValidationPubKeys = pubKeysRaw.([]string)
+	}
+	if jwksURLRaw, ok := data.GetOk("jwks_url"); ok {
+		role.JWKSURL = jwksURLRaw.(string)
+	}
+	if jwksCAPEMRaw, ok := data.GetOk("jwks_ca_pem"); ok {
+		role.JWKSCAPEM = jwksCAPEMRaw.(string)
+	}
+	if len(role.JWTValidationPubKeys) != 0 && role.JWKSURL != "" {
+		return logical.ErrorResponse(`"jwt_validation_pubkeys" and "jwks_url" are mutually exclusive`), nil
+	}
+	for _, v := range role.JWTValidationPubKeys {
+		if _, err := jwt.ParsePublicKeyPEM([]byte(v)); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing public key: %v", err)), nil
+		}
+	}
+	if role.JWKSURL != "" {
+		if _, err := jwt.NewJSONWebKeySet(ctx, role.JWKSURL, role.JWKSCAPEM); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error checking jwks_ca_pem: %v", err)), nil
+		}
+	}
+
 	// handle upgrade cases
 	{
 		if err := tokenutil.UpgradeValue(data, "policies", "token_policies", &role.Policies, &role.TokenPolicies); err != nil {
@@ -1037,11 +1173,14 @@ func (b *backend) pathRoleRead(ctx context.Context, req *logical.Request, data *
 	}
 
 	respData := map[string]interface{}{
-		"bind_secret_id":        role.BindSecretID,
-		"secret_id_bound_cidrs": role.SecretIDBoundCIDRs,
-		"secret_id_num_uses":    role.SecretIDNumUses,
-		"secret_id_ttl":         role.SecretIDTTL / time.Second,
-		"local_secret_ids":      false,
+		"bind_secret_id":         role.BindSecretID,
+		"secret_id_bound_cidrs":  role.SecretIDBoundCIDRs,
+		"secret_id_num_uses":     role.SecretIDNumUses,
+		"secret_id_ttl":          role.SecretIDTTL / time.Second,
+		"local_secret_ids":       false,
+		"jwt_validation_pubkeys": role.JWTValidationPubKeys,
+		"jwks_url":               role.JWKSURL,
+		"jwks_ca_pem":            role.JWKSCAPEM,
 	}
 	role.PopulateTokenData(respData)
 
@@ -1137,6 +1276,7 @@ func (b *backend) pathRoleDelete(ctx context.Context, req *logical.Request, data
 	if err = req.Storage.Delete(ctx, "role/"+strings.ToLower(role.name)); err != nil {
 		return nil, err
 	}
+	b.invalidateJWTKeySet(role.name)
 
 	return nil, nil
 }
@@ -1213,19 +1353,32 @@ func (b *backend) pathRoleSecretIDLookupUpdate(ctx context.Context, req *logical
 
 func (entry *secretIDStorageEntry) ToResponseData() map[string]interface{} {
 	ret := map[string]interface{}{
-		"secret_id_accessor": entry.SecretIDAccessor,
-		"secret_id_num_uses": entry.SecretIDNumUses,
-		"secret_id_ttl":      entry.SecretIDTTL / time.Second,
-		"creation_time":      entry.CreationTime,
-		"expiration_time":    entry.ExpirationTime,
-		"last_updated_time":  entry.LastUpdatedTime,
-		"metadata":           entry.Metadata,
-		"cidr_list":          entry.CIDRList,
-		"token_bound_cidrs":  entry.TokenBoundCIDRs,
+		"secret_id_accessor":             entry.SecretIDAccessor,
+		"secret_id_num_uses":             entry.SecretIDNumUses,
+		"secret_id_ttl":                  entry.SecretIDTTL / time.Second,
+		"creation_time":                  entry.CreationTime,
+		"expiration_time":                entry.ExpirationTime,
+		"last_updated_time":              entry.LastUpdatedTime,
+		"metadata":                       entry.Metadata,
+		"cidr_list":                      entry.CIDRList,
+		"token_bound_cidrs":              entry.TokenBoundCIDRs,
+		"bound_client_cert_fingerprints": entry.BoundClientCertFingerprints,
+		"bound_jwt_claims":               entry.BoundJWTClaims,
+		"bound_audiences":                entry.BoundAudiences,
+		"bound_issuer":                   entry.BoundIssuer,
 	}
 	if len(entry.TokenBoundCIDRs) == 0 {
 		ret["token_bound_cidrs"] = []string{}
 	}
+	if len(entry.BoundClientCertFingerprints) == 0 {
+		ret["bound_client_cert_fingerprints"] = []string{}
+	}
+	if len(entry.BoundJWTClaims) == 0 {
+		ret["bound_jwt_claims"] = map[string]string{}
+	}
+	if len(entry.BoundAudiences) == 0 {
+		ret["bound_audiences"] = []string{}
+	}
 	return ret
 }
 
@@ -2375,6 +2528,25 @@ func (b *backend) handleRoleSecretIDCommon(ctx context.Context, req *logical.Req
 		return nil, err
 	}
 
+	certFingerprints, err := parseCertFingerprints(data.Get("bound_client_cert_fingerprints").([]string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	jwtBinding := &secretIDStorageEntry{
+		BoundJWTClaims: data.Get("bound_jwt_claims").(map[string]string),
+		BoundAudiences: data.Get("bound_audiences").([]string),
+		BoundIssuer:    data.Get("bound_issuer").(string),
+	}
+	if jwtBinding.hasJWTBinding() {
+		if len(jwtBinding.BoundAudiences) == 0 || jwtBinding.BoundIssuer == "" {
+			return logical.ErrorResponse(`"bound_audiences" and "bound_issuer" are required to bind the secret ID to a JWT`), nil
+		}
+		if !role.hasJWTKeys() {
+			return logical.ErrorResponse(`binding the secret ID to a JWT requires "jwt_validation_pubkeys" or "jwks_url" to be set on the role`), nil
+		}
+	}
+
 	var numUses int
 	// Check whether or not specified num_uses is defined, otherwise fallback to role's secret_id_num_uses
 	if numUsesRaw, ok := data.GetOk("num_uses"); ok {
@@ -2405,11 +2577,15 @@ func (b *backend) handleRoleSecretIDCommon(ctx context.Context, req *logical.Req
 	}
 
 	secretIDStorage := &secretIDStorageEntry{
-		SecretIDNumUses: numUses,
-		SecretIDTTL:     ttl,
-		Metadata:        make(map[string]string),
-		CIDRList:        secretIDCIDRs,
-		TokenBoundCIDRs: secretIDTokenCIDRs,
+		SecretIDNumUses:             numUses,
+		SecretIDTTL:                 ttl,
+		Metadata:                    make(map[string]string),
+		CIDRList:                    secretIDCIDRs,
+		TokenBoundCIDRs:             secretIDTokenCIDRs,
+		BoundClientCertFingerprints: certFingerprints,
+		BoundJWTClaims:              jwtBinding.BoundJWTClaims,
+		BoundAudiences:              jwtBinding.BoundAudiences,
+		BoundIssuer:                 jwtBinding.BoundIssuer,
 	}
 
 	if err = strutil.ParseArbitraryKeyValues(data.Get("metadata").(string), secretIDStorage.Metadata, ","); err != nil {
@@ -6,8 +6,12 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/cap/jwt"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
 	uuid "github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/vault/helper/parseip"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
@@ -56,6 +60,24 @@ type secretIDStorageEntry struct {
 	// restrictions on the usage of the token generated by this SecretID
 	TokenBoundCIDRs []string `json:"token_cidr_list" mapstructure:"token_bound_cidrs"`
 
+	// BoundClientCertFingerprints is a set of SHA-256 fingerprints of TLS
+	// client certificates, one of which must be presented when the SecretID
+	// is used
+	BoundClientCertFingerprints []string `json:"bound_client_cert_fingerprints" mapstructure:"bound_client_cert_fingerprints"`
+
+	// BoundJWTClaims is a set of claims which must be present in a JWT,
+	// signed by a key trusted by the role, presented when the SecretID is
+	// used
+	BoundJWTClaims map[string]string `json:"bound_jwt_claims" mapstructure:"bound_jwt_claims"`
+
+	// BoundAudiences is a set of audiences, one of which the JWT presented
+	// when the SecretID is used must be issued for
+	BoundAudiences []string `json:"bound_audiences" mapstructure:"bound_audiences"`
+
+	// BoundIssuer is the issuer of the JWT presented when the SecretID is
+	// used
+	BoundIssuer string `json:"bound_issuer" mapstructure:"bound_issuer"`
+
 	// This is a deprecated field
 	SecretIDNumUsesDeprecated int `json:"SecretIDNumUses" mapstructure:"SecretIDNumUses"`
 }
@@ -92,6 +114,173 @@ func verifyCIDRRoleSecretIDSubset(secretIDCIDRs []string, roleBoundCIDRList []st
 	return nil
 }
 
+// parseCertFingerprints normalizes the given SHA-256 certificate
+// fingerprints to lower case hex without separators.
+func parseCertFingerprints(fingerprints []string) ([]string, error) {
+	var ret []string
+	for _, fingerprint := range fingerprints {
+		normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(fingerprint), ":", ""))
+		decoded, err := hex.DecodeString(normalized)
+		if err != nil || len(decoded) != sha256.Size {
+			return nil, fmt.Errorf("invalid SHA-256 certificate fingerprint %q", fingerprint)
+		}
+		ret = append(ret, normalized)
+	}
+	return ret, nil
+}
+
+// verifyClientCertFingerprint checks if the TLS client certificate presented
+// on the connection matches one of the given fingerprints
+func verifyClientCertFingerprint(req *logical.Request, fingerprints []string) error {
+	if req.Connection == nil || req.Connection.ConnState == nil || len(req.Connection.ConnState.PeerCertificates) == 0 {
+		return fmt.Errorf("client certificate must be supplied")
+	}
+
+	sum := sha256.Sum256(req.Connection.ConnState.PeerCertificates[0].Raw)
+	if !strutil.StrListContains(fingerprints, hex.EncodeToString(sum[:])) {
+		return fmt.Errorf("client certificate unauthorized by restrictions on the secret ID")
+	}
+
+	return nil
+}
+
+// hasJWTBinding returns whether a JWT must be presented when the SecretID
+// is used
+func (entry *secretIDStorageEntry) hasJWTBinding() bool {
+	return len(entry.BoundJWTClaims) != 0 || len(entry.BoundAudiences) != 0 || entry.BoundIssuer != ""
+}
+
+// verifySecretIDBindings checks that the client certificate and JWT
+// presented at login satisfy the bindings set on the SecretID
+func (b *backend) verifySecretIDBindings(ctx context.Context, req *logical.Request, role *roleStorageEntry, entry *secretIDStorageEntry, token string) error {
+	// If the secret ID is bound to a client certificate, check if one of the
+	// certificates was presented on the connection
+	if len(entry.BoundClientCertFingerprints) != 0 {
+		if err := verifyClientCertFingerprint(req, entry.BoundClientCertFingerprints); err != nil {
+			return err
+		}
+	}
+
+	// If the secret ID is bound to a JWT, check if the supplied JWT is
+	// trusted by the role and carries the claims
+	if entry.hasJWTBinding() {
+		if err := b.verifyJWTClaims(ctx, role, entry, token); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// verifyJWTClaims validates the given JWT using the keys trusted by the
+// role, and checks that it was issued by the bound issuer for one of the
+// bound audiences, and carries the bound claims. A claim matches if it
+// equals the expected value, or if it is a list containing it.
+func (b *backend) verifyJWTClaims(ctx context.Context, role *roleStorageEntry, entry *secretIDStorageEntry, token string) error {
+	if token == "" {
+		return fmt.Errorf("missing jwt")
+	}
+
+	// Audiences and issuer are required when the SecretID is created, but
+	// check again rather than skipping their validation
+	if len(entry.BoundAudiences) == 0 || entry.BoundIssuer == "" {
+		return fmt.Errorf("secret ID is bound to a JWT without an audience and issuer")
+	}
+
+	keySet, err := b.roleJWTKeySet(role)
+	if err != nil {
+		return err
+	}
+	validator, err := jwt.NewValidator(keySet)
+	if err != nil {
+		return err
+	}
+
+	claims, err := validator.Validate(ctx, token, jwt.Expected{
+		Issuer:            entry.BoundIssuer,
+		Audiences:         entry.BoundAudiences,
+		SigningAlgorithms: supportedJWTAlgorithms,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to validate jwt: %w", err)
+	}
+	if _, ok := claims["exp"]; !ok {
+		return fmt.Errorf("failed to validate jwt: no expiration time (exp) claim in token")
+	}
+
+	for name, expected := range entry.BoundJWTClaims {
+		if !jwtClaimMatches(claims[name], expected) {
+			return fmt.Errorf("claim %q does not match restrictions on the secret ID", name)
+		}
+	}
+
+	return nil
+}
+
+func jwtClaimMatches(claim interface{}, expected string) bool {
+	switch v := claim.(type) {
+	case []interface{}:
+		for _, item := range v {
+			if jwtClaimMatches(item, expected) {
+				return true
+			}
+		}
+		return false
+	case string:
+		return v == expected
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64) == expected
+	case bool:
+		return strconv.FormatBool(v) == expected
+	default:
+		return false
+	}
+}
+
+// cachedJWTKeySet is a key set along with the configuration of the role it
+// was created from
+type cachedJWTKeySet struct {
+	keySet    jwt.KeySet
+	pubKeys   []string
+	jwksURL   string
+	jwksCAPEM string
+}
+
+// roleJWTKeySet returns the key set used to validate the JWTs presented for
+// secret IDs of the role, creating it if it is not cached
+func (b *backend) roleJWTKeySet(role *roleStorageEntry) (jwt.KeySet, error) {
+	b.jwtKeySetsLock.Lock()
+	defer b.jwtKeySetsLock.Unlock()
+
+	// The role may have been read before a concurrent update invalidated the
+	// cache, so make sure the cached key set matches its configuration
+	cached, ok := b.jwtKeySets[strings.ToLower(role.name)]
+	if ok && cached.jwksURL == role.JWKSURL && cached.jwksCAPEM == role.JWKSCAPEM &&
+		strutil.EquivalentSlices(cached.pubKeys, role.JWTValidationPubKeys) {
+		return cached.keySet, nil
+	}
+
+	keySet, err := role.jwtKeySet(b.keySetCtx)
+	if err != nil {
+		return nil, err
+	}
+	b.jwtKeySets[strings.ToLower(role.name)] = &cachedJWTKeySet{
+		keySet:    keySet,
+		pubKeys:   role.JWTValidationPubKeys,
+		jwksURL:   role.JWKSURL,
+		jwksCAPEM: role.JWKSCAPEM,
+	}
+
+	return keySet, nil
+}
+
+func (b *backend) invalidateJWTKeySet(roleName string) {
+	b.jwtKeySetsLock.Lock()
+	defer b.jwtKeySetsLock.Unlock()
+
+	delete(b.jwtKeySets, strings.ToLower(roleName))
+}
+
 const maxHmacInputLength = 1024
 
 // Creates a SHA256 HMAC of the given 'value' using the given 'key' and returns
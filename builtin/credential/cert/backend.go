@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/go-multierror"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -49,6 +50,8 @@ func Backend() *backend {
 	}
 
 	b.crlUpdateMutex = &sync.RWMutex{}
+	b.ocspCache, _ = lru.New(revocationCacheSize)
+	b.cdpCache, _ = lru.New(revocationCacheSize)
 
 	return &b
 }
@@ -59,6 +62,11 @@ type backend struct {
 
 	crls           map[string]CRLInfo
 	crlUpdateMutex *sync.RWMutex
+
+	// ocspCache and cdpCache cache OCSP responses and CRLs fetched from
+	// distribution points for cert roles checking them
+	ocspCache *lru.Cache
+	cdpCache  *lru.Cache
 }
 
 func (b *backend) invalidate(_ context.Context, key string) {
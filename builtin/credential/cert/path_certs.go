@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -122,6 +123,51 @@ in the certificate. The metadata key will be the string consisting of the oid nu
 separated by a dash (-) instead of a dot (.) to allow usage in ACL templates.`,
 			},
 
+			"ocsp_enabled": {
+				Type: framework.TypeBool,
+				Description: `Whether to check the revocation status of the
+client certificate and intermediate CAs of the chain through OCSP.`,
+			},
+
+			"ocsp_ca_certificates": {
+				Type: framework.TypeString,
+				Description: `Any additional OCSP responder certificates needed
+to verify OCSP responses, PEM encoded. Responses signed by the issuer of a
+certificate, or by a responder it delegated to, are always trusted.`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					EditType: "file",
+				},
+			},
+
+			"ocsp_servers_override": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `A comma-separated list of OCSP server addresses to
+query instead of the ones in the Authority Information Access extension of
+certificates. The servers are queried in order until one responds.`,
+			},
+
+			"ocsp_fail_open": {
+				Type: framework.TypeBool,
+				Description: `If set, a certificate whose OCSP status can't be
+determined is accepted; otherwise it is rejected. Revoked certificates are
+always rejected.`,
+			},
+
+			"crl_distribution_points_enabled": {
+				Type: framework.TypeBool,
+				Description: `Whether to check the client certificate and
+intermediate CAs of the chain against the CRLs fetched from the CRL
+Distribution Points extension of each certificate.`,
+			},
+
+			"crl_distribution_points_grace_period": {
+				Type: framework.TypeDurationSecond,
+				Description: `How long a previously fetched CRL may still be
+used past its next update time while its distribution point is unavailable.
+Afterwards, certificates it covers are rejected until a current CRL can be
+fetched. Defaults to 0, never using CRLs past their next update time.`,
+			},
+
 			"display_name": {
 				Type: framework.TypeString,
 				Description: `The display name to use for clients using this
@@ -242,16 +288,22 @@ func (b *backend) pathCertRead(ctx context.Context, req *logical.Request, d *fra
 	}
 
 	data := map[string]interface{}{
-		"certificate":                  cert.Certificate,
-		"display_name":                 cert.DisplayName,
-		"allowed_names":                cert.AllowedNames,
-		"allowed_common_names":         cert.AllowedCommonNames,
-		"allowed_dns_sans":             cert.AllowedDNSSANs,
-		"allowed_email_sans":           cert.AllowedEmailSANs,
-		"allowed_uri_sans":             cert.AllowedURISANs,
-		"allowed_organizational_units": cert.AllowedOrganizationalUnits,
-		"required_extensions":          cert.RequiredExtensions,
-		"allowed_metadata_extensions":  cert.AllowedMetadataExtensions,
+		"certificate":                          cert.Certificate,
+		"display_name":                         cert.DisplayName,
+		"allowed_names":                        cert.AllowedNames,
+		"allowed_common_names":                 cert.AllowedCommonNames,
+		"allowed_dns_sans":                     cert.AllowedDNSSANs,
+		"allowed_email_sans":                   cert.AllowedEmailSANs,
+		"allowed_uri_sans":                     cert.AllowedURISANs,
+		"allowed_organizational_units":         cert.AllowedOrganizationalUnits,
+		"required_extensions":                  cert.RequiredExtensions,
+		"allowed_metadata_extensions":          cert.AllowedMetadataExtensions,
+		"ocsp_enabled":                         cert.OCSPEnabled,
+		"ocsp_ca_certificates":                 cert.OCSPCACertificates,
+		"ocsp_servers_override":                cert.OCSPServersOverride,
+		"ocsp_fail_open":                       cert.OCSPFailOpen,
+		"crl_distribution_points_enabled":      cert.CRLDistributionPointsEnabled,
+		"crl_distribution_points_grace_period": int64(cert.CRLDistributionPointsGracePeriod.Seconds()),
 	}
 	cert.PopulateTokenData(data)
 
@@ -321,6 +373,24 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 	if allowedMetadataExtensionsRaw, ok := d.GetOk("allowed_metadata_extensions"); ok {
 		cert.AllowedMetadataExtensions = allowedMetadataExtensionsRaw.([]string)
 	}
+	if ocspEnabledRaw, ok := d.GetOk("ocsp_enabled"); ok {
+		cert.OCSPEnabled = ocspEnabledRaw.(bool)
+	}
+	if ocspCACertificatesRaw, ok := d.GetOk("ocsp_ca_certificates"); ok {
+		cert.OCSPCACertificates = ocspCACertificatesRaw.(string)
+	}
+	if ocspServersOverrideRaw, ok := d.GetOk("ocsp_servers_override"); ok {
+		cert.OCSPServersOverride = ocspServersOverrideRaw.([]string)
+	}
+	if ocspFailOpenRaw, ok := d.GetOk("ocsp_fail_open"); ok {
+		cert.OCSPFailOpen = ocspFailOpenRaw.(bool)
+	}
+	if crlDistributionPointsEnabledRaw, ok := d.GetOk("crl_distribution_points_enabled"); ok {
+		cert.CRLDistributionPointsEnabled = crlDistributionPointsEnabledRaw.(bool)
+	}
+	if gracePeriodRaw, ok := d.GetOk("crl_distribution_points_grace_period"); ok {
+		cert.CRLDistributionPointsGracePeriod = time.Duration(gracePeriodRaw.(int)) * time.Second
+	}
 
 	// Get tokenutil fields
 	if err := cert.ParseTokenFields(req, d); err != nil {
@@ -389,6 +459,24 @@ func (b *backend) pathCertWrite(ctx context.Context, req *logical.Request, d *fr
 		return logical.ErrorResponse("failed to parse certificate"), nil
 	}
 
+	if cert.OCSPCACertificates != "" && len(cert.parsedOCSPCACertificates()) == 0 {
+		return logical.ErrorResponse("failed to parse ocsp_ca_certificates"), nil
+	}
+	for _, server := range cert.OCSPServersOverride {
+		if u, err := url.Parse(server); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return logical.ErrorResponse("invalid OCSP server address %q", server), nil
+		}
+	}
+	if cert.CRLDistributionPointsGracePeriod < 0 {
+		return logical.ErrorResponse("crl_distribution_points_grace_period must not be negative"), nil
+	}
+
+	// OCSP responses and CRLs are verified against the issuer of a
+	// certificate, which isn't known for trusted non-CA certificates.
+	if !parsed[0].IsCA && cert.revocationChecksEnabled() {
+		return logical.ErrorResponse("ocsp_enabled and crl_distribution_points_enabled require a CA certificate"), nil
+	}
+
 	// If the certificate is not a CA cert, then ensure that x509.ExtKeyUsageClientAuth is set
 	if !parsed[0].IsCA && parsed[0].ExtKeyUsage != nil {
 		var clientAuth bool
@@ -438,6 +526,25 @@ type CertEntry struct {
 	RequiredExtensions         []string
 	AllowedMetadataExtensions  []string
 	BoundCIDRs                 []*sockaddr.SockAddrMarshaler
+
+	OCSPEnabled                      bool
+	OCSPCACertificates               string
+	OCSPServersOverride              []string
+	OCSPFailOpen                     bool
+	CRLDistributionPointsEnabled     bool
+	CRLDistributionPointsGracePeriod time.Duration
+}
+
+// revocationChecksEnabled returns whether certificates are checked through
+// OCSP or CRL distribution points.
+func (e *CertEntry) revocationChecksEnabled() bool {
+	return e.OCSPEnabled || e.CRLDistributionPointsEnabled
+}
+
+// parsedOCSPCACertificates returns the additional trusted OCSP responder
+// certificates.
+func (e *CertEntry) parsedOCSPCACertificates() []*x509.Certificate {
+	return parsePEM([]byte(e.OCSPCACertificates))
 }
 
 const pathCertHelpSyn = `
@@ -448,6 +555,10 @@ const pathCertHelpDesc = `
 This endpoint allows you to create, read, update, and delete trusted certificates
 that are allowed to authenticate.
 
+Certificates may optionally be checked for revocation through OCSP and the CRLs
+of their CRL distribution points, in addition to the CRLs managed through the
+"crls/" endpoint.
+
 Deleting a certificate will not revoke auth for prior authenticated connections.
 To do this, do a revoke on "login". If you don't need to revoke login immediately,
 then the next renew will cause the lease to expire.
@@ -255,7 +255,14 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 			// Check for client cert being explicitly listed in the config (and matching other constraints)
 			if tCert.SerialNumber.Cmp(clientCert.SerialNumber) == 0 &&
 				bytes.Equal(tCert.AuthorityKeyId, clientCert.AuthorityKeyId) &&
-				b.matchesConstraints(clientCert, trustedNonCA.Certificates, trustedNonCA) {
+				b.matchesConstraints(ctx, clientCert, trustedNonCA.Certificates, trustedNonCA) {
+				// The revocation status of a non-CA certificate can't be
+				// checked without its issuer; such configurations are
+				// rejected when written, but fail closed all the same.
+				if trustedNonCA.Entry.revocationChecksEnabled() {
+					b.Logger().Warn("revocation checks are not supported for non-CA certificates, rejecting login", "cert_name", trustedNonCA.Entry.Name)
+					continue
+				}
 				return trustedNonCA, nil, nil
 			}
 		}
@@ -274,7 +281,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 			for _, chain := range trustedChains { // For each root chain that we matched
 				for _, cCert := range chain { // For each cert in the matched chain
 					if tCert.Equal(cCert) && // ParsedCert intersects with matched chain
-						b.matchesConstraints(clientCert, chain, trust) { // validate client cert + matched chain against the config
+						b.matchesConstraints(ctx, clientCert, chain, trust) { // validate client cert + matched chain against the config
 						// Add the match to the list
 						matches = append(matches, trust)
 					}
@@ -292,7 +299,7 @@ func (b *backend) verifyCredentials(ctx context.Context, req *logical.Request, d
 	return matches[0], nil, nil
 }
 
-func (b *backend) matchesConstraints(clientCert *x509.Certificate, trustedChain []*x509.Certificate, config *ParsedCert) bool {
+func (b *backend) matchesConstraints(ctx context.Context, clientCert *x509.Certificate, trustedChain []*x509.Certificate, config *ParsedCert) bool {
 	return !b.checkForChainInCRLs(trustedChain) &&
 		b.matchesNames(clientCert, config) &&
 		b.matchesCommonName(clientCert, config) &&
@@ -300,7 +307,8 @@ func (b *backend) matchesConstraints(clientCert *x509.Certificate, trustedChain
 		b.matchesEmailSANs(clientCert, config) &&
 		b.matchesURISANs(clientCert, config) &&
 		b.matchesOrganizationalUnits(clientCert, config) &&
-		b.matchesCertificateExtensions(clientCert, config)
+		b.matchesCertificateExtensions(clientCert, config) &&
+		b.passesRevocationChecks(ctx, trustedChain, config)
 }
 
 // matchesNames verifies that the certificate matches at least one configured
@@ -458,6 +466,17 @@ func (b *backend) matchesCertificateExtensions(clientCert *x509.Certificate, con
 	return true
 }
 
+// passesRevocationChecks verifies that no certificate of the chain is revoked
+// according to the OCSP responders and CRL distribution points checked by the
+// configured certificate
+func (b *backend) passesRevocationChecks(ctx context.Context, trustedChain []*x509.Certificate, config *ParsedCert) bool {
+	if err := b.checkChainRevocation(ctx, trustedChain, config.Entry); err != nil {
+		b.Logger().Warn("certificate chain failed revocation checks", "cert_name", config.Entry.Name, "error", err)
+		return false
+	}
+	return true
+}
+
 // certificateExtensionsMetadata returns the metadata from configured
 // metadata extensions
 func (b *backend) certificateExtensionsMetadata(clientCert *x509.Certificate, config *ParsedCert) map[string]string {
@@ -0,0 +1,356 @@
+package cert
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ocsp"
+)
+
+const (
+	// revocationCacheSize is the number of OCSP responses and fetched CRLs
+	// kept in memory.
+	revocationCacheSize = 1000
+
+	// defaultOCSPCacheTTL is how long OCSP responses without a next update
+	// time are cached.
+	defaultOCSPCacheTTL = 5 * time.Minute
+
+	// defaultCDPCacheTTL is how long CRLs fetched from distribution points
+	// without a next update time are cached.
+	defaultCDPCacheTTL = time.Hour
+
+	maxOCSPResponseSize = 1024 * 1024
+	maxCDPCRLSize       = 32 * 1024 * 1024
+)
+
+// revocationHTTPClient is used to query OCSP responders and fetch CRLs from
+// distribution points.
+var revocationHTTPClient = &http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// errCertRevoked is returned when a certificate of a chain is revoked.
+var errCertRevoked = errors.New("certificate is revoked")
+
+// ocspCacheEntry is a cached OCSP status of a certificate.
+type ocspCacheEntry struct {
+	status    int
+	expiresAt time.Time
+}
+
+// cdpCacheEntry is a CRL fetched from a distribution point. expiresAt is the
+// next update time of the CRL.
+type cdpCacheEntry struct {
+	crl       *pkix.CertificateList
+	serials   map[string]struct{}
+	expiresAt time.Time
+}
+
+// checkChainRevocation checks the certificates of a verified chain for
+// revocation through OCSP and CRL distribution points, as configured by the
+// cert role. The root of the chain is not checked.
+func (b *backend) checkChainRevocation(ctx context.Context, chain []*x509.Certificate, entry *CertEntry) error {
+	if !entry.OCSPEnabled && !entry.CRLDistributionPointsEnabled {
+		return nil
+	}
+
+	for i := 0; i+1 < len(chain); i++ {
+		cert, issuer := chain[i], chain[i+1]
+		if entry.OCSPEnabled {
+			if err := b.checkCertOCSP(ctx, cert, issuer, entry); err != nil {
+				return err
+			}
+		}
+		if entry.CRLDistributionPointsEnabled {
+			if err := b.checkCertCDPs(ctx, cert, issuer, entry.CRLDistributionPointsGracePeriod); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkCertOCSP checks the OCSP status of a certificate. Errors obtaining
+// the status are ignored when the role fails open; a revoked certificate
+// always fails.
+func (b *backend) checkCertOCSP(ctx context.Context, cert, issuer *x509.Certificate, entry *CertEntry) error {
+	servers := entry.OCSPServersOverride
+	if len(servers) == 0 {
+		servers = cert.OCSPServer
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+
+	status, err := b.ocspStatus(ctx, cert, issuer, servers, entry)
+	switch {
+	case err != nil && entry.OCSPFailOpen:
+		b.Logger().Warn("failed to check OCSP status of certificate, allowing it as the role fails open",
+			"serial_number", cert.SerialNumber.String(), "error", err)
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to check OCSP status of certificate %s: %w", cert.SerialNumber, err)
+	case status == ocsp.Revoked:
+		return errCertRevoked
+	}
+
+	return nil
+}
+
+// ocspStatus returns the OCSP status of a certificate from the cache, or by
+// querying the given servers in order until one returns a valid response.
+func (b *backend) ocspStatus(ctx context.Context, cert, issuer *x509.Certificate, servers []string, entry *CertEntry) (int, error) {
+	// Responses are cached per role configuration, as the servers queried
+	// and responders trusted determine which responses are accepted.
+	issuerHash := sha256.Sum256(issuer.Raw)
+	configHash := sha256.Sum256([]byte(strings.Join(servers, "\n") + "\n" + entry.OCSPCACertificates))
+	cacheKey := hex.EncodeToString(issuerHash[:]) + ":" + hex.EncodeToString(configHash[:]) + ":" + cert.SerialNumber.String()
+	if raw, ok := b.ocspCache.Get(cacheKey); ok {
+		cached := raw.(*ocspCacheEntry)
+		if time.Now().Before(cached.expiresAt) {
+			return cached.status, nil
+		}
+		b.ocspCache.Remove(cacheKey)
+	}
+
+	ocspReq, err := ocsp.CreateRequest(cert, issuer, &ocsp.RequestOptions{Hash: crypto.SHA1})
+	if err != nil {
+		return 0, fmt.Errorf("error creating OCSP request: %w", err)
+	}
+
+	var errs []error
+	for _, server := range servers {
+		resp, err := b.queryOCSP(ctx, server, ocspReq, cert, issuer, entry)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", server, err))
+			continue
+		}
+
+		expiresAt := resp.NextUpdate
+		if expiresAt.IsZero() {
+			expiresAt = time.Now().Add(defaultOCSPCacheTTL)
+		}
+		b.ocspCache.Add(cacheKey, &ocspCacheEntry{
+			status:    resp.Status,
+			expiresAt: expiresAt,
+		})
+
+		return resp.Status, nil
+	}
+
+	return 0, fmt.Errorf("no valid OCSP response: %v", errs)
+}
+
+// queryOCSP queries an OCSP responder and validates its response.
+func (b *backend) queryOCSP(ctx context.Context, server string, ocspReq []byte, cert, issuer *x509.Certificate, entry *CertEntry) (*ocsp.Response, error) {
+	body, err := fetchRevocationData(ctx, http.MethodPost, server, "application/ocsp-request", ocspReq, maxOCSPResponseSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Responses signed by the issuer, or by a responder the issuer
+	// delegated to, are verified against the issuer.
+	resp, err := ocsp.ParseResponseForCert(body, cert, issuer)
+	switch {
+	case err != nil:
+		resp, err = verifyOCSPResponseWithResponders(body, cert, entry.parsedOCSPCACertificates())
+		if err != nil {
+			return nil, err
+		}
+	case resp.Certificate != nil && !resp.Certificate.Equal(issuer) && !hasOCSPSigningUsage(resp.Certificate):
+		return nil, fmt.Errorf("OCSP responder certificate is not authorized for OCSP signing")
+	}
+
+	now := time.Now()
+	if resp.ThisUpdate.After(now.Add(time.Minute)) {
+		return nil, fmt.Errorf("OCSP response is not yet valid")
+	}
+	if !resp.NextUpdate.IsZero() && resp.NextUpdate.Before(now) {
+		return nil, fmt.Errorf("OCSP response has expired")
+	}
+	if resp.Status != ocsp.Good && resp.Status != ocsp.Revoked {
+		return nil, fmt.Errorf("OCSP responder does not know the certificate")
+	}
+
+	return resp, nil
+}
+
+// verifyOCSPResponseWithResponders verifies an OCSP response signed by one of
+// the configured trusted responder certificates, or by a responder whose
+// certificate was issued by one of them.
+func verifyOCSPResponseWithResponders(body []byte, cert *x509.Certificate, responders []*x509.Certificate) (*ocsp.Response, error) {
+	if len(responders) == 0 {
+		return nil, fmt.Errorf("OCSP response is not signed by the certificate's issuer")
+	}
+
+	// Without an issuer only the signature of an embedded responder
+	// certificate is checked, so the signer is verified below.
+	resp, err := ocsp.ParseResponseForCert(body, cert, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing OCSP response: %w", err)
+	}
+
+	for _, responder := range responders {
+		if resp.Certificate != nil {
+			if resp.Certificate.Equal(responder) || resp.Certificate.CheckSignatureFrom(responder) == nil {
+				return resp, nil
+			}
+			continue
+		}
+		if resp.CheckSignatureFrom(responder) == nil {
+			return resp, nil
+		}
+	}
+
+	return nil, fmt.Errorf("OCSP response is not signed by the certificate's issuer or a trusted responder")
+}
+
+func hasOCSPSigningUsage(cert *x509.Certificate) bool {
+	for _, usage := range cert.ExtKeyUsage {
+		if usage == x509.ExtKeyUsageOCSPSigning {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCertCDPs checks a certificate against the CRLs of its distribution
+// points. At least one of them must be available.
+func (b *backend) checkCertCDPs(ctx context.Context, cert, issuer *x509.Certificate, gracePeriod time.Duration) error {
+	if len(cert.CRLDistributionPoints) == 0 {
+		return nil
+	}
+
+	var errs []error
+	var checked bool
+	for _, cdp := range cert.CRLDistributionPoints {
+		parsed, err := url.Parse(cdp)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+			continue
+		}
+
+		crl, err := b.cdpCRL(ctx, cdp, issuer, gracePeriod)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cdp, err))
+			continue
+		}
+		if _, ok := crl.serials[cert.SerialNumber.String()]; ok {
+			return errCertRevoked
+		}
+		checked = true
+	}
+
+	if !checked && len(errs) > 0 {
+		return fmt.Errorf("failed to check CRL distribution points of certificate %s: %v", cert.SerialNumber, errs)
+	}
+
+	return nil
+}
+
+// cdpCRL returns the CRL of a distribution point, fetching it when it isn't
+// cached or has expired. When fetching fails, a stale CRL is used until the
+// grace period past its next update time has elapsed.
+func (b *backend) cdpCRL(ctx context.Context, cdp string, issuer *x509.Certificate, gracePeriod time.Duration) (*cdpCacheEntry, error) {
+	var cached *cdpCacheEntry
+	now := time.Now()
+	if raw, ok := b.cdpCache.Get(cdp); ok {
+		cached = raw.(*cdpCacheEntry)
+		if now.Before(cached.expiresAt) {
+			return cached, checkCDPCRLSignature(cached.crl, issuer)
+		}
+	}
+
+	fetched, err := fetchCDPCRL(ctx, cdp)
+	if err != nil {
+		if cached != nil && now.Before(cached.expiresAt.Add(gracePeriod)) {
+			b.Logger().Warn("failed to refresh CRL from distribution point, using the previous CRL", "url", cdp, "error", err)
+			return cached, checkCDPCRLSignature(cached.crl, issuer)
+		}
+		return nil, err
+	}
+	if err := checkCDPCRLSignature(fetched.crl, issuer); err != nil {
+		return nil, err
+	}
+
+	b.cdpCache.Add(cdp, fetched)
+	return fetched, nil
+}
+
+func fetchCDPCRL(ctx context.Context, cdp string) (*cdpCacheEntry, error) {
+	body, err := fetchRevocationData(ctx, http.MethodGet, cdp, "", nil, maxCDPCRLSize)
+	if err != nil {
+		return nil, err
+	}
+
+	crl, err := x509.ParseCRL(body)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing CRL: %w", err)
+	}
+
+	entry := &cdpCacheEntry{
+		crl:       crl,
+		serials:   make(map[string]struct{}, len(crl.TBSCertList.RevokedCertificates)),
+		expiresAt: crl.TBSCertList.NextUpdate,
+	}
+	if entry.expiresAt.IsZero() {
+		entry.expiresAt = time.Now().Add(defaultCDPCacheTTL)
+	}
+	for _, revoked := range crl.TBSCertList.RevokedCertificates {
+		entry.serials[revoked.SerialNumber.String()] = struct{}{}
+	}
+
+	return entry, nil
+}
+
+func checkCDPCRLSignature(crl *pkix.CertificateList, issuer *x509.Certificate) error {
+	if err := issuer.CheckCRLSignature(crl); err != nil {
+		return fmt.Errorf("CRL is not signed by the certificate's issuer: %w", err)
+	}
+	return nil
+}
+
+// fetchRevocationData requests revocation data, such as an OCSP response or
+// a CRL, returning the response body.
+func fetchRevocationData(ctx context.Context, method, target, contentType string, body []byte, maxSize int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := revocationHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected response code %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, fmt.Errorf("response exceeds %d bytes", maxSize)
+	}
+
+	return data, nil
+}
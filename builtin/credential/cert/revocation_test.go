@@ -0,0 +1,258 @@
+package cert
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ocsp"
+)
+
+type testRevocationCA struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func newTestRevocationCA(t *testing.T, commonName string) *testRevocationCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: commonName},
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testRevocationCA{cert: cert, key: key}
+}
+
+func (ca *testRevocationCA) pem() string {
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}))
+}
+
+func (ca *testRevocationCA) issue(t *testing.T, serial int64, ocspServer, cdp string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "client.example.com"},
+		SerialNumber:          big.NewInt(serial),
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		OCSPServer:            []string{ocspServer},
+		CRLDistributionPoints: []string{cdp},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, key.Public(), ca.key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestCert_RevocationChecks(t *testing.T) {
+	ca := newTestRevocationCA(t, "Root CA")
+	untrustedResponder := newTestRevocationCA(t, "Responder")
+
+	const (
+		goodSerial    = 1001
+		revokedSerial = 1002
+		failingSerial = 1003
+	)
+
+	var lock sync.Mutex
+	ocspRequests := 0
+	responder := ca
+	ocspServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		req, err := ocsp.ParseRequest(body)
+		require.NoError(t, err)
+
+		lock.Lock()
+		defer lock.Unlock()
+		ocspRequests++
+
+		template := ocsp.Response{
+			SerialNumber: req.SerialNumber,
+			Status:       ocsp.Good,
+			ThisUpdate:   time.Now().Add(-time.Minute),
+			NextUpdate:   time.Now().Add(time.Hour),
+		}
+		switch req.SerialNumber.Int64() {
+		case revokedSerial:
+			template.Status = ocsp.Revoked
+			template.RevokedAt = time.Now().Add(-time.Minute)
+		case failingSerial:
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		resp, err := ocsp.CreateResponse(ca.cert, responder.cert, template, responder.key)
+		require.NoError(t, err)
+		w.Write(resp)
+	}))
+	defer ocspServer.Close()
+
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		RevokedCertificates: []pkix.RevokedCertificate{
+			{SerialNumber: big.NewInt(revokedSerial), RevocationTime: time.Now()},
+		},
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Minute),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, ca.cert, ca.key)
+	require.NoError(t, err)
+	crlServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(crl)
+	}))
+	defer crlServer.Close()
+
+	good := ca.issue(t, goodSerial, ocspServer.URL, crlServer.URL)
+	revoked := ca.issue(t, revokedSerial, ocspServer.URL, crlServer.URL)
+	failing := ca.issue(t, failingSerial, ocspServer.URL, crlServer.URL)
+
+	storage := &logical.InmemStorage{}
+	lb, err := Factory(context.Background(), &logical.BackendConfig{
+		System: &logical.StaticSystemView{
+			DefaultLeaseTTLVal: 300 * time.Second,
+			MaxLeaseTTLVal:     1800 * time.Second,
+		},
+		StorageView: storage,
+	})
+	require.NoError(t, err)
+	b := lb.(*backend)
+
+	writeRole := func(data map[string]interface{}) {
+		data["certificate"] = ca.pem()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "certs/web",
+			Storage:   storage,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		require.False(t, resp.IsError(), "error writing role: %v", resp)
+	}
+	login := func(cert *x509.Certificate) bool {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "login",
+			Storage:   storage,
+			Data:      map[string]interface{}{"name": "web"},
+			Connection: &logical.Connection{
+				ConnState: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}},
+			},
+		})
+		require.NoError(t, err)
+		return resp != nil && !resp.IsError() && resp.Auth != nil
+	}
+
+	// Without revocation checks all certificates are accepted.
+	writeRole(map[string]interface{}{})
+	require.True(t, login(revoked))
+
+	// OCSP checks fail closed by default.
+	writeRole(map[string]interface{}{"ocsp_enabled": true})
+	require.True(t, login(good))
+	require.False(t, login(revoked))
+	require.False(t, login(failing))
+
+	// OCSP responses are cached until their next update.
+	lock.Lock()
+	requests := ocspRequests
+	lock.Unlock()
+	require.True(t, login(good))
+	lock.Lock()
+	require.Equal(t, requests, ocspRequests)
+	lock.Unlock()
+
+	// Failing open accepts certificates whose status can't be determined,
+	// but still rejects revoked ones.
+	writeRole(map[string]interface{}{"ocsp_fail_open": true})
+	require.True(t, login(failing))
+	require.False(t, login(revoked))
+	writeRole(map[string]interface{}{"ocsp_fail_open": false})
+
+	// Responses signed by responders other than the issuer are only
+	// accepted when the responder is trusted.
+	b.ocspCache.Purge()
+	lock.Lock()
+	responder = untrustedResponder
+	lock.Unlock()
+	require.False(t, login(good))
+	writeRole(map[string]interface{}{"ocsp_ca_certificates": untrustedResponder.pem()})
+	require.True(t, login(good))
+
+	// The OCSP servers of certificates can be overridden, which isn't
+	// answered from responses cached for other servers.
+	writeRole(map[string]interface{}{"ocsp_servers_override": "http://127.0.0.1:1/ocsp"})
+	require.False(t, login(good))
+
+	// CRLs are fetched from the distribution points of certificates.
+	writeRole(map[string]interface{}{
+		"ocsp_enabled":                    false,
+		"crl_distribution_points_enabled": true,
+	})
+	require.True(t, login(good))
+	require.False(t, login(revoked))
+
+	// CRLs are cached and used while their distribution point is
+	// unavailable.
+	crlServer.Close()
+	require.False(t, login(revoked))
+	require.True(t, login(good))
+
+	// Past their next update time, CRLs are only used during the role's
+	// grace period.
+	for _, key := range b.cdpCache.Keys() {
+		cached, _ := b.cdpCache.Peek(key)
+		cached.(*cdpCacheEntry).expiresAt = time.Now().Add(-time.Minute)
+	}
+	require.False(t, login(good))
+	writeRole(map[string]interface{}{"crl_distribution_points_grace_period": "1h"})
+	require.True(t, login(good))
+	require.False(t, login(revoked))
+	b.cdpCache.Purge()
+	require.False(t, login(good))
+
+	// Invalid settings are rejected.
+	for _, data := range []map[string]interface{}{
+		{"ocsp_ca_certificates": "not a certificate"},
+		{"crl_distribution_points_grace_period": "-1h"},
+		// Revocation checks need the issuer of a certificate.
+		{
+			"certificate":  string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: good.Raw})),
+			"ocsp_enabled": true,
+		},
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "certs/web",
+			Storage:   storage,
+			Data:      data,
+		})
+		require.NoError(t, err)
+		require.True(t, resp.IsError(), "expected error writing %v", data)
+	}
+}
@@ -17,6 +17,10 @@ import (
 const (
 	mfaPushMethod = "push"
 	mfaTOTPMethod = "token:software:totp"
+
+	// oktaErrAuthenticationFailed is the error code Okta returns for
+	// authentication requests with invalid credentials.
+	oktaErrAuthenticationFailed = "E0000004"
 )
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -126,7 +130,11 @@ func (b *backend) Login(ctx context.Context, req *logical.Request, username, pas
 	rsp, err := shim.Do(authReq, &result)
 	if err != nil {
 		if oe, ok := err.(*okta.Error); ok {
-			return nil, logical.ErrorResponse("Okta auth failed: %v (code=%v)", err, oe.ErrorCode), nil, nil
+			resp := logical.ErrorResponse("Okta auth failed: %v (code=%v)", err, oe.ErrorCode)
+			if oe.ErrorCode == oktaErrAuthenticationFailed {
+				return nil, resp, nil, logical.ErrInvalidCredentials
+			}
+			return nil, resp, nil, nil
 		}
 		return nil, logical.ErrorResponse(fmt.Sprintf("Okta auth failed: %v", err)), nil, nil
 	}
@@ -88,9 +88,9 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 	defer b.verifyCache.Delete(nonce)
 
 	policies, resp, groupNames, err := b.Login(ctx, req, username, password, totp, nonce, preferredProvider)
-	// Handle an internal error
+	// Handle an internal error or invalid credentials
 	if err != nil {
-		return nil, err
+		return resp, err
 	}
 	if resp != nil {
 		// Handle a logical error
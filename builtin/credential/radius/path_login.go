@@ -97,9 +97,9 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 	}
 
 	policies, resp, err := b.RadiusLogin(ctx, req, username, password)
-	// Handle an internal error
+	// Handle an internal error or invalid credentials
 	if err != nil {
-		return nil, err
+		return resp, err
 	}
 	if resp != nil {
 		// Handle a logical error
@@ -196,7 +196,7 @@ func (b *backend) RadiusLogin(ctx context.Context, req *logical.Request, usernam
 		return nil, logical.ErrorResponse(err.Error()), nil
 	}
 	if received.Code != radius.CodeAccessAccept {
-		return nil, logical.ErrorResponse("access denied by the authentication server"), nil
+		return nil, logical.ErrorResponse("access denied by the authentication server"), logical.ErrInvalidCredentials
 	}
 
 	policies := cfg.UnregisteredUserPolicies
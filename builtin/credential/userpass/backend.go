@@ -27,11 +27,13 @@ func Backend() *backend {
 		},
 
 		Paths: []*framework.Path{
+			pathConfig(&b),
 			pathUsers(&b),
 			pathUsersList(&b),
 			pathUserPolicies(&b),
 			pathUserPassword(&b),
 			pathLogin(&b),
+			pathLoginPassword(&b),
 		},
 
 		AuthRenew:   b.pathLoginRenew,
@@ -52,5 +54,6 @@ are supported.
 
 The username/password combination is configured using the "users/"
 endpoints by a user with root access. Authentication is then done
-by supplying the two fields for "login".
+by supplying the two fields for "login". Users can change their own
+password through "login/<username>/password".
 `
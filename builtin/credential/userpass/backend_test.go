@@ -414,3 +414,247 @@ func TestBackend_UserUpgrade(t *testing.T) {
 		t.Fatal(diff)
 	}
 }
+
+// testUserpassSystemView validates passwords against a password policy
+// requiring at least 12 characters, and login MFA using a fixed passcode.
+type testUserpassSystemView struct {
+	logical.StaticSystemView
+	mfaPasscode *string
+}
+
+func (d testUserpassSystemView) ValidateLoginMFA(_ context.Context, req *logical.Request, aliasName string) error {
+	if d.mfaPasscode == nil || *d.mfaPasscode == "123456" {
+		return nil
+	}
+	return fmt.Errorf("login MFA validation failed")
+}
+
+func (d testUserpassSystemView) ValidatePasswordWithPolicy(_ context.Context, policyName string, password string) error {
+	if policyName != "long" {
+		return fmt.Errorf("no password policy found")
+	}
+	if len(password) < 12 {
+		return fmt.Errorf("must be at least 12 characters long")
+	}
+	return nil
+}
+
+func TestBackend_passwordRequirements(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	ctx := context.Background()
+
+	b, err := Factory(ctx, &logical.BackendConfig{
+		System: testUserpassSystemView{
+			StaticSystemView: logical.StaticSystemView{
+				DefaultLeaseTTLVal: testSysTTL,
+				MaxLeaseTTLVal:     testSysMaxTTL,
+			},
+		},
+		StorageView: storage,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create backend: %s", err)
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation:  operation,
+			Path:       path,
+			Storage:    storage,
+			Data:       data,
+			Connection: &logical.Connection{},
+		})
+		if err != nil && err != logical.ErrInvalidRequest && err != logical.ErrInvalidCredentials {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return resp
+	}
+	setPassword := func(password string) bool {
+		t.Helper()
+		resp := request(logical.UpdateOperation, "users/web/password", map[string]interface{}{"password": password})
+		return resp == nil || !resp.IsError()
+	}
+	login := func(password string) *logical.Response {
+		t.Helper()
+		return request(logical.UpdateOperation, "login/web", map[string]interface{}{"password": password})
+	}
+
+	resp := request(logical.UpdateOperation, "config", map[string]interface{}{
+		"password_policy":       "long",
+		"password_history_size": 2,
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Passwords must satisfy the password policy of the backend
+	resp = request(logical.CreateOperation, "users/web", map[string]interface{}{"password": "short"})
+	if resp == nil || !resp.IsError() {
+		t.Fatalf("expected short password to be rejected, got: %#v", resp)
+	}
+	resp = request(logical.CreateOperation, "users/web", map[string]interface{}{"password": "first-password"})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Recent passwords can't be reused
+	if setPassword("first-password") {
+		t.Fatal("expected current password to be rejected")
+	}
+	if !setPassword("second-password") {
+		t.Fatal("expected new password to be accepted")
+	}
+	if setPassword("first-password") {
+		t.Fatal("expected previous password to be rejected")
+	}
+	if !setPassword("third-password") {
+		t.Fatal("expected new password to be accepted")
+	}
+	if !setPassword("first-password") {
+		t.Fatal("expected password outside of the history to be accepted")
+	}
+
+	// Users can reference a password policy of their own
+	resp = request(logical.UpdateOperation, "users/web", map[string]interface{}{"password_policy": "missing"})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if setPassword("fourth-password") {
+		t.Fatal("expected password to be rejected by a missing password policy")
+	}
+	resp = request(logical.ReadOperation, "users/web", nil)
+	if resp.Data["password_policy"] != "missing" || resp.Data["password_last_updated"] == nil {
+		t.Fatalf("bad: %#v", resp.Data)
+	}
+	resp = request(logical.UpdateOperation, "users/web", map[string]interface{}{"password_policy": ""})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	// Expired passwords must be changed before logging in
+	resp = login("first-password")
+	if resp == nil || resp.Auth == nil {
+		t.Fatalf("expected login to succeed, got: %#v", resp)
+	}
+	resp = request(logical.UpdateOperation, "config", map[string]interface{}{"password_max_age": 1})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	resp = login("first-password")
+	if resp == nil || !resp.IsError() || resp.Auth != nil {
+		t.Fatalf("expected login with expired password to fail, got: %#v", resp)
+	}
+	if !setPassword("fifth-password") {
+		t.Fatal("expected new password to be accepted")
+	}
+	resp = login("fifth-password")
+	if resp == nil || resp.Auth == nil {
+		t.Fatalf("expected login to succeed, got: %#v", resp)
+	}
+
+	resp = request(logical.ReadOperation, "config", nil)
+	expected := map[string]interface{}{
+		"password_policy":       "long",
+		"password_history_size": 2,
+		"password_max_age":      int64(1),
+	}
+	if diff := deep.Equal(resp.Data, expected); diff != nil {
+		t.Fatal(diff)
+	}
+}
+
+func TestBackend_passwordChange(t *testing.T) {
+	storage := &logical.InmemStorage{}
+	ctx := context.Background()
+
+	mfaPasscode := ""
+	b, err := Factory(ctx, &logical.BackendConfig{
+		System: testUserpassSystemView{
+			StaticSystemView: logical.StaticSystemView{
+				DefaultLeaseTTLVal: testSysTTL,
+				MaxLeaseTTLVal:     testSysMaxTTL,
+			},
+			mfaPasscode: &mfaPasscode,
+		},
+		StorageView: storage,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create backend: %s", err)
+	}
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       path,
+			Storage:    storage,
+			Data:       data,
+			Connection: &logical.Connection{},
+		})
+	}
+	changePassword := func(current, newPassword string) (*logical.Response, error) {
+		return request("login/web/password", map[string]interface{}{
+			"password":     current,
+			"new_password": newPassword,
+		})
+	}
+
+	resp, err := request("users/web", map[string]interface{}{"password": "first-password"})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = request("config", map[string]interface{}{"password_policy": "long", "password_max_age": 1})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+
+	// The current password is required
+	resp, err = changePassword("wrong-password", "second-password")
+	if err != logical.ErrInvalidCredentials || !resp.IsError() {
+		t.Fatalf("expected invalid credentials, got resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = changePassword("first-password", "")
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected missing new password to be rejected, got resp: %#v\nerr: %v", resp, err)
+	}
+
+	// Login MFA must be satisfied
+	resp, err = changePassword("first-password", "second-password")
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected MFA failure, got resp: %#v\nerr: %v", resp, err)
+	}
+	mfaPasscode = "123456"
+
+	// New passwords must satisfy the password requirements
+	resp, err = changePassword("first-password", "short")
+	if err != logical.ErrInvalidRequest {
+		t.Fatalf("expected short password to be rejected, got resp: %#v\nerr: %v", resp, err)
+	}
+
+	// Expired passwords can be changed
+	time.Sleep(1100 * time.Millisecond)
+	resp, err = request("login/web", map[string]interface{}{"password": "first-password"})
+	if err != nil || !resp.IsError() {
+		t.Fatalf("expected expired password to be rejected, got resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = changePassword("first-password", "second-password")
+	if err != nil || resp != nil {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+	resp, err = request("login/web", map[string]interface{}{"password": "second-password"})
+	if err != nil || resp == nil || resp.Auth == nil {
+		t.Fatalf("expected login with the new password to succeed, got resp: %#v\nerr: %v", resp, err)
+	}
+
+	// The alias of the user is looked up for user lockout
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.AliasLookaheadOperation,
+		Path:      "login/web/password",
+		Storage:   storage,
+		Data:      map[string]interface{}{},
+	})
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.Alias.Name != "web" {
+		t.Fatalf("bad: resp: %#v\nerr: %v", resp, err)
+	}
+}
@@ -0,0 +1,130 @@
+package userpass
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// maxPasswordHistorySize bounds the number of previous password hashes kept
+// per user, as each of them is compared using bcrypt when a password is set.
+const maxPasswordHistorySize = 24
+
+func pathConfig(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config$",
+		Fields: map[string]*framework.FieldSchema{
+			"password_policy": {
+				Type:        framework.TypeString,
+				Description: "Name of the password policy new passwords must satisfy, unless a user references another one.",
+			},
+
+			"password_history_size": {
+				Type:        framework.TypeInt,
+				Description: fmt.Sprintf("Number of recent passwords of each user, including the current one, which can't be reused. Must be between 0 and %d.", maxPasswordHistorySize),
+			},
+
+			"password_max_age": {
+				Type:        framework.TypeDurationSecond,
+				Description: "Duration after which a password expires and must be changed before the user can log in again. Zero disables expiry.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigRead,
+			logical.UpdateOperation: b.pathConfigWrite,
+		},
+
+		HelpSynopsis:    pathConfigHelpSyn,
+		HelpDescription: pathConfigHelpDesc,
+	}
+}
+
+func (b *backend) config(ctx context.Context, s logical.Storage) (*ConfigEntry, error) {
+	entry, err := s.Get(ctx, "config")
+	if err != nil {
+		return nil, err
+	}
+
+	var result ConfigEntry
+	if entry == nil {
+		return &result, nil
+	}
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (b *backend) pathConfigRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"password_policy":       config.PasswordPolicy,
+			"password_history_size": config.PasswordHistorySize,
+			"password_max_age":      int64(config.PasswordMaxAge.Seconds()),
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if passwordPolicy, ok := d.GetOk("password_policy"); ok {
+		config.PasswordPolicy = passwordPolicy.(string)
+	}
+	if historySize, ok := d.GetOk("password_history_size"); ok {
+		config.PasswordHistorySize = historySize.(int)
+		if config.PasswordHistorySize < 0 || config.PasswordHistorySize > maxPasswordHistorySize {
+			return logical.ErrorResponse("password_history_size must be between 0 and %d", maxPasswordHistorySize), logical.ErrInvalidRequest
+		}
+	}
+	if maxAge, ok := d.GetOk("password_max_age"); ok {
+		config.PasswordMaxAge = time.Duration(maxAge.(int)) * time.Second
+		if config.PasswordMaxAge < 0 {
+			return logical.ErrorResponse("password_max_age must not be negative"), logical.ErrInvalidRequest
+		}
+	}
+
+	entry, err := logical.StorageEntryJSON("config", config)
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, req.Storage.Put(ctx, entry)
+}
+
+type ConfigEntry struct {
+	// PasswordPolicy is the name of the password policy new passwords must
+	// satisfy, unless overridden by the user.
+	PasswordPolicy string `json:"password_policy"`
+
+	// PasswordHistorySize is the number of recent passwords of each user,
+	// including the current one, which can't be reused.
+	PasswordHistorySize int `json:"password_history_size"`
+
+	// PasswordMaxAge is the duration after which passwords expire.
+	PasswordMaxAge time.Duration `json:"password_max_age"`
+}
+
+const pathConfigHelpSyn = `
+Configure password requirements of the userpass backend.
+`
+
+const pathConfigHelpDesc = `
+This endpoint configures the password policy new passwords must satisfy, the
+number of recent passwords of each user which can't be reused, and the age
+after which passwords expire. Users whose password has expired can't log in
+until their password is changed.
+`
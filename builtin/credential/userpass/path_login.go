@@ -5,6 +5,7 @@ import (
 	"crypto/subtle"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/helper/cidrutil"
@@ -61,8 +62,47 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 		return nil, fmt.Errorf("missing password")
 	}
 
+	user, resp, err := b.verifyPassword(ctx, req.Storage, username, password)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := b.checkBoundCIDRs(req, user); err != nil {
+		return nil, err
+	}
+
+	// Reject expired passwords, which must be changed before logging in
+	if !user.PasswordLastUpdated.IsZero() {
+		config, err := b.config(ctx, req.Storage)
+		if err != nil {
+			return nil, err
+		}
+		if config.PasswordMaxAge > 0 && time.Now().After(user.PasswordLastUpdated.Add(config.PasswordMaxAge)) {
+			return logical.ErrorResponse("password has expired and must be changed"), nil
+		}
+	}
+
+	auth := &logical.Auth{
+		Metadata: map[string]string{
+			"username": username,
+		},
+		DisplayName: username,
+		Alias: &logical.Alias{
+			Name: username,
+		},
+	}
+	user.PopulateTokenAuth(auth)
+
+	return &logical.Response{
+		Auth: auth,
+	}, nil
+}
+
+// verifyPassword returns the user if the password matches, or an error
+// response if the username or password is invalid.
+func (b *backend) verifyPassword(ctx context.Context, s logical.Storage, username, password string) (*UserEntry, *logical.Response, error) {
 	// Get the user and validate auth
-	user, userError := b.user(ctx, req.Storage, username)
+	user, userError := b.user(ctx, s, username)
 
 	var userPassword []byte
 	var legacyPassword bool
@@ -92,54 +132,46 @@ func (b *backend) pathLogin(ctx context.Context, req *logical.Request, d *framew
 			// The failed login info of existing users alone are tracked as only
 			// existing user's failed login information is stored in storage for optimization
 			if user == nil || userError != nil {
-				return logical.ErrorResponse("invalid username or password"), nil
+				return nil, logical.ErrorResponse("invalid username or password"), nil
 			}
-			return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
+			return nil, logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 		}
 	default:
 		if subtle.ConstantTimeCompare(userPassword, passwordBytes) != 1 {
 			// The failed login info of existing users alone are tracked as only
 			// existing user's failed login information is stored in storage for optimization
 			if user == nil || userError != nil {
-				return logical.ErrorResponse("invalid username or password"), nil
+				return nil, logical.ErrorResponse("invalid username or password"), nil
 			}
-			return logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
+			return nil, logical.ErrorResponse("invalid username or password"), logical.ErrInvalidCredentials
 		}
 
 	}
 
 	if userError != nil {
-		return nil, userError
+		return nil, nil, userError
 	}
 	if user == nil {
-		return logical.ErrorResponse("invalid username or password"), nil
+		return nil, logical.ErrorResponse("invalid username or password"), nil
 	}
 
-	// Check for a CIDR match.
-	if len(user.TokenBoundCIDRs) > 0 {
-		if req.Connection == nil {
-			b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
-			return nil, logical.ErrPermissionDenied
-		}
-		if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, user.TokenBoundCIDRs) {
-			return nil, logical.ErrPermissionDenied
-		}
-	}
+	return user, nil, nil
+}
 
-	auth := &logical.Auth{
-		Metadata: map[string]string{
-			"username": username,
-		},
-		DisplayName: username,
-		Alias: &logical.Alias{
-			Name: username,
-		},
+// checkBoundCIDRs rejects requests from addresses outside of the token bound
+// CIDRs of the user.
+func (b *backend) checkBoundCIDRs(req *logical.Request, user *UserEntry) error {
+	if len(user.TokenBoundCIDRs) == 0 {
+		return nil
 	}
-	user.PopulateTokenAuth(auth)
-
-	return &logical.Response{
-		Auth: auth,
-	}, nil
+	if req.Connection == nil {
+		b.Logger().Warn("token bound CIDRs found but no connection information available for validation")
+		return logical.ErrPermissionDenied
+	}
+	if !cidrutil.RemoteAddrIsOk(req.Connection.RemoteAddr, user.TokenBoundCIDRs) {
+		return logical.ErrPermissionDenied
+	}
+	return nil
 }
 
 func (b *backend) pathLoginRenew(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -0,0 +1,99 @@
+package userpass
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathLoginPassword(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "login/" + framework.GenericNameRegex("username") + "/password$",
+		Fields: map[string]*framework.FieldSchema{
+			"username": {
+				Type:        framework.TypeString,
+				Description: "Username of the user.",
+			},
+
+			"password": {
+				Type:        framework.TypeString,
+				Description: "Current password of the user.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+
+			"new_password": {
+				Type:        framework.TypeString,
+				Description: "New password for the user.",
+				DisplayAttrs: &framework.DisplayAttributes{
+					Sensitive: true,
+				},
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation:         b.pathLoginPasswordUpdate,
+			logical.AliasLookaheadOperation: b.pathLoginAliasLookahead,
+		},
+
+		HelpSynopsis:    pathLoginPasswordHelpSyn,
+		HelpDescription: pathLoginPasswordHelpDesc,
+	}
+}
+
+func (b *backend) pathLoginPasswordUpdate(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	username := strings.ToLower(d.Get("username").(string))
+
+	password := d.Get("password").(string)
+	if password == "" {
+		return logical.ErrorResponse("missing password"), logical.ErrInvalidRequest
+	}
+	newPassword := d.Get("new_password").(string)
+	if newPassword == "" {
+		return logical.ErrorResponse("missing new_password"), logical.ErrInvalidRequest
+	}
+
+	user, resp, err := b.verifyPassword(ctx, req.Storage, username, password)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	if err := b.checkBoundCIDRs(req, user); err != nil {
+		return nil, err
+	}
+
+	// Require the MFA the user would have to satisfy to log in
+	validator, ok := b.System().(logical.LoginMFAValidator)
+	if !ok {
+		return nil, fmt.Errorf("login MFA can't be enforced by this system view")
+	}
+	if err := validator.ValidateLoginMFA(ctx, req, username); err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrPermissionDenied
+	}
+
+	userErr, intErr := b.updateUserPassword(ctx, req, newPassword, user)
+	if intErr != nil {
+		return nil, intErr
+	}
+	if userErr != nil {
+		return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
+	}
+
+	return nil, b.setUser(ctx, req.Storage, username, user)
+}
+
+const pathLoginPasswordHelpSyn = `
+Change the password of a user, authenticating with the current password.
+`
+
+const pathLoginPasswordHelpDesc = `
+This endpoint lets users change their own password without a token, including
+a password which has expired. The current password must be given, and the
+login MFA enforced for the user must be satisfied by supplying MFA credentials
+with the request. The new password must satisfy the same requirements as
+passwords set through the "users/" endpoints.
+`
@@ -3,6 +3,7 @@ package userpass
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 
@@ -45,9 +46,9 @@ func (b *backend) pathUserPasswordUpdate(ctx context.Context, req *logical.Reque
 		return nil, fmt.Errorf("username does not exist")
 	}
 
-	userErr, intErr := b.updateUserPassword(req, d, userEntry)
+	userErr, intErr := b.updateUserPassword(ctx, req, d.Get("password").(string), userEntry)
 	if intErr != nil {
-		return nil, err
+		return nil, intErr
 	}
 	if userErr != nil {
 		return logical.ErrorResponse(userErr.Error()), logical.ErrInvalidRequest
@@ -56,17 +57,57 @@ func (b *backend) pathUserPasswordUpdate(ctx context.Context, req *logical.Reque
 	return nil, b.setUser(ctx, req.Storage, username, userEntry)
 }
 
-func (b *backend) updateUserPassword(req *logical.Request, d *framework.FieldData, userEntry *UserEntry) (error, error) {
-	password := d.Get("password").(string)
+func (b *backend) updateUserPassword(ctx context.Context, req *logical.Request, password string, userEntry *UserEntry) (error, error) {
 	if password == "" {
 		return fmt.Errorf("missing password"), nil
 	}
+
+	config, err := b.config(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordPolicy := userEntry.PasswordPolicy
+	if passwordPolicy == "" {
+		passwordPolicy = config.PasswordPolicy
+	}
+	if passwordPolicy != "" {
+		validator, ok := b.System().(logical.PasswordPolicyValidator)
+		if !ok {
+			return nil, fmt.Errorf("password policies are not supported by this system view")
+		}
+		if err := validator.ValidatePasswordWithPolicy(ctx, passwordPolicy, password); err != nil {
+			return fmt.Errorf("password rejected by password policy %q: %w", passwordPolicy, err), nil
+		}
+	}
+
+	// Reject the current password and the ones in the history
+	if config.PasswordHistorySize > 0 {
+		passwordBytes := []byte(password)
+		previous := userEntry.PasswordHistory
+		if userEntry.PasswordHash != nil {
+			previous = append([][]byte{userEntry.PasswordHash}, previous...)
+		}
+		if len(previous) > config.PasswordHistorySize {
+			previous = previous[:config.PasswordHistorySize]
+		}
+		for _, hash := range previous {
+			if bcrypt.CompareHashAndPassword(hash, passwordBytes) == nil {
+				return fmt.Errorf("password was used recently and can't be reused"), nil
+			}
+		}
+		userEntry.PasswordHistory = previous
+	} else {
+		userEntry.PasswordHistory = nil
+	}
+
 	// Generate a hash of the password
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return nil, err
 	}
 	userEntry.PasswordHash = hash
+	userEntry.PasswordLastUpdated = time.Now().UTC()
 	return nil, nil
 }
 
@@ -75,5 +116,7 @@ Reset user's password.
 `
 
 const pathUserPasswordHelpDesc = `
-This endpoint allows resetting the user's password.
+This endpoint allows resetting the user's password. The new password must
+satisfy the password policy of the user or of the backend, and can't be one
+of the user's recent passwords when a password history is configured.
 `
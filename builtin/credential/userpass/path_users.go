@@ -46,6 +46,11 @@ func pathUsers(b *backend) *framework.Path {
 				},
 			},
 
+			"password_policy": {
+				Type:        framework.TypeString,
+				Description: "Name of the password policy the user's passwords must satisfy, overriding the one configured for the backend.",
+			},
+
 			"policies": {
 				Type:        framework.TypeCommaStringSlice,
 				Description: tokenutil.DeprecationText("token_policies"),
@@ -187,6 +192,11 @@ func (b *backend) pathUserRead(ctx context.Context, req *logical.Request, d *fra
 		data["bound_cidrs"] = user.BoundCIDRs
 	}
 
+	data["password_policy"] = user.PasswordPolicy
+	if !user.PasswordLastUpdated.IsZero() {
+		data["password_last_updated"] = user.PasswordLastUpdated.Format(time.RFC3339)
+	}
+
 	return &logical.Response{
 		Data: data,
 	}, nil
@@ -207,8 +217,12 @@ func (b *backend) userCreateUpdate(ctx context.Context, req *logical.Request, d
 		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
 	}
 
+	if passwordPolicy, ok := d.GetOk("password_policy"); ok {
+		userEntry.PasswordPolicy = passwordPolicy.(string)
+	}
+
 	if _, ok := d.GetOk("password"); ok {
-		userErr, intErr := b.updateUserPassword(req, d, userEntry)
+		userErr, intErr := b.updateUserPassword(ctx, req, d.Get("password").(string), userEntry)
 		if intErr != nil {
 			return nil, intErr
 		}
@@ -258,6 +272,18 @@ type UserEntry struct {
 	// used instead of the actual password in Vault 0.2+.
 	PasswordHash []byte
 
+	// PasswordHistory holds bcrypt hashes of the user's previous passwords,
+	// most recent first, to prevent their reuse.
+	PasswordHistory [][]byte
+
+	// PasswordLastUpdated is when the password was last set. It is zero for
+	// passwords set before it was tracked, which don't expire.
+	PasswordLastUpdated time.Time
+
+	// PasswordPolicy is the name of the password policy the user's
+	// passwords must satisfy, overriding the one of the backend.
+	PasswordPolicy string
+
 	Policies []string
 
 	// Duration after which the user will be revoked unless renewed
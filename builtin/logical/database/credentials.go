@@ -5,15 +5,33 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/helper/random"
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/template"
 	"github.com/mitchellh/mapstructure"
 )
 
+const (
+	// defaultCommonNameTemplate is the template used to generate the common
+	// name of client certificates, which database plugins use to name the
+	// user authenticating with the certificate. It is truncated to fit the
+	// identifiers of PostgreSQL, which are shorter than the 64 characters
+	// allowed in common names.
+	defaultCommonNameTemplate = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 8) (.RoleName | truncate 8) (random 20) (unix_time) | truncate 63 }}`
+
+	// clientCertificateNotBeforeDuration is how far in the past client
+	// certificates are made valid from, to allow for clock skew.
+	clientCertificateNotBeforeDuration = 30 * time.Second
+)
+
 // passwordGenerator generates password credentials.
 // A zero value passwordGenerator is usable.
 type passwordGenerator struct {
@@ -167,3 +185,194 @@ func (kg rsaKeyGenerator) configMap() (map[string]interface{}, error) {
 	}
 	return config, nil
 }
+
+// clientCertificateGenerator generates client certificate credentials, issued
+// by the CA of its configuration.
+type clientCertificateGenerator struct {
+	// CommonNameTemplate is the template used to generate the common name of
+	// the client certificate. Defaults to defaultCommonNameTemplate.
+	CommonNameTemplate string `mapstructure:"common_name_template,omitempty"`
+
+	// CACert is the PEM-encoded certificate of the CA issuing the client
+	// certificates.
+	CACert string `mapstructure:"ca_cert,omitempty"`
+
+	// CAPrivateKey is the PEM-encoded private key of the given CA certificate.
+	CAPrivateKey string `mapstructure:"ca_private_key,omitempty"`
+
+	// KeyType is the type of key to generate.
+	// Options include: 'rsa' (default), 'ec', and 'ed25519'
+	KeyType string `mapstructure:"key_type,omitempty"`
+
+	// KeyBits is the bit size of the key to generate.
+	// Options include: 2048 (default), 3072, and 4096 for 'rsa' keys, and
+	// 224, 256 (default), 384, and 521 for 'ec' keys. Ignored for 'ed25519'.
+	KeyBits int `mapstructure:"key_bits,omitempty"`
+
+	// SignatureBits is the bit size of the hash used in the signature of the
+	// certificate. Options include: 256 (default), 384, and 512
+	SignatureBits int `mapstructure:"signature_bits,omitempty"`
+
+	cnProducer template.StringTemplate
+	caBundle   *certutil.ParsedCertBundle
+}
+
+// newClientCertificateGenerator returns a new clientCertificateGenerator using
+// the given config. Default values will be set on the returned
+// clientCertificateGenerator if not provided in the given config.
+func newClientCertificateGenerator(config map[string]interface{}) (clientCertificateGenerator, error) {
+	var cg clientCertificateGenerator
+	if err := mapstructure.WeakDecode(config, &cg); err != nil {
+		return cg, err
+	}
+
+	switch strings.ToLower(cg.KeyType) {
+	case "", "rsa":
+		cg.KeyType = "rsa"
+		switch cg.KeyBits {
+		case 0:
+			cg.KeyBits = 2048
+		case 2048, 3072, 4096:
+		default:
+			return cg, fmt.Errorf("invalid key_bits: %v", cg.KeyBits)
+		}
+	case "ec":
+		cg.KeyType = "ec"
+		switch cg.KeyBits {
+		case 0:
+			cg.KeyBits = 256
+		case 224, 256, 384, 521:
+		default:
+			return cg, fmt.Errorf("invalid key_bits: %v", cg.KeyBits)
+		}
+	case "ed25519":
+		cg.KeyType = "ed25519"
+		cg.KeyBits = 0
+	default:
+		return cg, fmt.Errorf("invalid key_type: %v", cg.KeyType)
+	}
+
+	switch cg.SignatureBits {
+	case 0:
+		cg.SignatureBits = 256
+	case 256, 384, 512:
+	default:
+		return cg, fmt.Errorf("invalid signature_bits: %v", cg.SignatureBits)
+	}
+
+	if cg.CommonNameTemplate == "" {
+		cg.CommonNameTemplate = defaultCommonNameTemplate
+	}
+	cnProducer, err := template.NewTemplate(template.Template(cg.CommonNameTemplate))
+	if err != nil {
+		return cg, fmt.Errorf("unable to initialize common_name_template: %w", err)
+	}
+	if _, err := cnProducer.Generate(v5.UsernameMetadata{}); err != nil {
+		return cg, fmt.Errorf("invalid common_name_template: %w", err)
+	}
+	cg.cnProducer = cnProducer
+
+	if cg.CACert == "" {
+		return cg, fmt.Errorf("missing ca_cert")
+	}
+	if cg.CAPrivateKey == "" {
+		return cg, fmt.Errorf("missing ca_private_key")
+	}
+	caBundle, err := certutil.ParsePEMBundle(cg.CACert + "\n" + cg.CAPrivateKey)
+	if err != nil {
+		return cg, fmt.Errorf("failed to parse CA: %w", err)
+	}
+	switch {
+	case caBundle.Certificate == nil:
+		return cg, fmt.Errorf("no certificate found in ca_cert")
+	case caBundle.PrivateKey == nil || caBundle.PrivateKeyType == certutil.UnknownPrivateKey:
+		return cg, fmt.Errorf("no private key found in ca_private_key")
+	case !caBundle.Certificate.BasicConstraintsValid || !caBundle.Certificate.IsCA:
+		return cg, fmt.Errorf("ca_cert is not marked for CA use")
+	}
+	if err := caBundle.Verify(); err != nil {
+		return cg, fmt.Errorf("ca_private_key does not match ca_cert: %w", err)
+	}
+	cg.caBundle = caBundle
+
+	return cg, nil
+}
+
+// generate issues a client certificate for a user valid until the given
+// expiration. Returns the issued certificate bundle and the subject
+// distinguished name of the certificate or an error.
+func (cg *clientCertificateGenerator) generate(r io.Reader, expiration time.Time, usernameConfig v5.UsernameMetadata) (*certutil.CertBundle, string, error) {
+	if cg.caBundle == nil {
+		return nil, "", fmt.Errorf("client certificate generator is not initialized")
+	}
+
+	if expiration.After(cg.caBundle.Certificate.NotAfter) {
+		return nil, "", fmt.Errorf("the certificate would expire after the CA certificate on %s", cg.caBundle.Certificate.NotAfter.Format(time.RFC3339))
+	}
+
+	commonName, err := cg.cnProducer.Generate(usernameConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	if commonName == "" {
+		return nil, "", fmt.Errorf("common_name_template generated an empty common name")
+	}
+
+	subject := pkix.Name{
+		CommonName: commonName,
+	}
+
+	creation := &certutil.CreationBundle{
+		Params: &certutil.CreationParameters{
+			Subject:           subject,
+			KeyType:           cg.KeyType,
+			KeyBits:           cg.KeyBits,
+			SignatureBits:     cg.SignatureBits,
+			NotAfter:          expiration,
+			NotBeforeDuration: clientCertificateNotBeforeDuration,
+			KeyUsage:          x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageKeyAgreement,
+			ExtKeyUsage:       certutil.ClientAuthExtKeyUsage,
+			URLs:              &certutil.URLEntries{},
+		},
+		SigningBundle: &certutil.CAInfoBundle{
+			ParsedCertBundle: *cg.caBundle,
+			URLs:             &certutil.URLEntries{},
+		},
+	}
+
+	parsedBundle, err := certutil.CreateCertificateWithRandomSource(creation, r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to issue client certificate: %w", err)
+	}
+
+	bundle, err := parsedBundle.ToCertBundle()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode client certificate: %w", err)
+	}
+
+	return bundle, subject.String(), nil
+}
+
+// configMap returns the configuration of the clientCertificateGenerator
+// as a map from string to string.
+func (cg clientCertificateGenerator) configMap() (map[string]interface{}, error) {
+	config := make(map[string]interface{})
+	if err := mapstructure.WeakDecode(cg, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// redactConfigMap returns the given configuration of a
+// clientCertificateGenerator as returned on reads, without the private key
+// of the CA.
+func (clientCertificateGenerator) redactConfigMap(config map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(config))
+	for k, v := range config {
+		if k == "ca_private_key" {
+			continue
+		}
+		redacted[k] = v
+	}
+	return redacted
+}
@@ -2,13 +2,21 @@ package database
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/pem"
+	"math/big"
 	"testing"
+	"time"
 
+	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
 	"github.com/hashicorp/vault/sdk/helper/base62"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/template"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -541,3 +549,349 @@ func Test_rsaKeyGenerator_configMap(t *testing.T) {
 		})
 	}
 }
+
+func Test_newClientCertificateGenerator(t *testing.T) {
+	caCert, caKey := testClientCertificateCA(t, time.Hour)
+	leafCert, leafKey := testClientCertificateLeaf(t)
+	_, otherKey := testClientCertificateCA(t, time.Hour)
+
+	type args struct {
+		config map[string]interface{}
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    clientCertificateGenerator
+		wantErr bool
+	}{
+		{
+			name: "newClientCertificateGenerator with CA and default config",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+				},
+			},
+			want: clientCertificateGenerator{
+				CommonNameTemplate: defaultCommonNameTemplate,
+				CACert:             caCert,
+				CAPrivateKey:       caKey,
+				KeyType:            "rsa",
+				KeyBits:            2048,
+				SignatureBits:      256,
+			},
+		},
+		{
+			name: "newClientCertificateGenerator with ec key_type",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"key_type":       "ec",
+					"signature_bits": "384",
+				},
+			},
+			want: clientCertificateGenerator{
+				CommonNameTemplate: defaultCommonNameTemplate,
+				CACert:             caCert,
+				CAPrivateKey:       caKey,
+				KeyType:            "ec",
+				KeyBits:            256,
+				SignatureBits:      384,
+			},
+		},
+		{
+			name: "newClientCertificateGenerator with ed25519 key_type ignores key_bits",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"key_type":       "ed25519",
+					"key_bits":       "4096",
+				},
+			},
+			want: clientCertificateGenerator{
+				CommonNameTemplate: defaultCommonNameTemplate,
+				CACert:             caCert,
+				CAPrivateKey:       caKey,
+				KeyType:            "ed25519",
+				KeyBits:            0,
+				SignatureBits:      256,
+			},
+		},
+		{
+			name: "newClientCertificateGenerator with common_name_template",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":              caCert,
+					"ca_private_key":       caKey,
+					"common_name_template": "{{ .RoleName }}",
+				},
+			},
+			want: clientCertificateGenerator{
+				CommonNameTemplate: "{{ .RoleName }}",
+				CACert:             caCert,
+				CAPrivateKey:       caKey,
+				KeyType:            "rsa",
+				KeyBits:            2048,
+				SignatureBits:      256,
+			},
+		},
+		{
+			name: "newClientCertificateGenerator with nil config",
+			args: args{
+				config: nil,
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator without ca_private_key",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert": caCert,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator with mismatched ca_private_key",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": otherKey,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator with non-CA certificate",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        leafCert,
+					"ca_private_key": leafKey,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator with invalid key_type",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"key_type":       "dsa",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator with invalid key_bits",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"key_type":       "ec",
+					"key_bits":       "2048",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator with invalid signature_bits",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"signature_bits": "128",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "newClientCertificateGenerator with invalid common_name_template",
+			args: args{
+				config: map[string]interface{}{
+					"ca_cert":              caCert,
+					"ca_private_key":       caKey,
+					"common_name_template": "{{ .RoleName",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newClientCertificateGenerator(tt.args.config)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotNil(t, got.caBundle)
+
+			// Only compare the exported configuration
+			got.cnProducer = template.StringTemplate{}
+			got.caBundle = nil
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_clientCertificateGenerator_generate(t *testing.T) {
+	caCert, caKey := testClientCertificateCA(t, time.Hour)
+
+	type args struct {
+		config map[string]interface{}
+	}
+	tests := []struct {
+		name string
+		args args
+	}{
+		{
+			name: "generate client certificate with default config",
+			args: args{
+				config: map[string]interface{}{},
+			},
+		},
+		{
+			name: "generate client certificate with ec key_type",
+			args: args{
+				config: map[string]interface{}{
+					"key_type": "ec",
+					"key_bits": "384",
+				},
+			},
+		},
+		{
+			name: "generate client certificate with ed25519 key_type",
+			args: args{
+				config: map[string]interface{}{
+					"key_type": "ed25519",
+				},
+			},
+		},
+		{
+			name: "generate client certificate with common_name_template",
+			args: args{
+				config: map[string]interface{}{
+					"common_name_template": "{{ .DisplayName }}-{{ .RoleName }}",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.args.config["ca_cert"] = caCert
+			tt.args.config["ca_private_key"] = caKey
+			cg, err := newClientCertificateGenerator(tt.args.config)
+			assert.NoError(t, err)
+
+			usernameConfig := v5.UsernameMetadata{
+				DisplayName: "token",
+				RoleName:    "test-role",
+			}
+			expiration := time.Now().Add(10 * time.Minute)
+			bundle, subject, err := cg.generate(rand.Reader, expiration, usernameConfig)
+			assert.NoError(t, err)
+			assert.NotNil(t, bundle)
+
+			parsed, err := bundle.ToParsedCertBundle()
+			assert.NoError(t, err)
+			cert := parsed.Certificate
+
+			// Assert that the certificate is issued by the CA for client
+			// authentication with the returned subject
+			assert.NoError(t, cert.CheckSignatureFrom(cg.caBundle.Certificate))
+			assert.Equal(t, subject, cert.Subject.String())
+			assert.NotEmpty(t, cert.Subject.CommonName)
+			assert.LessOrEqual(t, len(cert.Subject.CommonName), 63)
+			assert.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, cert.ExtKeyUsage)
+			assert.WithinDuration(t, expiration, cert.NotAfter, time.Second)
+			if cg.CommonNameTemplate != defaultCommonNameTemplate {
+				assert.Equal(t, "token-test-role", cert.Subject.CommonName)
+			}
+
+			// Assert that the private key matches the certificate and the
+			// configured key type
+			assert.NoError(t, parsed.Verify())
+			assert.Equal(t, bundle.PrivateKeyType, certutil.PrivateKeyType(cg.KeyType))
+		})
+	}
+
+	t.Run("generate client certificate expiring after the CA", func(t *testing.T) {
+		cg, err := newClientCertificateGenerator(map[string]interface{}{
+			"ca_cert":        caCert,
+			"ca_private_key": caKey,
+		})
+		assert.NoError(t, err)
+
+		_, _, err = cg.generate(rand.Reader, time.Now().Add(2*time.Hour), v5.UsernameMetadata{})
+		assert.Error(t, err)
+	})
+}
+
+func Test_clientCertificateGenerator_configMap(t *testing.T) {
+	caCert, caKey := testClientCertificateCA(t, time.Hour)
+
+	cg, err := newClientCertificateGenerator(map[string]interface{}{
+		"ca_cert":        caCert,
+		"ca_private_key": caKey,
+		"key_type":       "ec",
+	})
+	assert.NoError(t, err)
+
+	got, err := cg.configMap()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"common_name_template": defaultCommonNameTemplate,
+		"ca_cert":              caCert,
+		"ca_private_key":       caKey,
+		"key_type":             "ec",
+		"key_bits":             256,
+		"signature_bits":       256,
+	}, got)
+}
+
+// testClientCertificateCA returns a PEM-encoded self-signed CA certificate
+// valid for the given duration along with its PEM-encoded private key.
+func testClientCertificateCA(t *testing.T, validity time.Duration) (string, string) {
+	t.Helper()
+	return testClientCertificateSelfSigned(t, validity, true)
+}
+
+// testClientCertificateLeaf returns a PEM-encoded self-signed certificate not
+// marked for CA use along with its PEM-encoded private key.
+func testClientCertificateLeaf(t *testing.T) (string, string) {
+	t.Helper()
+	return testClientCertificateSelfSigned(t, time.Hour, false)
+}
+
+func testClientCertificateSelfSigned(t *testing.T, validity time.Duration, isCA bool) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(validity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, key.Public(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return string(certPEM), string(keyPEM)
+}
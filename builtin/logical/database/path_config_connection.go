@@ -8,6 +8,7 @@ import (
 	"sort"
 
 	"github.com/fatih/structs"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/go-uuid"
 	"github.com/hashicorp/go-version"
 
@@ -54,6 +55,14 @@ func (c *DatabaseConfig) SupportsCredentialType(credentialType v5.CredentialType
 	return false
 }
 
+// SelfManaged returns whether the database plugin connects as the static
+// accounts it manages, with their own credentials, instead of with root
+// credentials of the configuration.
+func (c *DatabaseConfig) SelfManaged() bool {
+	selfManaged, err := parseutil.ParseBool(c.ConnectionDetails["self_managed"])
+	return err == nil && selfManaged
+}
+
 // pathResetConnection configures a path to reset a plugin.
 func pathResetConnection(b *databaseBackend) *framework.Path {
 	return &framework.Path{
@@ -154,6 +154,27 @@ func (b *databaseBackend) pathCredsCreateRead() framework.OperationFunc {
 
 			// Set output credential
 			respData["rsa_private_key"] = string(private)
+
+		case v5.CredentialTypeClientCertificate:
+			generator, err := newClientCertificateGenerator(role.CredentialConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to construct credential generator: %s", err)
+			}
+
+			// Issue the client certificate
+			bundle, subject, err := generator.generate(b.GetRandomReader(), expiration, newUserReq.UsernameConfig)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate client certificate: %s", err)
+			}
+
+			// Set input credential
+			newUserReq.CredentialType = v5.CredentialTypeClientCertificate
+			newUserReq.Subject = subject
+
+			// Set output credential
+			respData["client_certificate"] = bundle.Certificate
+			respData["private_key"] = bundle.PrivateKey
+			respData["private_key_type"] = bundle.PrivateKeyType
 		}
 
 		// Overwriting the password in the event this is a legacy database
@@ -210,9 +231,14 @@ func (b *databaseBackend) pathStaticCredsRead() framework.OperationFunc {
 		respData := map[string]interface{}{
 			"username":            role.StaticAccount.Username,
 			"ttl":                 role.StaticAccount.CredentialTTL().Seconds(),
-			"rotation_period":     role.StaticAccount.RotationPeriod.Seconds(),
 			"last_vault_rotation": role.StaticAccount.LastVaultRotation,
 		}
+		if role.StaticAccount.RotationSchedule != "" {
+			respData["rotation_schedule"] = role.StaticAccount.RotationSchedule
+			respData["rotation_window"] = role.StaticAccount.RotationWindow.Seconds()
+		} else {
+			respData["rotation_period"] = role.StaticAccount.RotationPeriod.Seconds()
+		}
 
 		switch role.CredentialType {
 		case v5.CredentialTypePassword:
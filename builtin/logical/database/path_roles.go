@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/hashicorp/cronexpr"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	v4 "github.com/hashicorp/vault/sdk/database/dbplugin"
@@ -92,7 +93,8 @@ func fieldsForType(roleType string) map[string]*framework.FieldSchema {
 		"credential_type": {
 			Type: framework.TypeString,
 			Description: "The type of credential to manage. Options include: " +
-				"'password', 'rsa_private_key'. Defaults to 'password'.",
+				"'password', 'rsa_private_key', 'client_certificate'. Defaults to 'password'. " +
+				"The 'client_certificate' type is only supported by dynamic roles.",
 			Default: "password",
 		},
 		"credential_config": {
@@ -167,13 +169,26 @@ func staticFields() map[string]*framework.FieldSchema {
 		"username": {
 			Type: framework.TypeString,
 			Description: `Name of the static user account for Vault to manage.
-	Requires "rotation_period" to be specified`,
+	Requires "rotation_period" or "rotation_schedule" to be specified`,
 		},
 		"rotation_period": {
 			Type: framework.TypeDurationSecond,
 			Description: `Period for automatic
 	credential rotation of the given username. Not valid unless used with
-	"username".`,
+	"username". Mutually exclusive with "rotation_schedule".`,
+		},
+		"rotation_schedule": {
+			Type: framework.TypeString,
+			Description: `Schedule for automatic credential rotation of the
+	given username, as a standard five field cron expression evaluated in UTC,
+	such as "0 2 * * SAT". Mutually exclusive with "rotation_period".`,
+		},
+		"rotation_window": {
+			Type: framework.TypeDurationSecond,
+			Description: `The amount of time after each scheduled time during
+	which a missed rotation may still be performed, for example while Vault was
+	sealed. Rotations missed for longer are deferred to the next scheduled time.
+	Only valid with "rotation_schedule". Defaults to no limit.`,
 		},
 		"rotation_statements": {
 			Type: framework.TypeStringSlice,
@@ -182,6 +197,16 @@ func staticFields() map[string]*framework.FieldSchema {
 	this functionality. See the plugin's API page for more information on
 	support and formatting for this parameter.`,
 		},
+		"self_managed_password": {
+			Type: framework.TypeString,
+			Description: `The current password of the static account, used to
+	connect to the database as the account to rotate its own password. Required
+	to create static roles of self-managed database connections, and invalid
+	otherwise. Only valid with the "password" credential type.`,
+			DisplayAttrs: &framework.DisplayAttributes{
+				Sensitive: true,
+			},
+		},
 	}
 	return fields
 }
@@ -270,14 +295,26 @@ func (b *databaseBackend) pathStaticRoleRead(ctx context.Context, req *logical.R
 	if role.StaticAccount != nil {
 		data["username"] = role.StaticAccount.Username
 		data["rotation_statements"] = role.Statements.Rotation
-		data["rotation_period"] = role.StaticAccount.RotationPeriod.Seconds()
+		// Whether the account manages itself is a property of its connection
+		dbConfig, err := b.DatabaseConfig(ctx, req.Storage, role.DBName)
+		if err != nil {
+			return nil, err
+		}
+		data["self_managed"] = dbConfig.SelfManaged()
+		if role.StaticAccount.RotationSchedule != "" {
+			data["rotation_schedule"] = role.StaticAccount.RotationSchedule
+			data["rotation_window"] = role.StaticAccount.RotationWindow.Seconds()
+		} else {
+			data["rotation_period"] = role.StaticAccount.RotationPeriod.Seconds()
+		}
 		if !role.StaticAccount.LastVaultRotation.IsZero() {
 			data["last_vault_rotation"] = role.StaticAccount.LastVaultRotation
+			data["next_vault_rotation"] = role.StaticAccount.NextRotationTime()
 		}
 	}
 
 	if len(role.CredentialConfig) > 0 {
-		data["credential_config"] = role.CredentialConfig
+		data["credential_config"] = role.credentialConfigResponse()
 	}
 	if len(role.Statements.Rotation) == 0 {
 		data["rotation_statements"] = []string{}
@@ -308,7 +345,7 @@ func (b *databaseBackend) pathRoleRead(ctx context.Context, req *logical.Request
 		"credential_type":       role.CredentialType.String(),
 	}
 	if len(role.CredentialConfig) > 0 {
-		data["credential_config"] = role.CredentialConfig
+		data["credential_config"] = role.credentialConfigResponse()
 	}
 	if len(role.Statements.Creation) == 0 {
 		data["creation_statements"] = []string{}
@@ -513,12 +550,17 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 	}
 	role.StaticAccount.Username = username
 
-	// If it's a Create operation, both username and rotation_period must be included
-	rotationPeriodSecondsRaw, ok := data.GetOk("rotation_period")
-	if !ok && createRole {
-		return logical.ErrorResponse("rotation_period is required to create static accounts"), nil
+	// If it's a Create operation, both username and either rotation_period or
+	// rotation_schedule must be included
+	rotationPeriodSecondsRaw, rotationPeriodOk := data.GetOk("rotation_period")
+	rotationScheduleRaw, rotationScheduleOk := data.GetOk("rotation_schedule")
+	if rotationPeriodOk && rotationScheduleOk {
+		return logical.ErrorResponse("mutually exclusive fields rotation_period and rotation_schedule were both specified; only one of them can be provided"), nil
 	}
-	if ok {
+	if !rotationPeriodOk && !rotationScheduleOk && createRole {
+		return logical.ErrorResponse("one of rotation_period or rotation_schedule is required to create static accounts"), nil
+	}
+	if rotationPeriodOk {
 		rotationPeriodSeconds := rotationPeriodSecondsRaw.(int)
 		if rotationPeriodSeconds < defaultQueueTickSeconds {
 			// If rotation frequency is specified, and this is an update, the value
@@ -527,6 +569,27 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 			return logical.ErrorResponse(fmt.Sprintf("rotation_period must be %d seconds or more", defaultQueueTickSeconds)), nil
 		}
 		role.StaticAccount.RotationPeriod = time.Duration(rotationPeriodSeconds) * time.Second
+		role.StaticAccount.RotationSchedule = ""
+		role.StaticAccount.RotationWindow = 0
+	}
+	if rotationScheduleOk {
+		rotationSchedule := rotationScheduleRaw.(string)
+		if _, err := parseRotationSchedule(rotationSchedule); err != nil {
+			return logical.ErrorResponse("invalid rotation_schedule: %s", err), nil
+		}
+		role.StaticAccount.RotationSchedule = rotationSchedule
+		role.StaticAccount.RotationPeriod = 0
+	}
+
+	if rotationWindowSecondsRaw, ok := data.GetOk("rotation_window"); ok {
+		if role.StaticAccount.RotationSchedule == "" {
+			return logical.ErrorResponse("rotation_window is only valid with rotation_schedule"), nil
+		}
+		rotationWindowSeconds := rotationWindowSecondsRaw.(int)
+		if rotationWindowSeconds != 0 && rotationWindowSeconds < minRotationWindowSeconds {
+			return logical.ErrorResponse(fmt.Sprintf("rotation_window must be %d seconds or more", minRotationWindowSeconds)), nil
+		}
+		role.StaticAccount.RotationWindow = time.Duration(rotationWindowSeconds) * time.Second
 	}
 
 	if rotationStmtsRaw, ok := data.GetOk("rotation_statements"); ok {
@@ -541,6 +604,9 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 			return logical.ErrorResponse(err.Error()), nil
 		}
 	}
+	if role.CredentialType == v5.CredentialTypeClientCertificate {
+		return logical.ErrorResponse("credential_type %q is not supported for static roles", role.CredentialType.String()), nil
+	}
 
 	var credentialConfig map[string]string
 	if raw, ok := data.GetOk("credential_config"); ok {
@@ -552,6 +618,32 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 		return logical.ErrorResponse("credential_config validation failed: %s", err), nil
 	}
 
+	dbConfig, err := b.DatabaseConfig(ctx, req.Storage, role.DBName)
+	if err != nil {
+		return nil, err
+	}
+	if dbConfig != nil {
+		// Static accounts of self-managed connections rotate their own
+		// password, connecting as themselves with their current password
+		selfManagedPassword, selfManagedPasswordOk := data.GetOk("self_managed_password")
+		selfManaged := dbConfig.SelfManaged()
+		switch {
+		case !selfManaged && selfManagedPasswordOk:
+			return logical.ErrorResponse("self_managed_password is only valid for self-managed database connections"), nil
+		case selfManaged && role.CredentialType != v5.CredentialTypePassword:
+			return logical.ErrorResponse("credential_type %q is not supported for self-managed static roles", role.CredentialType.String()), nil
+		case selfManaged && selfManagedPasswordOk:
+			if selfManagedPassword.(string) == "" {
+				return logical.ErrorResponse("self_managed_password cannot be empty"), nil
+			}
+			// Updating the password of an existing role lets Vault connect
+			// again after it was changed outside of Vault
+			role.StaticAccount.Password = selfManagedPassword.(string)
+		case selfManaged && role.StaticAccount.Password == "":
+			return logical.ErrorResponse("self_managed_password is required for static roles of self-managed database connections"), nil
+		}
+	}
+
 	// lvr represents the roles' LastVaultRotation
 	lvr := role.StaticAccount.LastVaultRotation
 
@@ -586,6 +678,15 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 			Key: name,
 		}
 	case logical.UpdateOperation:
+		// Scheduled rotations are computed from the time of the update, so that
+		// switching to a schedule does not cause a rotation at an unscheduled
+		// time
+		from := lvr
+		if now := time.Now(); role.StaticAccount.RotationSchedule != "" && now.After(from) {
+			from = now
+		}
+		role.StaticAccount.SetNextVaultRotation(from)
+
 		// store updated Role
 		entry, err := logical.StorageEntryJSON(databaseStaticRolePath+name, role)
 		if err != nil {
@@ -600,7 +701,7 @@ func (b *databaseBackend) pathStaticRoleCreateUpdate(ctx context.Context, req *l
 		}
 	}
 
-	item.Priority = lvr.Add(role.StaticAccount.RotationPeriod).Unix()
+	item.Priority = role.StaticAccount.NextRotationTime().Unix()
 
 	// Add their rotation to the queue
 	if err := b.pushItem(item); err != nil {
@@ -628,6 +729,8 @@ func (r *roleEntry) setCredentialType(credentialType string) error {
 		r.CredentialType = v5.CredentialTypePassword
 	case v5.CredentialTypeRSAPrivateKey.String():
 		r.CredentialType = v5.CredentialTypeRSAPrivateKey
+	case v5.CredentialTypeClientCertificate.String():
+		r.CredentialType = v5.CredentialTypeClientCertificate
 	default:
 		return fmt.Errorf("invalid credential_type %q", credentialType)
 	}
@@ -669,11 +772,41 @@ func (r *roleEntry) setCredentialConfig(config map[string]string) error {
 		if len(cm) > 0 {
 			r.CredentialConfig = cm
 		}
+	case v5.CredentialTypeClientCertificate:
+		// Keep the existing CA on updates that don't change the configuration,
+		// as it is never returned and so can't be written back.
+		if len(c) == 0 {
+			for k, v := range r.CredentialConfig {
+				c[k] = v
+			}
+		}
+		generator, err := newClientCertificateGenerator(c)
+		if err != nil {
+			return err
+		}
+		cm, err := generator.configMap()
+		if err != nil {
+			return err
+		}
+		if len(cm) > 0 {
+			r.CredentialConfig = cm
+		}
 	}
 
 	return nil
 }
 
+// credentialConfigResponse returns the credential configuration of the role
+// as returned on reads, redacted by the generator of its credential type.
+func (r *roleEntry) credentialConfigResponse() map[string]interface{} {
+	switch r.CredentialType {
+	case v5.CredentialTypeClientCertificate:
+		return clientCertificateGenerator{}.redactConfigMap(r.CredentialConfig)
+	default:
+		return r.CredentialConfig
+	}
+}
+
 type staticAccount struct {
 	// Username to create or assume management for static accounts
 	Username string `json:"username"`
@@ -698,15 +831,80 @@ type staticAccount struct {
 	// determine if a password needs to be rotated
 	RotationPeriod time.Duration `json:"rotation_period"`
 
+	// RotationSchedule is a cron expression for the times at which the
+	// password is rotated. It is used instead of RotationPeriod when set.
+	RotationSchedule string `json:"rotation_schedule"`
+
+	// RotationWindow is the amount of time after a scheduled rotation time
+	// during which the rotation can still be performed. Zero means the
+	// rotation is always performed, however late.
+	RotationWindow time.Duration `json:"rotation_window"`
+
+	// NextVaultRotation represents the next time Vault is expected to rotate
+	// the password
+	NextVaultRotation time.Time `json:"next_vault_rotation"`
+
 	// RevokeUser is a boolean flag to indicate if Vault should revoke the
 	// database user when the role is deleted
 	RevokeUserOnDelete bool `json:"revoke_user_on_delete"`
 }
 
-// NextRotationTime calculates the next rotation by adding the Rotation Period
-// to the last known vault rotation
+// NextRotationTime returns the next expected rotation. For roles stored before
+// it was recorded, it is calculated by adding the Rotation Period to the last
+// known vault rotation
 func (s *staticAccount) NextRotationTime() time.Time {
-	return s.LastVaultRotation.Add(s.RotationPeriod)
+	if s.NextVaultRotation.IsZero() {
+		return s.NextRotationTimeFromInput(s.LastVaultRotation)
+	}
+	return s.NextVaultRotation
+}
+
+// SetNextVaultRotation records the next expected rotation after the given
+// time
+func (s *staticAccount) SetNextVaultRotation(from time.Time) {
+	s.NextVaultRotation = s.NextRotationTimeFromInput(from)
+}
+
+// NextRotationTimeFromInput calculates the next rotation after the given
+// time. For scheduled roles it is the first scheduled time after the input,
+// otherwise the Rotation Period is added to the input.
+func (s *staticAccount) NextRotationTimeFromInput(input time.Time) time.Time {
+	if s.RotationSchedule == "" {
+		return input.Add(s.RotationPeriod)
+	}
+
+	// The schedule was validated when the role was written
+	schedule, err := parseRotationSchedule(s.RotationSchedule)
+	if err != nil {
+		return input.Add(defaultQueueTickSeconds * time.Second)
+	}
+	return schedule.Next(input.UTC())
+}
+
+// IsInsideRotationWindow returns whether a rotation scheduled at the given
+// time can still be performed at now.
+func (s *staticAccount) IsInsideRotationWindow(scheduled, now time.Time) bool {
+	if s.RotationSchedule == "" || s.RotationWindow == 0 {
+		return true
+	}
+	return now.Before(scheduled.Add(s.RotationWindow))
+}
+
+// parseRotationSchedule parses a standard five field cron expression, or one
+// of the predefined schedules such as "@daily".
+func parseRotationSchedule(schedule string) (*cronexpr.Expression, error) {
+	schedule = strings.TrimSpace(schedule)
+	if !strings.HasPrefix(schedule, "@") && len(strings.Fields(schedule)) != 5 {
+		return nil, fmt.Errorf("expected 5 fields: minute, hour, day of month, month and day of week")
+	}
+	expr, err := cronexpr.Parse(schedule)
+	if err != nil {
+		return nil, err
+	}
+	if expr.Next(time.Now().UTC()).IsZero() {
+		return nil, fmt.Errorf("schedule does not match any future time")
+	}
+	return expr, nil
 }
 
 // CredentialTTL calculates the approximate time remaining until the credential is
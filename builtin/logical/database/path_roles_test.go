@@ -12,6 +12,7 @@ import (
 	"github.com/hashicorp/vault/helper/namespace"
 	postgreshelper "github.com/hashicorp/vault/helper/testhelpers/postgresql"
 	v5 "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -25,6 +26,7 @@ func TestBackend_Roles_CredentialTypes(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	caCert, caKey := testClientCertificateCA(t, time.Hour)
 
 	type args struct {
 		credentialType   v5.CredentialType
@@ -159,6 +161,46 @@ func TestBackend_Roles_CredentialTypes(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "role with client_certificate credential type and CA configuration",
+			args: args{
+				credentialType: v5.CredentialTypeClientCertificate,
+				credentialConfig: map[string]string{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"key_type":       "ec",
+				},
+			},
+			expectedResp: map[string]interface{}{
+				"credential_type": v5.CredentialTypeClientCertificate.String(),
+				"credential_config": map[string]interface{}{
+					"common_name_template": defaultCommonNameTemplate,
+					"ca_cert":              caCert,
+					"key_type":             "ec",
+					"key_bits":             json.Number("256"),
+					"signature_bits":       json.Number("256"),
+				},
+			},
+		},
+		{
+			name: "role with client_certificate credential type without CA configuration",
+			args: args{
+				credentialType: v5.CredentialTypeClientCertificate,
+			},
+			wantErr: true,
+		},
+		{
+			name: "role with client_certificate credential type invalid key_type configuration",
+			args: args{
+				credentialType: v5.CredentialTypeClientCertificate,
+				credentialConfig: map[string]string{
+					"ca_cert":        caCert,
+					"ca_private_key": caKey,
+					"key_type":       "dsa",
+				},
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -201,6 +243,58 @@ func TestBackend_Roles_CredentialTypes(t *testing.T) {
 	}
 }
 
+func TestBackend_Roles_ClientCertificateConfigRedacted(t *testing.T) {
+	ctx := context.Background()
+	config := logical.TestBackendConfig()
+	config.System = logical.TestSystemView()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, caKey := testClientCertificateCA(t, time.Hour)
+
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+		Data: map[string]interface{}{
+			"db_name":             "test-database",
+			"creation_statements": "CREATE USER {{name}}",
+			"credential_type":     v5.CredentialTypeClientCertificate.String(),
+			"credential_config": map[string]string{
+				"ca_cert":        caCert,
+				"ca_private_key": caKey,
+			},
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	// The private key of the CA is never returned
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "roles/test",
+		Storage:   config.StorageView,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+	credentialConfig := resp.Data["credential_config"].(map[string]interface{})
+	if _, ok := credentialConfig["ca_private_key"]; ok {
+		t.Fatalf("expected ca_private_key to be redacted, got %#v", credentialConfig)
+	}
+	assert.Equal(t, caCert, credentialConfig["ca_cert"])
+
+	// but is kept in storage to issue certificates
+	role, err := b.(*databaseBackend).Role(ctx, config.StorageView, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, caKey, role.CredentialConfig["ca_private_key"])
+}
+
 func TestBackend_StaticRole_Config(t *testing.T) {
 	cluster, sys := getCluster(t)
 	defer cluster.Cleanup()
@@ -825,6 +919,137 @@ func TestWALsDeletedOnRoleDeletion(t *testing.T) {
 	requireWALs(t, storage, 1)
 }
 
+func TestBackend_StaticRole_RotationSchedule(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	errorCases := map[string]map[string]interface{}{
+		"no rotation": {},
+		"period and schedule": {
+			"rotation_period":   "86400s",
+			"rotation_schedule": "0 0 * * *",
+		},
+		"invalid schedule": {
+			"rotation_schedule": "0 0 * *",
+		},
+		"schedule with seconds": {
+			"rotation_schedule": "0 0 0 * * *",
+		},
+		"window without schedule": {
+			"rotation_period": "86400s",
+			"rotation_window": "3600s",
+		},
+		"window too short": {
+			"rotation_schedule": "0 0 * * *",
+			"rotation_window":   "60s",
+		},
+	}
+	for name, data := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			data["username"] = "hashicorp"
+			data["db_name"] = "mockv5"
+			resp, err := b.HandleRequest(ctx, &logical.Request{
+				Operation: logical.CreateOperation,
+				Path:      "static-roles/hashicorp",
+				Storage:   storage,
+				Data:      data,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp == nil || !resp.IsError() {
+				t.Fatalf("expected error, got %#v", resp)
+			}
+		})
+	}
+
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":          "hashicorp",
+			"db_name":           "mockv5",
+			"rotation_schedule": "0 0 * * *",
+			"rotation_window":   "3600s",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	readRole := func() map[string]interface{} {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-roles/hashicorp",
+			Storage:   storage,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+		return resp.Data
+	}
+
+	data := readRole()
+	if data["rotation_schedule"] != "0 0 * * *" || data["rotation_window"] != float64(3600) {
+		t.Fatalf("bad schedule in response: %#v", data)
+	}
+	if _, ok := data["rotation_period"]; ok {
+		t.Fatalf("unexpected rotation_period in response: %#v", data)
+	}
+
+	// The next rotation is the next midnight UTC
+	lastRotation := data["last_vault_rotation"].(time.Time)
+	nextRotation := data["next_vault_rotation"].(time.Time)
+	year, month, day := lastRotation.UTC().Date()
+	expected := time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC)
+	if !nextRotation.Equal(expected) {
+		t.Fatalf("expected next rotation at %v, got %v", expected, nextRotation)
+	}
+
+	item, err := b.popFromRotationQueueByKey("hashicorp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Priority != expected.Unix() {
+		t.Fatalf("expected queue priority %d, got %d", expected.Unix(), item.Priority)
+	}
+	if err := b.pushItem(item); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching to a rotation period clears the schedule
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":        "hashicorp",
+			"rotation_period": "600s",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	data = readRole()
+	if _, ok := data["rotation_schedule"]; ok {
+		t.Fatalf("unexpected rotation_schedule in response: %#v", data)
+	}
+	if data["rotation_period"] != float64(600) {
+		t.Fatalf("bad rotation_period in response: %#v", data)
+	}
+	if !data["next_vault_rotation"].(time.Time).Equal(lastRotation.Add(600 * time.Second)) {
+		t.Fatalf("bad next rotation: %#v", data)
+	}
+}
+
 func createRole(t *testing.T, b *databaseBackend, storage logical.Storage, mockDB *mockNewDatabase, roleName string) {
 	t.Helper()
 	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
@@ -858,3 +1083,219 @@ ALTER USER "{{name}}" WITH PASSWORD '{{password}}';
 const testRoleStaticUpdateRotation = `
 ALTER USER "{{name}}" WITH PASSWORD '{{password}}';GRANT ALL PRIVILEGES ON ALL TABLES IN SCHEMA public TO "{{name}}";
 `
+
+func TestBackend_StaticRole_ClientCertificateCredentialType(t *testing.T) {
+	ctx := context.Background()
+	b, storage, _ := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	caCert, caKey := testClientCertificateCA(t, time.Hour)
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":        "hashicorp",
+			"db_name":         "mockv5",
+			"rotation_period": "5s",
+			"credential_type": v5.CredentialTypeClientCertificate.String(),
+			"credential_config": map[string]string{
+				"ca_cert":        caCert,
+				"ca_private_key": caKey,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() {
+		t.Fatal("expected error creating static role with client_certificate credential type")
+	}
+	if !strings.Contains(resp.Error().Error(), "not supported for static roles") {
+		t.Fatalf("unexpected error: %v", resp.Error())
+	}
+}
+
+func TestBackend_StaticRole_SelfManaged(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+
+	// Configure a self-managed connection
+	entry, err := logical.StorageEntryJSON("config/mockv5", &DatabaseConfig{
+		AllowedRoles: []string{"*"},
+		ConnectionDetails: map[string]interface{}{
+			"self_managed": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Put(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	createReq := &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":        "hashicorp",
+			"db_name":         "mockv5",
+			"rotation_period": "86400s",
+		},
+	}
+
+	// The current password of the account is required to connect as it
+	resp, err := b.HandleRequest(ctx, createReq)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.IsError() || !strings.Contains(resp.Error().Error(), "self_managed_password is required") {
+		t.Fatalf("expected missing self_managed_password error, got: %#v", resp)
+	}
+
+	// The account connects with its own password to rotate it on creation
+	mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+		return req.Username == "hashicorp" && req.SelfManagedPassword == "initial-password"
+	})).Return(v5.UpdateUserResponse{}, nil).Once()
+	createReq.Data["self_managed_password"] = "initial-password"
+	resp, err = b.HandleRequest(ctx, createReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	credsReq := &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "static-creds/hashicorp",
+		Storage:   storage,
+	}
+	resp, err = b.HandleRequest(ctx, credsReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+	rotatedPassword := resp.Data["password"].(string)
+	if rotatedPassword == "" || rotatedPassword == "initial-password" {
+		t.Fatalf("expected password to be rotated, got %q", rotatedPassword)
+	}
+
+	// The password is never returned
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+	assert.Equal(t, true, resp.Data["self_managed"])
+	assert.NotContains(t, resp.Data, "self_managed_password")
+
+	// Later rotations connect with the password of the previous rotation
+	mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+		return req.SelfManagedPassword == rotatedPassword
+	})).Return(v5.UpdateUserResponse{}, nil).Once()
+	resp, err = b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "rotate-role/hashicorp",
+		Storage:   storage,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+	resp, err = b.HandleRequest(ctx, credsReq)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+	currentPassword := resp.Data["password"].(string)
+
+	t.Run("roll forward after the password was changed", func(t *testing.T) {
+		// The WAL password may already be set in the database, in which case
+		// the account can only connect with it
+		walID, err := framework.PutWAL(ctx, storage, staticWALKey, &setCredentialsWAL{
+			CredentialType: v5.CredentialTypePassword,
+			NewPassword:    "wal-password",
+			RoleName:       "hashicorp",
+			Username:       "hashicorp",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+			return req.SelfManagedPassword == currentPassword
+		})).Return(v5.UpdateUserResponse{}, errors.New("password authentication failed")).Once()
+		mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+			return req.SelfManagedPassword == "wal-password" && req.Password.NewPassword == "wal-password"
+		})).Return(v5.UpdateUserResponse{}, nil).Once()
+
+		role, err := b.StaticRole(ctx, storage, "hashicorp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = b.setStaticAccount(ctx, storage, &setStaticAccountInput{
+			RoleName: "hashicorp",
+			Role:     role,
+			WALID:    walID,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		requireWALs(t, storage, 0)
+
+		resp, err := b.HandleRequest(ctx, credsReq)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+		assert.Equal(t, "wal-password", resp.Data["password"])
+	})
+
+	t.Run("self_managed_password is invalid for other connections", func(t *testing.T) {
+		configureDBMount(t, storage)
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.CreateOperation,
+			Path:      "static-roles/other",
+			Storage:   storage,
+			Data: map[string]interface{}{
+				"username":              "other",
+				"db_name":               "mockv5",
+				"rotation_period":       "86400s",
+				"self_managed_password": "initial-password",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !resp.IsError() {
+			t.Fatal("expected error creating static role with self_managed_password")
+		}
+	})
+
+	t.Run("connection no longer self-managed", func(t *testing.T) {
+		// The connection was reconfigured by the previous subtest, so the
+		// existing role now rotates with the root credentials
+		mockDB.On("UpdateUser", mock.Anything, mock.MatchedBy(func(req v5.UpdateUserRequest) bool {
+			return req.Username == "hashicorp" && req.SelfManagedPassword == ""
+		})).Return(v5.UpdateUserResponse{}, nil).Once()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "rotate-role/hashicorp",
+			Storage:   storage,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+
+		resp, err = b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "static-roles/hashicorp",
+			Storage:   storage,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatal(resp, err)
+		}
+		assert.Equal(t, false, resp.Data["self_managed"])
+	})
+
+	mockDB.AssertNumberOfCalls(t, "UpdateUser", 5)
+}
@@ -25,6 +25,10 @@ const (
 
 	// WAL storage key used for static account rotations
 	staticWALKey = "staticRotationKey"
+
+	// Minimum rotation window of scheduled static accounts, which leaves
+	// room for several retries of a failed rotation
+	minRotationWindowSeconds = 3600
 )
 
 // populateQueue loads the priority queue with existing static accounts. This
@@ -230,6 +234,30 @@ func (b *databaseBackend) rotateCredential(ctx context.Context, s logical.Storag
 		input.WALID = walID
 	}
 
+	// Scheduled rotations missed by more than the rotation window, such as
+	// while Vault was sealed, are deferred to the next scheduled time rather
+	// than performed at an unexpected time. The window is measured from the
+	// stored scheduled time, as the queue priority moves with each retry.
+	// Interrupted rotations are always completed.
+	now := time.Now()
+	if input.WALID == "" && !role.StaticAccount.IsInsideRotationWindow(role.StaticAccount.NextRotationTime(), now) {
+		b.logger.Debug("rotation window missed, deferring to the next scheduled rotation", "role", item.Key)
+		role.StaticAccount.SetNextVaultRotation(now)
+		entry, err := logical.StorageEntryJSON(databaseStaticRolePath+item.Key, role)
+		if err == nil {
+			err = s.Put(ctx, entry)
+		}
+		if err != nil {
+			b.logger.Warn("unable to store next rotation time", "role", item.Key, "error", err)
+		}
+
+		item.Priority = role.StaticAccount.NextRotationTime().Unix()
+		if err := b.pushItem(item); err != nil {
+			b.logger.Error("unable to push item on to queue", "error", err)
+		}
+		return true
+	}
+
 	resp, err := b.setStaticAccount(ctx, s, input)
 	if err != nil {
 		b.logger.Error("unable to rotate credentials in periodic function", "error", err)
@@ -257,7 +285,7 @@ func (b *databaseBackend) rotateCredential(ctx context.Context, s logical.Storag
 	}
 
 	// Update priority and push updated Item to the queue
-	nextRotation := lvr.Add(role.StaticAccount.RotationPeriod)
+	nextRotation := role.StaticAccount.NextRotationTimeFromInput(lvr)
 	item.Priority = nextRotation.Unix()
 	if err := b.pushItem(item); err != nil {
 		b.logger.Warn("unable to push item on to queue", "error", err)
@@ -364,9 +392,21 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 		Commands: input.Role.Statements.Rotation,
 	}
 
+	// Self-managed accounts connect to the database as themselves with
+	// their current password to rotate it. The connection decides whether
+	// accounts are self-managed, as it may have been reconfigured since the
+	// role was written.
+	if dbConfig.SelfManaged() {
+		if input.Role.StaticAccount.Password == "" {
+			return output, fmt.Errorf("static role %q of self-managed connection %q has no self_managed_password", input.RoleName, input.Role.DBName)
+		}
+		updateReq.SelfManagedPassword = input.Role.StaticAccount.Password
+	}
+
 	// Use credential from input if available. This happens if we're restoring from
 	// a WAL item or processing the rotation queue with an item that has a WAL
 	// associated with it
+	var rollForward bool
 	if output.WALID != "" {
 		wal, err := b.findStaticWAL(ctx, s, output.WALID)
 		if err != nil {
@@ -388,6 +428,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 			output.WALID = ""
 		case wal.CredentialType == v5.CredentialTypePassword:
 			// Roll forward by using the credential in the existing WAL entry
+			rollForward = true
 			updateReq.CredentialType = v5.CredentialTypePassword
 			updateReq.Password = &v5.ChangePassword{
 				NewPassword: wal.NewPassword,
@@ -474,6 +515,17 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	}
 
 	_, err = dbi.database.UpdateUser(ctx, updateReq, false)
+	if err != nil && rollForward && updateReq.SelfManagedPassword != "" &&
+		updateReq.SelfManagedPassword != updateReq.Password.NewPassword {
+		// When rolling forward a WAL entry, the password may already have
+		// been changed in the database before the rotation failed, in which
+		// case the account can only connect with the new password
+		rollForwardReq := updateReq
+		rollForwardReq.SelfManagedPassword = updateReq.Password.NewPassword
+		if _, rollForwardErr := dbi.database.UpdateUser(ctx, rollForwardReq, false); rollForwardErr == nil {
+			err = nil
+		}
+	}
 	if err != nil {
 		b.CloseIfShutdown(dbi, err)
 		return output, fmt.Errorf("error setting credentials: %w", err)
@@ -483,6 +535,7 @@ func (b *databaseBackend) setStaticAccount(ctx context.Context, s logical.Storag
 	// lvr is the known LastVaultRotation
 	lvr := time.Now()
 	input.Role.StaticAccount.LastVaultRotation = lvr
+	input.Role.StaticAccount.SetNextVaultRotation(lvr)
 	output.RotationTime = lvr
 
 	entry, err := logical.StorageEntryJSON(databaseStaticRolePath+input.RoleName, input.Role)
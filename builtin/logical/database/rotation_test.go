@@ -1311,6 +1311,109 @@ func TestDeletesOlderWALsOnLoad(t *testing.T) {
 	requireWALs(t, storage, 1)
 }
 
+func TestBackend_StaticRole_RotationWindow(t *testing.T) {
+	ctx := context.Background()
+	b, storage, mockDB := getBackend(t)
+	defer b.Cleanup(ctx)
+	configureDBMount(t, storage)
+
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	resp, err := b.HandleRequest(ctx, &logical.Request{
+		Operation: logical.CreateOperation,
+		Path:      "static-roles/hashicorp",
+		Storage:   storage,
+		Data: map[string]interface{}{
+			"username":          "hashicorp",
+			"db_name":           "mockv5",
+			"rotation_schedule": "*/30 * * * *",
+			"rotation_window":   "3600s",
+		},
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatal(resp, err)
+	}
+
+	role, err := b.StaticRole(ctx, storage, "hashicorp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lastRotation := role.StaticAccount.LastVaultRotation
+
+	// setScheduled stores the given scheduled rotation time, while the item
+	// is due in the queue as it would be when retrying a failed rotation.
+	setScheduled := func(scheduled time.Time) {
+		t.Helper()
+		role, err := b.StaticRole(ctx, storage, "hashicorp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		role.StaticAccount.NextVaultRotation = scheduled
+		entry, err := logical.StorageEntryJSON(databaseStaticRolePath+"hashicorp", role)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := storage.Put(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+
+		item, err := b.popFromRotationQueueByKey("hashicorp")
+		if err != nil {
+			t.Fatal(err)
+		}
+		item.Priority = time.Now().Add(-5 * time.Second).Unix()
+		if err := b.pushItem(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A rotation missed by more than the window is deferred to the next
+	// scheduled time, even when retried recently
+	setScheduled(time.Now().Add(-2 * time.Hour))
+	if !b.rotateCredential(ctx, storage) {
+		t.Fatal("expected item to be processed")
+	}
+	role, err = b.StaticRole(ctx, storage, "hashicorp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !role.StaticAccount.LastVaultRotation.Equal(lastRotation) {
+		t.Fatal("role was rotated outside of the rotation window")
+	}
+	next := role.StaticAccount.NextRotationTime()
+	if !next.After(time.Now()) || next.After(time.Now().Add(30*time.Minute)) || next.Minute()%30 != 0 {
+		t.Fatalf("bad next rotation %v", next)
+	}
+	item, err := b.popFromRotationQueueByKey("hashicorp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item.Priority != next.Unix() {
+		t.Fatalf("expected queue priority %d, got %d", next.Unix(), item.Priority)
+	}
+	if err := b.pushItem(item); err != nil {
+		t.Fatal(err)
+	}
+
+	// A rotation within the window is performed
+	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
+		Return(v5.UpdateUserResponse{}, nil).
+		Once()
+	setScheduled(time.Now().Add(-30 * time.Minute))
+	if !b.rotateCredential(ctx, storage) {
+		t.Fatal("expected item to be processed")
+	}
+	role, err = b.StaticRole(ctx, storage, "hashicorp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !role.StaticAccount.LastVaultRotation.After(lastRotation) {
+		t.Fatal("role was not rotated within the rotation window")
+	}
+	mockDB.AssertNumberOfCalls(t, "UpdateUser", 2)
+}
+
 func generateWALFromFailedRotation(t *testing.T, b *databaseBackend, storage logical.Storage, mockDB *mockNewDatabase, roleName string) {
 	t.Helper()
 	mockDB.On("UpdateUser", mock.Anything, mock.Anything).
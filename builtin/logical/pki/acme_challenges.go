@@ -0,0 +1,143 @@
+package pki
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	acmeChallengeTimeout      = 10 * time.Second
+	acmeMaxChallengeBodySize  = 8 * 1024
+	acmeMaxChallengeRedirects = 10
+)
+
+// acmeKeyAuthorization builds the key authorization for a challenge token
+// and account key thumbprint, per RFC 8555 Section 8.1.
+func acmeKeyAuthorization(token string, thumbprint string) string {
+	return token + "." + thumbprint
+}
+
+// validateAcmeChallenge checks that the client has provisioned the response
+// to the given challenge for the identifier.
+func (ac *acmeContext) validateAcmeChallenge(ctx context.Context, identifier acmeIdentifier, challenge *acmeChallenge, thumbprint string) error {
+	keyAuthz := acmeKeyAuthorization(challenge.Token, thumbprint)
+
+	ctx, cancel := context.WithTimeout(ctx, acmeChallengeTimeout)
+	defer cancel()
+
+	switch challenge.Type {
+	case acmeChallengeHTTP01:
+		return validateHTTP01Challenge(ctx, identifier, challenge.Token, keyAuthz, ac.config.HTTPChallengePort)
+	case acmeChallengeDNS01:
+		return validateDNS01Challenge(ctx, identifier, keyAuthz, ac.config.DNSResolver)
+	default:
+		return acmeErrorf(acmeErrMalformed, "unsupported challenge type: %v", challenge.Type)
+	}
+}
+
+func validateHTTP01Challenge(ctx context.Context, identifier acmeIdentifier, token string, keyAuthz string, port int) error {
+	host := identifier.Value
+	if port != defaultAcmeHTTPChallengePort {
+		host = net.JoinHostPort(host, strconv.Itoa(port))
+	} else if identifier.Type == acmeIdentifierIP && strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	url := fmt.Sprintf("http://%s/.well-known/acme-challenge/%s", host, token)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return acmeErrorf(acmeErrMalformed, "failed to build http-01 request: %v", err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: checkHTTP01Redirect,
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return acmeErrorf(acmeErrConnection, "failed to fetch %v: %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return acmeErrorf(acmeErrIncorrectResponse, "unexpected status code fetching %v: %v", url, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, acmeMaxChallengeBodySize))
+	if err != nil {
+		return acmeErrorf(acmeErrConnection, "failed to read response from %v: %v", url, err)
+	}
+
+	if strings.TrimSpace(string(body)) != keyAuthz {
+		return acmeErrorf(acmeErrIncorrectResponse, "key authorization served at %v does not match", url)
+	}
+
+	return nil
+}
+
+// checkHTTP01Redirect only lets http-01 validation follow a limited number of
+// redirects to the standard ports of http and https, as per RFC 8555 Section
+// 8.3, so that clients can't point the validation request at arbitrary
+// services reachable from Vault.
+func checkHTTP01Redirect(req *http.Request, via []*http.Request) error {
+	if len(via) > acmeMaxChallengeRedirects {
+		return fmt.Errorf("stopped after %d redirects", acmeMaxChallengeRedirects)
+	}
+
+	var defaultPort string
+	switch req.URL.Scheme {
+	case "http":
+		defaultPort = "80"
+	case "https":
+		defaultPort = "443"
+	default:
+		return fmt.Errorf("refusing redirect to scheme %q", req.URL.Scheme)
+	}
+
+	if port := req.URL.Port(); port != "" && port != defaultPort {
+		return fmt.Errorf("refusing redirect to port %v for scheme %v", port, req.URL.Scheme)
+	}
+
+	return nil
+}
+
+func validateDNS01Challenge(ctx context.Context, identifier acmeIdentifier, keyAuthz string, resolverAddr string) error {
+	if identifier.Type != acmeIdentifierDNS {
+		return acmeErrorf(acmeErrMalformed, "dns-01 challenges are only valid for dns identifiers")
+	}
+
+	resolver := net.DefaultResolver
+	if resolverAddr != "" {
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, resolverAddr)
+			},
+		}
+	}
+
+	name := "_acme-challenge." + strings.TrimPrefix(identifier.Value, "*.")
+	records, err := resolver.LookupTXT(ctx, name)
+	if err != nil {
+		return acmeErrorf(acmeErrDNS, "failed to look up TXT records for %v: %v", name, err)
+	}
+
+	digest := sha256.Sum256([]byte(keyAuthz))
+	expected := base64.RawURLEncoding.EncodeToString(digest[:])
+	for _, record := range records {
+		if strings.TrimSpace(record) == expected {
+			return nil
+		}
+	}
+
+	return acmeErrorf(acmeErrIncorrectResponse, "no TXT record for %v matched the key authorization", name)
+}
@@ -0,0 +1,213 @@
+package pki
+
+import (
+	"crypto"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+// acmeKeyMode describes how the JWS of an ACME request must identify its
+// signing key: either by embedding it (jwk), or by referencing an existing
+// account (kid). See RFC 8555 Section 6.2.
+type acmeKeyMode int
+
+const (
+	acmeRequireKID acmeKeyMode = iota
+	acmeRequireJWK
+	acmeAllowJWKOrKID
+)
+
+var acmeAllowedSignatureAlgorithms = []string{
+	string(jose.RS256),
+	string(jose.ES256),
+	string(jose.ES384),
+	string(jose.ES512),
+	string(jose.EdDSA),
+}
+
+var acmeJWSFields = map[string]*framework.FieldSchema{
+	"protected": {
+		Type:        framework.TypeString,
+		Description: "ACME request 'protected' value",
+	},
+	"payload": {
+		Type:        framework.TypeString,
+		Description: "ACME request 'payload' value",
+	},
+	"signature": {
+		Type:        framework.TypeString,
+		Description: "ACME request 'signature' value",
+	},
+}
+
+// acmeJWS is a verified ACME request body.
+type acmeJWS struct {
+	key        *jose.JSONWebKey
+	thumbprint string
+	account    *acmeAccount
+	payload    []byte
+}
+
+// isPostAsGet reports whether this was a POST-as-GET request, which carries
+// an empty payload (RFC 8555 Section 6.3).
+func (j *acmeJWS) isPostAsGet() bool {
+	return len(j.payload) == 0
+}
+
+func (j *acmeJWS) decodePayload(out interface{}) error {
+	if j.isPostAsGet() {
+		return nil
+	}
+	if err := json.Unmarshal(j.payload, out); err != nil {
+		return acmeErrorf(acmeErrMalformed, "failed to decode request payload: %v", err)
+	}
+	return nil
+}
+
+func addAcmeJWSFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	for name, schema := range acmeJWSFields {
+		fields[name] = schema
+	}
+	return fields
+}
+
+func jwkThumbprint(key *jose.JSONWebKey) (string, error) {
+	thumbprint, err := key.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", acmeErrorf(acmeErrBadPublicKey, "failed to compute key thumbprint: %v", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// verifyJWS parses and verifies the flattened JWS JSON serialization of an
+// ACME request, as described in RFC 8555 Section 6.2, consuming its nonce
+// once the signature has been verified.
+func (ac *acmeContext) verifyJWS(data *framework.FieldData, requestURL string, mode acmeKeyMode) (*acmeJWS, error) {
+	raw := map[string]string{
+		"protected": data.Get("protected").(string),
+		"payload":   data.Get("payload").(string),
+		"signature": data.Get("signature").(string),
+	}
+	if raw["protected"] == "" || raw["signature"] == "" {
+		return nil, acmeErrorf(acmeErrMalformed, "request is not a flattened JWS")
+	}
+
+	// go-jose refuses to parse JWSes embedding private keys; check for them
+	// first to report them as such.
+	if err := checkJWSEmbeddedKey(raw["protected"]); err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode JWS: %w", err)
+	}
+
+	parsed, err := jose.ParseSigned(string(encoded))
+	if err != nil {
+		return nil, acmeErrorf(acmeErrMalformed, "failed to parse JWS: %v", err)
+	}
+	if len(parsed.Signatures) != 1 {
+		return nil, acmeErrorf(acmeErrMalformed, "JWS must contain exactly one signature")
+	}
+	header := parsed.Signatures[0].Protected
+
+	allowed := false
+	for _, alg := range acmeAllowedSignatureAlgorithms {
+		if header.Algorithm == alg {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return nil, acmeErrorf(acmeErrBadSignatureAlgorithm, "unsupported JWS signature algorithm: %q", header.Algorithm)
+	}
+
+	headerURL, _ := header.ExtraHeaders["url"].(string)
+	if headerURL != requestURL {
+		return nil, acmeErrorf(acmeErrUnauthorized, "JWS url %q does not match request url %q", headerURL, requestURL)
+	}
+
+	result := &acmeJWS{}
+	switch {
+	case header.JSONWebKey != nil && header.KeyID != "":
+		return nil, acmeErrorf(acmeErrMalformed, "JWS must not contain both jwk and kid")
+	case header.JSONWebKey != nil:
+		if mode == acmeRequireKID {
+			return nil, acmeErrorf(acmeErrMalformed, "JWS must identify its account with kid")
+		}
+		result.key = header.JSONWebKey
+	case header.KeyID != "":
+		if mode == acmeRequireJWK {
+			return nil, acmeErrorf(acmeErrMalformed, "JWS must embed its public key as jwk")
+		}
+
+		accountPrefix := ac.baseURL + "account/"
+		if !strings.HasPrefix(header.KeyID, accountPrefix) {
+			return nil, acmeErrorf(acmeErrAccountDoesNotExist, "unknown account: %v", header.KeyID)
+		}
+		account, err := ac.sc.fetchAcmeAccount(strings.TrimPrefix(header.KeyID, accountPrefix))
+		if err != nil {
+			return nil, err
+		}
+		if account == nil {
+			return nil, acmeErrorf(acmeErrAccountDoesNotExist, "unknown account: %v", header.KeyID)
+		}
+		if account.Status != acmeStatusValid {
+			return nil, acmeErrorf(acmeErrUnauthorized, "account is %v", account.Status)
+		}
+
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(account.Key); err != nil {
+			return nil, fmt.Errorf("failed to decode stored account key: %w", err)
+		}
+		result.key = &key
+		result.account = account
+	default:
+		return nil, acmeErrorf(acmeErrMalformed, "JWS must contain either jwk or kid")
+	}
+
+	payload, err := parsed.Verify(result.key)
+	if err != nil {
+		return nil, acmeErrorf(acmeErrMalformed, "JWS signature verification failed: %v", err)
+	}
+	result.payload = payload
+
+	// Only redeem the nonce of authentic requests, so that a forged request
+	// can't burn the nonce of another.
+	if header.Nonce == "" || !ac.state.redeemNonce(header.Nonce) {
+		return nil, acmeErrorf(acmeErrBadNonce, "invalid or expired nonce")
+	}
+
+	if result.thumbprint, err = jwkThumbprint(result.key); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkJWSEmbeddedKey rejects an encoded protected header whose jwk is not a
+// public key.
+func checkJWSEmbeddedKey(protected string) error {
+	decoded, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return acmeErrorf(acmeErrMalformed, "failed to decode JWS protected header: %v", err)
+	}
+
+	var header struct {
+		JSONWebKey *jose.JSONWebKey `json:"jwk"`
+	}
+	if err := json.Unmarshal(decoded, &header); err != nil {
+		return acmeErrorf(acmeErrMalformed, "failed to parse JWS protected header: %v", err)
+	}
+	if header.JSONWebKey != nil && !header.JSONWebKey.IsPublic() {
+		return acmeErrorf(acmeErrBadPublicKey, "JWS jwk must be a public key")
+	}
+
+	return nil
+}
@@ -0,0 +1,297 @@
+package pki
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	acmeStoragePrefix       = "acme/"
+	acmeAccountPrefix       = "acme/accounts/"
+	acmeThumbprintPrefix    = "acme/account-thumbprints/"
+	acmeOrderPrefix         = "acme/orders/"
+	acmeAuthorizationPrefix = "acme/authorizations/"
+	acmeCertOwnerPrefix     = "acme/cert-accounts/"
+
+	// Nonces are only held in memory on the active node; a client whose
+	// nonce expired or was lost across a leadership change gets a
+	// badNonce error and retries with the fresh nonce we return with it.
+	acmeNonceLifetime = 15 * time.Minute
+	acmeMaxNonces     = 50000
+
+	acmeOrderLifetime = 24 * time.Hour
+)
+
+type acmeStatus string
+
+const (
+	acmeStatusPending     acmeStatus = "pending"
+	acmeStatusReady       acmeStatus = "ready"
+	acmeStatusValid       acmeStatus = "valid"
+	acmeStatusInvalid     acmeStatus = "invalid"
+	acmeStatusDeactivated acmeStatus = "deactivated"
+)
+
+const (
+	acmeIdentifierDNS = "dns"
+	acmeIdentifierIP  = "ip"
+
+	acmeChallengeHTTP01 = "http-01"
+	acmeChallengeDNS01  = "dns-01"
+)
+
+type acmeState struct {
+	nonceLock sync.Mutex
+	nonces    map[string]time.Time
+
+	// resourceLocks serialize updates to individual orders and
+	// authorizations, such as concurrent finalization of the same order.
+	resourceLocks []*locksutil.LockEntry
+}
+
+func newAcmeState() *acmeState {
+	return &acmeState{
+		nonces:        make(map[string]time.Time),
+		resourceLocks: locksutil.CreateLocks(),
+	}
+}
+
+func (a *acmeState) getNonce() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(raw)
+
+	a.nonceLock.Lock()
+	defer a.nonceLock.Unlock()
+
+	now := time.Now()
+	if len(a.nonces) >= acmeMaxNonces {
+		a.tidyNoncesLocked(now)
+	}
+	a.nonces[nonce] = now.Add(acmeNonceLifetime)
+
+	return nonce, nil
+}
+
+// redeemNonce consumes the nonce, returning whether it was valid.
+func (a *acmeState) redeemNonce(nonce string) bool {
+	a.nonceLock.Lock()
+	defer a.nonceLock.Unlock()
+
+	expiry, ok := a.nonces[nonce]
+	if !ok {
+		return false
+	}
+	delete(a.nonces, nonce)
+
+	return time.Now().Before(expiry)
+}
+
+func (a *acmeState) tidyNoncesLocked(now time.Time) {
+	for nonce, expiry := range a.nonces {
+		if now.After(expiry) {
+			delete(a.nonces, nonce)
+		}
+	}
+
+	// Still full of live nonces; drop everything rather than growing
+	// without bound. Affected clients retry on badNonce.
+	if len(a.nonces) >= acmeMaxNonces {
+		a.nonces = make(map[string]time.Time)
+	}
+}
+
+type acmeIdentifier struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type acmeAccount struct {
+	ID          string     `json:"id"`
+	Thumbprint  string     `json:"thumbprint"`
+	Key         []byte     `json:"key"`
+	Contact     []string   `json:"contact"`
+	Status      acmeStatus `json:"status"`
+	CreatedDate time.Time  `json:"created_date"`
+}
+
+type acmeOrder struct {
+	ID                string           `json:"id"`
+	AccountID         string           `json:"account_id"`
+	Role              string           `json:"role"`
+	Status            acmeStatus       `json:"status"`
+	Expires           time.Time        `json:"expires"`
+	Identifiers       []acmeIdentifier `json:"identifiers"`
+	AuthorizationIDs  []string         `json:"authorization_ids"`
+	NotAfter          time.Time        `json:"not_after"`
+	CertificateSerial string           `json:"certificate_serial"`
+	CertificateChain  string           `json:"certificate_chain"`
+}
+
+type acmeChallenge struct {
+	Type      string     `json:"type"`
+	Token     string     `json:"token"`
+	Status    acmeStatus `json:"status"`
+	Validated time.Time  `json:"validated"`
+	ErrorType string     `json:"error_type"`
+	Error     string     `json:"error"`
+}
+
+type acmeAuthorization struct {
+	ID         string           `json:"id"`
+	AccountID  string           `json:"account_id"`
+	Identifier acmeIdentifier   `json:"identifier"`
+	Wildcard   bool             `json:"wildcard"`
+	Status     acmeStatus       `json:"status"`
+	Expires    time.Time        `json:"expires"`
+	Challenges []*acmeChallenge `json:"challenges"`
+}
+
+func (sc *storageContext) acmeGet(path string, out interface{}) (bool, error) {
+	entry, err := sc.Storage.Get(sc.Context, path)
+	if err != nil {
+		return false, err
+	}
+	if entry == nil {
+		return false, nil
+	}
+
+	if err := entry.DecodeJSON(out); err != nil {
+		return false, fmt.Errorf("failed decoding ACME entry %v: %w", path, err)
+	}
+
+	return true, nil
+}
+
+func (sc *storageContext) acmePut(path string, value interface{}) error {
+	entry, err := logical.StorageEntryJSON(path, value)
+	if err != nil {
+		return err
+	}
+
+	return sc.Storage.Put(sc.Context, entry)
+}
+
+func (sc *storageContext) fetchAcmeAccount(id string) (*acmeAccount, error) {
+	var account acmeAccount
+	ok, err := sc.acmeGet(acmeAccountPrefix+id, &account)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &account, nil
+}
+
+func (sc *storageContext) fetchAcmeAccountByThumbprint(thumbprint string) (*acmeAccount, error) {
+	entry, err := sc.Storage.Get(sc.Context, acmeThumbprintPrefix+thumbprint)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+
+	return sc.fetchAcmeAccount(string(entry.Value))
+}
+
+func (sc *storageContext) writeAcmeAccount(account *acmeAccount) error {
+	if err := sc.acmePut(acmeAccountPrefix+account.ID, account); err != nil {
+		return err
+	}
+
+	return sc.Storage.Put(sc.Context, &logical.StorageEntry{
+		Key:   acmeThumbprintPrefix + account.Thumbprint,
+		Value: []byte(account.ID),
+	})
+}
+
+// Orders and authorizations are stored beneath the account which created
+// them, so that lookups on behalf of one account can never reach another's.
+func (sc *storageContext) fetchAcmeOrder(accountID string, id string) (*acmeOrder, error) {
+	var order acmeOrder
+	ok, err := sc.acmeGet(acmeOrderPrefix+accountID+"/"+id, &order)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (sc *storageContext) listAcmeOrders(accountID string) ([]string, error) {
+	return sc.Storage.List(sc.Context, acmeOrderPrefix+accountID+"/")
+}
+
+func (sc *storageContext) writeAcmeOrder(order *acmeOrder) error {
+	return sc.acmePut(acmeOrderPrefix+order.AccountID+"/"+order.ID, order)
+}
+
+func (sc *storageContext) fetchAcmeAuthorization(accountID string, id string) (*acmeAuthorization, error) {
+	var authz acmeAuthorization
+	ok, err := sc.acmeGet(acmeAuthorizationPrefix+accountID+"/"+id, &authz)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &authz, nil
+}
+
+func (sc *storageContext) writeAcmeAuthorization(authz *acmeAuthorization) error {
+	return sc.acmePut(acmeAuthorizationPrefix+authz.AccountID+"/"+authz.ID, authz)
+}
+
+func (sc *storageContext) fetchAcmeCertOwner(serial string) (string, error) {
+	entry, err := sc.Storage.Get(sc.Context, acmeCertOwnerPrefix+normalizeSerial(serial))
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return string(entry.Value), nil
+}
+
+func (sc *storageContext) writeAcmeCertOwner(serial string, accountID string) error {
+	return sc.Storage.Put(sc.Context, &logical.StorageEntry{
+		Key:   acmeCertOwnerPrefix + normalizeSerial(serial),
+		Value: []byte(accountID),
+	})
+}
+
+// updateAcmeOrderStatus recomputes the status of a pending order from its
+// authorizations, persisting the order when it changed.
+func (sc *storageContext) updateAcmeOrderStatus(order *acmeOrder) error {
+	if order.Status != acmeStatusPending {
+		return nil
+	}
+
+	if time.Now().After(order.Expires) {
+		order.Status = acmeStatusInvalid
+		return sc.writeAcmeOrder(order)
+	}
+
+	allValid := true
+	for _, authzID := range order.AuthorizationIDs {
+		authz, err := sc.fetchAcmeAuthorization(order.AccountID, authzID)
+		if err != nil {
+			return err
+		}
+		if authz == nil {
+			return fmt.Errorf("order %v references missing authorization %v", order.ID, authzID)
+		}
+
+		switch authz.Status {
+		case acmeStatusValid:
+		case acmeStatusPending:
+			allValid = false
+		default:
+			order.Status = acmeStatusInvalid
+			return sc.writeAcmeOrder(order)
+		}
+	}
+
+	if allValid {
+		order.Status = acmeStatusReady
+		return sc.writeAcmeOrder(order)
+	}
+
+	return nil
+}
@@ -0,0 +1,255 @@
+package pki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	acmeJSONContentType    = "application/json"
+	acmeProblemContentType = "application/problem+json"
+	acmeCertContentType    = "application/pem-certificate-chain"
+
+	acmeErrorPrefix = "urn:ietf:params:acme:error:"
+)
+
+// ACME error types from RFC 8555 Section 6.7, along with the HTTP status
+// code we return them with.
+var (
+	acmeErrAccountDoesNotExist    = acmeErrorType{"accountDoesNotExist", http.StatusBadRequest}
+	acmeErrAlreadyRevoked         = acmeErrorType{"alreadyRevoked", http.StatusBadRequest}
+	acmeErrBadCSR                 = acmeErrorType{"badCSR", http.StatusBadRequest}
+	acmeErrBadNonce               = acmeErrorType{"badNonce", http.StatusBadRequest}
+	acmeErrBadPublicKey           = acmeErrorType{"badPublicKey", http.StatusBadRequest}
+	acmeErrBadRevocationReason    = acmeErrorType{"badRevocationReason", http.StatusBadRequest}
+	acmeErrBadSignatureAlgorithm  = acmeErrorType{"badSignatureAlgorithm", http.StatusBadRequest}
+	acmeErrIncorrectResponse      = acmeErrorType{"incorrectResponse", http.StatusForbidden}
+	acmeErrMalformed              = acmeErrorType{"malformed", http.StatusBadRequest}
+	acmeErrNotFound               = acmeErrorType{"malformed", http.StatusNotFound}
+	acmeErrOrderNotReady          = acmeErrorType{"orderNotReady", http.StatusForbidden}
+	acmeErrRejectedIdentifier     = acmeErrorType{"rejectedIdentifier", http.StatusBadRequest}
+	acmeErrServerInternal         = acmeErrorType{"serverInternal", http.StatusInternalServerError}
+	acmeErrUnauthorized           = acmeErrorType{"unauthorized", http.StatusForbidden}
+	acmeErrUnsupportedIdentifier  = acmeErrorType{"unsupportedIdentifier", http.StatusBadRequest}
+	acmeErrConnection             = acmeErrorType{"connection", http.StatusBadRequest}
+	acmeErrDNS                    = acmeErrorType{"dns", http.StatusBadRequest}
+	acmeErrUnsupportedContactType = acmeErrorType{"unsupportedContact", http.StatusBadRequest}
+)
+
+type acmeErrorType struct {
+	name   string
+	status int
+}
+
+// acmeError is returned by ACME handlers to produce an RFC 7807 problem
+// document instead of a regular Vault error response.
+type acmeError struct {
+	errType acmeErrorType
+	detail  string
+}
+
+func (e *acmeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.errType.name, e.detail)
+}
+
+func acmeErrorf(errType acmeErrorType, format string, args ...interface{}) error {
+	return &acmeError{
+		errType: errType,
+		detail:  fmt.Sprintf(format, args...),
+	}
+}
+
+// acmeContext carries the state of a single request to one of the ACME
+// directories of this mount.
+type acmeContext struct {
+	sc     *storageContext
+	state  *acmeState
+	config *acmeConfigEntry
+
+	// baseURL is the absolute URL of the directory being served, with a
+	// trailing slash; all resource URLs are built relative to it.
+	baseURL string
+
+	// roleName and role are the role issuance is performed under; both are
+	// empty when issuing sign-verbatim style via the default directory.
+	roleName string
+	role     *roleEntry
+}
+
+type (
+	acmeOperation       func(ac *acmeContext, req *logical.Request, data *framework.FieldData) (*logical.Response, error)
+	acmeParsedOperation func(ac *acmeContext, req *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error)
+)
+
+// acmeWrapper loads the ACME configuration and directory role for the
+// request, then invokes the operation, converting any acmeError it returns
+// into a problem document.
+func (b *backend) acmeWrapper(op acmeOperation) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ac, err := b.newAcmeContext(ctx, req, data)
+		if err != nil {
+			return b.acmeErrorResponse(ac, err)
+		}
+
+		resp, err := op(ac, req, data)
+		if err != nil {
+			return b.acmeErrorResponse(ac, err)
+		}
+		return resp, nil
+	}
+}
+
+// acmeParsedWrapper is like acmeWrapper, but additionally verifies the JWS
+// body of the request before invoking the operation.
+func (b *backend) acmeParsedWrapper(mode acmeKeyMode, op acmeParsedOperation) framework.OperationFunc {
+	return b.acmeWrapper(func(ac *acmeContext, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		jws, err := ac.verifyJWS(data, ac.config.BaseURL+"/"+req.Path, mode)
+		if err != nil {
+			return nil, err
+		}
+
+		return op(ac, req, data, jws)
+	})
+}
+
+func (b *backend) newAcmeContext(ctx context.Context, req *logical.Request, data *framework.FieldData) (*acmeContext, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	ac := &acmeContext{
+		sc:    sc,
+		state: b.acmeState,
+	}
+
+	config, err := sc.getAcmeConfig()
+	if err != nil {
+		return ac, err
+	}
+	if !config.Enabled {
+		return ac, acmeErrorf(acmeErrServerInternal, "ACME is disabled on this mount")
+	}
+	ac.config = config
+
+	idx := strings.Index(req.Path, "acme/")
+	if idx < 0 {
+		return ac, fmt.Errorf("unexpected ACME request path: %v", req.Path)
+	}
+	ac.baseURL = config.BaseURL + "/" + req.Path[:idx+len("acme/")]
+
+	if roleRaw, ok := data.GetOk("role"); ok {
+		ac.roleName = roleRaw.(string)
+		if !config.isRoleAllowed(ac.roleName) {
+			return ac, acmeErrorf(acmeErrUnauthorized, "role %q is not allowed to be used with ACME", ac.roleName)
+		}
+	} else {
+		ac.roleName = config.DefaultRole
+	}
+
+	if ac.roleName != "" {
+		ac.role, err = b.getRole(ctx, req.Storage, ac.roleName)
+		if err != nil {
+			return ac, err
+		}
+		if ac.role == nil {
+			return ac, acmeErrorf(acmeErrServerInternal, "unknown role: %v", ac.roleName)
+		}
+	}
+
+	return ac, nil
+}
+
+func (b *backend) acmeErrorResponse(ac *acmeContext, err error) (*logical.Response, error) {
+	var aErr *acmeError
+	if !errors.As(err, &aErr) {
+		b.Logger().Warn("unexpected error processing ACME request", "error", err)
+		aErr = &acmeError{errType: acmeErrServerInternal, detail: "internal error processing ACME request"}
+	}
+
+	body := map[string]interface{}{
+		"type":   acmeErrorPrefix + aErr.errType.name,
+		"detail": aErr.detail,
+	}
+
+	if ac == nil || ac.config == nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPContentType: acmeProblemContentType,
+				logical.HTTPStatusCode:  aErr.errType.status,
+				logical.HTTPRawBody:     encoded,
+			},
+		}, nil
+	}
+
+	return ac.respondWithType(aErr.errType.status, acmeProblemContentType, body, "")
+}
+
+func (ac *acmeContext) respond(status int, body interface{}, location string) (*logical.Response, error) {
+	return ac.respondWithType(status, acmeJSONContentType, body, location)
+}
+
+func (ac *acmeContext) respondWithType(status int, contentType string, body interface{}, location string) (*logical.Response, error) {
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPStatusCode:         status,
+			logical.HTTPCacheControlHeader: "no-store",
+		},
+		Headers: map[string][]string{
+			"Link": {fmt.Sprintf("<%s>;rel=\"index\"", ac.baseURL+"directory")},
+		},
+	}
+
+	if status != http.StatusNoContent {
+		resp.Data[logical.HTTPContentType] = contentType
+	}
+
+	if body != nil {
+		var encoded []byte
+		switch typed := body.(type) {
+		case []byte:
+			encoded = typed
+		default:
+			var err error
+			if encoded, err = json.Marshal(body); err != nil {
+				return nil, fmt.Errorf("failed to encode ACME response: %w", err)
+			}
+		}
+		resp.Data[logical.HTTPRawBody] = encoded
+	}
+
+	nonce, err := ac.state.getNonce()
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers["Replay-Nonce"] = []string{nonce}
+
+	if location != "" {
+		resp.Headers["Location"] = []string{location}
+	}
+
+	return resp, nil
+}
+
+func (ac *acmeContext) accountURL(id string) string {
+	return ac.baseURL + "account/" + id
+}
+
+func (ac *acmeContext) orderURL(id string) string {
+	return ac.baseURL + "order/" + id
+}
+
+func (ac *acmeContext) authorizationURL(id string) string {
+	return ac.baseURL + "authorization/" + id
+}
+
+func (ac *acmeContext) challengeURL(authzID string, challengeType string) string {
+	return ac.baseURL + "challenge/" + authzID + "/" + challengeType
+}
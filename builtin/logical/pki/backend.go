@@ -94,6 +94,10 @@ func Backend(conf *logical.BackendConfig) *backend {
 				"issuers/", // LIST operations append a '/' to the requested path
 				"ocsp",     // OCSP POST
 				"ocsp/*",   // OCSP GET
+				"acme/*",
+				"roles/+/acme/*",
+				"est/*",
+				"roles/+/est/*",
 			},
 
 			LocalStorage: []string{
@@ -102,6 +106,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 				legacyCRLPath,
 				"crls/",
 				"certs/",
+				certInventoryPrefix,
+				acmeStoragePrefix,
 			},
 
 			Root: []string{
@@ -138,6 +144,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathTidyCancel(&b),
 			pathTidyStatus(&b),
 			pathConfigAutoTidy(&b),
+			pathConfigAcme(&b),
+			pathConfigEst(&b),
 
 			// Issuer APIs
 			pathListIssuers(&b),
@@ -148,6 +156,7 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathIssuerSign(&b),
 			pathIssuerSignIntermediate(&b),
 			pathIssuerSignSelfIssued(&b),
+			pathIssuerCrossSign(&b),
 			pathIssuerSignVerbatim(&b),
 			pathIssuerGenerateRoot(&b),
 			pathRotateRoot(&b),
@@ -172,6 +181,8 @@ func Backend(conf *logical.BackendConfig) *backend {
 			pathFetchValidRaw(&b),
 			pathFetchValid(&b),
 			pathFetchListCerts(&b),
+			pathCertSearch(&b),
+			pathCertExport(&b),
 
 			// OCSP APIs
 			buildPathOcspGet(&b),
@@ -188,9 +199,40 @@ func Backend(conf *logical.BackendConfig) *backend {
 		PeriodicFunc:   b.periodicFunc,
 	}
 
+	// ACME APIs are served both from the mount's default directory and from
+	// a directory per role.
+	for _, acmePrefix := range []string{"acme/", "roles/" + framework.GenericNameRegex("role") + "/acme/"} {
+		b.Backend.Paths = append(b.Backend.Paths,
+			pathAcmeDirectory(&b, acmePrefix),
+			pathAcmeNewNonce(&b, acmePrefix),
+			pathAcmeNewAccount(&b, acmePrefix),
+			pathAcmeAccount(&b, acmePrefix),
+			pathAcmeAccountOrders(&b, acmePrefix),
+			pathAcmeNewOrder(&b, acmePrefix),
+			pathAcmeOrder(&b, acmePrefix),
+			pathAcmeOrderFinalize(&b, acmePrefix),
+			pathAcmeOrderCert(&b, acmePrefix),
+			pathAcmeAuthorization(&b, acmePrefix),
+			pathAcmeChallenge(&b, acmePrefix),
+			pathAcmeRevokeCert(&b, acmePrefix),
+		)
+	}
+
+	// Likewise for EST, where the role takes the place of the optional
+	// label of RFC 7030.
+	for _, estPrefix := range []string{"est/", "roles/" + framework.GenericNameRegex("role") + "/est/"} {
+		b.Backend.Paths = append(b.Backend.Paths,
+			pathEstCACerts(&b, estPrefix),
+			pathEstCSRAttrs(&b, estPrefix),
+			pathEstSimpleEnroll(&b, estPrefix),
+			pathEstSimpleReenroll(&b, estPrefix),
+		)
+	}
+
 	b.tidyCASGuard = new(uint32)
 	b.tidyCancelCAS = new(uint32)
 	b.tidyStatus = &tidyStatus{state: tidyStatusInactive}
+	b.acmeState = newAcmeState()
 	b.storage = conf.StorageView
 	b.backendUUID = conf.BackendUUID
 
@@ -240,6 +282,8 @@ type backend struct {
 
 	// Write lock around issuers and keys.
 	issuersLock sync.RWMutex
+
+	acmeState *acmeState
 }
 
 type (
@@ -3514,6 +3514,154 @@ func TestBackend_AllowedDomainsTemplate(t *testing.T) {
 	}
 }
 
+func TestBackend_IssuancePolicy(t *testing.T) {
+	t.Parallel()
+	coreConfig := &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"userpass": userpass.Factory,
+		},
+		LogicalBackends: map[string]logical.Factory{
+			"pki": Factory,
+		},
+	}
+	cluster := vault.NewTestCluster(t, coreConfig, &vault.TestClusterOptions{
+		HandlerFunc: vaulthttp.Handler,
+	})
+	cluster.Start()
+	defer cluster.Cleanup()
+	client := cluster.Cores[0].Client
+
+	// Write test policy for userpass auth method.
+	err := client.Sys().PutPolicy("test", `
+   path "pki/*" {
+     capabilities = ["update"]
+   }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Enable userpass auth method.
+	if err := client.Sys().EnableAuth("userpass", "userpass", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Logical().Write("auth/userpass/users/userpassname", map[string]interface{}{
+		"password": "test",
+		"policies": "test",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Mount PKI.
+	err = client.Sys().Mount("pki", &api.MountInput{
+		Type: "pki",
+		Config: api.MountConfigInput{
+			DefaultLeaseTTL: "16h",
+			MaxLeaseTTL:     "60h",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Logical().Write("pki/root/generate/internal", map[string]interface{}{
+		"ttl":         "40h",
+		"common_name": "myvault.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Policies which don't parse are rejected.
+	_, err = client.Logical().Write("pki/roles/test", map[string]interface{}{
+		"issuance_policy": "{{ if }}",
+	})
+	if err == nil {
+		t.Fatal("expected error writing role with invalid issuance policy")
+	}
+
+	// Only allow names of the caller's team, and lowercase the common name.
+	policy := `
+{{- $team := index .Entity.Metadata "team" -}}
+{{- $suffix := printf ".%s.example.com" $team -}}
+{{- range .Request.DNSNames -}}
+  {{- if not (has_suffix . $suffix) }}{{ deny (printf "%s is not a name of team %q" . $team) }}{{ end -}}
+{{- end -}}
+{"common_name": {{ json (lowercase .Request.CommonName) }}}`
+	_, err = client.Logical().Write("pki/roles/test", map[string]interface{}{
+		"allowed_domains":    "example.com",
+		"allow_subdomains":   true,
+		"allow_bare_domains": true,
+		"issuance_policy":    policy,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Logical().Read("pki/roles/test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["issuance_policy"] != policy {
+		t.Fatalf("expected issuance policy to be stored, got %v", resp.Data["issuance_policy"])
+	}
+
+	// The root token has no entity and thus no team.
+	_, err = client.Logical().Write("pki/issue/test", map[string]interface{}{"common_name": "api.payments.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "denied by issuance policy") {
+		t.Fatalf("expected request without entity to be denied, got: %v", err)
+	}
+
+	// Login with userpass and tag the resulting entity with a team.
+	userpassAuth, err := auth.NewUserpassAuth("userpassname", &auth.Password{FromString: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	userClient, err := client.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret, err := userClient.Auth().Login(context.TODO(), userpassAuth)
+	if err != nil || secret == nil {
+		t.Fatal(err)
+	}
+	_, err = client.Logical().Write("identity/entity/id/"+secret.Auth.EntityID, map[string]interface{}{
+		"metadata": map[string]string{"team": "payments"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = userClient.Logical().Write("pki/issue/test", map[string]interface{}{
+		"common_name": "API.payments.example.com",
+		"alt_names":   "web.payments.example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert := parseCert(t, resp.Data["certificate"].(string))
+	if cert.Subject.CommonName != "api.payments.example.com" {
+		t.Fatalf("expected common name to be modified by the policy, got %q", cert.Subject.CommonName)
+	}
+
+	_, err = userClient.Logical().Write("pki/issue/test", map[string]interface{}{
+		"common_name": "api.payments.example.com",
+		"alt_names":   "web.billing.example.com",
+	})
+	if err == nil || !strings.Contains(err.Error(), `web.billing.example.com is not a name of team "payments"`) {
+		t.Fatalf("expected request for another team's name to be denied, got: %v", err)
+	}
+
+	// Names set by the policy must be allowed by the role.
+	_, err = client.Logical().JSONMergePatch(context.Background(), "pki/roles/test", map[string]interface{}{
+		"issuance_policy": `{"dns_names": ["evil.com"]}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = userClient.Logical().Write("pki/issue/test", map[string]interface{}{"common_name": "api.payments.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "evil.com set by the issuance policy not allowed") {
+		t.Fatalf("expected name set by the policy to be validated, got: %v", err)
+	}
+}
+
 func TestReadWriteDeleteRoles(t *testing.T) {
 	t.Parallel()
 	ctx := context.Background()
@@ -3617,6 +3765,7 @@ func TestReadWriteDeleteRoles(t *testing.T) {
 		"code_signing_flag":                  false,
 		"issuer_ref":                         "default",
 		"cn_validations":                     []interface{}{"email", "hostname"},
+		"issuance_policy":                    "",
 	}
 
 	if diff := deep.Equal(expectedData, resp.Data); len(diff) > 0 {
@@ -38,6 +38,10 @@ type inputBundle struct {
 	role    *roleEntry
 	req     *logical.Request
 	apiData *framework.FieldData
+	// namesAuthorized is set when the requested names were authorized
+	// beforehand, as the identifiers of an ACME order are, so that an
+	// issuance policy may not change them.
+	namesAuthorized bool
 }
 
 var (
@@ -86,22 +90,31 @@ func getFormat(data *framework.FieldData) string {
 // loading using the legacyBundleShimID and should be used with care. This should be called only once
 // within the request path otherwise you run the risk of a race condition with the issuer migration on perf-secondaries.
 func (sc *storageContext) fetchCAInfo(issuerRef string, usage issuerUsage) (*certutil.CAInfoBundle, error) {
+	caInfo, _, err := sc.fetchCAInfoAndIssuerId(issuerRef, usage)
+	return caInfo, err
+}
+
+// fetchCAInfoAndIssuerId behaves like fetchCAInfo, additionally returning the
+// identifier of the referenced issuer. The identifier is empty when the
+// legacy CA bundle is in use.
+func (sc *storageContext) fetchCAInfoAndIssuerId(issuerRef string, usage issuerUsage) (*certutil.CAInfoBundle, issuerID, error) {
 	var issuerId issuerID
 
 	if sc.Backend.useLegacyBundleCaStorage() {
 		// We have not completed the migration so attempt to load the bundle from the legacy location
 		sc.Backend.Logger().Info("Using legacy CA bundle as PKI migration has not completed.")
-		issuerId = legacyBundleShimID
-	} else {
-		var err error
-		issuerId, err = sc.resolveIssuerReference(issuerRef)
-		if err != nil {
-			// Usually a bad label from the user or mis-configured default.
-			return nil, errutil.UserError{Err: err.Error()}
-		}
+		caInfo, err := sc.fetchCAInfoByIssuerId(legacyBundleShimID, usage)
+		return caInfo, "", err
+	}
+
+	issuerId, err := sc.resolveIssuerReference(issuerRef)
+	if err != nil {
+		// Usually a bad label from the user or mis-configured default.
+		return nil, "", errutil.UserError{Err: err.Error()}
 	}
 
-	return sc.fetchCAInfoByIssuerId(issuerId, usage)
+	caInfo, err := sc.fetchCAInfoByIssuerId(issuerId, usage)
+	return caInfo, issuerId, err
 }
 
 // fetchCAInfoByIssuerId will fetch the CA info, will return an error if no ca info exists for the given issuerId.
@@ -690,6 +703,10 @@ func generateCert(sc *storageContext,
 		return nil, nil, errutil.InternalError{Err: "nil parameters received from parameter bundle generation"}
 	}
 
+	if err := applyIssuancePolicy(b, input, data.Params, nil); err != nil {
+		return nil, nil, err
+	}
+
 	if isCA {
 		data.Params.IsCA = isCA
 		data.Params.PermittedDNSDomains = input.apiData.Get("permitted_dns_domains").([]string)
@@ -925,6 +942,10 @@ func signCert(b *backend,
 	creation.Params.IsCA = isCA
 	creation.Params.UseCSRValues = useCSRValues
 
+	if err := applyIssuancePolicy(b, data, creation.Params, csr); err != nil {
+		return nil, nil, err
+	}
+
 	if isCA {
 		creation.Params.PermittedDNSDomains = data.apiData.Get("permitted_dns_domains").([]string)
 	}
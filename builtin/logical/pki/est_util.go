@@ -0,0 +1,318 @@
+package pki
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	estCertsContentType    = "application/pkcs7-mime; smime-type=certs-only"
+	estCSRAttrsContentType = "application/csrattrs"
+	estErrorContentType    = "text/plain; charset=utf-8"
+
+	// estMaxRequestSize bounds the size of the base64 encoded PKCS#10
+	// request bodies we are willing to read.
+	estMaxRequestSize = 64 * 1024
+)
+
+var (
+	oidPKCS7SignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidPKCS7Data       = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+)
+
+// estError is returned by EST handlers to produce a plain text error
+// response with the given HTTP status, as described by RFC 7030 Section
+// 4.2.3, instead of a regular Vault error response.
+type estError struct {
+	status int
+	detail string
+}
+
+func (e *estError) Error() string {
+	return e.detail
+}
+
+func estErrorf(status int, format string, args ...interface{}) error {
+	return &estError{
+		status: status,
+		detail: fmt.Sprintf(format, args...),
+	}
+}
+
+// estLoginSystemView is implemented by the system view Vault hands to
+// builtin backends. It is not available when running as an external plugin,
+// in which case EST clients cannot authenticate to simpleenroll.
+type estLoginSystemView interface {
+	AuthenticateLogin(ctx context.Context, accessor string, req *logical.Request) (*logical.Auth, error)
+}
+
+// estContext carries the state of a single request to one of the EST
+// endpoints of this mount.
+type estContext struct {
+	sc     *storageContext
+	config *estConfigEntry
+
+	// roleName and role are the role issuance is performed under; both are
+	// empty when issuing sign-verbatim style via the default endpoints.
+	roleName string
+	role     *roleEntry
+}
+
+type estOperation func(ec *estContext, req *logical.Request, data *framework.FieldData) (*logical.Response, error)
+
+// estWrapper loads the EST configuration and role for the request, then
+// invokes the operation, converting any estError it returns into a plain
+// text response.
+func (b *backend) estWrapper(op estOperation) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+		ec, err := b.newEstContext(ctx, req, data)
+		if err != nil {
+			return b.estErrorResponse(err)
+		}
+
+		resp, err := op(ec, req, data)
+		if err != nil {
+			return b.estErrorResponse(err)
+		}
+		return resp, nil
+	}
+}
+
+func (b *backend) newEstContext(ctx context.Context, req *logical.Request, data *framework.FieldData) (*estContext, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	ec := &estContext{
+		sc: sc,
+	}
+
+	config, err := sc.getEstConfig()
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, estErrorf(http.StatusNotFound, "EST is disabled on this mount")
+	}
+	ec.config = config
+
+	if roleRaw, ok := data.GetOk("role"); ok {
+		ec.roleName = roleRaw.(string)
+		if !config.isRoleAllowed(ec.roleName) {
+			return nil, estErrorf(http.StatusForbidden, "role %q is not allowed to be used with EST", ec.roleName)
+		}
+	} else {
+		ec.roleName = config.DefaultRole
+	}
+
+	if ec.roleName != "" {
+		ec.role, err = b.getRole(ctx, req.Storage, ec.roleName)
+		if err != nil {
+			return nil, err
+		}
+		if ec.role == nil {
+			return nil, estErrorf(http.StatusNotFound, "unknown role: %v", ec.roleName)
+		}
+	}
+
+	return ec, nil
+}
+
+func (b *backend) estErrorResponse(err error) (*logical.Response, error) {
+	var eErr *estError
+	if !errors.As(err, &eErr) {
+		// Let the request forwarding logic see these, so that issuance on a
+		// performance standby ends up on the active node.
+		if errors.Is(err, logical.ErrReadOnly) {
+			return nil, err
+		}
+		b.Logger().Warn("unexpected error processing EST request", "error", err)
+		eErr = &estError{status: http.StatusInternalServerError, detail: "internal error processing EST request"}
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: estErrorContentType,
+			logical.HTTPStatusCode:  eErr.status,
+			logical.HTTPRawBody:     []byte(eErr.detail + "\n"),
+		},
+	}
+	if eErr.status == http.StatusUnauthorized {
+		resp.Headers = map[string][]string{
+			"WWW-Authenticate": {`Basic realm="vault-est"`},
+		}
+	}
+	return resp, nil
+}
+
+// estBase64Response builds a successful EST response with the given DER
+// body, base64 encoded as RFC 7030 Section 4 requires.
+func estBase64Response(contentType string, der []byte) *logical.Response {
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType:        contentType,
+			logical.HTTPStatusCode:         http.StatusOK,
+			logical.HTTPRawBody:            []byte(base64.StdEncoding.EncodeToString(der)),
+			logical.HTTPCacheControlHeader: "no-store",
+		},
+		Headers: map[string][]string{
+			"Content-Transfer-Encoding": {"base64"},
+		},
+	}
+}
+
+// estCertsOnly encodes the given DER certificates as a degenerate, certs-only
+// PKCS#7 SignedData structure (RFC 5652 Section 5, RFC 7030 Section 4.1.3).
+func estCertsOnly(certs [][]byte) ([]byte, error) {
+	type contentInfo struct {
+		ContentType asn1.ObjectIdentifier
+	}
+	type signedData struct {
+		Version          int
+		DigestAlgorithms asn1.RawValue
+		ContentInfo      contentInfo
+		Certificates     asn1.RawValue
+		SignerInfos      asn1.RawValue
+	}
+
+	var certBytes []byte
+	for _, cert := range certs {
+		certBytes = append(certBytes, cert...)
+	}
+
+	emptySet := asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true}
+	sd, err := asn1.Marshal(signedData{
+		Version:          1,
+		DigestAlgorithms: emptySet,
+		ContentInfo:      contentInfo{ContentType: oidPKCS7Data},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certBytes},
+		SignerInfos:      emptySet,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return asn1.Marshal(struct {
+		ContentType asn1.ObjectIdentifier
+		Content     asn1.RawValue
+	}{
+		ContentType: oidPKCS7SignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: sd},
+	})
+}
+
+// readEstCSR reads and parses the base64 encoded PKCS#10 body of a
+// simpleenroll or simplereenroll request.
+func readEstCSR(req *logical.Request) (*x509.CertificateRequest, error) {
+	if req.HTTPRequest == nil || req.HTTPRequest.Body == nil {
+		return nil, estErrorf(http.StatusUnsupportedMediaType, "expected an application/pkcs10 request body")
+	}
+
+	rawBody := req.HTTPRequest.Body
+	defer rawBody.Close()
+
+	body, err := io.ReadAll(io.LimitReader(rawBody, estMaxRequestSize))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) >= estMaxRequestSize {
+		return nil, estErrorf(http.StatusRequestEntityTooLarge, "request is too large")
+	}
+
+	// Base64 bodies may be line wrapped, as with MIME.
+	encoded := strings.Map(func(r rune) rune {
+		switch r {
+		case '\r', '\n', ' ', '\t':
+			return -1
+		}
+		return r
+	}, string(body))
+
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, estErrorf(http.StatusBadRequest, "failed to decode CSR: %v", err)
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, estErrorf(http.StatusBadRequest, "failed to parse CSR: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, estErrorf(http.StatusBadRequest, "invalid CSR signature: %v", err)
+	}
+
+	return csr, nil
+}
+
+// estPeerCertificate returns the TLS client certificate of the request, if
+// any.
+func estPeerCertificate(req *logical.Request) *x509.Certificate {
+	if req.Connection == nil || req.Connection.ConnState == nil {
+		return nil
+	}
+	if len(req.Connection.ConnState.PeerCertificates) == 0 {
+		return nil
+	}
+	return req.Connection.ConnState.PeerCertificates[0]
+}
+
+// authenticate verifies the client of a simpleenroll request, using the TLS
+// client certificate or HTTP basic credentials of the request to log in to
+// the configured auth mounts.
+func (ec *estContext) authenticate(b *backend, req *logical.Request) error {
+	loginView, haveLogin := b.System().(estLoginSystemView)
+
+	if ec.config.CertAuthAccessor != "" && estPeerCertificate(req) != nil {
+		if !haveLogin {
+			return fmt.Errorf("authenticating EST clients is not supported by this system view")
+		}
+
+		_, err := loginView.AuthenticateLogin(ec.sc.Context, ec.config.CertAuthAccessor, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "login",
+			Connection: req.Connection,
+			Data:       map[string]interface{}{},
+		})
+		if err == nil {
+			return nil
+		}
+		b.Logger().Debug("EST client certificate login failed", "error", err)
+	}
+
+	if ec.config.BasicAuthAccessor != "" && req.HTTPRequest != nil {
+		if username, password, ok := req.HTTPRequest.BasicAuth(); ok && username != "" {
+			// The username becomes part of the login path, so it must not
+			// be able to reach other paths of the auth mount.
+			if strings.Contains(username, "/") {
+				b.Logger().Debug("EST basic login rejected, invalid username", "username", username)
+				return estErrorf(http.StatusUnauthorized, "authentication required")
+			}
+
+			if !haveLogin {
+				return fmt.Errorf("authenticating EST clients is not supported by this system view")
+			}
+
+			_, err := loginView.AuthenticateLogin(ec.sc.Context, ec.config.BasicAuthAccessor, &logical.Request{
+				Operation:  logical.UpdateOperation,
+				Path:       "login/" + username,
+				Connection: req.Connection,
+				Data: map[string]interface{}{
+					"password": password,
+				},
+			})
+			if err == nil {
+				return nil
+			}
+			b.Logger().Debug("EST basic login failed", "username", username, "error", err)
+		}
+	}
+
+	return estErrorf(http.StatusUnauthorized, "authentication required")
+}
@@ -9,6 +9,7 @@ import (
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/vault/sdk/logical"
@@ -387,3 +388,132 @@ func genTestRootCaWithIssuerName(t *testing.T, b *backend, s logical.Storage, is
 
 	return issuerId, keyId
 }
+
+func TestIntegration_CrossSignIssuer(t *testing.T) {
+	t.Parallel()
+	b, s := createBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root X1",
+		"issuer_name": "old-root",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating old root")
+	oldRoot := parseCert(t, resp.Data["certificate"].(string))
+
+	resp, err = CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root X2",
+		"issuer_name": "new-root",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating new root")
+	newRootPem := resp.Data["certificate"].(string)
+	newRoot := parseCert(t, newRootPem)
+
+	// Chains of stored issuers and of responses differ in trailing
+	// whitespace, so compare the parsed certificates instead.
+	requireChainContains := func(chain interface{}, cert *x509.Certificate) {
+		for _, pemCert := range chain.([]string) {
+			if parseCert(t, pemCert).Equal(cert) {
+				return
+			}
+		}
+		t.Fatalf("expected chain to contain %v: %v", cert.Subject, chain)
+	}
+
+	// Exactly one of existing_issuer_ref and certificate is required.
+	_, err = CBWrite(b, s, "issuer/new-root/cross-sign", map[string]interface{}{})
+	require.Error(t, err, "expected error without a certificate to cross-sign")
+	_, err = CBWrite(b, s, "issuer/new-root/cross-sign", map[string]interface{}{
+		"existing_issuer_ref": "old-root",
+		"certificate":         newRootPem,
+	})
+	require.Error(t, err, "expected error with both existing_issuer_ref and certificate")
+	_, err = CBWrite(b, s, "issuer/new-root/cross-sign", map[string]interface{}{
+		"certificate": newRootPem,
+	})
+	require.Error(t, err, "expected error cross-signing an issuer with its own key")
+	_, err = CBWrite(b, s, "issuer/new-root/cross-sign", map[string]interface{}{
+		"certificate": newRootPem,
+		"issuer_name": "cross",
+	})
+	require.Error(t, err, "expected error using issuer_name without existing_issuer_ref")
+
+	// Cross-sign the old root locally; the result is imported as an issuer
+	// sharing the old root's key and chaining to the new root.
+	resp, err = CBWrite(b, s, "issuer/new-root/cross-sign", map[string]interface{}{
+		"existing_issuer_ref": "old-root",
+		"issuer_name":         "old-root-cross",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed cross-signing old root")
+	requireFieldsSetInResp(t, resp, "certificate", "issuing_ca", "ca_chain", "serial_number", "expiration", "issuer_id", "issuer_name")
+	require.Equal(t, "old-root-cross", resp.Data["issuer_name"])
+	crossCert := parseCert(t, resp.Data["certificate"].(string))
+	require.Equal(t, oldRoot.RawSubject, crossCert.RawSubject)
+	require.Equal(t, oldRoot.SubjectKeyId, crossCert.SubjectKeyId)
+	require.Equal(t, oldRoot.NotAfter, crossCert.NotAfter)
+	requireMatchingPublicKeys(t, crossCert, oldRoot.PublicKey)
+	requireSignedBy(t, crossCert, newRoot)
+	requireChainContains(resp.Data["ca_chain"], newRoot)
+	localCrossSerial := resp.Data["serial_number"].(string)
+
+	resp, err = CBRead(b, s, "issuer/old-root-cross")
+	requireSuccessNonNilResponse(t, resp, err, "failed reading cross-signed issuer")
+	oldRootIssuer, err := CBRead(b, s, "issuer/old-root")
+	requireSuccessNonNilResponse(t, oldRootIssuer, err, "failed reading old root")
+	require.Equal(t, oldRootIssuer.Data["key_id"], resp.Data["key_id"])
+	requireChainContains(resp.Data["ca_chain"], newRoot)
+
+	// Cross-sign the root of another mount, then import the result and its
+	// chain there.
+	otherB, otherS := createBackendWithStorage(t)
+	resp, err = CBWrite(otherB, otherS, "root/generate/internal", map[string]interface{}{
+		"common_name": "Other Root",
+		"issuer_name": "other-root",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating other root")
+	otherRoot := parseCert(t, resp.Data["certificate"].(string))
+
+	resp, err = CBWrite(b, s, "issuer/new-root/cross-sign", map[string]interface{}{
+		"certificate": resp.Data["certificate"],
+		"ttl":         "2h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed cross-signing other root")
+	require.NotContains(t, resp.Data, "issuer_id")
+	crossCert = parseCert(t, resp.Data["certificate"].(string))
+	require.Equal(t, otherRoot.RawSubject, crossCert.RawSubject)
+	requireMatchingPublicKeys(t, crossCert, otherRoot.PublicKey)
+	requireSignedBy(t, crossCert, newRoot)
+	caChain := resp.Data["ca_chain"].([]string)
+	require.Equal(t, resp.Data["certificate"], caChain[0])
+	requireChainContains(caChain, newRoot)
+	otherCrossSerial := resp.Data["serial_number"].(string)
+
+	// Both cross-signed certificates are stored and inventoried as issued by
+	// the new root, so they can be found and revoked like any other.
+	for _, serial := range []string{localCrossSerial, otherCrossSerial} {
+		resp, err = CBRead(b, s, "cert/"+serial)
+		requireSuccessNonNilResponse(t, resp, err, "failed reading cross-signed certificate %s", serial)
+	}
+	resp, err = CBWrite(b, s, "certs/search", map[string]interface{}{
+		"issuer_ref": "new-root",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed searching certificates of the new root")
+	require.Subset(t, resp.Data["keys"], []string{localCrossSerial, otherCrossSerial})
+
+	resp, err = CBWrite(otherB, otherS, "issuers/import/cert", map[string]interface{}{
+		"pem_bundle": strings.Join(caChain, "\n"),
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed importing cross-signed certificate")
+	importedIssuers := resp.Data["imported_issuers"].([]string)
+	require.Len(t, importedIssuers, 2)
+
+	for _, id := range importedIssuers {
+		resp, err = CBRead(otherB, otherS, "issuer/"+id)
+		requireSuccessNonNilResponse(t, resp, err, "failed reading imported issuer")
+		if resp.Data["certificate"] == caChain[0] {
+			require.NotEmpty(t, resp.Data["key_id"], "expected cross-signed issuer to use the existing key")
+			requireChainContains(resp.Data["ca_chain"], newRoot)
+		}
+	}
+}
@@ -0,0 +1,375 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/template"
+)
+
+// errIssuancePolicyDenied is returned from the deny function of an issuance
+// policy to abort its evaluation.
+var errIssuancePolicyDenied = errors.New("denied by issuance policy")
+
+// issuancePolicyInput is the data a role's issuance policy is evaluated
+// against.
+type issuancePolicyInput struct {
+	// Role is the name of the role the request is made against; it is
+	// empty for sign-verbatim requests without a role.
+	Role    string
+	Request issuancePolicyRequest
+	// CSR is nil unless a CSR was submitted.
+	CSR    *issuancePolicyCSR
+	Entity issuancePolicyEntity
+}
+
+// issuancePolicyRequest holds the names that will be placed on the
+// certificate, after the role has been applied to the request.
+type issuancePolicyRequest struct {
+	CommonName     string
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []string
+	URISANs        []string
+	NotAfter       time.Time
+}
+
+type issuancePolicyCSR struct {
+	CommonName         string
+	Organization       []string
+	OrganizationalUnit []string
+	DNSNames           []string
+	EmailAddresses     []string
+	IPAddresses        []string
+	URISANs            []string
+	KeyType            string
+	KeyBits            int
+}
+
+// issuancePolicyEntity describes the identity of the caller; its ID is empty
+// when the request's token is not associated with an entity.
+type issuancePolicyEntity struct {
+	ID       string
+	Name     string
+	Metadata map[string]string
+	Aliases  []issuancePolicyAlias
+	Groups   []issuancePolicyGroup
+}
+
+type issuancePolicyAlias struct {
+	MountAccessor string
+	MountType     string
+	Name          string
+	Metadata      map[string]string
+}
+
+type issuancePolicyGroup struct {
+	ID       string
+	Name     string
+	Metadata map[string]string
+}
+
+// issuancePolicyResult holds the modifications to the request an issuance
+// policy emitted. Fields which are omitted from the output are left as
+// requested.
+type issuancePolicyResult struct {
+	CommonName     *string   `json:"common_name"`
+	DNSNames       *[]string `json:"dns_names"`
+	EmailAddresses *[]string `json:"email_addresses"`
+	IPAddresses    *[]string `json:"ip_addresses"`
+	URISANs        *[]string `json:"uri_sans"`
+}
+
+func (r *issuancePolicyResult) empty() bool {
+	return r.CommonName == nil && r.DNSNames == nil && r.EmailAddresses == nil && r.IPAddresses == nil && r.URISANs == nil
+}
+
+// parseIssuancePolicy parses an issuance policy template. Calls to deny in
+// the template record their reason through the given callback.
+func parseIssuancePolicy(policy string, deny func(reason string)) (template.StringTemplate, error) {
+	return template.NewTemplate(
+		template.Template(policy),
+		template.Function("deny", func(reason string) (string, error) {
+			deny(reason)
+			return "", errIssuancePolicyDenied
+		}),
+		template.Function("json", func(in interface{}) (string, error) {
+			out, err := json.Marshal(in)
+			return string(out), err
+		}),
+		template.Function("join", func(elems []string, sep string) string {
+			return strings.Join(elems, sep)
+		}),
+		template.Function("has_prefix", strings.HasPrefix),
+		template.Function("has_suffix", strings.HasSuffix),
+		template.Function("matches", regexp.MatchString),
+	)
+}
+
+// evaluateIssuancePolicy evaluates a role's issuance policy. An error
+// wrapping errIssuancePolicyDenied is returned when the policy denies the
+// request.
+func evaluateIssuancePolicy(policy string, input *issuancePolicyInput) (*issuancePolicyResult, error) {
+	var denyReason string
+	tmpl, err := parseIssuancePolicy(policy, func(reason string) {
+		denyReason = reason
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := tmpl.Generate(input)
+	if denyReason != "" || errors.Is(err, errIssuancePolicyDenied) {
+		return nil, fmt.Errorf("%w: %s", errIssuancePolicyDenied, denyReason)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &issuancePolicyResult{}
+	output = strings.TrimSpace(output)
+	if output == "" {
+		return result, nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewBufferString(output))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(result); err != nil {
+		return nil, fmt.Errorf("failed to parse issuance policy output: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("failed to parse issuance policy output: unexpected data after JSON object")
+	}
+
+	return result, nil
+}
+
+// applyIssuancePolicy evaluates the role's issuance policy, if any, against
+// the parameters of a certificate about to be issued, rejecting the request
+// or modifying its names as the policy dictates. Modified names are subject
+// to the role's constraints like requested ones, and may not differ from the
+// requested ones when those were authorized beforehand.
+func applyIssuancePolicy(b *backend, data *inputBundle, params *certutil.CreationParameters, csr *x509.CertificateRequest) error {
+	if data.role.IssuancePolicy == "" {
+		return nil
+	}
+
+	requestedNames := certificateNames(params)
+	if err := applyIssuancePolicyResult(b, data, params, csr); err != nil {
+		return err
+	}
+
+	if data.namesAuthorized && !strutil.EquivalentSlices(requestedNames, certificateNames(params)) {
+		return errutil.UserError{Err: "the issuance policy changed the names of the certificate, which must match the authorized identifiers"}
+	}
+
+	return nil
+}
+
+// certificateNames returns the distinct names of a certificate about to be
+// issued, its common name included.
+func certificateNames(params *certutil.CreationParameters) []string {
+	var names []string
+	if params.Subject.CommonName != "" {
+		names = append(names, strings.ToLower(params.Subject.CommonName))
+	}
+	for _, name := range params.DNSNames {
+		names = append(names, strings.ToLower(name))
+	}
+	names = append(names, params.EmailAddresses...)
+	names = append(names, ipStrings(params.IPAddresses)...)
+	names = append(names, uriStrings(params.URIs)...)
+	return strutil.RemoveDuplicates(names, false)
+}
+
+// applyIssuancePolicyResult evaluates the role's issuance policy and applies
+// the modifications of its result to the parameters.
+func applyIssuancePolicyResult(b *backend, data *inputBundle, params *certutil.CreationParameters, csr *x509.CertificateRequest) error {
+	input, err := buildIssuancePolicyInput(b, data, params, csr)
+	if err != nil {
+		return err
+	}
+
+	result, err := evaluateIssuancePolicy(data.role.IssuancePolicy, input)
+	if err != nil {
+		if errors.Is(err, errIssuancePolicyDenied) {
+			return errutil.UserError{Err: err.Error()}
+		}
+		return errutil.InternalError{Err: fmt.Sprintf("failed to evaluate issuance policy: %v", err)}
+	}
+	if result.empty() {
+		return nil
+	}
+
+	if params.UseCSRValues {
+		return errutil.UserError{Err: "the issuance policy modified the request, which is not supported when signing verbatim"}
+	}
+
+	if result.CommonName != nil {
+		if *result.CommonName != "" {
+			if badName := validateCommonName(b, data, *result.CommonName); badName != "" {
+				return errutil.UserError{Err: fmt.Sprintf(
+					"common name %s set by the issuance policy not allowed by this role", badName)}
+			}
+		}
+		params.Subject.CommonName = *result.CommonName
+	}
+
+	if result.DNSNames != nil {
+		if badName := validateNames(b, data, *result.DNSNames); badName != "" {
+			return errutil.UserError{Err: fmt.Sprintf(
+				"subject alternate name %s set by the issuance policy not allowed by this role", badName)}
+		}
+		params.DNSNames = *result.DNSNames
+	}
+
+	if result.EmailAddresses != nil {
+		if badName := validateNames(b, data, *result.EmailAddresses); badName != "" {
+			return errutil.UserError{Err: fmt.Sprintf(
+				"email address %s set by the issuance policy not allowed by this role", badName)}
+		}
+		params.EmailAddresses = *result.EmailAddresses
+	}
+
+	if result.IPAddresses != nil {
+		if len(*result.IPAddresses) > 0 && !data.role.AllowIPSANs {
+			return errutil.UserError{Err: "IP Subject Alternative Names set by the issuance policy are not allowed in this role"}
+		}
+		ipAddresses := make([]net.IP, 0, len(*result.IPAddresses))
+		for _, v := range *result.IPAddresses {
+			parsedIP := net.ParseIP(v)
+			if parsedIP == nil {
+				return errutil.UserError{Err: fmt.Sprintf(
+					"the value %q set by the issuance policy is not a valid IP address", v)}
+			}
+			ipAddresses = append(ipAddresses, parsedIP)
+		}
+		params.IPAddresses = ipAddresses
+	}
+
+	if result.URISANs != nil {
+		if len(*result.URISANs) > 0 && len(data.role.AllowedURISANs) == 0 {
+			return errutil.UserError{Err: "URI Subject Alternative Names set by the issuance policy are not allowed in this role"}
+		}
+		uris := make([]*url.URL, 0, len(*result.URISANs))
+		for _, uri := range *result.URISANs {
+			if !validateURISAN(b, data, uri) {
+				return errutil.UserError{Err: fmt.Sprintf(
+					"URI Subject Alternative Name %q set by the issuance policy is not valid for this role", uri)}
+			}
+			parsedURI, err := url.Parse(uri)
+			if parsedURI == nil || err != nil {
+				return errutil.UserError{Err: fmt.Sprintf(
+					"the URI Subject Alternative Name %q set by the issuance policy is not a valid URI", uri)}
+			}
+			uris = append(uris, parsedURI)
+		}
+		params.URIs = uris
+	}
+
+	return nil
+}
+
+func buildIssuancePolicyInput(b *backend, data *inputBundle, params *certutil.CreationParameters, csr *x509.CertificateRequest) (*issuancePolicyInput, error) {
+	input := &issuancePolicyInput{
+		Request: issuancePolicyRequest{
+			CommonName:     params.Subject.CommonName,
+			DNSNames:       params.DNSNames,
+			EmailAddresses: params.EmailAddresses,
+			IPAddresses:    ipStrings(params.IPAddresses),
+			URISANs:        uriStrings(params.URIs),
+			NotAfter:       params.NotAfter,
+		},
+	}
+
+	if data.apiData != nil {
+		if role, ok := data.apiData.GetOk("role"); ok {
+			input.Role = role.(string)
+		}
+	}
+
+	if csr != nil {
+		keyType, keyBits, err := getKeyTypeAndBitsFromPublicKeyForRole(csr.PublicKey)
+		if err != nil {
+			return nil, errutil.UserError{Err: err.Error()}
+		}
+		input.CSR = &issuancePolicyCSR{
+			CommonName:         csr.Subject.CommonName,
+			Organization:       csr.Subject.Organization,
+			OrganizationalUnit: csr.Subject.OrganizationalUnit,
+			DNSNames:           csr.DNSNames,
+			EmailAddresses:     csr.EmailAddresses,
+			IPAddresses:        ipStrings(csr.IPAddresses),
+			URISANs:            uriStrings(csr.URIs),
+			KeyType:            string(keyType),
+			KeyBits:            keyBits,
+		}
+	}
+
+	if data.req == nil || data.req.EntityID == "" {
+		return input, nil
+	}
+
+	entity, err := b.System().EntityInfo(data.req.EntityID)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("failed to look up entity: %v", err)}
+	}
+	if entity == nil {
+		return input, nil
+	}
+
+	input.Entity = issuancePolicyEntity{
+		ID:       entity.ID,
+		Name:     entity.Name,
+		Metadata: entity.Metadata,
+	}
+	for _, alias := range entity.Aliases {
+		input.Entity.Aliases = append(input.Entity.Aliases, issuancePolicyAlias{
+			MountAccessor: alias.MountAccessor,
+			MountType:     alias.MountType,
+			Name:          alias.Name,
+			Metadata:      alias.Metadata,
+		})
+	}
+
+	groups, err := b.System().GroupsForEntity(data.req.EntityID)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("failed to look up groups of entity: %v", err)}
+	}
+	for _, group := range groups {
+		input.Entity.Groups = append(input.Entity.Groups, issuancePolicyGroup{
+			ID:       group.ID,
+			Name:     group.Name,
+			Metadata: group.Metadata,
+		})
+	}
+
+	return input, nil
+}
+
+func ipStrings(ips []net.IP) []string {
+	out := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		out = append(out, ip.String())
+	}
+	return out
+}
+
+func uriStrings(uris []*url.URL) []string {
+	out := make([]string, 0, len(uris))
+	for _, uri := range uris {
+		out = append(out, uri.String())
+	}
+	return out
+}
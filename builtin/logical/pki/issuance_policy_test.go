@@ -0,0 +1,147 @@
+package pki
+
+import (
+	"crypto/x509/pkix"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+)
+
+func TestEvaluateIssuancePolicy(t *testing.T) {
+	t.Parallel()
+
+	input := &issuancePolicyInput{
+		Role: "web",
+		Request: issuancePolicyRequest{
+			CommonName: "Www.Example.com",
+			DNSNames:   []string{"www.example.com", "example.com"},
+		},
+		Entity: issuancePolicyEntity{
+			ID:       "entity-id",
+			Metadata: map[string]string{"team": "web"},
+		},
+	}
+	commonName := "www.example.com"
+	dnsNames := []string{"www.example.com"}
+
+	cases := []struct {
+		name     string
+		policy   string
+		expected *issuancePolicyResult
+		denied   bool
+		err      bool
+	}{
+		{
+			name:     "empty output",
+			policy:   `{{ if eq .Role "web" }}{{ end }}`,
+			expected: &issuancePolicyResult{},
+		},
+		{
+			name:     "modification",
+			policy:   `{"common_name": {{ json (lowercase .Request.CommonName) }}, "dns_names": ["{{ index .Request.DNSNames 0 }}"]}`,
+			expected: &issuancePolicyResult{CommonName: &commonName, DNSNames: &dnsNames},
+		},
+		{
+			name:   "deny",
+			policy: `{{ if ne (index .Entity.Metadata "team") "payments" }}{{ deny "wrong team" }}{{ end }}`,
+			denied: true,
+		},
+		{
+			name:     "no deny",
+			policy:   `{{ if not (matches "^[a-z]+$" (index .Entity.Metadata "team")) }}{{ deny "bad team" }}{{ end }}`,
+			expected: &issuancePolicyResult{},
+		},
+		{
+			name:   "unknown field",
+			policy: `{"ttl": "1h"}`,
+			err:    true,
+		},
+		{
+			name:   "trailing data",
+			policy: `{} {}`,
+			err:    true,
+		},
+		{
+			name:   "invalid json",
+			policy: `allow`,
+			err:    true,
+		},
+		{
+			name:   "execution error",
+			policy: `{{ index .Request.DNSNames 5 }}`,
+			err:    true,
+		},
+	}
+
+	for _, tc := range cases {
+		result, err := evaluateIssuancePolicy(tc.policy, input)
+		switch {
+		case tc.denied:
+			if !errors.Is(err, errIssuancePolicyDenied) {
+				t.Fatalf("%s: expected request to be denied, got: %v", tc.name, err)
+			}
+		case tc.err:
+			if err == nil || errors.Is(err, errIssuancePolicyDenied) {
+				t.Fatalf("%s: expected error, got: %v", tc.name, err)
+			}
+		default:
+			if err != nil {
+				t.Fatalf("%s: unexpected error: %v", tc.name, err)
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("%s: expected %#v, got %#v", tc.name, tc.expected, result)
+			}
+		}
+	}
+}
+
+func TestApplyIssuancePolicy_AuthorizedNames(t *testing.T) {
+	t.Parallel()
+
+	b, _ := createBackendWithStorage(t)
+
+	cases := []struct {
+		name    string
+		policy  string
+		allowed bool
+	}{
+		{
+			name:    "no modification",
+			policy:  `{{ if eq .Role "acme" }}{{ end }}`,
+			allowed: true,
+		},
+		{
+			name:    "common name moved to a SAN",
+			policy:  `{"common_name": "www.example.com"}`,
+			allowed: true,
+		},
+		{
+			name:   "added name",
+			policy: `{"dns_names": ["example.com", "www.example.com", "evil.example.com"]}`,
+		},
+		{
+			name:   "removed name",
+			policy: `{"common_name": "www.example.com", "dns_names": ["www.example.com"]}`,
+		},
+	}
+
+	for _, tc := range cases {
+		for _, namesAuthorized := range []bool{false, true} {
+			role := &roleEntry{
+				AllowAnyName:     true,
+				EnforceHostnames: true,
+				IssuancePolicy:   tc.policy,
+			}
+			params := &certutil.CreationParameters{
+				Subject:  pkix.Name{CommonName: "example.com"},
+				DNSNames: []string{"example.com", "www.example.com"},
+			}
+			err := applyIssuancePolicy(b, &inputBundle{role: role, namesAuthorized: namesAuthorized}, params, nil)
+			if (err == nil) != (tc.allowed || !namesAuthorized) {
+				t.Fatalf("%s: unexpected result with authorized names %v: %v", tc.name, namesAuthorized, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package pki
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func addAcmeRoleField(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The role to issue certificates with, when using a role-scoped ACME directory.`,
+	}
+	return fields
+}
+
+// buildAcmePath builds a JWS-authenticated (POST) ACME path under the given
+// directory prefix.
+func buildAcmePath(b *backend, pattern string, fields map[string]*framework.FieldSchema, mode acmeKeyMode, op acmeParsedOperation, synopsis string) *framework.Path {
+	return &framework.Path{
+		Pattern: pattern,
+		Fields:  addAcmeJWSFields(addAcmeRoleField(fields)),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                  b.acmeParsedWrapper(mode, op),
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    synopsis,
+		HelpDescription: pathAcmeHelpDesc,
+	}
+}
+
+func pathAcmeDirectory(b *backend, prefix string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "directory",
+		Fields:  addAcmeRoleField(map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                  b.acmeWrapper(b.acmeDirectoryHandler),
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathAcmeDirectoryHelpSyn,
+		HelpDescription: pathAcmeHelpDesc,
+	}
+}
+
+func pathAcmeNewNonce(b *backend, prefix string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "new-nonce",
+		Fields:  addAcmeRoleField(map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			// Nonces are only tracked on the active node, so we always
+			// forward to it. Clients usually fetch them with HEAD requests.
+			logical.HeaderOperation: &framework.PathOperation{
+				Callback:                  b.acmeWrapper(b.acmeNewNonceHandler),
+				ForwardPerformanceStandby: true,
+			},
+			logical.ReadOperation: &framework.PathOperation{
+				Callback:                  b.acmeWrapper(b.acmeNewNonceHandler),
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathAcmeNewNonceHelpSyn,
+		HelpDescription: pathAcmeHelpDesc,
+	}
+}
+
+func pathAcmeNewAccount(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"new-account", map[string]*framework.FieldSchema{},
+		acmeRequireJWK, b.acmeNewAccountHandler, pathAcmeNewAccountHelpSyn)
+}
+
+func pathAcmeAccount(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"account/"+framework.GenericNameRegex("account_id"), map[string]*framework.FieldSchema{
+		"account_id": {
+			Type:        framework.TypeString,
+			Description: "The ACME account identifier",
+		},
+	}, acmeRequireKID, b.acmeAccountHandler, pathAcmeAccountHelpSyn)
+}
+
+func pathAcmeAccountOrders(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"account/"+framework.GenericNameRegex("account_id")+"/orders", map[string]*framework.FieldSchema{
+		"account_id": {
+			Type:        framework.TypeString,
+			Description: "The ACME account identifier",
+		},
+	}, acmeRequireKID, b.acmeAccountOrdersHandler, pathAcmeAccountOrdersHelpSyn)
+}
+
+func (b *backend) acmeDirectoryHandler(ac *acmeContext, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	directory := map[string]interface{}{
+		"newNonce":   ac.baseURL + "new-nonce",
+		"newAccount": ac.baseURL + "new-account",
+		"newOrder":   ac.baseURL + "new-order",
+		"revokeCert": ac.baseURL + "revoke-cert",
+		"meta": map[string]interface{}{
+			"externalAccountRequired": false,
+		},
+	}
+
+	return ac.respond(http.StatusOK, directory, "")
+}
+
+func (b *backend) acmeNewNonceHandler(ac *acmeContext, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	// RFC 8555 Section 7.2: HEAD requests get a 200, GET requests a 204.
+	status := http.StatusNoContent
+	if req.Operation == logical.HeaderOperation {
+		status = http.StatusOK
+	}
+	return ac.respond(status, nil, "")
+}
+
+func (ac *acmeContext) accountResponseBody(account *acmeAccount) map[string]interface{} {
+	contact := account.Contact
+	if contact == nil {
+		contact = []string{}
+	}
+
+	return map[string]interface{}{
+		"status":  account.Status,
+		"contact": contact,
+		"orders":  ac.accountURL(account.ID) + "/orders",
+	}
+}
+
+func validateAcmeContacts(contacts []string) error {
+	for _, contact := range contacts {
+		if !strings.HasPrefix(contact, "mailto:") || strings.Contains(contact, ",") {
+			return acmeErrorf(acmeErrUnsupportedContactType, "unsupported contact: %q", contact)
+		}
+	}
+	return nil
+}
+
+func (b *backend) acmeNewAccountHandler(ac *acmeContext, _ *logical.Request, _ *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Contact              []string `json:"contact"`
+		TermsOfServiceAgreed bool     `json:"termsOfServiceAgreed"`
+		OnlyReturnExisting   bool     `json:"onlyReturnExisting"`
+	}
+	if err := jws.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	account, err := ac.sc.fetchAcmeAccountByThumbprint(jws.thumbprint)
+	if err != nil {
+		return nil, err
+	}
+	if account != nil {
+		if account.Status != acmeStatusValid {
+			return nil, acmeErrorf(acmeErrUnauthorized, "account is %v", account.Status)
+		}
+		return ac.respond(http.StatusOK, ac.accountResponseBody(account), ac.accountURL(account.ID))
+	}
+
+	if payload.OnlyReturnExisting {
+		return nil, acmeErrorf(acmeErrAccountDoesNotExist, "no account exists for the given key")
+	}
+
+	if err := validateAcmeContacts(payload.Contact); err != nil {
+		return nil, err
+	}
+
+	key, err := jws.key.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	account = &acmeAccount{
+		ID:          genUuid(),
+		Thumbprint:  jws.thumbprint,
+		Key:         key,
+		Contact:     payload.Contact,
+		Status:      acmeStatusValid,
+		CreatedDate: time.Now(),
+	}
+	if err := ac.sc.writeAcmeAccount(account); err != nil {
+		return nil, err
+	}
+
+	return ac.respond(http.StatusCreated, ac.accountResponseBody(account), ac.accountURL(account.ID))
+}
+
+func (b *backend) acmeAccountHandler(ac *acmeContext, _ *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	account := jws.account
+	if account.ID != data.Get("account_id").(string) {
+		return nil, acmeErrorf(acmeErrUnauthorized, "account does not match the request's key id")
+	}
+
+	var payload struct {
+		Contact []string   `json:"contact"`
+		Status  acmeStatus `json:"status"`
+	}
+	if err := jws.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	dirty := false
+	if payload.Contact != nil {
+		if err := validateAcmeContacts(payload.Contact); err != nil {
+			return nil, err
+		}
+		account.Contact = payload.Contact
+		dirty = true
+	}
+
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		account.Status = acmeStatusDeactivated
+		dirty = true
+	default:
+		return nil, acmeErrorf(acmeErrMalformed, "invalid account status update: %q", payload.Status)
+	}
+
+	if dirty {
+		if err := ac.sc.writeAcmeAccount(account); err != nil {
+			return nil, err
+		}
+	}
+
+	return ac.respond(http.StatusOK, ac.accountResponseBody(account), ac.accountURL(account.ID))
+}
+
+func (b *backend) acmeAccountOrdersHandler(ac *acmeContext, _ *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	account := jws.account
+	if account.ID != data.Get("account_id").(string) {
+		return nil, acmeErrorf(acmeErrUnauthorized, "account does not match the request's key id")
+	}
+
+	orderIDs, err := ac.sc.listAcmeOrders(account.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	orders := []string{}
+	for _, orderID := range orderIDs {
+		order, err := ac.sc.fetchAcmeOrder(account.ID, orderID)
+		if err != nil {
+			return nil, err
+		}
+		if order == nil || order.Role != ac.roleName {
+			continue
+		}
+		if err := ac.sc.updateAcmeOrderStatus(order); err != nil {
+			return nil, err
+		}
+		if order.Status == acmeStatusPending || order.Status == acmeStatusReady {
+			orders = append(orders, ac.orderURL(order.ID))
+		}
+	}
+
+	return ac.respond(http.StatusOK, map[string]interface{}{"orders": orders}, "")
+}
+
+const pathAcmeHelpDesc = `
+This path is part of the ACME (RFC 8555) server of this mount. ACME clients
+such as certbot, lego or cert-manager discover the remaining endpoints from
+the directory; see the config/acme path to enable and configure ACME.
+`
+
+const pathAcmeDirectoryHelpSyn = `
+The ACME directory, listing the URLs of the other ACME endpoints.
+`
+
+const pathAcmeNewNonceHelpSyn = `
+Fetch a fresh ACME anti-replay nonce.
+`
+
+const pathAcmeNewAccountHelpSyn = `
+Create, or look up, the ACME account of the signing key.
+`
+
+const pathAcmeAccountHelpSyn = `
+Read, update or deactivate an ACME account.
+`
+
+const pathAcmeAccountOrdersHelpSyn = `
+List the outstanding orders of an ACME account.
+`
@@ -0,0 +1,720 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/certutil"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+var acmeOrderFields = map[string]*framework.FieldSchema{
+	"order_id": {
+		Type:        framework.TypeString,
+		Description: "The ACME order identifier",
+	},
+}
+
+func pathAcmeNewOrder(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"new-order", map[string]*framework.FieldSchema{},
+		acmeRequireKID, b.acmeNewOrderHandler, pathAcmeNewOrderHelpSyn)
+}
+
+func pathAcmeOrder(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"order/"+framework.GenericNameRegex("order_id"), copyAcmeFields(acmeOrderFields),
+		acmeRequireKID, b.acmeOrderHandler, pathAcmeOrderHelpSyn)
+}
+
+func pathAcmeOrderFinalize(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"order/"+framework.GenericNameRegex("order_id")+"/finalize", copyAcmeFields(acmeOrderFields),
+		acmeRequireKID, b.acmeOrderFinalizeHandler, pathAcmeOrderFinalizeHelpSyn)
+}
+
+func pathAcmeOrderCert(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"order/"+framework.GenericNameRegex("order_id")+"/cert", copyAcmeFields(acmeOrderFields),
+		acmeRequireKID, b.acmeOrderCertHandler, pathAcmeOrderCertHelpSyn)
+}
+
+func pathAcmeAuthorization(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"authorization/"+framework.GenericNameRegex("auth_id"), map[string]*framework.FieldSchema{
+		"auth_id": {
+			Type:        framework.TypeString,
+			Description: "The ACME authorization identifier",
+		},
+	}, acmeRequireKID, b.acmeAuthorizationHandler, pathAcmeAuthorizationHelpSyn)
+}
+
+func pathAcmeChallenge(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"challenge/"+framework.GenericNameRegex("auth_id")+"/"+framework.GenericNameRegex("challenge_type"), map[string]*framework.FieldSchema{
+		"auth_id": {
+			Type:        framework.TypeString,
+			Description: "The ACME authorization identifier",
+		},
+		"challenge_type": {
+			Type:        framework.TypeString,
+			Description: "The ACME challenge type",
+		},
+	}, acmeRequireKID, b.acmeChallengeHandler, pathAcmeChallengeHelpSyn)
+}
+
+func pathAcmeRevokeCert(b *backend, prefix string) *framework.Path {
+	return buildAcmePath(b, prefix+"revoke-cert", map[string]*framework.FieldSchema{},
+		acmeAllowJWKOrKID, b.acmeRevokeCertHandler, pathAcmeRevokeCertHelpSyn)
+}
+
+func copyAcmeFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	ret := make(map[string]*framework.FieldSchema, len(fields))
+	for name, schema := range fields {
+		ret[name] = schema
+	}
+	return ret
+}
+
+func genAcmeToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate challenge token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// normalizeAcmeIdentifier validates a requested identifier, returning it in
+// its canonical form, and checks that the directory's role permits it.
+func (b *backend) normalizeAcmeIdentifier(ac *acmeContext, req *logical.Request, identifier acmeIdentifier) (acmeIdentifier, error) {
+	switch identifier.Type {
+	case acmeIdentifierDNS:
+		value := strings.ToLower(identifier.Value)
+		if !hostnameRegex.MatchString(value) || strings.HasSuffix(value, ".") || strings.Contains(strings.TrimPrefix(value, "*."), "*") {
+			return identifier, acmeErrorf(acmeErrRejectedIdentifier, "invalid dns identifier: %q", identifier.Value)
+		}
+		input := &inputBundle{role: ac.role, req: req}
+		if badName := validateNames(b, input, []string{value}); badName != "" {
+			return identifier, acmeErrorf(acmeErrRejectedIdentifier, "identifier %q is not allowed by role %q", badName, ac.roleName)
+		}
+		return acmeIdentifier{Type: acmeIdentifierDNS, Value: value}, nil
+	case acmeIdentifierIP:
+		ip := net.ParseIP(identifier.Value)
+		if ip == nil {
+			return identifier, acmeErrorf(acmeErrRejectedIdentifier, "invalid ip identifier: %q", identifier.Value)
+		}
+		if !ac.role.AllowIPSANs {
+			return identifier, acmeErrorf(acmeErrRejectedIdentifier, "ip identifiers are not allowed by role %q", ac.roleName)
+		}
+		return acmeIdentifier{Type: acmeIdentifierIP, Value: ip.String()}, nil
+	default:
+		return identifier, acmeErrorf(acmeErrUnsupportedIdentifier, "unsupported identifier type: %q", identifier.Type)
+	}
+}
+
+// newAcmeAuthorization builds the pending authorization for an identifier,
+// offering the challenge types which may prove control of it.
+func newAcmeAuthorization(accountID string, identifier acmeIdentifier, expires time.Time) (*acmeAuthorization, error) {
+	authz := &acmeAuthorization{
+		ID:         genUuid(),
+		AccountID:  accountID,
+		Identifier: identifier,
+		Status:     acmeStatusPending,
+		Expires:    expires,
+	}
+
+	var challengeTypes []string
+	switch {
+	case identifier.Type == acmeIdentifierIP:
+		challengeTypes = []string{acmeChallengeHTTP01}
+	case strings.HasPrefix(identifier.Value, "*."):
+		authz.Wildcard = true
+		authz.Identifier.Value = strings.TrimPrefix(identifier.Value, "*.")
+		challengeTypes = []string{acmeChallengeDNS01}
+	default:
+		challengeTypes = []string{acmeChallengeHTTP01, acmeChallengeDNS01}
+	}
+
+	for _, challengeType := range challengeTypes {
+		token, err := genAcmeToken()
+		if err != nil {
+			return nil, err
+		}
+		authz.Challenges = append(authz.Challenges, &acmeChallenge{
+			Type:   challengeType,
+			Token:  token,
+			Status: acmeStatusPending,
+		})
+	}
+
+	return authz, nil
+}
+
+func (ac *acmeContext) orderResponseBody(order *acmeOrder) map[string]interface{} {
+	authorizations := make([]string, 0, len(order.AuthorizationIDs))
+	for _, authzID := range order.AuthorizationIDs {
+		authorizations = append(authorizations, ac.authorizationURL(authzID))
+	}
+
+	body := map[string]interface{}{
+		"status":         order.Status,
+		"expires":        order.Expires.UTC().Format(time.RFC3339),
+		"identifiers":    order.Identifiers,
+		"authorizations": authorizations,
+		"finalize":       ac.orderURL(order.ID) + "/finalize",
+	}
+	if !order.NotAfter.IsZero() {
+		body["notAfter"] = order.NotAfter.UTC().Format(time.RFC3339)
+	}
+	if order.Status == acmeStatusValid {
+		body["certificate"] = ac.orderURL(order.ID) + "/cert"
+	}
+
+	return body
+}
+
+func (ac *acmeContext) challengeResponseBody(authz *acmeAuthorization, challenge *acmeChallenge) map[string]interface{} {
+	body := map[string]interface{}{
+		"type":   challenge.Type,
+		"url":    ac.challengeURL(authz.ID, challenge.Type),
+		"status": challenge.Status,
+		"token":  challenge.Token,
+	}
+	if !challenge.Validated.IsZero() {
+		body["validated"] = challenge.Validated.UTC().Format(time.RFC3339)
+	}
+	if challenge.Error != "" {
+		body["error"] = map[string]interface{}{
+			"type":   acmeErrorPrefix + challenge.ErrorType,
+			"detail": challenge.Error,
+		}
+	}
+	return body
+}
+
+func (ac *acmeContext) authorizationResponseBody(authz *acmeAuthorization) map[string]interface{} {
+	challenges := make([]map[string]interface{}, 0, len(authz.Challenges))
+	for _, challenge := range authz.Challenges {
+		challenges = append(challenges, ac.challengeResponseBody(authz, challenge))
+	}
+
+	body := map[string]interface{}{
+		"identifier": authz.Identifier,
+		"status":     authz.Status,
+		"expires":    authz.Expires.UTC().Format(time.RFC3339),
+		"challenges": challenges,
+	}
+	if authz.Wildcard {
+		body["wildcard"] = true
+	}
+	return body
+}
+
+// fetchRequestedOrder loads the order named by the request on behalf of the
+// account, refreshing its status.
+func (ac *acmeContext) fetchRequestedOrder(data *framework.FieldData, account *acmeAccount) (*acmeOrder, error) {
+	order, err := ac.sc.fetchAcmeOrder(account.ID, data.Get("order_id").(string))
+	if err != nil {
+		return nil, err
+	}
+	if order == nil || order.Role != ac.roleName {
+		return nil, acmeErrorf(acmeErrNotFound, "unknown order")
+	}
+
+	if err := ac.sc.updateAcmeOrderStatus(order); err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+func (b *backend) acmeNewOrderHandler(ac *acmeContext, req *logical.Request, _ *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Identifiers []acmeIdentifier `json:"identifiers"`
+		NotBefore   string           `json:"notBefore"`
+		NotAfter    string           `json:"notAfter"`
+	}
+	if err := jws.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	if err := ac.checkAcmeIssuanceRole(); err != nil {
+		return nil, err
+	}
+
+	if len(payload.Identifiers) == 0 {
+		return nil, acmeErrorf(acmeErrMalformed, "at least one identifier is required")
+	}
+	if payload.NotBefore != "" {
+		return nil, acmeErrorf(acmeErrMalformed, "notBefore is not supported")
+	}
+
+	order := &acmeOrder{
+		ID:        genUuid(),
+		AccountID: jws.account.ID,
+		Role:      ac.roleName,
+		Status:    acmeStatusPending,
+		Expires:   time.Now().Add(acmeOrderLifetime),
+	}
+
+	if payload.NotAfter != "" {
+		notAfter, err := time.Parse(time.RFC3339, payload.NotAfter)
+		if err != nil {
+			return nil, acmeErrorf(acmeErrMalformed, "invalid notAfter: %v", err)
+		}
+		order.NotAfter = notAfter
+	}
+
+	seen := make(map[acmeIdentifier]bool, len(payload.Identifiers))
+	var authorizations []*acmeAuthorization
+	for _, requested := range payload.Identifiers {
+		identifier, err := b.normalizeAcmeIdentifier(ac, req, requested)
+		if err != nil {
+			return nil, err
+		}
+		if seen[identifier] {
+			continue
+		}
+		seen[identifier] = true
+
+		authz, err := newAcmeAuthorization(order.AccountID, identifier, order.Expires)
+		if err != nil {
+			return nil, err
+		}
+
+		order.Identifiers = append(order.Identifiers, identifier)
+		order.AuthorizationIDs = append(order.AuthorizationIDs, authz.ID)
+		authorizations = append(authorizations, authz)
+	}
+
+	for _, authz := range authorizations {
+		if err := ac.sc.writeAcmeAuthorization(authz); err != nil {
+			return nil, err
+		}
+	}
+	if err := ac.sc.writeAcmeOrder(order); err != nil {
+		return nil, err
+	}
+
+	return ac.respond(http.StatusCreated, ac.orderResponseBody(order), ac.orderURL(order.ID))
+}
+
+func (b *backend) acmeOrderHandler(ac *acmeContext, _ *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	order, err := ac.fetchRequestedOrder(data, jws.account)
+	if err != nil {
+		return nil, err
+	}
+
+	return ac.respond(http.StatusOK, ac.orderResponseBody(order), "")
+}
+
+func (b *backend) acmeAuthorizationHandler(ac *acmeContext, _ *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	authzID := data.Get("auth_id").(string)
+
+	lock := locksutil.LockForKey(ac.state.resourceLocks, authzID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	authz, err := ac.sc.fetchAcmeAuthorization(jws.account.ID, authzID)
+	if err != nil {
+		return nil, err
+	}
+	if authz == nil {
+		return nil, acmeErrorf(acmeErrNotFound, "unknown authorization")
+	}
+
+	var payload struct {
+		Status acmeStatus `json:"status"`
+	}
+	if err := jws.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	switch payload.Status {
+	case "":
+	case acmeStatusDeactivated:
+		if authz.Status != acmeStatusPending && authz.Status != acmeStatusValid {
+			return nil, acmeErrorf(acmeErrMalformed, "cannot deactivate an authorization which is %v", authz.Status)
+		}
+		authz.Status = acmeStatusDeactivated
+		if err := ac.sc.writeAcmeAuthorization(authz); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, acmeErrorf(acmeErrMalformed, "invalid authorization status update: %q", payload.Status)
+	}
+
+	if authz.Status == acmeStatusPending && time.Now().After(authz.Expires) {
+		authz.Status = acmeStatusInvalid
+		if err := ac.sc.writeAcmeAuthorization(authz); err != nil {
+			return nil, err
+		}
+	}
+
+	return ac.respond(http.StatusOK, ac.authorizationResponseBody(authz), "")
+}
+
+func (b *backend) acmeChallengeHandler(ac *acmeContext, _ *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	authzID := data.Get("auth_id").(string)
+	challengeType := data.Get("challenge_type").(string)
+
+	lock := locksutil.LockForKey(ac.state.resourceLocks, authzID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	authz, err := ac.sc.fetchAcmeAuthorization(jws.account.ID, authzID)
+	if err != nil {
+		return nil, err
+	}
+	if authz == nil {
+		return nil, acmeErrorf(acmeErrNotFound, "unknown authorization")
+	}
+
+	var challenge *acmeChallenge
+	for _, candidate := range authz.Challenges {
+		if candidate.Type == challengeType {
+			challenge = candidate
+			break
+		}
+	}
+	if challenge == nil {
+		return nil, acmeErrorf(acmeErrNotFound, "unknown challenge")
+	}
+
+	// A POST-as-GET only fetches the challenge; any other payload (which
+	// is an empty JSON object) asks us to attempt validation. We validate
+	// synchronously; clients polling the authorization see the result.
+	if !jws.isPostAsGet() && authz.Status == acmeStatusPending && challenge.Status == acmeStatusPending {
+		if time.Now().After(authz.Expires) {
+			authz.Status = acmeStatusInvalid
+		} else {
+			err := ac.validateAcmeChallenge(ac.sc.Context, authz.Identifier, challenge, jws.thumbprint)
+			var aErr *acmeError
+			switch {
+			case err == nil:
+				challenge.Status = acmeStatusValid
+				challenge.Validated = time.Now()
+				authz.Status = acmeStatusValid
+			case errors.As(err, &aErr):
+				challenge.Status = acmeStatusInvalid
+				challenge.ErrorType = aErr.errType.name
+				challenge.Error = aErr.detail
+				authz.Status = acmeStatusInvalid
+			default:
+				return nil, err
+			}
+		}
+
+		if err := ac.sc.writeAcmeAuthorization(authz); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := ac.respond(http.StatusOK, ac.challengeResponseBody(authz, challenge), "")
+	if err != nil {
+		return nil, err
+	}
+	resp.Headers["Link"] = append(resp.Headers["Link"], fmt.Sprintf("<%s>;rel=\"up\"", ac.authorizationURL(authz.ID)))
+	return resp, nil
+}
+
+// acmeCSRIdentifiers returns the sorted identifiers requested by a CSR,
+// rejecting any name types which ACME cannot validate.
+func acmeCSRIdentifiers(csr *x509.CertificateRequest) ([]acmeIdentifier, error) {
+	if len(csr.EmailAddresses) > 0 || len(csr.URIs) > 0 {
+		return nil, acmeErrorf(acmeErrBadCSR, "CSR may only contain dns and ip subject alternative names")
+	}
+
+	seen := make(map[acmeIdentifier]bool)
+	add := func(identifier acmeIdentifier) {
+		seen[identifier] = true
+	}
+
+	for _, name := range csr.DNSNames {
+		add(acmeIdentifier{Type: acmeIdentifierDNS, Value: strings.ToLower(name)})
+	}
+	for _, ip := range csr.IPAddresses {
+		add(acmeIdentifier{Type: acmeIdentifierIP, Value: ip.String()})
+	}
+	if cn := csr.Subject.CommonName; cn != "" {
+		if ip := net.ParseIP(cn); ip != nil {
+			add(acmeIdentifier{Type: acmeIdentifierIP, Value: ip.String()})
+		} else {
+			add(acmeIdentifier{Type: acmeIdentifierDNS, Value: strings.ToLower(cn)})
+		}
+	}
+
+	return sortAcmeIdentifiers(seen), nil
+}
+
+func sortAcmeIdentifiers(set map[acmeIdentifier]bool) []acmeIdentifier {
+	ret := make([]acmeIdentifier, 0, len(set))
+	for identifier := range set {
+		ret = append(ret, identifier)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if ret[i].Type != ret[j].Type {
+			return ret[i].Type < ret[j].Type
+		}
+		return ret[i].Value < ret[j].Value
+	})
+	return ret
+}
+
+// checkAcmeIssuanceRole ensures the directory issues through a role: either
+// a role-scoped directory or the configured default_role. There is no
+// implicit role, so that operators always decide which names ACME clients
+// may obtain certificates for.
+func (ac *acmeContext) checkAcmeIssuanceRole() error {
+	if ac.role == nil {
+		return acmeErrorf(acmeErrUnauthorized, "no role is configured for issuance through this directory; set default_role or use a role-scoped directory")
+	}
+	return nil
+}
+
+// acmeIssuanceRole returns the role to sign the order's CSR with, which
+// checkAcmeIssuanceRole must have ensured exists.
+func (ac *acmeContext) acmeIssuanceRole() *roleEntry {
+	// The requested names always come from the CSR, which we've checked
+	// matches the validated identifiers of the order. ACME clients needn't
+	// set a common name.
+	entry := *ac.role
+	entry.UseCSRCommonName = true
+	entry.UseCSRSANs = true
+	entry.RequireCN = false
+	if entry.Issuer == "" {
+		entry.Issuer = defaultRef
+	}
+	return &entry
+}
+
+func (b *backend) acmeOrderFinalizeHandler(ac *acmeContext, req *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	orderID := data.Get("order_id").(string)
+
+	lock := locksutil.LockForKey(ac.state.resourceLocks, orderID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	order, err := ac.fetchRequestedOrder(data, jws.account)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusReady {
+		return nil, acmeErrorf(acmeErrOrderNotReady, "order is %v", order.Status)
+	}
+	if err := ac.checkAcmeIssuanceRole(); err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		CSR string `json:"csr"`
+	}
+	if err := jws.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	csrBytes, err := base64.RawURLEncoding.DecodeString(payload.CSR)
+	if err != nil {
+		return nil, acmeErrorf(acmeErrBadCSR, "failed to decode CSR: %v", err)
+	}
+	csr, err := x509.ParseCertificateRequest(csrBytes)
+	if err != nil {
+		return nil, acmeErrorf(acmeErrBadCSR, "failed to parse CSR: %v", err)
+	}
+	if err := csr.CheckSignature(); err != nil {
+		return nil, acmeErrorf(acmeErrBadCSR, "invalid CSR signature: %v", err)
+	}
+
+	csrIdentifiers, err := acmeCSRIdentifiers(csr)
+	if err != nil {
+		return nil, err
+	}
+	orderIdentifiers := make(map[acmeIdentifier]bool, len(order.Identifiers))
+	for _, identifier := range order.Identifiers {
+		orderIdentifiers[identifier] = true
+	}
+	expected := sortAcmeIdentifiers(orderIdentifiers)
+	if len(csrIdentifiers) != len(expected) {
+		return nil, acmeErrorf(acmeErrBadCSR, "CSR identifiers do not match the order's identifiers")
+	}
+	for index := range expected {
+		if csrIdentifiers[index] != expected[index] {
+			return nil, acmeErrorf(acmeErrBadCSR, "CSR identifiers do not match the order's identifiers")
+		}
+	}
+
+	role := ac.acmeIssuanceRole()
+	signingBundle, issuerId, err := ac.sc.fetchCAInfoAndIssuerId(role.Issuer, IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the CA certificate: %w", err)
+	}
+
+	fields := addNonCACommonFields(map[string]*framework.FieldSchema{})
+	fields["csr"] = &framework.FieldSchema{Type: framework.TypeString}
+	raw := map[string]interface{}{
+		"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrBytes})),
+	}
+	if !order.NotAfter.IsZero() {
+		raw["not_after"] = order.NotAfter.UTC().Format(time.RFC3339)
+	}
+
+	input := &inputBundle{
+		req:             req,
+		apiData:         &framework.FieldData{Raw: raw, Schema: fields},
+		role:            role,
+		namesAuthorized: true,
+	}
+	parsedBundle, _, err := signCert(b, input, signingBundle, false, false)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, acmeErrorf(acmeErrBadCSR, "%v", err)
+		default:
+			return nil, fmt.Errorf("error signing certificate: %w", err)
+		}
+	}
+
+	serial := serialFromCert(parsedBundle.Certificate)
+	if !role.NoStore {
+		if err := b.storeIssuedCert(ac.sc.Context, req.Storage, parsedBundle, ac.roleName, issuerId); err != nil {
+			return nil, err
+		}
+	}
+	if err := ac.sc.writeAcmeCertOwner(serial, jws.account.ID); err != nil {
+		return nil, err
+	}
+
+	var chain bytes.Buffer
+	chain.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: parsedBundle.CertificateBytes}))
+	for _, caCert := range parsedBundle.CAChain {
+		chain.Write(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Bytes}))
+	}
+
+	order.Status = acmeStatusValid
+	order.CertificateSerial = serial
+	order.CertificateChain = chain.String()
+	if err := ac.sc.writeAcmeOrder(order); err != nil {
+		return nil, err
+	}
+
+	return ac.respond(http.StatusOK, ac.orderResponseBody(order), ac.orderURL(order.ID))
+}
+
+func (b *backend) acmeOrderCertHandler(ac *acmeContext, _ *logical.Request, data *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	order, err := ac.fetchRequestedOrder(data, jws.account)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != acmeStatusValid {
+		return nil, acmeErrorf(acmeErrNotFound, "order has no certificate")
+	}
+
+	return ac.respondWithType(http.StatusOK, acmeCertContentType, []byte(order.CertificateChain), "")
+}
+
+func (b *backend) acmeRevokeCertHandler(ac *acmeContext, req *logical.Request, _ *framework.FieldData, jws *acmeJWS) (*logical.Response, error) {
+	var payload struct {
+		Certificate string `json:"certificate"`
+		Reason      int    `json:"reason"`
+	}
+	if err := jws.decodePayload(&payload); err != nil {
+		return nil, err
+	}
+
+	// Vault's CRLs do not carry revocation reasons, so only the
+	// unspecified reason can be honored.
+	if payload.Reason != 0 {
+		return nil, acmeErrorf(acmeErrBadRevocationReason, "only the unspecified (0) revocation reason is supported")
+	}
+
+	certBytes, err := base64.RawURLEncoding.DecodeString(payload.Certificate)
+	if err != nil {
+		return nil, acmeErrorf(acmeErrMalformed, "failed to decode certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return nil, acmeErrorf(acmeErrMalformed, "failed to parse certificate: %v", err)
+	}
+	serial := serialFromCert(cert)
+
+	certEntry, err := fetchCertBySerial(ac.sc.Context, b, req, "certs/", serial)
+	if err != nil {
+		return nil, err
+	}
+	if certEntry == nil || !bytes.Equal(certEntry.Value, certBytes) {
+		return nil, acmeErrorf(acmeErrUnauthorized, "certificate was not issued by this mount")
+	}
+
+	// The request must either be signed by the account which ordered the
+	// certificate, or by the certificate's own key.
+	if jws.account != nil {
+		owner, err := ac.sc.fetchAcmeCertOwner(serial)
+		if err != nil {
+			return nil, err
+		}
+		if owner != jws.account.ID {
+			return nil, acmeErrorf(acmeErrUnauthorized, "account is not authorized to revoke this certificate")
+		}
+	} else {
+		equal, err := certutil.ComparePublicKeysAndType(cert.PublicKey, jws.key.Key)
+		if err != nil || !equal {
+			return nil, acmeErrorf(acmeErrUnauthorized, "request is not signed by the certificate's key")
+		}
+	}
+
+	revEntry, err := fetchCertBySerial(ac.sc.Context, b, req, revokedPath, serial)
+	if err != nil {
+		return nil, err
+	}
+	if revEntry != nil {
+		return nil, acmeErrorf(acmeErrAlreadyRevoked, "certificate is already revoked")
+	}
+
+	b.revokeStorageLock.Lock()
+	defer b.revokeStorageLock.Unlock()
+
+	resp, err := revokeCert(ac.sc.Context, b, req, serial, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, acmeErrorf(acmeErrMalformed, "%v", resp.Error())
+	}
+
+	return ac.respond(http.StatusOK, nil, "")
+}
+
+const pathAcmeNewOrderHelpSyn = `
+Create a new ACME order for a set of identifiers.
+`
+
+const pathAcmeOrderHelpSyn = `
+Fetch the status of an ACME order.
+`
+
+const pathAcmeOrderFinalizeHelpSyn = `
+Finalize a ready ACME order by submitting its CSR.
+`
+
+const pathAcmeOrderCertHelpSyn = `
+Download the certificate chain issued for an ACME order.
+`
+
+const pathAcmeAuthorizationHelpSyn = `
+Fetch or deactivate an ACME authorization.
+`
+
+const pathAcmeChallengeHelpSyn = `
+Fetch an ACME challenge, or request its validation.
+`
+
+const pathAcmeRevokeCertHelpSyn = `
+Revoke a certificate issued through ACME.
+`
@@ -0,0 +1,567 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/dns/dnsmessage"
+	jose "gopkg.in/square/go-jose.v2"
+)
+
+const acmeTestBaseURL = "https://vault.example.com/v1/pki"
+
+func TestAcme_BasicWorkflow(t *testing.T) {
+	t.Parallel()
+	b, s := createBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root X1",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	root := parseCert(t, resp.Data["certificate"].(string))
+
+	_, err = CBWrite(b, s, "roles/acme", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+		"allow_localhost":  true,
+		"key_type":         "any",
+	})
+	require.NoError(t, err)
+
+	httpServer := newAcmeTestHTTPServer(t)
+	dnsServer := newAcmeTestDNSServer(t)
+	setupAcmeTestConfig(t, b, s, map[string]interface{}{
+		"dns_resolver":        dnsServer.addr,
+		"http_challenge_port": httpServer.port,
+		"default_role":        "acme",
+	})
+
+	client := newAcmeTestClient(t, b, s, "acme/")
+
+	// Directory should point at the other endpoints of this directory.
+	resp, err = CBRead(b, s, "acme/directory")
+	require.NoError(t, err)
+	directory := requireAcmeResponse(t, resp, http.StatusOK)
+	require.Equal(t, acmeTestBaseURL+"/acme/new-order", directory["newOrder"])
+
+	// Creating the account twice returns the same account.
+	status, account, location := client.post("acme/new-account", map[string]interface{}{
+		"contact":              []string{"mailto:admin@example.com"},
+		"termsOfServiceAgreed": true,
+	})
+	require.Equal(t, http.StatusCreated, status, "account: %v", account)
+	require.Equal(t, "valid", account["status"])
+	client.kid = location
+
+	kid := client.kid
+	client.kid = ""
+	status, _, location = client.post("acme/new-account", map[string]interface{}{})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, kid, location)
+	client.kid = kid
+
+	status, order, orderURL := client.post("acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{
+			{"type": "dns", "value": "localhost"},
+			{"type": "dns", "value": "*.example.com"},
+		},
+	})
+	require.Equal(t, http.StatusCreated, status, "order: %v", order)
+	require.Equal(t, "pending", order["status"])
+	require.Len(t, order["authorizations"], 2)
+
+	for _, authzURL := range order["authorizations"].([]interface{}) {
+		status, authz, _ := client.post(client.relative(authzURL.(string)), nil)
+		require.Equal(t, http.StatusOK, status)
+
+		identifier := authz["identifier"].(map[string]interface{})["value"].(string)
+		challenges := authz["challenges"].([]interface{})
+
+		var challenge map[string]interface{}
+		for _, raw := range challenges {
+			candidate := raw.(map[string]interface{})
+			if identifier == "localhost" && candidate["type"] == "http-01" {
+				challenge = candidate
+			}
+			if identifier == "example.com" && candidate["type"] == "dns-01" {
+				challenge = candidate
+			}
+		}
+		require.NotNil(t, challenge, "no usable challenge for %v in %v", identifier, challenges)
+		if identifier == "example.com" {
+			require.Equal(t, true, authz["wildcard"])
+			require.Len(t, challenges, 1, "wildcards may only be validated with dns-01")
+		}
+
+		keyAuthz := challenge["token"].(string) + "." + client.thumbprint()
+		switch challenge["type"] {
+		case "http-01":
+			httpServer.set(challenge["token"].(string), keyAuthz)
+		case "dns-01":
+			digest := sha256.Sum256([]byte(keyAuthz))
+			dnsServer.set("_acme-challenge."+identifier+".", base64.RawURLEncoding.EncodeToString(digest[:]))
+		}
+
+		status, result, _ := client.post(client.relative(challenge["url"].(string)), map[string]interface{}{})
+		require.Equal(t, http.StatusOK, status)
+		require.Equal(t, "valid", result["status"], "challenge: %v", result)
+	}
+
+	status, order, _ = client.post(client.relative(orderURL), nil)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, "ready", order["status"])
+
+	// A CSR for different names than the order must be rejected.
+	status, problem, _ := client.post(client.relative(order["finalize"].(string)), map[string]interface{}{
+		"csr": acmeTestCSR(t, "", "localhost", "other.example.com"),
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "urn:ietf:params:acme:error:badCSR", problem["type"])
+
+	status, order, _ = client.post(client.relative(order["finalize"].(string)), map[string]interface{}{
+		"csr": acmeTestCSR(t, "", "localhost", "*.example.com"),
+	})
+	require.Equal(t, http.StatusOK, status, "finalize: %v", order)
+	require.Equal(t, "valid", order["status"])
+
+	resp = client.postRaw(client.relative(order["certificate"].(string)), nil)
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode])
+	require.Equal(t, acmeCertContentType, resp.Data[logical.HTTPContentType])
+	leaf := parseCert(t, string(resp.Data[logical.HTTPRawBody].([]byte)))
+	requireSignedBy(t, leaf, root)
+	require.ElementsMatch(t, []string{"localhost", "*.example.com"}, leaf.DNSNames)
+
+	// The certificate is in the regular certificate store, and can be
+	// revoked by the account which ordered it, exactly once.
+	resp, err = CBRead(b, s, "cert/"+serialFromCert(leaf))
+	requireSuccessNonNilResponse(t, resp, err)
+
+	revokeReq := map[string]interface{}{
+		"certificate": base64.RawURLEncoding.EncodeToString(leaf.Raw),
+	}
+	status, _, _ = client.post("acme/revoke-cert", revokeReq)
+	require.Equal(t, http.StatusOK, status)
+
+	resp, err = CBRead(b, s, "cert/"+serialFromCert(leaf))
+	requireSuccessNonNilResponse(t, resp, err)
+	require.NotZero(t, resp.Data["revocation_time"])
+
+	status, problem, _ = client.post("acme/revoke-cert", revokeReq)
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "urn:ietf:params:acme:error:alreadyRevoked", problem["type"])
+}
+
+func TestAcme_RequestValidation(t *testing.T) {
+	t.Parallel()
+	b, s := createBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root X1",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+
+	// ACME is disabled by default.
+	resp, err = CBRead(b, s, "acme/directory")
+	require.NoError(t, err)
+	requireAcmeResponse(t, resp, http.StatusInternalServerError)
+
+	_, err = CBWrite(b, s, "config/acme", map[string]interface{}{"enabled": true})
+	require.Error(t, err, "expected enabling without base_url to fail")
+
+	_, err = CBWrite(b, s, "roles/example", map[string]interface{}{
+		"allowed_domains":  "example.com",
+		"allow_subdomains": true,
+	})
+	require.NoError(t, err)
+	setupAcmeTestConfig(t, b, s, map[string]interface{}{
+		"allowed_roles": "example",
+	})
+
+	client := newAcmeTestClient(t, b, s, "roles/example/acme/")
+	status, _, location := client.post("roles/example/acme/new-account", map[string]interface{}{})
+	require.Equal(t, http.StatusCreated, status)
+	client.kid = location
+
+	// Nonces may only be used once.
+	nonce := client.nonce
+	status, _, _ = client.post("roles/example/acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.com"}},
+	})
+	require.Equal(t, http.StatusCreated, status)
+	client.nonce = nonce
+	status, problem, _ := client.post("roles/example/acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.com"}},
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "urn:ietf:params:acme:error:badNonce", problem["type"])
+
+	// Requests with an invalid signature don't use up their nonce.
+	nonce = client.nonce
+	data := client.sign((&jose.SignerOptions{}).
+		WithHeader("nonce", nonce).
+		WithHeader("url", acmeTestBaseURL+"/roles/example/acme/new-order"), map[string]interface{}{})
+	data["signature"] = base64.RawURLEncoding.EncodeToString(make([]byte, 64))
+	problem = requireAcmeResponse(t, client.postData("roles/example/acme/new-order", data), http.StatusBadRequest)
+	require.Equal(t, "urn:ietf:params:acme:error:malformed", problem["type"])
+	client.nonce = nonce
+	status, _, _ = client.post("roles/example/acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.com"}},
+	})
+	require.Equal(t, http.StatusCreated, status)
+
+	// Embedded keys must be public keys.
+	kid := client.kid
+	client.kid = ""
+	privateKey := jose.JSONWebKey{Key: client.key}
+	data = client.sign((&jose.SignerOptions{}).
+		WithHeader("nonce", client.nonce).
+		WithHeader("url", acmeTestBaseURL+"/roles/example/acme/new-account").
+		WithHeader("jwk", privateKey), map[string]interface{}{})
+	client.kid = kid
+	problem = requireAcmeResponse(t, client.postData("roles/example/acme/new-account", data), http.StatusBadRequest)
+	require.Equal(t, "urn:ietf:params:acme:error:badPublicKey", problem["type"])
+
+	// Identifiers are checked against the role of the directory.
+	status, problem, _ = client.post("roles/example/acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.org"}},
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+	require.Equal(t, "urn:ietf:params:acme:error:rejectedIdentifier", problem["type"])
+
+	// The signed url must match the requested url.
+	resp = client.postRawWithURL("roles/example/acme/new-order", acmeTestBaseURL+"/acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.com"}},
+	})
+	problem = requireAcmeResponse(t, resp, http.StatusForbidden)
+	require.Equal(t, "urn:ietf:params:acme:error:unauthorized", problem["type"])
+
+	// Without a default_role, the acme/ directory can't issue.
+	defaultClient := newAcmeTestClient(t, b, s, "acme/")
+	status, _, location = defaultClient.post("acme/new-account", map[string]interface{}{})
+	require.Equal(t, http.StatusCreated, status)
+	defaultClient.kid = location
+	status, problem, _ = defaultClient.post("acme/new-order", map[string]interface{}{
+		"identifiers": []map[string]string{{"type": "dns", "value": "www.example.com"}},
+	})
+	require.Equal(t, http.StatusForbidden, status)
+	require.Equal(t, "urn:ietf:params:acme:error:unauthorized", problem["type"])
+
+	// Roles not listed in allowed_roles cannot be used.
+	_, err = CBWrite(b, s, "roles/other", map[string]interface{}{"allow_any_name": true})
+	require.NoError(t, err)
+	resp, err = CBRead(b, s, "roles/other/acme/directory")
+	require.NoError(t, err)
+	requireAcmeResponse(t, resp, http.StatusForbidden)
+}
+
+func setupAcmeTestConfig(t *testing.T, b *backend, s logical.Storage, extra map[string]interface{}) {
+	config := map[string]interface{}{
+		"enabled":  true,
+		"base_url": acmeTestBaseURL,
+	}
+	for k, v := range extra {
+		config[k] = v
+	}
+	resp, err := CBWrite(b, s, "config/acme", config)
+	requireSuccessNonNilResponse(t, resp, err, "failed writing ACME config")
+}
+
+func requireAcmeResponse(t *testing.T, resp *logical.Response, status int) map[string]interface{} {
+	t.Helper()
+	require.NotNil(t, resp)
+	require.Equal(t, status, resp.Data[logical.HTTPStatusCode], "response: %v", resp.Data)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &body))
+	return body
+}
+
+type acmeTestClient struct {
+	t     *testing.T
+	b     *backend
+	s     logical.Storage
+	key   *ecdsa.PrivateKey
+	kid   string
+	nonce string
+}
+
+func newAcmeTestClient(t *testing.T, b *backend, s logical.Storage, prefix string) *acmeTestClient {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	resp, err := CBRead(b, s, prefix+"new-nonce")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.Data[logical.HTTPStatusCode])
+	require.NotEmpty(t, resp.Headers["Replay-Nonce"])
+
+	resp, err = CBReq(b, s, logical.HeaderOperation, prefix+"new-nonce", nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.Data[logical.HTTPStatusCode])
+
+	return &acmeTestClient{
+		t:     t,
+		b:     b,
+		s:     s,
+		key:   key,
+		nonce: resp.Headers["Replay-Nonce"][0],
+	}
+}
+
+func (c *acmeTestClient) thumbprint() string {
+	thumbprint, err := jwkThumbprint(&jose.JSONWebKey{Key: c.key.Public()})
+	require.NoError(c.t, err)
+	return thumbprint
+}
+
+func (c *acmeTestClient) relative(absURL string) string {
+	require.True(c.t, strings.HasPrefix(absURL, acmeTestBaseURL+"/"), "unexpected url %v", absURL)
+	return strings.TrimPrefix(absURL, acmeTestBaseURL+"/")
+}
+
+func (c *acmeTestClient) post(path string, payload interface{}) (int, map[string]interface{}, string) {
+	resp := c.postRaw(path, payload)
+
+	var body map[string]interface{}
+	if raw, ok := resp.Data[logical.HTTPRawBody].([]byte); ok && len(raw) > 0 {
+		require.NoError(c.t, json.Unmarshal(raw, &body))
+	}
+
+	var location string
+	if locations := resp.Headers["Location"]; len(locations) > 0 {
+		location = locations[0]
+	}
+
+	return resp.Data[logical.HTTPStatusCode].(int), body, location
+}
+
+func (c *acmeTestClient) postRaw(path string, payload interface{}) *logical.Response {
+	return c.postRawWithURL(path, acmeTestBaseURL+"/"+path, payload)
+}
+
+func (c *acmeTestClient) postRawWithURL(path string, signedURL string, payload interface{}) *logical.Response {
+	opts := (&jose.SignerOptions{EmbedJWK: c.kid == ""}).
+		WithHeader("nonce", c.nonce).
+		WithHeader("url", signedURL)
+	return c.postData(path, c.sign(opts, payload))
+}
+
+func (c *acmeTestClient) sign(opts *jose.SignerOptions, payload interface{}) map[string]interface{} {
+	var encoded []byte
+	if payload != nil {
+		var err error
+		encoded, err = json.Marshal(payload)
+		require.NoError(c.t, err)
+	}
+
+	signer, err := jose.NewSigner(jose.SigningKey{
+		Algorithm: jose.ES256,
+		Key:       jose.JSONWebKey{Key: c.key, KeyID: c.kid},
+	}, opts)
+	require.NoError(c.t, err)
+
+	jws, err := signer.Sign(encoded)
+	require.NoError(c.t, err)
+
+	var data map[string]interface{}
+	require.NoError(c.t, json.Unmarshal([]byte(jws.FullSerialize()), &data))
+	return data
+}
+
+func (c *acmeTestClient) postData(path string, data map[string]interface{}) *logical.Response {
+	resp, err := CBWrite(c.b, c.s, path, data)
+	require.NoError(c.t, err)
+	require.NotNil(c.t, resp)
+
+	c.nonce = resp.Headers["Replay-Nonce"][0]
+	return resp
+}
+
+func acmeTestCSR(t *testing.T, commonName string, dnsNames ...string) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: dnsNames,
+	}, key)
+	require.NoError(t, err)
+
+	return base64.RawURLEncoding.EncodeToString(csr)
+}
+
+// acmeTestHTTPServer stands in for the web server of the identifiers being
+// validated through http-01.
+type acmeTestHTTPServer struct {
+	lock      sync.Mutex
+	responses map[string]string
+	port      int
+}
+
+func newAcmeTestHTTPServer(t *testing.T) *acmeTestHTTPServer {
+	ts := &acmeTestHTTPServer{responses: make(map[string]string)}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ts.lock.Lock()
+		defer ts.lock.Unlock()
+
+		response, ok := ts.responses[strings.TrimPrefix(r.URL.Path, "/.well-known/acme-challenge/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	ts.port, err = strconv.Atoi(serverURL.Port())
+	require.NoError(t, err)
+
+	return ts
+}
+
+func (ts *acmeTestHTTPServer) set(token string, keyAuthz string) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.responses[token] = keyAuthz
+}
+
+// acmeTestDNSServer is a minimal UDP DNS server answering TXT queries, to
+// stand in for the authoritative servers of dns-01 identifiers.
+type acmeTestDNSServer struct {
+	lock    sync.Mutex
+	records map[string]string
+	addr    string
+}
+
+func newAcmeTestDNSServer(t *testing.T) *acmeTestDNSServer {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ts := &acmeTestDNSServer{
+		records: make(map[string]string),
+		addr:    conn.LocalAddr().String(),
+	}
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if response := ts.answer(buf[:n]); response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+
+	return ts
+}
+
+func (ts *acmeTestDNSServer) set(name string, value string) {
+	ts.lock.Lock()
+	defer ts.lock.Unlock()
+	ts.records[name] = value
+}
+
+func (ts *acmeTestDNSServer) answer(packet []byte) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(packet)
+	if err != nil {
+		return nil
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil
+	}
+
+	ts.lock.Lock()
+	value, ok := ts.records[question.Name.String()]
+	ts.lock.Unlock()
+
+	header.Response = true
+	header.Authoritative = true
+	if !ok || question.Type != dnsmessage.TypeTXT {
+		header.RCode = dnsmessage.RCodeNameError
+	}
+
+	builder := dnsmessage.NewBuilder(nil, header)
+	builder.EnableCompression()
+	if err := builder.StartQuestions(); err != nil {
+		return nil
+	}
+	if err := builder.Question(question); err != nil {
+		return nil
+	}
+	if ok && question.Type == dnsmessage.TypeTXT {
+		if err := builder.StartAnswers(); err != nil {
+			return nil
+		}
+		err := builder.TXTResource(dnsmessage.ResourceHeader{
+			Name:  question.Name,
+			Class: dnsmessage.ClassINET,
+			TTL:   60,
+		}, dnsmessage.TXTResource{TXT: []string{value}})
+		if err != nil {
+			return nil
+		}
+	}
+
+	response, err := builder.Finish()
+	if err != nil {
+		return nil
+	}
+	return response
+}
+
+func TestAcme_HTTP01Redirects(t *testing.T) {
+	t.Parallel()
+
+	via := []*http.Request{httptest.NewRequest(http.MethodGet, "http://www.example.com/.well-known/acme-challenge/token", nil)}
+	for target, allowed := range map[string]bool{
+		"http://www.example.com/elsewhere":      true,
+		"http://www.example.com:80/elsewhere":   true,
+		"https://www.example.com/elsewhere":     true,
+		"https://www.example.com:443/elsewhere": true,
+		"http://www.example.com:8200/v1/sys":    false,
+		"https://www.example.com:80/elsewhere":  false,
+		"http://169.254.169.254:8080/latest":    false,
+		"ftp://www.example.com/elsewhere":       false,
+		"gopher://www.example.com:70/elsewhere": false,
+	} {
+		err := checkHTTP01Redirect(httptest.NewRequest(http.MethodGet, target, nil), via)
+		if allowed {
+			require.NoError(t, err, "expected redirect to %v to be followed", target)
+		} else {
+			require.Error(t, err, "expected redirect to %v to be refused", target)
+		}
+	}
+
+	for len(via) <= acmeMaxChallengeRedirects {
+		via = append(via, via[0])
+	}
+	require.Error(t, checkHTTP01Redirect(httptest.NewRequest(http.MethodGet, "http://www.example.com/", nil), via))
+}
@@ -0,0 +1,359 @@
+package pki
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func addCertInventoryFilterFields(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["common_name"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Case-insensitive glob pattern, such as
+'*.payments.example.com', matched against the common name of certificates.`,
+	}
+	fields["san"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Case-insensitive glob pattern matched against the DNS,
+email, IP and URI Subject Alternative Names of certificates; a certificate
+matches when any of them does.`,
+	}
+	fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `Only return certificates issued through this role.`,
+	}
+	fields["issuer_ref"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Only return certificates issued by this issuer, given
+by identifier, name or 'default'.`,
+	}
+	fields["expires_after"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Only return certificates expiring at or after this
+time, in RFC 3339 format.`,
+	}
+	fields["expires_before"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Only return certificates expiring at or before this
+time, in RFC 3339 format. Mutually exclusive with expires_within.`,
+	}
+	fields["expires_within"] = &framework.FieldSchema{
+		Type: framework.TypeDurationSecond,
+		Description: `Only return certificates expiring within this duration
+from now. Mutually exclusive with expires_before.`,
+	}
+	fields["status"] = &framework.FieldSchema{
+		Type: framework.TypeString,
+		Description: `Only return certificates with this status: 'valid'
+(neither expired nor revoked), 'expired', 'revoked' or 'any'.`,
+		Default:       certInventoryStatusAny,
+		AllowedValues: []interface{}{certInventoryStatusAny, certInventoryStatusValid, certInventoryStatusExpired, certInventoryStatusRevoked},
+	}
+
+	return fields
+}
+
+func pathCertSearch(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/search",
+		Fields:  addCertInventoryFilterFields(map[string]*framework.FieldSchema{}),
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathCertSearch,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCertSearch,
+			},
+		},
+
+		HelpSynopsis:    pathCertSearchHelpSyn,
+		HelpDescription: pathCertSearchHelpDesc,
+	}
+}
+
+func pathCertExport(b *backend) *framework.Path {
+	fields := addCertInventoryFilterFields(map[string]*framework.FieldSchema{})
+	fields["format"] = &framework.FieldSchema{
+		Type:          framework.TypeString,
+		Description:   `Format of the export, either 'json' or 'csv'.`,
+		Default:       "json",
+		AllowedValues: []interface{}{"json", "csv"},
+	}
+	fields["include_certificates"] = &framework.FieldSchema{
+		Type: framework.TypeBool,
+		Description: `Whether to include the PEM-encoded certificates in the
+export.`,
+		Default: false,
+	}
+
+	return &framework.Path{
+		Pattern: "certs/export",
+		Fields:  fields,
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathCertExport,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathCertExport,
+			},
+		},
+
+		HelpSynopsis:    pathCertExportHelpSyn,
+		HelpDescription: pathCertExportHelpDesc,
+	}
+}
+
+func (b *backend) pathCertSearch(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	entries, resp, err := searchCertInventoryFromRequest(sc, data)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	keys := make([]string, 0, len(entries))
+	keyInfo := make(map[string]interface{}, len(entries))
+	for _, entry := range entries {
+		serial := denormalizeSerial(entry.SerialNumber)
+		keys = append(keys, serial)
+		keyInfo[serial] = entry.responseData()
+	}
+
+	resp = logical.ListResponseWithInfo(keys, keyInfo)
+	if data.Get("role").(string) != "" {
+		backfilled, err := sc.countBackfilledCertInventoryEntries()
+		if err != nil {
+			return nil, err
+		}
+		if backfilled > 0 {
+			resp.AddWarning(fmt.Sprintf("%d certificates were added to the inventory by tidy and have no recorded role; they are never matched by the role filter.", backfilled))
+		}
+	}
+
+	return resp, nil
+}
+
+func (b *backend) pathCertExport(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	entries, resp, err := searchCertInventoryFromRequest(sc, data)
+	if resp != nil || err != nil {
+		return resp, err
+	}
+
+	includeCerts := data.Get("include_certificates").(bool)
+	records := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		record := entry.responseData()
+		record["serial_number"] = denormalizeSerial(entry.SerialNumber)
+		if includeCerts {
+			certEntry, err := fetchCertBySerial(ctx, b, req, "certs/", entry.SerialNumber)
+			if err != nil {
+				return nil, err
+			}
+			var pemCert string
+			if certEntry != nil {
+				pemCert = strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+					Type:  "CERTIFICATE",
+					Bytes: certEntry.Value,
+				})))
+			}
+			record["certificate"] = pemCert
+		}
+		records = append(records, record)
+	}
+
+	var body []byte
+	var contentType string
+	switch data.Get("format").(string) {
+	case "csv":
+		body, err = certInventoryCSV(records, includeCerts)
+		contentType = "text/csv"
+	default:
+		body, err = json.Marshal(records)
+		contentType = "application/json"
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding certificate export: %w", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: contentType,
+			logical.HTTPRawBody:     body,
+			logical.HTTPStatusCode:  http.StatusOK,
+		},
+	}, nil
+}
+
+// searchCertInventoryFromRequest builds the inventory filter from the
+// request's fields and searches the inventory with it. An error response is
+// returned for invalid filters.
+func searchCertInventoryFromRequest(sc *storageContext, data *framework.FieldData) ([]*certInventoryEntry, *logical.Response, error) {
+	filter := &certInventoryFilter{
+		CommonName: data.Get("common_name").(string),
+		SAN:        data.Get("san").(string),
+		Role:       data.Get("role").(string),
+		Status:     data.Get("status").(string),
+	}
+
+	switch filter.Status {
+	case certInventoryStatusAny, certInventoryStatusValid, certInventoryStatusExpired, certInventoryStatusRevoked:
+	default:
+		return nil, logical.ErrorResponse("invalid status %q; must be one of %q, %q, %q or %q", filter.Status,
+			certInventoryStatusAny, certInventoryStatusValid, certInventoryStatusExpired, certInventoryStatusRevoked), nil
+	}
+
+	if issuerRef := data.Get("issuer_ref").(string); issuerRef != "" {
+		issuerId, err := sc.resolveIssuerReference(issuerRef)
+		if err != nil {
+			if issuerId == IssuerRefNotFound {
+				return nil, logical.ErrorResponse(err.Error()), nil
+			}
+			return nil, nil, err
+		}
+		filter.IssuerID = issuerId
+	}
+
+	if expiresAfter := data.Get("expires_after").(string); expiresAfter != "" {
+		parsed, err := time.Parse(time.RFC3339, expiresAfter)
+		if err != nil {
+			return nil, logical.ErrorResponse("invalid expires_after: %v", err), nil
+		}
+		filter.ExpiresAfter = parsed
+	}
+
+	expiresBefore := data.Get("expires_before").(string)
+	expiresWithin := time.Duration(data.Get("expires_within").(int)) * time.Second
+	switch {
+	case expiresBefore != "" && expiresWithin > 0:
+		return nil, logical.ErrorResponse("only one of expires_before or expires_within may be provided"), nil
+	case expiresBefore != "":
+		parsed, err := time.Parse(time.RFC3339, expiresBefore)
+		if err != nil {
+			return nil, logical.ErrorResponse("invalid expires_before: %v", err), nil
+		}
+		filter.ExpiresBefore = parsed
+	case expiresWithin > 0:
+		filter.ExpiresBefore = time.Now().Add(expiresWithin)
+	}
+
+	entries, err := sc.searchCertInventory(filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entries, nil, nil
+}
+
+func (e *certInventoryEntry) responseData() map[string]interface{} {
+	data := map[string]interface{}{
+		"common_name":     e.CommonName,
+		"dns_names":       nonNilStrings(e.DNSNames),
+		"email_addresses": nonNilStrings(e.EmailAddresses),
+		"ip_addresses":    nonNilStrings(e.IPAddresses),
+		"uri_sans":        nonNilStrings(e.URISANs),
+		"role":            e.Role,
+		"issuer_id":       e.IssuerID.String(),
+		"not_before":      e.NotBefore.UTC().Format(time.RFC3339),
+		"not_after":       e.NotAfter.UTC().Format(time.RFC3339),
+		"revoked":         e.Revoked,
+	}
+	if e.Revoked && !e.RevocationTime.IsZero() {
+		data["revocation_time"] = e.RevocationTime.Unix()
+		data["revocation_time_rfc3339"] = e.RevocationTime.UTC().Format(time.RFC3339Nano)
+	}
+
+	return data
+}
+
+var certInventoryCSVColumns = []string{
+	"serial_number",
+	"common_name",
+	"dns_names",
+	"email_addresses",
+	"ip_addresses",
+	"uri_sans",
+	"role",
+	"issuer_id",
+	"not_before",
+	"not_after",
+	"revoked",
+	"revocation_time_rfc3339",
+}
+
+// certInventoryCSV encodes export records as CSV with a header row. Lists of
+// names are joined with spaces.
+func certInventoryCSV(records []map[string]interface{}, includeCerts bool) ([]byte, error) {
+	columns := certInventoryCSVColumns
+	if includeCerts {
+		columns = append(append([]string{}, columns...), "certificate")
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(columns); err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		row := make([]string, 0, len(columns))
+		for _, column := range columns {
+			switch value := record[column].(type) {
+			case nil:
+				row = append(row, "")
+			case []string:
+				row = append(row, strings.Join(value, " "))
+			default:
+				row = append(row, fmt.Sprintf("%v", value))
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+
+	return buf.Bytes(), w.Error()
+}
+
+func nonNilStrings(in []string) []string {
+	if in == nil {
+		return []string{}
+	}
+	return in
+}
+
+const pathCertSearchHelpSyn = `
+Search the certificates stored by this mount.
+`
+
+const pathCertSearchHelpDesc = `
+This endpoint searches the certificates stored by this mount by common name,
+Subject Alternative Name, role, issuer, expiration and revocation status,
+returning the serial numbers of matching certificates along with their
+details. All given filters must match.
+
+Certificates issued by roles with no_store set are never stored and are not
+returned. Certificates stored before the inventory was introduced are only
+found once tidy has run with tidy_cert_store set, which adds them to the
+inventory without a role; they never match the role filter.
+`
+
+const pathCertExportHelpSyn = `
+Export the certificates stored by this mount as JSON or CSV.
+`
+
+const pathCertExportHelpDesc = `
+This endpoint exports the details of stored certificates matching the same
+filters as certs/search as a JSON array or a CSV document, optionally
+including the PEM-encoded certificates.
+`
@@ -0,0 +1,173 @@
+package pki
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPKI_CertInventory(t *testing.T) {
+	t.Parallel()
+	b, s := createBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root R1",
+		"issuer_name": "root-a",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root-a")
+	rootA := resp.Data["issuer_id"].(issuerID)
+	rootASerial := resp.Data["serial_number"].(string)
+
+	resp, err = CBWrite(b, s, "issuers/generate/root/internal", map[string]interface{}{
+		"common_name": "Root R2",
+		"issuer_name": "root-b",
+		"key_type":    "ec",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root-b")
+	rootBSerial := resp.Data["serial_number"].(string)
+
+	for name, domain := range map[string]string{"payments": "payments.example.com", "web": "example.com"} {
+		_, err = CBWrite(b, s, "roles/"+name, map[string]interface{}{
+			"allowed_domains":  domain,
+			"allow_subdomains": true,
+			"key_type":         "ec",
+		})
+		require.NoError(t, err, "failed creating role %s", name)
+	}
+
+	issue := func(path, commonName, ttl string) string {
+		resp, err := CBWrite(b, s, path, map[string]interface{}{
+			"common_name": commonName,
+			"ttl":         ttl,
+		})
+		requireSuccessNonNilResponse(t, resp, err, "failed issuing %s", commonName)
+		return resp.Data["serial_number"].(string)
+	}
+	paymentsSoon := issue("issue/payments", "api.payments.example.com", "1h")
+	paymentsLater := issue("issue/payments", "db.payments.example.com", "20h")
+	webSoon := issue("issuer/root-b/issue/web", "www.example.com", "1h")
+	revoked := issue("issue/payments", "old.payments.example.com", "1h")
+
+	_, err = CBWrite(b, s, "revoke", map[string]interface{}{
+		"serial_number": revoked,
+	})
+	require.NoError(t, err, "failed revoking certificate")
+
+	search := func(data map[string]interface{}) []string {
+		resp, err := CBWrite(b, s, "certs/search", data)
+		requireSuccessNonNilResponse(t, resp, err, "failed searching certificates with %v", data)
+		if resp.Data["keys"] == nil {
+			return nil
+		}
+		return resp.Data["keys"].([]string)
+	}
+
+	require.ElementsMatch(t, []string{rootASerial, rootBSerial, paymentsSoon, paymentsLater, webSoon, revoked}, search(map[string]interface{}{}))
+	require.ElementsMatch(t, []string{paymentsSoon, paymentsLater, revoked}, search(map[string]interface{}{"san": "*.PAYMENTS.example.com"}))
+	require.ElementsMatch(t, []string{webSoon}, search(map[string]interface{}{"common_name": "www.*"}))
+	require.ElementsMatch(t, []string{webSoon}, search(map[string]interface{}{"role": "web"}))
+	require.ElementsMatch(t, []string{rootASerial, paymentsSoon, paymentsLater, revoked}, search(map[string]interface{}{"issuer_ref": string(rootA)}))
+	require.ElementsMatch(t, []string{rootBSerial, webSoon}, search(map[string]interface{}{"issuer_ref": "root-b"}))
+	require.ElementsMatch(t, []string{paymentsSoon}, search(map[string]interface{}{
+		"san":            "*.payments.example.com",
+		"expires_within": "10h",
+		"status":         "valid",
+	}))
+	require.ElementsMatch(t, []string{revoked}, search(map[string]interface{}{"status": "revoked"}))
+	require.Empty(t, search(map[string]interface{}{"status": "expired"}))
+
+	resp, err = CBWrite(b, s, "certs/search", map[string]interface{}{"status": "revoked"})
+	requireSuccessNonNilResponse(t, resp, err)
+	info := resp.Data["key_info"].(map[string]interface{})[revoked].(map[string]interface{})
+	require.Equal(t, "old.payments.example.com", info["common_name"])
+	require.Equal(t, []string{"old.payments.example.com"}, info["dns_names"])
+	require.Equal(t, "payments", info["role"])
+	require.Equal(t, rootA.String(), info["issuer_id"])
+	require.Equal(t, true, info["revoked"])
+	require.NotEmpty(t, info["revocation_time"])
+
+	// Invalid filters are rejected.
+	for _, data := range []map[string]interface{}{
+		{"status": "unknown"},
+		{"issuer_ref": "missing"},
+		{"expires_after": "tomorrow"},
+		{"expires_before": "2030-01-01T00:00:00Z", "expires_within": "1h"},
+	} {
+		_, err = CBWrite(b, s, "certs/search", data)
+		require.Error(t, err, "expected error searching with %v", data)
+	}
+
+	// Certificates stored without an inventory entry, such as those issued
+	// before it existed, aren't found until tidy adds them to it.
+	sc := b.makeStorageContext(context.Background(), s)
+	require.NoError(t, sc.deleteCertInventoryEntry(webSoon))
+	indexed, err := s.List(context.Background(), certInventoryRoleIndex+"web/")
+	require.NoError(t, err)
+	require.Empty(t, indexed)
+	require.Empty(t, search(map[string]interface{}{"common_name": "www.example.com"}))
+
+	_, err = CBWrite(b, s, "tidy", map[string]interface{}{
+		"tidy_cert_store": true,
+	})
+	require.NoError(t, err)
+	for {
+		time.Sleep(125 * time.Millisecond)
+
+		resp, err = CBRead(b, s, "tidy-status")
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		state := resp.Data["state"].(string)
+		if state == "Finished" {
+			break
+		}
+		if state == "Error" {
+			t.Fatalf("unexpected state for tidy operation: Error:\nStatus: %v", resp.Data)
+		}
+	}
+
+	require.ElementsMatch(t, []string{webSoon}, search(map[string]interface{}{"common_name": "www.example.com"}))
+	require.ElementsMatch(t, []string{rootBSerial, webSoon}, search(map[string]interface{}{"issuer_ref": "root-b"}))
+	resp, err = CBWrite(b, s, "certs/search", map[string]interface{}{"role": "web"})
+	requireSuccessNonNilResponse(t, resp, err)
+	require.Empty(t, resp.Data["keys"])
+	require.Len(t, resp.Warnings, 1)
+	require.Contains(t, resp.Warnings[0], "1 certificates were added to the inventory by tidy")
+
+	// Export as JSON.
+	resp, err = CBWrite(b, s, "certs/export", map[string]interface{}{
+		"role":                 "payments",
+		"include_certificates": true,
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed exporting certificates as JSON")
+	require.Equal(t, "application/json", resp.Data[logical.HTTPContentType])
+	var records []map[string]interface{}
+	require.NoError(t, json.Unmarshal(resp.Data[logical.HTTPRawBody].([]byte), &records))
+	require.Len(t, records, 3)
+	for _, record := range records {
+		require.Equal(t, "payments", record["role"])
+		cert := parseCert(t, record["certificate"].(string))
+		require.Equal(t, record["serial_number"], serialFromCert(cert))
+	}
+
+	// Export as CSV.
+	resp, err = CBWrite(b, s, "certs/export", map[string]interface{}{
+		"format": "csv",
+		"status": "revoked",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed exporting certificates as CSV")
+	require.Equal(t, "text/csv", resp.Data[logical.HTTPContentType])
+	rows, err := csv.NewReader(strings.NewReader(string(resp.Data[logical.HTTPRawBody].([]byte)))).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, certInventoryCSVColumns, rows[0])
+	require.Equal(t, revoked, rows[1][0])
+	require.Equal(t, "old.payments.example.com", rows[1][1])
+	require.Equal(t, "true", rows[1][10])
+}
@@ -0,0 +1,225 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/asaskevich/govalidator"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	storageAcmeConfig = "config/acme"
+
+	defaultAcmeHTTPChallengePort = 80
+)
+
+type acmeConfigEntry struct {
+	Enabled           bool     `json:"enabled"`
+	BaseURL           string   `json:"base_url"`
+	DefaultRole       string   `json:"default_role"`
+	AllowedRoles      []string `json:"allowed_roles"`
+	DNSResolver       string   `json:"dns_resolver"`
+	HTTPChallengePort int      `json:"http_challenge_port"`
+}
+
+var defaultAcmeConfig = acmeConfigEntry{
+	Enabled:           false,
+	BaseURL:           "",
+	DefaultRole:       "",
+	AllowedRoles:      []string{"*"},
+	DNSResolver:       "",
+	HTTPChallengePort: defaultAcmeHTTPChallengePort,
+}
+
+// isRoleAllowed reports whether the named role may be used through one of
+// the role-scoped ACME directories.
+func (c *acmeConfigEntry) isRoleAllowed(name string) bool {
+	return strutil.StrListContains(c.AllowedRoles, "*") || strutil.StrListContains(c.AllowedRoles, name)
+}
+
+func pathConfigAcme(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/acme",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `Whether the ACME (RFC 8555) endpoints of this mount are enabled. Defaults to false.`,
+				Default:     defaultAcmeConfig.Enabled,
+			},
+			"base_url": {
+				Type: framework.TypeString,
+				Description: `The externally reachable URL of this mount, for example
+https://vault.example.com/v1/pki. ACME directories and resource URLs are
+built from this value and every JWS request URL is validated against it.
+Required when enabling ACME.`,
+			},
+			"default_role": {
+				Type: framework.TypeString,
+				Description: `The role used for issuance through the acme/ directory.
+When empty, the acme/ directory rejects new orders and only the role-scoped
+roles/:role/acme/ directories may be used to obtain certificates.`,
+			},
+			"allowed_roles": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Roles which may be used through the role-scoped
+roles/:role/acme/ directories. Defaults to "*", allowing all roles.`,
+				Default: defaultAcmeConfig.AllowedRoles,
+			},
+			"dns_resolver": {
+				Type: framework.TypeString,
+				Description: `An optional host:port of the DNS resolver used to
+validate dns-01 challenges. Defaults to the system resolver.`,
+			},
+			"http_challenge_port": {
+				Type:        framework.TypeInt,
+				Description: `The port contacted when validating http-01 challenges. Defaults to 80.`,
+				Default:     defaultAcmeConfig.HTTPChallengePort,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathAcmeConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathAcmeConfigWrite,
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigAcmeHelpSyn,
+		HelpDescription: pathConfigAcmeHelpDesc,
+	}
+}
+
+func (sc *storageContext) getAcmeConfig() (*acmeConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageAcmeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var result acmeConfigEntry
+	if entry == nil {
+		result = defaultAcmeConfig
+		return &result, nil
+	}
+
+	if err = entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (sc *storageContext) writeAcmeConfig(config *acmeConfigEntry) error {
+	entry, err := logical.StorageEntryJSON(storageAcmeConfig, config)
+	if err != nil {
+		return err
+	}
+
+	return sc.Storage.Put(sc.Context, entry)
+}
+
+func (b *backend) pathAcmeConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getAcmeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":             config.Enabled,
+			"base_url":            config.BaseURL,
+			"default_role":        config.DefaultRole,
+			"allowed_roles":       config.AllowedRoles,
+			"dns_resolver":        config.DNSResolver,
+			"http_challenge_port": config.HTTPChallengePort,
+		},
+	}, nil
+}
+
+func (b *backend) pathAcmeConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getAcmeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if baseURLRaw, ok := d.GetOk("base_url"); ok {
+		config.BaseURL = strings.TrimSuffix(baseURLRaw.(string), "/")
+		if config.BaseURL != "" && !govalidator.IsURL(config.BaseURL) {
+			return logical.ErrorResponse(fmt.Sprintf("invalid base_url: %v", config.BaseURL)), nil
+		}
+	}
+
+	if defaultRoleRaw, ok := d.GetOk("default_role"); ok {
+		config.DefaultRole = defaultRoleRaw.(string)
+	}
+
+	if allowedRolesRaw, ok := d.GetOk("allowed_roles"); ok {
+		config.AllowedRoles = allowedRolesRaw.([]string)
+	}
+
+	if resolverRaw, ok := d.GetOk("dns_resolver"); ok {
+		config.DNSResolver = resolverRaw.(string)
+		if config.DNSResolver != "" {
+			if _, _, err := net.SplitHostPort(config.DNSResolver); err != nil {
+				return logical.ErrorResponse(fmt.Sprintf("invalid dns_resolver, expected host:port: %v", err)), nil
+			}
+		}
+	}
+
+	if portRaw, ok := d.GetOk("http_challenge_port"); ok {
+		config.HTTPChallengePort = portRaw.(int)
+		if config.HTTPChallengePort <= 0 || config.HTTPChallengePort > 65535 {
+			return logical.ErrorResponse(fmt.Sprintf("invalid http_challenge_port: %v", config.HTTPChallengePort)), nil
+		}
+	}
+
+	if config.Enabled && config.BaseURL == "" {
+		return logical.ErrorResponse("base_url must be set when enabling ACME"), nil
+	}
+
+	if config.DefaultRole != "" {
+		role, err := b.getRole(ctx, req.Storage, config.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("default_role %q does not exist", config.DefaultRole)), nil
+		}
+	}
+
+	if err := sc.writeAcmeConfig(config); err != nil {
+		return nil, err
+	}
+
+	return b.pathAcmeConfigRead(ctx, req, d)
+}
+
+const pathConfigAcmeHelpSyn = `
+Configuration of the ACME (RFC 8555) server of this mount.
+`
+
+const pathConfigAcmeHelpDesc = `
+This path configures the ACME server endpoints of this PKI mount. When
+enabled, ACME clients may use the directory at acme/directory (issuing
+with the default_role) or roles/:role/acme/directory (issuing with the
+named role) to obtain certificates after proving control of the requested
+identifiers through the http-01 or dns-01 challenges.
+
+ACME clients require the Replay-Nonce, Location and Link response headers;
+add these to the mount's allowed_response_headers when enabling ACME.
+`
@@ -0,0 +1,198 @@
+package pki
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const storageEstConfig = "config/est"
+
+type estConfigEntry struct {
+	Enabled           bool     `json:"enabled"`
+	DefaultRole       string   `json:"default_role"`
+	AllowedRoles      []string `json:"allowed_roles"`
+	CertAuthAccessor  string   `json:"cert_auth_accessor"`
+	BasicAuthAccessor string   `json:"basic_auth_accessor"`
+}
+
+var defaultEstConfig = estConfigEntry{
+	Enabled:           false,
+	DefaultRole:       "",
+	AllowedRoles:      []string{"*"},
+	CertAuthAccessor:  "",
+	BasicAuthAccessor: "",
+}
+
+// isRoleAllowed reports whether the named role may be used through one of
+// the role-scoped EST endpoints.
+func (c *estConfigEntry) isRoleAllowed(name string) bool {
+	return strutil.StrListContains(c.AllowedRoles, "*") || strutil.StrListContains(c.AllowedRoles, name)
+}
+
+func pathConfigEst(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/est",
+		Fields: map[string]*framework.FieldSchema{
+			"enabled": {
+				Type:        framework.TypeBool,
+				Description: `Whether the EST (RFC 7030) endpoints of this mount are enabled. Defaults to false.`,
+				Default:     defaultEstConfig.Enabled,
+			},
+			"default_role": {
+				Type: framework.TypeString,
+				Description: `The role used for issuance through the est/ endpoints.
+When empty, certificates are issued as with sign-verbatim using the
+default issuer.`,
+			},
+			"allowed_roles": {
+				Type: framework.TypeCommaStringSlice,
+				Description: `Roles which may be used through the role-scoped
+roles/:role/est/ endpoints. Defaults to "*", allowing all roles.`,
+				Default: defaultEstConfig.AllowedRoles,
+			},
+			"cert_auth_accessor": {
+				Type: framework.TypeString,
+				Description: `The accessor of a cert auth mount used to authenticate
+EST clients presenting a TLS client certificate to simpleenroll.`,
+			},
+			"basic_auth_accessor": {
+				Type: framework.TypeString,
+				Description: `The accessor of a username and password based auth
+mount (such as userpass or ldap) used to authenticate EST clients using
+HTTP basic authentication on simpleenroll.`,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathEstConfigRead,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathEstConfigWrite,
+				// Read more about why these flags are set in backend.go.
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathConfigEstHelpSyn,
+		HelpDescription: pathConfigEstHelpDesc,
+	}
+}
+
+func (sc *storageContext) getEstConfig() (*estConfigEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, storageEstConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	var result estConfigEntry
+	if entry == nil {
+		result = defaultEstConfig
+		return &result, nil
+	}
+
+	if err = entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (sc *storageContext) writeEstConfig(config *estConfigEntry) error {
+	entry, err := logical.StorageEntryJSON(storageEstConfig, config)
+	if err != nil {
+		return err
+	}
+
+	return sc.Storage.Put(sc.Context, entry)
+}
+
+func (b *backend) pathEstConfigRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getEstConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"enabled":             config.Enabled,
+			"default_role":        config.DefaultRole,
+			"allowed_roles":       config.AllowedRoles,
+			"cert_auth_accessor":  config.CertAuthAccessor,
+			"basic_auth_accessor": config.BasicAuthAccessor,
+		},
+	}, nil
+}
+
+func (b *backend) pathEstConfigWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	sc := b.makeStorageContext(ctx, req.Storage)
+	config, err := sc.getEstConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	if enabledRaw, ok := d.GetOk("enabled"); ok {
+		config.Enabled = enabledRaw.(bool)
+	}
+
+	if defaultRoleRaw, ok := d.GetOk("default_role"); ok {
+		config.DefaultRole = defaultRoleRaw.(string)
+	}
+
+	if allowedRolesRaw, ok := d.GetOk("allowed_roles"); ok {
+		config.AllowedRoles = allowedRolesRaw.([]string)
+	}
+
+	if certAccessorRaw, ok := d.GetOk("cert_auth_accessor"); ok {
+		config.CertAuthAccessor = certAccessorRaw.(string)
+	}
+
+	if basicAccessorRaw, ok := d.GetOk("basic_auth_accessor"); ok {
+		config.BasicAuthAccessor = basicAccessorRaw.(string)
+	}
+
+	if config.DefaultRole != "" {
+		role, err := b.getRole(ctx, req.Storage, config.DefaultRole)
+		if err != nil {
+			return nil, err
+		}
+		if role == nil {
+			return logical.ErrorResponse(fmt.Sprintf("default_role %q does not exist", config.DefaultRole)), nil
+		}
+	}
+
+	if err := sc.writeEstConfig(config); err != nil {
+		return nil, err
+	}
+
+	return b.pathEstConfigRead(ctx, req, d)
+}
+
+const pathConfigEstHelpSyn = `
+Configuration of the EST (RFC 7030) server of this mount.
+`
+
+const pathConfigEstHelpDesc = `
+This path configures the EST enrollment endpoints of this PKI mount. When
+enabled, EST clients may use est/ (issuing with the default_role) or
+roles/:role/est/ (issuing with the named role) in place of the
+/.well-known/est/ prefix of RFC 7030.
+
+The cacerts and csrattrs operations are anonymous. Clients authenticate to
+simpleenroll with either a TLS client certificate, verified by logging in to
+the cert auth mount given by cert_auth_accessor, or HTTP basic credentials,
+verified by logging in to the auth mount given by basic_auth_accessor.
+simplereenroll requires a TLS client certificate issued by this mount which
+has not been revoked, and a CSR with the same subject and subject
+alternative names.
+
+Clients using HTTP basic authentication expect the WWW-Authenticate response
+header, and all clients the Content-Transfer-Encoding header; add these to
+the mount's allowed_response_headers when enabling EST.
+`
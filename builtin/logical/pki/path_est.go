@@ -0,0 +1,377 @@
+package pki
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+var (
+	oidRSAEncryption = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECPublicKey   = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	oidEd25519       = asn1.ObjectIdentifier{1, 3, 101, 112}
+)
+
+func addEstRoleField(fields map[string]*framework.FieldSchema) map[string]*framework.FieldSchema {
+	fields["role"] = &framework.FieldSchema{
+		Type:        framework.TypeString,
+		Description: `The role to issue certificates with, when using the role-scoped EST endpoints.`,
+	}
+	return fields
+}
+
+func pathEstCACerts(b *backend, prefix string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "cacerts",
+		Fields:  addEstRoleField(map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.estWrapper(b.estCACertsHandler),
+			},
+		},
+
+		HelpSynopsis:    pathEstCACertsHelpSyn,
+		HelpDescription: pathEstHelpDesc,
+	}
+}
+
+func pathEstCSRAttrs(b *backend, prefix string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "csrattrs",
+		Fields:  addEstRoleField(map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.estWrapper(b.estCSRAttrsHandler),
+			},
+		},
+
+		HelpSynopsis:    pathEstCSRAttrsHelpSyn,
+		HelpDescription: pathEstHelpDesc,
+	}
+}
+
+func pathEstSimpleEnroll(b *backend, prefix string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "simpleenroll",
+		Fields:  addEstRoleField(map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                  b.estWrapper(b.estSimpleEnrollHandler),
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathEstSimpleEnrollHelpSyn,
+		HelpDescription: pathEstHelpDesc,
+	}
+}
+
+func pathEstSimpleReenroll(b *backend, prefix string) *framework.Path {
+	return &framework.Path{
+		Pattern: prefix + "simplereenroll",
+		Fields:  addEstRoleField(map[string]*framework.FieldSchema{}),
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback:                  b.estWrapper(b.estSimpleReenrollHandler),
+				ForwardPerformanceStandby: true,
+			},
+		},
+
+		HelpSynopsis:    pathEstSimpleReenrollHelpSyn,
+		HelpDescription: pathEstHelpDesc,
+	}
+}
+
+// issuerRef returns the issuer certificates are issued from through this
+// request.
+func (ec *estContext) issuerRef() string {
+	if ec.role != nil && ec.role.Issuer != "" {
+		return ec.role.Issuer
+	}
+	return defaultRef
+}
+
+// estIssuanceRole returns the role to sign an EST CSR with. Without a
+// configured role we sign as sign-verbatim would; with a role the requested
+// names always come from the CSR, as EST has no other way to convey them.
+func (ec *estContext) estIssuanceRole() *roleEntry {
+	if ec.role == nil {
+		entry := &roleEntry{
+			AllowLocalhost:            true,
+			AllowAnyName:              true,
+			AllowIPSANs:               true,
+			AllowWildcardCertificates: new(bool),
+			EnforceHostnames:          false,
+			KeyType:                   "any",
+			UseCSRCommonName:          true,
+			UseCSRSANs:                true,
+			AllowedOtherSANs:          []string{"*"},
+			AllowedSerialNumbers:      []string{"*"},
+			AllowedURISANs:            []string{"*"},
+			CNValidations:             []string{"disabled"},
+			GenerateLease:             new(bool),
+			KeyUsage:                  []string{"DigitalSignature", "KeyAgreement", "KeyEncipherment"},
+			Issuer:                    defaultRef,
+		}
+		*entry.AllowWildcardCertificates = true
+		return entry
+	}
+
+	entry := *ec.role
+	entry.UseCSRCommonName = true
+	entry.UseCSRSANs = true
+	if entry.Issuer == "" {
+		entry.Issuer = defaultRef
+	}
+	return &entry
+}
+
+func (b *backend) estCACertsHandler(ec *estContext, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	issuerId, err := ec.sc.resolveIssuerReference(ec.issuerRef())
+	if err != nil {
+		return nil, estErrorf(http.StatusNotFound, "unable to resolve issuer: %v", err)
+	}
+	issuer, err := ec.sc.fetchIssuerById(issuerId)
+	if err != nil {
+		return nil, err
+	}
+
+	chain := issuer.CAChain
+	if len(chain) == 0 {
+		chain = []string{issuer.Certificate}
+	}
+
+	var certs [][]byte
+	for _, certPem := range chain {
+		block, _ := pem.Decode([]byte(certPem))
+		if block == nil {
+			return nil, fmt.Errorf("failed to decode certificate of issuer %v", issuer.ID)
+		}
+		certs = append(certs, block.Bytes)
+	}
+
+	encoded, err := estCertsOnly(certs)
+	if err != nil {
+		return nil, err
+	}
+
+	return estBase64Response(estCertsContentType, encoded), nil
+}
+
+func (b *backend) estCSRAttrsHandler(ec *estContext, _ *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	// The only attribute we can meaningfully request is the key type
+	// required by the role; otherwise we have no attributes to offer.
+	var attrs []asn1.ObjectIdentifier
+	if ec.role != nil {
+		switch ec.role.KeyType {
+		case "rsa":
+			attrs = append(attrs, oidRSAEncryption)
+		case "ec":
+			attrs = append(attrs, oidECPublicKey)
+		case "ed25519":
+			attrs = append(attrs, oidEd25519)
+		}
+	}
+
+	if len(attrs) == 0 {
+		return &logical.Response{
+			Data: map[string]interface{}{
+				logical.HTTPStatusCode: http.StatusNoContent,
+			},
+		}, nil
+	}
+
+	encoded, err := asn1.Marshal(attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return estBase64Response(estCSRAttrsContentType, encoded), nil
+}
+
+func (b *backend) estSimpleEnrollHandler(ec *estContext, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	csr, err := readEstCSR(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ec.authenticate(b, req); err != nil {
+		return nil, err
+	}
+
+	return b.estIssue(ec, req, csr)
+}
+
+func (b *backend) estSimpleReenrollHandler(ec *estContext, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	csr, err := readEstCSR(req)
+	if err != nil {
+		return nil, err
+	}
+
+	cert := estPeerCertificate(req)
+	if cert == nil {
+		return nil, estErrorf(http.StatusUnauthorized, "re-enrollment requires a TLS client certificate")
+	}
+	if err := b.estVerifyIssuedCert(ec, req, cert); err != nil {
+		return nil, err
+	}
+
+	// RFC 7030 Section 4.2.2: the subject and subject alternative names
+	// must be identical to those of the certificate being renewed.
+	if !bytes.Equal(csr.RawSubject, cert.RawSubject) {
+		return nil, estErrorf(http.StatusBadRequest, "CSR subject does not match the client certificate")
+	}
+	if !estSANsEqual(csr, cert) {
+		return nil, estErrorf(http.StatusBadRequest, "CSR subject alternative names do not match the client certificate")
+	}
+
+	return b.estIssue(ec, req, csr)
+}
+
+// estVerifyIssuedCert checks that the given client certificate was issued
+// by one of the issuers of this mount, is currently valid, and has not
+// been revoked.
+func (b *backend) estVerifyIssuedCert(ec *estContext, req *logical.Request, cert *x509.Certificate) error {
+	issuerIds, err := ec.sc.listIssuers()
+	if err != nil {
+		return err
+	}
+
+	roots := x509.NewCertPool()
+	for _, issuerId := range issuerIds {
+		issuer, err := ec.sc.fetchIssuerById(issuerId)
+		if err != nil {
+			return err
+		}
+		issuerCert, err := issuer.GetCertificate()
+		if err != nil {
+			return err
+		}
+		roots.AddCert(issuerCert)
+	}
+
+	opts := x509.VerifyOptions{
+		Roots:     roots,
+		KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	}
+	if _, err := cert.Verify(opts); err != nil {
+		return estErrorf(http.StatusUnauthorized, "client certificate was not issued by this mount: %v", err)
+	}
+
+	revEntry, err := fetchCertBySerial(ec.sc.Context, b, req, revokedPath, serialFromCert(cert))
+	if err != nil {
+		return err
+	}
+	if revEntry != nil {
+		return estErrorf(http.StatusUnauthorized, "client certificate has been revoked")
+	}
+
+	return nil
+}
+
+func estSANsEqual(csr *x509.CertificateRequest, cert *x509.Certificate) bool {
+	sans := func(dnsNames []string, emails []string, ips []net.IP, uris []*url.URL) []string {
+		var ret []string
+		for _, value := range dnsNames {
+			ret = append(ret, "dns:"+value)
+		}
+		for _, value := range emails {
+			ret = append(ret, "email:"+value)
+		}
+		for _, value := range ips {
+			ret = append(ret, "ip:"+value.String())
+		}
+		for _, value := range uris {
+			ret = append(ret, "uri:"+value.String())
+		}
+		return ret
+	}
+
+	csrSANs := sans(csr.DNSNames, csr.EmailAddresses, csr.IPAddresses, csr.URIs)
+	certSANs := sans(cert.DNSNames, cert.EmailAddresses, cert.IPAddresses, cert.URIs)
+	return strutil.EquivalentSlices(csrSANs, certSANs)
+}
+
+// estIssue signs the given CSR for an EST client and builds the certs-only
+// response carrying the issued certificate.
+func (b *backend) estIssue(ec *estContext, req *logical.Request, csr *x509.CertificateRequest) (*logical.Response, error) {
+	role := ec.estIssuanceRole()
+
+	// If storing the certificate and on a performance standby, forward this
+	// request on to the active node.
+	if !role.NoStore && b.System().ReplicationState().HasState(consts.ReplicationPerformanceStandby) {
+		return nil, logical.ErrReadOnly
+	}
+
+	signingBundle, issuerId, err := ec.sc.fetchCAInfoAndIssuerId(role.Issuer, IssuanceUsage)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch the CA certificate: %w", err)
+	}
+
+	fields := addNonCACommonFields(map[string]*framework.FieldSchema{})
+	fields["csr"] = &framework.FieldSchema{Type: framework.TypeString}
+	raw := map[string]interface{}{
+		"csr": string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csr.Raw})),
+	}
+
+	input := &inputBundle{
+		req:     req,
+		apiData: &framework.FieldData{Raw: raw, Schema: fields},
+		role:    role,
+	}
+	parsedBundle, _, err := signCert(b, input, signingBundle, false, ec.role == nil)
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, estErrorf(http.StatusBadRequest, "%v", err)
+		default:
+			return nil, fmt.Errorf("error signing certificate: %w", err)
+		}
+	}
+
+	if !role.NoStore {
+		if err := b.storeIssuedCert(ec.sc.Context, req.Storage, parsedBundle, ec.roleName, issuerId); err != nil {
+			return nil, err
+		}
+	}
+
+	encoded, err := estCertsOnly([][]byte{parsedBundle.CertificateBytes})
+	if err != nil {
+		return nil, err
+	}
+
+	return estBase64Response(estCertsContentType, encoded), nil
+}
+
+const pathEstHelpDesc = `
+This path is part of the EST (RFC 7030) server of this mount, taking the
+place of the /.well-known/est/ prefix. See the config/est path to enable and
+configure EST.
+`
+
+const pathEstCACertsHelpSyn = `
+Fetch the CA certificates of the issuer used for EST enrollment.
+`
+
+const pathEstCSRAttrsHelpSyn = `
+Fetch the attributes EST clients should include in their CSRs.
+`
+
+const pathEstSimpleEnrollHelpSyn = `
+Enroll for a new certificate over EST.
+`
+
+const pathEstSimpleReenrollHelpSyn = `
+Renew or rekey a certificate previously issued by this mount over EST.
+`
@@ -0,0 +1,339 @@
+package pki
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/credential/aws/pkcs7"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	estTestBasicAccessor = "auth_userpass_est"
+	estTestCertAccessor  = "auth_cert_est"
+)
+
+// estTestSystemView stands in for the system view of Vault core, which lets
+// the backend log in to auth mounts on behalf of EST clients.
+type estTestSystemView struct {
+	logical.StaticSystemView
+
+	loginPaths []string
+}
+
+func (v *estTestSystemView) AuthenticateLogin(_ context.Context, accessor string, req *logical.Request) (*logical.Auth, error) {
+	v.loginPaths = append(v.loginPaths, req.Path)
+	switch accessor {
+	case estTestBasicAccessor:
+		if req.Path == "login/device" && req.Data["password"] == "hunter2" {
+			return &logical.Auth{DisplayName: "device"}, nil
+		}
+	case estTestCertAccessor:
+		if req.Connection != nil && req.Connection.ConnState != nil &&
+			req.Connection.ConnState.PeerCertificates[0].Subject.CommonName == "trusted-device" {
+			return &logical.Auth{DisplayName: "trusted-device"}, nil
+		}
+	default:
+		return nil, fmt.Errorf("no auth mount found for accessor %q", accessor)
+	}
+	return nil, fmt.Errorf("invalid credentials")
+}
+
+func createEstBackendWithStorage(t *testing.T) (*backend, logical.Storage) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	config.System = &estTestSystemView{StaticSystemView: logical.StaticSystemView{
+		DefaultLeaseTTLVal: 24 * time.Hour,
+		MaxLeaseTTLVal:     32 * 24 * time.Hour,
+	}}
+
+	b := Backend(config)
+	require.NoError(t, b.Setup(context.Background(), config))
+	b.pkiStorageVersion.Store(1)
+	return b, config.StorageView
+}
+
+type estTestRequest struct {
+	basicUser     string
+	basicPassword string
+	clientCert    *x509.Certificate
+}
+
+func estTestDo(t *testing.T, b *backend, s logical.Storage, operation logical.Operation, path string, csr []byte, opts estTestRequest) (int, []byte, *logical.Response) {
+	req := &logical.Request{
+		Operation:  operation,
+		Path:       path,
+		Storage:    s,
+		MountPoint: "pki/",
+		Data:       map[string]interface{}{},
+		Connection: &logical.Connection{RemoteAddr: "127.0.0.1"},
+	}
+
+	if operation == logical.UpdateOperation {
+		httpReq := httptest.NewRequest(http.MethodPost, "/v1/pki/"+path,
+			strings.NewReader(base64.StdEncoding.EncodeToString(csr)))
+		httpReq.Header.Set("Content-Type", "application/pkcs10")
+		if opts.basicUser != "" {
+			httpReq.SetBasicAuth(opts.basicUser, opts.basicPassword)
+		}
+		req.HTTPRequest = httpReq
+	}
+	if opts.clientCert != nil {
+		req.Connection.ConnState = &tls.ConnectionState{
+			PeerCertificates: []*x509.Certificate{opts.clientCert},
+		}
+	}
+
+	resp, err := b.HandleRequest(context.Background(), req)
+	require.NoError(t, err)
+	require.NotNil(t, resp)
+
+	status := resp.Data[logical.HTTPStatusCode].(int)
+	body, _ := resp.Data[logical.HTTPRawBody].([]byte)
+	return status, body, resp
+}
+
+func estTestCerts(t *testing.T, body []byte) []*x509.Certificate {
+	der, err := base64.StdEncoding.DecodeString(string(body))
+	require.NoError(t, err)
+	p7, err := pkcs7.Parse(der)
+	require.NoError(t, err)
+	return p7.Certificates
+}
+
+func estTestCSR(t *testing.T, commonName string, dnsNames ...string) []byte {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	csr, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: commonName},
+		DNSNames: dnsNames,
+	}, key)
+	require.NoError(t, err)
+	return csr
+}
+
+func estTestSelfSigned(t *testing.T, commonName string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func TestEst_Enrollment(t *testing.T) {
+	t.Parallel()
+	b, s := createEstBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root X1",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	root := parseCert(t, resp.Data["certificate"].(string))
+
+	// EST is disabled by default.
+	status, _, _ := estTestDo(t, b, s, logical.ReadOperation, "est/cacerts", nil, estTestRequest{})
+	require.Equal(t, http.StatusNotFound, status)
+
+	resp, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":             true,
+		"basic_auth_accessor": estTestBasicAccessor,
+		"cert_auth_accessor":  estTestCertAccessor,
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed enabling EST")
+
+	// cacerts returns the chain of the default issuer.
+	status, body, resp := estTestDo(t, b, s, logical.ReadOperation, "est/cacerts", nil, estTestRequest{})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, estCertsContentType, resp.Data[logical.HTTPContentType])
+	require.Equal(t, []string{"base64"}, resp.Headers["Content-Transfer-Encoding"])
+	caCerts := estTestCerts(t, body)
+	require.Len(t, caCerts, 1)
+	require.True(t, caCerts[0].Equal(root))
+
+	// Without a role there are no CSR attributes to ask for.
+	status, _, _ = estTestDo(t, b, s, logical.ReadOperation, "est/csrattrs", nil, estTestRequest{})
+	require.Equal(t, http.StatusNoContent, status)
+
+	csr := estTestCSR(t, "device.example.com", "device.example.com")
+
+	// Enrollment requires authentication.
+	status, _, resp = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", csr, estTestRequest{})
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.NotEmpty(t, resp.Headers["WWW-Authenticate"])
+
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", csr, estTestRequest{
+		basicUser:     "device",
+		basicPassword: "wrong",
+	})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// Usernames can't reach paths of the auth mount other than login.
+	sysView := b.System().(*estTestSystemView)
+	numLogins := len(sysView.loginPaths)
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", csr, estTestRequest{
+		basicUser:     "device/password",
+		basicPassword: "hunter2",
+	})
+	require.Equal(t, http.StatusUnauthorized, status)
+	require.Len(t, sysView.loginPaths, numLogins)
+
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", csr, estTestRequest{
+		clientCert: estTestSelfSigned(t, "untrusted-device"),
+	})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	// Garbage bodies are rejected.
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", []byte("not a csr"), estTestRequest{
+		basicUser:     "device",
+		basicPassword: "hunter2",
+	})
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, body, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", csr, estTestRequest{
+		basicUser:     "device",
+		basicPassword: "hunter2",
+	})
+	require.Equal(t, http.StatusOK, status, "body: %s", body)
+	issued := estTestCerts(t, body)
+	require.Len(t, issued, 1)
+	leaf := issued[0]
+	require.Equal(t, "device.example.com", leaf.Subject.CommonName)
+	require.Equal(t, []string{"device.example.com"}, leaf.DNSNames)
+	requireSignedBy(t, leaf, root)
+
+	// The issued certificate is stored like any other.
+	resp, err = CBRead(b, s, "cert/"+serialFromCert(leaf))
+	requireSuccessNonNilResponse(t, resp, err, "failed reading issued certificate")
+
+	status, body, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simpleenroll", csr, estTestRequest{
+		clientCert: estTestSelfSigned(t, "trusted-device"),
+	})
+	require.Equal(t, http.StatusOK, status, "body: %s", body)
+
+	// Re-enrollment requires a certificate issued by this mount, with a
+	// matching subject.
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simplereenroll", csr, estTestRequest{
+		basicUser:     "device",
+		basicPassword: "hunter2",
+	})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simplereenroll", csr, estTestRequest{
+		clientCert: estTestSelfSigned(t, "device.example.com"),
+	})
+	require.Equal(t, http.StatusUnauthorized, status)
+
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simplereenroll",
+		estTestCSR(t, "other.example.com", "other.example.com"), estTestRequest{clientCert: leaf})
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simplereenroll",
+		estTestCSR(t, "device.example.com", "device.example.com", "extra.example.com"), estTestRequest{clientCert: leaf})
+	require.Equal(t, http.StatusBadRequest, status)
+
+	rekeyCSR := estTestCSR(t, "device.example.com", "device.example.com")
+	status, body, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simplereenroll", rekeyCSR, estTestRequest{clientCert: leaf})
+	require.Equal(t, http.StatusOK, status, "body: %s", body)
+	renewed := estTestCerts(t, body)[0]
+	require.Equal(t, leaf.Subject.CommonName, renewed.Subject.CommonName)
+	require.NotEqual(t, leaf.SerialNumber, renewed.SerialNumber)
+
+	// Revoked certificates can no longer be used to re-enroll.
+	_, err = CBWrite(b, s, "revoke", map[string]interface{}{
+		"serial_number": serialFromCert(leaf),
+	})
+	require.NoError(t, err)
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "est/simplereenroll", rekeyCSR, estTestRequest{clientCert: leaf})
+	require.Equal(t, http.StatusUnauthorized, status)
+}
+
+func TestEst_Roles(t *testing.T) {
+	t.Parallel()
+	b, s := createEstBackendWithStorage(t)
+
+	resp, err := CBWrite(b, s, "root/generate/internal", map[string]interface{}{
+		"common_name": "Root X1",
+		"key_type":    "ec",
+		"ttl":         "87600h",
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed generating root")
+	root := parseCert(t, resp.Data["certificate"].(string))
+
+	for _, role := range []string{"devices", "servers"} {
+		_, err = CBWrite(b, s, "roles/"+role, map[string]interface{}{
+			"allowed_domains":  "example.com",
+			"allow_subdomains": true,
+			"key_type":         "ec",
+			"ttl":              "1h",
+		})
+		require.NoError(t, err)
+	}
+
+	_, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":             true,
+		"default_role":        "missing",
+		"basic_auth_accessor": estTestBasicAccessor,
+	})
+	require.Error(t, err)
+
+	resp, err = CBWrite(b, s, "config/est", map[string]interface{}{
+		"enabled":             true,
+		"allowed_roles":       "devices",
+		"basic_auth_accessor": estTestBasicAccessor,
+	})
+	requireSuccessNonNilResponse(t, resp, err, "failed enabling EST")
+	require.Equal(t, []string{"devices"}, resp.Data["allowed_roles"])
+
+	// Roles ask for their key type.
+	status, body, resp := estTestDo(t, b, s, logical.ReadOperation, "roles/devices/est/csrattrs", nil, estTestRequest{})
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, estCSRAttrsContentType, resp.Data[logical.HTTPContentType])
+	der, err := base64.StdEncoding.DecodeString(string(body))
+	require.NoError(t, err)
+	var attrs []asn1.ObjectIdentifier
+	_, err = asn1.Unmarshal(der, &attrs)
+	require.NoError(t, err)
+	require.Equal(t, []asn1.ObjectIdentifier{oidECPublicKey}, attrs)
+
+	creds := estTestRequest{basicUser: "device", basicPassword: "hunter2"}
+
+	status, _, _ = estTestDo(t, b, s, logical.ReadOperation, "roles/servers/est/cacerts", nil, estTestRequest{})
+	require.Equal(t, http.StatusForbidden, status)
+
+	status, _, _ = estTestDo(t, b, s, logical.UpdateOperation, "roles/devices/est/simpleenroll",
+		estTestCSR(t, "device.example.org", "device.example.org"), creds)
+	require.Equal(t, http.StatusBadRequest, status)
+
+	status, body, _ = estTestDo(t, b, s, logical.UpdateOperation, "roles/devices/est/simpleenroll",
+		estTestCSR(t, "device.example.com", "device.example.com"), creds)
+	require.Equal(t, http.StatusOK, status, "body: %s", body)
+	leaf := estTestCerts(t, body)[0]
+	requireSignedBy(t, leaf, root)
+	require.Equal(t, "device.example.com", leaf.Subject.CommonName)
+	require.WithinDuration(t, time.Now().Add(time.Hour), leaf.NotAfter, 5*time.Minute)
+}
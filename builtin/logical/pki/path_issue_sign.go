@@ -241,6 +241,7 @@ func (b *backend) pathSignVerbatim(ctx context.Context, req *logical.Request, da
 		}
 		entry.NoStore = role.NoStore
 		entry.Issuer = role.Issuer
+		entry.IssuancePolicy = role.IssuancePolicy
 	}
 
 	if len(entry.Issuer) == 0 {
@@ -290,7 +291,7 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 
 	var caErr error
 	sc := b.makeStorageContext(ctx, req.Storage)
-	signingBundle, caErr := sc.fetchCAInfo(issuerName, IssuanceUsage)
+	signingBundle, issuerId, caErr := sc.fetchCAInfoAndIssuerId(issuerName, IssuanceUsage)
 	if caErr != nil {
 		switch caErr.(type) {
 		case errutil.UserError:
@@ -409,16 +410,13 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	}
 
 	if !role.NoStore {
-		key := "certs/" + normalizeSerial(cb.SerialNumber)
-		certsCounted := b.certsCounted.Load()
-		err = req.Storage.Put(ctx, &logical.StorageEntry{
-			Key:   key,
-			Value: parsedBundle.CertificateBytes,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+		var roleName string
+		if rawRoleName, ok := data.GetOk("role"); ok {
+			roleName = rawRoleName.(string)
+		}
+		if err := b.storeIssuedCert(ctx, req.Storage, parsedBundle, roleName, issuerId); err != nil {
+			return nil, err
 		}
-		b.incrementTotalCertificatesCount(certsCounted, key)
 	}
 
 	if useCSR {
@@ -435,6 +433,29 @@ func (b *backend) pathIssueSignCert(ctx context.Context, req *logical.Request, d
 	return resp, nil
 }
 
+// storeIssuedCert persists a newly issued certificate under certs/ along
+// with its inventory record, updating the count of stored certificates.
+func (b *backend) storeIssuedCert(ctx context.Context, s logical.Storage, parsedBundle *certutil.ParsedCertBundle, roleName string, issuerId issuerID) error {
+	serial := serialFromCert(parsedBundle.Certificate)
+	key := "certs/" + normalizeSerial(serial)
+	certsCounted := b.certsCounted.Load()
+	err := s.Put(ctx, &logical.StorageEntry{
+		Key:   key,
+		Value: parsedBundle.CertificateBytes,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to store certificate locally: %w", err)
+	}
+	b.incrementTotalCertificatesCount(certsCounted, key)
+
+	sc := b.makeStorageContext(ctx, s)
+	if err := sc.writeCertInventoryEntry(newCertInventoryEntry(parsedBundle.Certificate, roleName, issuerId)); err != nil {
+		return fmt.Errorf("unable to store certificate inventory entry: %w", err)
+	}
+
+	return nil
+}
+
 type caChainOutput struct {
 	chain []*certutil.CertBlock
 }
@@ -390,6 +390,21 @@ func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, dat
 			if err != nil {
 				return nil, err
 			}
+
+			// The certificate wasn't stored when issued, so it has no
+			// inventory entry either; add one without a role.
+			sc := b.makeStorageContext(ctx, req.Storage)
+			issuerIDCertMap, err := fetchIssuerMapForRevocationChecking(sc)
+			if err != nil {
+				return nil, err
+			}
+			cert, err := x509.ParseCertificate(certBytes)
+			if err != nil {
+				return nil, err
+			}
+			if err := sc.backfillCertInventoryEntry(cert, issuerIDCertMap); err != nil {
+				return nil, err
+			}
 		}
 
 		// Finally, we have a valid serial number to use for BYOC revocation!
@@ -440,6 +440,14 @@ The value format should be given in UTC format YYYY-MM-ddTHH:MM:SSZ.`,
 serviced by this role.`,
 				Default: defaultRef,
 			},
+			"issuance_policy": {
+				Type: framework.TypeString,
+				Description: `A template evaluated against the request, the
+CSR and the identity of the caller before a certificate is issued. The
+template may deny the request by calling deny, or modify the names of the
+certificate by rendering a JSON object. Names set by the policy must be
+allowed by the role.`,
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -703,6 +711,7 @@ func (b *backend) pathRoleCreate(ctx context.Context, req *logical.Request, data
 		NotBeforeDuration:             time.Duration(data.Get("not_before_duration").(int)) * time.Second,
 		NotAfter:                      data.Get("not_after").(string),
 		Issuer:                        data.Get("issuer_ref").(string),
+		IssuancePolicy:                data.Get("issuance_policy").(string),
 	}
 
 	allowedOtherSANs := data.Get("allowed_other_sans").([]string)
@@ -796,6 +805,12 @@ func validateRole(b *backend, entry *roleEntry, ctx context.Context, s logical.S
 		}
 	}
 
+	if entry.IssuancePolicy != "" {
+		if _, err := parseIssuancePolicy(entry.IssuancePolicy, func(string) {}); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid issuance_policy: %v", err)), nil
+		}
+	}
+
 	// Ensure issuers ref is set to a non-empty value. Note that we never
 	// resolve the reference (to an issuerId) at role creation time; instead,
 	// resolve it at use time. This allows values such as `default` or other
@@ -903,6 +918,7 @@ func (b *backend) pathRolePatch(ctx context.Context, req *logical.Request, data
 		NotBeforeDuration:             getTimeWithExplicitDefault(data, "not_before_duration", oldEntry.NotBeforeDuration),
 		NotAfter:                      getWithExplicitDefault(data, "not_after", oldEntry.NotAfter).(string),
 		Issuer:                        getWithExplicitDefault(data, "issuer_ref", oldEntry.Issuer).(string),
+		IssuancePolicy:                getWithExplicitDefault(data, "issuance_policy", oldEntry.IssuancePolicy).(string),
 	}
 
 	allowedOtherSANsData, wasSet := data.GetOk("allowed_other_sans")
@@ -1108,6 +1124,7 @@ type roleEntry struct {
 	NotBeforeDuration             time.Duration `json:"not_before_duration"`
 	NotAfter                      string        `json:"not_after"`
 	Issuer                        string        `json:"issuer"`
+	IssuancePolicy                string        `json:"issuance_policy"`
 }
 
 func (r *roleEntry) ToResponseData() map[string]interface{} {
@@ -1157,6 +1174,7 @@ func (r *roleEntry) ToResponseData() map[string]interface{} {
 		"not_before_duration":                int64(r.NotBeforeDuration.Seconds()),
 		"not_after":                          r.NotAfter,
 		"issuer_ref":                         r.Issuer,
+		"issuance_policy":                    r.IssuancePolicy,
 	}
 	if r.MaxPathLength != nil {
 		responseData["max_path_length"] = r.MaxPathLength
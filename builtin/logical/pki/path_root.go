@@ -258,16 +258,9 @@ func (b *backend) pathCAGenerateRoot(ctx context.Context, req *logical.Request,
 
 	// Also store it as just the certificate identified by serial number, so it
 	// can be revoked
-	key := "certs/" + normalizeSerial(cb.SerialNumber)
-	certsCounted := b.certsCounted.Load()
-	err = req.Storage.Put(ctx, &logical.StorageEntry{
-		Key:   key,
-		Value: parsedBundle.CertificateBytes,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+	if err := b.storeIssuedCert(ctx, req.Storage, parsedBundle, "", myIssuer.ID); err != nil {
+		return nil, err
 	}
-	b.incrementTotalCertificatesCount(certsCounted, key)
 
 	// Build a fresh CRL
 	err = b.crlBuilder.rebuild(ctx, b, req, true)
@@ -331,7 +324,7 @@ func (b *backend) pathIssuerSignIntermediate(ctx context.Context, req *logical.R
 
 	var caErr error
 	sc := b.makeStorageContext(ctx, req.Storage)
-	signingBundle, caErr := sc.fetchCAInfo(issuerName, IssuanceUsage)
+	signingBundle, signingIssuerId, caErr := sc.fetchCAInfoAndIssuerId(issuerName, IssuanceUsage)
 	if caErr != nil {
 		switch caErr.(type) {
 		case errutil.UserError:
@@ -445,16 +438,9 @@ func (b *backend) pathIssuerSignIntermediate(ctx context.Context, req *logical.R
 		return nil, fmt.Errorf("unsupported format argument: %s", format)
 	}
 
-	key := "certs/" + normalizeSerial(cb.SerialNumber)
-	certsCounted := b.certsCounted.Load()
-	err = req.Storage.Put(ctx, &logical.StorageEntry{
-		Key:   key,
-		Value: parsedBundle.CertificateBytes,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+	if err := b.storeIssuedCert(ctx, req.Storage, parsedBundle, "", signingIssuerId); err != nil {
+		return nil, err
 	}
-	b.incrementTotalCertificatesCount(certsCounted, key)
 
 	if parsedBundle.Certificate.MaxPathLen == 0 {
 		resp.AddWarning("Max path length of the signed certificate is zero. This certificate cannot be used to issue intermediate CA certificates.")
@@ -562,6 +548,213 @@ func (b *backend) pathIssuerSignSelfIssued(ctx context.Context, req *logical.Req
 	}, nil
 }
 
+func (b *backend) pathIssuerCrossSign(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	// Cross-signing a local issuer imports the result, so grab the lock
+	// to get a consistent view of the issuers.
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if b.useLegacyBundleCaStorage() {
+		return logical.ErrorResponse("Can not cross-sign issuers until migration has completed"), nil
+	}
+
+	issuerName := getIssuerRef(data)
+	if len(issuerName) == 0 {
+		return logical.ErrorResponse("missing issuer reference"), nil
+	}
+
+	existingRef := strings.TrimSpace(data.Get("existing_issuer_ref").(string))
+	certPem := data.Get("certificate").(string)
+	if (existingRef == "") == (certPem == "") {
+		return logical.ErrorResponse("exactly one of existing_issuer_ref or certificate must be provided"), nil
+	}
+
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	var cert *x509.Certificate
+	var newIssuerName string
+	var err error
+	if existingRef != "" {
+		newIssuerName, err = getIssuerName(sc, data)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+
+		existingID, err := sc.resolveIssuerReference(existingRef)
+		if err != nil {
+			if existingID == IssuerRefNotFound {
+				return logical.ErrorResponse(err.Error()), nil
+			}
+			return nil, err
+		}
+		existing, err := sc.fetchIssuerById(existingID)
+		if err != nil {
+			return nil, err
+		}
+		if len(existing.KeyID) == 0 {
+			return logical.ErrorResponse("existing issuer has no key associated with it; the cross-signed certificate could not be used by this mount"), nil
+		}
+		cert, err = existing.GetCertificate()
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		if _, ok := data.GetOk("issuer_name"); ok {
+			return logical.ErrorResponse("issuer_name can only be used with existing_issuer_ref"), nil
+		}
+		cert, err = parseCertificateFromBytes([]byte(certPem))
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("error parsing certificate: %s", err)), nil
+		}
+	}
+
+	if !cert.BasicConstraintsValid || !cert.IsCA {
+		return logical.ErrorResponse("given certificate is not a CA certificate"), nil
+	}
+
+	signingBundle, signingIssuerId, caErr := sc.fetchCAInfoAndIssuerId(issuerName, IssuanceUsage)
+	if caErr != nil {
+		switch caErr.(type) {
+		case errutil.UserError:
+			return nil, errutil.UserError{Err: fmt.Sprintf(
+				"could not fetch the CA certificate (was one set?): %s", caErr)}
+		default:
+			return nil, errutil.InternalError{Err: fmt.Sprintf("error fetching CA certificate: %s", caErr)}
+		}
+	}
+
+	sameKey, err := certutil.ComparePublicKeysAndType(signingBundle.Certificate.PublicKey, cert.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if sameKey {
+		return logical.ErrorResponse("the certificate to cross-sign uses the key of the signing issuer; use the issuer's reissuance workflow instead"), nil
+	}
+
+	notAfter := cert.NotAfter
+	ttl := time.Duration(data.Get("ttl").(int)) * time.Second
+	notAfterStr := data.Get("not_after").(string)
+	switch {
+	case ttl > 0 && notAfterStr != "":
+		return logical.ErrorResponse("Either ttl or not_after should be provided. Both should not be provided in the same request."), nil
+	case ttl > 0:
+		notAfter = time.Now().Add(ttl)
+	case notAfterStr != "":
+		notAfter, err = time.Parse(time.RFC3339, notAfterStr)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+	}
+
+	serialNumber, err := certutil.GenerateSerialNumber()
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the subject, key and SKID of the existing certificate so the
+	// cross-signed certificate is interchangeable with it in chains, along
+	// with its CA constraints. Configured URLs for CRLs/OCSP/etc. are taken
+	// from the signing issuer.
+	urls := &certutil.URLEntries{}
+	if signingBundle.URLs != nil {
+		urls = signingBundle.URLs
+	}
+	template := &x509.Certificate{
+		SerialNumber:                serialNumber,
+		RawSubject:                  cert.RawSubject,
+		SubjectKeyId:                cert.SubjectKeyId,
+		NotBefore:                   time.Now().Add(-30 * time.Second),
+		NotAfter:                    notAfter,
+		KeyUsage:                    cert.KeyUsage,
+		ExtKeyUsage:                 cert.ExtKeyUsage,
+		UnknownExtKeyUsage:          cert.UnknownExtKeyUsage,
+		BasicConstraintsValid:       true,
+		IsCA:                        true,
+		MaxPathLen:                  cert.MaxPathLen,
+		MaxPathLenZero:              cert.MaxPathLenZero,
+		PermittedDNSDomainsCritical: cert.PermittedDNSDomainsCritical,
+		PermittedDNSDomains:         cert.PermittedDNSDomains,
+		ExcludedDNSDomains:          cert.ExcludedDNSDomains,
+		PermittedIPRanges:           cert.PermittedIPRanges,
+		ExcludedIPRanges:            cert.ExcludedIPRanges,
+		PermittedEmailAddresses:     cert.PermittedEmailAddresses,
+		ExcludedEmailAddresses:      cert.ExcludedEmailAddresses,
+		PermittedURIDomains:         cert.PermittedURIDomains,
+		ExcludedURIDomains:          cert.ExcludedURIDomains,
+		PolicyIdentifiers:           cert.PolicyIdentifiers,
+		IssuingCertificateURL:       urls.IssuingCertificates,
+		CRLDistributionPoints:       urls.CRLDistributionPoints,
+		OCSPServer:                  urls.OCSPServers,
+	}
+
+	newCert, err := x509.CreateCertificate(rand.Reader, template, signingBundle.Certificate, cert.PublicKey, signingBundle.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("error cross-signing certificate: %w", err)
+	}
+	parsedCert, err := x509.ParseCertificate(newCert)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing cross-signed certificate: %w", err)
+	}
+	pemCert := strings.TrimSpace(string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: newCert,
+	})))
+
+	// Like any other certificate issued by this mount, store the
+	// cross-signed certificate so it can be listed and revoked.
+	if err := b.storeIssuedCert(ctx, req.Storage, &certutil.ParsedCertBundle{
+		Certificate:      parsedCert,
+		CertificateBytes: newCert,
+	}, "", signingIssuerId); err != nil {
+		return nil, err
+	}
+
+	signingCB, err := signingBundle.ToCertBundle()
+	if err != nil {
+		return nil, fmt.Errorf("error converting raw signing bundle to cert bundle: %w", err)
+	}
+	caChain := append([]string{pemCert}, signingCB.CAChain...)
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"certificate":   pemCert,
+			"issuing_ca":    signingCB.Certificate,
+			"ca_chain":      caChain,
+			"serial_number": certutil.GetHexFormatted(serialNumber.Bytes(), ":"),
+			"expiration":    notAfter.Unix(),
+		},
+	}
+
+	if signingBundle.Certificate.NotAfter.Before(notAfter) {
+		resp.AddWarning("The expiration time for the cross-signed certificate is after the CA's expiration time. Validation paths with the certificate past the issuing CA's expiration time will fail.")
+	}
+
+	if existingRef == "" {
+		return resp, nil
+	}
+
+	imported, _, err := sc.importIssuer(pemCert, newIssuerName)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["issuer_id"] = imported.ID
+	resp.Data["issuer_name"] = imported.Name
+
+	// Importing rebuilt the chains of the mount, so our issuer should now
+	// chain to the signing issuer.
+	imported, err = sc.fetchIssuerById(imported.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp.Data["ca_chain"] = imported.CAChain
+
+	if err := b.crlBuilder.rebuild(ctx, b, req, true); err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
 // Adapted from similar code in https://github.com/golang/go/blob/4a4221e8187189adcc6463d2d96fe2e8da290132/src/crypto/x509/x509.go#L1342,
 // may need to be updated in the future.
 func publicKeyType(pub crypto.PublicKey) (pubType x509.PublicKeyAlgorithm, sigAlgo x509.SignatureAlgorithm, err error) {
@@ -173,3 +173,76 @@ verbatim from the given certificate.
 See the API documentation for more information about required parameters.
 `
 )
+
+func pathIssuerCrossSign(b *backend) *framework.Path {
+	fields := map[string]*framework.FieldSchema{
+		"existing_issuer_ref": {
+			Type: framework.TypeString,
+			Description: `Reference to an existing issuer of this mount to
+cross-sign. The resulting certificate is imported into this mount as a new
+issuer sharing the existing issuer's key. Mutually exclusive with
+certificate.`,
+		},
+		"certificate": {
+			Type: framework.TypeString,
+			Description: `PEM-format CA certificate to cross-sign, such as an
+issuer of another PKI mount. The resulting certificate is returned but not
+imported. Mutually exclusive with existing_issuer_ref.`,
+		},
+		"issuer_name": {
+			Type: framework.TypeString,
+			Description: `Name to give the imported issuer when cross-signing
+an existing issuer of this mount; the name must be unique across all issuers
+and not be the reserved value 'default'.`,
+		},
+		"ttl": {
+			Type: framework.TypeDurationSecond,
+			Description: `The requested time-to-live of the cross-signed
+certificate. Defaults to the expiration of the certificate being
+cross-signed.`,
+		},
+		"not_after": {
+			Type: framework.TypeString,
+			Description: `Set the not after field of the certificate with specified date value.
+The value format should be given in UTC format YYYY-MM-ddTHH:MM:SSZ.`,
+		},
+	}
+	fields = addIssuerRefField(fields)
+
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex(issuerRefParam) + "/cross-sign",
+		Fields:  fields,
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathIssuerCrossSign,
+				// Read more about why these flags are set in backend.go
+				ForwardPerformanceStandby:   true,
+				ForwardPerformanceSecondary: true,
+			},
+		},
+
+		HelpSynopsis:    pathIssuerCrossSignHelpSyn,
+		HelpDescription: pathIssuerCrossSignHelpDesc,
+	}
+}
+
+const (
+	pathIssuerCrossSignHelpSyn  = `Cross-sign an existing CA certificate with this issuer.`
+	pathIssuerCrossSignHelpDesc = `
+This API endpoint issues a certificate for the subject, key and subject key
+identifier of an existing CA certificate, signed by the issuer given in the
+path. The cross-signed certificate gives the existing CA an alternative
+validation path through this issuer, which is useful when migrating to a new
+root.
+
+When cross-signing an existing issuer of this mount, the new certificate is
+imported as an issuer using the existing issuer's key and the issuer chains of
+the mount are rebuilt. When cross-signing a certificate from elsewhere, such as
+an issuer of another PKI mount, the new certificate and the chain of this
+issuer are returned; importing both into the other mount through its
+issuers/import/cert endpoint rebuilds its issuer chains.
+
+Like sign-self-issued, this is a very privileged operation and should be
+extremely restricted in terms of who is allowed to use it.
+`
+)
@@ -234,6 +234,16 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 		return fmt.Errorf("error fetching list of certs: %w", err)
 	}
 
+	sc := b.makeStorageContext(ctx, req.Storage)
+
+	// Certificates stored before the inventory existed get an entry when
+	// kept, so that searches find them; their issuer is identified among
+	// the present issuers.
+	issuerIDCertMap, err := fetchIssuerMapForRevocationChecking(sc)
+	if err != nil {
+		return err
+	}
+
 	serialCount := len(serials)
 	metrics.SetGauge([]string{"secrets", "pki", "tidy", "cert_store_total_entries"}, float32(serialCount))
 	for i, serial := range serials {
@@ -260,6 +270,9 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
 				return fmt.Errorf("error deleting nil entry with serial %s: %w", serial, err)
 			}
+			if err := sc.deleteCertInventoryEntry(serial); err != nil {
+				return fmt.Errorf("error deleting inventory entry with serial %s: %w", serial, err)
+			}
 			b.tidyStatusIncCertStoreCount()
 			continue
 		}
@@ -269,6 +282,9 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
 				return fmt.Errorf("error deleting entry with nil value with serial %s: %w", serial, err)
 			}
+			if err := sc.deleteCertInventoryEntry(serial); err != nil {
+				return fmt.Errorf("error deleting inventory entry with serial %s: %w", serial, err)
+			}
 			b.tidyStatusIncCertStoreCount()
 			continue
 		}
@@ -282,7 +298,15 @@ func (b *backend) doTidyCertStore(ctx context.Context, req *logical.Request, log
 			if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
 				return fmt.Errorf("error deleting serial %q from storage: %w", serial, err)
 			}
+			if err := sc.deleteCertInventoryEntry(serial); err != nil {
+				return fmt.Errorf("error deleting serial %q from inventory: %w", serial, err)
+			}
 			b.tidyStatusIncCertStoreCount()
+			continue
+		}
+
+		if err := sc.backfillCertInventoryEntry(cert, issuerIDCertMap); err != nil {
+			return fmt.Errorf("error adding serial %q to inventory: %w", serial, err)
 		}
 	}
 
@@ -393,6 +417,9 @@ func (b *backend) doTidyRevocationStore(ctx context.Context, req *logical.Reques
 				if err := req.Storage.Delete(ctx, "certs/"+serial); err != nil {
 					return fmt.Errorf("error deleting serial %q from store when tidying revoked: %w", serial, err)
 				}
+				if err := sc.deleteCertInventoryEntry(serial); err != nil {
+					return fmt.Errorf("error deleting serial %q from inventory when tidying revoked: %w", serial, err)
+				}
 				rebuildCRL = true
 				storeCert = false
 				b.tidyStatusIncRevokedCertCount()
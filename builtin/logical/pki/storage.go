@@ -6,6 +6,7 @@ import (
 	"crypto"
 	"crypto/x509"
 	"fmt"
+	"net/url"
 	"sort"
 	"strings"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/hashicorp/vault/sdk/helper/certutil"
 	"github.com/hashicorp/vault/sdk/helper/errutil"
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/ryanuber/go-glob"
 )
 
 const (
@@ -31,6 +33,8 @@ const (
 
 	autoTidyConfigPath = "config/auto-tidy"
 
+	certInventoryPrefix = "certs-inventory/"
+
 	// Used as a quick sanity check for a reference id lookups...
 	uuidLength = 36
 
@@ -1216,3 +1220,544 @@ func (sc *storageContext) writeAutoTidyConfig(config *tidyConfig) error {
 
 	return sc.Storage.Put(sc.Context, entry)
 }
+
+// certInventoryEntry is the searchable record of an issued certificate,
+// stored alongside it under certInventoryPrefix. It holds the information
+// needed to search the certificate store without parsing every certificate,
+// along with the role and issuer used, which aren't otherwise recorded.
+type certInventoryEntry struct {
+	SerialNumber   string    `json:"serial_number"`
+	CommonName     string    `json:"common_name"`
+	DNSNames       []string  `json:"dns_names"`
+	EmailAddresses []string  `json:"email_addresses"`
+	IPAddresses    []string  `json:"ip_addresses"`
+	URISANs        []string  `json:"uri_sans"`
+	Role           string    `json:"role"`
+	IssuerID       issuerID  `json:"issuer_id"`
+	NotBefore      time.Time `json:"not_before"`
+	NotAfter       time.Time `json:"not_after"`
+
+	// Backfilled is set on records added by tidy for certificates stored
+	// without one, which lack the role.
+	Backfilled bool `json:"backfilled,omitempty"`
+
+	// The revocation status is tracked under revoked/ and only filled in
+	// when searching.
+	Revoked        bool      `json:"-"`
+	RevocationTime time.Time `json:"-"`
+}
+
+func newCertInventoryEntry(cert *x509.Certificate, role string, issuerId issuerID) *certInventoryEntry {
+	return &certInventoryEntry{
+		SerialNumber:   serialFromCert(cert),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    ipStrings(cert.IPAddresses),
+		URISANs:        uriStrings(cert.URIs),
+		Role:           role,
+		IssuerID:       issuerId,
+		NotBefore:      cert.NotBefore,
+		NotAfter:       cert.NotAfter,
+	}
+}
+
+// certInventoryFilter selects certificates from the inventory; zero values
+// match every certificate.
+type certInventoryFilter struct {
+	// CommonName and SAN are case-insensitive glob patterns matched against
+	// the common name and any subject alternative name respectively.
+	CommonName    string
+	SAN           string
+	Role          string
+	IssuerID      issuerID
+	ExpiresAfter  time.Time
+	ExpiresBefore time.Time
+	// Status is one of the certInventoryStatus values or empty.
+	Status string
+}
+
+const (
+	certInventoryStatusAny     = "any"
+	certInventoryStatusValid   = "valid"
+	certInventoryStatusExpired = "expired"
+	certInventoryStatusRevoked = "revoked"
+)
+
+func (f *certInventoryFilter) matches(entry *certInventoryEntry, now time.Time) bool {
+	if f.CommonName != "" && !glob.Glob(strings.ToLower(f.CommonName), strings.ToLower(entry.CommonName)) {
+		return false
+	}
+
+	if f.SAN != "" {
+		pattern := strings.ToLower(f.SAN)
+		found := false
+		for _, names := range [][]string{entry.DNSNames, entry.EmailAddresses, entry.IPAddresses, entry.URISANs} {
+			for _, name := range names {
+				if glob.Glob(pattern, strings.ToLower(name)) {
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Role != "" && f.Role != entry.Role {
+		return false
+	}
+	if f.IssuerID != "" && f.IssuerID != entry.IssuerID {
+		return false
+	}
+	if !f.ExpiresAfter.IsZero() && entry.NotAfter.Before(f.ExpiresAfter) {
+		return false
+	}
+	if !f.ExpiresBefore.IsZero() && entry.NotAfter.After(f.ExpiresBefore) {
+		return false
+	}
+
+	switch f.Status {
+	case certInventoryStatusValid:
+		return !entry.Revoked && now.Before(entry.NotAfter)
+	case certInventoryStatusExpired:
+		return !now.Before(entry.NotAfter)
+	case certInventoryStatusRevoked:
+		return entry.Revoked
+	}
+
+	return true
+}
+
+// The inventory keeps secondary indexes under certInventoryIndexPrefix, so
+// that searches only read the records of candidate certificates:
+//
+//	index/expiry/<YYYY-MM-DD>/<serial>             by day of NotAfter (UTC)
+//	index/role/<role>/<serial>                     by role, when known
+//	index/issuer/<issuer id>/<serial>              by issuer, when known
+//	index/cn/<label>/.../%certs/<serial>           by reversed common name
+//	index/san/<label>/.../%certs/<serial>          by reversed SANs
+//	index/backfilled/<serial>                      records added by tidy
+//
+// Names are lower cased and split on dots, most significant label first, so
+// "www.example.com" is indexed under "com/example/www/". Labels are path
+// escaped; '%' never appears unescaped in them, so the "%certs" marker and
+// "%" for an empty label can't collide with a name.
+const (
+	certInventoryIndexPrefix     = certInventoryPrefix + "index/"
+	certInventoryExpiryIndex     = certInventoryIndexPrefix + "expiry/"
+	certInventoryRoleIndex       = certInventoryIndexPrefix + "role/"
+	certInventoryIssuerIndex     = certInventoryIndexPrefix + "issuer/"
+	certInventoryCNIndex         = certInventoryIndexPrefix + "cn/"
+	certInventorySANIndex        = certInventoryIndexPrefix + "san/"
+	certInventoryBackfilledIndex = certInventoryIndexPrefix + "backfilled/"
+	certInventoryNameCerts       = "%certs/"
+	certInventoryExpiryFormat    = "2006-01-02"
+)
+
+// certInventoryNamePath returns the index path of a name relative to the
+// cn/ or san/ index, with a trailing slash.
+func certInventoryNamePath(name string) string {
+	labels := strings.Split(strings.ToLower(name), ".")
+	var path strings.Builder
+	for i := len(labels) - 1; i >= 0; i-- {
+		label := url.PathEscape(labels[i])
+		if label == "" {
+			label = "%"
+		}
+		path.WriteString(label)
+		path.WriteString("/")
+	}
+	return path.String()
+}
+
+// certInventoryPatternPath returns the index path, relative to the cn/ or
+// san/ index, under which every name matching the glob pattern is found,
+// and whether the pattern has no wildcard and so only matches names indexed
+// at exactly that path. ok is false when the pattern starts with a wildcard
+// label, such that no part of the index can be excluded.
+func certInventoryPatternPath(pattern string) (path string, literal bool, ok bool) {
+	labels := strings.Split(strings.ToLower(pattern), ".")
+	var builder strings.Builder
+	literal = true
+	for i := len(labels) - 1; i >= 0; i-- {
+		if strings.Contains(labels[i], "*") {
+			literal = false
+			break
+		}
+		label := url.PathEscape(labels[i])
+		if label == "" {
+			label = "%"
+		}
+		builder.WriteString(label)
+		builder.WriteString("/")
+		ok = true
+	}
+	return builder.String(), literal, ok
+}
+
+func (e *certInventoryEntry) indexKeys() []string {
+	serial := normalizeSerial(e.SerialNumber)
+	keys := []string{
+		certInventoryExpiryIndex + e.NotAfter.UTC().Format(certInventoryExpiryFormat) + "/" + serial,
+	}
+	if e.Role != "" {
+		keys = append(keys, certInventoryRoleIndex+url.PathEscape(e.Role)+"/"+serial)
+	}
+	if e.IssuerID != "" {
+		keys = append(keys, certInventoryIssuerIndex+url.PathEscape(e.IssuerID.String())+"/"+serial)
+	}
+	if e.Backfilled {
+		keys = append(keys, certInventoryBackfilledIndex+serial)
+	}
+	if e.CommonName != "" {
+		keys = append(keys, certInventoryCNIndex+certInventoryNamePath(e.CommonName)+certInventoryNameCerts+serial)
+	}
+
+	seen := make(map[string]struct{})
+	for _, names := range [][]string{e.DNSNames, e.EmailAddresses, e.IPAddresses, e.URISANs} {
+		for _, name := range names {
+			key := certInventorySANIndex + certInventoryNamePath(name) + certInventoryNameCerts + serial
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
+
+// writeCertInventoryEntry stores the inventory record of a certificate and
+// its index keys, removing index keys of a previous record which no longer
+// apply.
+func (sc *storageContext) writeCertInventoryEntry(entry *certInventoryEntry) error {
+	previous, err := sc.fetchCertInventoryEntry(entry.SerialNumber)
+	if err != nil {
+		return err
+	}
+
+	storageEntry, err := logical.StorageEntryJSON(certInventoryPrefix+normalizeSerial(entry.SerialNumber), entry)
+	if err != nil {
+		return err
+	}
+	if err := sc.Storage.Put(sc.Context, storageEntry); err != nil {
+		return err
+	}
+
+	keys := entry.indexKeys()
+	for _, key := range keys {
+		if err := sc.Storage.Put(sc.Context, &logical.StorageEntry{Key: key}); err != nil {
+			return err
+		}
+	}
+
+	if previous != nil {
+		current := make(map[string]struct{}, len(keys))
+		for _, key := range keys {
+			current[key] = struct{}{}
+		}
+		for _, key := range previous.indexKeys() {
+			if _, ok := current[key]; ok {
+				continue
+			}
+			if err := sc.Storage.Delete(sc.Context, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// deleteCertInventoryEntry removes the inventory record of a certificate
+// along with its index keys.
+func (sc *storageContext) deleteCertInventoryEntry(serial string) error {
+	entry, err := sc.fetchCertInventoryEntry(serial)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		for _, key := range entry.indexKeys() {
+			if err := sc.Storage.Delete(sc.Context, key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return sc.Storage.Delete(sc.Context, certInventoryPrefix+normalizeSerial(serial))
+}
+
+// fetchCertInventoryEntry returns the inventory record of the certificate
+// with the given serial, or nil if it has none.
+func (sc *storageContext) fetchCertInventoryEntry(serial string) (*certInventoryEntry, error) {
+	entry, err := sc.Storage.Get(sc.Context, certInventoryPrefix+normalizeSerial(serial))
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var result certInventoryEntry
+	if err := entry.DecodeJSON(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// backfillCertInventoryEntry adds a certificate stored without an inventory
+// record, such as one issued before the inventory existed, to the inventory.
+// The role it was issued through is unknown; its issuer is identified among
+// the given issuers when possible.
+func (sc *storageContext) backfillCertInventoryEntry(cert *x509.Certificate, issuerIDCertMap map[issuerID]*x509.Certificate) error {
+	existing, err := sc.fetchCertInventoryEntry(serialFromCert(cert))
+	if err != nil || existing != nil {
+		return err
+	}
+
+	var issuerId issuerID
+	for id, issuerCert := range issuerIDCertMap {
+		if id == legacyBundleShimID || !bytes.Equal(cert.RawIssuer, issuerCert.RawSubject) {
+			continue
+		}
+		if err := cert.CheckSignatureFrom(issuerCert); err == nil {
+			issuerId = id
+			break
+		}
+	}
+
+	entry := newCertInventoryEntry(cert, "", issuerId)
+	entry.Backfilled = true
+	return sc.writeCertInventoryEntry(entry)
+}
+
+// listCertInventoryIndex returns the serials indexed directly under the
+// given index path.
+func (sc *storageContext) listCertInventoryIndex(path string, into map[string]struct{}) error {
+	serials, err := sc.Storage.List(sc.Context, path)
+	if err != nil {
+		return fmt.Errorf("error listing certificate inventory index %q: %w", path, err)
+	}
+	for _, serial := range serials {
+		if !strings.HasSuffix(serial, "/") {
+			into[serial] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// listCertInventoryNames returns the serials of certificates with a name
+// indexed at the given name path or, if subtree is set, below it.
+func (sc *storageContext) listCertInventoryNames(path string, subtree bool, into map[string]struct{}) error {
+	if !subtree {
+		return sc.listCertInventoryIndex(path+certInventoryNameCerts, into)
+	}
+
+	children, err := sc.Storage.List(sc.Context, path)
+	if err != nil {
+		return fmt.Errorf("error listing certificate inventory index %q: %w", path, err)
+	}
+	for _, child := range children {
+		if err := sc.Context.Err(); err != nil {
+			return err
+		}
+		switch {
+		case child == certInventoryNameCerts:
+			err = sc.listCertInventoryIndex(path+child, into)
+		case strings.HasSuffix(child, "/"):
+			err = sc.listCertInventoryNames(path+child, true, into)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listCertInventoryExpiry returns the serials of certificates expiring on
+// the days between after and before, inclusive; zero values are unbounded.
+func (sc *storageContext) listCertInventoryExpiry(after, before time.Time, into map[string]struct{}) error {
+	days, err := sc.Storage.List(sc.Context, certInventoryExpiryIndex)
+	if err != nil {
+		return fmt.Errorf("error listing certificate inventory expiry index: %w", err)
+	}
+	for _, day := range days {
+		date := strings.TrimSuffix(day, "/")
+		if !after.IsZero() && date < after.UTC().Format(certInventoryExpiryFormat) {
+			continue
+		}
+		if !before.IsZero() && date > before.UTC().Format(certInventoryExpiryFormat) {
+			continue
+		}
+		if err := sc.listCertInventoryIndex(certInventoryExpiryIndex+day, into); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// certInventoryCandidates returns the serials of the certificates which may
+// match the filter, as found through the indexes. When no index applies to
+// the filter, every certificate in the inventory is a candidate.
+func (sc *storageContext) certInventoryCandidates(filter *certInventoryFilter, revoked map[string]string, now time.Time) (map[string]struct{}, error) {
+	var candidates map[string]struct{}
+	intersect := func(found map[string]struct{}) {
+		if candidates == nil {
+			candidates = found
+			return
+		}
+		for serial := range candidates {
+			if _, ok := found[serial]; !ok {
+				delete(candidates, serial)
+			}
+		}
+	}
+
+	if filter.Role != "" {
+		found := make(map[string]struct{})
+		if err := sc.listCertInventoryIndex(certInventoryRoleIndex+url.PathEscape(filter.Role)+"/", found); err != nil {
+			return nil, err
+		}
+		intersect(found)
+	}
+
+	if filter.IssuerID != "" {
+		found := make(map[string]struct{})
+		if err := sc.listCertInventoryIndex(certInventoryIssuerIndex+url.PathEscape(filter.IssuerID.String())+"/", found); err != nil {
+			return nil, err
+		}
+		intersect(found)
+	}
+
+	for _, name := range []struct {
+		pattern string
+		index   string
+	}{
+		{filter.CommonName, certInventoryCNIndex},
+		{filter.SAN, certInventorySANIndex},
+	} {
+		if name.pattern == "" {
+			continue
+		}
+		path, literal, ok := certInventoryPatternPath(name.pattern)
+		if !ok {
+			continue
+		}
+		found := make(map[string]struct{})
+		if err := sc.listCertInventoryNames(name.index+path, !literal, found); err != nil {
+			return nil, err
+		}
+		intersect(found)
+	}
+
+	expiresAfter, expiresBefore := filter.ExpiresAfter, filter.ExpiresBefore
+	switch filter.Status {
+	case certInventoryStatusValid:
+		if expiresAfter.IsZero() || expiresAfter.Before(now) {
+			expiresAfter = now
+		}
+	case certInventoryStatusExpired:
+		if expiresBefore.IsZero() || expiresBefore.After(now) {
+			expiresBefore = now
+		}
+	case certInventoryStatusRevoked:
+		found := make(map[string]struct{}, len(revoked))
+		for serial := range revoked {
+			found[serial] = struct{}{}
+		}
+		intersect(found)
+	}
+	if !expiresAfter.IsZero() || !expiresBefore.IsZero() {
+		found := make(map[string]struct{})
+		if err := sc.listCertInventoryExpiry(expiresAfter, expiresBefore, found); err != nil {
+			return nil, err
+		}
+		intersect(found)
+	}
+
+	if candidates == nil {
+		candidates = make(map[string]struct{})
+		if err := sc.listCertInventoryIndex(certInventoryPrefix, candidates); err != nil {
+			return nil, err
+		}
+	}
+
+	return candidates, nil
+}
+
+// searchCertInventory returns the inventory records of all stored
+// certificates matching the filter, sorted by serial number. Candidates are
+// found through the inventory's indexes, and their records then checked
+// against the whole filter.
+func (sc *storageContext) searchCertInventory(filter *certInventoryFilter) ([]*certInventoryEntry, error) {
+	revokedSerials, err := sc.Storage.List(sc.Context, revokedPath)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching list of revoked certs: %w", err)
+	}
+	// Map normalized serials to their storage keys, as entries written by
+	// older versions use colon-separated serials.
+	revoked := make(map[string]string, len(revokedSerials))
+	for _, serial := range revokedSerials {
+		revoked[normalizeSerial(serial)] = revokedPath + serial
+	}
+
+	now := time.Now()
+	candidates, err := sc.certInventoryCandidates(filter, revoked, now)
+	if err != nil {
+		return nil, err
+	}
+	serials := make([]string, 0, len(candidates))
+	for serial := range candidates {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+
+	var results []*certInventoryEntry
+	for _, serial := range serials {
+		if err := sc.Context.Err(); err != nil {
+			return nil, err
+		}
+
+		entry, err := sc.fetchCertInventoryEntry(serial)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		if revokedKey, ok := revoked[normalizeSerial(serial)]; ok {
+			entry.Revoked = true
+			revokedEntry, err := sc.Storage.Get(sc.Context, revokedKey)
+			if err != nil {
+				return nil, err
+			}
+			if revokedEntry != nil {
+				var info revocationInfo
+				if err := revokedEntry.DecodeJSON(&info); err != nil {
+					return nil, fmt.Errorf("error decoding revocation entry for serial %q: %w", serial, err)
+				}
+				entry.RevocationTime = time.Unix(info.RevocationTime, 0)
+			}
+		}
+
+		if filter.matches(entry, now) {
+			results = append(results, entry)
+		}
+	}
+
+	return results, nil
+}
+
+// countBackfilledCertInventoryEntries returns the number of inventory records
+// added by tidy, which have no role recorded.
+func (sc *storageContext) countBackfilledCertInventoryEntries() (int, error) {
+	serials, err := sc.Storage.List(sc.Context, certInventoryBackfilledIndex)
+	if err != nil {
+		return 0, fmt.Errorf("error listing backfilled certificate inventory entries: %w", err)
+	}
+	return len(serials), nil
+}
@@ -15,6 +15,12 @@ type backend struct {
 	view      logical.Storage
 	salt      *salt.Salt
 	saltMutex sync.RWMutex
+
+	// revokeLock serializes updates to the revocation list
+	revokeLock sync.Mutex
+
+	// issuersLock serializes updates to the issuers and their configuration
+	issuersLock sync.Mutex
 }
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -38,6 +44,9 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			Unauthenticated: []string{
 				"verify",
 				"public_key",
+				"krl",
+				"public_keys",
+				"issuer/+/public_key",
 			},
 
 			LocalStorage: []string{
@@ -48,6 +57,7 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 				caPrivateKey,
 				caPrivateKeyStoragePath,
 				"keys/",
+				issuersStoragePath,
 			},
 		},
 
@@ -63,6 +73,17 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			pathSign(&b),
 			pathIssue(&b),
 			pathFetchPublicKey(&b),
+			pathFetchKRL(&b),
+			pathFetchListCerts(&b),
+			pathFetchCert(&b),
+			pathRevoke(&b),
+			pathListIssuers(&b),
+			pathGenerateIssuer(&b),
+			pathImportIssuer(&b),
+			pathIssuer(&b),
+			pathFetchIssuerPublicKey(&b),
+			pathConfigIssuers(&b),
+			pathFetchPublicKeys(&b),
 		},
 
 		Secrets: []*framework.Secret{
@@ -70,8 +91,9 @@ func Backend(conf *logical.BackendConfig) (*backend, error) {
 			secretOTP(&b),
 		},
 
-		Invalidate:  b.invalidate,
-		BackendType: logical.TypeLogical,
+		InitializeFunc: b.initialize,
+		Invalidate:     b.invalidate,
+		BackendType:    logical.TypeLogical,
 	}
 	return &b, nil
 }
@@ -0,0 +1,371 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/sdk/helper/consts"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	issuersStoragePath       = "issuers/"
+	issuersConfigStoragePath = "config/issuers"
+
+	// defaultRef is the issuer reference which always resolves to the
+	// default issuer of the mount.
+	defaultRef = "default"
+)
+
+var (
+	issuerNameRegex = regexp.MustCompile(`^\w(([\w-.]+)?\w)?$`)
+
+	errIssuerNotFound = errors.New("issuer not found")
+)
+
+// sshIssuer is a named SSH CA key pair which certificates can be signed with.
+type sshIssuer struct {
+	ID         string `json:"id"`
+	Name       string `json:"name"`
+	PublicKey  string `json:"public_key"`
+	PrivateKey string `json:"private_key"`
+}
+
+// issuersConfig holds the mount-wide issuer configuration.
+type issuersConfig struct {
+	DefaultIssuerID string `json:"default"`
+}
+
+// Signer parses the private key of the issuer.
+func (i *sshIssuer) Signer() (ssh.Signer, error) {
+	signer, err := ssh.ParsePrivateKey([]byte(i.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored CA private key: %w", err)
+	}
+	return signer, nil
+}
+
+// SSHPublicKey parses the public key of the issuer.
+func (i *sshIssuer) SSHPublicKey() (ssh.PublicKey, error) {
+	publicKey, err := parsePublicSSHKey(i.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse stored CA public key: %w", err)
+	}
+	return publicKey, nil
+}
+
+func getIssuersConfig(ctx context.Context, s logical.Storage) (*issuersConfig, error) {
+	entry, err := s.Get(ctx, issuersConfigStoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuers configuration: %w", err)
+	}
+
+	config := &issuersConfig{}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func putIssuersConfig(ctx context.Context, s logical.Storage, config *issuersConfig) error {
+	entry, err := logical.StorageEntryJSON(issuersConfigStoragePath, config)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func fetchIssuerByID(ctx context.Context, s logical.Storage, id string) (*sshIssuer, error) {
+	entry, err := s.Get(ctx, issuersStoragePath+id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read issuer %q: %w", id, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var issuer sshIssuer
+	if err := entry.DecodeJSON(&issuer); err != nil {
+		return nil, err
+	}
+	return &issuer, nil
+}
+
+func putIssuer(ctx context.Context, s logical.Storage, issuer *sshIssuer) error {
+	entry, err := logical.StorageEntryJSON(issuersStoragePath+issuer.ID, issuer)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// listIssuers returns all issuers of the mount, sorted by ID.
+func listIssuers(ctx context.Context, s logical.Storage) ([]*sshIssuer, error) {
+	ids, err := s.List(ctx, issuersStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+
+	issuers := make([]*sshIssuer, 0, len(ids))
+	for _, id := range ids {
+		issuer, err := fetchIssuerByID(ctx, s, id)
+		if err != nil {
+			return nil, err
+		}
+		if issuer != nil {
+			issuers = append(issuers, issuer)
+		}
+	}
+	return issuers, nil
+}
+
+// allIssuers returns all issuers of the mount, including the CA configured
+// through config/ca when the mount's storage has not been migrated yet.
+func allIssuers(ctx context.Context, s logical.Storage) ([]*sshIssuer, error) {
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if len(issuers) > 0 {
+		return issuers, nil
+	}
+
+	legacy, err := fetchLegacyIssuer(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil {
+		issuers = append(issuers, legacy)
+	}
+	return issuers, nil
+}
+
+// resolveIssuer returns the issuer referenced by ID, by name, or by the
+// special "default" reference. When no issuer matches, errIssuerNotFound is
+// returned.
+func resolveIssuer(ctx context.Context, s logical.Storage, ref string) (*sshIssuer, error) {
+	if ref == "" || ref == defaultRef {
+		config, err := getIssuersConfig(ctx, s)
+		if err != nil {
+			return nil, err
+		}
+		if config.DefaultIssuerID == "" {
+			// Until the mount's storage is migrated, the CA configured
+			// through config/ca is the default issuer.
+			legacy, err := fetchLegacyIssuer(ctx, s)
+			if err != nil {
+				return nil, err
+			}
+			if legacy == nil {
+				return nil, errIssuerNotFound
+			}
+			return legacy, nil
+		}
+		ref = config.DefaultIssuerID
+	}
+
+	issuer, err := fetchIssuerByID(ctx, s, ref)
+	if err != nil {
+		return nil, err
+	}
+	if issuer != nil {
+		return issuer, nil
+	}
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuer := range issuers {
+		if issuer.Name == ref {
+			return issuer, nil
+		}
+	}
+
+	return nil, errIssuerNotFound
+}
+
+// createIssuer stores a new issuer, making it the default issuer when the
+// mount has none yet. The caller must hold the issuers lock and have
+// validated the name.
+func createIssuer(ctx context.Context, s logical.Storage, name, publicKey, privateKey string) (*sshIssuer, error) {
+	id, err := uuid.GenerateUUID()
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &sshIssuer{
+		ID:         id,
+		Name:       name,
+		PublicKey:  publicKey,
+		PrivateKey: privateKey,
+	}
+	if err := putIssuer(ctx, s, issuer); err != nil {
+		return nil, err
+	}
+
+	config, err := getIssuersConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	if config.DefaultIssuerID == "" {
+		config.DefaultIssuerID = issuer.ID
+		if err := putIssuersConfig(ctx, s, config); err != nil {
+			return nil, err
+		}
+	}
+
+	return issuer, nil
+}
+
+// deleteIssuer removes an issuer, clearing the default issuer if it was the
+// one removed. The caller must hold the issuers lock.
+func deleteIssuer(ctx context.Context, s logical.Storage, id string) error {
+	if err := s.Delete(ctx, issuersStoragePath+id); err != nil {
+		return err
+	}
+
+	config, err := getIssuersConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	if config.DefaultIssuerID == id {
+		config.DefaultIssuerID = ""
+		return putIssuersConfig(ctx, s, config)
+	}
+	return nil
+}
+
+// validateIssuerName checks that a name is usable as an issuer reference and
+// not already taken by an issuer other than the one with the given ID.
+func validateIssuerName(ctx context.Context, s logical.Storage, name, id string) error {
+	if name == "" {
+		return nil
+	}
+	if name == defaultRef {
+		return fmt.Errorf("issuer name %q is reserved", defaultRef)
+	}
+	if !issuerNameRegex.MatchString(name) {
+		return fmt.Errorf("issuer name %q contains invalid characters", name)
+	}
+
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return err
+	}
+	for _, issuer := range issuers {
+		if issuer.ID == name || (issuer.Name == name && issuer.ID != id) {
+			return fmt.Errorf("issuer name %q is already in use", name)
+		}
+	}
+	return nil
+}
+
+// fetchLegacyIssuer returns the CA stored by config/ca prior to the
+// introduction of issuers, if any.
+func fetchLegacyIssuer(ctx context.Context, s logical.Storage) (*sshIssuer, error) {
+	publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	privateKeyEntry, err := caKey(ctx, s, caPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if publicKeyEntry == nil || publicKeyEntry.Key == "" || privateKeyEntry == nil || privateKeyEntry.Key == "" {
+		return nil, nil
+	}
+
+	return &sshIssuer{
+		PublicKey:  publicKeyEntry.Key,
+		PrivateKey: privateKeyEntry.Key,
+	}, nil
+}
+
+func (b *backend) initialize(ctx context.Context, req *logical.InitializationRequest) error {
+	// Only migrate where storage is writable.
+	if b.System().ReplicationState().HasState(consts.ReplicationDRSecondary|consts.ReplicationPerformanceStandby) ||
+		(!b.System().LocalMount() && b.System().ReplicationState().HasState(consts.ReplicationPerformanceSecondary)) {
+		return nil
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
+		b.Logger().Error("error migrating SSH CA to issuers", "error", err)
+		return err
+	}
+	return nil
+}
+
+// migrateLegacyCA moves the CA configured through config/ca prior to the
+// introduction of issuers into an issuer, which becomes the default.
+func migrateLegacyCA(ctx context.Context, s logical.Storage) error {
+	legacy, err := fetchLegacyIssuer(ctx, s)
+	if err != nil {
+		return err
+	}
+	if legacy == nil {
+		return nil
+	}
+
+	issuer, err := createIssuer(ctx, s, "", legacy.PublicKey, legacy.PrivateKey)
+	if err != nil {
+		return err
+	}
+
+	config, err := getIssuersConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	config.DefaultIssuerID = issuer.ID
+	if err := putIssuersConfig(ctx, s, config); err != nil {
+		return err
+	}
+
+	if err := s.Delete(ctx, caPrivateKeyStoragePath); err != nil {
+		return err
+	}
+	return s.Delete(ctx, caPublicKeyStoragePath)
+}
+
+// publicKeyBundle returns the public keys of all issuers, the default issuer
+// first, in authorized_keys format.
+func publicKeyBundle(ctx context.Context, s logical.Storage) (string, error) {
+	issuers, err := listIssuers(ctx, s)
+	if err != nil {
+		return "", err
+	}
+
+	var keys []string
+	defaultIssuer, err := resolveIssuer(ctx, s, defaultRef)
+	switch {
+	case err == nil:
+		keys = append(keys, strings.TrimSpace(defaultIssuer.PublicKey))
+	case !errors.Is(err, errIssuerNotFound):
+		return "", err
+	}
+
+	for _, issuer := range issuers {
+		if defaultIssuer != nil && issuer.ID == defaultIssuer.ID {
+			continue
+		}
+		keys = append(keys, strings.TrimSpace(issuer.PublicKey))
+	}
+
+	if len(keys) == 0 {
+		return "", nil
+	}
+	return strings.Join(keys, "\n") + "\n", nil
+}
@@ -0,0 +1,140 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Constants from the OpenSSH KRL format; see PROTOCOL.krl in the OpenSSH
+// source tree.
+const (
+	krlMagic         uint64 = 0x5353484b524c0a00
+	krlFormatVersion uint32 = 1
+
+	krlSectionCertificates uint8 = 1
+	krlSectionExplicitKey  uint8 = 2
+
+	krlSectionCertSerialList uint8 = 0x20
+	krlSectionCertKeyID      uint8 = 0x23
+)
+
+// krl holds the contents of an OpenSSH Key Revocation List, suitable for use
+// with the sshd RevokedKeys option.
+type krl struct {
+	Version       uint64
+	GeneratedDate time.Time
+	Comment       string
+
+	// CAKeys are the public keys of the CAs the revoked serials and key IDs
+	// were issued by; a certificate section is emitted for each of them.
+	CAKeys  []ssh.PublicKey
+	Serials []uint64
+	KeyIDs  []string
+
+	// Keys are plain public keys to revoke, along with any certificate
+	// issued for them.
+	Keys []ssh.PublicKey
+}
+
+// Marshal encodes the KRL in its binary wire format.
+func (k *krl) Marshal() []byte {
+	var out bytes.Buffer
+	writeUint64(&out, krlMagic)
+	writeUint32(&out, krlFormatVersion)
+	writeUint64(&out, k.Version)
+	writeUint64(&out, uint64(k.GeneratedDate.Unix()))
+	// flags
+	writeUint64(&out, 0)
+	// reserved
+	writeString(&out, nil)
+	writeString(&out, []byte(k.Comment))
+
+	if len(k.Serials) > 0 || len(k.KeyIDs) > 0 {
+		var revoked bytes.Buffer
+		if len(k.Serials) > 0 {
+			serials := make([]uint64, len(k.Serials))
+			copy(serials, k.Serials)
+			sort.Slice(serials, func(i, j int) bool { return serials[i] < serials[j] })
+
+			var section bytes.Buffer
+			for i, serial := range serials {
+				if i > 0 && serial == serials[i-1] {
+					continue
+				}
+				writeUint64(&section, serial)
+			}
+			writeSection(&revoked, krlSectionCertSerialList, section.Bytes())
+		}
+
+		if len(k.KeyIDs) > 0 {
+			keyIDs := make([]string, len(k.KeyIDs))
+			copy(keyIDs, k.KeyIDs)
+			sort.Strings(keyIDs)
+
+			var section bytes.Buffer
+			for i, keyID := range keyIDs {
+				if i > 0 && keyID == keyIDs[i-1] {
+					continue
+				}
+				writeString(&section, []byte(keyID))
+			}
+			writeSection(&revoked, krlSectionCertKeyID, section.Bytes())
+		}
+
+		for _, caKey := range k.CAKeys {
+			var certs bytes.Buffer
+			writeString(&certs, caKey.Marshal())
+			// reserved
+			writeString(&certs, nil)
+			certs.Write(revoked.Bytes())
+
+			writeSection(&out, krlSectionCertificates, certs.Bytes())
+		}
+	}
+
+	if len(k.Keys) > 0 {
+		// OpenSSH requires the key blobs of a section to be sorted.
+		blobs := make([][]byte, 0, len(k.Keys))
+		for _, key := range k.Keys {
+			blobs = append(blobs, key.Marshal())
+		}
+		sort.Slice(blobs, func(i, j int) bool { return bytes.Compare(blobs[i], blobs[j]) < 0 })
+
+		var section bytes.Buffer
+		for i, blob := range blobs {
+			if i > 0 && bytes.Equal(blob, blobs[i-1]) {
+				continue
+			}
+			writeString(&section, blob)
+		}
+		writeSection(&out, krlSectionExplicitKey, section.Bytes())
+	}
+
+	return out.Bytes()
+}
+
+func writeSection(buf *bytes.Buffer, sectionType uint8, data []byte) {
+	buf.WriteByte(sectionType)
+	writeString(buf, data)
+}
+
+func writeString(buf *bytes.Buffer, s []byte) {
+	writeUint32(buf, uint32(len(s)))
+	buf.Write(s)
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	buf.Write(b[:])
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	buf.Write(b[:])
+}
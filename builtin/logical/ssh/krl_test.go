@@ -0,0 +1,257 @@
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// parsedKRL is the decoded form of a KRL, as produced by parseKRL.
+type parsedKRL struct {
+	Version       uint64
+	GeneratedDate uint64
+	Comment       string
+	CAKeys        [][]byte
+	Serials       []uint64
+	KeyIDs        []string
+	Keys          [][]byte
+}
+
+type krlReader struct {
+	buf *bytes.Reader
+}
+
+func (r *krlReader) uint32() (uint32, error) {
+	var v uint32
+	err := binary.Read(r.buf, binary.BigEndian, &v)
+	return v, err
+}
+
+func (r *krlReader) uint64() (uint64, error) {
+	var v uint64
+	err := binary.Read(r.buf, binary.BigEndian, &v)
+	return v, err
+}
+
+func (r *krlReader) string() ([]byte, error) {
+	l, err := r.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if int(l) > r.buf.Len() {
+		return nil, fmt.Errorf("string length %d exceeds remaining data", l)
+	}
+	s := make([]byte, l)
+	_, err = io.ReadFull(r.buf, s)
+	return s, err
+}
+
+func parseKRL(data []byte) (*parsedKRL, error) {
+	r := &krlReader{buf: bytes.NewReader(data)}
+
+	magic, err := r.uint64()
+	if err != nil || magic != krlMagic {
+		return nil, fmt.Errorf("bad magic %x: %v", magic, err)
+	}
+	format, err := r.uint32()
+	if err != nil || format != krlFormatVersion {
+		return nil, fmt.Errorf("bad format version %d: %v", format, err)
+	}
+
+	result := &parsedKRL{}
+	if result.Version, err = r.uint64(); err != nil {
+		return nil, err
+	}
+	if result.GeneratedDate, err = r.uint64(); err != nil {
+		return nil, err
+	}
+	if _, err = r.uint64(); err != nil {
+		return nil, err
+	}
+	if _, err = r.string(); err != nil {
+		return nil, err
+	}
+	comment, err := r.string()
+	if err != nil {
+		return nil, err
+	}
+	result.Comment = string(comment)
+
+	for r.buf.Len() > 0 {
+		sectionType, err := r.buf.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		data, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		section := &krlReader{buf: bytes.NewReader(data)}
+
+		switch sectionType {
+		case krlSectionCertificates:
+			caKey, err := section.string()
+			if err != nil {
+				return nil, err
+			}
+			result.CAKeys = append(result.CAKeys, caKey)
+
+			// Every certificate section lists the same revocations, so
+			// only the first one is collected.
+			collect := len(result.CAKeys) == 1
+			if _, err = section.string(); err != nil {
+				return nil, err
+			}
+			for section.buf.Len() > 0 {
+				subType, err := section.buf.ReadByte()
+				if err != nil {
+					return nil, err
+				}
+				subData, err := section.string()
+				if err != nil {
+					return nil, err
+				}
+				sub := &krlReader{buf: bytes.NewReader(subData)}
+				for sub.buf.Len() > 0 {
+					switch {
+					case !collect:
+						sub.buf.Reset(nil)
+					case subType == krlSectionCertSerialList:
+						serial, err := sub.uint64()
+						if err != nil {
+							return nil, err
+						}
+						result.Serials = append(result.Serials, serial)
+					case subType == krlSectionCertKeyID:
+						keyID, err := sub.string()
+						if err != nil {
+							return nil, err
+						}
+						result.KeyIDs = append(result.KeyIDs, string(keyID))
+					default:
+						return nil, fmt.Errorf("unexpected certificate section type %x", subType)
+					}
+				}
+			}
+		case krlSectionExplicitKey:
+			for section.buf.Len() > 0 {
+				blob, err := section.string()
+				if err != nil {
+					return nil, err
+				}
+				result.Keys = append(result.Keys, blob)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected section type %x", sectionType)
+		}
+	}
+
+	return result, nil
+}
+
+func generateTestSSHKey(t *testing.T) ssh.PublicKey {
+	t.Helper()
+
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestKRL_Marshal(t *testing.T) {
+	caKey := generateTestSSHKey(t)
+	caKey2 := generateTestSSHKey(t)
+	key1 := generateTestSSHKey(t)
+	key2 := generateTestSSHKey(t)
+	now := time.Now()
+
+	tests := map[string]struct {
+		krl      *krl
+		expected *parsedKRL
+	}{
+		"empty": {
+			krl: &krl{
+				Version:       1,
+				GeneratedDate: now,
+			},
+			expected: &parsedKRL{
+				Version:       1,
+				GeneratedDate: uint64(now.Unix()),
+			},
+		},
+		"certificates sorted and deduplicated": {
+			krl: &krl{
+				Version:       5,
+				GeneratedDate: now,
+				Comment:       "test",
+				CAKeys:        []ssh.PublicKey{caKey, caKey2},
+				Serials:       []uint64{30, 10, 20, 10},
+				KeyIDs:        []string{"bob", "alice", "bob"},
+			},
+			expected: &parsedKRL{
+				Version:       5,
+				GeneratedDate: uint64(now.Unix()),
+				Comment:       "test",
+				CAKeys:        [][]byte{caKey.Marshal(), caKey2.Marshal()},
+				Serials:       []uint64{10, 20, 30},
+				KeyIDs:        []string{"alice", "bob"},
+			},
+		},
+		"no certificate section without a CA": {
+			krl: &krl{
+				GeneratedDate: now,
+				Serials:       []uint64{10},
+				KeyIDs:        []string{"alice"},
+			},
+			expected: &parsedKRL{
+				GeneratedDate: uint64(now.Unix()),
+			},
+		},
+		"explicit keys": {
+			krl: &krl{
+				GeneratedDate: now,
+				CAKeys:        []ssh.PublicKey{caKey},
+				Keys:          []ssh.PublicKey{key1, key2, key1},
+			},
+			expected: &parsedKRL{
+				GeneratedDate: uint64(now.Unix()),
+				Keys:          sortedBlobs(key1, key2),
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			actual, err := parseKRL(test.krl.Marshal())
+			if err != nil {
+				t.Fatalf("failed to parse KRL: %v", err)
+			}
+			if !reflect.DeepEqual(actual, test.expected) {
+				t.Fatalf("expected %#v, got %#v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func sortedBlobs(keys ...ssh.PublicKey) [][]byte {
+	var blobs [][]byte
+	for _, key := range keys {
+		blobs = append(blobs, key.Marshal())
+	}
+	sort.Slice(blobs, func(i, j int) bool { return bytes.Compare(blobs[i], blobs[j]) < 0 })
+	return blobs
+}
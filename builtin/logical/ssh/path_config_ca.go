@@ -14,7 +14,6 @@ import (
 	"fmt"
 	"io"
 
-	multierror "github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"golang.org/x/crypto/ssh"
@@ -76,23 +75,25 @@ by this mount. The fields must be in the standard private and public SSH format.
 
 For security reasons, the private key cannot be retrieved later.
 
+The keys are stored as the default issuer of this mount; additional issuers
+can be managed through the issuers endpoints.
+
 Read operations will return the public key, if already stored/generated.`,
 	}
 }
 
 func (b *backend) pathConfigCARead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	issuer, err := resolveIssuer(ctx, req.Storage, defaultRef)
 	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return logical.ErrorResponse("keys haven't been configured yet"), nil
+		}
 		return nil, fmt.Errorf("failed to read CA public key: %w", err)
 	}
 
-	if publicKeyEntry == nil {
-		return logical.ErrorResponse("keys haven't been configured yet"), nil
-	}
-
 	response := &logical.Response{
 		Data: map[string]interface{}{
-			"public_key": publicKeyEntry.Key,
+			"public_key": issuer.PublicKey,
 		},
 	}
 
@@ -100,10 +101,22 @@ func (b *backend) pathConfigCARead(ctx context.Context, req *logical.Request, da
 }
 
 func (b *backend) pathConfigCADelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	if err := req.Storage.Delete(ctx, caPrivateKeyStoragePath); err != nil {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
 		return nil, err
 	}
-	if err := req.Storage.Delete(ctx, caPublicKeyStoragePath); err != nil {
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if config.DefaultIssuerID == "" {
+		return nil, nil
+	}
+
+	if err := deleteIssuer(ctx, req.Storage, config.DefaultIssuerID); err != nil {
 		return nil, err
 	}
 	return nil, nil
@@ -188,14 +201,8 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 			return logical.ErrorResponse("missing private_key"), nil
 		}
 
-		_, err := ssh.ParsePrivateKey([]byte(privateKey))
-		if err != nil {
-			return logical.ErrorResponse(fmt.Sprintf("Unable to parse private_key as an SSH private key: %v", err)), nil
-		}
-
-		_, err = parsePublicSSHKey(publicKey)
-		if err != nil {
-			return logical.ErrorResponse(fmt.Sprintf("Unable to parse public_key as an SSH public key: %v", err)), nil
+		if err := validateCAKeyPair(publicKey, privateKey); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
 		}
 
 	// not set and no public/private key provided so generate
@@ -221,55 +228,23 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 		return nil, fmt.Errorf("failed to generate or parse the keys")
 	}
 
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CA public key: %w", err)
-	}
-
-	privateKeyEntry, err := caKey(ctx, req.Storage, caPrivateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read CA private key: %w", err)
-	}
-
-	if (publicKeyEntry != nil && publicKeyEntry.Key != "") || (privateKeyEntry != nil && privateKeyEntry.Key != "") {
-		return logical.ErrorResponse("keys are already configured; delete them before reconfiguring"), nil
-	}
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
 
-	entry, err := logical.StorageEntryJSON(caPublicKeyStoragePath, &keyStorageEntry{
-		Key: publicKey,
-	})
-	if err != nil {
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
 		return nil, err
 	}
 
-	// Save the public key
-	err = req.Storage.Put(ctx, entry)
+	config, err := getIssuersConfig(ctx, req.Storage)
 	if err != nil {
 		return nil, err
 	}
-
-	entry, err = logical.StorageEntryJSON(caPrivateKeyStoragePath, &keyStorageEntry{
-		Key: privateKey,
-	})
-	if err != nil {
-		return nil, err
+	if config.DefaultIssuerID != "" {
+		return logical.ErrorResponse("keys are already configured; delete them before reconfiguring"), nil
 	}
 
-	// Save the private key
-	err = req.Storage.Put(ctx, entry)
-	if err != nil {
-		var mErr *multierror.Error
-
-		mErr = multierror.Append(mErr, fmt.Errorf("failed to store CA private key: %w", err))
-
-		// If storing private key fails, the corresponding public key should be
-		// removed
-		if delErr := req.Storage.Delete(ctx, caPublicKeyStoragePath); delErr != nil {
-			mErr = multierror.Append(mErr, fmt.Errorf("failed to cleanup CA public key: %w", delErr))
-			return nil, mErr
-		}
-
-		return nil, err
+	if _, err := createIssuer(ctx, req.Storage, "", publicKey, privateKey); err != nil {
+		return nil, fmt.Errorf("failed to store CA key: %w", err)
 	}
 
 	if generateSigningKey {
@@ -285,6 +260,18 @@ func (b *backend) pathConfigCAUpdate(ctx context.Context, req *logical.Request,
 	return nil, nil
 }
 
+func validateCAKeyPair(publicKey, privateKey string) error {
+	if _, err := ssh.ParsePrivateKey([]byte(privateKey)); err != nil {
+		return fmt.Errorf("Unable to parse private_key as an SSH private key: %v", err)
+	}
+
+	if _, err := parsePublicSSHKey(publicKey); err != nil {
+		return fmt.Errorf("Unable to parse public_key as an SSH public key: %v", err)
+	}
+
+	return nil
+}
+
 func generateSSHKeyPair(randomSource io.Reader, keyType string, keyBits int) (string, string, error) {
 	if randomSource == nil {
 		randomSource = rand.Reader
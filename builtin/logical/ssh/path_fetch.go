@@ -2,6 +2,8 @@ package ssh
 
 import (
 	"context"
+	"errors"
+	"strconv"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -16,26 +18,131 @@ func pathFetchPublicKey(b *backend) *framework.Path {
 		},
 
 		HelpSynopsis:    `Retrieve the public key.`,
-		HelpDescription: `This allows the public key, that this backend has been configured with, to be fetched.`,
+		HelpDescription: `This allows the public key of the default issuer of this backend to be fetched.`,
 	}
 }
 
 func (b *backend) pathFetchPublicKey(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	publicKeyEntry, err := caKey(ctx, req.Storage, caPublicKey)
+	issuer, err := resolveIssuer(ctx, req.Storage, defaultRef)
 	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return nil, nil
+		}
 		return nil, err
 	}
-	if publicKeyEntry == nil || publicKeyEntry.Key == "" {
-		return nil, nil
-	}
 
 	response := &logical.Response{
 		Data: map[string]interface{}{
 			logical.HTTPContentType: "text/plain",
-			logical.HTTPRawBody:     []byte(publicKeyEntry.Key),
+			logical.HTTPRawBody:     []byte(issuer.PublicKey),
+			logical.HTTPStatusCode:  200,
+		},
+	}
+
+	return response, nil
+}
+
+func pathFetchKRL(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `krl`,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchKRL,
+		},
+
+		HelpSynopsis: `Retrieve the Key Revocation List.`,
+		HelpDescription: `This allows the revoked certificates and keys of this backend to be fetched
+as a binary OpenSSH Key Revocation List (KRL), for use with the sshd
+RevokedKeys option.`,
+	}
+}
+
+func (b *backend) pathFetchKRL(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	krl, err := buildKRL(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "application/octet-stream",
+			logical.HTTPRawBody:     krl.Marshal(),
 			logical.HTTPStatusCode:  200,
 		},
 	}
 
 	return response, nil
 }
+
+func pathFetchListCerts(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "certs/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathFetchCertList,
+		},
+
+		HelpSynopsis:    `List the serial numbers of certificates signed by this backend.`,
+		HelpDescription: `This lists the serial numbers, in hexadecimal, of the certificates signed by this backend through roles that store them.`,
+	}
+}
+
+func (b *backend) pathFetchCertList(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	entries, err := req.Storage.List(ctx, certsStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	return logical.ListResponse(entries), nil
+}
+
+func pathFetchCert(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `cert/(?P<serial>[0-9A-Fa-f]+)`,
+		Fields: map[string]*framework.FieldSchema{
+			"serial": {
+				Type:        framework.TypeString,
+				Description: `Certificate serial number, in hexadecimal.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchCertRead,
+		},
+
+		HelpSynopsis:    `Retrieve a certificate signed by this backend.`,
+		HelpDescription: `This allows a certificate signed by this backend to be fetched by its serial number, along with its revocation status.`,
+	}
+}
+
+func (b *backend) pathFetchCertRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	serial, err := parseSerialNumber(data.Get("serial").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	cert, err := fetchCertEntry(ctx, req.Storage, strconv.FormatUint(serial, 16))
+	if err != nil {
+		return nil, err
+	}
+	if cert == nil {
+		return nil, nil
+	}
+
+	var revocationTime int64
+	if !cert.RevocationTime.IsZero() {
+		revocationTime = cert.RevocationTime.Unix()
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"serial_number":    cert.SerialNumber,
+			"issuer_id":        cert.IssuerID,
+			"key_id":           cert.KeyID,
+			"cert_type":        cert.CertType,
+			"valid_principals": cert.ValidPrincipals,
+			"signed_key":       cert.SignedKey,
+			"expiration":       cert.Expiration.Unix(),
+			"revocation_time":  revocationTime,
+		},
+	}, nil
+}
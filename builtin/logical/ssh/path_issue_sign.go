@@ -98,17 +98,17 @@ func (b *backend) pathSignIssueCertificateHelper(ctx context.Context, req *logic
 		return logical.ErrorResponse(err.Error()), nil
 	}
 
-	privateKeyEntry, err := caKey(ctx, req.Storage, caPrivateKey)
+	issuer, err := resolveIssuer(ctx, req.Storage, role.IssuerRef)
 	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return logical.ErrorResponse(fmt.Sprintf("issuer %q referenced by role not found", role.IssuerRef)), nil
+		}
 		return nil, fmt.Errorf("failed to read CA private key: %w", err)
 	}
-	if privateKeyEntry == nil || privateKeyEntry.Key == "" {
-		return nil, errors.New("failed to read CA private key")
-	}
 
-	signer, err := ssh.ParsePrivateKey([]byte(privateKeyEntry.Key))
+	signer, err := issuer.Signer()
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse stored CA private key: %w", err)
+		return nil, err
 	}
 
 	cBundle := creationBundle{
@@ -133,10 +133,17 @@ func (b *backend) pathSignIssueCertificateHelper(ctx context.Context, req *logic
 		return nil, errors.New("error marshaling signed certificate")
 	}
 
+	if !role.NoStore {
+		if err := storeCertificate(ctx, req.Storage, issuer.ID, certificate); err != nil {
+			return nil, fmt.Errorf("unable to store certificate locally: %w", err)
+		}
+	}
+
 	response := &logical.Response{
 		Data: map[string]interface{}{
 			"serial_number": strconv.FormatUint(certificate.Serial, 16),
 			"signed_key":    string(signedSSHCertificate),
+			"issuer_id":     issuer.ID,
 		},
 	}
 
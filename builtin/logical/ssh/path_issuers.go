@@ -0,0 +1,406 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func pathListIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/?$",
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ListOperation: b.pathListIssuers,
+		},
+
+		HelpSynopsis:    `List the SSH CA issuers of this mount.`,
+		HelpDescription: `This lists the IDs of the SSH CA issuers of this mount, along with their names and whether they are the default issuer.`,
+	}
+}
+
+func (b *backend) pathListIssuers(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuers, err := listIssuers(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	keyInfo := map[string]interface{}{}
+	for _, issuer := range issuers {
+		keys = append(keys, issuer.ID)
+		keyInfo[issuer.ID] = map[string]interface{}{
+			"issuer_name": issuer.Name,
+			"is_default":  issuer.ID == config.DefaultIssuerID,
+		}
+	}
+
+	return logical.ListResponseWithInfo(keys, keyInfo), nil
+}
+
+func pathGenerateIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/generate",
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_name": {
+				Type:        framework.TypeString,
+				Description: `Optional name of the new issuer, which can be used to reference it in place of its ID.`,
+			},
+			"key_type": {
+				Type:        framework.TypeString,
+				Description: `Specifies the desired key type; could be a OpenSSH key type identifier (ssh-rsa, ecdsa-sha2-nistp256, ecdsa-sha2-nistp384, ecdsa-sha2-nistp521, or ssh-ed25519) or an algorithm (rsa, ec, ed25519).`,
+				Default:     "ssh-rsa",
+			},
+			"key_bits": {
+				Type:        framework.TypeInt,
+				Description: `Specifies the desired key bits for variable-length keys (such as when key_type="ssh-rsa") or which NIST P-curve to use when key_type="ec" (256, 384, or 521).`,
+				Default:     0,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathGenerateIssuer,
+		},
+
+		HelpSynopsis: `Generate a new SSH CA issuer.`,
+		HelpDescription: `This generates a new SSH CA key pair as an issuer of this mount. When the
+mount has no default issuer, the new issuer becomes the default.`,
+	}
+}
+
+func (b *backend) pathGenerateIssuer(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey, privateKey, err := generateSSHKeyPair(b.Backend.GetRandomReader(), data.Get("key_type").(string), data.Get("key_bits").(int))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.storeNewIssuer(ctx, req, data.Get("issuer_name").(string), publicKey, privateKey)
+}
+
+func pathImportIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuers/import",
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_name": {
+				Type:        framework.TypeString,
+				Description: `Optional name of the new issuer, which can be used to reference it in place of its ID.`,
+			},
+			"private_key": {
+				Type:        framework.TypeString,
+				Description: `Private half of the SSH key that will be used to sign certificates.`,
+			},
+			"public_key": {
+				Type:        framework.TypeString,
+				Description: `Public half of the SSH key that will be used to sign certificates.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathImportIssuer,
+		},
+
+		HelpSynopsis: `Import an SSH CA issuer.`,
+		HelpDescription: `This imports an existing SSH CA key pair as an issuer of this mount. When
+the mount has no default issuer, the new issuer becomes the default.
+
+For security reasons, the private key cannot be retrieved later.`,
+	}
+}
+
+func (b *backend) pathImportIssuer(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	publicKey := data.Get("public_key").(string)
+	privateKey := data.Get("private_key").(string)
+
+	if publicKey == "" {
+		return logical.ErrorResponse("missing public_key"), nil
+	}
+	if privateKey == "" {
+		return logical.ErrorResponse("missing private_key"), nil
+	}
+	if err := validateCAKeyPair(publicKey, privateKey); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	return b.storeNewIssuer(ctx, req, data.Get("issuer_name").(string), publicKey, privateKey)
+}
+
+func (b *backend) storeNewIssuer(ctx context.Context, req *logical.Request, name, publicKey, privateKey string) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	if err := validateIssuerName(ctx, req.Storage, name, ""); err != nil {
+		return logical.ErrorResponse(err.Error()), nil
+	}
+
+	issuer, err := createIssuer(ctx, req.Storage, name, publicKey, privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return b.issuerResponse(ctx, req.Storage, issuer)
+}
+
+func pathIssuer(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref"),
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": {
+				Type:        framework.TypeString,
+				Description: `Reference to an issuer, either by ID or name, or "default" for the default issuer.`,
+			},
+			"issuer_name": {
+				Type:        framework.TypeString,
+				Description: `Name of the issuer, which can be used to reference it in place of its ID.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathIssuerRead,
+			logical.UpdateOperation: b.pathIssuerUpdate,
+			logical.DeleteOperation: b.pathIssuerDelete,
+		},
+
+		HelpSynopsis: `Manage an SSH CA issuer.`,
+		HelpDescription: `This allows reading the public key of an issuer, renaming it and deleting it.
+Certificates signed by a deleted issuer are no longer trusted once its public
+key is removed from the SSH servers and clients.`,
+	}
+}
+
+func (b *backend) pathIssuerRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return b.issuerResponse(ctx, req.Storage, issuer)
+}
+
+func (b *backend) pathIssuerUpdate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, err := resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		return nil, err
+	}
+
+	if nameRaw, ok := data.GetOk("issuer_name"); ok {
+		name := nameRaw.(string)
+		if err := validateIssuerName(ctx, req.Storage, name, issuer.ID); err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		issuer.Name = name
+	}
+
+	if err := putIssuer(ctx, req.Storage, issuer); err != nil {
+		return nil, err
+	}
+
+	return b.issuerResponse(ctx, req.Storage, issuer)
+}
+
+func (b *backend) pathIssuerDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, err := resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := deleteIssuer(ctx, req.Storage, issuer.ID); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (b *backend) issuerResponse(ctx context.Context, s logical.Storage, issuer *sshIssuer) (*logical.Response, error) {
+	config, err := getIssuersConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"issuer_id":   issuer.ID,
+			"issuer_name": issuer.Name,
+			"public_key":  issuer.PublicKey,
+			"is_default":  issuer.ID == config.DefaultIssuerID,
+		},
+	}, nil
+}
+
+func pathFetchIssuerPublicKey(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "issuer/" + framework.GenericNameRegex("issuer_ref") + "/public_key",
+		Fields: map[string]*framework.FieldSchema{
+			"issuer_ref": {
+				Type:        framework.TypeString,
+				Description: `Reference to an issuer, either by ID or name, or "default" for the default issuer.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchIssuerPublicKey,
+		},
+
+		HelpSynopsis:    `Retrieve the public key of an issuer.`,
+		HelpDescription: `This allows the public key of an issuer of this backend to be fetched.`,
+	}
+}
+
+func (b *backend) pathFetchIssuerPublicKey(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	issuer, err := resolveIssuer(ctx, req.Storage, data.Get("issuer_ref").(string))
+	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(issuer.PublicKey),
+			logical.HTTPStatusCode:  200,
+		},
+	}
+
+	return response, nil
+}
+
+func pathConfigIssuers(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "config/issuers",
+		Fields: map[string]*framework.FieldSchema{
+			"default": {
+				Type:        framework.TypeString,
+				Description: `Reference (ID or name) to the issuer used to sign certificates for roles without an explicit issuer_ref.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation:   b.pathConfigIssuersRead,
+			logical.UpdateOperation: b.pathConfigIssuersWrite,
+		},
+
+		HelpSynopsis:    `Read and set the default SSH CA issuer.`,
+		HelpDescription: `This allows the default issuer of this mount to be read and changed.`,
+	}
+}
+
+func (b *backend) pathConfigIssuersRead(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default": config.DefaultIssuerID,
+		},
+	}, nil
+}
+
+func (b *backend) pathConfigIssuersWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	ref := data.Get("default").(string)
+	if ref == "" || ref == defaultRef {
+		return logical.ErrorResponse("a reference to an issuer must be provided as the default"), nil
+	}
+
+	b.issuersLock.Lock()
+	defer b.issuersLock.Unlock()
+
+	if err := migrateLegacyCA(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	issuer, err := resolveIssuer(ctx, req.Storage, ref)
+	if err != nil {
+		if errors.Is(err, errIssuerNotFound) {
+			return logical.ErrorResponse(fmt.Sprintf("issuer %q not found", ref)), nil
+		}
+		return nil, err
+	}
+
+	config, err := getIssuersConfig(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	config.DefaultIssuerID = issuer.ID
+	if err := putIssuersConfig(ctx, req.Storage, config); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"default": config.DefaultIssuerID,
+		},
+	}, nil
+}
+
+func pathFetchPublicKeys(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `public_keys`,
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.ReadOperation: b.pathFetchPublicKeys,
+		},
+
+		HelpSynopsis: `Retrieve the public keys of all issuers.`,
+		HelpDescription: `This allows the public keys of all issuers of this backend to be fetched, one
+per line with the default issuer first, suitable for the sshd TrustedUserCAKeys
+file. Serving every issuer allows servers to trust a new CA before it becomes
+the default.`,
+	}
+}
+
+func (b *backend) pathFetchPublicKeys(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	bundle, err := publicKeyBundle(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == "" {
+		return nil, nil
+	}
+
+	response := &logical.Response{
+		Data: map[string]interface{}{
+			logical.HTTPContentType: "text/plain",
+			logical.HTTPRawBody:     []byte(bundle),
+			logical.HTTPStatusCode:  200,
+		},
+	}
+
+	return response, nil
+}
@@ -0,0 +1,279 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_Issuers(t *testing.T) {
+	ctx := context.Background()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(ctx, config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+	}
+	mustRequest := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(operation, path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s %s: err: %v, resp: %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+	mustFail := func(operation logical.Operation, path string, data map[string]interface{}) {
+		t.Helper()
+		resp, err := request(operation, path, data)
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("%s %s: expected error, got resp: %#v, err: %v", operation, path, resp, err)
+		}
+	}
+
+	// The first issuer becomes the default.
+	resp := mustRequest(logical.UpdateOperation, "issuers/generate", map[string]interface{}{
+		"issuer_name": "old",
+		"key_type":    "ed25519",
+	})
+	oldID := resp.Data["issuer_id"].(string)
+	oldKey := resp.Data["public_key"].(string)
+	if !resp.Data["is_default"].(bool) {
+		t.Fatalf("expected first issuer to be the default")
+	}
+
+	resp = mustRequest(logical.UpdateOperation, "issuers/import", map[string]interface{}{
+		"issuer_name": "new",
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	newID := resp.Data["issuer_id"].(string)
+	if resp.Data["is_default"].(bool) {
+		t.Fatalf("expected second issuer to not be the default")
+	}
+
+	// Names must be unique and can't shadow the default reference.
+	mustFail(logical.UpdateOperation, "issuers/generate", map[string]interface{}{"issuer_name": "new"})
+	mustFail(logical.UpdateOperation, "issuers/generate", map[string]interface{}{"issuer_name": "default"})
+	mustFail(logical.UpdateOperation, "issuer/new", map[string]interface{}{"issuer_name": "old"})
+	mustFail(logical.UpdateOperation, "issuers/import", map[string]interface{}{"public_key": testCAPublicKey})
+
+	resp = mustRequest(logical.ListOperation, "issuers/", nil)
+	if len(resp.Data["keys"].([]string)) != 2 {
+		t.Fatalf("expected 2 issuers, got %v", resp.Data["keys"])
+	}
+	info := resp.Data["key_info"].(map[string]interface{})
+	if info[oldID].(map[string]interface{})["issuer_name"] != "old" || !info[oldID].(map[string]interface{})["is_default"].(bool) {
+		t.Fatalf("unexpected key info for %s: %v", oldID, info[oldID])
+	}
+
+	// Issuers resolve by name and by ID.
+	resp = mustRequest(logical.ReadOperation, "issuer/new", nil)
+	if resp.Data["issuer_id"] != newID || resp.Data["public_key"] != testCAPublicKey {
+		t.Fatalf("unexpected issuer: %#v", resp.Data)
+	}
+	resp = mustRequest(logical.ReadOperation, "issuer/"+oldID+"/public_key", nil)
+	if string(resp.Data[logical.HTTPRawBody].([]byte)) != oldKey {
+		t.Fatalf("unexpected public key for %s", oldID)
+	}
+
+	// Roles sign with the default issuer unless configured otherwise.
+	mustFail(logical.UpdateOperation, "roles/missing", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"issuer_ref":              "missing",
+	})
+	mustRequest(logical.UpdateOperation, "roles/default", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+	})
+	mustRequest(logical.UpdateOperation, "roles/pinned", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"issuer_ref":              "new",
+	})
+	resp = mustRequest(logical.ReadOperation, "roles/default", nil)
+	if resp.Data["issuer_ref"] != defaultRef {
+		t.Fatalf("expected issuer_ref %q, got %v", defaultRef, resp.Data["issuer_ref"])
+	}
+
+	signedBy := func(role string) (ssh.PublicKey, string) {
+		t.Helper()
+		resp := mustRequest(logical.UpdateOperation, "sign/"+role, map[string]interface{}{
+			"public_key": testCAPublicKeyEd25519,
+		})
+		key, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key.(*ssh.Certificate).SignatureKey, resp.Data["issuer_id"].(string)
+	}
+	assertSignedBy := func(role, publicKey, issuerID string) {
+		t.Helper()
+		expected, err := parsePublicSSHKey(publicKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, actualID := signedBy(role)
+		if !bytes.Equal(actual.Marshal(), expected.Marshal()) || actualID != issuerID {
+			t.Fatalf("role %s: expected certificate signed by %s, got %s", role, issuerID, actualID)
+		}
+	}
+	assertSignedBy("default", oldKey, oldID)
+	assertSignedBy("pinned", testCAPublicKey, newID)
+
+	// The bundle serves every issuer, the default first.
+	resp = mustRequest(logical.ReadOperation, "public_keys", nil)
+	bundle := string(resp.Data[logical.HTTPRawBody].([]byte))
+	expectedBundle := strings.TrimSpace(oldKey) + "\n" + strings.TrimSpace(testCAPublicKey) + "\n"
+	if bundle != expectedBundle {
+		t.Fatalf("expected bundle %q, got %q", expectedBundle, bundle)
+	}
+
+	// Rotate the default to the new issuer.
+	mustFail(logical.UpdateOperation, "config/issuers", map[string]interface{}{"default": "missing"})
+	mustRequest(logical.UpdateOperation, "config/issuers", map[string]interface{}{"default": "new"})
+	resp = mustRequest(logical.ReadOperation, "config/issuers", nil)
+	if resp.Data["default"] != newID {
+		t.Fatalf("expected default %s, got %v", newID, resp.Data["default"])
+	}
+	assertSignedBy("default", testCAPublicKey, newID)
+
+	resp = mustRequest(logical.ReadOperation, "public_key", nil)
+	if string(resp.Data[logical.HTTPRawBody].([]byte)) != testCAPublicKey {
+		t.Fatalf("expected public_key to serve the new default issuer")
+	}
+	resp = mustRequest(logical.ReadOperation, "public_keys", nil)
+	bundle = string(resp.Data[logical.HTTPRawBody].([]byte))
+	if !strings.HasPrefix(bundle, strings.TrimSpace(testCAPublicKey)+"\n") {
+		t.Fatalf("expected the new default issuer first in the bundle, got %q", bundle)
+	}
+
+	// Revocations apply to every issuer.
+	mustRequest(logical.UpdateOperation, "revoke", map[string]interface{}{"key_id": "compromised"})
+	resp = mustRequest(logical.ReadOperation, "krl", nil)
+	krl, err := parseKRL(resp.Data[logical.HTTPRawBody].([]byte))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(krl.CAKeys) != 2 {
+		t.Fatalf("expected a certificate section per issuer, got %d", len(krl.CAKeys))
+	}
+
+	// Retire the old issuer.
+	mustRequest(logical.DeleteOperation, "issuer/old", nil)
+	resp = mustRequest(logical.ReadOperation, "public_keys", nil)
+	if string(resp.Data[logical.HTTPRawBody].([]byte)) != strings.TrimSpace(testCAPublicKey)+"\n" {
+		t.Fatalf("expected only the new issuer in the bundle")
+	}
+
+	// Deleting the default issuer through config/ca leaves no default.
+	mustRequest(logical.DeleteOperation, "config/ca", nil)
+	resp = mustRequest(logical.ReadOperation, "config/issuers", nil)
+	if resp.Data["default"] != "" {
+		t.Fatalf("expected no default issuer, got %v", resp.Data["default"])
+	}
+	mustFail(logical.UpdateOperation, "sign/default", map[string]interface{}{
+		"public_key": testCAPublicKeyEd25519,
+	})
+}
+
+func TestSSH_IssuersLegacyMigration(t *testing.T) {
+	ctx := context.Background()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Factory(ctx, config)
+	if err != nil {
+		t.Fatalf("Cannot create backend: %s", err)
+	}
+
+	// Store a CA the way config/ca did prior to issuers.
+	for path, key := range map[string]string{
+		caPublicKeyStoragePath:  testCAPublicKey,
+		caPrivateKeyStoragePath: testCAPrivateKey,
+	} {
+		entry, err := logical.StorageEntryJSON(path, &keyStorageEntry{Key: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := config.StorageView.Put(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	readPublicKey := func() string {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.ReadOperation,
+			Path:      "public_key",
+			Storage:   config.StorageView,
+		})
+		if err != nil || resp == nil {
+			t.Fatalf("failed to read public key: resp: %#v, err: %v", resp, err)
+		}
+		return string(resp.Data[logical.HTTPRawBody].([]byte))
+	}
+
+	// Prior to migration, the legacy CA is served as the default issuer.
+	if key := readPublicKey(); key != testCAPublicKey {
+		t.Fatalf("expected legacy public key, got %q", key)
+	}
+
+	if err := b.Initialize(ctx, &logical.InitializationRequest{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+
+	issuers, err := listIssuers(ctx, config.StorageView)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(issuers) != 1 || issuers[0].PublicKey != testCAPublicKey || issuers[0].PrivateKey != testCAPrivateKey {
+		t.Fatalf("expected the legacy CA to be migrated to an issuer, got %#v", issuers)
+	}
+
+	issuersConfig, err := getIssuersConfig(ctx, config.StorageView)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if issuersConfig.DefaultIssuerID != issuers[0].ID {
+		t.Fatalf("expected the migrated issuer to be the default")
+	}
+
+	for _, path := range []string{caPublicKeyStoragePath, caPrivateKeyStoragePath} {
+		entry, err := config.StorageView.Get(ctx, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if entry != nil {
+			t.Fatalf("expected legacy entry %s to be removed", path)
+		}
+	}
+
+	if key := readPublicKey(); key != testCAPublicKey {
+		t.Fatalf("expected migrated public key, got %q", key)
+	}
+
+	// Migrating again is a no-op.
+	if err := b.Initialize(ctx, &logical.InitializationRequest{Storage: config.StorageView}); err != nil {
+		t.Fatal(err)
+	}
+	if issuers, err = listIssuers(ctx, config.StorageView); err != nil || len(issuers) != 1 {
+		t.Fatalf("expected a single issuer after migrating twice, got %d: %v", len(issuers), err)
+	}
+}
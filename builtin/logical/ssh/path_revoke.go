@@ -0,0 +1,417 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	certsStoragePath          = "certs/"
+	revokedSerialsStoragePath = "revoked/serials/"
+	revokedKeyIDsStoragePath  = "revoked/key_ids/"
+	revokedKeysStoragePath    = "revoked/keys/"
+	krlConfigStoragePath      = "config/krl"
+)
+
+// sshCertEntry tracks a certificate signed by this mount, so that it can be
+// looked up and revoked by serial number later.
+type sshCertEntry struct {
+	SerialNumber    string    `json:"serial_number"`
+	IssuerID        string    `json:"issuer_id"`
+	KeyID           string    `json:"key_id"`
+	CertType        string    `json:"cert_type"`
+	ValidPrincipals []string  `json:"valid_principals"`
+	SignedKey       string    `json:"signed_key"`
+	Expiration      time.Time `json:"expiration"`
+	RevocationTime  time.Time `json:"revocation_time"`
+}
+
+// revokedEntry is a single entry in the revocation list. Exactly one of
+// SerialNumber, KeyID or PublicKey is set.
+type revokedEntry struct {
+	SerialNumber   string    `json:"serial_number,omitempty"`
+	KeyID          string    `json:"key_id,omitempty"`
+	PublicKey      string    `json:"public_key,omitempty"`
+	Expiration     time.Time `json:"expiration"`
+	RevocationTime time.Time `json:"revocation_time"`
+}
+
+// krlConfig holds the version of the KRL, which increases every time the
+// revocation list is modified.
+type krlConfig struct {
+	Version uint64 `json:"version"`
+}
+
+func pathRevoke(b *backend) *framework.Path {
+	return &framework.Path{
+		Pattern: "revoke",
+		Fields: map[string]*framework.FieldSchema{
+			"serial_number": {
+				Type:        framework.TypeString,
+				Description: `Serial number of the certificate to revoke, in hexadecimal as returned when it was signed.`,
+			},
+			"key_id": {
+				Type:        framework.TypeString,
+				Description: `Key ID to revoke; all certificates signed by an issuer of this mount with this key ID are revoked.`,
+			},
+			"public_key": {
+				Type: framework.TypeString,
+				Description: `SSH public key or certificate to revoke. A plain public key is revoked
+along with every certificate issued for it; a certificate signed by this
+mount is revoked by its serial number.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathRevokeWrite,
+		},
+
+		HelpSynopsis:    pathRevokeHelpSyn,
+		HelpDescription: pathRevokeHelpDesc,
+	}
+}
+
+func (b *backend) pathRevokeWrite(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
+	serialNumber := data.Get("serial_number").(string)
+	keyID := data.Get("key_id").(string)
+	publicKey := data.Get("public_key").(string)
+
+	if serialNumber == "" && keyID == "" && publicKey == "" {
+		return logical.ErrorResponse("one of 'serial_number', 'key_id' or 'public_key' must be provided"), nil
+	}
+
+	var serials []uint64
+	if serialNumber != "" {
+		serial, err := parseSerialNumber(serialNumber)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), nil
+		}
+		serials = append(serials, serial)
+	}
+
+	var keys []ssh.PublicKey
+	if publicKey != "" {
+		parsedKey, err := parsePublicSSHKey(publicKey)
+		if err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("failed to parse public_key: %v", err)), nil
+		}
+
+		if cert, ok := parsedKey.(*ssh.Certificate); ok {
+			issued, err := issuedByMount(ctx, req.Storage, cert)
+			if err != nil {
+				return nil, err
+			}
+			if !issued {
+				return logical.ErrorResponse("certificate was not signed by an issuer of this mount"), nil
+			}
+			if cert.Serial == 0 {
+				return logical.ErrorResponse("certificate has no serial number and must be revoked by key_id or its public key"), nil
+			}
+			serials = append(serials, cert.Serial)
+		} else {
+			keys = append(keys, parsedKey)
+		}
+	}
+
+	b.revokeLock.Lock()
+	defer b.revokeLock.Unlock()
+
+	now := time.Now()
+
+	for _, serial := range serials {
+		if err := b.revokeSerial(ctx, req.Storage, serial, now); err != nil {
+			return nil, err
+		}
+	}
+
+	if keyID != "" {
+		entry, err := logical.StorageEntryJSON(revokedKeyIDsStoragePath+hashString(keyID), &revokedEntry{
+			KeyID:          keyID,
+			RevocationTime: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, key := range keys {
+		entry, err := logical.StorageEntryJSON(revokedKeysStoragePath+hashString(string(key.Marshal())), &revokedEntry{
+			PublicKey:      strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))),
+			RevocationTime: now,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := req.Storage.Put(ctx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := bumpKRLVersion(ctx, req.Storage); err != nil {
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"revocation_time": now.Unix(),
+		},
+	}, nil
+}
+
+// revokeSerial adds the given serial to the revocation list and, when the
+// certificate was tracked at signing time, marks it as revoked.
+func (b *backend) revokeSerial(ctx context.Context, s logical.Storage, serial uint64, now time.Time) error {
+	serialNumber := strconv.FormatUint(serial, 16)
+
+	cert, err := fetchCertEntry(ctx, s, serialNumber)
+	if err != nil {
+		return err
+	}
+
+	revoked := &revokedEntry{
+		SerialNumber:   serialNumber,
+		RevocationTime: now,
+	}
+
+	if cert != nil {
+		if !cert.RevocationTime.IsZero() {
+			// Already revoked; keep the original revocation time.
+			return nil
+		}
+		cert.RevocationTime = now
+		entry, err := logical.StorageEntryJSON(certsStoragePath+serialNumber, cert)
+		if err != nil {
+			return err
+		}
+		if err := s.Put(ctx, entry); err != nil {
+			return err
+		}
+		revoked.Expiration = cert.Expiration
+	}
+
+	entry, err := logical.StorageEntryJSON(revokedSerialsStoragePath+serialNumber, revoked)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func fetchCertEntry(ctx context.Context, s logical.Storage, serialNumber string) (*sshCertEntry, error) {
+	entry, err := s.Get(ctx, certsStoragePath+serialNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read certificate %q: %w", serialNumber, err)
+	}
+	if entry == nil {
+		return nil, nil
+	}
+
+	var cert sshCertEntry
+	if err := entry.DecodeJSON(&cert); err != nil {
+		return nil, err
+	}
+	return &cert, nil
+}
+
+// storeCertificate tracks a newly signed certificate by its serial number.
+func storeCertificate(ctx context.Context, s logical.Storage, issuerID string, certificate *ssh.Certificate) error {
+	certType := "user"
+	if certificate.CertType == ssh.HostCert {
+		certType = "host"
+	}
+
+	serialNumber := strconv.FormatUint(certificate.Serial, 16)
+	entry, err := logical.StorageEntryJSON(certsStoragePath+serialNumber, &sshCertEntry{
+		SerialNumber:    serialNumber,
+		IssuerID:        issuerID,
+		KeyID:           certificate.KeyId,
+		CertType:        certType,
+		ValidPrincipals: certificate.ValidPrincipals,
+		SignedKey:       strings.TrimSpace(string(ssh.MarshalAuthorizedKey(certificate))),
+		Expiration:      time.Unix(int64(certificate.ValidBefore), 0).UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func bumpKRLVersion(ctx context.Context, s logical.Storage) error {
+	config, err := getKRLConfig(ctx, s)
+	if err != nil {
+		return err
+	}
+	config.Version++
+
+	entry, err := logical.StorageEntryJSON(krlConfigStoragePath, config)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+func getKRLConfig(ctx context.Context, s logical.Storage) (*krlConfig, error) {
+	entry, err := s.Get(ctx, krlConfigStoragePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KRL configuration: %w", err)
+	}
+
+	config := &krlConfig{}
+	if entry == nil {
+		return config, nil
+	}
+	if err := entry.DecodeJSON(config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// buildKRL assembles the KRL from the revocation entries in storage. Serials
+// of certificates that have already expired are left out. As serial numbers
+// are random, revoked serials and key IDs are listed for every issuer.
+func buildKRL(ctx context.Context, s logical.Storage) (*krl, error) {
+	config, err := getKRLConfig(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result := &krl{
+		Version:       config.Version,
+		GeneratedDate: now,
+	}
+
+	issuers, err := allIssuers(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	for _, issuer := range issuers {
+		caKey, err := issuer.SSHPublicKey()
+		if err != nil {
+			return nil, err
+		}
+		result.CAKeys = append(result.CAKeys, caKey)
+	}
+
+	if len(result.CAKeys) > 0 {
+		serials, err := listRevokedEntries(ctx, s, revokedSerialsStoragePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, revoked := range serials {
+			if !revoked.Expiration.IsZero() && revoked.Expiration.Before(now) {
+				continue
+			}
+			serial, err := parseSerialNumber(revoked.SerialNumber)
+			if err != nil {
+				return nil, err
+			}
+			result.Serials = append(result.Serials, serial)
+		}
+
+		keyIDs, err := listRevokedEntries(ctx, s, revokedKeyIDsStoragePath)
+		if err != nil {
+			return nil, err
+		}
+		for _, revoked := range keyIDs {
+			result.KeyIDs = append(result.KeyIDs, revoked.KeyID)
+		}
+	}
+
+	keys, err := listRevokedEntries(ctx, s, revokedKeysStoragePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, revoked := range keys {
+		key, err := parsePublicSSHKey(revoked.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse revoked public key: %w", err)
+		}
+		result.Keys = append(result.Keys, key)
+	}
+
+	return result, nil
+}
+
+// issuedByMount checks whether the certificate was signed by one of the
+// issuers of this mount.
+func issuedByMount(ctx context.Context, s logical.Storage, cert *ssh.Certificate) (bool, error) {
+	issuers, err := allIssuers(ctx, s)
+	if err != nil {
+		return false, err
+	}
+	for _, issuer := range issuers {
+		caKey, err := issuer.SSHPublicKey()
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(cert.SignatureKey.Marshal(), caKey.Marshal()) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func listRevokedEntries(ctx context.Context, s logical.Storage, prefix string) ([]*revokedEntry, error) {
+	names, err := s.List(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*revokedEntry, 0, len(names))
+	for _, name := range names {
+		entry, err := s.Get(ctx, prefix+name)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			continue
+		}
+
+		var revoked revokedEntry
+		if err := entry.DecodeJSON(&revoked); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &revoked)
+	}
+	return entries, nil
+}
+
+func parseSerialNumber(serialNumber string) (uint64, error) {
+	serial, err := strconv.ParseUint(serialNumber, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid serial_number %q: must be a hexadecimal number", serialNumber)
+	}
+	if serial == 0 {
+		return 0, fmt.Errorf("serial_number cannot be zero")
+	}
+	return serial, nil
+}
+
+func hashString(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+const pathRevokeHelpSyn = `
+Revoke an SSH certificate or public key.
+`
+
+const pathRevokeHelpDesc = `
+This path adds a certificate serial number, certificate key ID or public key
+to the revocation list of this mount. The revocation list is published in the
+OpenSSH Key Revocation List (KRL) format on the 'krl' endpoint, which can be
+used with the sshd RevokedKeys option.
+`
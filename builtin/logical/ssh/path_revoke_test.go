@@ -0,0 +1,180 @@
+package ssh
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestSSH_RevokeAndFetchKRL(t *testing.T) {
+	ctx := context.Background()
+
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+
+	b, err := Backend(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Setup(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+
+	request := func(operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: operation,
+			Path:      path,
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s %s: err: %v, resp: %#v", operation, path, err, resp)
+		}
+		return resp
+	}
+
+	fetchKRL := func() *parsedKRL {
+		t.Helper()
+		resp := request(logical.ReadOperation, "krl", nil)
+		if resp.Data[logical.HTTPContentType] != "application/octet-stream" {
+			t.Fatalf("unexpected content type %v", resp.Data[logical.HTTPContentType])
+		}
+		parsed, err := parseKRL(resp.Data[logical.HTTPRawBody].([]byte))
+		if err != nil {
+			t.Fatalf("failed to parse KRL: %v", err)
+		}
+		return parsed
+	}
+
+	request(logical.UpdateOperation, "config/ca", map[string]interface{}{
+		"public_key":  testCAPublicKey,
+		"private_key": testCAPrivateKey,
+	})
+	caPub, err := parsePublicSSHKey(testCAPublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request(logical.UpdateOperation, "roles/stored", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"allow_user_key_ids":      true,
+	})
+	request(logical.UpdateOperation, "roles/unstored", map[string]interface{}{
+		"key_type":                "ca",
+		"allow_user_certificates": true,
+		"no_store":                true,
+	})
+
+	// An empty KRL is still valid.
+	krl := fetchKRL()
+	if krl.Version != 0 || len(krl.Serials) != 0 || len(krl.KeyIDs) != 0 || len(krl.Keys) != 0 {
+		t.Fatalf("expected empty KRL, got %#v", krl)
+	}
+
+	sign := func(role string, data map[string]interface{}) *ssh.Certificate {
+		t.Helper()
+		data["public_key"] = testCAPublicKeyEd25519
+		resp := request(logical.UpdateOperation, "sign/"+role, data)
+		key, err := parsePublicSSHKey(resp.Data["signed_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key.(*ssh.Certificate)
+	}
+
+	stored := sign("stored", map[string]interface{}{"key_id": "stored-key-id"})
+	storedSerial := strconv.FormatUint(stored.Serial, 16)
+	unstored := sign("unstored", map[string]interface{}{})
+	unstoredSerial := strconv.FormatUint(unstored.Serial, 16)
+
+	// Only certificates of roles that store them are tracked.
+	resp := request(logical.ListOperation, "certs/", nil)
+	if !reflect.DeepEqual(resp.Data["keys"], []string{storedSerial}) {
+		t.Fatalf("expected only %s to be stored, got %v", storedSerial, resp.Data["keys"])
+	}
+
+	resp = request(logical.ReadOperation, "cert/"+storedSerial, nil)
+	if resp.Data["key_id"] != "stored-key-id" || resp.Data["cert_type"] != "user" {
+		t.Fatalf("unexpected certificate entry: %#v", resp.Data)
+	}
+	if resp.Data["revocation_time"].(int64) != 0 {
+		t.Fatalf("expected certificate to not be revoked")
+	}
+	if resp.Data["expiration"].(int64) != int64(stored.ValidBefore) {
+		t.Fatalf("expected expiration %d, got %v", stored.ValidBefore, resp.Data["expiration"])
+	}
+
+	// Revoke the stored certificate by serial, the other one by presenting
+	// it, and additionally a key ID and a plain public key.
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": storedSerial,
+	})
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"public_key": string(ssh.MarshalAuthorizedKey(unstored)),
+	})
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"key_id":     "compromised",
+		"public_key": testPublicKeyInstall,
+	})
+
+	resp = request(logical.ReadOperation, "cert/"+storedSerial, nil)
+	if resp.Data["revocation_time"].(int64) == 0 {
+		t.Fatalf("expected certificate to be revoked")
+	}
+
+	krl = fetchKRL()
+	if krl.Version != 3 {
+		t.Fatalf("expected KRL version 3, got %d", krl.Version)
+	}
+	if !reflect.DeepEqual(krl.CAKeys, [][]byte{caPub.Marshal()}) {
+		t.Fatalf("unexpected CA key in KRL")
+	}
+	expectedSerials := []uint64{stored.Serial, unstored.Serial}
+	sort.Slice(expectedSerials, func(i, j int) bool { return expectedSerials[i] < expectedSerials[j] })
+	if !reflect.DeepEqual(krl.Serials, expectedSerials) {
+		t.Fatalf("expected serials %v, got %v", expectedSerials, krl.Serials)
+	}
+	if !reflect.DeepEqual(krl.KeyIDs, []string{"compromised"}) {
+		t.Fatalf("expected key IDs [compromised], got %v", krl.KeyIDs)
+	}
+	revokedKey, err := parsePublicSSHKey(testPublicKeyInstall)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(krl.Keys, [][]byte{revokedKey.Marshal()}) {
+		t.Fatalf("unexpected revoked keys in KRL")
+	}
+
+	// Revoking an already revoked serial keeps it listed once.
+	request(logical.UpdateOperation, "revoke", map[string]interface{}{
+		"serial_number": unstoredSerial,
+	})
+	if krl = fetchKRL(); len(krl.Serials) != 2 {
+		t.Fatalf("expected 2 revoked serials, got %v", krl.Serials)
+	}
+
+	// Invalid requests
+	for _, data := range []map[string]interface{}{
+		{},
+		{"serial_number": "0"},
+		{"serial_number": "not-hex"},
+		{"public_key": "invalid"},
+	} {
+		resp, err := b.HandleRequest(ctx, &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "revoke",
+			Storage:   config.StorageView,
+			Data:      data,
+		})
+		if err != nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected error revoking %v, got resp: %#v, err: %v", data, resp, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package ssh
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -68,6 +69,8 @@ type sshRole struct {
 	AlgorithmSigner            string            `mapstructure:"algorithm_signer" json:"algorithm_signer"`
 	Version                    int               `mapstructure:"role_version" json:"role_version"`
 	NotBeforeDuration          time.Duration     `mapstructure:"not_before_duration" json:"not_before_duration"`
+	NoStore                    bool              `mapstructure:"no_store" json:"no_store"`
+	IssuerRef                  string            `mapstructure:"issuer_ref" json:"issuer_ref"`
 }
 
 func pathListRoles(b *backend) *framework.Path {
@@ -394,6 +397,30 @@ func pathRoles(b *backend) *framework.Path {
 					Value: 30,
 				},
 			},
+			"no_store": {
+				Type: framework.TypeBool,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				If set, certificates signed against this role will not be stored in the
+				storage backend. Such certificates can still be revoked by serial number,
+				key ID or public key, but cannot be listed or read.
+				`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Do not store certificates",
+				},
+			},
+			"issuer_ref": {
+				Type:    framework.TypeString,
+				Default: defaultRef,
+				Description: `
+				[Not applicable for Dynamic type] [Not applicable for OTP type] [Optional for CA type]
+				Reference to the issuer, by ID or name, used to sign certificates against this role.
+				Defaults to "default", the default issuer of the mount.
+				`,
+				DisplayAttrs: &framework.DisplayAttributes{
+					Name: "Issuer",
+				},
+			},
 		},
 
 		Callbacks: map[logical.Operation]framework.OperationFunc{
@@ -550,6 +577,17 @@ func (b *backend) pathRoleWrite(ctx context.Context, req *logical.Request, d *fr
 		if errorResponse != nil {
 			return errorResponse, nil
 		}
+
+		// The default issuer may legitimately not exist yet, but an
+		// explicitly referenced one must.
+		if role.IssuerRef != defaultRef {
+			if _, err := resolveIssuer(ctx, req.Storage, role.IssuerRef); err != nil {
+				if errors.Is(err, errIssuerNotFound) {
+					return logical.ErrorResponse(fmt.Sprintf("issuer %q not found", role.IssuerRef)), nil
+				}
+				return nil, err
+			}
+		}
 		roleEntry = *role
 	} else {
 		return logical.ErrorResponse("invalid key type"), nil
@@ -589,6 +627,8 @@ func (b *backend) createCARole(allowedUsers, defaultUser, signer string, data *f
 		AlgorithmSigner:           signer,
 		Version:                   roleEntryVersion,
 		NotBeforeDuration:         time.Duration(data.Get("not_before_duration").(int)) * time.Second,
+		NoStore:                   data.Get("no_store").(bool),
+		IssuerRef:                 data.Get("issuer_ref").(string),
 	}
 
 	if !role.AllowUserCertificates && !role.AllowHostCertificates {
@@ -677,17 +717,13 @@ func (b *backend) checkUpgrade(ctx context.Context, s logical.Storage, n string,
 		// signing key type as we want to make ssh-rsa an explicitly notated
 		// algorithm choice.
 		var publicKey ssh.PublicKey
-		publicKeyEntry, err := caKey(ctx, s, caPublicKey)
+		issuer, err := resolveIssuer(ctx, s, result.IssuerRef)
 		if err != nil {
 			b.Logger().Debug(fmt.Sprintf("failed to load public key entry while attempting to migrate: %v", err))
 			goto SKIPVERSION2
 		}
-		if publicKeyEntry == nil || publicKeyEntry.Key == "" {
-			b.Logger().Debug(fmt.Sprintf("got empty public key entry while attempting to migrate"))
-			goto SKIPVERSION2
-		}
 
-		publicKey, err = parsePublicSSHKey(publicKeyEntry.Key)
+		publicKey, err = parsePublicSSHKey(issuer.PublicKey)
 		if err == nil {
 			// Move an empty signing algorithm to an explicit ssh-rsa (SHA-1)
 			// if this key is of type RSA. This isn't a secure default but
@@ -757,6 +793,12 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 			return nil, err
 		}
 
+		// Roles created prior to issuers implicitly use the default one.
+		issuerRef := role.IssuerRef
+		if issuerRef == "" {
+			issuerRef = defaultRef
+		}
+
 		result = map[string]interface{}{
 			"allowed_users":               role.AllowedUsers,
 			"allowed_users_template":      role.AllowedUsersTemplate,
@@ -782,6 +824,8 @@ func (b *backend) parseRole(role *sshRole) (map[string]interface{}, error) {
 			"allowed_user_key_lengths":    role.AllowedUserKeyTypesLengths,
 			"algorithm_signer":            role.AlgorithmSigner,
 			"not_before_duration":         int64(role.NotBeforeDuration.Seconds()),
+			"no_store":                    role.NoStore,
+			"issuer_ref":                  issuerRef,
 		}
 	case KeyTypeDynamic:
 		result = map[string]interface{}{
@@ -3,11 +3,10 @@ package totp
 import (
 	"context"
 	"strings"
-	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
-	cache "github.com/patrickmn/go-cache"
 )
 
 func Factory(ctx context.Context, conf *logical.BackendConfig) (logical.Backend, error) {
@@ -39,7 +38,7 @@ func Backend() *backend {
 		BackendType: logical.TypeLogical,
 	}
 
-	b.usedCodes = cache.New(0, 30*time.Second)
+	b.keyLocks = locksutil.CreateLocks()
 
 	return &b
 }
@@ -47,9 +46,12 @@ func Backend() *backend {
 type backend struct {
 	*framework.Backend
 
-	usedCodes *cache.Cache
+	// keyLocks serialize validations of a key, which advance its counter or
+	// last used time step.
+	keyLocks []*locksutil.LockEntry
 }
 
 const backendHelp = `
-The TOTP backend dynamically generates time-based one-time use passwords.
+The TOTP backend dynamically generates time-based and counter-based one-time
+use passwords.
 `
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/mitchellh/mapstructure"
 	otplib "github.com/pquerna/otp"
+	hotplib "github.com/pquerna/otp/hotp"
 	totplib "github.com/pquerna/otp/totp"
 )
 
@@ -335,7 +336,7 @@ func TestBackend_keyCrudDefaultValues(t *testing.T) {
 			testAccStepCreateKey(t, "test", keyData, false),
 			testAccStepReadKey(t, "test", expected),
 			testAccStepValidateCode(t, "test", code, true, false),
-			// Next step should fail because it should be in the used cache
+			// Next step should fail because the code has already been used
 			testAccStepValidateCode(t, "test", code, false, true),
 			testAccStepValidateCode(t, "test", invalidCode, false, false),
 			testAccStepDeleteKey(t, "test"),
@@ -1034,6 +1035,189 @@ func TestBackend_generatedKeyExportedFalse(t *testing.T) {
 	})
 }
 
+func TestBackend_totpReplayProtection(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	keyData := map[string]interface{}{
+		"key":      key,
+		"generate": false,
+	}
+
+	previousCode, _ := totplib.GenerateCodeCustom(key, time.Now().Add(-30*time.Second), totplib.ValidateOpts{
+		Period:    30,
+		Digits:    otplib.DigitsSix,
+		Algorithm: otplib.AlgorithmSHA1,
+	})
+	code, _ := generateCode(key, 30, otplib.DigitsSix, otplib.AlgorithmSHA1)
+
+	if resp := testHandleRequest(t, b, config.StorageView, logical.UpdateOperation, "keys/test", keyData); resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := testHandleRequest(t, b, config.StorageView, logical.UpdateOperation, "code/test", map[string]interface{}{"code": code}); resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code to be valid: %#v", resp)
+	}
+
+	// Used codes are tracked in storage, so they are rejected by a backend
+	// sharing the storage as well, while the unused code of the previous
+	// period within the skew is still accepted once
+	b2, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := testHandleRequest(t, b2, config.StorageView, logical.UpdateOperation, "code/test", map[string]interface{}{"code": code}); !resp.IsError() {
+		t.Fatalf("expected used code to be rejected: %#v", resp)
+	}
+	if resp := testHandleRequest(t, b2, config.StorageView, logical.UpdateOperation, "code/test", map[string]interface{}{"code": previousCode}); resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code of the previous period to be valid: %#v", resp)
+	}
+	if resp := testHandleRequest(t, b2, config.StorageView, logical.UpdateOperation, "code/test", map[string]interface{}{"code": previousCode}); !resp.IsError() {
+		t.Fatalf("expected used code to be rejected: %#v", resp)
+	}
+
+	// Recreating the key resets its validation state
+	if resp := testHandleRequest(t, b2, config.StorageView, logical.UpdateOperation, "keys/test", keyData); resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+	if resp := testHandleRequest(t, b2, config.StorageView, logical.UpdateOperation, "code/test", map[string]interface{}{"code": code}); resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code to be valid: %#v", resp)
+	}
+}
+
+func TestBackend_hotpKey(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	keyData := map[string]interface{}{
+		"type":       "hotp",
+		"key":        key,
+		"generate":   false,
+		"counter":    5,
+		"look_ahead": 3,
+	}
+
+	hotpCode := func(counter uint64) string {
+		code, err := hotplib.GenerateCode(key, counter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	logicaltest.Test(t, logicaltest.TestCase{
+		LogicalBackend: b,
+		Steps: []logicaltest.TestStep{
+			testAccStepCreateKey(t, "test", keyData, false),
+			testAccStepReadHOTPKey(t, "test", 5, 3),
+			// Generating codes advances the counter
+			testAccStepReadHOTPCode(t, "test", hotpCode(5)),
+			testAccStepReadHOTPCode(t, "test", hotpCode(6)),
+			testAccStepReadHOTPKey(t, "test", 7, 3),
+			// Codes prior to the counter are not valid
+			testAccStepValidateCode(t, "test", hotpCode(6), false, false),
+			testAccStepValidateCode(t, "test", hotpCode(7), true, false),
+			testAccStepValidateCode(t, "test", hotpCode(7), false, false),
+			testAccStepReadHOTPKey(t, "test", 8, 3),
+			// Codes within the look-ahead window resynchronize the counter
+			testAccStepValidateCode(t, "test", hotpCode(11), true, false),
+			testAccStepReadHOTPKey(t, "test", 12, 3),
+			testAccStepValidateCode(t, "test", hotpCode(10), false, false),
+			// Codes past the look-ahead window are not valid
+			testAccStepValidateCode(t, "test", hotpCode(16), false, false),
+			testAccStepReadHOTPKey(t, "test", 12, 3),
+			testAccStepDeleteKey(t, "test"),
+			testAccStepReadKey(t, "test", nil),
+		},
+	})
+}
+
+func TestBackend_hotpGeneratedKey(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp := testHandleRequest(t, b, config.StorageView, logical.UpdateOperation, "keys/test", map[string]interface{}{
+		"type":         "hotp",
+		"generate":     true,
+		"issuer":       "Vault",
+		"account_name": "Test",
+		"counter":      2,
+	})
+	if resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	keyURL, err := url.Parse(resp.Data["url"].(string))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keyURL.Host != "hotp" || keyURL.Query().Get("counter") != "2" {
+		t.Fatalf("unexpected url for a HOTP key: %s", keyURL)
+	}
+
+	// The url configures an equivalent key
+	resp = testHandleRequest(t, b, config.StorageView, logical.UpdateOperation, "keys/imported", map[string]interface{}{
+		"url": keyURL.String(),
+	})
+	if resp != nil && resp.IsError() {
+		t.Fatalf("bad: %#v", resp)
+	}
+
+	resp = testHandleRequest(t, b, config.StorageView, logical.ReadOperation, "keys/imported", nil)
+	if resp.Data["type"] != "hotp" || resp.Data["counter"] != uint64(2) {
+		t.Fatalf("unexpected imported key: %#v", resp.Data)
+	}
+
+	code, err := hotplib.GenerateCode(keyURL.Query().Get("secret"), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp = testHandleRequest(t, b, config.StorageView, logical.UpdateOperation, "code/imported", map[string]interface{}{"code": code})
+	if resp.IsError() || !resp.Data["valid"].(bool) {
+		t.Fatalf("expected code to be valid: %#v", resp)
+	}
+}
+
+func TestBackend_createKeyInvalidType(t *testing.T) {
+	config := logical.TestBackendConfig()
+	config.StorageView = &logical.InmemStorage{}
+	b, err := Factory(context.Background(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, _ := createKey()
+
+	for _, keyData := range []map[string]interface{}{
+		{"type": "motp", "key": key, "generate": false},
+		{"type": "hotp", "key": key, "generate": false, "counter": -1},
+		{"type": "hotp", "key": key, "generate": false, "look_ahead": -1},
+	} {
+		logicaltest.Test(t, logicaltest.TestCase{
+			LogicalBackend: b,
+			Steps: []logicaltest.TestStep{
+				testAccStepCreateKey(t, "test", keyData, true),
+				testAccStepReadKey(t, "test", nil),
+			},
+		})
+	}
+}
+
 func testAccStepCreateKey(t *testing.T, name string, keyData map[string]interface{}, expectFail bool) logicaltest.TestStep {
 	return logicaltest.TestStep{
 		Operation: logical.UpdateOperation,
@@ -1238,3 +1422,52 @@ func testAccStepValidateCode(t *testing.T, name string, code string, valid, expe
 		},
 	}
 }
+
+func testAccStepReadHOTPKey(t *testing.T, name string, counter uint64, lookAhead uint) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.ReadOperation,
+		Path:      "keys/" + name,
+		Check: func(resp *logical.Response) error {
+			if resp == nil {
+				return fmt.Errorf("bad: %#v", resp)
+			}
+
+			switch {
+			case resp.Data["type"] != "hotp":
+				return fmt.Errorf("type should equal: hotp")
+			case resp.Data["counter"] != counter:
+				return fmt.Errorf("counter should equal: %d, got %v", counter, resp.Data["counter"])
+			case resp.Data["look_ahead"] != lookAhead:
+				return fmt.Errorf("look_ahead should equal: %d", lookAhead)
+			}
+			return nil
+		},
+	}
+}
+
+func testAccStepReadHOTPCode(t *testing.T, name string, expected string) logicaltest.TestStep {
+	return logicaltest.TestStep{
+		Operation: logical.ReadOperation,
+		Path:      "code/" + name,
+		Check: func(resp *logical.Response) error {
+			if resp == nil || resp.Data["code"] != expected {
+				return fmt.Errorf("code should equal: %s, got %#v", expected, resp)
+			}
+			return nil
+		},
+	}
+}
+
+func testHandleRequest(t *testing.T, b logical.Backend, s logical.Storage, operation logical.Operation, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+	resp, err := b.HandleRequest(namespace.RootContext(nil), &logical.Request{
+		Path:      path,
+		Operation: operation,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("%s %s: %v", operation, path, err)
+	}
+	return resp
+}
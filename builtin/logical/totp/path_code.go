@@ -2,12 +2,15 @@ package totp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	otplib "github.com/pquerna/otp"
+	hotplib "github.com/pquerna/otp/hotp"
 	totplib "github.com/pquerna/otp/totp"
 )
 
@@ -21,7 +24,7 @@ func pathCode(b *backend) *framework.Path {
 			},
 			"code": {
 				Type:        framework.TypeString,
-				Description: "TOTP code to be validated.",
+				Description: "TOTP or HOTP code to be validated.",
 			},
 		},
 
@@ -38,6 +41,12 @@ func pathCode(b *backend) *framework.Path {
 func (b *backend) pathReadCode(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
 
+	// Generating a HOTP code advances the key's counter, so it must not
+	// interleave with other generations or validations of the same key
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	// Get the key
 	key, err := b.Key(ctx, req.Storage, name)
 	if err != nil {
@@ -47,12 +56,27 @@ func (b *backend) pathReadCode(ctx context.Context, req *logical.Request, data *
 		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
 	}
 
-	// Generate password using totp library
-	totpToken, err := totplib.GenerateCodeCustom(key.Key, time.Now(), totplib.ValidateOpts{
-		Period:    key.Period,
-		Digits:    key.Digits,
-		Algorithm: key.Algorithm,
-	})
+	// Generate password using the otp library for the key's type
+	var token string
+	switch key.keyType() {
+	case keyTypeHOTP:
+		token, err = hotplib.GenerateCodeCustom(key.Key, key.Counter, key.hotpOpts())
+		if err != nil {
+			return nil, err
+		}
+
+		// Each counter value yields a single code
+		key.Counter++
+		if err := b.putKey(ctx, req.Storage, name, key); err != nil {
+			return nil, fmt.Errorf("error updating key state: %w", err)
+		}
+	default:
+		token, err = totplib.GenerateCodeCustom(key.Key, time.Now(), totplib.ValidateOpts{
+			Period:    key.Period,
+			Digits:    key.Digits,
+			Algorithm: key.Algorithm,
+		})
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +84,7 @@ func (b *backend) pathReadCode(ctx context.Context, req *logical.Request, data *
 	// Return the secret
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"code": totpToken,
+			"code": token,
 		},
 	}, nil
 }
@@ -74,6 +98,12 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse("the code value is required"), nil
 	}
 
+	// Validating a code advances the key's state, so validations of the
+	// same key must not interleave
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
 	// Get the key's stored values
 	key, err := b.Key(ctx, req.Storage, name)
 	if err != nil {
@@ -83,31 +113,24 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 		return logical.ErrorResponse(fmt.Sprintf("unknown key: %s", name)), nil
 	}
 
-	usedName := fmt.Sprintf("%s_%s", name, code)
-
-	_, ok := b.usedCodes.Get(usedName)
-	if ok {
+	var valid bool
+	switch key.keyType() {
+	case keyTypeHOTP:
+		valid, err = validateHOTP(code, key)
+	default:
+		valid, err = validateTOTP(code, key, time.Now())
+	}
+	if err == errCodeAlreadyUsed {
 		return logical.ErrorResponse("code already used; wait until the next time period"), nil
 	}
-
-	valid, err := totplib.ValidateCustom(code, key.Key, time.Now(), totplib.ValidateOpts{
-		Period:    key.Period,
-		Skew:      key.Skew,
-		Digits:    key.Digits,
-		Algorithm: key.Algorithm,
-	})
 	if err != nil && err != otplib.ErrValidateInputInvalidLength {
 		return logical.ErrorResponse("an error occurred while validating the code"), err
 	}
 
-	// Take the key skew, add two for behind and in front, and multiple that by
-	// the period to cover the full possibility of the validity of the key
-	err = b.usedCodes.Add(usedName, nil, time.Duration(
-		int64(time.Second)*
-			int64(key.Period)*
-			int64((2+key.Skew))))
-	if err != nil {
-		return nil, fmt.Errorf("error adding code to used cache: %w", err)
+	if valid {
+		if err := b.putKey(ctx, req.Storage, name, key); err != nil {
+			return nil, fmt.Errorf("error updating key state: %w", err)
+		}
 	}
 
 	return &logical.Response{
@@ -117,11 +140,73 @@ func (b *backend) pathValidateCode(ctx context.Context, req *logical.Request, da
 	}, nil
 }
 
+var errCodeAlreadyUsed = errors.New("code already used")
+
+// validateTOTP validates a TOTP token against the time steps within the skew
+// of the given time. On success, the matching step is recorded as used so the
+// token can't be replayed; tokens of other steps within the skew stay valid.
+func validateTOTP(code string, key *keyEntry, now time.Time) (bool, error) {
+	if key.Period == 0 {
+		return false, fmt.Errorf("invalid period")
+	}
+
+	current := uint64(now.Unix()) / uint64(key.Period)
+	first := current - uint64(key.Skew)
+	if uint64(key.Skew) > current {
+		first = 0
+	}
+
+	for step := first; step <= current+uint64(key.Skew); step++ {
+		valid, err := hotplib.ValidateCustom(code, step, key.Key, key.hotpOpts())
+		if err != nil {
+			return false, err
+		}
+		if !valid {
+			continue
+		}
+		usedSteps := make([]uint64, 0, len(key.UsedSteps)+1)
+		for _, used := range key.UsedSteps {
+			if used == step {
+				return false, errCodeAlreadyUsed
+			}
+			// Steps before the skew can't be validated anymore
+			if used >= first {
+				usedSteps = append(usedSteps, used)
+			}
+		}
+		key.UsedSteps = append(usedSteps, step)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// validateHOTP validates a HOTP token against the key's expected counter and
+// the look-ahead window past it. On success, the key's counter is advanced
+// past the matching counter value, resynchronizing it with the token.
+func validateHOTP(code string, key *keyEntry) (bool, error) {
+	for counter := key.Counter; counter <= key.Counter+uint64(key.LookAhead); counter++ {
+		valid, err := hotplib.ValidateCustom(code, counter, key.Key, key.hotpOpts())
+		if err != nil {
+			return false, err
+		}
+		if valid {
+			key.Counter = counter + 1
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
 const pathCodeHelpSyn = `
-Request time-based one-time use password or validate a password for a certain key .
+Request a one-time use password or validate a password for a certain key.
 `
 
 const pathCodeHelpDesc = `
-This path generates and validates time-based one-time use passwords for a certain key. 
-
+This path generates and validates time-based or counter-based one-time use
+passwords for a certain key. A password is accepted at most once: validating
+a TOTP password rejects further passwords of the same time period, and
+validating a HOTP password advances the key's counter past it. Generating a
+HOTP password advances the key's counter as well.
 `
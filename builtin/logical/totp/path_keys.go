@@ -12,11 +12,18 @@ import (
 	"strings"
 
 	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/locksutil"
 	"github.com/hashicorp/vault/sdk/logical"
 	otplib "github.com/pquerna/otp"
+	hotplib "github.com/pquerna/otp/hotp"
 	totplib "github.com/pquerna/otp/totp"
 )
 
+const (
+	keyTypeTOTP = "totp"
+	keyTypeHOTP = "hotp"
+)
+
 func pathListKeys(b *backend) *framework.Path {
 	return &framework.Path{
 		Pattern: "keys/?$",
@@ -39,6 +46,12 @@ func pathKeys(b *backend) *framework.Path {
 				Description: "Name of the key.",
 			},
 
+			"type": {
+				Type:        framework.TypeString,
+				Default:     keyTypeTOTP,
+				Description: `The type of the key. Options include "totp" for time-based and "hotp" for counter-based one-time use passwords.`,
+			},
+
 			"generate": {
 				Type:        framework.TypeBool,
 				Default:     false,
@@ -96,6 +109,18 @@ func pathKeys(b *backend) *framework.Path {
 				Description: `The number of delay periods that are allowed when validating a TOTP token. This value can either be 0 or 1. Only used if generate is true.`,
 			},
 
+			"counter": {
+				Type:        framework.TypeInt,
+				Default:     0,
+				Description: `The initial counter value of a HOTP key. Only used if type is hotp.`,
+			},
+
+			"look_ahead": {
+				Type:        framework.TypeInt,
+				Default:     10,
+				Description: `The number of counter values past the expected one that are accepted when validating a HOTP token, to resynchronize with tokens whose counter advanced without a validation. Only used if type is hotp.`,
+			},
+
 			"qr_size": {
 				Type:        framework.TypeInt,
 				Default:     200,
@@ -137,7 +162,13 @@ func (b *backend) Key(ctx context.Context, s logical.Storage, n string) (*keyEnt
 }
 
 func (b *backend) pathKeyDelete(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
-	err := req.Storage.Delete(ctx, "key/"+data.Get("name").(string))
+	name := data.Get("name").(string)
+
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := req.Storage.Delete(ctx, "key/"+name)
 	if err != nil {
 		return nil, err
 	}
@@ -158,15 +189,25 @@ func (b *backend) pathKeyRead(ctx context.Context, req *logical.Request, data *f
 	algorithm := key.Algorithm.String()
 
 	// Return values of key
-	return &logical.Response{
+	resp := &logical.Response{
 		Data: map[string]interface{}{
+			"type":         key.keyType(),
 			"issuer":       key.Issuer,
 			"account_name": key.AccountName,
-			"period":       key.Period,
 			"algorithm":    algorithm,
 			"digits":       key.Digits,
 		},
-	}, nil
+	}
+
+	switch key.keyType() {
+	case keyTypeHOTP:
+		resp.Data["counter"] = key.Counter
+		resp.Data["look_ahead"] = key.LookAhead
+	default:
+		resp.Data["period"] = key.Period
+	}
+
+	return resp, nil
 }
 
 func (b *backend) pathKeyList(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
@@ -180,6 +221,7 @@ func (b *backend) pathKeyList(ctx context.Context, req *logical.Request, d *fram
 
 func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data *framework.FieldData) (*logical.Response, error) {
 	name := data.Get("name").(string)
+	keyType := data.Get("type").(string)
 	generate := data.Get("generate").(bool)
 	exported := data.Get("exported").(bool)
 	keyString := data.Get("key").(string)
@@ -192,6 +234,8 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 	qrSize := data.Get("qr_size").(int)
 	keySize := data.Get("key_size").(int)
 	inputURL := data.Get("url").(string)
+	counter := data.Get("counter").(int)
+	lookAhead := data.Get("look_ahead").(int)
 
 	if generate {
 		if keyString != "" {
@@ -210,6 +254,11 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 			return logical.ErrorResponse("an error occurred while parsing url string"), err
 		}
 
+		// Read type
+		if urlObject.Host != "" {
+			keyType = urlObject.Host
+		}
+
 		// Set up query object
 		urlQuery := urlObject.Query()
 		path := strings.TrimPrefix(urlObject.Path, "/")
@@ -255,6 +304,16 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 			digits = digitsInt
 		}
 
+		// Read counter
+		counterQuery := urlQuery.Get("counter")
+		if counterQuery != "" {
+			counterInt, err := strconv.Atoi(counterQuery)
+			if err != nil {
+				return logical.ErrorResponse("an error occurred while parsing counter value in url"), err
+			}
+			counter = counterInt
+		}
+
 		// Read algorithm
 		algorithmQuery := urlQuery.Get("algorithm")
 		if algorithmQuery != "" {
@@ -286,6 +345,20 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 	}
 
 	// Enforce input value requirements
+	switch keyType {
+	case keyTypeTOTP, keyTypeHOTP:
+	default:
+		return logical.ErrorResponse("the type value must be totp or hotp"), nil
+	}
+
+	if counter < 0 {
+		return logical.ErrorResponse("the counter value must be greater than or equal to zero"), nil
+	}
+
+	if lookAhead < 0 {
+		return logical.ErrorResponse("the look_ahead value must be greater than or equal to zero"), nil
+	}
+
 	if period <= 0 {
 		return logical.ErrorResponse("the period value must be greater than zero"), nil
 	}
@@ -325,15 +398,29 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		}
 
 		// Generate a new key
-		keyObject, err := totplib.Generate(totplib.GenerateOpts{
-			Issuer:      issuer,
-			AccountName: accountName,
-			Period:      uintPeriod,
-			Digits:      keyDigits,
-			Algorithm:   keyAlgorithm,
-			SecretSize:  uintKeySize,
-			Rand:        b.GetRandomReader(),
-		})
+		var keyObject *otplib.Key
+		var err error
+		switch keyType {
+		case keyTypeHOTP:
+			keyObject, err = generateHOTPKey(hotplib.GenerateOpts{
+				Issuer:      issuer,
+				AccountName: accountName,
+				Digits:      keyDigits,
+				Algorithm:   keyAlgorithm,
+				SecretSize:  uintKeySize,
+				Rand:        b.GetRandomReader(),
+			}, uint64(counter))
+		default:
+			keyObject, err = totplib.Generate(totplib.GenerateOpts{
+				Issuer:      issuer,
+				AccountName: accountName,
+				Period:      uintPeriod,
+				Digits:      keyDigits,
+				Algorithm:   keyAlgorithm,
+				SecretSize:  uintKeySize,
+				Rand:        b.GetRandomReader(),
+			})
+		}
 		if err != nil {
 			return logical.ErrorResponse("an error occurred while generating a key"), err
 		}
@@ -386,8 +473,12 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		}
 	}
 
-	// Store it
-	entry, err := logical.StorageEntryJSON("key/"+name, &keyEntry{
+	// Store it, resetting the validation state of any previous key
+	lock := locksutil.LockForKey(b.keyLocks, name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := b.putKey(ctx, req.Storage, name, &keyEntry{
 		Key:         keyString,
 		Issuer:      issuer,
 		AccountName: accountName,
@@ -395,15 +486,42 @@ func (b *backend) pathKeyCreate(ctx context.Context, req *logical.Request, data
 		Algorithm:   keyAlgorithm,
 		Digits:      keyDigits,
 		Skew:        uintSkew,
+		Type:        keyType,
+		Counter:     uint64(counter),
+		LookAhead:   uint(lookAhead),
 	})
 	if err != nil {
 		return nil, err
 	}
-	if err := req.Storage.Put(ctx, entry); err != nil {
+
+	return response, nil
+}
+
+func (b *backend) putKey(ctx context.Context, s logical.Storage, name string, key *keyEntry) error {
+	entry, err := logical.StorageEntryJSON("key/"+name, key)
+	if err != nil {
+		return err
+	}
+	return s.Put(ctx, entry)
+}
+
+// generateHOTPKey generates a new HOTP key whose url carries the initial
+// counter value, which authenticator apps require to provision the key.
+func generateHOTPKey(opts hotplib.GenerateOpts, counter uint64) (*otplib.Key, error) {
+	keyObject, err := hotplib.Generate(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keyURL, err := url.Parse(keyObject.String())
+	if err != nil {
 		return nil, err
 	}
+	query := keyURL.Query()
+	query.Set("counter", strconv.FormatUint(counter, 10))
+	keyURL.RawQuery = query.Encode()
 
-	return response, nil
+	return otplib.NewKeyFromURL(keyURL.String())
 }
 
 type keyEntry struct {
@@ -414,6 +532,31 @@ type keyEntry struct {
 	Algorithm   otplib.Algorithm `json:"algorithm" mapstructure:"algorithm" structs:"algorithm"`
 	Digits      otplib.Digits    `json:"digits" mapstructure:"digits" structs:"digits"`
 	Skew        uint             `json:"skew" mapstructure:"skew" structs:"skew"`
+	Type        string           `json:"type" mapstructure:"type" structs:"type"`
+
+	// Counter is the next expected counter value of a HOTP key.
+	Counter   uint64 `json:"counter" mapstructure:"counter" structs:"counter"`
+	LookAhead uint   `json:"look_ahead" mapstructure:"look_ahead" structs:"look_ahead"`
+
+	// UsedSteps are the time steps within the skew of the last validation
+	// whose TOTP tokens were successfully validated, and are rejected.
+	UsedSteps []uint64 `json:"used_steps" mapstructure:"used_steps" structs:"used_steps"`
+}
+
+// keyType returns the type of the key; keys stored prior to the introduction
+// of HOTP keys are time-based.
+func (k *keyEntry) keyType() string {
+	if k.Type == "" {
+		return keyTypeTOTP
+	}
+	return k.Type
+}
+
+func (k *keyEntry) hotpOpts() hotplib.ValidateOpts {
+	return hotplib.ValidateOpts{
+		Digits:    k.Digits,
+		Algorithm: k.Algorithm,
+	}
 }
 
 const pathKeyHelpSyn = `
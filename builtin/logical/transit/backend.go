@@ -52,9 +52,15 @@ func Backend(ctx context.Context, conf *logical.BackendConfig) (*backend, error)
 			b.pathKeys(),
 			b.pathListKeys(),
 			b.pathExportKeys(),
+			b.pathBYOKExportKeys(),
 			b.pathEncrypt(),
 			b.pathDecrypt(),
+			b.pathFPEEncrypt(),
+			b.pathFPEDecrypt(),
 			b.pathDatakey(),
+			b.pathStreamStart(),
+			b.pathStreamEncrypt(),
+			b.pathStreamDecrypt(),
 			b.pathRandom(),
 			b.pathHash(),
 			b.pathHMAC(),
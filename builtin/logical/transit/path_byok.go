@@ -0,0 +1,270 @@
+package transit
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/google/tink/go/kwp/subtle"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// minBYOKWrappingKeyBits is the minimum size of the RSA public keys keys are
+// exported to.
+const minBYOKWrappingKeyBits = 2048
+
+func (b *backend) pathBYOKExportKeys() *framework.Path {
+	return &framework.Path{
+		Pattern: "byok-export/" + framework.GenericNameRegex("name") + framework.OptionalParamRegex("version"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+			"version": {
+				Type:        framework.TypeString,
+				Description: "Version of the key. Defaults to all versions.",
+			},
+			"public_key": {
+				Type: framework.TypeString,
+				Description: `The PEM encoded RSA public key of the destination, such as the
+wrapping key of another Vault cluster read from its transit wrapping_key
+endpoint, or the public wrapping key of an HSM.`,
+			},
+			"hash_function": {
+				Type:    framework.TypeString,
+				Default: "SHA256",
+				Description: `The hash function used as a random oracle in the OAEP wrapping of the ephemeral
+AES key. Can be one of "SHA1", "SHA224", "SHA256" (default), "SHA384", or "SHA512"`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathPolicyBYOKExportWrite,
+		},
+
+		HelpSynopsis:    pathBYOKExportHelpSyn,
+		HelpDescription: pathBYOKExportHelpDesc,
+	}
+}
+
+func (b *backend) pathPolicyBYOKExportWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	name := d.Get("name").(string)
+	version := d.Get("version").(string)
+
+	hashFn, err := parseHashFn(d.Get("hash_function").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	wrappingKey, err := parseBYOKWrappingKey(d.Get("public_key").(string))
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    name,
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, nil
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	defer p.Unlock()
+
+	if !p.Exportable {
+		return logical.ErrorResponse("key is not exportable"), nil
+	}
+
+	versions := map[string]keysutil.KeyEntry{}
+	switch version {
+	case "":
+		for k, v := range p.Keys {
+			versions[k] = v
+		}
+
+	default:
+		var versionValue int
+		if version == "latest" {
+			versionValue = p.LatestVersion
+		} else {
+			version = strings.TrimPrefix(version, "v")
+			versionValue, err = strconv.Atoi(version)
+			if err != nil {
+				return logical.ErrorResponse("invalid key version"), logical.ErrInvalidRequest
+			}
+		}
+
+		if versionValue < p.MinDecryptionVersion {
+			return logical.ErrorResponse("version for export is below minimum decryption version"), logical.ErrInvalidRequest
+		}
+		key, ok := p.Keys[strconv.Itoa(versionValue)]
+		if !ok {
+			return logical.ErrorResponse("version does not exist or cannot be found"), logical.ErrInvalidRequest
+		}
+		versions[strconv.Itoa(versionValue)] = key
+	}
+
+	retKeys := map[string]string{}
+	for k, v := range versions {
+		keyMaterial, err := getBYOKExportKey(p, &v)
+		if err != nil {
+			if _, ok := err.(errutil.UserError); ok {
+				return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+			}
+			return nil, err
+		}
+
+		wrapped, err := b.wrapBYOKExportKey(wrappingKey, hashFn, keyMaterial)
+		if err != nil {
+			return nil, fmt.Errorf("error wrapping key version %s: %w", k, err)
+		}
+		retKeys[k] = base64.StdEncoding.EncodeToString(wrapped)
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"name": p.Name,
+			"type": p.Type.String(),
+			"keys": retKeys,
+		},
+	}
+
+	return resp, nil
+}
+
+func parseBYOKWrappingKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, errors.New("failed to decode public_key as PEM")
+	}
+
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public_key: %w", err)
+	}
+
+	publicKey, ok := parsed.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("public_key must be an RSA public key")
+	}
+	if publicKey.N.BitLen() < minBYOKWrappingKeyBits {
+		return nil, fmt.Errorf("public_key must be at least %d bits", minBYOKWrappingKeyBits)
+	}
+
+	return publicKey, nil
+}
+
+// wrapBYOKExportKey wraps the key material in the format accepted by the
+// import endpoints, and by HSMs as CKM_RSA_AES_KEY_WRAP: a fresh 256-bit AES
+// key encrypted with RSA-OAEP under the wrapping key, followed by the key
+// material wrapped with AES-KWP under the AES key.
+func (b *backend) wrapBYOKExportKey(wrappingKey *rsa.PublicKey, hashFn hash.Hash, keyMaterial []byte) ([]byte, error) {
+	ephKey := make([]byte, 32)
+	if _, err := io.ReadFull(b.GetRandomReader(), ephKey); err != nil {
+		return nil, err
+	}
+
+	// Zero out the ephemeral AES key just to be extra cautious, as on import.
+	defer func() {
+		for i := range ephKey {
+			ephKey[i] = 0
+		}
+	}()
+
+	kwp, err := subtle.NewKWP(ephKey)
+	if err != nil {
+		return nil, err
+	}
+	wrappedKey, err := kwp.Wrap(keyMaterial)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedEphKey, err := rsa.EncryptOAEP(hashFn, b.GetRandomReader(), wrappingKey, ephKey, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(wrappedEphKey, wrappedKey...), nil
+}
+
+// getBYOKExportKey returns the key material of the key entry in the format
+// expected by the import endpoints: the raw key for symmetric keys and a
+// PKCS#8 encoded private key for asymmetric ones.
+func getBYOKExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry) ([]byte, error) {
+	if policy == nil {
+		return nil, errors.New("nil policy provided")
+	}
+
+	switch policy.Type {
+	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_HMAC, keysutil.KeyType_AES256_FF3_1:
+		return key.Key, nil
+
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521:
+		var curve elliptic.Curve
+		switch policy.Type {
+		case keysutil.KeyType_ECDSA_P384:
+			curve = elliptic.P384()
+		case keysutil.KeyType_ECDSA_P521:
+			curve = elliptic.P521()
+		default:
+			curve = elliptic.P256()
+		}
+		return x509.MarshalPKCS8PrivateKey(&ecdsa.PrivateKey{
+			PublicKey: ecdsa.PublicKey{
+				Curve: curve,
+				X:     key.EC_X,
+				Y:     key.EC_Y,
+			},
+			D: key.EC_D,
+		})
+
+	case keysutil.KeyType_ED25519:
+		return x509.MarshalPKCS8PrivateKey(ed25519.PrivateKey(key.Key))
+
+	case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+		return x509.MarshalPKCS8PrivateKey(key.RSAKey)
+
+	case keysutil.KeyType_MLDSA44, keysutil.KeyType_MLDSA65, keysutil.KeyType_MLDSA87,
+		keysutil.KeyType_MLDSA44_ECDSA_P256, keysutil.KeyType_MLDSA65_ECDSA_P384, keysutil.KeyType_MLDSA87_ECDSA_P521:
+		// The import endpoints don't accept ML-DSA keys, which have no
+		// settled PKCS#8 encoding yet
+		return nil, errutil.UserError{Err: fmt.Sprintf("byok-export is not supported for key type %v", policy.Type)}
+	}
+
+	return nil, fmt.Errorf("unknown key type %v", policy.Type)
+}
+
+const pathBYOKExportHelpSyn = `Securely export a named key to an external destination`
+
+const pathBYOKExportHelpDesc = `
+This path is used to export the named keys that are configured as exportable
+to another system without exposing them in plaintext, for example to move keys
+between Vault clusters or into an HSM. Each version of the key is wrapped for
+the given RSA public key using RSA-OAEP and AES-KWP, in the format accepted by
+the keys/:name/import and keys/:name/import_version endpoints. Symmetric keys
+are wrapped raw and asymmetric keys as PKCS#8 private keys.
+
+Note that the import endpoints expect the wrapping key of the destination
+cluster, read from its wrapping_key endpoint, to have been used.
+`
@@ -0,0 +1,170 @@
+package transit
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_BYOKExport(t *testing.T) {
+	srcBackend, srcStorage := createBackendWithStorage(t)
+	dstBackend, dstStorage := createBackendWithStorage(t)
+
+	request := func(b *backend, s logical.Storage, op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: op,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("%s: err:%v resp:%#v", path, err, resp)
+		}
+		return resp
+	}
+
+	resp := request(dstBackend, dstStorage, logical.ReadOperation, "wrapping_key", nil)
+	wrappingKey := resp.Data["public_key"].(string)
+
+	for _, keyType := range []string{"aes256-gcm96", "chacha20-poly1305", "ecdsa-p256", "ed25519", "rsa-2048"} {
+		t.Run(keyType, func(t *testing.T) {
+			request(srcBackend, srcStorage, logical.UpdateOperation, "keys/"+keyType, map[string]interface{}{
+				"type":       keyType,
+				"exportable": true,
+			})
+			request(srcBackend, srcStorage, logical.UpdateOperation, fmt.Sprintf("keys/%s/rotate", keyType), nil)
+
+			resp := request(srcBackend, srcStorage, logical.UpdateOperation, "byok-export/"+keyType, map[string]interface{}{
+				"public_key": wrappingKey,
+			})
+			keys := resp.Data["keys"].(map[string]string)
+			if len(keys) != 2 {
+				t.Fatalf("expected 2 key versions, got %d", len(keys))
+			}
+
+			request(dstBackend, dstStorage, logical.UpdateOperation, fmt.Sprintf("keys/%s/import", keyType), map[string]interface{}{
+				"type":       keyType,
+				"ciphertext": keys["1"],
+			})
+			request(dstBackend, dstStorage, logical.UpdateOperation, fmt.Sprintf("keys/%s/import_version", keyType), map[string]interface{}{
+				"ciphertext": keys["2"],
+			})
+
+			input := base64.StdEncoding.EncodeToString([]byte(testPlaintext))
+			switch keyType {
+			case "aes256-gcm96", "chacha20-poly1305":
+				resp := request(srcBackend, srcStorage, logical.UpdateOperation, "encrypt/"+keyType, map[string]interface{}{
+					"plaintext": input,
+				})
+				resp = request(dstBackend, dstStorage, logical.UpdateOperation, "decrypt/"+keyType, map[string]interface{}{
+					"ciphertext": resp.Data["ciphertext"],
+				})
+				if resp.Data["plaintext"] != input {
+					t.Fatalf("bad plaintext %q", resp.Data["plaintext"])
+				}
+			default:
+				resp := request(srcBackend, srcStorage, logical.UpdateOperation, "sign/"+keyType, map[string]interface{}{
+					"input": input,
+				})
+				resp = request(dstBackend, dstStorage, logical.UpdateOperation, "verify/"+keyType, map[string]interface{}{
+					"input":     input,
+					"signature": resp.Data["signature"],
+				})
+				if !resp.Data["valid"].(bool) {
+					t.Fatal("signature made with the source key not valid for the imported key")
+				}
+			}
+		})
+	}
+}
+
+func TestTransit_BYOKExport_Errors(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	weakKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	derBytes, err := x509.MarshalPKIXPublicKey(weakKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	weakKeyPEM := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: derBytes}))
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "wrapping_key",
+		Storage:   s,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	wrappingKey := resp.Data["public_key"].(string)
+
+	for _, name := range []string{"exportable", "non-exportable"} {
+		_, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "keys/" + name,
+			Storage:   s,
+			Data: map[string]interface{}{
+				"exportable": name == "exportable",
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tc := range []struct {
+		path string
+		data map[string]interface{}
+	}{
+		{"byok-export/non-exportable", map[string]interface{}{"public_key": wrappingKey}},
+		{"byok-export/exportable", map[string]interface{}{"public_key": weakKeyPEM}},
+		{"byok-export/exportable", map[string]interface{}{"public_key": "not a key"}},
+		{"byok-export/exportable", map[string]interface{}{"public_key": wrappingKey, "hash_function": "MD5"}},
+		{"byok-export/exportable/2", map[string]interface{}{"public_key": wrappingKey}},
+	} {
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      tc.path,
+			Storage:   s,
+			Data:      tc.data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error for %s %#v, got %#v", tc.path, tc.data, resp)
+		}
+	}
+
+	// Key types the import endpoints don't support are rejected as invalid
+	// requests
+	_, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "keys/exportable-mldsa",
+		Storage:   s,
+		Data: map[string]interface{}{
+			"type":       "ml-dsa-44",
+			"exportable": true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "byok-export/exportable-mldsa",
+		Storage:   s,
+		Data:      map[string]interface{}{"public_key": wrappingKey},
+	})
+	if err != logical.ErrInvalidRequest || resp == nil || !resp.IsError() {
+		t.Fatalf("expected invalid request error, got err:%v resp:%#v", err, resp)
+	}
+}
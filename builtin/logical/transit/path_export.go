@@ -22,6 +22,7 @@ const (
 	exportTypeEncryptionKey = "encryption-key"
 	exportTypeSigningKey    = "signing-key"
 	exportTypeHMACKey       = "hmac-key"
+	exportTypePublicKey     = "public-key"
 )
 
 func (b *backend) pathExportKeys() *framework.Path {
@@ -30,7 +31,7 @@ func (b *backend) pathExportKeys() *framework.Path {
 		Fields: map[string]*framework.FieldSchema{
 			"type": {
 				Type:        framework.TypeString,
-				Description: "Type of key to export (encryption-key, signing-key, hmac-key)",
+				Description: "Type of key to export (encryption-key, signing-key, hmac-key, public-key)",
 			},
 			"name": {
 				Type:        framework.TypeString,
@@ -60,6 +61,7 @@ func (b *backend) pathPolicyExportRead(ctx context.Context, req *logical.Request
 	case exportTypeEncryptionKey:
 	case exportTypeSigningKey:
 	case exportTypeHMACKey:
+	case exportTypePublicKey:
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("invalid export type: %s", exportType)), logical.ErrInvalidRequest
 	}
@@ -79,19 +81,29 @@ func (b *backend) pathPolicyExportRead(ctx context.Context, req *logical.Request
 	}
 	defer p.Unlock()
 
-	if !p.Exportable {
+	// Public keys can be exported regardless of the exportable setting, as
+	// they can already be read from the key
+	if !p.Exportable && exportType != exportTypePublicKey {
 		return logical.ErrorResponse("key is not exportable"), nil
 	}
 
 	switch exportType {
 	case exportTypeEncryptionKey:
-		if !p.Type.EncryptionSupported() {
+		if !p.Type.EncryptionSupported() && !p.Type.FPESupported() {
 			return logical.ErrorResponse("encryption not supported for the key"), logical.ErrInvalidRequest
 		}
 	case exportTypeSigningKey:
 		if !p.Type.SigningSupported() {
 			return logical.ErrorResponse("signing not supported for the key"), logical.ErrInvalidRequest
 		}
+		switch p.Type {
+		case keysutil.KeyType_MLDSA44_ECDSA_P256, keysutil.KeyType_MLDSA65_ECDSA_P384, keysutil.KeyType_MLDSA87_ECDSA_P521:
+			return logical.ErrorResponse("signing key export is not supported for hybrid keys"), logical.ErrInvalidRequest
+		}
+	case exportTypePublicKey:
+		if !p.Type.SigningSupported() {
+			return logical.ErrorResponse("public key export not supported for the key"), logical.ErrInvalidRequest
+		}
 	}
 
 	retKeys := map[string]string{}
@@ -155,7 +167,7 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 
 	case exportTypeEncryptionKey:
 		switch policy.Type {
-		case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
+		case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_AES256_FF3_1:
 			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.Key)), nil
 
 		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
@@ -180,12 +192,21 @@ func getExportKey(policy *keysutil.Policy, key *keysutil.KeyEntry, exportType st
 			}
 			return ecKey, nil
 
-		case keysutil.KeyType_ED25519:
+		case keysutil.KeyType_ED25519, keysutil.KeyType_MLDSA44, keysutil.KeyType_MLDSA65, keysutil.KeyType_MLDSA87:
 			return strings.TrimSpace(base64.StdEncoding.EncodeToString(key.Key)), nil
 
 		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
 			return encodeRSAPrivateKey(key.RSAKey), nil
 		}
+
+	case exportTypePublicKey:
+		switch policy.Type {
+		case keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+			return encodeRSAPublicKey(key.RSAKey)
+
+		default:
+			return strings.TrimSpace(key.FormattedPublicKey), nil
+		}
 	}
 
 	return "", fmt.Errorf("unknown key type %v", policy.Type)
@@ -203,6 +224,18 @@ func encodeRSAPrivateKey(key *rsa.PrivateKey) string {
 	return string(pemBytes)
 }
 
+func encodeRSAPublicKey(key *rsa.PrivateKey) (string, error) {
+	derBytes, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return "", fmt.Errorf("error marshaling RSA public key: %w", err)
+	}
+	pemBlock := &pem.Block{
+		Type:  "PUBLIC KEY",
+		Bytes: derBytes,
+	}
+	return strings.TrimSpace(string(pem.EncodeToMemory(pemBlock))), nil
+}
+
 func keyEntryToECPrivateKey(k *keysutil.KeyEntry, curve elliptic.Curve) (string, error) {
 	if k == nil {
 		return "", errors.New("nil KeyEntry provided")
@@ -235,5 +268,10 @@ const pathExportHelpSyn = `Export named encryption or signing key`
 
 const pathExportHelpDesc = `
 This path is used to export the named keys that are configured as
-exportable.
+exportable. The public-key type exports the public keys of asymmetric keys
+and does not require the key to be exportable.
+
+ML-DSA signing keys are exported as their base64 encoded 32-byte seed, from
+which FIPS 204 implementations derive the key pair. Signing keys of hybrid
+ML-DSA and ECDSA types cannot be exported.
 `
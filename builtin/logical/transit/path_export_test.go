@@ -25,6 +25,12 @@ func TestTransit_Export_KeyVersion_ExportsCorrectVersion(t *testing.T) {
 	verifyExportsCorrectVersion(t, "hmac-key", "ecdsa-p384")
 	verifyExportsCorrectVersion(t, "hmac-key", "ecdsa-p521")
 	verifyExportsCorrectVersion(t, "hmac-key", "ed25519")
+	verifyExportsCorrectVersion(t, "signing-key", "ml-dsa-44")
+	verifyExportsCorrectVersion(t, "public-key", "ecdsa-p256")
+	verifyExportsCorrectVersion(t, "public-key", "ed25519")
+	verifyExportsCorrectVersion(t, "public-key", "rsa-2048")
+	verifyExportsCorrectVersion(t, "public-key", "ml-dsa-65")
+	verifyExportsCorrectVersion(t, "public-key", "ml-dsa-87-ecdsa-p521")
 }
 
 func verifyExportsCorrectVersion(t *testing.T, exportType, keyType string) {
@@ -247,6 +253,81 @@ func TestTransit_Export_KeysNotMarkedExportable_ReturnsError(t *testing.T) {
 	}
 }
 
+func TestTransit_Export_PublicKey_KeysNotMarkedExportable(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Data: map[string]interface{}{
+			"type": "ml-dsa-44",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "export/public-key/foo",
+	}
+	rsp, err := b.HandleRequest(context.Background(), req)
+	if err != nil || rsp.IsError() {
+		t.Fatalf("err: %v resp: %#v", err, rsp)
+	}
+	exported := rsp.Data["keys"].(map[string]string)["1"]
+
+	req.Path = "keys/foo"
+	rsp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := rsp.Data["keys"].(map[string]map[string]interface{})["1"]["public_key"].(string)
+	if exported == "" || exported != publicKey {
+		t.Fatalf("exported public key %q does not match %q", exported, publicKey)
+	}
+
+	req.Path = "export/signing-key/foo"
+	rsp, err = b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rsp.IsError() {
+		t.Fatal("Key not marked as exportable but was exported.")
+	}
+}
+
+func TestTransit_Export_SigningKey_Hybrid_ReturnsError(t *testing.T) {
+	b, storage := createBackendWithSysView(t)
+
+	req := &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/foo",
+		Data: map[string]interface{}{
+			"exportable": true,
+			"type":       "ml-dsa-44-ecdsa-p256",
+		},
+	}
+	_, err := b.HandleRequest(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req = &logical.Request{
+		Storage:   storage,
+		Operation: logical.ReadOperation,
+		Path:      "export/signing-key/foo",
+	}
+	_, err = b.HandleRequest(context.Background(), req)
+	if err == nil {
+		t.Fatal("Hybrid signing key was exported without error.")
+	}
+}
+
 func TestTransit_Export_SigningDoesNotSupportSigning_ReturnsError(t *testing.T) {
 	b, storage := createBackendWithSysView(t)
 
@@ -0,0 +1,274 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/mitchellh/mapstructure"
+)
+
+// FPEBatchRequestItem represents a request item for batch processing of
+// format preserving encryption and decryption
+type FPEBatchRequestItem struct {
+	// Plaintext for encryption
+	Plaintext string `json:"plaintext" structs:"plaintext" mapstructure:"plaintext"`
+
+	// Ciphertext for decryption
+	Ciphertext string `json:"ciphertext" structs:"ciphertext" mapstructure:"ciphertext"`
+
+	// Tweak is the base64 encoded 56-bit tweak
+	Tweak string `json:"tweak" structs:"tweak" mapstructure:"tweak"`
+
+	// The key version to be used
+	KeyVersion int `json:"key_version" structs:"key_version" mapstructure:"key_version"`
+}
+
+// FPEBatchResponseItem represents a response item for batch processing of
+// format preserving encryption and decryption
+type FPEBatchResponseItem struct {
+	// Plaintext for the ciphertext present in the corresponding batch
+	// request item
+	Plaintext string `json:"plaintext,omitempty" structs:"plaintext" mapstructure:"plaintext"`
+
+	// Ciphertext for the plaintext present in the corresponding batch
+	// request item
+	Ciphertext string `json:"ciphertext,omitempty" structs:"ciphertext" mapstructure:"ciphertext"`
+
+	// KeyVersion defines the key version used
+	KeyVersion int `json:"key_version,omitempty" structs:"key_version" mapstructure:"key_version"`
+
+	// Error, if set represents a failure encountered while processing the
+	// corresponding batch request item
+	Error string `json:"error,omitempty" structs:"error" mapstructure:"error"`
+}
+
+func (b *backend) pathFPEEncrypt() *framework.Path {
+	return &framework.Path{
+		Pattern: "fpe/encrypt/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"plaintext": {
+				Type:        framework.TypeString,
+				Description: "Value to encrypt, consisting of characters from the alphabet of the key",
+			},
+
+			"tweak": {
+				Type: framework.TypeString,
+				Description: `
+Base64 encoded 56-bit (7-byte) tweak. The same value must be provided for
+decryption. Defaults to all zeroes.`,
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `The version of the key to use for encryption.
+Must be 0 (for latest) or a value greater than or equal
+to the min_encryption_version configured on the key.`,
+			},
+
+			"batch_input": {
+				Type: framework.TypeSlice,
+				Description: `
+Specifies a list of items to be encrypted in a single batch. When this
+parameter is set, if the parameters 'plaintext', 'tweak' and 'key_version'
+are also set, they will be ignored.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathFPEWrite(true),
+		},
+
+		HelpSynopsis:    pathFPEEncryptHelpSyn,
+		HelpDescription: pathFPEHelpDesc,
+	}
+}
+
+func (b *backend) pathFPEDecrypt() *framework.Path {
+	return &framework.Path{
+		Pattern: "fpe/decrypt/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"ciphertext": {
+				Type:        framework.TypeString,
+				Description: "Value to decrypt, as returned by fpe/encrypt",
+			},
+
+			"tweak": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded 56-bit (7-byte) tweak used during encryption",
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `The version of the key used for encryption, as
+returned by fpe/encrypt. Required, as the ciphertext doesn't record it.`,
+			},
+
+			"batch_input": {
+				Type: framework.TypeSlice,
+				Description: `
+Specifies a list of items to be decrypted in a single batch. When this
+parameter is set, if the parameters 'ciphertext', 'tweak' and 'key_version'
+are also set, they will be ignored.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathFPEWrite(false),
+		},
+
+		HelpSynopsis:    pathFPEDecryptHelpSyn,
+		HelpDescription: pathFPEHelpDesc,
+	}
+}
+
+func (b *backend) pathFPEWrite(encrypt bool) framework.OperationFunc {
+	return func(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+		name := d.Get("name").(string)
+
+		batchInputRaw := d.Raw["batch_input"]
+		var batchInputItems []FPEBatchRequestItem
+		if batchInputRaw != nil {
+			if err := mapstructure.Decode(batchInputRaw, &batchInputItems); err != nil {
+				return nil, fmt.Errorf("failed to parse batch input: %w", err)
+			}
+
+			if len(batchInputItems) == 0 {
+				return logical.ErrorResponse("missing batch input to process"), logical.ErrInvalidRequest
+			}
+		} else {
+			item := FPEBatchRequestItem{
+				Tweak:      d.Get("tweak").(string),
+				KeyVersion: d.Get("key_version").(int),
+			}
+			if encrypt {
+				item.Plaintext = d.Get("plaintext").(string)
+			} else {
+				item.Ciphertext = d.Get("ciphertext").(string)
+			}
+			batchInputItems = []FPEBatchRequestItem{item}
+		}
+
+		p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+			Storage: req.Storage,
+			Name:    name,
+		}, b.GetRandomReader())
+		if err != nil {
+			return nil, err
+		}
+		if p == nil {
+			return logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+		}
+		if !b.System().CachingDisabled() {
+			p.Lock(false)
+		}
+		defer p.Unlock()
+
+		if !p.Type.FPESupported() {
+			return logical.ErrorResponse(fmt.Sprintf("format preserving encryption not supported for key type %v", p.Type)), logical.ErrInvalidRequest
+		}
+
+		batchResponseItems := make([]FPEBatchResponseItem, len(batchInputItems))
+		var successesInBatch, userErrorInBatch, internalErrorInBatch bool
+		for i, item := range batchInputItems {
+			tweak := make([]byte, keysutil.FF31TweakSize)
+			if item.Tweak != "" {
+				tweak, err = base64.StdEncoding.DecodeString(item.Tweak)
+				if err != nil {
+					userErrorInBatch = true
+					batchResponseItems[i].Error = "failed to base64-decode tweak"
+					continue
+				}
+			}
+
+			keyVersion := item.KeyVersion
+			if encrypt && keyVersion == 0 {
+				keyVersion = p.LatestVersion
+			}
+
+			var result string
+			if encrypt {
+				result, err = p.FPEEncrypt(keyVersion, tweak, item.Plaintext)
+			} else {
+				result, err = p.FPEDecrypt(keyVersion, tweak, item.Ciphertext)
+			}
+			if err != nil {
+				switch err.(type) {
+				case errutil.InternalError:
+					internalErrorInBatch = true
+				default:
+					userErrorInBatch = true
+				}
+				batchResponseItems[i].Error = err.Error()
+				continue
+			}
+
+			successesInBatch = true
+			if encrypt {
+				batchResponseItems[i].Ciphertext = result
+				batchResponseItems[i].KeyVersion = keyVersion
+			} else {
+				batchResponseItems[i].Plaintext = result
+			}
+		}
+
+		resp := &logical.Response{}
+		if batchInputRaw != nil {
+			resp.Data = map[string]interface{}{
+				"batch_results": batchResponseItems,
+			}
+			return batchRequestResponse(d, resp, req, successesInBatch, userErrorInBatch, internalErrorInBatch)
+		}
+
+		if batchResponseItems[0].Error != "" {
+			if internalErrorInBatch {
+				return nil, errutil.InternalError{Err: batchResponseItems[0].Error}
+			}
+			return logical.ErrorResponse(batchResponseItems[0].Error), logical.ErrInvalidRequest
+		}
+
+		if encrypt {
+			resp.Data = map[string]interface{}{
+				"ciphertext":  batchResponseItems[0].Ciphertext,
+				"key_version": batchResponseItems[0].KeyVersion,
+			}
+		} else {
+			resp.Data = map[string]interface{}{
+				"plaintext": batchResponseItems[0].Plaintext,
+			}
+		}
+		return resp, nil
+	}
+}
+
+const pathFPEEncryptHelpSyn = `Encrypt a value preserving its format`
+
+const pathFPEDecryptHelpSyn = `Decrypt a value encrypted preserving its format`
+
+const pathFPEHelpDesc = `
+These paths use a key of type "aes256-ff3-1" to encrypt and decrypt values
+with the FF3-1 format preserving encryption mode of NIST SP 800-38G Rev. 1.
+Ciphertexts have the same length as the plaintexts and consist of characters
+from the same alphabet, which is declared when the key is created. The length
+of values must lie within the bounds reported when reading the key.
+
+As the ciphertext cannot carry the version of the key used, encryption
+returns it and it must be provided again on decryption. Values encrypted with
+versions below min_decryption_version can no longer be decrypted. An optional
+56-bit tweak, such as a hash of the record the value belongs to, makes the
+ciphertexts of equal plaintexts differ between tweaks.
+`
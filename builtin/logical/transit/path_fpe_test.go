@@ -0,0 +1,204 @@
+package transit
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestTransit_FPE(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+	mustRequest := func(path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := request(path, data)
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err:%v resp:%#v", err, resp)
+		}
+		return resp
+	}
+
+	mustRequest("keys/cards", map[string]interface{}{
+		"type": "aes256-ff3-1",
+	})
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.ReadOperation,
+		Path:      "keys/cards",
+		Storage:   s,
+	})
+	if err != nil || resp == nil {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["type"] != "aes256-ff3-1" || resp.Data["alphabet"] != "0123456789" {
+		t.Fatalf("bad key: %#v", resp.Data)
+	}
+	if resp.Data["min_length"] != 6 || resp.Data["max_length"] != 56 {
+		t.Fatalf("bad length bounds: %#v", resp.Data)
+	}
+
+	tweak := base64.StdEncoding.EncodeToString([]byte("card-01"))
+	plaintext := "4111111111111111"
+	resp = mustRequest("fpe/encrypt/cards", map[string]interface{}{
+		"plaintext": plaintext,
+		"tweak":     tweak,
+	})
+	ciphertext := resp.Data["ciphertext"].(string)
+	if len(ciphertext) != len(plaintext) || ciphertext == plaintext {
+		t.Fatalf("bad ciphertext %q", ciphertext)
+	}
+	for _, r := range ciphertext {
+		if r < '0' || r > '9' {
+			t.Fatalf("ciphertext %q not in alphabet", ciphertext)
+		}
+	}
+	if resp.Data["key_version"] != 1 {
+		t.Fatalf("bad key version: %v", resp.Data["key_version"])
+	}
+
+	// Encryption is deterministic for a given tweak
+	resp = mustRequest("fpe/encrypt/cards", map[string]interface{}{
+		"plaintext": plaintext,
+		"tweak":     tweak,
+	})
+	if resp.Data["ciphertext"] != ciphertext {
+		t.Fatalf("expected %q, got %q", ciphertext, resp.Data["ciphertext"])
+	}
+	resp = mustRequest("fpe/encrypt/cards", map[string]interface{}{
+		"plaintext": plaintext,
+	})
+	if resp.Data["ciphertext"] == ciphertext {
+		t.Fatal("expected a different ciphertext for a different tweak")
+	}
+
+	// Rotation keeps older values decryptable with their version
+	mustRequest("keys/cards/rotate", nil)
+	resp = mustRequest("fpe/encrypt/cards", map[string]interface{}{
+		"plaintext": plaintext,
+		"tweak":     tweak,
+	})
+	if resp.Data["key_version"] != 2 || resp.Data["ciphertext"] == ciphertext {
+		t.Fatalf("bad response after rotation: %#v", resp.Data)
+	}
+	ciphertext2 := resp.Data["ciphertext"].(string)
+	resp = mustRequest("fpe/decrypt/cards", map[string]interface{}{
+		"ciphertext":  ciphertext,
+		"tweak":       tweak,
+		"key_version": 1,
+	})
+	if resp.Data["plaintext"] != plaintext {
+		t.Fatalf("expected %q, got %q", plaintext, resp.Data["plaintext"])
+	}
+
+	resp = mustRequest("fpe/decrypt/cards", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"ciphertext": ciphertext, "tweak": tweak, "key_version": 1},
+			map[string]interface{}{"ciphertext": ciphertext2, "tweak": tweak, "key_version": 2},
+		},
+	})
+	results := resp.Data["batch_results"].([]FPEBatchResponseItem)
+	if results[0].Plaintext != plaintext || results[1].Plaintext != plaintext {
+		t.Fatalf("bad batch results: %#v", results)
+	}
+
+	// The key version must be given for decryption, as decrypting with
+	// another version than the one used for encryption can't be detected
+	resp, err = request("fpe/decrypt/cards", map[string]interface{}{
+		"ciphertext": ciphertext2,
+		"tweak":      tweak,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error decrypting without a key version, got %#v", resp)
+	}
+
+	// min_decryption_version is enforced
+	mustRequest("keys/cards/config", map[string]interface{}{
+		"min_decryption_version": 2,
+	})
+	resp, err = request("fpe/decrypt/cards", map[string]interface{}{
+		"ciphertext":  ciphertext,
+		"tweak":       tweak,
+		"key_version": 1,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error decrypting with a version below min_decryption_version, got %#v", resp)
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"plaintext": "12345"},
+		{"plaintext": "1234-5678"},
+		{"plaintext": "123456", "tweak": base64.StdEncoding.EncodeToString([]byte("short"))},
+	} {
+		resp, err := request("fpe/encrypt/cards", data)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %#v, got %#v", data, resp)
+		}
+	}
+}
+
+func TestTransit_FPE_Alphabet(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	request := func(path string, data map[string]interface{}) (*logical.Response, error) {
+		return b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      path,
+			Storage:   s,
+			Data:      data,
+		})
+	}
+
+	for _, data := range []map[string]interface{}{
+		{"type": "aes256-ff3-1", "alphabet": "a"},
+		{"type": "aes256-ff3-1", "alphabet": "abca"},
+		{"type": "aes256-gcm96", "alphabet": "0123456789"},
+	} {
+		resp, err := request("keys/bad", data)
+		if err == nil || resp == nil || !resp.IsError() {
+			t.Fatalf("expected error for %#v, got %#v", data, resp)
+		}
+	}
+
+	resp, err := request("keys/ids", map[string]interface{}{
+		"type":     "aes256-ff3-1",
+		"alphabet": "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	resp, err = request("fpe/encrypt/ids", map[string]interface{}{
+		"plaintext": "AB123456C",
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	ciphertext := resp.Data["ciphertext"].(string)
+	resp, err = request("fpe/decrypt/ids", map[string]interface{}{
+		"ciphertext":  ciphertext,
+		"key_version": 1,
+	})
+	if err != nil || resp.IsError() {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	if resp.Data["plaintext"] != "AB123456C" {
+		t.Fatalf("bad plaintext %q", resp.Data["plaintext"])
+	}
+
+	// Regular encryption is not supported by format preserving keys
+	resp, err = request("encrypt/ids", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString([]byte("foo")),
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got %#v", resp)
+	}
+}
@@ -46,10 +46,19 @@ func (b *backend) pathKeys() *framework.Path {
 				Description: `
 The type of key to create. Currently, "aes128-gcm96" (symmetric), "aes256-gcm96" (symmetric), "ecdsa-p256"
 (asymmetric), "ecdsa-p384" (asymmetric), "ecdsa-p521" (asymmetric), "ed25519" (asymmetric), "rsa-2048" (asymmetric), "rsa-3072"
-(asymmetric), "rsa-4096" (asymmetric) are supported.  Defaults to "aes256-gcm96".
+(asymmetric), "rsa-4096" (asymmetric), "aes256-ff3-1" (format preserving), "ml-dsa-44", "ml-dsa-65", "ml-dsa-87"
+(post-quantum asymmetric), "ml-dsa-44-ecdsa-p256", "ml-dsa-65-ecdsa-p384", "ml-dsa-87-ecdsa-p521" (hybrid
+asymmetric) are supported.  Defaults to "aes256-gcm96".
 `,
 			},
 
+			"alphabet": {
+				Type: framework.TypeString,
+				Description: `The characters of the values encrypted with a
+format preserving encryption key. Only applies to
+"aes256-ff3-1" keys. Defaults to "0123456789".`,
+			},
+
 			"derived": {
 				Type: framework.TypeBool,
 				Description: `Enables key derivation mode. This
@@ -182,6 +191,20 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		polReq.KeyType = keysutil.KeyType_RSA4096
 	case "hmac":
 		polReq.KeyType = keysutil.KeyType_HMAC
+	case "aes256-ff3-1":
+		polReq.KeyType = keysutil.KeyType_AES256_FF3_1
+	case "ml-dsa-44":
+		polReq.KeyType = keysutil.KeyType_MLDSA44
+	case "ml-dsa-65":
+		polReq.KeyType = keysutil.KeyType_MLDSA65
+	case "ml-dsa-87":
+		polReq.KeyType = keysutil.KeyType_MLDSA87
+	case "ml-dsa-44-ecdsa-p256":
+		polReq.KeyType = keysutil.KeyType_MLDSA44_ECDSA_P256
+	case "ml-dsa-65-ecdsa-p384":
+		polReq.KeyType = keysutil.KeyType_MLDSA65_ECDSA_P384
+	case "ml-dsa-87-ecdsa-p521":
+		polReq.KeyType = keysutil.KeyType_MLDSA87_ECDSA_P521
 	default:
 		return logical.ErrorResponse(fmt.Sprintf("unknown key type %v", keyType)), logical.ErrInvalidRequest
 	}
@@ -194,6 +217,19 @@ func (b *backend) pathPolicyWrite(ctx context.Context, req *logical.Request, d *
 		}
 		polReq.KeySize = keySize
 	}
+	if alphabet, ok := d.GetOk("alphabet"); ok {
+		if !polReq.KeyType.FPESupported() {
+			return logical.ErrorResponse(fmt.Sprintf("alphabet is not valid for algorithm %v", polReq.KeyType)), logical.ErrInvalidRequest
+		}
+		polReq.FPEAlphabet = alphabet.(string)
+	} else if polReq.KeyType.FPESupported() {
+		polReq.FPEAlphabet = "0123456789"
+	}
+	if polReq.KeyType.FPESupported() {
+		if err := keysutil.ValidateFF31Alphabet(polReq.FPEAlphabet); err != nil {
+			return logical.ErrorResponse(fmt.Sprintf("invalid alphabet: %v", err)), logical.ErrInvalidRequest
+		}
+	}
 
 	p, upserted, err := b.GetPolicy(ctx, polReq, b.GetRandomReader())
 	if err != nil {
@@ -264,6 +300,16 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 		resp.Data["key_size"] = p.KeySize
 	}
 
+	if p.Type.FPESupported() {
+		minLength, maxLength, err := keysutil.FF31LengthBounds(p.FPEAlphabet)
+		if err != nil {
+			return nil, err
+		}
+		resp.Data["alphabet"] = p.FPEAlphabet
+		resp.Data["min_length"] = minLength
+		resp.Data["max_length"] = maxLength
+	}
+
 	if p.Imported {
 		resp.Data["imported_key_allow_rotation"] = p.AllowImportedKeyRotation
 	}
@@ -305,14 +351,15 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 	}
 
 	switch p.Type {
-	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305:
+	case keysutil.KeyType_AES128_GCM96, keysutil.KeyType_AES256_GCM96, keysutil.KeyType_ChaCha20_Poly1305, keysutil.KeyType_AES256_FF3_1:
 		retKeys := map[string]int64{}
 		for k, v := range p.Keys {
 			retKeys[k] = v.DeprecatedCreationTime
 		}
 		resp.Data["keys"] = retKeys
 
-	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521, keysutil.KeyType_ED25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096:
+	case keysutil.KeyType_ECDSA_P256, keysutil.KeyType_ECDSA_P384, keysutil.KeyType_ECDSA_P521, keysutil.KeyType_ED25519, keysutil.KeyType_RSA2048, keysutil.KeyType_RSA3072, keysutil.KeyType_RSA4096,
+		keysutil.KeyType_MLDSA44, keysutil.KeyType_MLDSA65, keysutil.KeyType_MLDSA87, keysutil.KeyType_MLDSA44_ECDSA_P256, keysutil.KeyType_MLDSA65_ECDSA_P384, keysutil.KeyType_MLDSA87_ECDSA_P521:
 		retKeys := map[string]map[string]interface{}{}
 		for k, v := range p.Keys {
 			key := asymKey{
@@ -373,6 +420,8 @@ func (b *backend) pathPolicyRead(ctx context.Context, req *logical.Request, d *f
 					return nil, fmt.Errorf("failed to PEM-encode RSA public key")
 				}
 				key.PublicKey = string(pemBytes)
+			default:
+				key.Name = p.Type.String()
 			}
 
 			retKeys[k] = structs.New(key).Map()
@@ -13,6 +13,7 @@ import (
 	"golang.org/x/crypto/ed25519"
 
 	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/helper/mldsa"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/mitchellh/mapstructure"
 )
@@ -968,3 +969,150 @@ func testTransit_SignVerify_RSA_PSS(t *testing.T, bits int) {
 		}
 	}
 }
+
+func TestTransit_SignVerify_MLDSA(t *testing.T) {
+	keyTypes := map[string]*mldsa.Params{
+		"ml-dsa-44":            mldsa.MLDSA44,
+		"ml-dsa-65":            mldsa.MLDSA65,
+		"ml-dsa-87":            mldsa.MLDSA87,
+		"ml-dsa-44-ecdsa-p256": nil,
+		"ml-dsa-65-ecdsa-p384": nil,
+		"ml-dsa-87-ecdsa-p521": nil,
+	}
+
+	for keyType, params := range keyTypes {
+		t.Run(keyType, func(t *testing.T) {
+			testTransit_SignVerify_MLDSA(t, keyType, params)
+		})
+	}
+}
+
+func testTransit_SignVerify_MLDSA(t *testing.T, keyType string, params *mldsa.Params) {
+	b, storage := createBackendWithSysView(t)
+
+	request := func(op logical.Operation, path string, data map[string]interface{}) *logical.Response {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Storage:   storage,
+			Operation: op,
+			Path:      path,
+			Data:      data,
+		})
+		if err != nil || (resp != nil && resp.IsError()) {
+			t.Fatalf("err: %v resp: %#v", err, resp)
+		}
+		return resp
+	}
+
+	verify := func(input, sig string) bool {
+		t.Helper()
+		resp := request(logical.UpdateOperation, "verify/foo", map[string]interface{}{
+			"input":     input,
+			"signature": sig,
+		})
+		return resp.Data["valid"].(bool)
+	}
+
+	// Derivation is not supported
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "keys/bar",
+		Data: map[string]interface{}{
+			"type":    keyType,
+			"derived": true,
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected error creating derived key")
+	}
+
+	request(logical.UpdateOperation, "keys/foo", map[string]interface{}{
+		"type": keyType,
+	})
+
+	resp = request(logical.ReadOperation, "keys/foo", nil)
+	key := resp.Data["keys"].(map[string]map[string]interface{})["1"]
+	if key["name"] != keyType {
+		t.Fatalf("bad key name %v", key["name"])
+	}
+
+	input := base64.StdEncoding.EncodeToString([]byte("the quick brown fox"))
+	resp = request(logical.UpdateOperation, "sign/foo", map[string]interface{}{
+		"input": input,
+	})
+	sig := resp.Data["signature"].(string)
+	if !strings.HasPrefix(sig, "vault:v1:") {
+		t.Fatalf("bad signature %q", sig)
+	}
+
+	if !verify(input, sig) {
+		t.Fatal("signature did not verify")
+	}
+	if verify(base64.StdEncoding.EncodeToString([]byte("the quick brown fax")), sig) {
+		t.Fatal("signature verified for other input")
+	}
+
+	// Signatures of plain ML-DSA keys verify with the public key alone
+	if params != nil {
+		pubKeyBytes, err := base64.StdEncoding.DecodeString(key["public_key"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubKey, err := mldsa.ParsePublicKey(params, pubKeyBytes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig, "vault:v1:"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !mldsa.Verify(pubKey, []byte("the quick brown fox"), sigBytes, nil) {
+			t.Fatal("signature did not verify with the public key")
+		}
+	}
+
+	// Batch signing
+	resp = request(logical.UpdateOperation, "sign/foo", map[string]interface{}{
+		"batch_input": []interface{}{
+			map[string]interface{}{"input": input},
+			map[string]interface{}{"input": base64.StdEncoding.EncodeToString([]byte("jumps over"))},
+		},
+	})
+	batchResults := resp.Data["batch_results"].([]batchResponseSignItem)
+	if len(batchResults) != 2 || batchResults[0].Signature == "" || batchResults[1].Signature == "" {
+		t.Fatalf("bad batch results %#v", batchResults)
+	}
+	if !verify(base64.StdEncoding.EncodeToString([]byte("jumps over")), batchResults[1].Signature) {
+		t.Fatal("batch signature did not verify")
+	}
+
+	// Signatures of older versions verify after rotation until they are
+	// disallowed
+	request(logical.UpdateOperation, "keys/foo/rotate", nil)
+	resp = request(logical.UpdateOperation, "sign/foo", map[string]interface{}{
+		"input": input,
+	})
+	if !strings.HasPrefix(resp.Data["signature"].(string), "vault:v2:") {
+		t.Fatalf("bad signature %q", resp.Data["signature"])
+	}
+	if !verify(input, sig) {
+		t.Fatal("signature of previous version did not verify")
+	}
+
+	request(logical.UpdateOperation, "keys/foo/config", map[string]interface{}{
+		"min_decryption_version": 2,
+	})
+	resp, err = b.HandleRequest(context.Background(), &logical.Request{
+		Storage:   storage,
+		Operation: logical.UpdateOperation,
+		Path:      "verify/foo",
+		Data: map[string]interface{}{
+			"input":     input,
+			"signature": sig,
+		},
+	})
+	if err == nil && (resp == nil || !resp.IsError()) {
+		t.Fatal("expected error verifying signature of disallowed version")
+	}
+}
@@ -0,0 +1,427 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/keysutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// Streams are encrypted with a random 256-bit data key which is wrapped by
+// the named key, in the same way as keys returned by the datakey endpoint.
+// The framed ciphertext format of a stream is
+//
+//	stream = header || frame_0 || frame_1 || ... || frame_n
+//	header = "vts" || 0x01 || uint16(len(wrapped)) || wrapped
+//	frame  = uint32(len(nonce || sealed)) || nonce || sealed
+//
+// with all integers big endian. wrapped is the data key as encrypted by the
+// named key ("vault:v1:..."), nonce is a random 96-bit nonce and sealed is
+// the chunk encrypted with AES-256-GCM under the data key, using
+//
+//	header || uint64(index) || final
+//
+// as the associated data, where index is the position of the frame in the
+// stream and final is 0x01 for the last frame and 0x00 for all others. This
+// binds every frame to its stream and position, so that frames cannot be
+// reordered, and allows truncated streams to be detected.
+const (
+	streamMagic        = "vts\x01"
+	streamNonceSize    = 12
+	streamDataKeySize  = 32
+	streamMaxChunkSize = 16 * 1024 * 1024
+)
+
+// streamDataKeyAD is the associated data the data key is wrapped with, which
+// separates stream headers from ciphertexts produced by other endpoints.
+var streamDataKeyAD = base64.StdEncoding.EncodeToString([]byte("vault-transit-stream-v1"))
+
+func (b *backend) pathStreamStart() *framework.Path {
+	return &framework.Path{
+		Pattern: "stream/start/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"context": {
+				Type: framework.TypeString,
+				Description: `
+Base64 encoded context for key derivation. Required if key derivation is
+enabled, and must be provided with every chunk of the stream.`,
+			},
+
+			"key_version": {
+				Type: framework.TypeInt,
+				Description: `The version of the key to use for encryption of
+the data key of the stream. Must be 0 (for latest) or a value greater than or
+equal to the min_encryption_version configured on the key.`,
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathStreamStartWrite,
+		},
+
+		HelpSynopsis:    pathStreamStartHelpSyn,
+		HelpDescription: pathStreamHelpDesc,
+	}
+}
+
+func (b *backend) pathStreamEncrypt() *framework.Path {
+	return &framework.Path{
+		Pattern: "stream/encrypt/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"header": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded header of the stream, as returned by stream/start.",
+			},
+
+			"context": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded context for key derivation, as given to stream/start.",
+			},
+
+			"plaintext": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded chunk of the stream to encrypt.",
+			},
+
+			"index": {
+				Type:        framework.TypeInt,
+				Description: "Position of the chunk in the stream, starting at 0.",
+			},
+
+			"final": {
+				Type:        framework.TypeBool,
+				Description: "Whether this is the last chunk of the stream.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathStreamEncryptWrite,
+		},
+
+		HelpSynopsis:    pathStreamEncryptHelpSyn,
+		HelpDescription: pathStreamHelpDesc,
+	}
+}
+
+func (b *backend) pathStreamDecrypt() *framework.Path {
+	return &framework.Path{
+		Pattern: "stream/decrypt/" + framework.GenericNameRegex("name"),
+		Fields: map[string]*framework.FieldSchema{
+			"name": {
+				Type:        framework.TypeString,
+				Description: "Name of the key",
+			},
+
+			"header": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded header of the stream.",
+			},
+
+			"context": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded context for key derivation, as given to stream/start.",
+			},
+
+			"ciphertext": {
+				Type:        framework.TypeString,
+				Description: "Base64 encoded frame of the stream to decrypt, including its length prefix.",
+			},
+
+			"index": {
+				Type:        framework.TypeInt,
+				Description: "Position of the frame in the stream, starting at 0.",
+			},
+		},
+
+		Callbacks: map[logical.Operation]framework.OperationFunc{
+			logical.UpdateOperation: b.pathStreamDecryptWrite,
+		},
+
+		HelpSynopsis:    pathStreamDecryptHelpSyn,
+		HelpDescription: pathStreamHelpDesc,
+	}
+}
+
+func (b *backend) pathStreamStartWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	ver := d.Get("key_version").(int)
+
+	context, err := decodeStreamContext(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	p, resp, err := b.getStreamPolicy(ctx, req, d)
+	if p == nil {
+		return resp, err
+	}
+	defer p.Unlock()
+
+	dataKey := make([]byte, streamDataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := p.EncryptWithFactory(ver, context, nil, base64.StdEncoding.EncodeToString(dataKey), AssocDataFactory{streamDataKeyAD})
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, err
+		}
+	}
+	if wrapped == "" {
+		return nil, fmt.Errorf("empty ciphertext returned")
+	}
+	if len(wrapped) > math.MaxUint16 {
+		return nil, fmt.Errorf("wrapped data key too long")
+	}
+
+	header := make([]byte, 0, len(streamMagic)+2+len(wrapped))
+	header = append(header, streamMagic...)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(wrapped)))
+	header = append(header, wrapped...)
+
+	keyVersion := ver
+	if keyVersion == 0 {
+		keyVersion = p.LatestVersion
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"header":      base64.StdEncoding.EncodeToString(header),
+			"key_version": keyVersion,
+		},
+	}, nil
+}
+
+func (b *backend) pathStreamEncryptWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	index, err := streamIndex(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+	final := d.Get("final").(bool)
+
+	plaintext, err := base64.StdEncoding.DecodeString(d.Get("plaintext").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode plaintext"), logical.ErrInvalidRequest
+	}
+	if len(plaintext) > streamMaxChunkSize {
+		return logical.ErrorResponse(fmt.Sprintf("chunk too large; must be at most %d bytes", streamMaxChunkSize)), logical.ErrInvalidRequest
+	}
+
+	header, aead, resp, err := b.openStreamHeader(ctx, req, d)
+	if aead == nil {
+		return resp, err
+	}
+
+	frame := make([]byte, 4+streamNonceSize, 4+streamNonceSize+len(plaintext)+aead.Overhead())
+	binary.BigEndian.PutUint32(frame, uint32(cap(frame)-4))
+	if _, err := rand.Read(frame[4:]); err != nil {
+		return nil, err
+	}
+	frame = aead.Seal(frame, frame[4:], plaintext, streamChunkAD(header, index, final))
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"ciphertext": base64.StdEncoding.EncodeToString(frame),
+		},
+	}, nil
+}
+
+func (b *backend) pathStreamDecryptWrite(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	index, err := streamIndex(d)
+	if err != nil {
+		return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	frame, err := base64.StdEncoding.DecodeString(d.Get("ciphertext").(string))
+	if err != nil {
+		return logical.ErrorResponse("failed to base64-decode ciphertext"), logical.ErrInvalidRequest
+	}
+	if len(frame) < 4+streamNonceSize || int(binary.BigEndian.Uint32(frame)) != len(frame)-4 {
+		return logical.ErrorResponse("invalid ciphertext: bad frame length"), logical.ErrInvalidRequest
+	}
+
+	header, aead, resp, err := b.openStreamHeader(ctx, req, d)
+	if aead == nil {
+		return resp, err
+	}
+
+	nonce, sealed := frame[4:4+streamNonceSize], frame[4+streamNonceSize:]
+	final := false
+	plaintext, err := aead.Open(nil, nonce, sealed, streamChunkAD(header, index, final))
+	if err != nil {
+		final = true
+		plaintext, err = aead.Open(nil, nonce, sealed, streamChunkAD(header, index, final))
+	}
+	if err != nil {
+		return logical.ErrorResponse("invalid ciphertext: unable to decrypt chunk"), logical.ErrInvalidRequest
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+			"final":     final,
+		},
+	}, nil
+}
+
+// getStreamPolicy returns the read locked policy named in the request. When
+// no policy is returned, the response and error should be returned as is.
+func (b *backend) getStreamPolicy(ctx context.Context, req *logical.Request, d *framework.FieldData) (*keysutil.Policy, *logical.Response, error) {
+	p, _, err := b.GetPolicy(ctx, keysutil.PolicyRequest{
+		Storage: req.Storage,
+		Name:    d.Get("name").(string),
+	}, b.GetRandomReader())
+	if err != nil {
+		return nil, nil, err
+	}
+	if p == nil {
+		return nil, logical.ErrorResponse("encryption key not found"), logical.ErrInvalidRequest
+	}
+	if !p.Type.AssociatedDataSupported() {
+		return nil, logical.ErrorResponse(fmt.Sprintf("streaming encryption not supported for key type %v", p.Type)), logical.ErrInvalidRequest
+	}
+	if !b.System().CachingDisabled() {
+		p.Lock(false)
+	}
+	return p, nil, nil
+}
+
+// openStreamHeader decodes the stream header given in the request and
+// unwraps its data key. When no AEAD is returned, the response and error
+// should be returned as is.
+func (b *backend) openStreamHeader(ctx context.Context, req *logical.Request, d *framework.FieldData) ([]byte, cipher.AEAD, *logical.Response, error) {
+	context, err := decodeStreamContext(d)
+	if err != nil {
+		return nil, nil, logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	header, err := base64.StdEncoding.DecodeString(d.Get("header").(string))
+	if err != nil {
+		return nil, nil, logical.ErrorResponse("failed to base64-decode header"), logical.ErrInvalidRequest
+	}
+	wrapped, err := parseStreamHeader(header)
+	if err != nil {
+		return nil, nil, logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+	}
+
+	p, resp, err := b.getStreamPolicy(ctx, req, d)
+	if p == nil {
+		return nil, nil, resp, err
+	}
+	encodedKey, err := p.DecryptWithFactory(context, nil, wrapped, AssocDataFactory{streamDataKeyAD})
+	p.Unlock()
+	if err != nil {
+		switch err.(type) {
+		case errutil.UserError:
+			return nil, nil, logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		default:
+			return nil, nil, nil, err
+		}
+	}
+
+	dataKey, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(dataKey) != streamDataKeySize {
+		return nil, nil, logical.ErrorResponse("invalid header: bad data key"), logical.ErrInvalidRequest
+	}
+
+	block, err := aes.NewCipher(dataKey)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return header, aead, nil, nil
+}
+
+// parseStreamHeader returns the wrapped data key held by the header.
+func parseStreamHeader(header []byte) (string, error) {
+	if !bytes.HasPrefix(header, []byte(streamMagic)) {
+		return "", errors.New("invalid header: unknown format")
+	}
+	header = header[len(streamMagic):]
+	if len(header) < 2 || int(binary.BigEndian.Uint16(header)) != len(header)-2 {
+		return "", errors.New("invalid header: bad length")
+	}
+	return string(header[2:]), nil
+}
+
+func decodeStreamContext(d *framework.FieldData) ([]byte, error) {
+	contextRaw := d.Get("context").(string)
+	if len(contextRaw) == 0 {
+		return nil, nil
+	}
+	context, err := base64.StdEncoding.DecodeString(contextRaw)
+	if err != nil {
+		return nil, errors.New("failed to base64-decode context")
+	}
+	return context, nil
+}
+
+func streamIndex(d *framework.FieldData) (uint64, error) {
+	index := d.Get("index").(int)
+	if index < 0 || int64(index) > math.MaxUint32 {
+		return 0, errors.New("index out of range")
+	}
+	return uint64(index), nil
+}
+
+func streamChunkAD(header []byte, index uint64, final bool) []byte {
+	ad := make([]byte, 0, len(header)+9)
+	ad = append(ad, header...)
+	ad = binary.BigEndian.AppendUint64(ad, index)
+	if final {
+		return append(ad, 0x01)
+	}
+	return append(ad, 0x00)
+}
+
+const pathStreamStartHelpSyn = `Start encrypting a stream`
+
+const pathStreamEncryptHelpSyn = `Encrypt a chunk of a stream`
+
+const pathStreamDecryptHelpSyn = `Decrypt a frame of a stream`
+
+const pathStreamHelpDesc = `
+These paths encrypt and decrypt payloads too large for a single request, such
+as backups, in chunks of up to 16 MiB. A stream is started by writing to
+stream/start, which returns the header of the stream. It holds a new data key
+protected by the named key, which must be an AEAD key.
+
+The stream is then encrypted by writing each chunk to stream/encrypt with the
+header and the index of the chunk, setting final on the last one. Each call
+returns a length prefixed frame; the header followed by the frames in order
+forms the encrypted stream.
+
+To decrypt, the client reads the header and splits the frames off the stream
+using their big endian uint32 length prefixes, writing each frame to
+stream/decrypt with its index. Decryption reports whether a frame is the final
+one; a stream whose last frame is not final has been truncated, and frames
+following the final frame must be rejected.
+`
@@ -0,0 +1,178 @@
+package transit
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func streamRequest(t *testing.T, b *backend, s logical.Storage, path string, data map[string]interface{}) *logical.Response {
+	t.Helper()
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      path,
+		Storage:   s,
+		Data:      data,
+	})
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err:%v resp:%#v", err, resp)
+	}
+	return resp
+}
+
+func TestTransit_Stream(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	streamRequest(t, b, s, "keys/backup", map[string]interface{}{
+		"derived": true,
+	})
+
+	keyContext := base64.StdEncoding.EncodeToString([]byte("backups/2022-10-01"))
+	resp := streamRequest(t, b, s, "stream/start/backup", map[string]interface{}{
+		"context": keyContext,
+	})
+	header := resp.Data["header"].(string)
+	if resp.Data["key_version"].(int) != 1 {
+		t.Fatalf("bad key version: %v", resp.Data["key_version"])
+	}
+
+	chunks := [][]byte{
+		bytes.Repeat([]byte("a"), 1024),
+		bytes.Repeat([]byte("b"), 1024),
+		[]byte("c"),
+	}
+
+	var stream bytes.Buffer
+	headerBytes, _ := base64.StdEncoding.DecodeString(header)
+	stream.Write(headerBytes)
+	for i, chunk := range chunks {
+		resp := streamRequest(t, b, s, "stream/encrypt/backup", map[string]interface{}{
+			"header":    header,
+			"context":   keyContext,
+			"plaintext": base64.StdEncoding.EncodeToString(chunk),
+			"index":     i,
+			"final":     i == len(chunks)-1,
+		})
+		frame, err := base64.StdEncoding.DecodeString(resp.Data["ciphertext"].(string))
+		if err != nil {
+			t.Fatal(err)
+		}
+		stream.Write(frame)
+	}
+
+	// Split the stream as a client would
+	raw := stream.Bytes()
+	wrappedLen := int(binary.BigEndian.Uint16(raw[len(streamMagic):]))
+	headerLen := len(streamMagic) + 2 + wrappedLen
+	if headerLen != len(headerBytes) {
+		t.Fatalf("bad header length %d", headerLen)
+	}
+	streamHeader := base64.StdEncoding.EncodeToString(raw[:headerLen])
+	raw = raw[headerLen:]
+
+	var frames []string
+	for len(raw) > 0 {
+		frameLen := 4 + int(binary.BigEndian.Uint32(raw))
+		frames = append(frames, base64.StdEncoding.EncodeToString(raw[:frameLen]))
+		raw = raw[frameLen:]
+	}
+	if len(frames) != len(chunks) {
+		t.Fatalf("expected %d frames, got %d", len(chunks), len(frames))
+	}
+
+	for i, frame := range frames {
+		resp := streamRequest(t, b, s, "stream/decrypt/backup", map[string]interface{}{
+			"header":     streamHeader,
+			"context":    keyContext,
+			"ciphertext": frame,
+			"index":      i,
+		})
+		plaintext, _ := base64.StdEncoding.DecodeString(resp.Data["plaintext"].(string))
+		if !bytes.Equal(plaintext, chunks[i]) {
+			t.Fatalf("bad plaintext for frame %d", i)
+		}
+		if resp.Data["final"].(bool) != (i == len(chunks)-1) {
+			t.Fatalf("bad final flag for frame %d", i)
+		}
+	}
+
+	decryptErr := func(data map[string]interface{}) {
+		t.Helper()
+		resp, err := b.HandleRequest(context.Background(), &logical.Request{
+			Operation: logical.UpdateOperation,
+			Path:      "stream/decrypt/backup",
+			Storage:   s,
+			Data:      data,
+		})
+		if err == nil && (resp == nil || !resp.IsError()) {
+			t.Fatalf("expected error, got %#v", resp)
+		}
+	}
+
+	// Reordered frames
+	decryptErr(map[string]interface{}{
+		"header":     streamHeader,
+		"context":    keyContext,
+		"ciphertext": frames[0],
+		"index":      1,
+	})
+
+	// Wrong context
+	decryptErr(map[string]interface{}{
+		"header":     streamHeader,
+		"context":    base64.StdEncoding.EncodeToString([]byte("other")),
+		"ciphertext": frames[0],
+		"index":      0,
+	})
+
+	// Frames of another stream
+	resp = streamRequest(t, b, s, "stream/start/backup", map[string]interface{}{
+		"context": keyContext,
+	})
+	decryptErr(map[string]interface{}{
+		"header":     resp.Data["header"].(string),
+		"context":    keyContext,
+		"ciphertext": frames[0],
+		"index":      0,
+	})
+
+	// Tampered frame
+	frame, _ := base64.StdEncoding.DecodeString(frames[0])
+	frame[len(frame)-1] ^= 0x01
+	decryptErr(map[string]interface{}{
+		"header":     streamHeader,
+		"context":    keyContext,
+		"ciphertext": base64.StdEncoding.EncodeToString(frame),
+		"index":      0,
+	})
+
+	// Truncated frame
+	decryptErr(map[string]interface{}{
+		"header":     streamHeader,
+		"context":    keyContext,
+		"ciphertext": base64.StdEncoding.EncodeToString(frame[:len(frame)-1]),
+		"index":      0,
+	})
+}
+
+func TestTransit_Stream_KeyType(t *testing.T) {
+	b, s := createBackendWithStorage(t)
+
+	streamRequest(t, b, s, "keys/rsa", map[string]interface{}{
+		"type": "rsa-2048",
+	})
+
+	resp, err := b.HandleRequest(context.Background(), &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "stream/start/rsa",
+		Storage:   s,
+	})
+	if err == nil || resp == nil || !resp.IsError() {
+		t.Fatalf("expected error, got: %v, %#v", err, resp)
+	}
+}
@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -36,9 +37,12 @@ import (
 	"github.com/hashicorp/vault/command/agent/cache/cachememdb"
 	"github.com/hashicorp/vault/command/agent/cache/keymanager"
 	agentConfig "github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/command/agent/exec"
 	"github.com/hashicorp/vault/command/agent/sink"
+	commandsink "github.com/hashicorp/vault/command/agent/sink/command"
 	"github.com/hashicorp/vault/command/agent/sink/file"
 	"github.com/hashicorp/vault/command/agent/sink/inmem"
+	"github.com/hashicorp/vault/command/agent/sink/socket"
 	"github.com/hashicorp/vault/command/agent/template"
 	"github.com/hashicorp/vault/command/agent/winsvc"
 	"github.com/hashicorp/vault/helper/metricsutil"
@@ -404,6 +408,42 @@ func (c *AgentCommand) Run(args []string) int {
 				}
 				config.Sink = s
 				sinks = append(sinks, config)
+			case "command":
+				config := &sink.SinkConfig{
+					Logger:    c.logger.Named("sink.command"),
+					Config:    sc.Config,
+					Client:    sinkClient,
+					WrapTTL:   sc.WrapTTL,
+					DHType:    sc.DHType,
+					DeriveKey: sc.DeriveKey,
+					DHPath:    sc.DHPath,
+					AAD:       sc.AAD,
+				}
+				s, err := commandsink.NewCommandSink(config)
+				if err != nil {
+					c.UI.Error(fmt.Errorf("Error creating command sink: %w", err).Error())
+					return 1
+				}
+				config.Sink = s
+				sinks = append(sinks, config)
+			case "socket":
+				config := &sink.SinkConfig{
+					Logger:    c.logger.Named("sink.socket"),
+					Config:    sc.Config,
+					Client:    sinkClient,
+					WrapTTL:   sc.WrapTTL,
+					DHType:    sc.DHType,
+					DeriveKey: sc.DeriveKey,
+					DHPath:    sc.DHPath,
+					AAD:       sc.AAD,
+				}
+				s, err := socket.NewSocketSink(config)
+				if err != nil {
+					c.UI.Error(fmt.Errorf("Error creating socket sink: %w", err).Error())
+					return 1
+				}
+				config.Sink = s
+				sinks = append(sinks, config)
 			default:
 				c.UI.Error(fmt.Sprintf("Unknown sink type %q", sc.Type))
 				return 1
@@ -823,6 +863,7 @@ func (c *AgentCommand) Run(args []string) int {
 	// Start auto-auth and sink servers
 	if method != nil {
 		enableTokenCh := len(config.Templates) > 0
+		enableExecTokenCh := config.Exec != nil && len(config.EnvTemplates) > 0
 
 		// Auth Handler is going to set its own retry values, so we want to
 		// work on a copy of the client to not affect other subsystems.
@@ -848,6 +889,7 @@ func (c *AgentCommand) Run(args []string) int {
 			MaxBackoff:                   config.AutoAuth.Method.MaxBackoff,
 			EnableReauthOnNewCredentials: config.AutoAuth.EnableReauthOnNewCredentials,
 			EnableTemplateTokenCh:        enableTokenCh,
+			EnableExecTokenCh:            enableExecTokenCh,
 			Token:                        previousToken,
 			ExitOnError:                  config.AutoAuth.Method.ExitOnError,
 		})
@@ -921,6 +963,26 @@ func (c *AgentCommand) Run(args []string) int {
 			ts.Stop()
 		})
 
+		if enableExecTokenCh {
+			es := exec.NewServer(&exec.ServerConfig{
+				Logger:      c.logger.Named("exec.server"),
+				LogLevel:    level,
+				LogWriter:   c.logWriter,
+				AgentConfig: config,
+				Namespace:   templateNamespace,
+			})
+
+			g.Add(func() error {
+				return es.Run(ctx, ah.ExecTokenCh)
+			}, func(error) {
+				// Let the lease cache know this is a shutdown; no need to evict
+				// everything
+				if leaseCache != nil {
+					leaseCache.SetShuttingDown(true)
+				}
+				cancelFunc()
+			})
+		}
 	}
 
 	// Server configuration output
@@ -955,6 +1017,13 @@ func (c *AgentCommand) Run(args []string) int {
 	}()
 
 	if err := g.Run(); err != nil {
+		// In exec mode, exit with the exit code of the child process
+		var processExitError *exec.ProcessExitError
+		if errors.As(err, &processExitError) {
+			c.logger.Info("exiting with the exit code of the child process", "exit_code", processExitError.ExitCode)
+			return processExitError.ExitCode
+		}
+
 		c.logger.Error("runtime error encountered", "error", err)
 		c.UI.Error("Error encountered during run, refer to logs for more details.")
 		return 1
@@ -49,6 +49,7 @@ type AuthConfig struct {
 type AuthHandler struct {
 	OutputCh                     chan string
 	TemplateTokenCh              chan string
+	ExecTokenCh                  chan string
 	token                        string
 	logger                       hclog.Logger
 	client                       *api.Client
@@ -58,6 +59,7 @@ type AuthHandler struct {
 	minBackoff                   time.Duration
 	enableReauthOnNewCredentials bool
 	enableTemplateTokenCh        bool
+	enableExecTokenCh            bool
 	exitOnError                  bool
 }
 
@@ -70,6 +72,7 @@ type AuthHandlerConfig struct {
 	Token                        string
 	EnableReauthOnNewCredentials bool
 	EnableTemplateTokenCh        bool
+	EnableExecTokenCh            bool
 	ExitOnError                  bool
 }
 
@@ -79,6 +82,7 @@ func NewAuthHandler(conf *AuthHandlerConfig) *AuthHandler {
 		// has been shut down, during agent shutdown, we won't block
 		OutputCh:                     make(chan string, 1),
 		TemplateTokenCh:              make(chan string, 1),
+		ExecTokenCh:                  make(chan string, 1),
 		token:                        conf.Token,
 		logger:                       conf.Logger,
 		client:                       conf.Client,
@@ -88,6 +92,7 @@ func NewAuthHandler(conf *AuthHandlerConfig) *AuthHandler {
 		maxBackoff:                   conf.MaxBackoff,
 		enableReauthOnNewCredentials: conf.EnableReauthOnNewCredentials,
 		enableTemplateTokenCh:        conf.EnableTemplateTokenCh,
+		enableExecTokenCh:            conf.EnableExecTokenCh,
 		exitOnError:                  conf.ExitOnError,
 	}
 
@@ -130,6 +135,7 @@ func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) error {
 		am.Shutdown()
 		close(ah.OutputCh)
 		close(ah.TemplateTokenCh)
+		close(ah.ExecTokenCh)
 		ah.logger.Info("auth handler stopped")
 	}()
 
@@ -304,6 +310,9 @@ func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) error {
 			if ah.enableTemplateTokenCh {
 				ah.TemplateTokenCh <- string(wrappedResp)
 			}
+			if ah.enableExecTokenCh {
+				ah.ExecTokenCh <- string(wrappedResp)
+			}
 
 			am.CredSuccess()
 			backoffCfg.reset()
@@ -342,6 +351,9 @@ func (ah *AuthHandler) Run(ctx context.Context, am AuthMethod) error {
 			if ah.enableTemplateTokenCh {
 				ah.TemplateTokenCh <- secret.Auth.ClientToken
 			}
+			if ah.enableExecTokenCh {
+				ah.ExecTokenCh <- secret.Auth.ClientToken
+			}
 
 			am.CredSuccess()
 			backoffCfg.reset()
@@ -7,10 +7,13 @@ import (
 	"io/ioutil"
 	"net"
 	"os"
+	"regexp"
 	"strings"
+	"syscall"
 	"time"
 
 	ctconfig "github.com/hashicorp/consul-template/config"
+	ctsignals "github.com/hashicorp/consul-template/signals"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-secure-stdlib/parseutil"
 	"github.com/hashicorp/hcl"
@@ -30,6 +33,8 @@ type Config struct {
 	Vault                       *Vault                     `hcl:"vault"`
 	TemplateConfig              *TemplateConfig            `hcl:"template_config"`
 	Templates                   []*ctconfig.TemplateConfig `hcl:"templates"`
+	Exec                        *ExecConfig                `hcl:"-"`
+	EnvTemplates                []*EnvTemplateConfig       `hcl:"-"`
 	DisableIdleConns            []string                   `hcl:"disable_idle_connections"`
 	DisableIdleConnsCaching     bool                       `hcl:"-"`
 	DisableIdleConnsTemplating  bool                       `hcl:"-"`
@@ -154,6 +159,30 @@ type TemplateConfig struct {
 	StaticSecretRenderInt    time.Duration `hcl:"-"`
 }
 
+// ExecConfig configures the process supervisor mode of the agent, in which
+// it runs a child process with secrets rendered into its environment
+type ExecConfig struct {
+	Command                []string  `mapstructure:"command"`
+	RestartOnSecretChanges string    `mapstructure:"restart_on_secret_changes"`
+	RestartStopSignal      os.Signal `mapstructure:"restart_stop_signal"`
+}
+
+// EnvTemplateConfig is a template whose rendered contents are passed to the
+// child process run in exec mode as an environment variable
+type EnvTemplateConfig struct {
+	Name     string
+	Template *ctconfig.TemplateConfig
+}
+
+const (
+	ExecRestartAlways = "always"
+	ExecRestartNever  = "never"
+)
+
+// envVarNameRegex matches the environment variable names env_template
+// blocks may use
+var envVarNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 func NewConfig() *Config {
 	return &Config{
 		SharedConfig: new(configutil.SharedConfig),
@@ -231,8 +260,30 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("error parsing 'template': %w", err)
 	}
 
+	if err := parseExec(result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'exec': %w", err)
+	}
+
+	if err := parseEnvTemplates(result, list); err != nil {
+		return nil, fmt.Errorf("error parsing 'env_template': %w", err)
+	}
+
+	if result.Exec != nil {
+		if len(result.EnvTemplates) == 0 {
+			return nil, fmt.Errorf("exec requires at least one env_template to be defined")
+		}
+		if result.AutoAuth == nil {
+			return nil, fmt.Errorf("exec requires auto_auth to be configured")
+		}
+		if result.ExitAfterAuth {
+			return nil, fmt.Errorf("exec cannot be used with exit_after_auth")
+		}
+	} else if len(result.EnvTemplates) > 0 {
+		return nil, fmt.Errorf("env_template requires an exec block to be defined")
+	}
+
 	if result.Cache != nil {
-		if len(result.Listeners) < 1 && len(result.Templates) < 1 {
+		if len(result.Listeners) < 1 && len(result.Templates) < 1 && len(result.EnvTemplates) < 1 {
 			return nil, fmt.Errorf("enabling the cache requires at least 1 template or 1 listener to be defined")
 		}
 
@@ -249,7 +300,8 @@ func LoadConfig(path string) (*Config, error) {
 	if result.AutoAuth != nil {
 		if len(result.AutoAuth.Sinks) == 0 &&
 			(result.Cache == nil || !result.Cache.UseAutoAuthToken) &&
-			len(result.Templates) == 0 {
+			len(result.Templates) == 0 &&
+			len(result.EnvTemplates) == 0 {
 			return nil, fmt.Errorf("auto_auth requires at least one sink or at least one template or cache.use_auto_auth_token=true")
 		}
 	}
@@ -752,3 +804,140 @@ func parseTemplates(result *Config, list *ast.ObjectList) error {
 	result.Templates = tcs
 	return nil
 }
+
+func parseExec(result *Config, list *ast.ObjectList) error {
+	name := "exec"
+
+	execList := list.Filter(name)
+	if len(execList.Items) == 0 {
+		return nil
+	}
+
+	if len(execList.Items) > 1 {
+		return fmt.Errorf("at most one %q block is allowed", name)
+	}
+
+	item := execList.Items[0]
+
+	var shadow interface{}
+	if err := hcl.DecodeObject(&shadow, item.Val); err != nil {
+		return fmt.Errorf("error decoding config: %s", err)
+	}
+
+	parsed, ok := shadow.(map[string]interface{})
+	if !ok {
+		return errors.New("error converting config")
+	}
+
+	var execConfig ExecConfig
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToSliceHookFunc(" "),
+			ctsignals.StringToSignalFunc(),
+		),
+		ErrorUnused: true,
+		Result:      &execConfig,
+	})
+	if err != nil {
+		return errors.New("mapstructure decoder creation failed")
+	}
+	if err := decoder.Decode(parsed); err != nil {
+		return err
+	}
+
+	if len(execConfig.Command) == 0 || execConfig.Command[0] == "" {
+		return errors.New("command must be specified")
+	}
+
+	switch execConfig.RestartOnSecretChanges {
+	case "":
+		execConfig.RestartOnSecretChanges = ExecRestartAlways
+	case ExecRestartAlways, ExecRestartNever:
+	default:
+		return fmt.Errorf("invalid value for 'restart_on_secret_changes': %q, must be one of %q or %q",
+			execConfig.RestartOnSecretChanges, ExecRestartAlways, ExecRestartNever)
+	}
+
+	if execConfig.RestartStopSignal == nil || execConfig.RestartStopSignal == ctsignals.SIGNULL {
+		execConfig.RestartStopSignal = syscall.SIGTERM
+	}
+
+	result.Exec = &execConfig
+	return nil
+}
+
+func parseEnvTemplates(result *Config, list *ast.ObjectList) error {
+	name := "env_template"
+
+	envTemplateList := list.Filter(name)
+	if len(envTemplateList.Items) < 1 {
+		return nil
+	}
+
+	var envTemplates []*EnvTemplateConfig
+	seen := make(map[string]bool, len(envTemplateList.Items))
+
+	for _, item := range envTemplateList.Items {
+		if len(item.Keys) != 1 {
+			return errors.New("env_template requires the name of the environment variable")
+		}
+		envVarName := item.Keys[0].Token.Value().(string)
+		if !envVarNameRegex.MatchString(envVarName) {
+			return fmt.Errorf("invalid environment variable name %q", envVarName)
+		}
+		if seen[envVarName] {
+			return fmt.Errorf("duplicate env_template %q", envVarName)
+		}
+		seen[envVarName] = true
+
+		var shadow interface{}
+		if err := hcl.DecodeObject(&shadow, item.Val); err != nil {
+			return fmt.Errorf("error decoding config: %s", err)
+		}
+
+		parsed, ok := shadow.(map[string]interface{})
+		if !ok {
+			return errors.New("error converting config")
+		}
+
+		// The rendered contents only ever end up in the environment of the
+		// child process, so the options dealing with the destination file or
+		// running a command on render do not apply.
+		for _, key := range []string{"destination", "create_dest_dirs", "perms", "backup", "command", "command_timeout", "exec"} {
+			if _, ok := parsed[key]; ok {
+				return fmt.Errorf("%q is not supported in env_template %q", key, envVarName)
+			}
+		}
+
+		// See parseTemplates for why only the last wait stanza is kept.
+		wait, ok := parsed["wait"].([]map[string]interface{})
+		if ok {
+			parsed["wait"] = wait[len(wait)-1]
+		}
+
+		var tc ctconfig.TemplateConfig
+		decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+			DecodeHook: mapstructure.ComposeDecodeHookFunc(
+				ctconfig.StringToWaitDurationHookFunc(),
+				mapstructure.StringToSliceHookFunc(","),
+				mapstructure.StringToTimeDurationHookFunc(),
+			),
+			ErrorUnused: true,
+			Result:      &tc,
+		})
+		if err != nil {
+			return errors.New("mapstructure decoder creation failed")
+		}
+		if err := decoder.Decode(parsed); err != nil {
+			return multierror.Prefix(err, fmt.Sprintf("env_template.%s", envVarName))
+		}
+
+		envTemplates = append(envTemplates, &EnvTemplateConfig{
+			Name:     envVarName,
+			Template: &tc,
+		})
+	}
+
+	result.EnvTemplates = envTemplates
+	return nil
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"syscall"
 	"testing"
 	"time"
 
@@ -1697,3 +1698,81 @@ func TestLoadConfigFile_Bad_Value_Disable_Keep_Alives(t *testing.T) {
 		t.Fatal("should have error, it didn't")
 	}
 }
+
+func TestLoadConfigFile_Exec(t *testing.T) {
+	testCases := map[string]struct {
+		fixturePath          string
+		expectedExec         *ExecConfig
+		expectedEnvTemplates []*EnvTemplateConfig
+	}{
+		"full": {
+			fixturePath: "./test-fixtures/config-exec.hcl",
+			expectedExec: &ExecConfig{
+				Command:                []string{"/path/to/app", "--port", "8080"},
+				RestartOnSecretChanges: "always",
+				RestartStopSignal:      syscall.SIGINT,
+			},
+			expectedEnvTemplates: []*EnvTemplateConfig{
+				{
+					Name: "DB_PASSWORD",
+					Template: &ctconfig.TemplateConfig{
+						Contents:      pointerutil.StringPtr(`{{ with secret "database/creds/app" }}{{ .Data.password }}{{ end }}`),
+						ErrMissingKey: pointerutil.BoolPtr(true),
+					},
+				},
+				{
+					Name: "API_KEY",
+					Template: &ctconfig.TemplateConfig{
+						Source: pointerutil.StringPtr("/path/on/disk/to/api_key.ctmpl"),
+					},
+				},
+			},
+		},
+		"defaults": {
+			fixturePath: "./test-fixtures/config-exec-defaults.hcl",
+			expectedExec: &ExecConfig{
+				Command:                []string{"/path/to/app"},
+				RestartOnSecretChanges: "always",
+				RestartStopSignal:      syscall.SIGTERM,
+			},
+			expectedEnvTemplates: []*EnvTemplateConfig{
+				{
+					Name: "API_KEY",
+					Template: &ctconfig.TemplateConfig{
+						Contents: pointerutil.StringPtr(`{{ with secret "kv/data/app" }}{{ .Data.data.api_key }}{{ end }}`),
+					},
+				},
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			config, err := LoadConfig(tc.fixturePath)
+			if err != nil {
+				t.Fatalf("err: %s", err)
+			}
+
+			if diff := deep.Equal(config.Exec, tc.expectedExec); diff != nil {
+				t.Fatal(diff)
+			}
+			if diff := deep.Equal(config.EnvTemplates, tc.expectedEnvTemplates); diff != nil {
+				t.Fatal(diff)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFile_Bad_Exec(t *testing.T) {
+	for _, fixture := range []string{
+		"./test-fixtures/bad-config-env_template-no-exec.hcl",
+		"./test-fixtures/bad-config-exec-no-env_template.hcl",
+		"./test-fixtures/bad-config-env_template-destination.hcl",
+		"./test-fixtures/bad-config-exec-restart.hcl",
+	} {
+		_, err := LoadConfig(fixture)
+		if err == nil {
+			t.Fatalf("%s: should have error, it didn't", fixture)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type = "aws"
+
+    config = {
+      role = "foobar"
+    }
+  }
+}
+
+exec {
+  command = ["/path/to/app"]
+}
+
+env_template "API_KEY" {
+  contents    = "{{ with secret \"kv/data/app\" }}{{ .Data.data.api_key }}{{ end }}"
+  destination = "/path/on/disk/where/template/will/render.txt"
+}
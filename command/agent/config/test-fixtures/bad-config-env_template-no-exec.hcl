@@ -0,0 +1,15 @@
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type = "aws"
+
+    config = {
+      role = "foobar"
+    }
+  }
+}
+
+env_template "API_KEY" {
+  contents = "{{ with secret \"kv/data/app\" }}{{ .Data.data.api_key }}{{ end }}"
+}
@@ -0,0 +1,21 @@
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type = "aws"
+
+    config = {
+      role = "foobar"
+    }
+  }
+
+  sink "file" {
+    config = {
+      path = "/tmp/file-foo"
+    }
+  }
+}
+
+exec {
+  command = ["/path/to/app"]
+}
@@ -0,0 +1,26 @@
+pid_file = "./pidfile"
+
+auto_auth {
+  method {
+    type = "aws"
+
+    config = {
+      role = "foobar"
+    }
+  }
+}
+
+exec {
+  command                   = ["/path/to/app", "--port", "8080"]
+  restart_on_secret_changes = "always"
+  restart_stop_signal       = "SIGINT"
+}
+
+env_template "DB_PASSWORD" {
+  contents             = "{{ with secret \"database/creds/app\" }}{{ .Data.password }}{{ end }}"
+  error_on_missing_key = true
+}
+
+env_template "API_KEY" {
+  source = "/path/on/disk/to/api_key.ctmpl"
+}
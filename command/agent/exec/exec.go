@@ -0,0 +1,321 @@
+// Package exec is responsible for running a child process with secrets
+// rendered into its environment. The Server type renders the configured
+// environment variable templates using a Consul Template Runner in dry mode,
+// starts the child process once all of them have been rendered, and restarts
+// it whenever the rendered values change.
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul-template/child"
+	ctconfig "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/consul-template/manager"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-uuid"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/command/agent/template"
+	"github.com/hashicorp/vault/sdk/helper/pointerutil"
+	"github.com/hashicorp/vault/sdk/helper/strutil"
+)
+
+// childKillTimeout is how long the child process is given to exit after
+// being sent the stop signal, before it is killed.
+const childKillTimeout = 30 * time.Second
+
+// ServerConfig is a config struct for setting up the exec Server
+type ServerConfig struct {
+	Logger      hclog.Logger
+	AgentConfig *config.Config
+
+	Namespace string
+
+	// LogLevel and LogWriter are used to set up the logging of the internal
+	// Consul Template Runner, see template.ServerConfig.
+	LogLevel  hclog.Level
+	LogWriter io.Writer
+
+	// Stdout and Stderr are the streams the output of the child process is
+	// written to. They default to the streams of the agent.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// ProcessExitError is returned by Run when the child process exited on its
+// own, so that the agent can exit with the same code.
+type ProcessExitError struct {
+	ExitCode int
+}
+
+func (e *ProcessExitError) Error() string {
+	return fmt.Sprintf("process exited with %d", e.ExitCode)
+}
+
+// Server manages the child process and the Consul Template Runner rendering
+// its environment
+type Server struct {
+	config *ServerConfig
+	logger hclog.Logger
+
+	runner *manager.Runner
+
+	// renderDir is the nonexistent directory the destinations of the
+	// templates point to. Rendering in dry mode never writes to them, but
+	// the names of the files identify the environment variables.
+	renderDir string
+
+	// childProcess is the running child process, if any, and
+	// childProcessExitCh the channel its exit code is delivered on. The
+	// channel is closed without a value when the process is stopped by us.
+	childProcess       *child.Child
+	childProcessExitCh <-chan int
+
+	// lastRenderedEnv holds the sorted NAME=value pairs the child process
+	// was last started with.
+	lastRenderedEnv []string
+}
+
+// NewServer returns a new configured server
+func NewServer(conf *ServerConfig) *Server {
+	return &Server{
+		config: conf,
+		logger: conf.Logger,
+	}
+}
+
+// Run starts rendering the environment variable templates with the tokens
+// received from the auth handler, and manages the child process until the
+// context is canceled or the child process exits on its own. In the latter
+// case a *ProcessExitError is returned.
+func (s *Server) Run(ctx context.Context, incoming chan string) error {
+	if incoming == nil {
+		return errors.New("exec server: incoming channel is nil")
+	}
+
+	s.logger.Info("starting exec server")
+	defer func() {
+		s.stopChildProcess()
+		s.logger.Info("exec server stopped")
+	}()
+
+	execConfig := s.config.AgentConfig.Exec
+	envTemplates := s.config.AgentConfig.EnvTemplates
+	if execConfig == nil || len(envTemplates) == 0 {
+		s.logger.Info("no exec configuration or env templates found")
+		<-ctx.Done()
+		return nil
+	}
+
+	renderID, err := uuid.GenerateUUID()
+	if err != nil {
+		return err
+	}
+	s.renderDir = filepath.Join(os.TempDir(), "vault-agent-exec-"+renderID)
+
+	templates := make(ctconfig.TemplateConfigs, 0, len(envTemplates))
+	for _, envTemplate := range envTemplates {
+		tc := envTemplate.Template.Copy()
+		tc.Destination = pointerutil.StringPtr(filepath.Join(s.renderDir, envTemplate.Name))
+		templates = append(templates, tc)
+	}
+
+	runnerConfig, err := template.NewRunnerConfig(&template.ServerConfig{
+		Logger:      s.logger,
+		AgentConfig: s.config.AgentConfig,
+		Namespace:   s.config.Namespace,
+		LogLevel:    s.config.LogLevel,
+		LogWriter:   s.config.LogWriter,
+	}, templates)
+	if err != nil {
+		return fmt.Errorf("exec server failed to generate runner config: %w", err)
+	}
+
+	if err := s.newRunner(runnerConfig); err != nil {
+		return err
+	}
+
+	latestToken := new(string)
+	for {
+		select {
+		case <-ctx.Done():
+			s.runner.Stop()
+			return nil
+
+		case token, ok := <-incoming:
+			if !ok {
+				incoming = nil
+				continue
+			}
+			if token == *latestToken {
+				continue
+			}
+			s.logger.Info("exec server received new token")
+
+			s.runner.Stop()
+			*latestToken = token
+			runnerConfig = runnerConfig.Merge(&ctconfig.Config{
+				Vault: &ctconfig.VaultConfig{
+					Token: latestToken,
+				},
+			})
+			if err := s.newRunner(runnerConfig); err != nil {
+				s.logger.Error("exec server failed with new Vault token", "error", err)
+				continue
+			}
+			go s.runner.Start()
+
+		case err := <-s.runner.ErrCh:
+			s.logger.Error("template error", "error", err.Error())
+			s.runner.StopImmediately()
+
+			if s.config.AgentConfig.TemplateConfig != nil && s.config.AgentConfig.TemplateConfig.ExitOnRetryFailure {
+				return fmt.Errorf("exec server: %w", err)
+			}
+
+			if err := s.newRunner(runnerConfig); err != nil {
+				return err
+			}
+			go s.runner.Start()
+
+		case <-s.runner.TemplateRenderedCh():
+			env, done := s.renderedEnv()
+			if !done {
+				continue
+			}
+			if strutil.EquivalentSlices(env, s.lastRenderedEnv) {
+				continue
+			}
+
+			if s.childProcess != nil && execConfig.RestartOnSecretChanges == config.ExecRestartNever {
+				s.logger.Info("secrets changed, but not restarting the child process as restart_on_secret_changes is set to never")
+				continue
+			}
+
+			if err := s.restartChildProcess(env); err != nil {
+				s.runner.Stop()
+				return fmt.Errorf("exec server failed to start the child process: %w", err)
+			}
+			s.lastRenderedEnv = env
+
+		case code, ok := <-s.childProcessExitCh:
+			if !ok {
+				// Stopped by us
+				s.childProcessExitCh = nil
+				continue
+			}
+
+			s.logger.Info("child process exited", "exit_code", code)
+			s.childProcess = nil
+			s.childProcessExitCh = nil
+			s.runner.Stop()
+			return &ProcessExitError{ExitCode: code}
+		}
+	}
+}
+
+// newRunner replaces the runner with a new dry mode runner for the given
+// configuration.
+func (s *Server) newRunner(runnerConfig *ctconfig.Config) error {
+	runner, err := manager.NewRunner(runnerConfig, true)
+	if err != nil {
+		return fmt.Errorf("exec server failed to create runner: %w", err)
+	}
+
+	// Dry mode writes the rendered contents to the out stream; they are
+	// read from the render events instead.
+	runner.SetOutStream(io.Discard)
+	s.runner = runner
+	return nil
+}
+
+// renderedEnv returns the environment variables rendered by the runner,
+// reporting whether all of the templates have been rendered yet.
+func (s *Server) renderedEnv() ([]string, bool) {
+	events := s.runner.RenderEvents()
+	idMap := s.runner.TemplateConfigMapping()
+	if len(events) < len(idMap) {
+		return nil, false
+	}
+
+	var env []string
+	for id, tcs := range idMap {
+		event, ok := events[id]
+		if !ok || event.LastWouldRender.IsZero() {
+			return nil, false
+		}
+
+		// Templates with identical contents share the same ID and thus the
+		// same render event.
+		for _, tc := range tcs {
+			name := filepath.Base(ctconfig.StringVal(tc.Destination))
+			env = append(env, name+"="+string(event.Contents))
+		}
+	}
+
+	sort.Strings(env)
+	return env, true
+}
+
+// restartChildProcess stops the running child process, if any, and starts it
+// again with the given environment variables on top of the environment of
+// the agent.
+func (s *Server) restartChildProcess(env []string) error {
+	execConfig := s.config.AgentConfig.Exec
+
+	if s.childProcess != nil {
+		s.logger.Info("restarting the child process as secrets changed", "signal", execConfig.RestartStopSignal)
+		s.stopChildProcess()
+	}
+
+	stdout, stderr := s.config.Stdout, s.config.Stderr
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+	if stderr == nil {
+		stderr = os.Stderr
+	}
+
+	proc, err := child.New(&child.NewInput{
+		Stdin:       os.Stdin,
+		Stdout:      stdout,
+		Stderr:      stderr,
+		Command:     execConfig.Command[0],
+		Args:        execConfig.Command[1:],
+		Env:         append(os.Environ(), env...),
+		KillSignal:  execConfig.RestartStopSignal,
+		KillTimeout: childKillTimeout,
+		Setpgid:     true,
+		Logger: s.logger.StandardLogger(&hclog.StandardLoggerOptions{
+			InferLevels: true,
+		}),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := proc.Start(); err != nil {
+		return err
+	}
+	s.logger.Info("started the child process", "pid", proc.Pid())
+
+	s.childProcess = proc
+	s.childProcessExitCh = proc.ExitCh()
+	return nil
+}
+
+func (s *Server) stopChildProcess() {
+	if s.childProcess == nil {
+		return
+	}
+
+	s.childProcess.Stop()
+	s.childProcess = nil
+	s.childProcessExitCh = nil
+}
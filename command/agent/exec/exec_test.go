@@ -0,0 +1,254 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	ctconfig "github.com/hashicorp/consul-template/config"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/config"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+	"github.com/hashicorp/vault/sdk/helper/pointerutil"
+)
+
+const (
+	// The test binary doubles as the child process when these are set, see
+	// TestMain.
+	helperOutEnv      = "VAULT_AGENT_EXEC_TEST_HELPER_OUT"
+	helperExitCodeEnv = "VAULT_AGENT_EXEC_TEST_HELPER_EXIT_CODE"
+)
+
+func TestMain(m *testing.M) {
+	if out := os.Getenv(helperOutEnv); out != "" {
+		runHelper(out)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+// runHelper records its pid and the rendered secret, then either exits with
+// the requested code or waits to be stopped.
+func runHelper(out string) {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+
+	f, err := os.OpenFile(out, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		os.Exit(100)
+	}
+	fmt.Fprintf(f, "%d %s\n", os.Getpid(), os.Getenv("MY_SECRET"))
+	f.Close()
+
+	if code := os.Getenv(helperExitCodeEnv); code != "" {
+		exitCode, _ := strconv.Atoi(code)
+		os.Exit(exitCode)
+	}
+
+	<-sigCh
+	os.Exit(0)
+}
+
+type testVault struct {
+	sync.Mutex
+	password string
+}
+
+func (v *testVault) setPassword(password string) {
+	v.Lock()
+	defer v.Unlock()
+	v.password = password
+}
+
+func (v *testVault) server() *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/kv/myapp/config", func(w http.ResponseWriter, r *http.Request) {
+		v.Lock()
+		defer v.Unlock()
+		fmt.Fprintf(w, `{"lease_duration": 0, "data": {"data": {"password": %q}}}`, v.password)
+	})
+	return httptest.NewServer(mux)
+}
+
+func newTestServer(t *testing.T, vaultAddr string, execConfig *config.ExecConfig) *Server {
+	t.Helper()
+
+	return NewServer(&ServerConfig{
+		Logger: logging.NewVaultLogger(hclog.Trace),
+		AgentConfig: &config.Config{
+			Vault: &config.Vault{
+				Address: vaultAddr,
+				Retry: &config.Retry{
+					NumRetries: 3,
+				},
+			},
+			TemplateConfig: &config.TemplateConfig{
+				ExitOnRetryFailure:    true,
+				StaticSecretRenderInt: time.Second,
+			},
+			Exec: execConfig,
+			EnvTemplates: []*config.EnvTemplateConfig{
+				{
+					Name: "MY_SECRET",
+					Template: &ctconfig.TemplateConfig{
+						Contents: pointerutil.StringPtr(`{{ with secret "kv/myapp/config" }}{{ .Data.data.password }}{{ end }}`),
+					},
+				},
+			},
+		},
+		LogLevel:  hclog.Trace,
+		LogWriter: hclog.DefaultOutput,
+	})
+}
+
+// waitForLines waits until the helper output file has n lines, returning
+// them.
+func waitForLines(t *testing.T, out string, n int) []string {
+	t.Helper()
+
+	deadline := time.Now().Add(20 * time.Second)
+	for time.Now().Before(deadline) {
+		content, _ := os.ReadFile(out)
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		if len(content) > 0 && len(lines) >= n {
+			return lines
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for %d child process starts", n)
+	return nil
+}
+
+func TestServer_Run_Restart(t *testing.T) {
+	vault := &testVault{password: "first"}
+	ts := vault.server()
+	defer ts.Close()
+
+	out := filepath.Join(t.TempDir(), "out")
+	t.Setenv(helperOutEnv, out)
+
+	server := newTestServer(t, ts.URL, &config.ExecConfig{
+		Command:                []string{os.Args[0]},
+		RestartOnSecretChanges: config.ExecRestartAlways,
+		RestartStopSignal:      syscall.SIGTERM,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tokenCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Run(ctx, tokenCh)
+	}()
+	tokenCh <- "test"
+
+	lines := waitForLines(t, out, 1)
+	if !strings.HasSuffix(lines[0], " first") {
+		t.Fatalf("unexpected child process environment: %q", lines[0])
+	}
+
+	vault.setPassword("second")
+	lines = waitForLines(t, out, 2)
+	if !strings.HasSuffix(lines[1], " second") {
+		t.Fatalf("unexpected child process environment: %q", lines[1])
+	}
+	if strings.Fields(lines[0])[0] == strings.Fields(lines[1])[0] {
+		t.Fatalf("expected the child process to be restarted: %q", lines)
+	}
+
+	cancel()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	case <-time.After(20 * time.Second):
+		t.Fatal("timed out waiting for the server to stop")
+	}
+}
+
+func TestServer_Run_NoRestart(t *testing.T) {
+	vault := &testVault{password: "first"}
+	ts := vault.server()
+	defer ts.Close()
+
+	out := filepath.Join(t.TempDir(), "out")
+	t.Setenv(helperOutEnv, out)
+
+	server := newTestServer(t, ts.URL, &config.ExecConfig{
+		Command:                []string{os.Args[0]},
+		RestartOnSecretChanges: config.ExecRestartNever,
+		RestartStopSignal:      syscall.SIGTERM,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tokenCh := make(chan string, 1)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.Run(ctx, tokenCh)
+	}()
+	tokenCh <- "test"
+
+	waitForLines(t, out, 1)
+	vault.setPassword("second")
+
+	// Give the secret a few chances to be re-rendered.
+	time.Sleep(3 * time.Second)
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Split(strings.TrimSpace(string(content)), "\n"); len(lines) != 1 {
+		t.Fatalf("expected the child process not to be restarted: %q", lines)
+	}
+
+	cancel()
+	if err := <-errCh; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestServer_Run_ExitCode(t *testing.T) {
+	vault := &testVault{password: "first"}
+	ts := vault.server()
+	defer ts.Close()
+
+	out := filepath.Join(t.TempDir(), "out")
+	t.Setenv(helperOutEnv, out)
+	t.Setenv(helperExitCodeEnv, "3")
+
+	server := newTestServer(t, ts.URL, &config.ExecConfig{
+		Command:                []string{os.Args[0]},
+		RestartOnSecretChanges: config.ExecRestartAlways,
+		RestartStopSignal:      syscall.SIGTERM,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	tokenCh := make(chan string, 1)
+	tokenCh <- "test"
+
+	err := server.Run(ctx, tokenCh)
+	var processExitError *ProcessExitError
+	if !errors.As(err, &processExitError) {
+		t.Fatalf("expected a process exit error, got: %v", err)
+	}
+	if processExitError.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %d", processExitError.ExitCode)
+	}
+}
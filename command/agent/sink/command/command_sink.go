@@ -0,0 +1,143 @@
+package command
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/command/agent/sink"
+)
+
+const (
+	defaultTimeout = 30 * time.Second
+
+	// maxOutputSize bounds how much of the output of the command is kept
+	// for logging and error messages.
+	maxOutputSize = 4096
+)
+
+// commandSink is a Sink implementation that hands a token to a command by
+// writing it to the standard input of the command
+type commandSink struct {
+	command []string
+	timeout time.Duration
+	logger  hclog.Logger
+}
+
+// NewCommandSink creates a new command sink with the given configuration
+func NewCommandSink(conf *sink.SinkConfig) (sink.Sink, error) {
+	if conf.Logger == nil {
+		return nil, errors.New("nil logger provided")
+	}
+
+	conf.Logger.Info("creating command sink")
+
+	c := &commandSink{
+		logger:  conf.Logger,
+		timeout: defaultTimeout,
+	}
+
+	commandRaw, ok := conf.Config["command"]
+	if !ok {
+		return nil, errors.New("'command' not specified for command sink")
+	}
+	switch command := commandRaw.(type) {
+	case string:
+		c.command = strings.Fields(command)
+	case []string:
+		c.command = command
+	case []interface{}:
+		for _, arg := range command {
+			argStr, ok := arg.(string)
+			if !ok {
+				return nil, errors.New("could not parse 'command' as a list of strings")
+			}
+			c.command = append(c.command, argStr)
+		}
+	default:
+		return nil, errors.New("could not parse 'command' as a string or list of strings")
+	}
+	if len(c.command) == 0 || c.command[0] == "" {
+		return nil, errors.New("'command' must not be empty")
+	}
+
+	if timeoutRaw, ok := conf.Config["timeout"]; ok {
+		timeout, err := parseutil.ParseDurationSecond(timeoutRaw)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse 'timeout': %w", err)
+		}
+		if timeout <= 0 {
+			return nil, errors.New("'timeout' must be positive")
+		}
+		c.timeout = timeout
+	}
+
+	if _, err := exec.LookPath(c.command[0]); err != nil {
+		return nil, fmt.Errorf("error finding command %q: %w", c.command[0], err)
+	}
+
+	c.logger.Info("command sink configured", "command", c.command[0], "timeout", c.timeout)
+
+	return c, nil
+}
+
+// WriteToken implements the Server interface and runs the configured command
+// with the token on its standard input. The token is never passed as an
+// argument or in the environment, where other processes could observe it.
+// A blank token is ignored.
+func (c *commandSink) WriteToken(token string) error {
+	c.logger.Trace("enter write_token", "command", c.command[0])
+	defer c.logger.Trace("exit write_token", "command", c.command[0])
+
+	if token == "" {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	output := &limitedBuffer{limit: maxOutputSize}
+	cmd := exec.CommandContext(ctx, c.command[0], c.command[1:]...)
+	cmd.Stdin = strings.NewReader(token)
+	cmd.Stdout = output
+	cmd.Stderr = output
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command %q did not complete within %s", c.command[0], c.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("error running command %q: %w: %s", c.command[0], err, strings.TrimSpace(output.String()))
+	}
+
+	if output.Len() > 0 {
+		c.logger.Debug("command output", "command", c.command[0], "output", strings.TrimSpace(output.String()))
+	}
+
+	c.logger.Info("token written", "command", c.command[0])
+	return nil
+}
+
+// limitedBuffer is a bytes.Buffer which silently discards anything written
+// past its limit.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := b.limit - b.Len(); remaining > 0 {
+		if len(p) > remaining {
+			b.Buffer.Write(p[:remaining])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
@@ -0,0 +1,99 @@
+package command
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+)
+
+func testCommandSink(t *testing.T, config map[string]interface{}) sink.Sink {
+	t.Helper()
+
+	s, err := NewCommandSink(&sink.SinkConfig{
+		Logger: logging.NewVaultLogger(hclog.Trace).Named("sink.command"),
+		Config: config,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestCommandSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+
+	s := testCommandSink(t, map[string]interface{}{
+		"command": []interface{}{"sh", "-c", `cat > "$0"`, path},
+	})
+
+	// A blank token does not run the command
+	if err := s.WriteToken(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the command not to be run, got: %v", err)
+	}
+
+	if err := s.WriteToken("foo"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "foo" {
+		t.Fatalf("expected foo, got %q", string(b))
+	}
+}
+
+func TestCommandSink_Error(t *testing.T) {
+	s := testCommandSink(t, map[string]interface{}{
+		"command": []interface{}{"sh", "-c", "echo refused >&2; exit 3"},
+	})
+
+	err := s.WriteToken("foo")
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "exit status 3") || !strings.Contains(err.Error(), "refused") {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestCommandSink_Timeout(t *testing.T) {
+	s := testCommandSink(t, map[string]interface{}{
+		"command": "sleep 10",
+		"timeout": "100ms",
+	})
+
+	err := s.WriteToken("foo")
+	if err == nil || !strings.Contains(err.Error(), "did not complete") {
+		t.Fatalf("expected timeout error, got: %v", err)
+	}
+}
+
+func TestCommandSink_Config(t *testing.T) {
+	log := logging.NewVaultLogger(hclog.Trace)
+
+	for _, c := range []map[string]interface{}{
+		{},
+		{"command": ""},
+		{"command": []interface{}{}},
+		{"command": []interface{}{1}},
+		{"command": "vault-agent-nonexistent-command"},
+		{"command": "true", "timeout": "0s"},
+	} {
+		_, err := NewCommandSink(&sink.SinkConfig{
+			Logger: log.Named("sink.command"),
+			Config: c,
+		})
+		if err == nil {
+			t.Fatalf("expected error for config %#v", c)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package socket
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/command/agent/sink"
+)
+
+const defaultTimeout = 10 * time.Second
+
+// socketSink is a Sink implementation that writes a token to a consumer
+// listening on a Unix domain socket
+type socketSink struct {
+	path    string
+	timeout time.Duration
+	logger  hclog.Logger
+}
+
+// NewSocketSink creates a new socket sink with the given configuration
+func NewSocketSink(conf *sink.SinkConfig) (sink.Sink, error) {
+	if conf.Logger == nil {
+		return nil, errors.New("nil logger provided")
+	}
+
+	conf.Logger.Info("creating socket sink")
+
+	s := &socketSink{
+		logger:  conf.Logger,
+		timeout: defaultTimeout,
+	}
+
+	pathRaw, ok := conf.Config["path"]
+	if !ok {
+		return nil, errors.New("'path' not specified for socket sink")
+	}
+	path, ok := pathRaw.(string)
+	if !ok {
+		return nil, errors.New("could not parse 'path' as string")
+	}
+	if path == "" {
+		return nil, errors.New("'path' must not be empty")
+	}
+
+	s.path = path
+
+	if timeoutRaw, ok := conf.Config["timeout"]; ok {
+		timeout, err := parseutil.ParseDurationSecond(timeoutRaw)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse 'timeout': %w", err)
+		}
+		if timeout <= 0 {
+			return nil, errors.New("'timeout' must be positive")
+		}
+		s.timeout = timeout
+	}
+
+	// Unlike the file sink no write check is performed, as the consumer may
+	// not be listening yet. Failed writes are retried by the sink server.
+
+	s.logger.Info("socket sink configured", "path", s.path, "timeout", s.timeout)
+
+	return s, nil
+}
+
+// WriteToken implements the Server interface and writes the token to the
+// consumer listening on the socket. A new connection is made for every token,
+// which is closed once the token has been written. A blank token is ignored.
+func (s *socketSink) WriteToken(token string) error {
+	s.logger.Trace("enter write_token", "path", s.path)
+	defer s.logger.Trace("exit write_token", "path", s.path)
+
+	if token == "" {
+		return nil
+	}
+
+	conn, err := net.DialTimeout("unix", s.path, s.timeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to socket: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetWriteDeadline(time.Now().Add(s.timeout)); err != nil {
+		return fmt.Errorf("error setting write deadline: %w", err)
+	}
+	if _, err := conn.Write([]byte(token)); err != nil {
+		return fmt.Errorf("error writing token to socket: %w", err)
+	}
+	if err := conn.Close(); err != nil {
+		return fmt.Errorf("error closing socket connection: %w", err)
+	}
+
+	s.logger.Info("token written", "path", s.path)
+	return nil
+}
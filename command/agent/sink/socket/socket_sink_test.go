@@ -0,0 +1,83 @@
+package socket
+
+import (
+	"io"
+	"net"
+	"path/filepath"
+	"testing"
+
+	hclog "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/command/agent/sink"
+	"github.com/hashicorp/vault/sdk/helper/logging"
+)
+
+func TestSocketSink(t *testing.T) {
+	log := logging.NewVaultLogger(hclog.Trace)
+
+	path := filepath.Join(t.TempDir(), "agent.sock")
+
+	config := &sink.SinkConfig{
+		Logger: log.Named("sink.socket"),
+		Config: map[string]interface{}{
+			"path":    path,
+			"timeout": "2s",
+		},
+	}
+	s, err := NewSocketSink(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing is listening yet
+	if err := s.WriteToken("foo"); err == nil {
+		t.Fatal("expected error writing to a socket without a consumer")
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			b, _ := io.ReadAll(conn)
+			conn.Close()
+			received <- string(b)
+		}
+	}()
+
+	for _, token := range []string{"foo", "bar"} {
+		if err := s.WriteToken(token); err != nil {
+			t.Fatal(err)
+		}
+		if actual := <-received; actual != token {
+			t.Fatalf("expected %q, got %q", token, actual)
+		}
+	}
+}
+
+func TestSocketSink_Config(t *testing.T) {
+	log := logging.NewVaultLogger(hclog.Trace)
+
+	for _, c := range []map[string]interface{}{
+		{},
+		{"path": 1},
+		{"path": ""},
+		{"path": "/tmp/agent.sock", "timeout": "-1s"},
+		{"path": "/tmp/agent.sock", "timeout": "bad"},
+	} {
+		_, err := NewSocketSink(&sink.SinkConfig{
+			Logger: log.Named("sink.socket"),
+			Config: c,
+		})
+		if err == nil {
+			t.Fatalf("expected error for config %#v", c)
+		}
+	}
+}
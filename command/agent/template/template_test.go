@@ -151,7 +151,7 @@ func TestCacheConfig(t *testing.T) {
 			}
 			serverConfig := ServerConfig{AgentConfig: agentConfig}
 
-			ctConfig, err := newRunnerConfig(&serverConfig, ctconfig.TemplateConfigs{})
+			ctConfig, err := NewRunnerConfig(&serverConfig, ctconfig.TemplateConfigs{})
 			if len(tc.expectedErr) > 0 {
 				require.Error(t, err, tc.expectedErr)
 				return
@@ -179,7 +179,7 @@ func TestCacheConfigNoListener(t *testing.T) {
 	agentConfig.Cache.InProcDialer = listenerutil.NewBufConnWrapper(bListener)
 	serverConfig := ServerConfig{AgentConfig: agentConfig}
 
-	ctConfig, err := newRunnerConfig(&serverConfig, ctconfig.TemplateConfigs{})
+	ctConfig, err := NewRunnerConfig(&serverConfig, ctconfig.TemplateConfigs{})
 	if err != nil {
 		t.Fatalf("unexpected error: %s", err)
 	}
@@ -131,6 +131,37 @@ func (g *StringGenerator) generate(rng io.Reader) (str string, err error) {
 	return string(candidate), nil
 }
 
+// Validate a string that wasn't generated by this generator, such as a user-chosen password, against the
+// generator's length, charset and rules. The string may be longer than the configured length.
+func (g *StringGenerator) Validate(value string) error {
+	err := g.validateConfig()
+	if err != nil {
+		return err
+	}
+
+	candidate := []rune(value)
+	if len(candidate) < g.Length {
+		return fmt.Errorf("must be at least %d characters long", g.Length)
+	}
+	for _, r := range candidate {
+		if !charIn(r, g.charset) {
+			return fmt.Errorf("contains characters outside of the allowed charset %q", string(g.charset))
+		}
+	}
+
+	for _, rule := range g.Rules {
+		if rule.Pass(candidate) {
+			continue
+		}
+		if cr, ok := rule.(CharsetRule); ok {
+			return fmt.Errorf("must contain at least %d of the characters %q", cr.MinChars, string(cr.Charset))
+		}
+		return fmt.Errorf("does not satisfy %s rule", rule.Type())
+	}
+
+	return nil
+}
+
 const (
 	// maxCharsetLen is the maximum length a charset is allowed to be when generating a candidate string.
 	// This is the total number of numbers available for selecting an index out of the charset slice.
@@ -632,6 +632,47 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestStringGenerator_Validate(t *testing.T) {
+	generator := &StringGenerator{
+		Length: 8,
+		Rules: []Rule{
+			CharsetRule{
+				Charset:  LowercaseRuneset,
+				MinChars: 1,
+			},
+			CharsetRule{
+				Charset:  NumericRuneset,
+				MinChars: 2,
+			},
+		},
+	}
+
+	tests := map[string]struct {
+		value     string
+		expectErr bool
+	}{
+		"valid":              {value: "abcdef12", expectErr: false},
+		"longer than length": {value: "abcdefghijkl123", expectErr: false},
+		"too short":          {value: "abcde12", expectErr: true},
+		"outside charset":    {value: "abcdef12!", expectErr: true},
+		"not enough numbers": {value: "abcdefg1", expectErr: true},
+		"missing lowercase":  {value: "12345678", expectErr: true},
+		"empty":              {value: "", expectErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := generator.Validate(test.value)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+		})
+	}
+}
+
 type testNonCharsetRule struct {
 	String string `mapstructure:"string" json:"string"`
 }
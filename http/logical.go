@@ -102,11 +102,12 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 		bufferedBody := newBufferedReader(r.Body)
 		r.Body = bufferedBody
 
-		// If we are uploading a snapshot or receiving an ocsp-request (which
-		// is der encoded) we don't want to parse it. Instead, we will simply
-		// add the HTTP request to the logical request object for later consumption.
+		// If we are uploading a snapshot, receiving an ocsp-request (which
+		// is der encoded) or an EST pkcs10 request (which is base64 encoded)
+		// we don't want to parse it. Instead, we will simply add the HTTP
+		// request to the logical request object for later consumption.
 		contentType := r.Header.Get("Content-Type")
-		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || isOcspRequest(contentType) {
+		if path == "sys/storage/raft/snapshot" || path == "sys/storage/raft/snapshot-force" || isOcspRequest(contentType) || isEstRequest(contentType) {
 			passHTTPReq = true
 			origBody = r.Body
 		} else {
@@ -180,7 +181,11 @@ func buildLogicalRequestNoAuth(perfStandby bool, w http.ResponseWriter, r *http.
 
 		data = parseQuery(r.URL.Query())
 
-	case "OPTIONS", "HEAD":
+	case "HEAD":
+		op = logical.HeaderOperation
+		data = parseQuery(r.URL.Query())
+
+	case "OPTIONS":
 	default:
 		return nil, nil, http.StatusMethodNotAllowed, nil
 	}
@@ -218,6 +223,15 @@ func isOcspRequest(contentType string) bool {
 	return contentType == "application/ocsp-request"
 }
 
+func isEstRequest(contentType string) bool {
+	contentType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return contentType == "application/pkcs10"
+}
+
 func buildLogicalPath(r *http.Request) (string, int, error) {
 	ns, err := namespace.FromContext(r.Context())
 	if err != nil {
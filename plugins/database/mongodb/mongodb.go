@@ -7,6 +7,7 @@ import (
 	"io"
 	"strings"
 
+	"github.com/go-ldap/ldap/v3"
 	log "github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
@@ -22,6 +23,10 @@ const (
 	mongoDBTypeName = "mongodb"
 
 	defaultUserNameTemplate = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 15) (.RoleName | truncate 15) (random 20) (unix_time) | replace "." "-" | truncate 100 }}`
+
+	// externalDB is the database of users authenticating with client
+	// certificates
+	externalDB = "$external"
 )
 
 // MongoDB is an implementation of Database interface
@@ -106,6 +111,10 @@ func (m *MongoDB) Initialize(ctx context.Context, req dbplugin.InitializeRequest
 	resp := dbplugin.InitializeResponse{
 		Config: req.Config,
 	}
+	resp.SetSupportedCredentialTypes([]dbplugin.CredentialType{
+		dbplugin.CredentialTypePassword,
+		dbplugin.CredentialTypeClientCertificate,
+	})
 	return resp, nil
 }
 
@@ -114,18 +123,30 @@ func (m *MongoDB) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (dbp
 		return dbplugin.NewUserResponse{}, dbutil.ErrEmptyCreationStatement
 	}
 
-	username, err := m.usernameProducer.Generate(req.UsernameConfig)
-	if err != nil {
-		return dbplugin.NewUserResponse{}, err
-	}
-
 	// Unmarshal statements.CreationStatements into mongodbRoles
 	var mongoCS mongoDBStatement
-	err = json.Unmarshal([]byte(req.Statements.Commands[0]), &mongoCS)
+	err := json.Unmarshal([]byte(req.Statements.Commands[0]), &mongoCS)
 	if err != nil {
 		return dbplugin.NewUserResponse{}, err
 	}
 
+	var username string
+	switch req.CredentialType {
+	case dbplugin.CredentialTypeClientCertificate:
+		// MongoDB authenticates client certificates as the user named after
+		// their subject in the $external database
+		if req.Subject == "" {
+			return dbplugin.NewUserResponse{}, fmt.Errorf("missing certificate subject")
+		}
+		username = req.Subject
+		mongoCS.DB = externalDB
+	default:
+		username, err = m.usernameProducer.Generate(req.UsernameConfig)
+		if err != nil {
+			return dbplugin.NewUserResponse{}, err
+		}
+	}
+
 	// Default to "admin" if no db provided
 	if mongoCS.DB == "" {
 		mongoCS.DB = "admin"
@@ -205,8 +226,14 @@ func (m *MongoDB) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest
 	}
 
 	db := mongoCS.DB
-	// If db is not specified, use the default authenticationDatabase "admin"
-	if db == "" {
+	switch {
+	case db != "":
+	case isSubject(req.Username):
+		// Users named after a certificate subject were created in the
+		// $external database
+		db = externalDB
+	default:
+		// If db is not specified, use the default authenticationDatabase "admin"
 		db = "admin"
 	}
 
@@ -225,6 +252,14 @@ func (m *MongoDB) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest
 	return dbplugin.DeleteUserResponse{}, err
 }
 
+// isSubject returns whether the username is the distinguished name of a
+// client certificate subject. Generated usernames never are, as they contain
+// no attribute type and value pairs.
+func isSubject(username string) bool {
+	dn, err := ldap.ParseDN(username)
+	return err == nil && len(dn.RDNs) > 0
+}
+
 // runCommandWithRetry runs a command and retries once more if there's a failure
 // on the first attempt. This should be called with the lock held
 func (m *MongoDB) runCommandWithRetry(ctx context.Context, db string, cmd interface{}) error {
@@ -38,6 +38,10 @@ func TestMongoDB_Initialize(t *testing.T) {
 
 	// Make a copy since the original map could be modified by the Initialize call
 	expectedConfig := copyConfig(config)
+	expectedConfig[dbplugin.SupportedCredentialTypesKey] = []interface{}{
+		dbplugin.CredentialTypePassword.String(),
+		dbplugin.CredentialTypeClientCertificate.String(),
+	}
 
 	req := dbplugin.InitializeRequest{
 		Config:           config,
@@ -7,6 +7,7 @@ import (
 	"database/sql"
 	"fmt"
 	"net/url"
+	"strings"
 	"sync"
 	"time"
 
@@ -28,6 +29,8 @@ type mySQLConnectionProducer struct {
 	Username string `json:"username" mapstructure:"username" structs:"username"`
 	Password string `json:"password" mapstructure:"password" structs:"password"`
 
+	SelfManaged bool `json:"self_managed" mapstructure:"self_managed" structs:"self_managed"`
+
 	TLSCertificateKeyData []byte `json:"tls_certificate_key" mapstructure:"tls_certificate_key" structs:"-"`
 	TLSCAData             []byte `json:"tls_ca"              mapstructure:"tls_ca"              structs:"-"`
 
@@ -61,15 +64,20 @@ func (c *mySQLConnectionProducer) Init(ctx context.Context, conf map[string]inte
 		return nil, fmt.Errorf("connection_url cannot be empty")
 	}
 
-	// Don't escape special characters for MySQL password
-	password := c.Password
-
-	// QueryHelper doesn't do any SQL escaping, but if it starts to do so
-	// then maybe we won't be able to use it to do URL substitution any more.
-	c.ConnectionURL = dbutil.QueryHelper(c.ConnectionURL, map[string]string{
-		"username": url.PathEscape(c.Username),
-		"password": password,
-	})
+	if c.SelfManaged {
+		// Self-managed connections have no credentials of their own, as
+		// the connection URL is templated with the credentials of each
+		// static account to connect as.
+		if c.Username != "" || c.Password != "" {
+			return nil, fmt.Errorf("username and password cannot be set for self-managed connections")
+		}
+		if !strings.Contains(c.ConnectionURL, "{{username}}") ||
+			!strings.Contains(c.ConnectionURL, "{{password}}") {
+			return nil, fmt.Errorf("connection_url must contain the {{username}} and {{password}} template variables for self-managed connections")
+		}
+	} else {
+		c.ConnectionURL = c.connectionURL(c.Username, c.Password)
+	}
 
 	if c.MaxOpenConnections == 0 {
 		c.MaxOpenConnections = 4
@@ -110,7 +118,8 @@ func (c *mySQLConnectionProducer) Init(ctx context.Context, conf map[string]inte
 	// and the connection can be established at a later time.
 	c.Initialized = true
 
-	if verifyConnection {
+	// There are no credentials to verify self-managed connections with
+	if verifyConnection && !c.SelfManaged {
 		if _, err := c.Connection(ctx); err != nil {
 			return nil, fmt.Errorf("error verifying connection: %w", err)
 		}
@@ -127,6 +136,9 @@ func (c *mySQLConnectionProducer) Connection(ctx context.Context) (interface{},
 	if !c.Initialized {
 		return nil, connutil.ErrNotInitialized
 	}
+	if c.SelfManaged {
+		return nil, connutil.ErrSelfManagedConnection
+	}
 
 	// If we already have a DB, test it and return
 	if c.db != nil {
@@ -138,23 +150,71 @@ func (c *mySQLConnectionProducer) Connection(ctx context.Context) (interface{},
 		c.db.Close()
 	}
 
-	connURL, err := c.addTLStoDSN()
+	var err error
+	c.db, err = c.open(c.ConnectionURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.db, nil
+}
+
+// StaticConnection returns a new connection to the database authenticated as
+// the given static account of a self-managed connection. The caller is
+// responsible for closing the returned connection.
+func (c *mySQLConnectionProducer) StaticConnection(ctx context.Context, username, password string) (*sql.DB, error) {
+	if !c.Initialized {
+		return nil, connutil.ErrNotInitialized
+	}
+	if !c.SelfManaged {
+		return nil, fmt.Errorf("static account connections are only supported for self-managed connections")
+	}
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("missing static account credentials")
+	}
+
+	db, err := c.open(c.connectionURL(username, password))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// connectionURL returns the connection URL with the given credentials
+// substituted in.
+func (c *mySQLConnectionProducer) connectionURL(username, password string) string {
+	// Don't escape special characters for MySQL password
+	// QueryHelper doesn't do any SQL escaping, but if it starts to do so
+	// then maybe we won't be able to use it to do URL substitution any more.
+	return dbutil.QueryHelper(c.ConnectionURL, map[string]string{
+		"username": url.PathEscape(username),
+		"password": password,
+	})
+}
+
+func (c *mySQLConnectionProducer) open(connURL string) (*sql.DB, error) {
+	connURL, err := c.addTLStoDSN(connURL)
 	if err != nil {
 		return nil, err
 	}
 
-	c.db, err = sql.Open("mysql", connURL)
+	db, err := sql.Open("mysql", connURL)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set some connection pool settings. We don't need much of this,
 	// since the request rate shouldn't be high.
-	c.db.SetMaxOpenConns(c.MaxOpenConnections)
-	c.db.SetMaxIdleConns(c.MaxIdleConnections)
-	c.db.SetConnMaxLifetime(c.maxConnectionLifetime)
+	db.SetMaxOpenConns(c.MaxOpenConnections)
+	db.SetMaxIdleConns(c.MaxIdleConnections)
+	db.SetConnMaxLifetime(c.maxConnectionLifetime)
 
-	return c.db, nil
+	return db, nil
 }
 
 func (c *mySQLConnectionProducer) SecretValues() map[string]string {
@@ -211,8 +271,8 @@ func (c *mySQLConnectionProducer) getTLSAuth() (tlsConfig *tls.Config, err error
 	return tlsConfig, nil
 }
 
-func (c *mySQLConnectionProducer) addTLStoDSN() (connURL string, err error) {
-	config, err := mysql.ParseDSN(c.ConnectionURL)
+func (c *mySQLConnectionProducer) addTLStoDSN(dsn string) (connURL string, err error) {
+	config, err := mysql.ParseDSN(dsn)
 	if err != nil {
 		return "", fmt.Errorf("unable to parse connectionURL: %s", err)
 	}
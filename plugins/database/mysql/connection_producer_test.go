@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/vault/helper/testhelpers/certhelpers"
+	"github.com/hashicorp/vault/sdk/database/helper/connutil"
 	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
 	dockertest "github.com/ory/dockertest/v3"
 )
@@ -59,7 +61,7 @@ func Test_addTLStoDSN(t *testing.T) {
 				tlsConfigName: test.tlsConfigName,
 			}
 
-			actual, err := tCase.addTLStoDSN()
+			actual, err := tCase.addTLStoDSN(tCase.ConnectionURL)
 			if err != nil {
 				t.Fatalf("error occurred in test: %s", err)
 			}
@@ -70,6 +72,65 @@ func Test_addTLStoDSN(t *testing.T) {
 	}
 }
 
+func TestInit_selfManaged(t *testing.T) {
+	type testCase struct {
+		conf      map[string]interface{}
+		expectErr bool
+	}
+
+	tests := map[string]testCase{
+		"templated connection url": {
+			conf: map[string]interface{}{
+				"connection_url": "{{username}}:{{password}}@tcp(localhost:3306)/test",
+				"self_managed":   true,
+			},
+		},
+		"connection url without templates": {
+			conf: map[string]interface{}{
+				"connection_url": "user:password@tcp(localhost:3306)/test",
+				"self_managed":   true,
+			},
+			expectErr: true,
+		},
+		"username and password set": {
+			conf: map[string]interface{}{
+				"connection_url": "{{username}}:{{password}}@tcp(localhost:3306)/test",
+				"username":       "root",
+				"password":       "secret",
+				"self_managed":   true,
+			},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := &mySQLConnectionProducer{}
+
+			// Self-managed connections are never verified, as they have no
+			// credentials of their own
+			_, err := c.Init(context.Background(), test.conf, true)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("err expected, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+
+			expectedURL := "user%2Fname:pa?ssword@tcp(localhost:3306)/test"
+			if actual := c.connectionURL("user/name", "pa?ssword"); actual != expectedURL {
+				t.Fatalf("generated: %s, expected: %s", actual, expectedURL)
+			}
+			if _, err := c.Connection(context.Background()); !errors.Is(err, connutil.ErrSelfManagedConnection) {
+				t.Fatalf("expected self-managed connection error, got: %v", err)
+			}
+		})
+	}
+}
+
 func TestInit_clientTLS(t *testing.T) {
 	t.Skip("Skipping this test because CircleCI can't mount the files we need without further investigation: " +
 		"https://support.circleci.com/hc/en-us/articles/360007324514-How-can-I-mount-volumes-to-docker-containers-")
@@ -24,6 +24,13 @@ const (
 		ALTER USER '{{username}}'@'%' IDENTIFIED BY '{{password}}';
 	`
 
+	// defaultMySQLSelfManagedRotateCredentialsSQL changes the password of the
+	// account the statement is run as, which does not require the CREATE USER
+	// privilege unlike naming the account.
+	defaultMySQLSelfManagedRotateCredentialsSQL = `
+		ALTER USER USER() IDENTIFIED BY '{{password}}';
+	`
+
 	mySQLTypeName = "mysql"
 
 	DefaultUserNameTemplate       = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 10) (.RoleName | truncate 10) (random 20) (unix_time) | truncate 32 }}`
@@ -194,7 +201,7 @@ func (m *MySQL) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequest)
 	}
 
 	if req.Password != nil {
-		err := m.changeUserPassword(ctx, req.Username, req.Password.NewPassword, req.Password.Statements.Commands)
+		err := m.changeUserPassword(ctx, req.Username, req.Password.NewPassword, req.Password.Statements.Commands, req.SelfManagedPassword)
 		if err != nil {
 			return dbplugin.UpdateUserResponse{}, fmt.Errorf("failed to change password: %w", err)
 		}
@@ -205,13 +212,19 @@ func (m *MySQL) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequest)
 	return dbplugin.UpdateUserResponse{}, nil
 }
 
-func (m *MySQL) changeUserPassword(ctx context.Context, username, password string, rotateStatements []string) error {
+// changeUserPassword changes the password of the user. The password of
+// self-managed static accounts is changed over a connection made as the user
+// with its current password.
+func (m *MySQL) changeUserPassword(ctx context.Context, username, password string, rotateStatements []string, selfManagedPass string) error {
 	if username == "" || password == "" {
 		return errors.New("must provide both username and password")
 	}
 
 	if len(rotateStatements) == 0 {
 		rotateStatements = []string{defaultMySQLRotateCredentialsSQL}
+		if selfManagedPass != "" {
+			rotateStatements = []string{defaultMySQLSelfManagedRotateCredentialsSQL}
+		}
 	}
 
 	queryMap := map[string]string{
@@ -220,12 +233,32 @@ func (m *MySQL) changeUserPassword(ctx context.Context, username, password strin
 		"password": password,
 	}
 
+	if selfManagedPass != "" {
+		return m.executeSelfManagedStatementsWithMap(ctx, username, selfManagedPass, rotateStatements, queryMap)
+	}
+
 	if err := m.executePreparedStatementsWithMap(ctx, rotateStatements, queryMap); err != nil {
 		return err
 	}
 	return nil
 }
 
+// executeSelfManagedStatementsWithMap executes the given templated SQL
+// statements over a connection made as the given static account.
+func (m *MySQL) executeSelfManagedStatementsWithMap(ctx context.Context, username, password string, statements []string, queryMap map[string]string) error {
+	// Grab the lock
+	m.Lock()
+	defer m.Unlock()
+
+	db, err := m.StaticConnection(ctx, username, password)
+	if err != nil {
+		return fmt.Errorf("unable to get connection as %q: %w", username, err)
+	}
+	defer db.Close()
+
+	return executePreparedStatements(ctx, db, statements, queryMap)
+}
+
 // executePreparedStatementsWithMap loops through the given templated SQL statements and
 // applies the map to them, interpolating values into the templates, returning
 // the resulting username and password
@@ -239,6 +272,13 @@ func (m *MySQL) executePreparedStatementsWithMap(ctx context.Context, statements
 	if err != nil {
 		return err
 	}
+
+	return executePreparedStatements(ctx, db, statements, queryMap)
+}
+
+// executePreparedStatements executes the templated SQL statements in a
+// transaction of the given connection.
+func executePreparedStatements(ctx context.Context, db *sql.DB, statements []string, queryMap map[string]string) error {
 	// Start a transaction
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/go-ldap/ldap/v3"
 	"github.com/hashicorp/go-multierror"
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/vault/sdk/database/dbplugin/v5"
@@ -29,6 +30,10 @@ ALTER ROLE "{{username}}" WITH PASSWORD '{{password}}';
 
 	expirationFormat = "2006-01-02 15:04:05-0700"
 
+	// maxIdentifierLength is the maximum length of PostgreSQL identifiers,
+	// such as role names, which are otherwise silently truncated.
+	maxIdentifierLength = 63
+
 	defaultUserNameTemplate = `{{ printf "v-%s-%s-%s-%s" (.DisplayName | truncate 8) (.RoleName | truncate 8) (random 20) (unix_time) | truncate 63 }}`
 )
 
@@ -105,6 +110,10 @@ func (p *PostgreSQL) Initialize(ctx context.Context, req dbplugin.InitializeRequ
 	resp := dbplugin.InitializeResponse{
 		Config: newConf,
 	}
+	resp.SetSupportedCredentialTypes([]dbplugin.CredentialType{
+		dbplugin.CredentialTypePassword,
+		dbplugin.CredentialTypeClientCertificate,
+	})
 	return resp, nil
 }
 
@@ -131,7 +140,7 @@ func (p *PostgreSQL) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequ
 
 	merr := &multierror.Error{}
 	if req.Password != nil {
-		err := p.changeUserPassword(ctx, req.Username, req.Password)
+		err := p.changeUserPassword(ctx, req.Username, req.Password, req.SelfManagedPassword)
 		merr = multierror.Append(merr, err)
 	}
 	if req.Expiration != nil {
@@ -141,7 +150,10 @@ func (p *PostgreSQL) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequ
 	return dbplugin.UpdateUserResponse{}, merr.ErrorOrNil()
 }
 
-func (p *PostgreSQL) changeUserPassword(ctx context.Context, username string, changePass *dbplugin.ChangePassword) error {
+// changeUserPassword changes the password of the user. The password of
+// self-managed static accounts is changed over a connection made as the user
+// with its current password.
+func (p *PostgreSQL) changeUserPassword(ctx context.Context, username string, changePass *dbplugin.ChangePassword, selfManagedPass string) error {
 	stmts := changePass.Statements.Commands
 	if len(stmts) == 0 {
 		stmts = []string{defaultChangePasswordStatement}
@@ -155,9 +167,19 @@ func (p *PostgreSQL) changeUserPassword(ctx context.Context, username string, ch
 	p.Lock()
 	defer p.Unlock()
 
-	db, err := p.getConnection(ctx)
-	if err != nil {
-		return fmt.Errorf("unable to get connection: %w", err)
+	var db *sql.DB
+	var err error
+	if selfManagedPass == "" {
+		db, err = p.getConnection(ctx)
+		if err != nil {
+			return fmt.Errorf("unable to get connection: %w", err)
+		}
+	} else {
+		db, err = p.StaticConnection(ctx, username, selfManagedPass)
+		if err != nil {
+			return fmt.Errorf("unable to get connection as %q: %w", username, err)
+		}
+		defer db.Close()
 	}
 
 	// Check if the role exists
@@ -251,7 +273,16 @@ func (p *PostgreSQL) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (
 	p.Lock()
 	defer p.Unlock()
 
-	username, err := p.usernameProducer.Generate(req.UsernameConfig)
+	var username string
+	var err error
+	switch req.CredentialType {
+	case dbplugin.CredentialTypeClientCertificate:
+		// PostgreSQL authenticates client certificates as the role named
+		// after their common name
+		username, err = commonNameFromSubject(req.Subject)
+	default:
+		username, err = p.usernameProducer.Generate(req.UsernameConfig)
+	}
 	if err != nil {
 		return dbplugin.NewUserResponse{}, err
 	}
@@ -312,6 +343,32 @@ func (p *PostgreSQL) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (
 	return resp, nil
 }
 
+// commonNameFromSubject returns the common name of the distinguished name of a
+// client certificate.
+func commonNameFromSubject(subject string) (string, error) {
+	dn, err := ldap.ParseDN(subject)
+	if err != nil {
+		return "", fmt.Errorf("invalid certificate subject %q: %w", subject, err)
+	}
+
+	var commonName string
+	for _, rdn := range dn.RDNs {
+		for _, attr := range rdn.Attributes {
+			if strings.EqualFold(attr.Type, "CN") {
+				commonName = attr.Value
+			}
+		}
+	}
+
+	switch {
+	case commonName == "":
+		return "", fmt.Errorf("certificate subject %q has no common name", subject)
+	case len(commonName) > maxIdentifierLength:
+		return "", fmt.Errorf("certificate common name %q is longer than %d bytes", commonName, maxIdentifierLength)
+	}
+	return commonName, nil
+}
+
 func (p *PostgreSQL) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
 	p.Lock()
 	defer p.Unlock()
@@ -404,6 +404,81 @@ func TestUpdateUser_Password(t *testing.T) {
 	})
 }
 
+func TestUpdateUser_SelfManagedPassword(t *testing.T) {
+	db, cleanup := getPostgreSQL(t, nil)
+	defer cleanup()
+
+	// Create the static account with the root connection
+	initialPass := "myreallysecurepassword"
+	createReq := dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "test",
+			RoleName:    "test",
+		},
+		Statements: dbplugin.Statements{
+			Commands: []string{createAdminUser},
+		},
+		Password:   initialPass,
+		Expiration: time.Now().Add(time.Hour),
+	}
+	createResp := dbtesting.AssertNewUser(t, db, createReq)
+	assertCredsExist(t, db.ConnectionURL, createResp.Username, initialPass)
+
+	// Rotate the static account with a self-managed connection, which has no
+	// credentials of its own
+	selfManagedDB := new()
+	initReq := dbplugin.InitializeRequest{
+		Config: map[string]interface{}{
+			"connection_url": strings.Replace(db.ConnectionURL, "postgres:secret", "{{username}}:{{password}}", 1),
+			"self_managed":   true,
+		},
+		VerifyConnection: true,
+	}
+	dbtesting.AssertInitialize(t, selfManagedDB, initReq)
+	defer dbtesting.AssertClose(t, selfManagedDB)
+
+	newPass := "somenewpassword"
+	updateReq := dbplugin.UpdateUserRequest{
+		Username: createResp.Username,
+		Password: &dbplugin.ChangePassword{
+			NewPassword: newPass,
+		},
+		SelfManagedPassword: initialPass,
+	}
+	dbtesting.AssertUpdateUser(t, selfManagedDB, updateReq)
+
+	assertCredsDoNotExist(t, db.ConnectionURL, createResp.Username, initialPass)
+	assertCredsExist(t, db.ConnectionURL, createResp.Username, newPass)
+
+	t.Run("wrong current password", func(t *testing.T) {
+		updateReq := dbplugin.UpdateUserRequest{
+			Username: createResp.Username,
+			Password: &dbplugin.ChangePassword{
+				NewPassword: "anothernewpassword",
+			},
+			SelfManagedPassword: initialPass,
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := selfManagedDB.UpdateUser(ctx, updateReq)
+		if err == nil {
+			t.Fatalf("err expected, got nil")
+		}
+
+		assertCredsExist(t, db.ConnectionURL, createResp.Username, newPass)
+	})
+
+	t.Run("root connection is unavailable", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := selfManagedDB.NewUser(ctx, createReq)
+		if err == nil {
+			t.Fatalf("err expected, got nil")
+		}
+	})
+}
+
 func TestUpdateUser_Expiration(t *testing.T) {
 	type testCase struct {
 		initialExpiration  time.Time
@@ -774,6 +849,56 @@ func TestContainsMultilineStatement(t *testing.T) {
 	}
 }
 
+func TestCommonNameFromSubject(t *testing.T) {
+	type testCase struct {
+		Input     string
+		Expected  string
+		ExpectErr bool
+	}
+
+	testCases := map[string]*testCase{
+		"common name only": {
+			Input:    "CN=v-token-role-abc",
+			Expected: "v-token-role-abc",
+		},
+		"common name with other attributes": {
+			Input:    "CN=v-token-role-abc,OU=Engineering,O=HashiCorp",
+			Expected: "v-token-role-abc",
+		},
+		"escaped common name": {
+			Input:    `CN=foo\,bar`,
+			Expected: "foo,bar",
+		},
+		"no common name": {
+			Input:     "OU=Engineering,O=HashiCorp",
+			ExpectErr: true,
+		},
+		"common name too long": {
+			Input:     "CN=" + strings.Repeat("a", 64),
+			ExpectErr: true,
+		},
+		"invalid subject": {
+			Input:     "not a subject",
+			ExpectErr: true,
+		},
+	}
+
+	for tName, tCase := range testCases {
+		t.Run(tName, func(t *testing.T) {
+			actual, err := commonNameFromSubject(tCase.Input)
+			if tCase.ExpectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !tCase.ExpectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+			if actual != tCase.Expected {
+				t.Fatalf("expected %q, got %q", tCase.Expected, actual)
+			}
+		})
+	}
+}
+
 func TestExtractQuotedStrings(t *testing.T) {
 	type testCase struct {
 		Input    string
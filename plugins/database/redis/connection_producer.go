@@ -0,0 +1,234 @@
+package redis
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/go-secure-stdlib/tlsutil"
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/connutil"
+	"github.com/mediocregopher/radix/v4"
+	"github.com/mitchellh/mapstructure"
+)
+
+// redisConnectionProducer implements ConnectionProducer and provides an
+// interface for Redis servers and Redis Cluster deployments to make
+// connections.
+type redisConnectionProducer struct {
+	Host          string `json:"host" structs:"host" mapstructure:"host"`
+	Port          int    `json:"port" structs:"port" mapstructure:"port"`
+	Username      string `json:"username" structs:"username" mapstructure:"username"`
+	Password      string `json:"password" structs:"password" mapstructure:"password"`
+	TLS           bool   `json:"tls" structs:"tls" mapstructure:"tls"`
+	InsecureTLS   bool   `json:"insecure_tls" structs:"insecure_tls" mapstructure:"insecure_tls"`
+	CACert        string `json:"ca_cert" structs:"ca_cert" mapstructure:"ca_cert"`
+	TLSMinVersion string `json:"tls_min_version" structs:"tls_min_version" mapstructure:"tls_min_version"`
+	Cluster       bool   `json:"cluster" structs:"cluster" mapstructure:"cluster"`
+
+	addr      string
+	rawConfig map[string]interface{}
+
+	Initialized bool
+	Type        string
+
+	// nodes holds a client for every node users are managed on, keyed by
+	// address. It only holds the configured server unless Cluster is set.
+	nodes map[string]radix.Client
+	sync.Mutex
+}
+
+func (c *redisConnectionProducer) secretValues() map[string]string {
+	return map[string]string{
+		c.Password: "[password]",
+	}
+}
+
+func (c *redisConnectionProducer) Initialize(ctx context.Context, req dbplugin.InitializeRequest) (dbplugin.InitializeResponse, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.rawConfig = req.Config
+
+	err := mapstructure.WeakDecode(req.Config, c)
+	if err != nil {
+		return dbplugin.InitializeResponse{}, err
+	}
+
+	switch {
+	case len(c.Host) == 0:
+		return dbplugin.InitializeResponse{}, fmt.Errorf("host cannot be empty")
+	case c.Port == 0:
+		return dbplugin.InitializeResponse{}, fmt.Errorf("port cannot be empty")
+	case len(c.Username) == 0:
+		return dbplugin.InitializeResponse{}, fmt.Errorf("username cannot be empty")
+	case len(c.Password) == 0:
+		return dbplugin.InitializeResponse{}, fmt.Errorf("password cannot be empty")
+	}
+
+	if c.TLSMinVersion != "" {
+		if _, ok := tlsutil.TLSLookup[c.TLSMinVersion]; !ok {
+			return dbplugin.InitializeResponse{}, fmt.Errorf("invalid tls_min_version %q", c.TLSMinVersion)
+		}
+	}
+
+	c.addr = net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+
+	// Close any connections made with a previous configuration
+	c.close()
+
+	// Set initialized to true at this point since all fields are set,
+	// and the connection can be established at a later time.
+	c.Initialized = true
+
+	if req.VerifyConnection {
+		if _, err := c.Connection(ctx); err != nil {
+			c.close()
+			return dbplugin.InitializeResponse{}, fmt.Errorf("error verifying connection: %w", err)
+		}
+	}
+
+	resp := dbplugin.InitializeResponse{
+		Config: req.Config,
+	}
+
+	return resp, nil
+}
+
+// Connection returns the clients of all nodes users are managed on, keyed by
+// address. The caller must hold the lock.
+func (c *redisConnectionProducer) Connection(ctx context.Context) (interface{}, error) {
+	if !c.Initialized {
+		return nil, connutil.ErrNotInitialized
+	}
+
+	// If we already have the clients, return them
+	if c.nodes != nil {
+		return c.nodes, nil
+	}
+
+	poolConfig, err := c.poolConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := poolConfig.New(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, err
+	}
+	nodes := map[string]radix.Client{
+		c.addr: seed,
+	}
+
+	if c.Cluster {
+		// ACL users are local to each node of a cluster, so every primary
+		// and replica the cluster reports has to be managed.
+		var topo radix.ClusterTopo
+		if err := seed.Do(ctx, radix.Cmd(&topo, "CLUSTER", "SLOTS")); err != nil {
+			seed.Close()
+			return nil, fmt.Errorf("failed to discover cluster nodes: %w", err)
+		}
+		if len(topo) == 0 {
+			seed.Close()
+			return nil, fmt.Errorf("failed to discover cluster nodes: no cluster slots assigned")
+		}
+
+		// The seed is only kept when the cluster reports it by the same
+		// address, as it would otherwise be managed twice.
+		delete(nodes, c.addr)
+		defer func() {
+			if _, ok := nodes[c.addr]; !ok {
+				seed.Close()
+			}
+		}()
+
+		for _, node := range topo {
+			if _, ok := nodes[node.Addr]; ok {
+				continue
+			}
+			if node.Addr == c.addr {
+				nodes[node.Addr] = seed
+				continue
+			}
+
+			client, err := poolConfig.New(ctx, "tcp", node.Addr)
+			if err != nil {
+				closeClients(nodes)
+				return nil, fmt.Errorf("failed to connect to cluster node %q: %w", node.Addr, err)
+			}
+			nodes[node.Addr] = client
+		}
+	}
+
+	//  Store the clients in backend for reuse
+	c.nodes = nodes
+
+	return c.nodes, nil
+}
+
+func (c *redisConnectionProducer) poolConfig() (radix.PoolConfig, error) {
+	dialer := radix.Dialer{
+		AuthUser: c.Username,
+		AuthPass: c.Password,
+	}
+
+	if c.TLS {
+		tlsConfig := &tls.Config{
+			InsecureSkipVerify: c.InsecureTLS,
+		}
+		if len(c.CACert) > 0 {
+			rootCAs := x509.NewCertPool()
+			if !rootCAs.AppendCertsFromPEM([]byte(c.CACert)) {
+				return radix.PoolConfig{}, fmt.Errorf("failed to parse ca_cert")
+			}
+			tlsConfig.RootCAs = rootCAs
+		}
+		if c.TLSMinVersion != "" {
+			tlsConfig.MinVersion = tlsutil.TLSLookup[c.TLSMinVersion]
+		}
+		dialer.NetDialer = &tls.Dialer{
+			Config: tlsConfig,
+		}
+	}
+
+	return radix.PoolConfig{
+		Dialer: dialer,
+	}, nil
+}
+
+// close terminates the connections without locking
+func (c *redisConnectionProducer) close() {
+	closeClients(c.nodes)
+	c.nodes = nil
+}
+
+func (c *redisConnectionProducer) Close() error {
+	// Grab the write lock
+	c.Lock()
+	defer c.Unlock()
+
+	c.close()
+
+	return nil
+}
+
+func closeClients(clients map[string]radix.Client) {
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// sortedAddrs returns the addresses of the nodes in a stable order.
+func sortedAddrs(nodes map[string]radix.Client) []string {
+	addrs := make([]string, 0, len(nodes))
+	for addr := range nodes {
+		addrs = append(addrs, addr)
+	}
+	sort.Strings(addrs)
+	return addrs
+}
@@ -0,0 +1,266 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	multierror "github.com/hashicorp/go-multierror"
+	"github.com/hashicorp/go-secure-stdlib/strutil"
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	"github.com/hashicorp/vault/sdk/database/helper/dbutil"
+	"github.com/hashicorp/vault/sdk/helper/template"
+	"github.com/mediocregopher/radix/v4"
+)
+
+const (
+	redisTypeName = "redis"
+
+	// defaultRedisUserRules allows created users to read any key
+	defaultRedisUserRules = `["~*", "+@read"]`
+
+	defaultUserNameTemplate = `{{ printf "v_%s_%s_%s_%s" (.DisplayName | truncate 15) (.RoleName | truncate 15) (random 20) (unix_time) | truncate 100 | uppercase }}`
+)
+
+var _ dbplugin.Database = &Redis{}
+
+// Redis is an implementation of Database interface that manages Redis 6+ ACL
+// users, on a single server or on every node of a Redis Cluster.
+type Redis struct {
+	*redisConnectionProducer
+
+	usernameProducer template.StringTemplate
+}
+
+// New returns a new Redis instance
+func New() (interface{}, error) {
+	db := new()
+	dbType := dbplugin.NewDatabaseErrorSanitizerMiddleware(db, db.secretValues)
+
+	return dbType, nil
+}
+
+func new() *Redis {
+	connProducer := &redisConnectionProducer{}
+	connProducer.Type = redisTypeName
+
+	return &Redis{
+		redisConnectionProducer: connProducer,
+	}
+}
+
+// Type returns the TypeName for this backend
+func (r *Redis) Type() (string, error) {
+	return redisTypeName, nil
+}
+
+func (r *Redis) getConnection(ctx context.Context) (map[string]radix.Client, error) {
+	nodes, err := r.Connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes.(map[string]radix.Client), nil
+}
+
+func (r *Redis) Initialize(ctx context.Context, req dbplugin.InitializeRequest) (dbplugin.InitializeResponse, error) {
+	usernameTemplate, err := strutil.GetString(req.Config, "username_template")
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("failed to retrieve username_template: %w", err)
+	}
+	if usernameTemplate == "" {
+		usernameTemplate = defaultUserNameTemplate
+	}
+
+	up, err := template.NewTemplate(template.Template(usernameTemplate))
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("unable to initialize username template: %w", err)
+	}
+	r.usernameProducer = up
+
+	_, err = r.usernameProducer.Generate(dbplugin.UsernameMetadata{})
+	if err != nil {
+		return dbplugin.InitializeResponse{}, fmt.Errorf("invalid username template: %w", err)
+	}
+
+	return r.redisConnectionProducer.Initialize(ctx, req)
+}
+
+// NewUser creates an ACL user with the rules of the creation statements on
+// every managed node.
+func (r *Redis) NewUser(ctx context.Context, req dbplugin.NewUserRequest) (dbplugin.NewUserResponse, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	username, err := r.usernameProducer.Generate(req.UsernameConfig)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, err
+	}
+
+	creationStatements := req.Statements.Commands
+	if len(removeEmpty(creationStatements)) == 0 {
+		creationStatements = []string{defaultRedisUserRules}
+	}
+
+	rules, err := parseRules(creationStatements, map[string]string{
+		"username": username,
+		"name":     username,
+	})
+	if err != nil {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("invalid creation statements: %w", err)
+	}
+
+	nodes, err := r.getConnection(ctx)
+	if err != nil {
+		return dbplugin.NewUserResponse{}, fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	// Start from a user without any rules, so that a user left behind by
+	// a previous attempt cannot keep rules that were not requested.
+	args := append([]string{"SETUSER", username, "RESET", "ON", ">" + req.Password}, rules...)
+	for _, addr := range sortedAddrs(nodes) {
+		if err := nodes[addr].Do(ctx, radix.Cmd(nil, "ACL", args...)); err != nil {
+			// Attempt to remove the user from the nodes it was created on
+			deleteUser(ctx, nodes, username)
+			return dbplugin.NewUserResponse{}, fmt.Errorf("failed to create user on %q: %w", addr, err)
+		}
+	}
+
+	resp := dbplugin.NewUserResponse{
+		Username: username,
+	}
+	return resp, nil
+}
+
+// DeleteUser removes the ACL user from every managed node. Revocation
+// statements are not supported as users are always removed with ACL DELUSER.
+func (r *Redis) DeleteUser(ctx context.Context, req dbplugin.DeleteUserRequest) (dbplugin.DeleteUserResponse, error) {
+	r.Lock()
+	defer r.Unlock()
+
+	nodes, err := r.getConnection(ctx)
+	if err != nil {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	if err := deleteUser(ctx, nodes, req.Username); err != nil {
+		return dbplugin.DeleteUserResponse{}, fmt.Errorf("failed to delete user cleanly: %w", err)
+	}
+	return dbplugin.DeleteUserResponse{}, nil
+}
+
+func deleteUser(ctx context.Context, nodes map[string]radix.Client, username string) error {
+	var result *multierror.Error
+	for _, addr := range sortedAddrs(nodes) {
+		// Deleting a user that does not exist on a node is not an error
+		if err := nodes[addr].Do(ctx, radix.Cmd(nil, "ACL", "DELUSER", username)); err != nil {
+			result = multierror.Append(result, fmt.Errorf("%q: %w", addr, err))
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+func (r *Redis) UpdateUser(ctx context.Context, req dbplugin.UpdateUserRequest) (dbplugin.UpdateUserResponse, error) {
+	if req.Password == nil && req.Expiration == nil {
+		return dbplugin.UpdateUserResponse{}, fmt.Errorf("no changes requested")
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if req.Password != nil {
+		err := r.changeUserPassword(ctx, req.Username, req.Password)
+		if err != nil {
+			return dbplugin.UpdateUserResponse{}, fmt.Errorf("failed to change %q password: %w", req.Username, err)
+		}
+	}
+	// Expiration is a no-op
+	return dbplugin.UpdateUserResponse{}, nil
+}
+
+// changeUserPassword replaces the passwords of the user on every managed node,
+// along with applying the rules of the rotation statements if any are given.
+func (r *Redis) changeUserPassword(ctx context.Context, username string, changePassword *dbplugin.ChangePassword) error {
+	rules, err := parseRules(changePassword.Statements.Commands, map[string]string{
+		"username": username,
+		"name":     username,
+	})
+	if err != nil {
+		return fmt.Errorf("invalid rotation statements: %w", err)
+	}
+
+	nodes, err := r.getConnection(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get connection: %w", err)
+	}
+
+	// Check that the user exists on every node before changing anything, as
+	// ACL SETUSER would otherwise silently create it.
+	addrs := sortedAddrs(nodes)
+	for _, addr := range addrs {
+		var user []interface{}
+		mb := radix.Maybe{Rcv: &user}
+		if err := nodes[addr].Do(ctx, radix.Cmd(&mb, "ACL", "GETUSER", username)); err != nil {
+			return fmt.Errorf("failed to look up user on %q: %w", addr, err)
+		}
+		if mb.Null {
+			return fmt.Errorf("user does not exist on %q", addr)
+		}
+	}
+
+	args := append([]string{"SETUSER", username, "RESETPASS", ">" + changePassword.NewPassword}, rules...)
+	var result *multierror.Error
+	for _, addr := range addrs {
+		if err := nodes[addr].Do(ctx, radix.Cmd(nil, "ACL", args...)); err != nil {
+			result = multierror.Append(result, fmt.Errorf("%q: %w", addr, err))
+		}
+	}
+
+	err = result.ErrorOrNil()
+	if err != nil {
+		return fmt.Errorf("failed to execute rotation commands: %w", err)
+	}
+
+	// The connections of the configured user are authenticated with the
+	// previous password, so new ones have to be made with the new one.
+	if username == r.Username {
+		r.Password = changePassword.NewPassword
+		r.close()
+	}
+
+	return nil
+}
+
+// parseRules parses statements holding JSON arrays of ACL rules, such as
+// ["~cache:*", "+@read"], and fills in the templated fields of the rules.
+func parseRules(statements []string, m map[string]string) ([]string, error) {
+	var rules []string
+	for _, stmt := range removeEmpty(statements) {
+		var stmtRules []string
+		if err := json.Unmarshal([]byte(stmt), &stmtRules); err != nil {
+			return nil, fmt.Errorf("statement must be a JSON array of ACL rules: %w", err)
+		}
+		for _, rule := range stmtRules {
+			rule = strings.TrimSpace(rule)
+			if len(rule) == 0 {
+				continue
+			}
+			rules = append(rules, dbutil.QueryHelper(rule, m))
+		}
+	}
+	return rules, nil
+}
+
+func removeEmpty(strs []string) []string {
+	var newStrs []string
+	for _, str := range strs {
+		str = strings.TrimSpace(str)
+		if str == "" {
+			continue
+		}
+		newStrs = append(newStrs, str)
+	}
+
+	return newStrs
+}
@@ -0,0 +1,917 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dbplugin "github.com/hashicorp/vault/sdk/database/dbplugin/v5"
+	dbtesting "github.com/hashicorp/vault/sdk/database/dbplugin/v5/testing"
+	"github.com/mediocregopher/radix/v4"
+)
+
+const (
+	adminUsername = "vault-admin"
+	adminPassword = "vault-admin-password"
+)
+
+// fakeRedis is a stand-in for redis-server, speaking enough of RESP2 and
+// supporting enough of the ACL commands to manage users the way the plugin
+// does. Nodes created with newFakeRedisCluster answer CLUSTER SLOTS with the
+// addresses of all the nodes of the cluster.
+type fakeRedis struct {
+	t        *testing.T
+	listener net.Listener
+	cluster  []*fakeRedis
+
+	l           sync.Mutex
+	users       map[string]*fakeUser
+	failSetUser bool
+}
+
+type fakeUser struct {
+	enabled   bool
+	passwords map[string]bool
+	rules     []string
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &fakeRedis{
+		t:        t,
+		listener: listener,
+		users: map[string]*fakeUser{
+			adminUsername: {
+				enabled:   true,
+				passwords: map[string]bool{adminPassword: true},
+				rules:     []string{"~*", "&*", "+@all"},
+			},
+		},
+	}
+	go f.serve()
+	t.Cleanup(func() { listener.Close() })
+
+	return f
+}
+
+func newFakeRedisCluster(t *testing.T, n int) []*fakeRedis {
+	t.Helper()
+
+	var nodes []*fakeRedis
+	for i := 0; i < n; i++ {
+		nodes = append(nodes, newFakeRedis(t))
+	}
+	for _, node := range nodes {
+		node.cluster = nodes
+	}
+	return nodes
+}
+
+func (f *fakeRedis) addr() string {
+	return f.listener.Addr().String()
+}
+
+func (f *fakeRedis) connectionParams() map[string]interface{} {
+	host, port, _ := net.SplitHostPort(f.addr())
+	portNum, _ := strconv.Atoi(port)
+	return map[string]interface{}{
+		"host":     host,
+		"port":     portNum,
+		"username": adminUsername,
+		"password": adminPassword,
+	}
+}
+
+func (f *fakeRedis) user(name string) *fakeUser {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	user, ok := f.users[name]
+	if !ok {
+		return nil
+	}
+	copied := &fakeUser{
+		enabled:   user.enabled,
+		passwords: map[string]bool{},
+		rules:     append([]string(nil), user.rules...),
+	}
+	for password := range user.passwords {
+		copied.passwords[password] = true
+	}
+	return copied
+}
+
+func (f *fakeRedis) serve() {
+	for {
+		conn, err := f.listener.Accept()
+		if err != nil {
+			return
+		}
+		go f.handle(conn)
+	}
+}
+
+func (f *fakeRedis) handle(conn net.Conn) {
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	w := bufio.NewWriter(conn)
+	authenticated := false
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			if err != io.EOF && !strings.Contains(err.Error(), "use of closed network connection") {
+				f.t.Logf("fake redis: error reading command: %v", err)
+			}
+			return
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		cmd := strings.ToUpper(args[0])
+		switch {
+		case cmd == "AUTH":
+			if len(args) != 3 {
+				w.WriteString("-ERR wrong number of arguments for 'auth' command\r\n")
+				break
+			}
+			if !f.authenticate(args[1], args[2]) {
+				w.WriteString("-WRONGPASS invalid username-password pair or user is disabled.\r\n")
+				break
+			}
+			authenticated = true
+			w.WriteString("+OK\r\n")
+		case !authenticated:
+			w.WriteString("-NOAUTH Authentication required.\r\n")
+		case cmd == "PING":
+			w.WriteString("+PONG\r\n")
+		case cmd == "ACL":
+			f.acl(w, args[1:])
+		case cmd == "CLUSTER" && len(args) == 2 && strings.ToUpper(args[1]) == "SLOTS" && f.cluster != nil:
+			f.clusterSlots(w)
+		default:
+			fmt.Fprintf(w, "-ERR unknown command '%s'\r\n", args[0])
+		}
+
+		if err := w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) authenticate(username, password string) bool {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	user, ok := f.users[username]
+	return ok && user.enabled && user.passwords[password]
+}
+
+func (f *fakeRedis) acl(w *bufio.Writer, args []string) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if len(args) < 2 {
+		w.WriteString("-ERR wrong number of arguments for 'acl' command\r\n")
+		return
+	}
+
+	switch strings.ToUpper(args[0]) {
+	case "SETUSER":
+		if f.failSetUser {
+			w.WriteString("-ERR injected failure\r\n")
+			return
+		}
+		user, ok := f.users[args[1]]
+		if !ok {
+			user = &fakeUser{passwords: map[string]bool{}}
+		}
+		// Rules are applied to a copy, so that an invalid rule leaves the
+		// user untouched like it does on redis-server
+		updated := &fakeUser{
+			enabled:   user.enabled,
+			passwords: map[string]bool{},
+			rules:     append([]string(nil), user.rules...),
+		}
+		for password := range user.passwords {
+			updated.passwords[password] = true
+		}
+		for _, rule := range args[2:] {
+			switch {
+			case strings.EqualFold(rule, "on"):
+				updated.enabled = true
+			case strings.EqualFold(rule, "off"):
+				updated.enabled = false
+			case strings.EqualFold(rule, "reset"):
+				updated = &fakeUser{passwords: map[string]bool{}}
+			case strings.EqualFold(rule, "resetpass"):
+				updated.passwords = map[string]bool{}
+			case strings.HasPrefix(rule, ">"):
+				updated.passwords[rule[1:]] = true
+			case strings.HasPrefix(rule, "<"):
+				delete(updated.passwords, rule[1:])
+			case strings.HasPrefix(rule, "~"), strings.HasPrefix(rule, "&"),
+				strings.HasPrefix(rule, "+"), strings.HasPrefix(rule, "-"):
+				updated.rules = append(updated.rules, rule)
+			default:
+				fmt.Fprintf(w, "-ERR Error in ACL SETUSER modifier '%s': Syntax error\r\n", rule)
+				return
+			}
+		}
+		f.users[args[1]] = updated
+		w.WriteString("+OK\r\n")
+
+	case "DELUSER":
+		deleted := 0
+		for _, name := range args[1:] {
+			if _, ok := f.users[name]; ok {
+				delete(f.users, name)
+				deleted++
+			}
+		}
+		fmt.Fprintf(w, ":%d\r\n", deleted)
+
+	case "GETUSER":
+		user, ok := f.users[args[1]]
+		if !ok {
+			w.WriteString("*-1\r\n")
+			return
+		}
+		flag := "off"
+		if user.enabled {
+			flag = "on"
+		}
+		var passwords []string
+		for password := range user.passwords {
+			passwords = append(passwords, password)
+		}
+		sort.Strings(passwords)
+
+		w.WriteString("*6\r\n")
+		writeBulk(w, "flags")
+		writeArray(w, []string{flag})
+		writeBulk(w, "passwords")
+		writeArray(w, passwords)
+		writeBulk(w, "rules")
+		writeBulk(w, strings.Join(user.rules, " "))
+
+	default:
+		fmt.Fprintf(w, "-ERR unknown subcommand '%s'\r\n", args[0])
+	}
+}
+
+func (f *fakeRedis) clusterSlots(w *bufio.Writer) {
+	// Split the slots evenly between the nodes, all of them being primaries
+	perNode := 16384 / len(f.cluster)
+	fmt.Fprintf(w, "*%d\r\n", len(f.cluster))
+	for i, node := range f.cluster {
+		end := (i+1)*perNode - 1
+		if i == len(f.cluster)-1 {
+			end = 16383
+		}
+		host, port, _ := net.SplitHostPort(node.addr())
+		fmt.Fprintf(w, "*3\r\n:%d\r\n:%d\r\n", i*perNode, end)
+		fmt.Fprintf(w, "*3\r\n")
+		writeBulk(w, host)
+		fmt.Fprintf(w, ":%s\r\n", port)
+		writeBulk(w, fmt.Sprintf("node%d", i))
+	}
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if !strings.HasPrefix(line, "*") {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimRight(header, "\r\n")[1:])
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args = append(args, string(buf[:size]))
+	}
+	return args, nil
+}
+
+func writeBulk(w *bufio.Writer, s string) {
+	fmt.Fprintf(w, "$%d\r\n%s\r\n", len(s), s)
+}
+
+func writeArray(w *bufio.Writer, elems []string) {
+	fmt.Fprintf(w, "*%d\r\n", len(elems))
+	for _, elem := range elems {
+		writeBulk(w, elem)
+	}
+}
+
+// assertLogin checks that the user can authenticate to the node with the
+// password.
+func assertLogin(t *testing.T, node *fakeRedis, username, password string) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn, err := radix.Dialer{AuthUser: username, AuthPass: password}.Dial(ctx, "tcp", node.addr())
+	if err != nil {
+		t.Fatalf("failed to log in as %q on %q: %s", username, node.addr(), err)
+	}
+	defer conn.Close()
+
+	var pong string
+	if err := conn.Do(ctx, radix.Cmd(&pong, "PING")); err != nil {
+		t.Fatalf("failed to ping as %q: %s", username, err)
+	}
+}
+
+func makeConfig(rootConfig map[string]interface{}, keyValues ...interface{}) map[string]interface{} {
+	if len(keyValues)%2 != 0 {
+		panic("makeConfig must be provided with key and value pairs")
+	}
+
+	// Make a copy of the map so there isn't a chance of test bleedover between maps
+	config := make(map[string]interface{}, len(rootConfig)+(len(keyValues)/2))
+	for k, v := range rootConfig {
+		config[k] = v
+	}
+	for i := 0; i < len(keyValues); i += 2 {
+		k := keyValues[i].(string) // Will panic if the key field isn't a string and that's fine in a test
+		v := keyValues[i+1]
+		config[k] = v
+	}
+	return config
+}
+
+func newInitializedDB(t *testing.T, config map[string]interface{}) *Redis {
+	t.Helper()
+
+	db := new()
+	t.Cleanup(func() { db.Close() })
+	dbtesting.AssertInitialize(t, db, dbplugin.InitializeRequest{
+		Config:           config,
+		VerifyConnection: true,
+	})
+	return db
+}
+
+func TestRedis_Initialize(t *testing.T) {
+	node := newFakeRedis(t)
+
+	type testCase struct {
+		req               dbplugin.InitializeRequest
+		expectErr         bool
+		expectInitialized bool
+	}
+
+	tests := map[string]testCase{
+		"port is an int": {
+			req: dbplugin.InitializeRequest{
+				Config:           node.connectionParams(),
+				VerifyConnection: true,
+			},
+			expectInitialized: true,
+		},
+		"port is a string": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "port", strconv.Itoa(node.connectionParams()["port"].(int))),
+				VerifyConnection: true,
+			},
+			expectInitialized: true,
+		},
+		"missing config": {
+			req: dbplugin.InitializeRequest{
+				Config:           nil,
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"missing host": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "host", ""),
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"missing port": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "port", 0),
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"missing username": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "username", ""),
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"missing password": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "password", ""),
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"invalid tls_min_version": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "tls", true, "tls_min_version", "tls99"),
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"invalid username template": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "username_template", "{{ .DisplayName"),
+				VerifyConnection: true,
+			},
+			expectErr: true,
+		},
+		"wrong password": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "password", "wrong"),
+				VerifyConnection: true,
+			},
+			expectErr:         true,
+			expectInitialized: true,
+		},
+		"wrong password without verifying the connection": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "password", "wrong"),
+				VerifyConnection: false,
+			},
+			expectInitialized: true,
+		},
+		"cluster on a standalone server": {
+			req: dbplugin.InitializeRequest{
+				Config:           makeConfig(node.connectionParams(), "cluster", true),
+				VerifyConnection: true,
+			},
+			expectErr:         true,
+			expectInitialized: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			db := new()
+			defer dbtesting.AssertClose(t, db)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := db.Initialize(ctx, test.req)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+
+			if !test.expectErr && !reflect.DeepEqual(resp.Config, test.req.Config) {
+				t.Fatalf("Actual config: %#v\nExpected config: %#v", resp.Config, test.req.Config)
+			}
+
+			if test.expectInitialized && !db.Initialized {
+				t.Fatalf("Database should be initialized but wasn't")
+			} else if !test.expectInitialized && db.Initialized {
+				t.Fatalf("Database was initialized when it shouldn't")
+			}
+		})
+	}
+}
+
+func TestRedis_NewUser(t *testing.T) {
+	type testCase struct {
+		commands      []string
+		expectErr     bool
+		expectedRules []string
+	}
+
+	tests := map[string]testCase{
+		"default rules": {
+			commands:      nil,
+			expectedRules: []string{"~*", "+@read"},
+		},
+		"templated rules": {
+			commands:      []string{`["~{{username}}:*", "+@read", "+set"]`},
+			expectedRules: []string{"~{{username}}:*", "+@read", "+set"},
+		},
+		"multiple statements": {
+			commands:      []string{`["~cache:*"]`, ` `, `["+@read", "-keys"]`},
+			expectedRules: []string{"~cache:*", "+@read", "-keys"},
+		},
+		"not a JSON array": {
+			commands:  []string{`+@read`},
+			expectErr: true,
+		},
+		"invalid rule": {
+			commands:  []string{`["~*", "bogus"]`},
+			expectErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			node := newFakeRedis(t)
+			db := newInitializedDB(t, node.connectionParams())
+
+			req := dbplugin.NewUserRequest{
+				UsernameConfig: dbplugin.UsernameMetadata{
+					DisplayName: "token",
+					RoleName:    "my-role",
+				},
+				Statements: dbplugin.Statements{
+					Commands: test.commands,
+				},
+				Password:   "3n-4$$3mb1y_P4$$w0rd",
+				Expiration: time.Now().Add(time.Minute),
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			resp, err := db.NewUser(ctx, req)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("err expected, got nil")
+				}
+				node.l.Lock()
+				numUsers := len(node.users)
+				node.l.Unlock()
+				if numUsers != 1 {
+					t.Fatalf("expected no user to be left behind, got %d users", numUsers)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+
+			if !strings.HasPrefix(resp.Username, "V_TOKEN_MY-ROLE_") {
+				t.Fatalf("unexpected username %q", resp.Username)
+			}
+
+			user := node.user(resp.Username)
+			if user == nil {
+				t.Fatalf("user %q was not created", resp.Username)
+			}
+			var expectedRules []string
+			for _, rule := range test.expectedRules {
+				expectedRules = append(expectedRules, strings.ReplaceAll(rule, "{{username}}", resp.Username))
+			}
+			if !reflect.DeepEqual(user.rules, expectedRules) {
+				t.Fatalf("Actual rules: %#v\nExpected rules: %#v", user.rules, expectedRules)
+			}
+			assertLogin(t, node, resp.Username, req.Password)
+		})
+	}
+}
+
+func TestRedis_NewUser_UsernameTemplate(t *testing.T) {
+	node := newFakeRedis(t)
+	db := newInitializedDB(t, makeConfig(node.connectionParams(),
+		"username_template", "{{.DisplayName}}_{{.RoleName}}",
+	))
+
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "cache",
+		},
+		Password:   "Th1s-1s-a-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+	if resp.Username != "token_cache" {
+		t.Fatalf("unexpected username %q", resp.Username)
+	}
+	assertLogin(t, node, resp.Username, "Th1s-1s-a-p4ssw0rd")
+}
+
+func TestRedis_DeleteUser(t *testing.T) {
+	node := newFakeRedis(t)
+	db := newInitializedDB(t, node.connectionParams())
+
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "my-role",
+		},
+		Password:   "Th1s-1s-a-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+	assertLogin(t, node, resp.Username, "Th1s-1s-a-p4ssw0rd")
+
+	dbtesting.AssertDeleteUser(t, db, dbplugin.DeleteUserRequest{
+		Username: resp.Username,
+	})
+	if node.user(resp.Username) != nil {
+		t.Fatalf("user %q was not deleted", resp.Username)
+	}
+
+	// Deleting a user that no longer exists is not an error
+	dbtesting.AssertDeleteUser(t, db, dbplugin.DeleteUserRequest{
+		Username: resp.Username,
+	})
+}
+
+func TestRedis_UpdateUser_Password(t *testing.T) {
+	node := newFakeRedis(t)
+	db := newInitializedDB(t, node.connectionParams())
+
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "static",
+		},
+		Statements: dbplugin.Statements{
+			Commands: []string{`["~*", "+@read"]`},
+		},
+		Password:   "0ld-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+
+	t.Run("rotate password", func(t *testing.T) {
+		dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+			Username: resp.Username,
+			Password: &dbplugin.ChangePassword{
+				NewPassword: "n3w-p4ssw0rd",
+			},
+		})
+
+		user := node.user(resp.Username)
+		if !reflect.DeepEqual(user.passwords, map[string]bool{"n3w-p4ssw0rd": true}) {
+			t.Fatalf("unexpected passwords %#v", user.passwords)
+		}
+		if !reflect.DeepEqual(user.rules, []string{"~*", "+@read"}) {
+			t.Fatalf("rules were not preserved: %#v", user.rules)
+		}
+		assertLogin(t, node, resp.Username, "n3w-p4ssw0rd")
+	})
+
+	t.Run("rotate password with rules", func(t *testing.T) {
+		dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+			Username: resp.Username,
+			Password: &dbplugin.ChangePassword{
+				NewPassword: "n3w3r-p4ssw0rd",
+				Statements: dbplugin.Statements{
+					Commands: []string{`["on", "+set"]`},
+				},
+			},
+		})
+
+		user := node.user(resp.Username)
+		if !reflect.DeepEqual(user.rules, []string{"~*", "+@read", "+set"}) {
+			t.Fatalf("unexpected rules %#v", user.rules)
+		}
+		assertLogin(t, node, resp.Username, "n3w3r-p4ssw0rd")
+	})
+
+	t.Run("missing user", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err := db.UpdateUser(ctx, dbplugin.UpdateUserRequest{
+			Username: "missing",
+			Password: &dbplugin.ChangePassword{
+				NewPassword: "n3w-p4ssw0rd",
+			},
+		})
+		if err == nil {
+			t.Fatalf("err expected, got nil")
+		}
+		if node.user("missing") != nil {
+			t.Fatalf("missing user was created")
+		}
+	})
+
+	t.Run("no changes", func(t *testing.T) {
+		_, err := db.UpdateUser(context.Background(), dbplugin.UpdateUserRequest{
+			Username: resp.Username,
+		})
+		if err == nil {
+			t.Fatalf("err expected, got nil")
+		}
+	})
+}
+
+func TestRedis_UpdateUser_RootPassword(t *testing.T) {
+	node := newFakeRedis(t)
+	db := newInitializedDB(t, node.connectionParams())
+
+	dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+		Username: adminUsername,
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "r0t4t3d-p4ssw0rd",
+		},
+	})
+	assertLogin(t, node, adminUsername, "r0t4t3d-p4ssw0rd")
+
+	// The plugin has to keep working with the rotated password
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "my-role",
+		},
+		Password:   "Th1s-1s-a-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+	if node.user(resp.Username) == nil {
+		t.Fatalf("user %q was not created", resp.Username)
+	}
+}
+
+func TestRedis_Cluster(t *testing.T) {
+	nodes := newFakeRedisCluster(t, 3)
+	db := newInitializedDB(t, makeConfig(nodes[1].connectionParams(), "cluster", true))
+
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "my-role",
+		},
+		Statements: dbplugin.Statements{
+			Commands: []string{`["~{{username}}:*", "+@all"]`},
+		},
+		Password:   "Th1s-1s-a-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+	for _, node := range nodes {
+		user := node.user(resp.Username)
+		if user == nil {
+			t.Fatalf("user %q was not created on %q", resp.Username, node.addr())
+		}
+		expectedRules := []string{"~" + resp.Username + ":*", "+@all"}
+		if !reflect.DeepEqual(user.rules, expectedRules) {
+			t.Fatalf("Actual rules on %q: %#v\nExpected rules: %#v", node.addr(), user.rules, expectedRules)
+		}
+		assertLogin(t, node, resp.Username, "Th1s-1s-a-p4ssw0rd")
+	}
+
+	dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+		Username: resp.Username,
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "n3w-p4ssw0rd",
+		},
+	})
+	for _, node := range nodes {
+		assertLogin(t, node, resp.Username, "n3w-p4ssw0rd")
+	}
+
+	dbtesting.AssertDeleteUser(t, db, dbplugin.DeleteUserRequest{
+		Username: resp.Username,
+	})
+	for _, node := range nodes {
+		if node.user(resp.Username) != nil {
+			t.Fatalf("user %q was not deleted from %q", resp.Username, node.addr())
+		}
+	}
+}
+
+func TestRedis_Cluster_Rollback(t *testing.T) {
+	nodes := newFakeRedisCluster(t, 3)
+	db := newInitializedDB(t, makeConfig(nodes[0].connectionParams(), "cluster", true))
+
+	// Make creating the user fail on the last node, after it succeeded on
+	// the others
+	addrs := sortedAddrs(db.nodes)
+	for _, node := range nodes {
+		if node.addr() == addrs[len(addrs)-1] {
+			node.l.Lock()
+			node.failSetUser = true
+			node.l.Unlock()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := db.NewUser(ctx, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "my-role",
+		},
+		Password:   "Th1s-1s-a-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+	if err == nil {
+		t.Fatalf("err expected, got nil")
+	}
+
+	for _, node := range nodes {
+		node.l.Lock()
+		numUsers := len(node.users)
+		node.l.Unlock()
+		if numUsers != 1 {
+			t.Fatalf("expected the user to be rolled back on %q, got %d users", node.addr(), numUsers)
+		}
+	}
+}
+
+// TestRedis_V010Config verifies that connections and users set up with the
+// external vault-plugin-database-redis v0.1.0, which this plugin replaces
+// under the same name, keep working.
+func TestRedis_V010Config(t *testing.T) {
+	node := newFakeRedis(t)
+
+	// The connection details as stored by the database secrets engine for
+	// v0.1.0, which decodes them from JSON
+	host, port, _ := net.SplitHostPort(node.addr())
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(fmt.Sprintf(`{
+		"host": %q,
+		"port": %s,
+		"username": %q,
+		"password": %q,
+		"tls": false,
+		"insecure_tls": false,
+		"ca_cert": ""
+	}`, host, port, adminUsername, adminPassword)), &config); err != nil {
+		t.Fatal(err)
+	}
+	db := newInitializedDB(t, config)
+
+	// A user created by v0.1.0, whose usernames were upper cased
+	legacyUsername := "V_TOKEN_MY-ROLE_KZIC2UUYNTQI1BCMPD1O-1666000000"
+	node.l.Lock()
+	node.users[legacyUsername] = &fakeUser{
+		enabled:   true,
+		passwords: map[string]bool{"l3g4cy-p4ssw0rd": true},
+		rules:     []string{"~*", "+@read"},
+	}
+	node.l.Unlock()
+
+	// Creation statements of v0.1.0 roles are a single JSON array of rules
+	resp := dbtesting.AssertNewUser(t, db, dbplugin.NewUserRequest{
+		UsernameConfig: dbplugin.UsernameMetadata{
+			DisplayName: "token",
+			RoleName:    "my-role",
+		},
+		Statements: dbplugin.Statements{
+			Commands: []string{`["~*", "+@read"]`},
+		},
+		Password:   "Th1s-1s-a-p4ssw0rd",
+		Expiration: time.Now().Add(time.Minute),
+	})
+	if !strings.HasPrefix(resp.Username, "V_TOKEN_MY-ROLE_") {
+		t.Fatalf("unexpected username %q", resp.Username)
+	}
+	if user := node.user(resp.Username); !reflect.DeepEqual(user.rules, []string{"~*", "+@read"}) {
+		t.Fatalf("unexpected rules %#v", user.rules)
+	}
+	assertLogin(t, node, resp.Username, "Th1s-1s-a-p4ssw0rd")
+
+	// Users created by v0.1.0 can be rotated and revoked
+	dbtesting.AssertUpdateUser(t, db, dbplugin.UpdateUserRequest{
+		Username: legacyUsername,
+		Password: &dbplugin.ChangePassword{
+			NewPassword: "n3w-p4ssw0rd",
+		},
+	})
+	if user := node.user(legacyUsername); !reflect.DeepEqual(user.rules, []string{"~*", "+@read"}) {
+		t.Fatalf("rules were not preserved: %#v", user.rules)
+	}
+	assertLogin(t, node, legacyUsername, "n3w-p4ssw0rd")
+
+	dbtesting.AssertDeleteUser(t, db, dbplugin.DeleteUserRequest{
+		Username: legacyUsername,
+	})
+	if node.user(legacyUsername) != nil {
+		t.Fatalf("user %q was not deleted", legacyUsername)
+	}
+}
@@ -61,6 +61,7 @@ func TestConversionsHaveAllFields(t *testing.T) {
 			},
 			CredentialType: CredentialTypeRSAPrivateKey,
 			PublicKey:      []byte("-----BEGIN PUBLIC KEY-----"),
+			Subject:        "CN=subject",
 			Password:       "password",
 			Expiration:     time.Now(),
 		}
@@ -112,6 +113,7 @@ func TestConversionsHaveAllFields(t *testing.T) {
 					},
 				},
 			},
+			SelfManagedPassword: "currentpassword",
 		}
 
 		protoReq, err := updateUserReqToProto(req)
@@ -190,6 +192,7 @@ func TestConversionsHaveAllFields(t *testing.T) {
 					},
 				},
 			},
+			SelfManagedPassword: "currentpassword",
 		}
 
 		protoReq, err := getUpdateUserRequest(req)
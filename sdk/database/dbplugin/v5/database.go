@@ -120,6 +120,11 @@ type NewUserRequest struct {
 	// The value is set when the credential type is CredentialTypeRSAPrivateKey.
 	PublicKey []byte
 
+	// Subject is the distinguished name of the client certificate credential
+	// the user authenticates with, such as "CN=v-token-role-abc".
+	// The value is set when the credential type is CredentialTypeClientCertificate.
+	Subject string
+
 	// Expiration of the user. Not all database plugins will support this.
 	Expiration time.Time
 }
@@ -143,6 +148,7 @@ type CredentialType int
 const (
 	CredentialTypePassword CredentialType = iota
 	CredentialTypeRSAPrivateKey
+	CredentialTypeClientCertificate
 )
 
 func (k CredentialType) String() string {
@@ -151,6 +157,8 @@ func (k CredentialType) String() string {
 		return "password"
 	case CredentialTypeRSAPrivateKey:
 		return "rsa_private_key"
+	case CredentialTypeClientCertificate:
+		return "client_certificate"
 	default:
 		return "unknown"
 	}
@@ -182,6 +190,11 @@ type UpdateUserRequest struct {
 	// Expiration indicates the new expiration date to change to.
 	// If nil, no change is requested.
 	Expiration *ChangeExpiration
+
+	// SelfManagedPassword is the current password of the user. It is set for
+	// self-managed static accounts, in which case the plugin should connect
+	// to the database as the user in order to make the changes.
+	SelfManagedPassword string
 }
 
 // ChangePublicKey of a given user
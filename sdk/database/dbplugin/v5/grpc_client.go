@@ -101,6 +101,10 @@ func newUserReqToProto(req NewUserRequest) (*proto.NewUserRequest, error) {
 		if len(req.PublicKey) == 0 {
 			return nil, fmt.Errorf("missing public key credential")
 		}
+	case CredentialTypeClientCertificate:
+		if req.Subject == "" {
+			return nil, fmt.Errorf("missing certificate subject")
+		}
 	default:
 		return nil, fmt.Errorf("unknown credential type")
 	}
@@ -118,6 +122,7 @@ func newUserReqToProto(req NewUserRequest) (*proto.NewUserRequest, error) {
 		CredentialType: int32(req.CredentialType),
 		Password:       req.Password,
 		PublicKey:      req.PublicKey,
+		Subject:        req.Subject,
 		Expiration:     expiration,
 		Statements: &proto.Statements{
 			Commands: req.Statements.Commands,
@@ -191,11 +196,12 @@ func updateUserReqToProto(req UpdateUserRequest) (*proto.UpdateUserRequest, erro
 	}
 
 	rpcReq := &proto.UpdateUserRequest{
-		Username:       req.Username,
-		CredentialType: int32(req.CredentialType),
-		Password:       password,
-		PublicKey:      publicKey,
-		Expiration:     expiration,
+		Username:            req.Username,
+		CredentialType:      int32(req.CredentialType),
+		Password:            password,
+		PublicKey:           publicKey,
+		Expiration:          expiration,
+		SelfManagedPassword: req.SelfManagedPassword,
 	}
 	return rpcReq, nil
 }
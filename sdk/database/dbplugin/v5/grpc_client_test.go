@@ -131,6 +131,16 @@ func TestGRPCClient_NewUser(t *testing.T) {
 			doneCtx:   runningCtx,
 			assertErr: assertErrNotNil,
 		},
+		"missing certificate subject": {
+			client: fakeClient{},
+			req: NewUserRequest{
+				CredentialType: CredentialTypeClientCertificate,
+				Subject:        "",
+				Expiration:     time.Now(),
+			},
+			doneCtx:   runningCtx,
+			assertErr: assertErrNotNil,
+		},
 		"bad expiration": {
 			client: fakeClient{},
 			req: NewUserRequest{
@@ -178,6 +188,23 @@ func TestGRPCClient_NewUser(t *testing.T) {
 			},
 			assertErr: assertErrNil,
 		},
+		"happy path with client certificate": {
+			client: fakeClient{
+				newUserResp: &proto.NewUserResponse{
+					Username: "new_user",
+				},
+			},
+			req: NewUserRequest{
+				CredentialType: CredentialTypeClientCertificate,
+				Subject:        "CN=new_user",
+				Expiration:     time.Now(),
+			},
+			doneCtx: runningCtx,
+			expectedResp: NewUserResponse{
+				Username: "new_user",
+			},
+			assertErr: assertErrNil,
+		},
 	}
 
 	for name, test := range tests {
@@ -6,7 +6,14 @@ import (
 	"sync"
 )
 
-var ErrNotInitialized = errors.New("connection has not been initialized")
+var (
+	ErrNotInitialized = errors.New("connection has not been initialized")
+
+	// ErrSelfManagedConnection is returned when the connection of a
+	// self-managed database configuration is requested, as it has no
+	// credentials of its own.
+	ErrSelfManagedConnection = errors.New("self-managed connections can only be used to manage static accounts with their own credentials")
+)
 
 // ConnectionProducer can be used as an embedded interface in the Database
 // definition. It implements the methods dealing with individual database
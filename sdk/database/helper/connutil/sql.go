@@ -27,6 +27,7 @@ type SQLConnectionProducer struct {
 	Username                 string      `json:"username" mapstructure:"username" structs:"username"`
 	Password                 string      `json:"password" mapstructure:"password" structs:"password"`
 	DisableEscaping          bool        `json:"disable_escaping" mapstructure:"disable_escaping" structs:"disable_escaping"`
+	SelfManaged              bool        `json:"self_managed" mapstructure:"self_managed" structs:"self_managed"`
 
 	Type                  string
 	RawConfig             map[string]interface{}
@@ -56,6 +57,19 @@ func (c *SQLConnectionProducer) Init(ctx context.Context, conf map[string]interf
 		return nil, fmt.Errorf("connection_url cannot be empty")
 	}
 
+	if c.SelfManaged {
+		// Self-managed connections have no credentials of their own, as
+		// the connection URL is templated with the credentials of each
+		// static account to connect as.
+		if c.Username != "" || c.Password != "" {
+			return nil, fmt.Errorf("username and password cannot be set for self-managed connections")
+		}
+		if !strings.Contains(c.ConnectionURL, "{{username}}") ||
+			!strings.Contains(c.ConnectionURL, "{{password}}") {
+			return nil, fmt.Errorf("connection_url must contain the {{username}} and {{password}} template variables for self-managed connections")
+		}
+	}
+
 	// Do not allow the username or password template pattern to be used as
 	// part of the user-supplied username or password
 	if strings.Contains(c.Username, "{{username}}") ||
@@ -66,24 +80,9 @@ func (c *SQLConnectionProducer) Init(ctx context.Context, conf map[string]interf
 		return nil, fmt.Errorf("username and/or password cannot contain the template variables")
 	}
 
-	// Don't escape special characters for MySQL password
-	// Also don't escape special characters for the username and password if
-	// the disable_escaping parameter is set to true
-	username := c.Username
-	password := c.Password
-	if !c.DisableEscaping {
-		username = url.PathEscape(c.Username)
+	if !c.SelfManaged {
+		c.ConnectionURL = c.connectionURL(c.Username, c.Password)
 	}
-	if (c.Type != "mysql") && !c.DisableEscaping {
-		password = url.PathEscape(c.Password)
-	}
-
-	// QueryHelper doesn't do any SQL escaping, but if it starts to do so
-	// then maybe we won't be able to use it to do URL substitution any more.
-	c.ConnectionURL = dbutil.QueryHelper(c.ConnectionURL, map[string]string{
-		"username": username,
-		"password": password,
-	})
 
 	if c.MaxOpenConnections == 0 {
 		c.MaxOpenConnections = 4
@@ -108,7 +107,8 @@ func (c *SQLConnectionProducer) Init(ctx context.Context, conf map[string]interf
 	// and the connection can be established at a later time.
 	c.Initialized = true
 
-	if verifyConnection {
+	// There are no credentials to verify self-managed connections with
+	if verifyConnection && !c.SelfManaged {
 		if _, err := c.Connection(ctx); err != nil {
 			return nil, errwrap.Wrapf("error verifying connection: {{err}}", err)
 		}
@@ -125,6 +125,9 @@ func (c *SQLConnectionProducer) Connection(ctx context.Context) (interface{}, er
 	if !c.Initialized {
 		return nil, ErrNotInitialized
 	}
+	if c.SelfManaged {
+		return nil, ErrSelfManagedConnection
+	}
 
 	// If we already have a DB, test it and return
 	if c.db != nil {
@@ -136,15 +139,70 @@ func (c *SQLConnectionProducer) Connection(ctx context.Context) (interface{}, er
 		c.db.Close()
 	}
 
+	// Otherwise, attempt to make connection
+	var err error
+	c.db, err = c.open(c.ConnectionURL)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.db, nil
+}
+
+// StaticConnection returns a new connection to the database authenticated as
+// the given static account of a self-managed connection. The caller is
+// responsible for closing the returned connection.
+func (c *SQLConnectionProducer) StaticConnection(ctx context.Context, username, password string) (*sql.DB, error) {
+	if !c.Initialized {
+		return nil, ErrNotInitialized
+	}
+	if !c.SelfManaged {
+		return nil, fmt.Errorf("static account connections are only supported for self-managed connections")
+	}
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("missing static account credentials")
+	}
+
+	db, err := c.open(c.connectionURL(username, password))
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// connectionURL returns the connection URL with the given credentials
+// substituted in.
+func (c *SQLConnectionProducer) connectionURL(username, password string) string {
+	// Don't escape special characters for MySQL password
+	// Also don't escape special characters for the username and password if
+	// the disable_escaping parameter is set to true
+	if !c.DisableEscaping {
+		username = url.PathEscape(username)
+	}
+	if (c.Type != "mysql") && !c.DisableEscaping {
+		password = url.PathEscape(password)
+	}
+
+	// QueryHelper doesn't do any SQL escaping, but if it starts to do so
+	// then maybe we won't be able to use it to do URL substitution any more.
+	return dbutil.QueryHelper(c.ConnectionURL, map[string]string{
+		"username": username,
+		"password": password,
+	})
+}
+
+func (c *SQLConnectionProducer) open(conn string) (*sql.DB, error) {
 	// For mssql backend, switch to sqlserver instead
 	dbType := c.Type
 	if c.Type == "mssql" {
 		dbType = "sqlserver"
 	}
 
-	// Otherwise, attempt to make connection
-	conn := c.ConnectionURL
-
 	// PostgreSQL specific settings
 	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
 		// Ensure timezone is set to UTC for all the connections
@@ -160,19 +218,18 @@ func (c *SQLConnectionProducer) Connection(ctx context.Context) (interface{}, er
 		}
 	}
 
-	var err error
-	c.db, err = sql.Open(dbType, conn)
+	db, err := sql.Open(dbType, conn)
 	if err != nil {
 		return nil, err
 	}
 
 	// Set some connection pool settings. We don't need much of this,
 	// since the request rate shouldn't be high.
-	c.db.SetMaxOpenConns(c.MaxOpenConnections)
-	c.db.SetMaxIdleConns(c.MaxIdleConnections)
-	c.db.SetConnMaxLifetime(c.maxConnectionLifetime)
+	db.SetMaxOpenConns(c.MaxOpenConnections)
+	db.SetMaxIdleConns(c.MaxIdleConnections)
+	db.SetConnMaxLifetime(c.maxConnectionLifetime)
 
-	return c.db, nil
+	return db, nil
 }
 
 func (c *SQLConnectionProducer) SecretValues() map[string]interface{} {
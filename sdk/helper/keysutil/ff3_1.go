@@ -0,0 +1,281 @@
+package keysutil
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+	"math/big"
+	"unicode/utf8"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+)
+
+// This file implements the FF3-1 format-preserving encryption mode of NIST
+// SP 800-38G Rev. 1, used by keys of type KeyType_AES256_FF3_1.
+
+const (
+	// FF31TweakSize is the size in bytes of an FF3-1 tweak
+	FF31TweakSize = 7
+
+	ff31Rounds   = 8
+	ff31MaxRadix = 1 << 16
+)
+
+// ff31Cipher encrypts strings of characters from an alphabet, each character
+// being a numeral of the radix given by the size of the alphabet.
+type ff31Cipher struct {
+	block cipher.Block
+
+	alphabet []rune
+	numerals map[rune]int
+	radix    *big.Int
+
+	minLen int
+	maxLen int
+}
+
+// ValidateFF31Alphabet checks that the alphabet can be used for FF3-1
+// encryption: it must consist of at least two and at most 65536 distinct
+// characters.
+func ValidateFF31Alphabet(alphabet string) error {
+	_, _, err := parseFF31Alphabet(alphabet)
+	return err
+}
+
+func parseFF31Alphabet(alphabet string) ([]rune, map[rune]int, error) {
+	if !utf8.ValidString(alphabet) {
+		return nil, nil, fmt.Errorf("alphabet is not valid UTF-8")
+	}
+
+	runes := []rune(alphabet)
+	if len(runes) < 2 || len(runes) > ff31MaxRadix {
+		return nil, nil, fmt.Errorf("alphabet must contain between 2 and %d characters", ff31MaxRadix)
+	}
+
+	numerals := make(map[rune]int, len(runes))
+	for i, r := range runes {
+		if _, ok := numerals[r]; ok {
+			return nil, nil, fmt.Errorf("alphabet contains duplicate character %q", r)
+		}
+		numerals[r] = i
+	}
+
+	return runes, numerals, nil
+}
+
+// FF31LengthBounds returns the minimum and maximum length of the strings that
+// can be encrypted with the given alphabet. The minimum is set so that the
+// domain holds at least one million values, the maximum by the 96-bit limit
+// on each half of the input.
+func FF31LengthBounds(alphabet string) (int, int, error) {
+	runes, _, err := parseFF31Alphabet(alphabet)
+	if err != nil {
+		return 0, 0, err
+	}
+	minLen, maxLen := ff31LengthBounds(big.NewInt(int64(len(runes))))
+	return minLen, maxLen, nil
+}
+
+func ff31LengthBounds(radix *big.Int) (int, int) {
+	domain := big.NewInt(1)
+	minDomain := big.NewInt(1000000)
+	minLen := 0
+	for domain.Cmp(minDomain) < 0 {
+		domain.Mul(domain, radix)
+		minLen++
+	}
+	if minLen < 2 {
+		minLen = 2
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), 96)
+	half := 0
+	for domain.SetInt64(1); ; half++ {
+		domain.Mul(domain, radix)
+		if domain.Cmp(limit) > 0 {
+			break
+		}
+	}
+
+	return minLen, 2 * half
+}
+
+func newFF31Cipher(key []byte, alphabet string) (*ff31Cipher, error) {
+	runes, numerals, err := parseFF31Alphabet(alphabet)
+	if err != nil {
+		return nil, err
+	}
+
+	// FF3-1 uses the AES key with its bytes reversed
+	reversed := make([]byte, len(key))
+	for i, b := range key {
+		reversed[len(key)-1-i] = b
+	}
+	block, err := aes.NewCipher(reversed)
+	if err != nil {
+		return nil, err
+	}
+
+	radix := big.NewInt(int64(len(runes)))
+	minLen, maxLen := ff31LengthBounds(radix)
+
+	return &ff31Cipher{
+		block:    block,
+		alphabet: runes,
+		numerals: numerals,
+		radix:    radix,
+		minLen:   minLen,
+		maxLen:   maxLen,
+	}, nil
+}
+
+// Encrypt encrypts the value under the given 56-bit tweak.
+func (c *ff31Cipher) Encrypt(tweak []byte, value string) (string, error) {
+	return c.crypt(tweak, value, true)
+}
+
+// Decrypt decrypts the value under the given 56-bit tweak.
+func (c *ff31Cipher) Decrypt(tweak []byte, value string) (string, error) {
+	return c.crypt(tweak, value, false)
+}
+
+func (c *ff31Cipher) crypt(tweak []byte, value string, encrypt bool) (string, error) {
+	if len(tweak) != FF31TweakSize {
+		return "", errutil.UserError{Err: fmt.Sprintf("tweak must be %d bytes", FF31TweakSize)}
+	}
+
+	x, err := c.toNumerals(value)
+	if err != nil {
+		return "", err
+	}
+	if len(x) < c.minLen || len(x) > c.maxLen {
+		return "", errutil.UserError{Err: fmt.Sprintf("value must be between %d and %d characters long", c.minLen, c.maxLen)}
+	}
+
+	// Split the tweak into its 28-bit left and right halves
+	var tl, tr [4]byte
+	copy(tl[:], tweak[:4])
+	tl[3] &= 0xf0
+	copy(tr[:], tweak[4:])
+	tr[3] = tweak[3] << 4
+
+	if encrypt {
+		return c.fromNumerals(c.encryptNumerals(tl, tr, x)), nil
+	}
+	return c.fromNumerals(c.decryptNumerals(tl, tr, x)), nil
+}
+
+// encryptNumerals implements FF3-1 encryption, given the halves of the tweak.
+func (c *ff31Cipher) encryptNumerals(tl, tr [4]byte, x []int) []int {
+	u := (len(x) + 1) / 2
+	v := len(x) - u
+	a := append([]int(nil), x[:u]...)
+	b := append([]int(nil), x[u:]...)
+
+	for i := 0; i < ff31Rounds; i++ {
+		m, w := u, tr
+		if i%2 == 1 {
+			m, w = v, tl
+		}
+
+		y := c.roundValue(w, i, b)
+		num := c.numRev(a)
+		num.Add(num, y)
+		num.Mod(num, new(big.Int).Exp(c.radix, big.NewInt(int64(m)), nil))
+
+		a, b = b, c.strRev(m, num)
+	}
+
+	return append(a, b...)
+}
+
+// decryptNumerals implements FF3-1 decryption, given the halves of the tweak.
+func (c *ff31Cipher) decryptNumerals(tl, tr [4]byte, x []int) []int {
+	u := (len(x) + 1) / 2
+	v := len(x) - u
+	a := append([]int(nil), x[:u]...)
+	b := append([]int(nil), x[u:]...)
+
+	for i := ff31Rounds - 1; i >= 0; i-- {
+		m, w := u, tr
+		if i%2 == 1 {
+			m, w = v, tl
+		}
+
+		y := c.roundValue(w, i, a)
+		num := c.numRev(b)
+		num.Sub(num, y)
+		num.Mod(num, new(big.Int).Exp(c.radix, big.NewInt(int64(m)), nil))
+
+		a, b = c.strRev(m, num), a
+	}
+
+	return append(a, b...)
+}
+
+// roundValue computes the round function output y for round i, encrypting
+// the 128-bit block W xor [i]^4 || [NUM_radix(REV(half))]^12 with the byte
+// order reversed on both sides of the block cipher.
+func (c *ff31Cipher) roundValue(w [4]byte, i int, half []int) *big.Int {
+	var p [aes.BlockSize]byte
+	copy(p[:4], w[:])
+	p[3] ^= byte(i)
+	c.numRev(half).FillBytes(p[4:])
+
+	reverseBytes(p[:])
+	c.block.Encrypt(p[:], p[:])
+	reverseBytes(p[:])
+
+	return new(big.Int).SetBytes(p[:])
+}
+
+// numRev returns NUM_radix(REV(x)), treating x as little endian.
+func (c *ff31Cipher) numRev(x []int) *big.Int {
+	num := new(big.Int)
+	for i := len(x) - 1; i >= 0; i-- {
+		num.Mul(num, c.radix)
+		num.Add(num, big.NewInt(int64(x[i])))
+	}
+	return num
+}
+
+// strRev returns REV(STR^m_radix(num)), the m little endian numerals of num.
+func (c *ff31Cipher) strRev(m int, num *big.Int) []int {
+	out := make([]int, m)
+	num = new(big.Int).Set(num)
+	rem := new(big.Int)
+	for i := 0; i < m; i++ {
+		num.QuoRem(num, c.radix, rem)
+		out[i] = int(rem.Int64())
+	}
+	return out
+}
+
+func (c *ff31Cipher) toNumerals(value string) ([]int, error) {
+	if !utf8.ValidString(value) {
+		return nil, errutil.UserError{Err: "value is not valid UTF-8"}
+	}
+	x := make([]int, 0, len(value))
+	for _, r := range value {
+		n, ok := c.numerals[r]
+		if !ok {
+			return nil, errutil.UserError{Err: fmt.Sprintf("value contains character %q not in the alphabet of the key", r)}
+		}
+		x = append(x, n)
+	}
+	return x, nil
+}
+
+func (c *ff31Cipher) fromNumerals(x []int) string {
+	out := make([]rune, len(x))
+	for i, n := range x {
+		out[i] = c.alphabet[n]
+	}
+	return string(out)
+}
+
+func reverseBytes(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
@@ -59,6 +59,9 @@ type PolicyRequest struct {
 
 	// AllowImportedKeyRotation indicates whether an imported key may be rotated by Vault
 	AllowImportedKeyRotation bool
+
+	// The alphabet of format preserving encryption keys
+	FPEAlphabet string
 }
 
 type LockManager struct {
@@ -381,6 +384,20 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 				cleanup()
 				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
 			}
+		case KeyType_AES256_FF3_1:
+			if req.Derived || req.Convergent {
+				cleanup()
+				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+			}
+			if err := ValidateFF31Alphabet(req.FPEAlphabet); err != nil {
+				cleanup()
+				return nil, false, fmt.Errorf("invalid alphabet: %w", err)
+			}
+		case KeyType_MLDSA44, KeyType_MLDSA65, KeyType_MLDSA87, KeyType_MLDSA44_ECDSA_P256, KeyType_MLDSA65_ECDSA_P384, KeyType_MLDSA87_ECDSA_P521:
+			if req.Derived || req.Convergent {
+				cleanup()
+				return nil, false, fmt.Errorf("key derivation and convergent encryption not supported for keys of type %v", req.KeyType)
+			}
 
 		default:
 			cleanup()
@@ -398,6 +415,10 @@ func (lm *LockManager) GetPolicy(ctx context.Context, req PolicyRequest, rand io
 			KeySize:              req.KeySize,
 		}
 
+		if req.KeyType.FPESupported() {
+			p.FPEAlphabet = req.FPEAlphabet
+		}
+
 		if req.Derived {
 			p.KDF = Kdf_hkdf_sha256
 			if req.Convergent {
@@ -0,0 +1,175 @@
+package keysutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"encoding/asn1"
+	"encoding/base64"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/vault/sdk/helper/errutil"
+	"github.com/hashicorp/vault/sdk/helper/mldsa"
+)
+
+// This file implements the ML-DSA (FIPS 204) signing key types, and the
+// hybrid key types that combine ML-DSA with ECDSA.
+//
+// ML-DSA keys store their 32-byte seed in KeyEntry.Key and sign the input
+// with an empty context, so that signatures can be checked by any FIPS 204
+// implementation. Their public key is the base64 encoded FIPS 204 public key.
+//
+// Hybrid keys additionally store an ECDSA key in the EC_* fields. Their
+// public key is the ML-DSA public key followed by the uncompressed ECDSA
+// point, and their signatures are the fixed-length ML-DSA signature followed
+// by an ASN.1 ECDSA signature. Both components are bound to the name of the
+// key type, so that neither can be stripped off and passed off as a
+// signature of a non-hybrid key, and both must be valid for the signature to
+// verify.
+
+// mldsaKeyParams returns the ML-DSA parameter set of the key type, along with
+// the curve and hash of its ECDSA component for hybrid key types.
+func mldsaKeyParams(kt KeyType) (*mldsa.Params, elliptic.Curve, crypto.Hash) {
+	switch kt {
+	case KeyType_MLDSA44:
+		return mldsa.MLDSA44, nil, 0
+	case KeyType_MLDSA65:
+		return mldsa.MLDSA65, nil, 0
+	case KeyType_MLDSA87:
+		return mldsa.MLDSA87, nil, 0
+	case KeyType_MLDSA44_ECDSA_P256:
+		return mldsa.MLDSA44, elliptic.P256(), crypto.SHA256
+	case KeyType_MLDSA65_ECDSA_P384:
+		return mldsa.MLDSA65, elliptic.P384(), crypto.SHA384
+	case KeyType_MLDSA87_ECDSA_P521:
+		return mldsa.MLDSA87, elliptic.P521(), crypto.SHA512
+	}
+	return nil, nil, 0
+}
+
+// generateMLDSAKey generates the key material of a new version of an ML-DSA
+// or hybrid key.
+func generateMLDSAKey(kt KeyType, randReader io.Reader, entry *KeyEntry) error {
+	params, curve, _ := mldsaKeyParams(kt)
+	if params == nil {
+		return fmt.Errorf("unsupported key type %v", kt)
+	}
+
+	sk, err := mldsa.GenerateKey(params, randReader)
+	if err != nil {
+		return err
+	}
+	entry.Key = sk.Seed()
+	pubKey := sk.PublicKey().Bytes()
+
+	if curve != nil {
+		ecKey, err := ecdsa.GenerateKey(curve, randReader)
+		if err != nil {
+			return err
+		}
+		entry.EC_D = ecKey.D
+		entry.EC_X = ecKey.X
+		entry.EC_Y = ecKey.Y
+		pubKey = append(pubKey, elliptic.Marshal(curve, ecKey.X, ecKey.Y)...)
+	}
+
+	entry.FormattedPublicKey = base64.StdEncoding.EncodeToString(pubKey)
+	return nil
+}
+
+// signMLDSA signs the input with an ML-DSA or hybrid key.
+func signMLDSA(kt KeyType, randReader io.Reader, entry *KeyEntry, input []byte) ([]byte, error) {
+	params, curve, hash := mldsaKeyParams(kt)
+	if params == nil {
+		return nil, fmt.Errorf("unsupported key type %v", kt)
+	}
+
+	sk, err := mldsa.NewPrivateKey(params, entry.Key)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error loading ML-DSA key: %v", err)}
+	}
+
+	if curve == nil {
+		return sk.Sign(randReader, input, nil)
+	}
+
+	label := []byte(kt.String())
+	sig, err := sk.Sign(randReader, input, label)
+	if err != nil {
+		return nil, err
+	}
+
+	ecKey := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: curve,
+			X:     entry.EC_X,
+			Y:     entry.EC_Y,
+		},
+		D: entry.EC_D,
+	}
+	r, s, err := ecdsa.Sign(randReader, ecKey, hybridECDSADigest(hash, label, input))
+	if err != nil {
+		return nil, err
+	}
+	ecSig, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	if err != nil {
+		return nil, err
+	}
+
+	return append(sig, ecSig...), nil
+}
+
+// verifyMLDSA verifies the signature of the input with an ML-DSA or hybrid
+// key.
+func verifyMLDSA(kt KeyType, entry *KeyEntry, input, sig []byte) (bool, error) {
+	params, curve, hash := mldsaKeyParams(kt)
+	if params == nil {
+		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", kt)}
+	}
+
+	sk, err := mldsa.NewPrivateKey(params, entry.Key)
+	if err != nil {
+		return false, errutil.InternalError{Err: fmt.Sprintf("error loading ML-DSA key: %v", err)}
+	}
+
+	if curve == nil {
+		return mldsa.Verify(sk.PublicKey(), input, sig, nil), nil
+	}
+
+	if len(sig) <= params.SignatureSize() {
+		return false, errutil.UserError{Err: "supplied signature is invalid"}
+	}
+	mldsaSig, ecSigBytes := sig[:params.SignatureSize()], sig[params.SignatureSize():]
+
+	var ecSig ecdsaSignature
+	rest, err := asn1.Unmarshal(ecSigBytes, &ecSig)
+	if err != nil {
+		return false, errutil.UserError{Err: "supplied signature is invalid"}
+	}
+	if len(rest) != 0 {
+		return false, errutil.UserError{Err: "supplied signature contains extra data"}
+	}
+
+	label := []byte(kt.String())
+	ecKey := &ecdsa.PublicKey{
+		Curve: curve,
+		X:     entry.EC_X,
+		Y:     entry.EC_Y,
+	}
+
+	// Evaluate both components regardless of the outcome of the first
+	mldsaValid := mldsa.Verify(sk.PublicKey(), input, mldsaSig, label)
+	ecValid := ecdsa.Verify(ecKey, hybridECDSADigest(hash, label, input), ecSig.R, ecSig.S)
+
+	return mldsaValid && ecValid, nil
+}
+
+// hybridECDSADigest returns the digest signed by the ECDSA component of
+// hybrid signatures, which is bound to the key type.
+func hybridECDSADigest(hash crypto.Hash, label, input []byte) []byte {
+	h := hash.New()
+	h.Write(label)
+	h.Write(input)
+	return h.Sum(nil)
+}
@@ -0,0 +1,106 @@
+package keysutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/helper/mldsa"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func Test_MLDSA(t *testing.T) {
+	ctx := context.Background()
+	input := []byte("Sphinx of black quartz, judge my vow")
+
+	keyTypes := []KeyType{
+		KeyType_MLDSA44,
+		KeyType_MLDSA65,
+		KeyType_MLDSA87,
+		KeyType_MLDSA44_ECDSA_P256,
+		KeyType_MLDSA65_ECDSA_P384,
+		KeyType_MLDSA87_ECDSA_P521,
+	}
+
+	for _, keyType := range keyTypes {
+		t.Run(keyType.String(), func(t *testing.T) {
+			p := NewPolicy(PolicyConfig{
+				Name: "test",
+				Type: keyType,
+			})
+			if err := p.Rotate(ctx, &logical.InmemStorage{}, rand.Reader); err != nil {
+				t.Fatal(err)
+			}
+
+			if !keyType.SigningSupported() || keyType.HashSignatureInput() {
+				t.Fatal("bad key type capabilities")
+			}
+
+			for _, marshaling := range []MarshalingType{MarshalingTypeASN1, MarshalingTypeJWS} {
+				options := &SigningOptions{Marshaling: marshaling}
+				sig, err := p.SignWithOptions(0, nil, input, options)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				valid, err := p.VerifySignatureWithOptions(nil, input, sig.Signature, options)
+				if err != nil || !valid {
+					t.Fatalf("signature did not verify: %v", err)
+				}
+
+				valid, _ = p.VerifySignatureWithOptions(nil, []byte("other input"), sig.Signature, options)
+				if valid {
+					t.Fatal("signature verified for other input")
+				}
+			}
+
+			// The public key of plain ML-DSA keys verifies signatures
+			// with any FIPS 204 implementation
+			params, curve, _ := mldsaKeyParams(keyType)
+			sig, err := p.SignWithOptions(0, nil, input, &SigningOptions{Marshaling: MarshalingTypeASN1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			sigBytes, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sig.Signature, "vault:v1:"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubKeyBytes, err := base64.StdEncoding.DecodeString(p.Keys["1"].FormattedPublicKey)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pubKey, err := mldsa.ParsePublicKey(params, pubKeyBytes[:params.PublicKeySize()])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if curve == nil {
+				if !mldsa.Verify(pubKey, input, sigBytes, nil) {
+					t.Fatal("signature did not verify with the public key")
+				}
+				return
+			}
+
+			// Both components of hybrid signatures are required
+			mldsaSig := sigBytes[:params.SignatureSize()]
+			if mldsa.Verify(pubKey, input, mldsaSig, nil) {
+				t.Fatal("ML-DSA component of hybrid signature verified without the key type context")
+			}
+			if !mldsa.Verify(pubKey, input, mldsaSig, []byte(keyType.String())) {
+				t.Fatal("ML-DSA component of hybrid signature did not verify")
+			}
+			stripped := "vault:v1:" + base64.StdEncoding.EncodeToString(mldsaSig)
+			if _, err := p.VerifySignatureWithOptions(nil, input, stripped, &SigningOptions{Marshaling: MarshalingTypeASN1}); err == nil {
+				t.Fatal("expected error verifying hybrid signature without ECDSA component")
+			}
+
+			tampered := append([]byte(nil), sigBytes...)
+			tampered[len(tampered)-1] ^= 0x01
+			valid, _ := p.VerifySignatureWithOptions(nil, input, "vault:v1:"+base64.StdEncoding.EncodeToString(tampered), &SigningOptions{Marshaling: MarshalingTypeASN1})
+			if valid {
+				t.Fatal("hybrid signature with tampered ECDSA component verified")
+			}
+		})
+	}
+}
@@ -64,6 +64,13 @@ const (
 	KeyType_RSA3072
 	KeyType_MANAGED_KEY
 	KeyType_HMAC
+	KeyType_AES256_FF3_1
+	KeyType_MLDSA44
+	KeyType_MLDSA65
+	KeyType_MLDSA87
+	KeyType_MLDSA44_ECDSA_P256
+	KeyType_MLDSA65_ECDSA_P384
+	KeyType_MLDSA87_ECDSA_P521
 )
 
 const (
@@ -131,6 +138,8 @@ func (kt KeyType) SigningSupported() bool {
 	switch kt {
 	case KeyType_ECDSA_P256, KeyType_ECDSA_P384, KeyType_ECDSA_P521, KeyType_ED25519, KeyType_RSA2048, KeyType_RSA3072, KeyType_RSA4096:
 		return true
+	case KeyType_MLDSA44, KeyType_MLDSA65, KeyType_MLDSA87, KeyType_MLDSA44_ECDSA_P256, KeyType_MLDSA65_ECDSA_P384, KeyType_MLDSA87_ECDSA_P521:
+		return true
 	}
 	return false
 }
@@ -159,6 +168,14 @@ func (kt KeyType) AssociatedDataSupported() bool {
 	return false
 }
 
+func (kt KeyType) FPESupported() bool {
+	switch kt {
+	case KeyType_AES256_FF3_1:
+		return true
+	}
+	return false
+}
+
 func (kt KeyType) String() string {
 	switch kt {
 	case KeyType_AES128_GCM96:
@@ -183,6 +200,20 @@ func (kt KeyType) String() string {
 		return "rsa-4096"
 	case KeyType_HMAC:
 		return "hmac"
+	case KeyType_AES256_FF3_1:
+		return "aes256-ff3-1"
+	case KeyType_MLDSA44:
+		return "ml-dsa-44"
+	case KeyType_MLDSA65:
+		return "ml-dsa-65"
+	case KeyType_MLDSA87:
+		return "ml-dsa-87"
+	case KeyType_MLDSA44_ECDSA_P256:
+		return "ml-dsa-44-ecdsa-p256"
+	case KeyType_MLDSA65_ECDSA_P384:
+		return "ml-dsa-65-ecdsa-p384"
+	case KeyType_MLDSA87_ECDSA_P521:
+		return "ml-dsa-87-ecdsa-p521"
 	}
 
 	return "[unknown]"
@@ -421,6 +452,10 @@ type Policy struct {
 	AllowImportedKeyRotation bool
 
 	ManagedKeyName string `json:"managed_key_name,omitempty"`
+
+	// FPEAlphabet is the alphabet of the values encrypted with format
+	// preserving encryption keys
+	FPEAlphabet string `json:"fpe_alphabet,omitempty"`
 }
 
 func (p *Policy) Lock(exclusive bool) {
@@ -970,6 +1005,70 @@ func (p *Policy) DecryptWithFactory(context, nonce []byte, value string, factori
 	return base64.StdEncoding.EncodeToString(plain), nil
 }
 
+// FPEEncrypt encrypts the value, a string of characters from the alphabet of
+// the key, preserving its format. Since the ciphertext cannot carry the key
+// version, the version used must be given again on decryption.
+func (p *Policy) FPEEncrypt(ver int, tweak []byte, value string) (string, error) {
+	if !p.Type.FPESupported() {
+		return "", errutil.UserError{Err: fmt.Sprintf("format preserving encryption not supported for key type %v", p.Type)}
+	}
+
+	switch {
+	case ver == 0:
+		ver = p.LatestVersion
+	case ver < 0:
+		return "", errutil.UserError{Err: "requested version for encryption is negative"}
+	case ver > p.LatestVersion:
+		return "", errutil.UserError{Err: "requested version for encryption is higher than the latest key version"}
+	case ver < p.MinEncryptionVersion:
+		return "", errutil.UserError{Err: "requested version for encryption is less than the minimum encryption key version"}
+	}
+
+	c, err := p.fpeCipher(ver)
+	if err != nil {
+		return "", err
+	}
+	return c.Encrypt(tweak, value)
+}
+
+// FPEDecrypt decrypts a value encrypted with FPEEncrypt using the given key
+// version. The version is required: decrypting with any other version than
+// the one used for encryption silently yields a wrong value.
+func (p *Policy) FPEDecrypt(ver int, tweak []byte, value string) (string, error) {
+	if !p.Type.FPESupported() {
+		return "", errutil.UserError{Err: fmt.Sprintf("format preserving decryption not supported for key type %v", p.Type)}
+	}
+
+	switch {
+	case ver == 0:
+		return "", errutil.UserError{Err: "the key version used for encryption is required for format preserving decryption"}
+	case ver < 0:
+		return "", errutil.UserError{Err: "requested version for decryption is negative"}
+	case ver > p.LatestVersion:
+		return "", errutil.UserError{Err: "requested version for decryption is higher than the latest key version"}
+	case p.MinDecryptionVersion > 0 && ver < p.MinDecryptionVersion:
+		return "", errutil.UserError{Err: ErrTooOld}
+	}
+
+	c, err := p.fpeCipher(ver)
+	if err != nil {
+		return "", err
+	}
+	return c.Decrypt(tweak, value)
+}
+
+func (p *Policy) fpeCipher(ver int) (*ff31Cipher, error) {
+	keyEntry, err := p.safeGetKeyEntry(ver)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newFF31Cipher(keyEntry.Key, p.FPEAlphabet)
+	if err != nil {
+		return nil, errutil.InternalError{Err: fmt.Sprintf("error creating cipher: %v", err)}
+	}
+	return c, nil
+}
+
 func (p *Policy) HMACKey(version int) ([]byte, error) {
 	switch {
 	case version < 0:
@@ -1160,6 +1259,13 @@ func (p *Policy) SignWithOptions(ver int, context, input []byte, options *Signin
 			return nil, errutil.InternalError{Err: fmt.Sprintf("unsupported rsa signature algorithm %s", sigAlgorithm)}
 		}
 
+	case KeyType_MLDSA44, KeyType_MLDSA65, KeyType_MLDSA87, KeyType_MLDSA44_ECDSA_P256, KeyType_MLDSA65_ECDSA_P384, KeyType_MLDSA87_ECDSA_P521:
+		// Like ed25519, ML-DSA signs the input itself rather than a digest
+		sig, err = signMLDSA(p.Type, rand.Reader, &keyParams, input)
+		if err != nil {
+			return nil, err
+		}
+
 	default:
 		return nil, fmt.Errorf("unsupported key type %v", p.Type)
 	}
@@ -1333,6 +1439,14 @@ func (p *Policy) VerifySignatureWithOptions(context, input []byte, sig string, o
 
 		return err == nil, nil
 
+	case KeyType_MLDSA44, KeyType_MLDSA65, KeyType_MLDSA87, KeyType_MLDSA44_ECDSA_P256, KeyType_MLDSA65_ECDSA_P384, KeyType_MLDSA87_ECDSA_P521:
+		keyEntry, err := p.safeGetKeyEntry(ver)
+		if err != nil {
+			return false, err
+		}
+
+		return verifyMLDSA(p.Type, &keyEntry, input, sigBytes)
+
 	default:
 		return false, errutil.InternalError{Err: fmt.Sprintf("unsupported key type %v", p.Type)}
 	}
@@ -1514,7 +1628,7 @@ func (p *Policy) RotateInMemory(randReader io.Reader) (retErr error) {
 	entry.HMACKey = hmacKey
 
 	switch p.Type {
-	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_HMAC:
+	case KeyType_AES128_GCM96, KeyType_AES256_GCM96, KeyType_ChaCha20_Poly1305, KeyType_HMAC, KeyType_AES256_FF3_1:
 		// Default to 256 bit key
 		numBytes := 32
 		if p.Type == KeyType_AES128_GCM96 {
@@ -1584,6 +1698,11 @@ func (p *Policy) RotateInMemory(randReader io.Reader) (retErr error) {
 		if err != nil {
 			return err
 		}
+
+	case KeyType_MLDSA44, KeyType_MLDSA65, KeyType_MLDSA87, KeyType_MLDSA44_ECDSA_P256, KeyType_MLDSA65_ECDSA_P384, KeyType_MLDSA87_ECDSA_P521:
+		if err := generateMLDSAKey(p.Type, randReader, &entry); err != nil {
+			return err
+		}
 	}
 
 	if p.ConvergentEncryption {
@@ -0,0 +1,839 @@
+// Package mldsa implements the ML-DSA post-quantum digital signature scheme
+// specified in NIST FIPS 204, with the ML-DSA-44, ML-DSA-65 and ML-DSA-87
+// parameter sets. Private keys are represented by the 32-byte seed they are
+// generated from, and signing follows the pure (non pre-hashed) variant with
+// an optional context string.
+package mldsa
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	n = 256
+	q = 8380417
+	d = 13
+
+	// nInv is 256^-1 mod q, used to scale the inverse NTT.
+	nInv = 8347681
+
+	// zeta is the 512th root of unity mod q used by the NTT.
+	zeta = 1753
+
+	// SeedSize is the size of the seeds private keys are generated from.
+	SeedSize = 32
+
+	// MaxContextSize is the maximum size of the context strings signatures
+	// may be bound to.
+	MaxContextSize = 255
+
+	rhoSize = 32
+	keySize = 32
+	trSize  = 64
+	muSize  = 64
+	rndSize = 32
+
+	// t1Bits is the size of the coefficients of t1 in public keys,
+	// bitlen(q-1) - d.
+	t1Bits = 10
+)
+
+// Params is an ML-DSA parameter set.
+type Params struct {
+	name   string
+	k, l   int
+	eta    int
+	tau    int
+	lambda int
+	gamma1 int
+	gamma2 int
+	omega  int
+}
+
+var (
+	// MLDSA44 is the ML-DSA-44 parameter set, NIST security category 2.
+	MLDSA44 = &Params{name: "ML-DSA-44", k: 4, l: 4, eta: 2, tau: 39, lambda: 128, gamma1: 1 << 17, gamma2: (q - 1) / 88, omega: 80}
+
+	// MLDSA65 is the ML-DSA-65 parameter set, NIST security category 3.
+	MLDSA65 = &Params{name: "ML-DSA-65", k: 6, l: 5, eta: 4, tau: 49, lambda: 192, gamma1: 1 << 19, gamma2: (q - 1) / 32, omega: 55}
+
+	// MLDSA87 is the ML-DSA-87 parameter set, NIST security category 5.
+	MLDSA87 = &Params{name: "ML-DSA-87", k: 8, l: 7, eta: 2, tau: 60, lambda: 256, gamma1: 1 << 19, gamma2: (q - 1) / 32, omega: 75}
+)
+
+func (p *Params) String() string {
+	return p.name
+}
+
+// PublicKeySize returns the size of encoded public keys.
+func (p *Params) PublicKeySize() int {
+	return rhoSize + p.k*n*t1Bits/8
+}
+
+// SignatureSize returns the size of signatures.
+func (p *Params) SignatureSize() int {
+	return p.cTildeSize() + p.l*n*p.gamma1Bits()/8 + p.omega + p.k
+}
+
+func (p *Params) beta() int32 {
+	return int32(p.tau * p.eta)
+}
+
+func (p *Params) cTildeSize() int {
+	return p.lambda / 4
+}
+
+// gamma1Bits is the size of the coefficients of z in signatures,
+// 1 + bitlen(gamma1 - 1).
+func (p *Params) gamma1Bits() int {
+	if p.gamma1 == 1<<17 {
+		return 18
+	}
+	return 20
+}
+
+// w1Bits is the size of the coefficients of w1 when hashed,
+// bitlen((q-1)/(2*gamma2) - 1).
+func (p *Params) w1Bits() int {
+	if p.gamma2 == (q-1)/88 {
+		return 6
+	}
+	return 4
+}
+
+// poly is a polynomial of R_q, with coefficients in [0, q).
+type poly [n]uint32
+
+// PublicKey is an ML-DSA public key.
+type PublicKey struct {
+	params  *Params
+	encoded []byte
+	tr      [trSize]byte
+
+	aHat    [][]poly
+	t1Hat2d []poly
+}
+
+// PrivateKey is an ML-DSA private key.
+type PrivateKey struct {
+	params *Params
+	seed   [SeedSize]byte
+	key    [keySize]byte
+
+	s1Hat []poly
+	s2Hat []poly
+	t0Hat []poly
+
+	pub *PublicKey
+}
+
+// GenerateKey generates a new private key using entropy from rand.
+func GenerateKey(p *Params, rand io.Reader) (*PrivateKey, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := io.ReadFull(rand, seed); err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(p, seed)
+}
+
+// NewPrivateKey derives the private key for the given seed (ML-DSA.KeyGen
+// with the seed as xi).
+func NewPrivateKey(p *Params, seed []byte) (*PrivateKey, error) {
+	if len(seed) != SeedSize {
+		return nil, fmt.Errorf("invalid seed size %d", len(seed))
+	}
+
+	sk := &PrivateKey{params: p}
+	copy(sk.seed[:], seed)
+
+	var expanded [rhoSize + 64 + keySize]byte
+	h := sha3.NewShake256()
+	h.Write(seed)
+	h.Write([]byte{byte(p.k), byte(p.l)})
+	h.Read(expanded[:])
+	rho, rhoPrime := expanded[:rhoSize], expanded[rhoSize:rhoSize+64]
+	copy(sk.key[:], expanded[rhoSize+64:])
+
+	aHat := expandA(p, rho)
+
+	s1 := make([]poly, p.l)
+	for r := range s1 {
+		rejBoundedPoly(&s1[r], p.eta, rhoPrime, uint16(r))
+	}
+	s2 := make([]poly, p.k)
+	for r := range s2 {
+		rejBoundedPoly(&s2[r], p.eta, rhoPrime, uint16(r+p.l))
+	}
+
+	sk.s1Hat = make([]poly, p.l)
+	for j := range s1 {
+		sk.s1Hat[j] = s1[j]
+		ntt(&sk.s1Hat[j])
+	}
+
+	t1 := make([]poly, p.k)
+	t0 := make([]poly, p.k)
+	for i := 0; i < p.k; i++ {
+		var t poly
+		for j := 0; j < p.l; j++ {
+			mulAddNTT(&t, &aHat[i][j], &sk.s1Hat[j])
+		}
+		invNTT(&t)
+		for c := range t {
+			t1[i][c], t0[i][c] = power2Round(fieldAdd(t[c], s2[i][c]))
+		}
+	}
+
+	sk.s2Hat = s2
+	for i := range sk.s2Hat {
+		ntt(&sk.s2Hat[i])
+	}
+	sk.t0Hat = t0
+	for i := range sk.t0Hat {
+		ntt(&sk.t0Hat[i])
+	}
+
+	encoded := make([]byte, 0, p.PublicKeySize())
+	encoded = append(encoded, rho...)
+	for i := range t1 {
+		encoded = packBits(encoded, &t1[i], t1Bits)
+	}
+	sk.pub = newPublicKey(p, encoded, aHat, t1)
+
+	return sk, nil
+}
+
+// Seed returns the seed the private key was generated from.
+func (sk *PrivateKey) Seed() []byte {
+	return append([]byte(nil), sk.seed[:]...)
+}
+
+// Params returns the parameter set of the key.
+func (sk *PrivateKey) Params() *Params {
+	return sk.params
+}
+
+// PublicKey returns the public key corresponding to the private key.
+func (sk *PrivateKey) PublicKey() *PublicKey {
+	return sk.pub
+}
+
+// ParsePublicKey parses an encoded public key.
+func ParsePublicKey(p *Params, encoded []byte) (*PublicKey, error) {
+	if len(encoded) != p.PublicKeySize() {
+		return nil, fmt.Errorf("invalid public key size %d for %s", len(encoded), p)
+	}
+
+	rho := encoded[:rhoSize]
+	packed := encoded[rhoSize:]
+	t1 := make([]poly, p.k)
+	for i := range t1 {
+		unpackBits(&t1[i], packed[i*n*t1Bits/8:], t1Bits)
+	}
+
+	return newPublicKey(p, append([]byte(nil), encoded...), expandA(p, rho), t1), nil
+}
+
+func newPublicKey(p *Params, encoded []byte, aHat [][]poly, t1 []poly) *PublicKey {
+	pk := &PublicKey{
+		params:  p,
+		encoded: encoded,
+		aHat:    aHat,
+		t1Hat2d: make([]poly, p.k),
+	}
+
+	h := sha3.NewShake256()
+	h.Write(encoded)
+	h.Read(pk.tr[:])
+
+	for i := range t1 {
+		for c := range t1[i] {
+			pk.t1Hat2d[i][c] = t1[i][c] << d
+		}
+		ntt(&pk.t1Hat2d[i])
+	}
+
+	return pk
+}
+
+// Bytes returns the encoded public key.
+func (pk *PublicKey) Bytes() []byte {
+	return append([]byte(nil), pk.encoded...)
+}
+
+// Params returns the parameter set of the key.
+func (pk *PublicKey) Params() *Params {
+	return pk.params
+}
+
+// Equal reports whether the public keys are the same.
+func (pk *PublicKey) Equal(other *PublicKey) bool {
+	return pk.params == other.params && subtle.ConstantTimeCompare(pk.encoded, other.encoded) == 1
+}
+
+// Sign signs the message bound to the given context, which may be empty. A
+// nil rand produces the deterministic variant of the signature; otherwise
+// signing is hedged with randomness read from rand.
+func (sk *PrivateKey) Sign(rand io.Reader, message, context []byte) ([]byte, error) {
+	if len(context) > MaxContextSize {
+		return nil, errors.New("context too long")
+	}
+
+	var rnd [rndSize]byte
+	if rand != nil {
+		if _, err := io.ReadFull(rand, rnd[:]); err != nil {
+			return nil, err
+		}
+	}
+
+	mu := computeMu(&sk.pub.tr, message, context)
+	return sk.signInternal(&mu, &rnd), nil
+}
+
+// signInternal implements ML-DSA.Sign_internal.
+func (sk *PrivateKey) signInternal(mu *[muSize]byte, rnd *[rndSize]byte) []byte {
+	p := sk.params
+	aHat := sk.pub.aHat
+
+	var rhoPrime [64]byte
+	h := sha3.NewShake256()
+	h.Write(sk.key[:])
+	h.Write(rnd[:])
+	h.Write(mu[:])
+	h.Read(rhoPrime[:])
+
+	beta := p.beta()
+	gamma1 := int32(p.gamma1)
+	gamma2 := int32(p.gamma2)
+
+	y := make([]poly, p.l)
+	yHat := make([]poly, p.l)
+	z := make([]poly, p.l)
+	w := make([]poly, p.k)
+	w1 := make([]poly, p.k)
+	hint := make([]poly, p.k)
+	cTilde := make([]byte, p.cTildeSize())
+
+	for kappa := 0; ; kappa += p.l {
+		expandMask(p, y, rhoPrime[:], kappa)
+		for j := range y {
+			yHat[j] = y[j]
+			ntt(&yHat[j])
+		}
+
+		for i := 0; i < p.k; i++ {
+			w[i] = poly{}
+			for j := 0; j < p.l; j++ {
+				mulAddNTT(&w[i], &aHat[i][j], &yHat[j])
+			}
+			invNTT(&w[i])
+			for c := range w[i] {
+				r1, _ := decompose(p, w[i][c])
+				w1[i][c] = r1
+			}
+		}
+
+		h := sha3.NewShake256()
+		h.Write(mu[:])
+		h.Write(encodeW1(p, w1))
+		h.Read(cTilde)
+
+		cHat := sampleInBall(p, cTilde)
+		ntt(&cHat)
+
+		if !computeZ(p, z, y, &cHat, sk.s1Hat, gamma1-beta) {
+			continue
+		}
+
+		ok := true
+		hints := 0
+		for i := 0; i < p.k && ok; i++ {
+			var cs2, ct0 poly
+			mulNTT(&cs2, &cHat, &sk.s2Hat[i])
+			invNTT(&cs2)
+			mulNTT(&ct0, &cHat, &sk.t0Hat[i])
+			invNTT(&ct0)
+
+			for c := 0; c < n; c++ {
+				r := fieldSub(w[i][c], cs2[c])
+				r1, r0 := decompose(p, r)
+				if abs(r0) >= gamma2-beta || abs(centered(ct0[c])) >= gamma2 {
+					ok = false
+					break
+				}
+
+				// MakeHint(-ct0, r + ct0)
+				v1, _ := decompose(p, fieldAdd(r, ct0[c]))
+				hint[i][c] = 0
+				if v1 != r1 {
+					hint[i][c] = 1
+					hints++
+				}
+			}
+		}
+		if !ok || hints > p.omega {
+			continue
+		}
+
+		return encodeSignature(p, cTilde, z, hint)
+	}
+}
+
+// computeZ sets z = y + NTT^-1(cHat * s1Hat), reporting whether its infinity
+// norm is below the bound.
+func computeZ(p *Params, z, y []poly, cHat *poly, s1Hat []poly, bound int32) bool {
+	for j := 0; j < p.l; j++ {
+		var cs1 poly
+		mulNTT(&cs1, cHat, &s1Hat[j])
+		invNTT(&cs1)
+		for c := range cs1 {
+			z[j][c] = fieldAdd(y[j][c], cs1[c])
+			if abs(centered(z[j][c])) >= bound {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Verify reports whether sig is a valid signature of the message bound to
+// the given context by the public key.
+func Verify(pk *PublicKey, message, sig, context []byte) bool {
+	p := pk.params
+	if len(context) > MaxContextSize || len(sig) != p.SignatureSize() {
+		return false
+	}
+
+	cTilde := sig[:p.cTildeSize()]
+	packedZ := sig[p.cTildeSize() : p.cTildeSize()+p.l*n*p.gamma1Bits()/8]
+	packedHint := sig[p.cTildeSize()+p.l*n*p.gamma1Bits()/8:]
+
+	gamma1 := int32(p.gamma1)
+	bound := gamma1 - p.beta()
+	zHat := make([]poly, p.l)
+	for j := range zHat {
+		unpackBits(&zHat[j], packedZ[j*n*p.gamma1Bits()/8:], p.gamma1Bits())
+		for c := range zHat[j] {
+			v := gamma1 - int32(zHat[j][c])
+			if abs(v) >= bound {
+				return false
+			}
+			zHat[j][c] = fromSigned(v)
+		}
+		ntt(&zHat[j])
+	}
+
+	hint, ok := decodeHint(p, packedHint)
+	if !ok {
+		return false
+	}
+
+	mu := computeMu(&pk.tr, message, context)
+
+	cHat := sampleInBall(p, cTilde)
+	ntt(&cHat)
+
+	w1 := make([]poly, p.k)
+	for i := 0; i < p.k; i++ {
+		var wApprox, ct1 poly
+		for j := 0; j < p.l; j++ {
+			mulAddNTT(&wApprox, &pk.aHat[i][j], &zHat[j])
+		}
+		mulNTT(&ct1, &cHat, &pk.t1Hat2d[i])
+		for c := range wApprox {
+			wApprox[c] = fieldSub(wApprox[c], ct1[c])
+		}
+		invNTT(&wApprox)
+		for c := range wApprox {
+			w1[i][c] = useHint(p, hint[i][c], wApprox[c])
+		}
+	}
+
+	cTildePrime := make([]byte, p.cTildeSize())
+	h := sha3.NewShake256()
+	h.Write(mu[:])
+	h.Write(encodeW1(p, w1))
+	h.Read(cTildePrime)
+
+	return subtle.ConstantTimeCompare(cTilde, cTildePrime) == 1
+}
+
+// computeMu computes the message representative of the external ML-DSA
+// interface, H(tr || 0 || len(context) || context || message).
+func computeMu(tr *[trSize]byte, message, context []byte) [muSize]byte {
+	var mu [muSize]byte
+	h := sha3.NewShake256()
+	h.Write(tr[:])
+	h.Write([]byte{0, byte(len(context))})
+	h.Write(context)
+	h.Write(message)
+	h.Read(mu[:])
+	return mu
+}
+
+func encodeW1(p *Params, w1 []poly) []byte {
+	out := make([]byte, 0, p.k*n*p.w1Bits()/8)
+	for i := range w1 {
+		out = packBits(out, &w1[i], p.w1Bits())
+	}
+	return out
+}
+
+// encodeSignature implements sigEncode.
+func encodeSignature(p *Params, cTilde []byte, z, hint []poly) []byte {
+	sig := make([]byte, 0, p.SignatureSize())
+	sig = append(sig, cTilde...)
+
+	gamma1 := int32(p.gamma1)
+	for j := range z {
+		var packed poly
+		for c := range z[j] {
+			packed[c] = uint32(gamma1 - centered(z[j][c]))
+		}
+		sig = packBits(sig, &packed, p.gamma1Bits())
+	}
+
+	packedHint := make([]byte, p.omega+p.k)
+	index := 0
+	for i := range hint {
+		for c := range hint[i] {
+			if hint[i][c] != 0 {
+				packedHint[index] = byte(c)
+				index++
+			}
+		}
+		packedHint[p.omega+i] = byte(index)
+	}
+
+	return append(sig, packedHint...)
+}
+
+// decodeHint implements HintBitUnpack, rejecting malformed encodings.
+func decodeHint(p *Params, packed []byte) ([]poly, bool) {
+	hint := make([]poly, p.k)
+	index := 0
+	for i := 0; i < p.k; i++ {
+		end := int(packed[p.omega+i])
+		if end < index || end > p.omega {
+			return nil, false
+		}
+		first := index
+		for ; index < end; index++ {
+			if index > first && packed[index-1] >= packed[index] {
+				return nil, false
+			}
+			hint[i][packed[index]] = 1
+		}
+	}
+	for ; index < p.omega; index++ {
+		if packed[index] != 0 {
+			return nil, false
+		}
+	}
+	return hint, true
+}
+
+// expandA implements ExpandA, returning the matrix A in the NTT domain.
+func expandA(p *Params, rho []byte) [][]poly {
+	aHat := make([][]poly, p.k)
+	for r := range aHat {
+		aHat[r] = make([]poly, p.l)
+		for s := range aHat[r] {
+			rejNTTPoly(&aHat[r][s], rho, byte(s), byte(r))
+		}
+	}
+	return aHat
+}
+
+// rejNTTPoly implements RejNTTPoly.
+func rejNTTPoly(f *poly, rho []byte, s, r byte) {
+	h := sha3.NewShake128()
+	h.Write(rho)
+	h.Write([]byte{s, r})
+
+	var buf [168]byte
+	for j := 0; j < n; {
+		h.Read(buf[:])
+		for i := 0; i+3 <= len(buf) && j < n; i += 3 {
+			v := uint32(buf[i]) | uint32(buf[i+1])<<8 | uint32(buf[i+2]&0x7f)<<16
+			if v < q {
+				f[j] = v
+				j++
+			}
+		}
+	}
+}
+
+// rejBoundedPoly implements RejBoundedPoly.
+func rejBoundedPoly(f *poly, eta int, rho []byte, nonce uint16) {
+	h := sha3.NewShake256()
+	h.Write(rho)
+	h.Write([]byte{byte(nonce), byte(nonce >> 8)})
+
+	var buf [136]byte
+	for j := 0; j < n; {
+		h.Read(buf[:])
+		for i := 0; i < len(buf) && j < n; i++ {
+			if v, ok := coeffFromHalfByte(eta, buf[i]&0x0f); ok {
+				f[j] = v
+				j++
+			}
+			if v, ok := coeffFromHalfByte(eta, buf[i]>>4); ok && j < n {
+				f[j] = v
+				j++
+			}
+		}
+	}
+}
+
+func coeffFromHalfByte(eta int, b byte) (uint32, bool) {
+	switch {
+	case eta == 2 && b < 15:
+		return fromSigned(2 - int32(b%5)), true
+	case eta == 4 && b < 9:
+		return fromSigned(4 - int32(b)), true
+	}
+	return 0, false
+}
+
+// expandMask implements ExpandMask, setting y to the mask for the given
+// counter.
+func expandMask(p *Params, y []poly, rho []byte, kappa int) {
+	bits := p.gamma1Bits()
+	buf := make([]byte, n*bits/8)
+	gamma1 := int32(p.gamma1)
+	for r := range y {
+		nonce := uint16(kappa + r)
+		h := sha3.NewShake256()
+		h.Write(rho)
+		h.Write([]byte{byte(nonce), byte(nonce >> 8)})
+		h.Read(buf)
+
+		unpackBits(&y[r], buf, bits)
+		for c := range y[r] {
+			y[r][c] = fromSigned(gamma1 - int32(y[r][c]))
+		}
+	}
+}
+
+// sampleInBall implements SampleInBall, returning a polynomial with tau
+// coefficients in {-1, 1} and the others zero.
+func sampleInBall(p *Params, seed []byte) poly {
+	h := sha3.NewShake256()
+	h.Write(seed)
+
+	var signBytes [8]byte
+	h.Read(signBytes[:])
+	signs := binary.LittleEndian.Uint64(signBytes[:])
+
+	var c poly
+	var b [1]byte
+	for i := n - p.tau; i < n; i++ {
+		for {
+			h.Read(b[:])
+			if int(b[0]) <= i {
+				break
+			}
+		}
+		j := b[0]
+		c[i] = c[j]
+		c[j] = 1
+		if signs&1 == 1 {
+			c[j] = q - 1
+		}
+		signs >>= 1
+	}
+	return c
+}
+
+// power2Round implements Power2Round, returning r1 and r0 mod q.
+func power2Round(r uint32) (uint32, uint32) {
+	r0 := int32(r & (1<<d - 1))
+	if r0 > 1<<(d-1) {
+		r0 -= 1 << d
+	}
+	return uint32((int32(r) - r0) >> d), fromSigned(r0)
+}
+
+// decompose implements Decompose, returning r1 and the centered r0.
+func decompose(p *Params, r uint32) (uint32, int32) {
+	// Dividing by constants avoids variable time division instructions.
+	var r0 int32
+	var g2 int32
+	switch p.gamma2 {
+	case (q - 1) / 88:
+		g2 = 2 * ((q - 1) / 88)
+		r0 = int32(r % (2 * ((q - 1) / 88)))
+	default:
+		g2 = 2 * ((q - 1) / 32)
+		r0 = int32(r % (2 * ((q - 1) / 32)))
+	}
+	if r0 > g2/2 {
+		r0 -= g2
+	}
+
+	if int32(r)-r0 == q-1 {
+		return 0, r0 - 1
+	}
+
+	switch p.gamma2 {
+	case (q - 1) / 88:
+		return uint32(int32(r)-r0) / (2 * ((q - 1) / 88)), r0
+	default:
+		return uint32(int32(r)-r0) / (2 * ((q - 1) / 32)), r0
+	}
+}
+
+// useHint implements UseHint.
+func useHint(p *Params, h uint32, r uint32) uint32 {
+	m := uint32((q - 1) / (2 * p.gamma2))
+	r1, r0 := decompose(p, r)
+	if h == 0 {
+		return r1
+	}
+	if r0 > 0 {
+		return (r1 + 1) % m
+	}
+	return (r1 + m - 1) % m
+}
+
+// packBits appends the coefficients of f encoded with the given number of
+// bits each, least significant bit first.
+func packBits(dst []byte, f *poly, bits int) []byte {
+	var acc uint64
+	accBits := 0
+	for _, c := range f {
+		acc |= uint64(c) << accBits
+		accBits += bits
+		for accBits >= 8 {
+			dst = append(dst, byte(acc))
+			acc >>= 8
+			accBits -= 8
+		}
+	}
+	return dst
+}
+
+// unpackBits decodes the coefficients of f packed by packBits.
+func unpackBits(f *poly, src []byte, bits int) {
+	var acc uint64
+	accBits := 0
+	mask := uint64(1)<<bits - 1
+	i := 0
+	for c := range f {
+		for accBits < bits {
+			acc |= uint64(src[i]) << accBits
+			i++
+			accBits += 8
+		}
+		f[c] = uint32(acc & mask)
+		acc >>= bits
+		accBits -= bits
+	}
+}
+
+var zetas [n]uint32
+
+func init() {
+	for k := range zetas {
+		// zeta^BitRev8(k) mod q
+		exp := 0
+		for b := 0; b < 8; b++ {
+			exp |= (k >> b & 1) << (7 - b)
+		}
+		z := uint32(1)
+		for e := 0; e < exp; e++ {
+			z = fieldMul(z, zeta)
+		}
+		zetas[k] = z
+	}
+}
+
+// ntt transforms f into the NTT domain in place.
+func ntt(f *poly) {
+	m := 0
+	for length := 128; length >= 1; length /= 2 {
+		for start := 0; start < n; start += 2 * length {
+			m++
+			z := zetas[m]
+			for j := start; j < start+length; j++ {
+				t := fieldMul(z, f[j+length])
+				f[j+length] = fieldSub(f[j], t)
+				f[j] = fieldAdd(f[j], t)
+			}
+		}
+	}
+}
+
+// invNTT transforms f out of the NTT domain in place.
+func invNTT(f *poly) {
+	m := n
+	for length := 1; length < n; length *= 2 {
+		for start := 0; start < n; start += 2 * length {
+			m--
+			z := q - zetas[m]
+			for j := start; j < start+length; j++ {
+				t := f[j]
+				f[j] = fieldAdd(t, f[j+length])
+				f[j+length] = fieldMul(z, fieldSub(t, f[j+length]))
+			}
+		}
+	}
+	for j := range f {
+		f[j] = fieldMul(nInv, f[j])
+	}
+}
+
+// mulNTT sets out to the product of a and b in the NTT domain.
+func mulNTT(out, a, b *poly) {
+	for c := range out {
+		out[c] = fieldMul(a[c], b[c])
+	}
+}
+
+// mulAddNTT adds the product of a and b in the NTT domain to acc.
+func mulAddNTT(acc, a, b *poly) {
+	for c := range acc {
+		acc[c] = fieldAdd(acc[c], fieldMul(a[c], b[c]))
+	}
+}
+
+// fieldReduceOnce maps a value in [0, 2q) to [0, q) in constant time.
+func fieldReduceOnce(a uint32) uint32 {
+	x := a - q
+	return x + (q & uint32(int32(x)>>31))
+}
+
+func fieldAdd(a, b uint32) uint32 {
+	return fieldReduceOnce(a + b)
+}
+
+func fieldSub(a, b uint32) uint32 {
+	return fieldReduceOnce(a - b + q)
+}
+
+func fieldMul(a, b uint32) uint32 {
+	return uint32(uint64(a) * uint64(b) % q)
+}
+
+// centered returns a mod± q, in (-(q-1)/2, (q-1)/2].
+func centered(a uint32) int32 {
+	x := int32(a)
+	return x - (q & (((q-1)/2 - x) >> 31))
+}
+
+// fromSigned maps a value in (-q, q) to [0, q).
+func fromSigned(x int32) uint32 {
+	return uint32(x + (q & (x >> 31)))
+}
+
+func abs(x int32) int32 {
+	mask := x >> 31
+	return (x ^ mask) - mask
+}
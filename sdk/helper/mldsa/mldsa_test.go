@@ -0,0 +1,151 @@
+package mldsa
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKnownAnswer(t *testing.T) {
+	seed := make([]byte, SeedSize)
+	for i := range seed {
+		seed[i] = byte(i)
+	}
+
+	// The expected values are the SHA-256 digests of the public key and the
+	// deterministic signature of "message" with context "context", as
+	// produced by the Go standard library crypto/mldsa package for the same
+	// seed.
+	tests := []struct {
+		params *Params
+		pk     string
+		sig    string
+	}{
+		{MLDSA44, "9f107644c1084526af3bc8098680b05499a2325a644e388fb4f970e058d19d46", "437eff3b5995aaa99468a279fc77e697fa8bc204d48a7f511fb83fe502f0f1f3"},
+		{MLDSA65, "d666806e11cee19a7c989f7445f90dd419cf4d2d51db8c0fdb4c0f0a542238c9", "99ed95a700b778e438939a7252a264840487f710dbc3ba2b0728fa21bfa84cfa"},
+		{MLDSA87, "91dc389cfaa01470b7f66eee45a4ae9026d154817c754dfe22298b3fa241ffcd", "0148c9e8f79ffd5fa8c7af7220899bdd9503616e9acb379bb1930057badef6f7"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.params.String(), func(t *testing.T) {
+			sk, err := NewPrivateKey(tc.params, seed)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pk := sk.PublicKey().Bytes()
+			if len(pk) != tc.params.PublicKeySize() {
+				t.Fatalf("bad public key size %d", len(pk))
+			}
+			digest := sha256.Sum256(pk)
+			if hex.EncodeToString(digest[:]) != tc.pk {
+				t.Fatalf("bad public key digest %x", digest)
+			}
+
+			sig, err := sk.Sign(nil, []byte("message"), []byte("context"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(sig) != tc.params.SignatureSize() {
+				t.Fatalf("bad signature size %d", len(sig))
+			}
+			digest = sha256.Sum256(sig)
+			if hex.EncodeToString(digest[:]) != tc.sig {
+				t.Fatalf("bad signature digest %x", digest)
+			}
+		})
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	for _, params := range []*Params{MLDSA44, MLDSA65, MLDSA87} {
+		t.Run(params.String(), func(t *testing.T) {
+			sk, err := GenerateKey(params, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			restored, err := NewPrivateKey(params, sk.Seed())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !restored.PublicKey().Equal(sk.PublicKey()) {
+				t.Fatal("key restored from seed differs")
+			}
+
+			pk, err := ParsePublicKey(params, sk.PublicKey().Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			message := []byte("the quick brown fox")
+			context := []byte("vault")
+			sig, err := sk.Sign(rand.Reader, message, context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !Verify(pk, message, sig, context) {
+				t.Fatal("signature did not verify")
+			}
+
+			// Hedged signatures differ between calls
+			other, err := sk.Sign(rand.Reader, message, context)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(sig, other) {
+				t.Fatal("hedged signatures are equal")
+			}
+
+			if Verify(pk, []byte("the quick brown fax"), sig, context) {
+				t.Fatal("signature verified for another message")
+			}
+			if Verify(pk, message, sig, nil) {
+				t.Fatal("signature verified for another context")
+			}
+			if Verify(pk, message, sig[:len(sig)-1], context) {
+				t.Fatal("truncated signature verified")
+			}
+
+			tampered := append([]byte(nil), sig...)
+			tampered[0] ^= 0x01
+			if Verify(pk, message, tampered, context) {
+				t.Fatal("tampered signature verified")
+			}
+
+			// Non-zero padding in the hint encoding must be rejected
+			tampered = append([]byte(nil), sig...)
+			tampered[len(tampered)-params.k-1] ^= 0xff
+			if Verify(pk, message, tampered, context) {
+				t.Fatal("signature with malformed hints verified")
+			}
+
+			otherKey, err := GenerateKey(params, rand.Reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if Verify(otherKey.PublicKey(), message, sig, context) {
+				t.Fatal("signature verified with another key")
+			}
+		})
+	}
+}
+
+func TestInvalidInputs(t *testing.T) {
+	if _, err := NewPrivateKey(MLDSA44, make([]byte, SeedSize-1)); err == nil {
+		t.Fatal("expected error for short seed")
+	}
+	if _, err := ParsePublicKey(MLDSA65, make([]byte, MLDSA44.PublicKeySize())); err == nil {
+		t.Fatal("expected error for public key of another parameter set")
+	}
+
+	sk, err := GenerateKey(MLDSA44, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sk.Sign(nil, []byte("message"), make([]byte, MaxContextSize+1)); err == nil {
+		t.Fatal("expected error for long context")
+	}
+}
@@ -98,6 +98,27 @@ type ExtendedSystemView interface {
 	ForwardGenericRequest(context.Context, *Request) (*Response, error)
 }
 
+// PasswordPolicyValidator is implemented by system views which can validate
+// passwords chosen by users against password policies. It is not available to
+// plugins running in a separate process.
+type PasswordPolicyValidator interface {
+	// ValidatePasswordWithPolicy returns an error describing why the password
+	// does not satisfy the policy referenced. If the policy does not exist,
+	// this will return an error.
+	ValidatePasswordWithPolicy(ctx context.Context, policyName string, password string) error
+}
+
+// LoginMFAValidator is implemented by system views which can enforce login
+// MFA on unauthenticated requests to auth methods that don't issue a token,
+// such as self-service password changes. It is not available to plugins
+// running in a separate process.
+type LoginMFAValidator interface {
+	// ValidateLoginMFA returns an error if login MFA is enforced for the user
+	// with the given alias name in the mount, and the MFA credentials
+	// supplied with the request are missing or invalid.
+	ValidateLoginMFA(ctx context.Context, req *Request, aliasName string) error
+}
+
 type PasswordGenerator func() (password string, err error)
 
 type StaticSystemView struct {
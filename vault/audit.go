@@ -110,6 +110,11 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	view.setReadOnlyErr(logical.ErrSetupReadOnly)
 	defer view.setReadOnlyErr(origViewReadOnlyErr)
 
+	filter, err := auditFilter(entry.Options)
+	if err != nil {
+		return err
+	}
+
 	// Lookup the new backend
 	backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 	if err != nil {
@@ -152,7 +157,7 @@ func (c *Core) enableAudit(ctx context.Context, entry *MountEntry, updateStorage
 	c.audit = newTable
 
 	// Register the backend
-	c.auditBroker.Register(entry.Path, backend, view, entry.Local)
+	c.auditBroker.Register(entry.Path, backend, view, entry.Local, filter)
 	if c.logger.IsInfo() {
 		c.logger.Info("enabled audit backend", "path", entry.Path, "type", entry.Type)
 	}
@@ -401,6 +406,12 @@ func (c *Core) setupAudits(ctx context.Context) error {
 			view.setReadOnlyErr(origViewReadOnlyErr)
 		})
 
+		filter, err := auditFilter(entry.Options)
+		if err != nil {
+			c.logger.Error("failed to create audit entry", "path", entry.Path, "error", err)
+			continue
+		}
+
 		// Initialize the backend
 		backend, err := c.newAuditBackend(ctx, entry, view, entry.Options)
 		if err != nil {
@@ -413,7 +424,7 @@ func (c *Core) setupAudits(ctx context.Context) error {
 		}
 
 		// Mount the backend
-		broker.Register(entry.Path, backend, view, entry.Local)
+		broker.Register(entry.Path, backend, view, entry.Local, filter)
 
 		successCount++
 	}
@@ -436,6 +447,9 @@ func (c *Core) teardownAudits() error {
 		for _, entry := range c.audit.Entries {
 			c.removeAuditReloadFunc(entry)
 			removeAuditPathChecker(c, entry)
+			if c.auditBroker != nil {
+				c.auditBroker.Deregister(entry.Path)
+			}
 		}
 	}
 
@@ -462,6 +476,21 @@ func (c *Core) removeAuditReloadFunc(entry *MountEntry) {
 	}
 }
 
+// auditFilter parses the filter option of an audit device, returning nil
+// when the device receives all entries.
+func auditFilter(options map[string]string) (*audit.Filter, error) {
+	expr := strings.TrimSpace(options["filter"])
+	if expr == "" {
+		return nil, nil
+	}
+
+	filter, err := audit.NewFilter(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	return filter, nil
+}
+
 // newAuditBackend is used to create and configure a new audit backend by name
 func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logical.Storage, conf map[string]string) (audit.Backend, error) {
 	f, ok := c.auditBackends[entry.Type]
@@ -510,6 +539,12 @@ func (c *Core) newAuditBackend(ctx context.Context, entry *MountEntry, view logi
 		})
 
 		c.reloadFuncsLock.Unlock()
+	case "http":
+		if auditLogger.IsDebug() {
+			if entry.Options != nil {
+				auditLogger.Debug("http backend options", "path", entry.Path, "url", entry.Options["url"], "failure_policy", entry.Options["failure_policy"])
+			}
+		}
 	case "socket":
 		if auditLogger.IsDebug() {
 			if entry.Options != nil {
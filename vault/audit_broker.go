@@ -9,6 +9,7 @@ import (
 	metrics "github.com/armon/go-metrics"
 	log "github.com/hashicorp/go-hclog"
 	multierror "github.com/hashicorp/go-multierror"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/sdk/logical"
 )
@@ -17,6 +18,7 @@ type backendEntry struct {
 	backend audit.Backend
 	view    *BarrierView
 	local   bool
+	filter  *audit.Filter
 }
 
 // AuditBroker is used to provide a single ingest interface to auditable
@@ -25,33 +27,100 @@ type AuditBroker struct {
 	sync.RWMutex
 	backends map[string]backendEntry
 	logger   log.Logger
+
+	// filterDecisions holds, by backend name and request ID, whether a
+	// filtered backend logged a request, so that its response is logged
+	// along with it.
+	filterDecisions *lru.Cache
 }
 
+// auditFilterDecisionsSize bounds the number of requests whose filter
+// decisions are held until their responses are logged.
+const auditFilterDecisionsSize = 16384
+
 // NewAuditBroker creates a new audit broker
 func NewAuditBroker(log log.Logger) *AuditBroker {
+	filterDecisions, _ := lru.New(auditFilterDecisionsSize)
 	b := &AuditBroker{
-		backends: make(map[string]backendEntry),
-		logger:   log,
+		backends:        make(map[string]backendEntry),
+		logger:          log,
+		filterDecisions: filterDecisions,
 	}
 	return b
 }
 
-// Register is used to add new audit backend to the broker
-func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool) {
+// Register is used to add new audit backend to the broker. When filter is
+// non-nil, only entries matching it are sent to the backend.
+func (a *AuditBroker) Register(name string, b audit.Backend, v *BarrierView, local bool, filter *audit.Filter) {
 	a.Lock()
 	defer a.Unlock()
 	a.backends[name] = backendEntry{
 		backend: b,
 		view:    v,
 		local:   local,
+		filter:  filter,
+	}
+}
+
+// matchesFilter reports whether the given entry should be sent to the
+// backend. Both requests and responses are evaluated against the filter, so
+// that selectors such as error see the outcome of the request. A response is
+// logged if either it or its request matches; when only the response
+// matches, lateRequest is set and the request entry must be logged before
+// it, so that a request and its response are always logged together.
+// Entries are logged if the filter cannot be evaluated.
+func (a *AuditBroker) matchesFilter(ctx context.Context, name string, be backendEntry, in *logical.LogInput, response bool) (match, lateRequest bool) {
+	if be.filter == nil {
+		return true, false
+	}
+
+	var key string
+	if in.Request != nil && in.Request.ID != "" {
+		key = name + "/" + in.Request.ID
+	}
+
+	match, err := be.filter.Evaluate(ctx, in)
+	if err != nil {
+		a.logger.Warn("failed to evaluate audit filter, logging entry", "backend", name, "error", err)
+		metrics.IncrCounter([]string{"audit", name, "filter_error"}, 1)
+		match = true
+	}
+
+	switch {
+	case key == "":
+	case !response:
+		a.filterDecisions.Add(key, match)
+	default:
+		if decision, ok := a.filterDecisions.Peek(key); ok {
+			a.filterDecisions.Remove(key)
+			requestMatched := decision.(bool)
+			lateRequest = match && !requestMatched
+			match = match || requestMatched
+		}
+	}
+
+	if !match {
+		metrics.IncrCounter([]string{"audit", name, "filtered"}, 1)
 	}
+	return match, lateRequest
 }
 
 // Deregister is used to remove an audit backend from the broker
 func (a *AuditBroker) Deregister(name string) {
 	a.Lock()
-	defer a.Unlock()
+	be, ok := a.backends[name]
 	delete(a.backends, name)
+	a.Unlock()
+
+	// Close outside of the lock, as closing may block on in-flight writes
+	// to the backend, which would otherwise hold up all auditing.
+	if ok {
+		if closer, ok := be.backend.(audit.Closer); ok {
+			if err := closer.Close(); err != nil {
+				a.logger.Error("failed to close audit backend", "backend", name, "error", err)
+			}
+		}
+	}
 }
 
 // IsRegistered is used to check if a given audit backend is registered
@@ -129,10 +198,17 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs, unless every backend filtered the
+	// request out
 	anyLogged := false
+	anyAttempted := false
 	for name, be := range a.backends {
 		in.Request.Headers = nil
+		if match, _ := a.matchesFilter(ctx, name, be, in, false); !match {
+			continue
+		}
+		anyAttempted = true
+
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
 			a.logger.Error("backend failed to include headers", "backend", name, "error", thErr)
@@ -149,7 +225,7 @@ func (a *AuditBroker) LogRequest(ctx context.Context, in *logical.LogInput, head
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyAttempted {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the request"))
 	}
 
@@ -194,10 +270,18 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		in.Request.Headers = headers
 	}()
 
-	// Ensure at least one backend logs
+	// Ensure at least one backend logs, unless every backend filtered the
+	// response out
 	anyLogged := false
+	anyAttempted := false
 	for name, be := range a.backends {
 		in.Request.Headers = nil
+		match, lateRequest := a.matchesFilter(ctx, name, be, in, true)
+		if !match {
+			continue
+		}
+		anyAttempted = true
+
 		transHeaders, thErr := headersConfig.ApplyConfig(ctx, headers, be.backend.GetHash)
 		if thErr != nil {
 			a.logger.Error("backend failed to include headers", "backend", name, "error", thErr)
@@ -205,6 +289,20 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 		}
 		in.Request.Headers = transHeaders
 
+		// The request was filtered out when it was logged, but its response
+		// matches the filter: log the request now, ahead of its response.
+		if lateRequest {
+			reqIn := &logical.LogInput{
+				Auth:               in.Auth,
+				Request:            in.Request,
+				NonHMACReqDataKeys: in.NonHMACReqDataKeys,
+			}
+			if lrErr := be.backend.LogRequest(ctx, reqIn); lrErr != nil {
+				a.logger.Error("backend failed to log request", "backend", name, "error", lrErr)
+				continue
+			}
+		}
+
 		start := time.Now()
 		lrErr := be.backend.LogResponse(ctx, in)
 		metrics.MeasureSince([]string{"audit", name, "log_response"}, start)
@@ -214,7 +312,7 @@ func (a *AuditBroker) LogResponse(ctx context.Context, in *logical.LogInput, hea
 			anyLogged = true
 		}
 	}
-	if !anyLogged && len(a.backends) > 0 {
+	if !anyLogged && anyAttempted {
 		retErr = multierror.Append(retErr, fmt.Errorf("no audit backend succeeded in logging the response"))
 	}
 
@@ -359,8 +359,8 @@ func TestAuditBroker_LogRequest(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -445,8 +445,8 @@ func TestAuditBroker_LogResponse(t *testing.T) {
 	b := NewAuditBroker(l)
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		NumUses:     10,
@@ -551,8 +551,8 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 	view := NewBarrierView(barrier, "headers/")
 	a1 := &NoopAudit{}
 	a2 := &NoopAudit{}
-	b.Register("foo", a1, nil, false)
-	b.Register("bar", a2, nil, false)
+	b.Register("foo", a1, nil, false, nil)
+	b.Register("bar", a2, nil, false, nil)
 
 	auth := &logical.Auth{
 		ClientToken: "foo",
@@ -626,3 +626,150 @@ func TestAuditBroker_AuditHeaders(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestAuditBroker_Filter(t *testing.T) {
+	l := logging.NewVaultLogger(log.Trace)
+	b := NewAuditBroker(l)
+
+	filter, err := audit.NewFilter(`mount_type != "transit" or error != ""`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1 := &NoopAudit{}
+	a2 := &NoopAudit{}
+	b.Register("foo", a1, nil, false, filter)
+	b.Register("bar", a2, nil, false, nil)
+
+	headersConf := &AuditedHeadersConfig{
+		Headers: make(map[string]*auditedHeaderSettings),
+	}
+	ctx := namespace.RootContext(nil)
+
+	logInput := &logical.LogInput{
+		Auth: &logical.Auth{ClientToken: "foo"},
+		Request: &logical.Request{
+			ID:        "request-1",
+			Operation: logical.UpdateOperation,
+			Path:      "transit/encrypt/foo",
+			MountType: "transit",
+		},
+	}
+	if err := b.LogRequest(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Neither the request nor its successful response match the filter.
+	logInput.Response = &logical.Response{}
+	if err := b.LogResponse(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 0 || len(a1.Resp) != 0 {
+		t.Fatalf("expected filtered backend to receive nothing: %#v %#v", a1.Req, a1.Resp)
+	}
+	if len(a2.Req) != 1 || len(a2.Resp) != 1 {
+		t.Fatalf("expected unfiltered backend to receive the entries: %#v %#v", a2.Req, a2.Resp)
+	}
+
+	// An error returned while handling the request matches the filter: the
+	// response is logged, preceded by its request.
+	logInput.Request.ID = "request-1b"
+	logInput.Response = nil
+	if err := b.LogRequest(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 0 {
+		t.Fatalf("expected filtered backend to receive nothing yet: %#v", a1.Req)
+	}
+	logInput.Response = logical.ErrorResponse("bad input")
+	if err := b.LogResponse(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 1 || len(a1.Resp) != 1 || !a1.Resp[0].IsError() {
+		t.Fatalf("expected filtered backend to receive the error response and its request: %#v %#v", a1.Req, a1.Resp)
+	}
+
+	logInput.Request.ID = "request-1c"
+	logInput.Response = nil
+	if err := b.LogRequest(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	logInput.OuterErr = logical.ErrInvalidRequest
+	if err := b.LogResponse(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 2 || len(a1.Resp) != 2 {
+		t.Fatalf("expected filtered backend to receive the failed request's entries: %#v %#v", a1.Req, a1.Resp)
+	}
+
+	// Requests failing before being handled match the filter, along with
+	// their responses.
+	logInput.Request.ID = "request-2"
+	logInput.Response = nil
+	logInput.OuterErr = logical.ErrPermissionDenied
+	if err := b.LogRequest(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	logInput.OuterErr = nil
+	logInput.Response = &logical.Response{}
+	if err := b.LogResponse(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 3 || len(a1.Resp) != 3 {
+		t.Fatalf("expected filtered backend to receive both entries: %#v %#v", a1.Req, a1.Resp)
+	}
+
+	// Entries are logged when the filter can't be evaluated, here as the
+	// context lacks a namespace.
+	logInput.Request.ID = "request-3"
+	if err := b.LogRequest(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := b.LogResponse(context.Background(), logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(a1.Req) != 4 || len(a1.Resp) != 4 {
+		t.Fatalf("expected filtered backend to receive the entries: %#v %#v", a1.Req, a1.Resp)
+	}
+
+	// An entry filtered out by every backend is not a failure to log.
+	b.Deregister("bar")
+	logInput.Request.ID = "request-4"
+	logInput.Response = &logical.Response{}
+	if err := b.LogResponse(ctx, logInput, headersConf); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// But failing to log an entry that matched still is.
+	a1.RespErr = fmt.Errorf("failed")
+	logInput.Request.ID = "request-5"
+	logInput.Response = logical.ErrorResponse("bad input")
+	err = b.LogResponse(ctx, logInput, headersConf)
+	if !errwrap.Contains(err, "no audit backend succeeded in logging the response") {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestCore_EnableAudit_InvalidFilter(t *testing.T) {
+	c, _, _ := TestCoreUnsealed(t)
+	c.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		return &NoopAudit{
+			Config: config,
+		}, nil
+	}
+
+	me := &MountEntry{
+		Table: auditTableType,
+		Path:  "foo",
+		Type:  "noop",
+		Options: map[string]string{
+			"filter": `mount_type ==`,
+		},
+	}
+	err := c.enableAudit(namespace.RootContext(nil), me, true)
+	if err == nil || !strings.Contains(err.Error(), "invalid filter") {
+		t.Fatalf("expected invalid filter error, got: %v", err)
+	}
+	if c.auditBroker.IsRegistered("foo/") {
+		t.Fatalf("audit backend should not be registered")
+	}
+}
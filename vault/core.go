@@ -34,6 +34,7 @@ import (
 	"github.com/hashicorp/go-secure-stdlib/strutil"
 	"github.com/hashicorp/go-secure-stdlib/tlsutil"
 	"github.com/hashicorp/go-uuid"
+	lru "github.com/hashicorp/golang-lru"
 	"github.com/hashicorp/vault/api"
 	"github.com/hashicorp/vault/audit"
 	"github.com/hashicorp/vault/command/server"
@@ -407,6 +408,14 @@ type Core struct {
 	mfaResponseAuthQueue     *LoginMFAPriorityQueue
 	mfaResponseAuthQueueLock sync.Mutex
 
+	// userFailedLogins tracks the failed login attempts of users of auth
+	// mounts subject to user lockout. Users without an entity alias are
+	// tracked in the bounded unknownUserFailedLogins instead.
+	userFailedLogins          map[lockoutUser]*failedLoginInfo
+	unknownUserFailedLogins   *lru.Cache
+	userFailedLoginsLastSweep time.Time
+	userFailedLoginsLock      sync.Mutex
+
 	// metricSink is the destination for all metrics that have
 	// a cluster label.
 	metricSink *metricsutil.ClusterMetricSink
@@ -873,6 +882,7 @@ func CreateCore(conf *CoreConfig) (*Core, error) {
 		customListenerHeader: new(atomic.Value),
 		seal:                 conf.Seal,
 		router:               NewRouter(),
+		userFailedLogins:     make(map[lockoutUser]*failedLoginInfo),
 		sealed:               new(uint32),
 		sealMigrationDone:    new(uint32),
 		standby:              true,
@@ -941,6 +951,12 @@ func CreateCore(conf *CoreConfig) (*Core, error) {
 		InFlightReqCount: uberAtomic.NewUint64(0),
 	}
 
+	unknownUserFailedLogins, err := lru.New(unknownUserFailedLoginsSize)
+	if err != nil {
+		return nil, err
+	}
+	c.unknownUserFailedLogins = unknownUserFailedLogins
+
 	c.SetConfig(conf.RawConfig)
 
 	atomic.StoreUint32(c.replicationState, uint32(consts.ReplicationDRDisabled|consts.ReplicationPerformanceDisabled))
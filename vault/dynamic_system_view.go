@@ -133,6 +133,29 @@ func (e extendedSystemViewImpl) SudoPrivilege(ctx context.Context, path string,
 	return authResults.RootPrivs
 }
 
+// AuthenticateLogin routes the given login request to the auth mount with the
+// given accessor and returns the resulting auth, without creating a token
+// for it. This lets builtin backends which speak protocols other than
+// Vault's own API (such as PKI's EST endpoints) delegate authentication of
+// their clients to an auth method. The request path is relative to the auth
+// mount, e.g. "login/<username>" for userpass. The login is subject to the
+// rate limit quotas, auditing, user lockout and login MFA enforcement of
+// regular logins.
+func (e extendedSystemViewImpl) AuthenticateLogin(ctx context.Context, accessor string, req *logical.Request) (*logical.Auth, error) {
+	entry := e.core.router.MatchingMountByAccessor(accessor)
+	if entry == nil || entry.Table != credentialTableType {
+		return nil, fmt.Errorf("no auth mount found for accessor %q", accessor)
+	}
+	if entry.NamespaceID != e.mountEntry.NamespaceID {
+		return nil, fmt.Errorf("auth mount %q is not in the namespace of this mount", accessor)
+	}
+
+	ctx = namespace.ContextWithNamespace(ctx, entry.Namespace())
+	req.Path = credentialRoutePrefix + entry.Path + req.Path
+
+	return e.core.handleDelegatedLoginRequest(ctx, req)
+}
+
 func (d dynamicSystemView) DefaultLeaseTTL() time.Duration {
 	def, _ := d.fetchTTLs()
 	return def
@@ -411,3 +434,45 @@ func (d dynamicSystemView) GeneratePasswordFromPolicy(ctx context.Context, polic
 
 	return passPolicy.Generate(ctx, nil)
 }
+
+func (d dynamicSystemView) ValidatePasswordWithPolicy(ctx context.Context, policyName string, password string) error {
+	if policyName == "" {
+		return fmt.Errorf("missing password policy name")
+	}
+
+	ctx = namespace.ContextWithNamespace(ctx, d.mountEntry.Namespace())
+
+	policyCfg, err := d.retrievePasswordPolicy(ctx, policyName)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve password policy: %w", err)
+	}
+
+	if policyCfg == nil {
+		return fmt.Errorf("no password policy found")
+	}
+
+	passPolicy, err := random.ParsePolicy(policyCfg.HCLPolicy)
+	if err != nil {
+		return fmt.Errorf("stored password policy is invalid: %w", err)
+	}
+
+	return passPolicy.Validate(password)
+}
+
+func (d dynamicSystemView) ValidateLoginMFA(ctx context.Context, req *logical.Request, aliasName string) error {
+	if d.mountEntry == nil || d.mountEntry.Table != credentialTableType {
+		return fmt.Errorf("login MFA can only be validated by auth methods")
+	}
+	if aliasName == "" {
+		return fmt.Errorf("missing alias name")
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	ctx = namespace.ContextWithNamespace(ctx, d.mountEntry.Namespace())
+
+	return d.core.validateLoginMFAForAlias(ctx, d.mountEntry, aliasName, remoteAddr, loginMFACredsFromContext(ctx))
+}
@@ -10,7 +10,9 @@ import (
 	"time"
 
 	log "github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/audit"
 	ldapcred "github.com/hashicorp/vault/builtin/credential/ldap"
+	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
 	"github.com/hashicorp/vault/helper/namespace"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -292,6 +294,153 @@ func TestDynamicSystemView_GeneratePasswordFromPolicy_failed(t *testing.T) {
 	}
 }
 
+func TestDynamicSystemView_ValidatePasswordWithPolicy(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	ctx := namespace.RootContext(nil)
+
+	rawPolicy := `
+length = 20
+rule "charset" {
+	charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	min-chars = 1
+}
+rule "charset" {
+	charset = "0123456789"
+	min-chars = 2
+}`
+
+	req := logical.TestRequest(t, logical.CreateOperation, "sys/policies/password/"+testPolicyName)
+	req.ClientToken = root
+	req.Data["policy"] = base64.StdEncoding.EncodeToString([]byte(rawPolicy))
+	resp, err := core.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	dsv := TestDynamicSystemView(core, nil)
+
+	tests := map[string]struct {
+		policyName string
+		password   string
+		expectErr  bool
+	}{
+		"valid password":      {policyName: testPolicyName, password: "abcdefghijKLMNOPQRST0123", expectErr: false},
+		"too short":           {policyName: testPolicyName, password: "abcDEF012", expectErr: true},
+		"missing rule chars":  {policyName: testPolicyName, password: "abcdefghijklmnopqrstuvwxyz", expectErr: true},
+		"outside the charset": {policyName: testPolicyName, password: "abcdefghijKLMNOPQRST0123!", expectErr: true},
+		"no policy name":      {policyName: "", password: "abcdefghijKLMNOPQRST0123", expectErr: true},
+		"no policy found":     {policyName: "missing", password: "abcdefghijKLMNOPQRST0123", expectErr: true},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := dsv.ValidatePasswordWithPolicy(ctx, test.policyName, test.password)
+			if test.expectErr && err == nil {
+				t.Fatalf("err expected, got nil")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("no error expected, got: %s", err)
+			}
+		})
+	}
+}
+
+func TestDynamicSystemView_AuthenticateLogin(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+	noop := &NoopAudit{}
+	core.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		noop.Config = config
+		return noop, nil
+	}
+	ctx := namespace.RootContext(nil)
+
+	req := logical.TestRequest(t, logical.UpdateOperation, "sys/audit/noop")
+	req.ClientToken = root
+	req.Data["type"] = "noop"
+	resp, err := core.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "sys/auth/userpass")
+	req.ClientToken = root
+	req.Data["type"] = "userpass"
+	req.Data["config"] = map[string]interface{}{
+		"user_lockout_config": map[string]interface{}{
+			"lockout_threshold": "2",
+			"lockout_duration":  "1h",
+		},
+	}
+	resp, err = core.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	req = logical.TestRequest(t, logical.UpdateOperation, "auth/userpass/users/test")
+	req.ClientToken = root
+	req.Data["password"] = "foo"
+	req.Data["policies"] = "default"
+	resp, err = core.HandleRequest(ctx, req)
+	if err != nil || (resp != nil && resp.IsError()) {
+		t.Fatalf("err: %v, resp: %#v", err, resp)
+	}
+
+	accessor := core.router.MatchingMountEntry(ctx, "auth/userpass/").Accessor
+	sysView := core.mountEntrySysView(core.router.MatchingMountEntry(ctx, "secret/"))
+
+	login := func(password string) (*logical.Auth, error) {
+		return sysView.(extendedSystemViewImpl).AuthenticateLogin(ctx, accessor, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "login/test",
+			Connection: &logical.Connection{},
+			Data: map[string]interface{}{
+				"password": password,
+			},
+		})
+	}
+
+	numReqs := len(noop.Req)
+	auth, err := login("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if auth.DisplayName != "test" {
+		t.Fatalf("bad: %#v", auth)
+	}
+
+	// No token should have been created for the login.
+	if auth.ClientToken != "" {
+		t.Fatalf("expected no token to be created, got %q", auth.ClientToken)
+	}
+
+	// The login should have been audited like a regular one.
+	if len(noop.Req) != numReqs+1 || noop.Req[numReqs].Path != "auth/userpass/login/test" {
+		t.Fatalf("expected the login request to be audited: %#v", noop.Req)
+	}
+	if len(noop.Resp) == 0 || noop.Resp[len(noop.Resp)-1] == nil || noop.Resp[len(noop.Resp)-1].Auth == nil {
+		t.Fatalf("expected the login response to be audited: %#v", noop.Resp)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := login("bar"); err == nil {
+			t.Fatal("expected login with the wrong password to fail")
+		}
+	}
+
+	// The user is now locked out, even with the right password.
+	if _, err := login("foo"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected locked out user to be denied, got: %v", err)
+	}
+
+	if _, err := sysView.(extendedSystemViewImpl).AuthenticateLogin(ctx, "auth_unknown_1234", &logical.Request{
+		Operation: logical.UpdateOperation,
+		Path:      "login/test",
+	}); err == nil {
+		t.Fatal("expected login against an unknown accessor to fail")
+	}
+}
+
 type runes []rune
 
 func (r runes) Len() int           { return len(r) }
@@ -0,0 +1,111 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/builtin/logical/totp"
+	"github.com/hashicorp/vault/helper/testhelpers"
+	vaulthttp "github.com/hashicorp/vault/http"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/hashicorp/vault/vault"
+)
+
+func TestLoginMfaUserpassPasswordChange(t *testing.T) {
+	cluster := vault.NewTestCluster(t, &vault.CoreConfig{
+		CredentialBackends: map[string]logical.Factory{
+			"userpass": userpass.Factory,
+		},
+		LogicalBackends: map[string]logical.Factory{
+			"totp": totp.Factory,
+		},
+	},
+		&vault.TestClusterOptions{
+			HandlerFunc: vaulthttp.Handler,
+		})
+
+	cluster.Start()
+	defer cluster.Cleanup()
+
+	client := cluster.Cores[0].Client
+
+	testhelpers.SetupTOTPMount(t, client)
+	mountAccessor := testhelpers.SetupUserpassMountAccessor(t, client)
+
+	userClient, entityID, _ := testhelpers.CreateEntityAndAlias(t, client, mountAccessor, "entity1", "testuser1")
+	userClient.ClearToken()
+
+	passwordPath := "auth/userpass/login/testuser1/password"
+	changePassword := func(current, newPassword string) error {
+		_, err := userClient.Logical().WriteWithContext(context.Background(), passwordPath, map[string]interface{}{
+			"password":     current,
+			"new_password": newPassword,
+		})
+		return err
+	}
+
+	// Without login MFA enforcement, the current password is enough
+	if err := changePassword("wrongpassword", "newpassword"); err == nil {
+		t.Fatal("expected password change with a wrong current password to fail")
+	}
+	if err := changePassword("testpassword", "newpassword"); err != nil {
+		t.Fatalf("failed to change password: %v", err)
+	}
+
+	methodID := testhelpers.SetupTOTPMethod(t, client, map[string]interface{}{
+		"issuer":                  "yCorp",
+		"period":                  5,
+		"algorithm":               "SHA1",
+		"digits":                  6,
+		"skew":                    1,
+		"key_size":                10,
+		"qr_size":                 100,
+		"max_validation_attempts": 3,
+	})
+	// Registering the entity enforces login MFA for it
+	enginePath := testhelpers.RegisterEntityInTOTPEngine(t, client, entityID, methodID)
+
+	// With login MFA enforced, MFA credentials are required
+	if err := changePassword("newpassword", "otherpassword"); err == nil {
+		t.Fatal("expected password change without MFA credentials to fail")
+	}
+	userClient.AddHeader("X-Vault-MFA", fmt.Sprintf("%s:%s", methodID, "000000"))
+	if err := changePassword("newpassword", "otherpassword"); err == nil {
+		t.Fatal("expected password change with invalid MFA credentials to fail")
+	}
+
+	time.Sleep(5 * time.Second)
+	testhelpers.RetryUntil(t, 20*time.Second, func() error {
+		totpPasscode := testhelpers.GetTOTPCodeFromEngine(t, client, enginePath)
+		headers := userClient.Headers()
+		headers.Set("X-Vault-MFA", fmt.Sprintf("%s:%s", methodID, totpPasscode))
+		userClient.SetHeaders(headers)
+		if err := changePassword("newpassword", "otherpassword"); err != nil {
+			return fmt.Errorf("password change with MFA failed: %w", err)
+		}
+		return nil
+	})
+
+	// The previous password no longer works
+	headers := userClient.Headers()
+	headers.Del("X-Vault-MFA")
+	userClient.SetHeaders(headers)
+	_, err := userClient.Logical().WriteWithContext(context.Background(), "auth/userpass/login/testuser1", map[string]interface{}{
+		"password": "newpassword",
+	})
+	if err == nil {
+		t.Fatal("expected login with the previous password to fail")
+	}
+	secret, err := userClient.Logical().WriteWithContext(context.Background(), "auth/userpass/login/testuser1", map[string]interface{}{
+		"password": "otherpassword",
+	})
+	if err != nil {
+		t.Fatalf("failed to log in with the new password: %v", err)
+	}
+	if secret == nil || secret.Auth == nil || secret.Auth.MFARequirement == nil {
+		t.Fatalf("expected login with the new password to require MFA, got: %#v", secret)
+	}
+}
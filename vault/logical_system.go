@@ -190,6 +190,7 @@ func NewSystemBackend(core *Core, logger log.Logger) *SystemBackend {
 	b.Backend.Paths = append(b.Backend.Paths, b.quotasPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.rootActivityPaths()...)
 	b.Backend.Paths = append(b.Backend.Paths, b.loginMFAPaths()...)
+	b.Backend.Paths = append(b.Backend.Paths, b.lockedUsersPaths()...)
 
 	if core.rawEnabled {
 		b.Backend.Paths = append(b.Backend.Paths, b.rawPaths()...)
@@ -1578,6 +1579,9 @@ func (b *SystemBackend) handleTuneReadCommon(ctx context.Context, path string) (
 
 	if mountEntry.Table == credentialTableType {
 		resp.Data["token_type"] = mountEntry.Config.TokenType.String()
+		if supportsUserLockout(mountEntry.Type) {
+			resp.Data["user_lockout_config"] = userLockoutConfigResponse(mountEntry)
+		}
 	}
 
 	if rawVal, ok := mountEntry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
@@ -1979,6 +1983,35 @@ func (b *SystemBackend) handleTuneWriteCommon(ctx context.Context, path string,
 		}
 	}
 
+	if rawVal, ok := data.GetOk("user_lockout_config"); ok {
+		if mountEntry.Table != credentialTableType || !supportsUserLockout(mountEntry.Type) {
+			return logical.ErrorResponse("user_lockout_config is only supported by auth methods of type %s", strings.Join(userLockoutAuthMethods, ", ")),
+				logical.ErrInvalidRequest
+		}
+
+		var apiUserLockoutConfig APIUserLockoutConfig
+		if err := mapstructure.WeakDecode(rawVal, &apiUserLockoutConfig); err != nil {
+			return logical.ErrorResponse("unable to parse user_lockout_config: %s", err), logical.ErrInvalidRequest
+		}
+		userLockoutConfig, err := parseUserLockoutConfig(&apiUserLockoutConfig)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+
+		oldVal := mountEntry.Config.UserLockoutConfig
+		mountEntry.Config.UserLockoutConfig = userLockoutConfig
+
+		// Update the mount table
+		if err := b.Core.persistAuth(ctx, b.Core.auth, &mountEntry.Local); err != nil {
+			mountEntry.Config.UserLockoutConfig = oldVal
+			return handleError(err)
+		}
+
+		if b.Core.logger.IsInfo() {
+			b.Core.logger.Info("mount tuning of user_lockout_config successful", "path", path)
+		}
+	}
+
 	var err error
 	var resp *logical.Response
 	var options map[string]string
@@ -2466,6 +2499,17 @@ func (b *SystemBackend) handleEnableAuth(ctx context.Context, req *logical.Reque
 	if len(apiConfig.AllowedManagedKeys) > 0 {
 		config.AllowedManagedKeys = apiConfig.AllowedManagedKeys
 	}
+	if apiConfig.UserLockoutConfig != nil {
+		if !supportsUserLockout(logicalType) {
+			return logical.ErrorResponse("user_lockout_config is only supported by auth methods of type %s", strings.Join(userLockoutAuthMethods, ", ")),
+				logical.ErrInvalidRequest
+		}
+		userLockoutConfig, err := parseUserLockoutConfig(apiConfig.UserLockoutConfig)
+		if err != nil {
+			return logical.ErrorResponse(err.Error()), logical.ErrInvalidRequest
+		}
+		config.UserLockoutConfig = userLockoutConfig
+	}
 
 	// Create the mount entry
 	me := &MountEntry{
@@ -5021,6 +5065,10 @@ in the plugin catalog.`,
 		`The options to pass into the backend. Should be a json object with string keys and values.`,
 	},
 
+	"tune_user_lockout_config": {
+		`The user lockout configuration for an auth mount, as a json object with the keys lockout_threshold, lockout_duration, lockout_counter_reset and disable_lockout.`,
+	},
+
 	"remount": {
 		"Move the mount point of an already-mounted backend, within or across namespaces",
 		`
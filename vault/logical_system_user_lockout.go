@@ -0,0 +1,114 @@
+package vault
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+// lockedUsersPaths returns paths to list and unlock users locked out of
+// auth mounts
+func (b *SystemBackend) lockedUsersPaths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: "locked-users/?$",
+			Fields: map[string]*framework.FieldSchema{
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "If set, only users locked out of the auth mount with this accessor are returned.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.handleLockedUsersRead,
+					Summary:  "Report the users locked out of auth mounts.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(lockedUsersHelp["locked-users"][0]),
+			HelpDescription: strings.TrimSpace(lockedUsersHelp["locked-users"][1]),
+		},
+		{
+			Pattern: "locked-users/(?P<mount_accessor>.+?)/unlock/(?P<alias_identifier>.+)",
+			Fields: map[string]*framework.FieldSchema{
+				"mount_accessor": {
+					Type:        framework.TypeString,
+					Description: "Accessor of the auth mount the user is locked out of.",
+				},
+				"alias_identifier": {
+					Type:        framework.TypeString,
+					Description: "Name of the user's entity alias in the auth mount, such as the username.",
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.handleUnlockUser,
+					Summary:  "Unlock a user locked out of an auth mount.",
+				},
+			},
+			HelpSynopsis:    strings.TrimSpace(lockedUsersHelp["unlock-user"][0]),
+			HelpDescription: strings.TrimSpace(lockedUsersHelp["unlock-user"][1]),
+		},
+	}
+}
+
+func (b *SystemBackend) handleLockedUsersRead(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	lockedUsers, err := b.Core.listLockedUsers(ctx, d.Get("mount_accessor").(string))
+	if err != nil {
+		return handleError(err)
+	}
+
+	users := make([]map[string]interface{}, 0, len(lockedUsers))
+	for _, locked := range lockedUsers {
+		users = append(users, map[string]interface{}{
+			"mount_accessor":        locked.MountAccessor,
+			"alias_identifier":      locked.AliasName,
+			"failed_login_attempts": locked.FailedLoginAttempts,
+			"locked_at":             locked.LockedAt.UTC().Format(time.RFC3339),
+			"locked_until":          locked.LockedUntil.UTC().Format(time.RFC3339),
+		})
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"locked_users": users,
+			"total":        len(users),
+		},
+	}, nil
+}
+
+func (b *SystemBackend) handleUnlockUser(ctx context.Context, req *logical.Request, d *framework.FieldData) (*logical.Response, error) {
+	mountAccessor := d.Get("mount_accessor").(string)
+	aliasIdentifier := d.Get("alias_identifier").(string)
+	if mountAccessor == "" || aliasIdentifier == "" {
+		return logical.ErrorResponse("mount_accessor and alias_identifier are required"), logical.ErrInvalidRequest
+	}
+
+	if err := b.Core.unlockUser(ctx, &lockoutUser{mountAccessor: mountAccessor, aliasName: aliasIdentifier}); err != nil {
+		return handleError(err)
+	}
+
+	return nil, nil
+}
+
+var lockedUsersHelp = map[string][2]string{
+	"locked-users": {
+		"Report the users locked out of auth mounts.",
+		`
+Users of the ldap, okta, radius and userpass auth methods are locked out
+after too many failed login attempts, as configured by the user_lockout_config
+of their auth mount. This endpoint returns the users currently locked out,
+optionally only those of the auth mount with the given mount_accessor.
+		`,
+	},
+	"unlock-user": {
+		"Unlock a user locked out of an auth mount.",
+		`
+Lifts the lockout of the user with the given entity alias name in the auth
+mount with the given accessor and clears its failed login attempts. Unlocking
+a user that is not locked out is not an error.
+		`,
+	},
+}
@@ -1594,6 +1594,55 @@ func (c *Core) validateLoginMFA(ctx context.Context, eConfig *mfa.MFAEnforcement
 	return multierror.Append(retErr, fmt.Errorf("login MFA validation failed for methodID: %v", eConfig.MFAMethodIDs))
 }
 
+// loginMFACredsContextKey holds the MFA credentials of a login request in its
+// context. The credentials are removed from the request routed to the auth
+// method, but builtin auth methods need them to enforce login MFA on
+// unauthenticated requests which don't issue a token, such as self-service
+// password changes.
+type loginMFACredsContextKey struct{}
+
+func contextWithLoginMFACreds(ctx context.Context, mfaCreds logical.MFACreds) context.Context {
+	return context.WithValue(ctx, loginMFACredsContextKey{}, mfaCreds)
+}
+
+func loginMFACredsFromContext(ctx context.Context) logical.MFACreds {
+	mfaCreds, _ := ctx.Value(loginMFACredsContextKey{}).(logical.MFACreds)
+	return mfaCreds
+}
+
+// validateLoginMFAForAlias enforces the login MFA which would apply to a login
+// of the user with the given alias in an auth mount. As no token is issued,
+// only single-phase validation of the supplied MFA credentials is supported.
+func (c *Core) validateLoginMFAForAlias(ctx context.Context, me *MountEntry, aliasName, requestConnRemoteAddr string, mfaCreds logical.MFACreds) error {
+	entity, err := c.identityStore.entityByAliasFactors(me.Accessor, aliasName, false)
+	if err != nil {
+		return err
+	}
+
+	matchedMfaEnforcementList, err := c.buildMFAEnforcementConfigList(ctx, entity, credentialRoutePrefix+me.Path)
+	if err != nil {
+		return fmt.Errorf("failed to find MFAEnforcement configuration, error: %v", err)
+	}
+	if len(matchedMfaEnforcementList) == 0 {
+		return nil
+	}
+
+	// As for logins, an entity is needed to enforce MFA
+	if entity == nil {
+		return logical.ErrPermissionDenied
+	}
+	if len(mfaCreds) == 0 {
+		return fmt.Errorf("login MFA is enforced, MFA credentials must be supplied with the X-Vault-MFA header")
+	}
+	for _, eConfig := range matchedMfaEnforcementList {
+		if err := c.validateLoginMFA(ctx, eConfig, entity, requestConnRemoteAddr, mfaCreds); err != nil {
+			return logical.ErrPermissionDenied
+		}
+	}
+
+	return nil
+}
+
 func (c *Core) validateLoginMFAInternal(ctx context.Context, methodID string, entity *identity.Entity, reqConnectionRemoteAddress string, mfaCreds []string) (retErr error) {
 	if entity == nil {
 		return fmt.Errorf("entity is nil")
@@ -785,6 +785,9 @@ func (c *Core) handleCancelableRequest(ctx context.Context, req *logical.Request
 		}
 	}
 
+	// User lockout errors carry details for the audit log only.
+	err = userLockoutClientError(err)
+
 	if walState.LocalIndex != 0 || walState.ReplicatedIndex != 0 {
 		walState.ClusterID = c.clusterID.Load()
 		if walState.LocalIndex == 0 {
@@ -1325,8 +1328,21 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 		return nil, nil, ErrInternalError
 	}
 
-	// Route the request
-	resp, routeErr := c.doRouting(ctx, req)
+	// Reject logins of users locked out after too many failed attempts, and
+	// track the outcome of the login for users subject to lockout.
+	lockoutUser, lockoutSettings := c.loginLockoutUser(ctx, entry, req)
+	if lockoutUser != nil {
+		if err := c.applyUserLockout(ctx, lockoutUser); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// Route the request. The MFA credentials of the request are made
+	// available to builtin auth methods which enforce login MFA themselves.
+	resp, routeErr := c.doRouting(contextWithLoginMFACreds(ctx, req.MFACreds), req)
+	if lockoutUser != nil {
+		routeErr = c.handleLoginLockoutResult(ctx, lockoutUser, lockoutSettings, resp, routeErr)
+	}
 	if resp != nil {
 		// If wrapping is used, use the shortest between the request and response
 		var wrapTTL time.Duration
@@ -1616,6 +1632,166 @@ func (c *Core) handleLoginRequest(ctx context.Context, req *logical.Request) (re
 	return resp, auth, routeErr
 }
 
+// handleDelegatedLoginRequest authenticates a login request made by a
+// builtin backend on behalf of one of its clients, see
+// extendedSystemViewImpl.AuthenticateLogin. The request is subject to the
+// same rate limit quotas, auditing, user lockout and login MFA enforcement
+// as a login made through the API, but no token is created for the
+// resulting auth. As such clients can't complete a two-phase MFA login,
+// logins subject to login MFA are rejected unless the request carries MFA
+// credentials.
+func (c *Core) handleDelegatedLoginRequest(ctx context.Context, req *logical.Request) (retAuth *logical.Auth, retErr error) {
+	defer metrics.MeasureSince([]string{"core", "handle_delegated_login_request"}, time.Now())
+
+	req.Unauthenticated = true
+	if req.ID == "" {
+		reqID, err := uuid.GenerateUUID()
+		if err != nil {
+			return nil, err
+		}
+		req.ID = reqID
+	}
+
+	ns, err := namespace.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := c.router.MatchingMountEntry(ctx, req.Path)
+	if entry == nil {
+		return nil, fmt.Errorf("no auth mount found for path %q", req.Path)
+	}
+	req.MountType = entry.Type
+	var nonHMACReqDataKeys, nonHMACRespDataKeys []string
+	if rawVals, ok := entry.synthesizedConfigCache.Load("audit_non_hmac_request_keys"); ok {
+		nonHMACReqDataKeys = rawVals.([]string)
+	}
+	if rawVals, ok := entry.synthesizedConfigCache.Load("audit_non_hmac_response_keys"); ok {
+		nonHMACRespDataKeys = rawVals.([]string)
+	}
+
+	var remoteAddr string
+	if req.Connection != nil {
+		remoteAddr = req.Connection.RemoteAddr
+	}
+
+	mountPath := strings.TrimPrefix(c.router.MatchingMount(ctx, req.Path), ns.Path)
+	quotaResp, err := c.ApplyRateLimitQuota(ctx, &quotas.Request{
+		Path:          req.Path,
+		MountPath:     mountPath,
+		Role:          c.DetermineRoleFromLoginRequest(mountPath, req.Data, ctx),
+		NamespacePath: ns.Path,
+		ClientAddress: remoteAddr,
+	})
+	if err != nil {
+		c.logger.Error("failed to apply quota", "path", req.Path, "error", err)
+		return nil, err
+	}
+	if !quotaResp.Allowed {
+		quotaErr := fmt.Errorf("request path %q: %w", req.Path, quotas.ErrRateLimitQuotaExceeded)
+		if c.RateLimitAuditLoggingEnabled() {
+			logInput := &logical.LogInput{
+				Request:            req,
+				OuterErr:           quotaErr,
+				NonHMACReqDataKeys: nonHMACReqDataKeys,
+			}
+			if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+				c.logger.Warn("failed to audit log request rejection caused by rate limit quota violation", "error", err)
+			}
+		}
+		return nil, quotaErr
+	}
+
+	logInput := &logical.LogInput{
+		Request:            req,
+		NonHMACReqDataKeys: nonHMACReqDataKeys,
+	}
+	if err := c.auditBroker.LogRequest(ctx, logInput, c.auditedHeaders); err != nil {
+		c.logger.Error("failed to audit request", "path", req.Path, "error", err)
+		return nil, ErrInternalError
+	}
+
+	var resp *logical.Response
+	defer func() {
+		logInput := &logical.LogInput{
+			Request:             req,
+			Response:            resp,
+			OuterErr:            retErr,
+			NonHMACReqDataKeys:  nonHMACReqDataKeys,
+			NonHMACRespDataKeys: nonHMACRespDataKeys,
+		}
+		if err := c.auditBroker.LogResponse(ctx, logInput, c.auditedHeaders); err != nil {
+			c.logger.Error("failed to audit response", "request_path", req.Path, "error", err)
+			retAuth, retErr = nil, ErrInternalError
+			return
+		}
+
+		// User lockout errors carry details for the audit log only.
+		retErr = userLockoutClientError(retErr)
+	}()
+
+	lockoutUser, lockoutSettings := c.loginLockoutUser(ctx, entry, req)
+	if lockoutUser != nil {
+		if err := c.applyUserLockout(ctx, lockoutUser); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err = c.doRouting(contextWithLoginMFACreds(ctx, req.MFACreds), req)
+	if lockoutUser != nil {
+		err = c.handleLoginLockoutResult(ctx, lockoutUser, lockoutSettings, resp, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil && resp.IsError() {
+		return nil, resp.Error()
+	}
+	if resp == nil || resp.Auth == nil {
+		return nil, fmt.Errorf("login request to %q returned no auth", req.Path)
+	}
+	if resp.Secret != nil {
+		c.logger.Error("unexpected Secret response for login path", "request_path", req.Path)
+		return nil, ErrInternalError
+	}
+
+	// Look up the entity of an existing alias only; delegated logins don't
+	// create identities.
+	var entity *identity.Entity
+	if resp.Auth.Alias != nil && c.identityStore != nil {
+		alias, err := c.identityStore.MemDBAliasByFactors(entry.Accessor, resp.Auth.Alias.Name, false, false)
+		if err != nil {
+			return nil, err
+		}
+		if alias != nil {
+			entity, err = c.identityStore.MemDBEntityByID(alias.CanonicalID, false)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if entity != nil && entity.Disabled {
+		return nil, logical.ErrPermissionDenied
+	}
+
+	matchedMfaEnforcementList, err := c.buildMFAEnforcementConfigList(ctx, entity, req.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find MFAEnforcement configuration, error: %v", err)
+	}
+	if len(matchedMfaEnforcementList) > 0 {
+		if entity == nil || len(req.MFACreds) == 0 {
+			return nil, logical.ErrPermissionDenied
+		}
+		for _, eConfig := range matchedMfaEnforcementList {
+			if err := c.validateLoginMFA(ctx, eConfig, entity, remoteAddr, req.MFACreds); err != nil {
+				return nil, logical.ErrPermissionDenied
+			}
+		}
+	}
+
+	return resp.Auth, nil
+}
+
 // LoginCreateToken creates a token as a result of a login request.
 // If MFA is enforced, mfa/validate endpoint calls this functions
 // after successful MFA validation to generate the token.
@@ -0,0 +1,502 @@
+package vault
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	metrics "github.com/armon/go-metrics"
+	"github.com/hashicorp/go-secure-stdlib/parseutil"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	// lockedUsersPrefix is the storage prefix of users locked out of an
+	// auth mount, stored by mount accessor and hashed alias name.
+	lockedUsersPrefix = "core/login/locked-users/"
+
+	defaultUserLockoutThreshold    = 5
+	defaultUserLockoutDuration     = 15 * time.Minute
+	defaultUserLockoutCounterReset = 15 * time.Minute
+
+	// userFailedLoginsSweepInterval is the minimum interval at which failed
+	// login attempts past their counter reset window are swept from memory.
+	userFailedLoginsSweepInterval = time.Minute
+
+	// unknownUserFailedLoginsSize bounds the number of users without an
+	// entity alias whose failed login attempts are tracked, as their alias
+	// names are chosen by unauthenticated clients.
+	unknownUserFailedLoginsSize = 4096
+)
+
+// userLockoutAuthMethods are the auth methods whose logins are subject to
+// user lockout. Their login paths support alias lookahead and return
+// logical.ErrInvalidCredentials for invalid credentials.
+var userLockoutAuthMethods = []string{"ldap", "okta", "radius", "userpass"}
+
+// UserLockoutConfig is the user lockout configuration of an auth mount.
+// Zero values select the defaults.
+type UserLockoutConfig struct {
+	LockoutThreshold    uint64        `json:"lockout_threshold,omitempty" mapstructure:"lockout_threshold"`
+	LockoutDuration     time.Duration `json:"lockout_duration,omitempty" mapstructure:"lockout_duration"`
+	LockoutCounterReset time.Duration `json:"lockout_counter_reset,omitempty" mapstructure:"lockout_counter_reset"`
+	DisableLockout      bool          `json:"disable_lockout,omitempty" mapstructure:"disable_lockout"`
+}
+
+// APIUserLockoutConfig is the user lockout configuration as given when
+// enabling an auth mount through the API.
+type APIUserLockoutConfig struct {
+	LockoutThreshold    string `json:"lockout_threshold,omitempty" mapstructure:"lockout_threshold"`
+	LockoutDuration     string `json:"lockout_duration,omitempty" mapstructure:"lockout_duration"`
+	LockoutCounterReset string `json:"lockout_counter_reset,omitempty" mapstructure:"lockout_counter_reset"`
+	DisableLockout      *bool  `json:"disable_lockout,omitempty" mapstructure:"disable_lockout"`
+}
+
+// userLockoutSettings are the effective user lockout settings of a mount.
+type userLockoutSettings struct {
+	threshold    uint64
+	duration     time.Duration
+	counterReset time.Duration
+}
+
+// userLockoutSettingsForMount returns the user lockout settings of the given
+// mount, or nil if logins to the mount are not subject to user lockout.
+func userLockoutSettingsForMount(entry *MountEntry) *userLockoutSettings {
+	if entry == nil || entry.Table != credentialTableType || !supportsUserLockout(entry.Type) {
+		return nil
+	}
+
+	settings := &userLockoutSettings{
+		threshold:    defaultUserLockoutThreshold,
+		duration:     defaultUserLockoutDuration,
+		counterReset: defaultUserLockoutCounterReset,
+	}
+
+	config := entry.Config.UserLockoutConfig
+	if config == nil {
+		return settings
+	}
+	if config.DisableLockout {
+		return nil
+	}
+	if config.LockoutThreshold > 0 {
+		settings.threshold = config.LockoutThreshold
+	}
+	if config.LockoutDuration > 0 {
+		settings.duration = config.LockoutDuration
+	}
+	if config.LockoutCounterReset > 0 {
+		settings.counterReset = config.LockoutCounterReset
+	}
+
+	return settings
+}
+
+// parseUserLockoutConfig converts the user lockout configuration given
+// through the API into the stored configuration.
+func parseUserLockoutConfig(apiConfig *APIUserLockoutConfig) (*UserLockoutConfig, error) {
+	config := &UserLockoutConfig{}
+
+	if apiConfig.LockoutThreshold != "" {
+		threshold, err := strconv.ParseUint(apiConfig.LockoutThreshold, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lockout_threshold: %w", err)
+		}
+		config.LockoutThreshold = threshold
+	}
+	if apiConfig.LockoutDuration != "" {
+		duration, err := parseutil.ParseDurationSecond(apiConfig.LockoutDuration)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lockout_duration: %w", err)
+		}
+		if duration < 0 {
+			return nil, errors.New("lockout_duration must not be negative")
+		}
+		config.LockoutDuration = duration
+	}
+	if apiConfig.LockoutCounterReset != "" {
+		counterReset, err := parseutil.ParseDurationSecond(apiConfig.LockoutCounterReset)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lockout_counter_reset: %w", err)
+		}
+		if counterReset < 0 {
+			return nil, errors.New("lockout_counter_reset must not be negative")
+		}
+		config.LockoutCounterReset = counterReset
+	}
+	if apiConfig.DisableLockout != nil {
+		config.DisableLockout = *apiConfig.DisableLockout
+	}
+
+	return config, nil
+}
+
+// userLockoutConfigResponse returns the effective user lockout configuration
+// of an auth mount as returned when reading its tuning.
+func userLockoutConfigResponse(entry *MountEntry) map[string]interface{} {
+	settings := userLockoutSettingsForMount(entry)
+	if settings == nil {
+		return map[string]interface{}{
+			"disable_lockout": true,
+		}
+	}
+	return map[string]interface{}{
+		"lockout_threshold":     settings.threshold,
+		"lockout_duration":      int64(settings.duration.Seconds()),
+		"lockout_counter_reset": int64(settings.counterReset.Seconds()),
+		"disable_lockout":       false,
+	}
+}
+
+func supportsUserLockout(mountType string) bool {
+	for _, t := range userLockoutAuthMethods {
+		if t == mountType {
+			return true
+		}
+	}
+	return false
+}
+
+// lockoutUser identifies a user of an auth mount for user lockout.
+type lockoutUser struct {
+	mountAccessor string
+	aliasName     string
+}
+
+func (u lockoutUser) storagePath() string {
+	hash := sha256.Sum256([]byte(u.aliasName))
+	return lockedUsersPrefix + u.mountAccessor + "/" + hex.EncodeToString(hash[:])
+}
+
+// failedLoginInfo tracks the failed login attempts of a user. Failed
+// attempts are tracked per node.
+type failedLoginInfo struct {
+	count           uint64
+	lastFailedLogin time.Time
+	// lockedUntil is set while the user is locked out, so the lockout is
+	// enforced on this node even when it could not be persisted.
+	lockedUntil time.Time
+}
+
+// lockedUserEntry is persisted for users that are locked out.
+type lockedUserEntry struct {
+	MountAccessor       string    `json:"mount_accessor"`
+	AliasName           string    `json:"alias_name"`
+	FailedLoginAttempts uint64    `json:"failed_login_attempts"`
+	LockedAt            time.Time `json:"locked_at"`
+	LockedUntil         time.Time `json:"locked_until"`
+}
+
+// userLockedOutError is returned for login requests of locked out users.
+// The details are recorded in the audit log; clients only see a permission
+// denied error so that locked out users can't be enumerated.
+type userLockedOutError struct {
+	user        lockoutUser
+	lockedUntil time.Time
+	// triggered is set when the request's failed login attempt caused the
+	// lockout.
+	triggered bool
+}
+
+func (e *userLockedOutError) Error() string {
+	if e.triggered {
+		return fmt.Sprintf("user %q of auth mount %q locked out until %s after too many failed login attempts",
+			e.user.aliasName, e.user.mountAccessor, e.lockedUntil.UTC().Format(time.RFC3339))
+	}
+	return fmt.Sprintf("user %q of auth mount %q is locked out until %s",
+		e.user.aliasName, e.user.mountAccessor, e.lockedUntil.UTC().Format(time.RFC3339))
+}
+
+func (e *userLockedOutError) Unwrap() error {
+	return logical.ErrPermissionDenied
+}
+
+// loginLockoutUser returns the user a login request is made for, if logins
+// to the mount are subject to user lockout. The alias name is determined
+// through an alias lookahead; nil is returned when it can't be determined.
+func (c *Core) loginLockoutUser(ctx context.Context, entry *MountEntry, req *logical.Request) (*lockoutUser, *userLockoutSettings) {
+	settings := userLockoutSettingsForMount(entry)
+	if settings == nil {
+		return nil, nil
+	}
+
+	lookaheadReq := &logical.Request{
+		ID:         req.ID,
+		Operation:  logical.AliasLookaheadOperation,
+		Path:       req.Path,
+		Data:       req.Data,
+		Connection: req.Connection,
+		Headers:    req.Headers,
+	}
+	resp, err := c.router.Route(ctx, lookaheadReq)
+	if err != nil || resp == nil || resp.Auth == nil || resp.Auth.Alias == nil || resp.Auth.Alias.Name == "" {
+		return nil, nil
+	}
+
+	return &lockoutUser{
+		mountAccessor: entry.Accessor,
+		aliasName:     resp.Auth.Alias.Name,
+	}, settings
+}
+
+// checkUserLockedOut returns a userLockedOutError if the user is currently
+// locked out.
+func (c *Core) checkUserLockedOut(ctx context.Context, user *lockoutUser) error {
+	now := time.Now()
+
+	c.userFailedLoginsLock.Lock()
+	info := c.failedLoginInfoLocked(user)
+	if info != nil && now.Before(info.lockedUntil) {
+		c.userFailedLoginsLock.Unlock()
+		return &userLockedOutError{user: *user, lockedUntil: info.lockedUntil}
+	}
+	c.userFailedLoginsLock.Unlock()
+
+	entry, err := c.barrier.Get(ctx, user.storagePath())
+	if err != nil {
+		return fmt.Errorf("failed to read locked user entry: %w", err)
+	}
+	if entry == nil {
+		return nil
+	}
+
+	var locked lockedUserEntry
+	if err := entry.DecodeJSON(&locked); err != nil {
+		return fmt.Errorf("failed to decode locked user entry: %w", err)
+	}
+	if now.Before(locked.LockedUntil) {
+		return &userLockedOutError{user: *user, lockedUntil: locked.LockedUntil}
+	}
+
+	// The lockout has expired; clean up its entry if we are able to.
+	if !c.perfStandby {
+		if err := c.barrier.Delete(ctx, user.storagePath()); err != nil {
+			c.logger.Warn("failed to delete expired locked user entry", "mount_accessor", user.mountAccessor, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// knownLockoutUser returns whether the user has an entity alias, i.e. has
+// logged in successfully before.
+func (c *Core) knownLockoutUser(user *lockoutUser) bool {
+	if c.identityStore == nil {
+		return false
+	}
+	alias, err := c.identityStore.MemDBAliasByFactors(user.mountAccessor, user.aliasName, false, false)
+	return err == nil && alias != nil
+}
+
+// failedLoginInfoLocked returns the failed login attempts tracked for the
+// user, if any. The caller must hold userFailedLoginsLock.
+func (c *Core) failedLoginInfoLocked(user *lockoutUser) *failedLoginInfo {
+	if info, ok := c.userFailedLogins[*user]; ok {
+		return info
+	}
+	if info, ok := c.unknownUserFailedLogins.Get(*user); ok {
+		return info.(*failedLoginInfo)
+	}
+	return nil
+}
+
+// recordFailedLogin records a failed login attempt of the user, locking the
+// user out once the attempts within the counter reset window reach the
+// threshold. A userLockedOutError is returned when the user is locked out.
+//
+// Users without an entity alias are tracked in a bounded cache and their
+// lockouts are only enforced in memory, so that clients can't grow memory
+// or storage by trying arbitrary user names.
+func (c *Core) recordFailedLogin(ctx context.Context, user *lockoutUser, settings *userLockoutSettings) error {
+	now := time.Now()
+	known := c.knownLockoutUser(user)
+
+	c.userFailedLoginsLock.Lock()
+	c.sweepUserFailedLoginsLocked(now)
+	info := c.failedLoginInfoLocked(user)
+	if info == nil || now.Sub(info.lastFailedLogin) > settings.counterReset {
+		info = &failedLoginInfo{}
+		if known {
+			c.userFailedLogins[*user] = info
+		} else {
+			c.unknownUserFailedLogins.Add(*user, info)
+		}
+	}
+	info.count++
+	info.lastFailedLogin = now
+	if info.count < settings.threshold {
+		c.userFailedLoginsLock.Unlock()
+		return nil
+	}
+
+	locked := &lockedUserEntry{
+		MountAccessor:       user.mountAccessor,
+		AliasName:           user.aliasName,
+		FailedLoginAttempts: info.count,
+		LockedAt:            now,
+		LockedUntil:         now.Add(settings.duration),
+	}
+	info.count = 0
+	info.lockedUntil = locked.LockedUntil
+	c.userFailedLoginsLock.Unlock()
+
+	c.logger.Warn("user locked out after too many failed login attempts",
+		"mount_accessor", user.mountAccessor, "locked_until", locked.LockedUntil.UTC().Format(time.RFC3339))
+	metrics.IncrCounter([]string{"core", "user_lockout", "locked_users"}, 1)
+
+	// Persist the lockout so it's visible through sys/locked-users and
+	// survives restarts. Performance standbys can't write to storage; the
+	// lockout is then only enforced in memory, as it is for unknown users.
+	if known && !c.perfStandby {
+		entry, err := logical.StorageEntryJSON(user.storagePath(), locked)
+		if err != nil {
+			return fmt.Errorf("failed to encode locked user entry: %w", err)
+		}
+		if err := c.barrier.Put(ctx, entry); err != nil {
+			c.logger.Error("failed to persist locked user entry", "mount_accessor", user.mountAccessor, "error", err)
+		}
+	}
+
+	return &userLockedOutError{user: *user, lockedUntil: locked.LockedUntil, triggered: true}
+}
+
+// resetFailedLogins clears the failed login attempts of a user after a
+// successful login.
+func (c *Core) resetFailedLogins(user *lockoutUser) {
+	c.userFailedLoginsLock.Lock()
+	defer c.userFailedLoginsLock.Unlock()
+
+	delete(c.userFailedLogins, *user)
+	c.unknownUserFailedLogins.Remove(*user)
+}
+
+// sweepUserFailedLoginsLocked removes failed login attempts which can no
+// longer lead to a lockout from memory. The caller must hold
+// userFailedLoginsLock.
+func (c *Core) sweepUserFailedLoginsLocked(now time.Time) {
+	if now.Sub(c.userFailedLoginsLastSweep) < userFailedLoginsSweepInterval {
+		return
+	}
+	c.userFailedLoginsLastSweep = now
+
+	for user, info := range c.userFailedLogins {
+		settings := userLockoutSettingsForMount(c.router.MatchingMountByAccessor(user.mountAccessor))
+		if settings == nil ||
+			(now.Sub(info.lastFailedLogin) > settings.counterReset && now.After(info.lockedUntil)) {
+			delete(c.userFailedLogins, user)
+		}
+	}
+}
+
+// listLockedUsers returns the users currently locked out, optionally limited
+// to a single mount, sorted by mount accessor and alias name.
+func (c *Core) listLockedUsers(ctx context.Context, mountAccessor string) ([]*lockedUserEntry, error) {
+	var accessors []string
+	if mountAccessor != "" {
+		accessors = []string{mountAccessor}
+	} else {
+		keys, err := c.barrier.List(ctx, lockedUsersPrefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			accessors = append(accessors, strings.TrimSuffix(key, "/"))
+		}
+	}
+
+	now := time.Now()
+	var lockedUsers []*lockedUserEntry
+	for _, accessor := range accessors {
+		prefix := lockedUsersPrefix + accessor + "/"
+		keys, err := c.barrier.List(ctx, prefix)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			entry, err := c.barrier.Get(ctx, prefix+key)
+			if err != nil {
+				return nil, err
+			}
+			if entry == nil {
+				continue
+			}
+			var locked lockedUserEntry
+			if err := entry.DecodeJSON(&locked); err != nil {
+				return nil, fmt.Errorf("failed to decode locked user entry: %w", err)
+			}
+			if now.Before(locked.LockedUntil) {
+				lockedUsers = append(lockedUsers, &locked)
+			}
+		}
+	}
+
+	sort.Slice(lockedUsers, func(i, j int) bool {
+		if lockedUsers[i].MountAccessor != lockedUsers[j].MountAccessor {
+			return lockedUsers[i].MountAccessor < lockedUsers[j].MountAccessor
+		}
+		return lockedUsers[i].AliasName < lockedUsers[j].AliasName
+	})
+
+	return lockedUsers, nil
+}
+
+// unlockUser lifts the lockout of a user and clears its failed login
+// attempts.
+func (c *Core) unlockUser(ctx context.Context, user *lockoutUser) error {
+	if c.perfStandby {
+		return logical.ErrReadOnly
+	}
+
+	if err := c.barrier.Delete(ctx, user.storagePath()); err != nil {
+		return err
+	}
+	c.resetFailedLogins(user)
+
+	return nil
+}
+
+// applyUserLockout checks whether the user of a login request is locked out
+// before the request is routed, returning a userLockedOutError if so.
+func (c *Core) applyUserLockout(ctx context.Context, user *lockoutUser) error {
+	err := c.checkUserLockedOut(ctx, user)
+	var lockedErr *userLockedOutError
+	if errors.As(err, &lockedErr) {
+		metrics.IncrCounter([]string{"core", "user_lockout", "rejected_logins"}, 1)
+		return err
+	}
+	if err != nil {
+		c.logger.Error("failed to check user lockout", "mount_accessor", user.mountAccessor, "error", err)
+		return ErrInternalError
+	}
+	return nil
+}
+
+// handleLoginLockoutResult updates the failed login attempts of a user
+// after its login request was routed, returning the error to respond with.
+func (c *Core) handleLoginLockoutResult(ctx context.Context, user *lockoutUser, settings *userLockoutSettings, resp *logical.Response, routeErr error) error {
+	switch {
+	case errors.Is(routeErr, logical.ErrInvalidCredentials):
+		if err := c.recordFailedLogin(ctx, user, settings); err != nil {
+			return err
+		}
+	case routeErr == nil && resp != nil && resp.Auth != nil:
+		c.resetFailedLogins(user)
+	}
+	return routeErr
+}
+
+// userLockoutClientError replaces user lockout errors with a plain
+// permission denied error once they have been audited.
+func userLockoutClientError(err error) error {
+	var lockedErr *userLockedOutError
+	if errors.As(err, &lockedErr) {
+		return logical.ErrPermissionDenied
+	}
+	return err
+}
@@ -0,0 +1,215 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/vault/audit"
+	credUserpass "github.com/hashicorp/vault/builtin/credential/userpass"
+	"github.com/hashicorp/vault/helper/namespace"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+func TestUserLockout_Userpass(t *testing.T) {
+	core, _, root := TestCoreUnsealed(t)
+	core.credentialBackends["userpass"] = credUserpass.Factory
+
+	noop := &NoopAudit{}
+	core.auditBackends["noop"] = func(ctx context.Context, config *audit.BackendConfig) (audit.Backend, error) {
+		noop.Config = config
+		return noop, nil
+	}
+
+	ctx := namespace.RootContext(nil)
+	request := func(op logical.Operation, path string, data map[string]interface{}) (*logical.Response, error) {
+		return core.HandleRequest(ctx, &logical.Request{
+			Operation:   op,
+			Path:        path,
+			ClientToken: root,
+			Data:        data,
+			Connection:  &logical.Connection{},
+		})
+	}
+	login := func(username, password string) error {
+		_, err := core.HandleRequest(ctx, &logical.Request{
+			Operation:  logical.UpdateOperation,
+			Path:       "auth/userpass/login/" + username,
+			Data:       map[string]interface{}{"password": password},
+			Connection: &logical.Connection{},
+		})
+		return err
+	}
+
+	if _, err := request(logical.UpdateOperation, "sys/audit/noop", map[string]interface{}{"type": "noop"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := request(logical.UpdateOperation, "sys/auth/userpass", map[string]interface{}{
+		"type": "userpass",
+		"config": map[string]interface{}{
+			"user_lockout_config": map[string]interface{}{
+				"lockout_threshold": "3",
+				"lockout_duration":  "1h",
+			},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"alice", "bob", "carol"} {
+		if _, err := request(logical.UpdateOperation, "auth/userpass/users/"+user, map[string]interface{}{"password": "secret"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mountEntry := core.router.MatchingMountEntry(ctx, "auth/userpass/")
+	if mountEntry == nil {
+		t.Fatal("userpass mount not found")
+	}
+	accessor := mountEntry.Accessor
+
+	resp, err := request(logical.ReadOperation, "sys/auth/userpass/tune", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := resp.Data["user_lockout_config"].(map[string]interface{})
+	if config["lockout_threshold"] != uint64(3) || config["lockout_duration"] != int64(3600) ||
+		config["lockout_counter_reset"] != int64(defaultUserLockoutCounterReset.Seconds()) {
+		t.Fatalf("bad: user lockout config %#v", config)
+	}
+
+	// Failed attempts below the threshold are reported as invalid
+	// credentials, and a successful login resets them.
+	for i := 0; i < 2; i++ {
+		if err := login("alice", "wrong"); !errors.Is(err, logical.ErrInvalidCredentials) {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+	}
+	if err := login("alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := login("alice", "wrong"); !errors.Is(err, logical.ErrInvalidCredentials) {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+	}
+
+	// The attempt reaching the threshold locks the user out, which is
+	// audited while clients only see permission denied.
+	err = login("alice", "wrong")
+	if err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	auditErr := noop.RespErrs[len(noop.RespErrs)-1]
+	var lockedErr *userLockedOutError
+	if !errors.As(auditErr, &lockedErr) || !lockedErr.triggered {
+		t.Fatalf("expected audited lockout, got %v", auditErr)
+	}
+	if !strings.Contains(auditErr.Error(), "locked out until") {
+		t.Fatalf("bad: audited error %v", auditErr)
+	}
+
+	// The correct password doesn't help while locked out, nor does it
+	// affect other users.
+	if err := login("alice", "secret"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	if err := login("bob", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err = request(logical.ReadOperation, "sys/locked-users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lockedUsers := resp.Data["locked_users"].([]map[string]interface{})
+	if len(lockedUsers) != 1 || lockedUsers[0]["mount_accessor"] != accessor ||
+		lockedUsers[0]["alias_identifier"] != "alice" || lockedUsers[0]["failed_login_attempts"] != uint64(3) {
+		t.Fatalf("bad: locked users %#v", lockedUsers)
+	}
+	resp, err = request(logical.ReadOperation, "sys/locked-users", map[string]interface{}{"mount_accessor": "auth_userpass_missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["total"] != 0 {
+		t.Fatalf("bad: locked users %#v", resp.Data)
+	}
+
+	// The lockout survives the in-memory state being lost.
+	core.userFailedLoginsLock.Lock()
+	core.userFailedLogins = make(map[lockoutUser]*failedLoginInfo)
+	core.userFailedLoginsLock.Unlock()
+	if err := login("alice", "secret"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+
+	if _, err := request(logical.UpdateOperation, "sys/locked-users/"+accessor+"/unlock/alice", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := login("alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	resp, err = request(logical.ReadOperation, "sys/locked-users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["total"] != 0 {
+		t.Fatalf("bad: locked users %#v", resp.Data)
+	}
+
+	// Users without an entity alias, who never logged in successfully, are
+	// locked out in memory only, and their failed login attempts are
+	// tracked in a bounded cache.
+	for i := 0; i < 2; i++ {
+		login("carol", "wrong")
+	}
+	if err := login("carol", "wrong"); err != logical.ErrPermissionDenied {
+		t.Fatalf("expected permission denied, got %v", err)
+	}
+	resp, err = request(logical.ReadOperation, "sys/locked-users", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.Data["total"] != 0 {
+		t.Fatalf("bad: locked users %#v", resp.Data)
+	}
+	core.userFailedLoginsLock.Lock()
+	_, tracked := core.userFailedLogins[lockoutUser{mountAccessor: accessor, aliasName: "carol"}]
+	core.userFailedLoginsLock.Unlock()
+	if tracked || core.unknownUserFailedLogins.Len() != 1 {
+		t.Fatal("expected unknown user to be tracked in the bounded cache only")
+	}
+	for i := 0; i < unknownUserFailedLoginsSize+10; i++ {
+		core.recordFailedLogin(ctx, &lockoutUser{mountAccessor: accessor, aliasName: fmt.Sprintf("user-%d", i)}, userLockoutSettingsForMount(mountEntry))
+	}
+	if core.unknownUserFailedLogins.Len() != unknownUserFailedLoginsSize {
+		t.Fatalf("bad: %d unknown users tracked", core.unknownUserFailedLogins.Len())
+	}
+
+	// Lockout can be disabled by tuning the mount.
+	if _, err := request(logical.UpdateOperation, "sys/auth/userpass/tune", map[string]interface{}{
+		"user_lockout_config": map[string]interface{}{"disable_lockout": true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := login("bob", "wrong"); !errors.Is(err, logical.ErrInvalidCredentials) {
+			t.Fatalf("expected invalid credentials, got %v", err)
+		}
+	}
+	if err := login("bob", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only supported auth methods can be configured.
+	if _, err := request(logical.UpdateOperation, "sys/auth/token/tune", map[string]interface{}{
+		"user_lockout_config": map[string]interface{}{"lockout_threshold": 10},
+	}); err == nil {
+		t.Fatal("expected error tuning user lockout of the token auth method")
+	}
+	if _, err := request(logical.UpdateOperation, "sys/auth/userpass/tune", map[string]interface{}{
+		"user_lockout_config": map[string]interface{}{"lockout_duration": "soon"},
+	}); err == nil {
+		t.Fatal("expected error tuning an invalid lockout duration")
+	}
+}